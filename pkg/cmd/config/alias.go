@@ -0,0 +1,221 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// defaultAliasTemplate names the alias of a context when neither --template nor the aliasTemplate
+// setting is set.
+const defaultAliasTemplate = "k{{.Context}}"
+
+var (
+	// invalidAliasCharacters are replaced by "-" in alias names, which every supported shell accepts.
+	invalidAliasCharacters = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+	// plainShellWord matches the words that need no quoting in the command line of any supported shell.
+	plainShellWord = regexp.MustCompile(`^[A-Za-z0-9_./-]+$`)
+)
+
+// aliasData is what the alias template of a context is executed with.
+type aliasData struct {
+	Context   string
+	Cluster   string
+	User      string
+	Namespace string
+	Tags      []string
+}
+
+// AliasOptions holds the command-line options for 'config alias export' sub command
+type AliasOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Shell        string
+	Template     string
+	Selector     string
+
+	genericclioptions.IOStreams
+}
+
+var (
+	aliasLong = templates.LongDesc(`
+		Print a shell alias for every context, which runs kubectl against the context and its
+		namespace.
+
+		The name of the alias of a context is a Go template executed with the fields Context,
+		Cluster, User, Namespace and Tags of the context, "k{{.Context}}" unless --template or the
+		aliasTemplate setting says otherwise. Characters shells do not accept in alias names are
+		replaced by "-", and contexts whose alias name is empty get no alias, so that a template can
+		leave some out.
+
+		With the shell integration of "kubectl config shell-init" loaded and KCFG_ALIASES=1, the
+		aliases are defined when the shell starts and kept up to date after every change made with
+		kcfg.`)
+
+	aliasExample = templates.Examples(`
+		# Define an alias such as kprod='kubectl --context prod -n shop' for every context
+		eval "$(kubectl config alias export)"
+
+		# Name the aliases after the clusters, only for the contexts tagged prod
+		kubectl config alias export --template 'k-{{.Cluster}}' -l prod
+
+		# Define the aliases in fish
+		kubectl config alias export --shell fish | source`)
+)
+
+// NewCmdConfigAlias returns a Command instance for 'config alias' sub command
+func NewCmdConfigAlias(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &AliasOptions{ConfigAccess: configAccess, IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "alias SUBCOMMAND",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Generate shell aliases for contexts"),
+		Long:                  aliasLong,
+		Example:               aliasExample,
+		Run:                   cmdutil.DefaultSubCommandRun(streams.ErrOut),
+	}
+
+	exportCmd := &cobra.Command{
+		Use:   "export [--shell=sh|fish|powershell] [--template=TEMPLATE] [--selector=SELECTOR]",
+		Short: i18n.T("Print a shell alias for every context"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			cmdutil.CheckErr(o.Complete())
+			cmdutil.CheckErr(o.RunExport())
+		},
+	}
+	exportCmd.Flags().StringVar(&o.Shell, "shell", "sh", "Syntax of the printed aliases. One of: sh|fish|powershell")
+	exportCmd.Flags().StringVar(&o.Template, "template", o.Template, "Go template naming the alias of a context. Defaults to the aliasTemplate setting, or "+defaultAliasTemplate)
+	exportCmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, "Selector (label query) on the tags and annotations of contexts, see 'config get-contexts'")
+	cmd.AddCommand(noWriteCommand(exportCmd))
+	return cmd
+}
+
+// Complete validates the shell and defaults the template to the setting
+func (o *AliasOptions) Complete() error {
+	switch o.Shell {
+	case "sh", "fish", "powershell":
+	default:
+		return fmt.Errorf("unsupported shell %q, must be one of: sh, fish, powershell", o.Shell)
+	}
+	if len(o.Template) == 0 {
+		settings, err := loadSettings(settingsFile())
+		if err != nil {
+			return err
+		}
+		o.Template = settings.AliasTemplate
+	}
+	if len(o.Template) == 0 {
+		o.Template = defaultAliasTemplate
+	}
+	return nil
+}
+
+// RunExport prints the aliases of the selected contexts
+func (o *AliasOptions) RunExport() error {
+	tmpl, err := template.New("alias").Option("missingkey=error").Parse(o.Template)
+	if err != nil {
+		return fmt.Errorf("invalid alias template: %v", err)
+	}
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	contexts, err := selectContexts(config, o.Selector)
+	if err != nil {
+		return err
+	}
+
+	defined := map[string]string{}
+	for _, name := range contexts {
+		context := config.Contexts[name]
+		metadata, err := readContextMetadata(context)
+		if err != nil {
+			return fmt.Errorf("context %q: %v", name, err)
+		}
+		buf := &bytes.Buffer{}
+		data := aliasData{Context: name, Cluster: context.Cluster, User: context.AuthInfo, Namespace: context.Namespace, Tags: metadata.Tags}
+		if err := tmpl.Execute(buf, data); err != nil {
+			return fmt.Errorf("context %q: %v", name, err)
+		}
+		alias := strings.Trim(invalidAliasCharacters.ReplaceAllString(strings.TrimSpace(buf.String()), "-"), "-")
+		if len(alias) == 0 {
+			continue
+		}
+		if other, ok := defined[alias]; ok {
+			fmt.Fprintf(o.ErrOut, "warning: skipping context %q, the alias %s is already defined for context %q\n", name, alias, other)
+			continue
+		}
+		defined[alias] = name
+
+		args := []string{"kubectl", "--context", name}
+		if len(context.Namespace) > 0 {
+			args = append(args, "-n", context.Namespace)
+		}
+		line, err := shellAlias(o.Shell, alias, args)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(o.Out, line)
+	}
+	return nil
+}
+
+// shellAlias returns the command defining alias to run args in shell, one of sh, fish or
+// powershell.
+func shellAlias(shell, alias string, args []string) (string, error) {
+	switch shell {
+	case "sh":
+		return fmt.Sprintf("alias %s=%s\n", alias, shellQuote(shellCommandLine(args, shellQuote))), nil
+	case "fish":
+		return fmt.Sprintf("alias %s %s\n", alias, fishQuote(shellCommandLine(args, fishQuote))), nil
+	case "powershell":
+		// PowerShell aliases cannot hold arguments, a function passes them on instead.
+		return fmt.Sprintf("function global:%s { & %s @args }\n", alias, shellCommandLine(args, func(s string) string {
+			return "'" + strings.Replace(s, "'", "''", -1) + "'"
+		})), nil
+	default:
+		return "", fmt.Errorf("unsupported shell %q, must be one of: sh, fish, powershell", shell)
+	}
+}
+
+// shellCommandLine joins args, quoting those that need it with quote.
+func shellCommandLine(args []string, quote func(string) string) string {
+	words := make([]string, 0, len(args))
+	for _, arg := range args {
+		if plainShellWord.MatchString(arg) {
+			words = append(words, arg)
+		} else {
+			words = append(words, quote(arg))
+		}
+	}
+	return strings.Join(words, " ")
+}
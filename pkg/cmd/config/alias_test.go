@@ -0,0 +1,111 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestAliasExport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "alias")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	config := clientcmdapi.NewConfig()
+	config.Contexts["prod"] = &clientcmdapi.Context{Cluster: "eks", AuthInfo: "admin", Namespace: "shop"}
+	config.Contexts["dev"] = &clientcmdapi.Context{Cluster: "kind", AuthInfo: "kind"}
+	config.Contexts["arn:aws:eks:eu-west-1:1234:cluster/it's"] = &clientcmdapi.Context{Cluster: "eks", AuthInfo: "admin"}
+	if err := writeContextMetadata(config.Contexts["prod"], contextMetadata{Tags: []string{"prod"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := clientcmd.WriteToFile(*config, filepath.Join(dir, "config")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = filepath.Join(dir, "config")
+	pathOptions.EnvVar = ""
+
+	tests := []struct {
+		name           string
+		shell          string
+		template       string
+		selector       string
+		expected       string
+		expectedErrOut string
+	}{
+		{
+			name:     "sh",
+			shell:    "sh",
+			template: defaultAliasTemplate,
+			expected: `alias karn-aws-eks-eu-west-1-1234-cluster-it-s='kubectl --context '\''arn:aws:eks:eu-west-1:1234:cluster/it'\''\'\'''\''s'\'''` + "\n" +
+				"alias kdev='kubectl --context dev'\n" +
+				"alias kprod='kubectl --context prod -n shop'\n",
+		},
+		{
+			name:     "fish",
+			shell:    "fish",
+			template: "k{{.Context}}",
+			selector: "prod",
+			expected: "alias kprod 'kubectl --context prod -n shop'\n",
+		},
+		{
+			name:     "powershell",
+			shell:    "powershell",
+			template: "k{{.Context}}",
+			selector: "prod",
+			expected: "function global:kprod { & kubectl --context prod -n shop @args }\n",
+		},
+		{
+			name:           "template leaving contexts out and clashing",
+			shell:          "sh",
+			template:       "{{if eq .Cluster \"eks\"}}k-{{.Cluster}}{{end}}",
+			expected:       "alias k-eks='kubectl --context '\\''arn:aws:eks:eu-west-1:1234:cluster/it'\\''\\'\\'''\\''s'\\'''\n",
+			expectedErrOut: "warning: skipping context \"prod\", the alias k-eks is already defined for context \"arn:aws:eks:eu-west-1:1234:cluster/it's\"\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			streams, _, out, errOut := genericclioptions.NewTestIOStreams()
+			o := &AliasOptions{ConfigAccess: pathOptions, Shell: tt.shell, Template: tt.template, Selector: tt.selector, IOStreams: streams}
+			if err := o.Complete(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := o.RunExport(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.String() != tt.expected {
+				t.Errorf("expected\n%s\ngot\n%s", tt.expected, out.String())
+			}
+			if errOut.String() != tt.expectedErrOut {
+				t.Errorf("expected warnings %q, got %q", tt.expectedErrOut, errOut.String())
+			}
+		})
+	}
+
+	o := &AliasOptions{ConfigAccess: pathOptions, Shell: "tcsh", Template: defaultAliasTemplate}
+	if err := o.Complete(); err == nil {
+		t.Errorf("expected an unsupported shell to fail")
+	}
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// ephemeralContext is stored in the ephemeralExtension of a context "config as" created. Expires
+// is an RFC 3339 time, after which "config gc" removes the context and its user.
+type ephemeralContext struct {
+	From    string `json:"from"`
+	Expires string `json:"expires"`
+}
+
+// expired reports whether the context has expired at now.
+func (e ephemeralContext) expired(now time.Time) bool {
+	expires, err := time.Parse(time.RFC3339, e.Expires)
+	return err == nil && !now.Before(expires)
+}
+
+// AsOptions holds the command-line options for 'config as' sub command
+type AsOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Identities   []string
+	From         string
+	Name         string
+	TTL          time.Duration
+	Now          func() time.Time
+
+	user   string
+	groups []string
+	extra  map[string][]string
+
+	genericclioptions.IOStreams
+}
+
+var (
+	asLong = templates.LongDesc(`
+		Create a temporary context impersonating a user or groups, to debug what they can do.
+
+		The context uses the cluster and namespace of the context given with --from, or the
+		current-context, and a copy of its user setting the impersonation fields, which kubectl
+		sends as Impersonate-* headers. Identities are given as user:NAME, group:NAME,
+		serviceaccount:NAMESPACE:NAME or extra:KEY=VALUE. The credentials of the original user
+		must be allowed to impersonate them.
+
+		The context is named after the original context and the identity, unless --name is
+		given, and expires after --ttl. "kubectl config gc" removes expired contexts along with
+		their users, so they are not left behind. Running the command again for the same name
+		renews the context.`)
+
+	asExample = templates.Examples(`
+		# Act as alice in the dev group for an hour, against the current-context
+		kubectl config as user:alice group:dev --ttl 1h
+		kubectl --context prod-as-alice auth can-i delete pods
+
+		# Act as a service account of prod
+		kubectl config as serviceaccount:kube-system:coredns --from prod`)
+)
+
+// NewCmdConfigAs returns a Command instance for 'config as' sub command
+func NewCmdConfigAs(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &AsOptions{
+		ConfigAccess: configAccess,
+		From:         currentContextShorthand,
+		TTL:          time.Hour,
+		Now:          time.Now,
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "as IDENTITY... [--from=CONTEXT_NAME] [--ttl=DURATION] [--name=NAME]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Create a temporary context impersonating a user or groups"),
+		Long:                  asLong,
+		Example:               asExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			o.Identities = args
+			cmdutil.CheckErr(o.Complete())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	cmd.Flags().StringVar(&o.From, "from", o.From, "The context to derive the temporary context from. Defaults to the current-context")
+	cmd.Flags().StringVar(&o.Name, "name", o.Name, "The name of the temporary context. Defaults to CONTEXT-as-USER")
+	cmd.Flags().DurationVar(&o.TTL, "ttl", o.TTL, "How long the temporary context lasts before 'kubectl config gc' removes it")
+	return cmd
+}
+
+// Complete parses the identities to impersonate
+func (o *AsOptions) Complete() error {
+	if len(o.Identities) == 0 {
+		return errors.New("you must specify an identity to impersonate, such as user:NAME or group:NAME")
+	}
+	if o.TTL <= 0 {
+		return fmt.Errorf("invalid --ttl %s, must be positive", o.TTL)
+	}
+	o.user, o.groups, o.extra = "", nil, nil
+	for _, identity := range o.Identities {
+		parts := strings.SplitN(identity, ":", 2)
+		if len(parts) != 2 || len(parts[1]) == 0 {
+			return fmt.Errorf("invalid identity %q, must be user:NAME, group:NAME, serviceaccount:NAMESPACE:NAME or extra:KEY=VALUE", identity)
+		}
+		kind, value := parts[0], parts[1]
+		switch kind {
+		case "user", "serviceaccount":
+			if len(o.user) > 0 {
+				return fmt.Errorf("only one user can be impersonated, got %s and %s", o.user, identity)
+			}
+			o.user = value
+			if kind == "serviceaccount" {
+				if strings.Count(value, ":") != 1 {
+					return fmt.Errorf("invalid identity %q, must be serviceaccount:NAMESPACE:NAME", identity)
+				}
+				o.user = "system:serviceaccount:" + value
+			}
+		case "group":
+			o.groups = append(o.groups, value)
+		case "extra":
+			keyValue := strings.SplitN(value, "=", 2)
+			if len(keyValue) != 2 || len(keyValue[0]) == 0 {
+				return fmt.Errorf("invalid identity %q, must be extra:KEY=VALUE", identity)
+			}
+			if o.extra == nil {
+				o.extra = map[string][]string{}
+			}
+			o.extra[keyValue[0]] = append(o.extra[keyValue[0]], keyValue[1])
+		default:
+			return fmt.Errorf("invalid identity %q, must be user:NAME, group:NAME, serviceaccount:NAMESPACE:NAME or extra:KEY=VALUE", identity)
+		}
+	}
+	// Kubernetes only accepts groups and extra together with the user they belong to.
+	if len(o.user) == 0 {
+		return errors.New("a user:NAME or serviceaccount:NAMESPACE:NAME identity is required, groups and extra cannot be impersonated alone")
+	}
+	return nil
+}
+
+// Run creates or renews the temporary context and its user
+func (o *AsOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	from, err := resolveContextName(config, o.From)
+	if err != nil {
+		return err
+	}
+	source, ok := config.Contexts[from]
+	if !ok {
+		return fmt.Errorf("no context exists with the name: %q", from)
+	}
+	authInfo, ok := config.AuthInfos[source.AuthInfo]
+	if !ok {
+		return fmt.Errorf("user %q of context %q does not exist", source.AuthInfo, from)
+	}
+
+	name := o.Name
+	if len(name) == 0 {
+		name = from + "-as-" + o.user[strings.LastIndex(o.user, ":")+1:]
+	}
+	// The context and its user share the name, and are only replaced when renewing them.
+	existing, renewing := config.Contexts[name]
+	if renewing {
+		if _, found, err := readEphemeralContext(existing); err != nil || !found {
+			return fmt.Errorf("context %q already exists and is not a temporary context of \"kubectl config as\", choose another --name", name)
+		}
+	}
+	if _, ok := config.AuthInfos[name]; ok && (!renewing || existing.AuthInfo != name) {
+		return fmt.Errorf("user %q already exists, choose another --name", name)
+	}
+
+	user := authInfo.DeepCopy()
+	user.LocationOfOrigin = ""
+	user.Extensions = nil
+	user.Impersonate = o.user
+	user.ImpersonateGroups = o.groups
+	user.ImpersonateUserExtra = o.extra
+	config.AuthInfos[name] = user
+
+	context := source.DeepCopy()
+	context.LocationOfOrigin = ""
+	context.Extensions = nil
+	context.AuthInfo = name
+	expires := o.Now().Add(o.TTL).UTC().Truncate(time.Second)
+	if err := writeExtension(&context.Extensions, ephemeralExtension, ephemeralContext{From: from, Expires: expires.Format(time.RFC3339)}); err != nil {
+		return err
+	}
+	config.Contexts[name] = context
+
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "Context %q acting as %s created, it expires at %s.\n", name, o.describe(), expires.Local().Format(time.RFC3339))
+	return nil
+}
+
+// describe returns the impersonated identity for messages.
+func (o *AsOptions) describe() string {
+	description := fmt.Sprintf("user %q", o.user)
+	if len(o.groups) > 0 {
+		description += fmt.Sprintf(" in groups %s", strings.Join(o.groups, ", "))
+	}
+	return description
+}
+
+// readEphemeralContext returns the expiry of a context "config as" created.
+func readEphemeralContext(context *clientcmdapi.Context) (ephemeralContext, bool, error) {
+	ephemeral := ephemeralContext{}
+	found, err := readExtension(context.Extensions, ephemeralExtension, &ephemeral)
+	return ephemeral, found, err
+}
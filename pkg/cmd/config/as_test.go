@@ -0,0 +1,159 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func TestAsIdentities(t *testing.T) {
+	tests := []struct {
+		identities []string
+		user       string
+		groups     []string
+		extra      map[string][]string
+		err        string
+	}{
+		{
+			identities: []string{"user:alice", "group:dev", "group:ops", "extra:scopes=view"},
+			user:       "alice",
+			groups:     []string{"dev", "ops"},
+			extra:      map[string][]string{"scopes": {"view"}},
+		},
+		{
+			identities: []string{"serviceaccount:kube-system:coredns"},
+			user:       "system:serviceaccount:kube-system:coredns",
+		},
+		{identities: []string{"group:dev"}, err: "groups and extra cannot be impersonated alone"},
+		{identities: []string{"user:alice", "user:bob"}, err: "only one user can be impersonated"},
+		{identities: []string{"serviceaccount:coredns"}, err: "must be serviceaccount:NAMESPACE:NAME"},
+		{identities: []string{"alice"}, err: `invalid identity "alice"`},
+		{identities: []string{"role:admin"}, err: `invalid identity "role:admin"`},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.identities, " "), func(t *testing.T) {
+			o := &AsOptions{Identities: tt.identities, TTL: time.Hour}
+			err := o.Complete()
+			if len(tt.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("expected an error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if o.user != tt.user || !reflect.DeepEqual(o.groups, tt.groups) || !reflect.DeepEqual(o.extra, tt.extra) {
+				t.Errorf("expected %s %v %v, got %s %v %v", tt.user, tt.groups, tt.extra, o.user, o.groups, o.extra)
+			}
+		})
+	}
+}
+
+func TestAs(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	if err := clientcmd.WriteToFile(newRedFederalCowHammerConfig(), fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	now := time.Date(2019, 8, 2, 10, 0, 0, 0, time.UTC)
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := &AsOptions{
+		ConfigAccess: pathOptions,
+		Identities:   []string{"user:alice", "group:dev"},
+		From:         "federal-context",
+		TTL:          time.Hour,
+		Now:          func() time.Time { return now },
+		IOStreams:    streams,
+	}
+	if err := o.Complete(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), `Context "federal-context-as-alice" acting as user "alice" in groups dev created`) {
+		t.Errorf("unexpected output %q", out.String())
+	}
+	config, err := clientcmd.LoadFromFile(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	context := config.Contexts["federal-context-as-alice"]
+	if context == nil || context.Cluster != "cow-cluster" || context.AuthInfo != "federal-context-as-alice" {
+		t.Fatalf("expected a context derived from federal-context, got %+v", context)
+	}
+	user := config.AuthInfos["federal-context-as-alice"]
+	if user == nil || user.Token != "red-token" || user.Impersonate != "alice" || !reflect.DeepEqual(user.ImpersonateGroups, []string{"dev"}) {
+		t.Errorf("expected a copy of red-user impersonating alice, got %+v", user)
+	}
+	if config.AuthInfos["red-user"].Impersonate != "" {
+		t.Errorf("expected the original user to be left alone")
+	}
+
+	// Renewing moves the expiry, and a context of another origin is never replaced.
+	now = now.Add(30 * time.Minute)
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	o.Name = "federal-context"
+	if err := o.Run(); err == nil || !strings.Contains(err.Error(), "is not a temporary context") {
+		t.Errorf("expected an existing context to be refused, got %v", err)
+	}
+
+	streams, _, out, _ = genericclioptions.NewTestIOStreams()
+	gc := &GCOptions{ConfigAccess: pathOptions, Now: func() time.Time { return now.Add(59 * time.Minute) }, IOStreams: streams}
+	if err := gc.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "No context") {
+		t.Errorf("expected the renewed context to be kept, got %q", out.String())
+	}
+	streams, _, out, _ = genericclioptions.NewTestIOStreams()
+	gc = &GCOptions{ConfigAccess: pathOptions, Now: func() time.Time { return now.Add(time.Hour) }, IOStreams: streams}
+	if err := gc.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `Removed context "federal-context-as-alice", a temporary context expired at 2019-08-02T11:30:00Z.
+Removed user "federal-context-as-alice", no longer used.
+`
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+	remaining, err := clientcmd.LoadFromFile(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if remaining.AuthInfos["red-user"] == nil || remaining.Clusters["cow-cluster"] == nil {
+		t.Errorf("expected the entries of federal-context to be kept, got %+v", remaining)
+	}
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	utilexec "k8s.io/utils/exec"
+)
+
+// sensitiveKubectlFlags are the global kubectl flags whose values are not recorded in the audit log.
+var sensitiveKubectlFlags = sets.NewString("token", "password")
+
+// auditLogFile returns the file the kubectl commands run by "config exec" are recorded in.
+func auditLogFile() string {
+	return filepath.Join(stateDir(), "audit.log")
+}
+
+// execAuditRecord is a kubectl command run by "config exec", a line of the audit log.
+type execAuditRecord struct {
+	Time     time.Time `json:"time"`
+	User     string    `json:"user,omitempty"`
+	Host     string    `json:"host,omitempty"`
+	Context  string    `json:"context"`
+	Cluster  string    `json:"cluster,omitempty"`
+	Server   string    `json:"server,omitempty"`
+	Command  []string  `json:"command"`
+	ExitCode int       `json:"exitCode"`
+	Duration string    `json:"duration"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// execAuditLog records the kubectl commands "config exec" runs, with the auditExec setting, in
+// Filename, and sends them to Webhook when it is set and NoNetwork is not.
+type execAuditLog struct {
+	Filename  string
+	Webhook   string
+	Client    *http.Client
+	NoNetwork bool
+}
+
+// newExecAuditRecord returns the record of the kubectl command line args run against the context
+// called name, which returned err after starting at started.
+func newExecAuditRecord(name, cluster, server string, args []string, started time.Time, err error) execAuditRecord {
+	record := execAuditRecord{
+		Time:     started.UTC(),
+		Context:  name,
+		Cluster:  cluster,
+		Server:   server,
+		Command:  redactKubectlArgs(args),
+		Duration: time.Since(started).Round(time.Millisecond).String(),
+	}
+	if current, err := user.Current(); err == nil {
+		record.User = current.Username
+	}
+	record.Host, _ = os.Hostname()
+	if err != nil {
+		record.ExitCode = -1
+		if exitErr, ok := err.(utilexec.ExitError); ok {
+			record.ExitCode = exitErr.ExitStatus()
+		}
+		if message := err.Error(); len(message) > 0 {
+			record.Error = message
+		}
+	}
+	return record
+}
+
+// redactKubectlArgs returns the kubectl command line args with the values of sensitiveKubectlFlags
+// replaced by REDACTED.
+func redactKubectlArgs(args []string) []string {
+	redacted := []string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return append(redacted, args[i:]...)
+		}
+		name, takesValue := globalFlag(arg)
+		if !strings.HasPrefix(arg, "-") || !sensitiveKubectlFlags.Has(name) {
+			redacted = append(redacted, arg)
+			continue
+		}
+		if i := strings.Index(arg, "="); i >= 0 {
+			redacted = append(redacted, arg[:i+1]+"REDACTED")
+			continue
+		}
+		redacted = append(redacted, arg)
+		if flagTakesSeparateValue(arg, takesValue, false) && i+1 < len(args) {
+			redacted = append(redacted, "REDACTED")
+			i++
+		}
+	}
+	return redacted
+}
+
+// record appends record to the audit log, and posts it to the webhook. Without network, the record
+// is only appended, and an error tells the webhook did not get it.
+func (l *execAuditLog) record(record execAuditRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(l.Filename), 0700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(l.Filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(data, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(l.Webhook) == 0 {
+		return nil
+	}
+	if l.NoNetwork {
+		return fmt.Errorf("audit webhook: not posted, network access is disabled by --%s or $%s", FlagNoNetwork, NoNetworkEnvVar)
+	}
+	client := l.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Post(l.Webhook, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("audit webhook: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("audit webhook: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	utilexec "k8s.io/utils/exec"
+)
+
+func TestExecAudit(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	if err := clientcmd.WriteToFile(newRedFederalCowHammerConfig(), fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	posted := []execAuditRecord{}
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		record := execAuditRecord{}
+		if err := json.NewDecoder(r.Body).Decode(&record); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		posted = append(posted, record)
+	}))
+	defer webhook.Close()
+
+	run := func(kubectlErr error, args ...string) error {
+		streams, _, _, _ := genericclioptions.NewTestIOStreams()
+		o := &ExecOptions{
+			ConfigAccess: pathOptions,
+			Context:      "federal-context",
+			Args:         args,
+			Kubectl:      "kubectl",
+			RunKubectl:   func(kubectl string, args []string) error { return kubectlErr },
+			Audit:        &execAuditLog{Filename: filepath.Join(dir, "audit.log"), Webhook: webhook.URL},
+			IOStreams:    streams,
+		}
+		return o.Run()
+	}
+	if err := run(nil, "get", "pods"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	failed := utilexec.CodeExitError{Err: errors.New(""), Code: 1}
+	if err := run(failed, "--token", "secret", "delete", "pod", "web"); err != failed {
+		t.Fatalf("expected the exit status of kubectl, got %v", err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "audit.log"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 records, got\n%s", data)
+	}
+	records := []execAuditRecord{}
+	for _, line := range lines {
+		record := execAuditRecord{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		records = append(records, record)
+	}
+	if records[0].Context != "federal-context" || records[0].Cluster != "cow-cluster" || records[0].ExitCode != 0 || !reflect.DeepEqual(records[0].Command, []string{"get", "pods"}) {
+		t.Errorf("unexpected record %+v", records[0])
+	}
+	if records[1].ExitCode != 1 || len(records[1].Error) > 0 || !reflect.DeepEqual(records[1].Command, []string{"--token", "REDACTED", "delete", "pod", "web"}) {
+		t.Errorf("unexpected record %+v", records[1])
+	}
+	if !reflect.DeepEqual(posted, records) {
+		t.Errorf("expected the webhook to get the records of the log, got %+v", posted)
+	}
+
+	streams, _, _, errOut := genericclioptions.NewTestIOStreams()
+	o := &ExecOptions{
+		ConfigAccess: pathOptions,
+		Context:      "federal-context",
+		Args:         []string{"get", "pods"},
+		Kubectl:      "kubectl",
+		RunKubectl:   func(kubectl string, args []string) error { return nil },
+		Audit:        &execAuditLog{Filename: filepath.Join(dir, "audit.log"), Webhook: webhook.URL, NoNetwork: true},
+		IOStreams:    streams,
+	}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(posted) != 2 {
+		t.Errorf("expected the webhook not to be posted to without network, got %+v", posted)
+	}
+	if !strings.Contains(errOut.String(), "audit webhook: not posted") {
+		t.Errorf("expected a warning about the webhook, got %q", errOut.String())
+	}
+	if data, err := ioutil.ReadFile(filepath.Join(dir, "audit.log")); err != nil || strings.Count(string(data), "\n") != 3 {
+		t.Errorf("expected the record to be logged without network, got %v\n%s", err, data)
+	}
+}
+
+func TestRedactKubectlArgs(t *testing.T) {
+	tests := map[string]struct {
+		args     []string
+		expected []string
+	}{
+		"nothing sensitive": {
+			args:     []string{"-n", "kube-system", "get", "pods"},
+			expected: []string{"-n", "kube-system", "get", "pods"},
+		},
+		"separate value": {
+			args:     []string{"--token", "abc", "get", "pods"},
+			expected: []string{"--token", "REDACTED", "get", "pods"},
+		},
+		"joined value": {
+			args:     []string{"get", "pods", "--password=hunter2"},
+			expected: []string{"get", "pods", "--password=REDACTED"},
+		},
+		"after --": {
+			args:     []string{"exec", "web", "--", "login", "--token", "abc"},
+			expected: []string{"exec", "web", "--", "login", "--token", "abc"},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if redacted := redactKubectlArgs(test.args); !reflect.DeepEqual(redacted, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, redacted)
+			}
+		})
+	}
+}
@@ -0,0 +1,557 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/printers"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// Keys of the conditions of autoswitch rules.
+const (
+	autoswitchDNSSuffix = "dns-suffix"
+	autoswitchInterface = "interface"
+	autoswitchCIDR      = "cidr"
+)
+
+var autoswitchConditionKeys = []string{autoswitchDNSSuffix, autoswitchInterface, autoswitchCIDR}
+
+// resolvConf is where the DNS search domains are read from.
+const resolvConf = "/etc/resolv.conf"
+
+// autoswitchRule switches to the context Use when all its conditions, of the form key=value, hold.
+type autoswitchRule struct {
+	When []string `json:"when"`
+	Use  string   `json:"use"`
+}
+
+func (r autoswitchRule) String() string {
+	return strings.Join(r.When, ",") + " -> " + r.Use
+}
+
+// autoswitchRules is stored in the preferences' autoswitchExtension. Matched is the rule that
+// matched at the last run, so that a rule only switches when the network changes and leaves the
+// contexts switched to by hand alone in the meantime.
+type autoswitchRules struct {
+	Rules   []autoswitchRule `json:"rules,omitempty"`
+	Matched string           `json:"matched,omitempty"`
+}
+
+// networkLocation is what autoswitch conditions are evaluated against.
+type networkLocation struct {
+	SearchDomains []string
+	// Interfaces are the names of the network interfaces that are up.
+	Interfaces []string
+	Addresses  []net.IP
+}
+
+// AutoswitchOptions holds the command-line options for 'config autoswitch' sub command
+type AutoswitchOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	When         []string
+	Use          string
+	Index        int
+	DryRun       bool
+	Daemon       bool
+	Interval     time.Duration
+
+	// Locate describes the network the machine is on, it is a field so tests can replace it.
+	Locate func() (networkLocation, error)
+	// Cooloff keeps the rules from switching to contexts whose acknowledgement ran out.
+	Cooloff *cooloffPolicy
+	// Confirm is the confirm setting, and Protected the contexts it covers when set to protected.
+	Confirm   string
+	Protected *cooloffPolicy
+
+	genericclioptions.IOStreams
+}
+
+var (
+	autoswitchLong = templates.LongDesc(`
+		Switch the current-context according to the network the machine is on.
+
+		Rules are evaluated in order by "kubectl config autoswitch run", and the first rule whose
+		conditions all hold selects the context switched to. The conditions are:
+
+		    * dns-suffix=DOMAIN, a DNS search domain is DOMAIN or ends with it, as set by most VPNs
+		      and office networks
+		    * interface=PATTERN, a network interface whose name matches the shell pattern is up,
+		      such as utun* or wg0
+		    * cidr=CIDR, the machine has an address in the CIDR
+
+		A rule only switches when it starts matching, so that a context switched to by hand is kept
+		until the network changes again. Contexts the cooloff setting applies to are only switched
+		to while acknowledged, see "kubectl config acknowledge", and contexts that need a touch of
+		the security key, see "kubectl config protect", or a confirmation, see the confirm
+		setting, never.
+
+		Run "kubectl config autoswitch run --daemon", or set KCFG_AUTOSWITCH=1 with the shell
+		integration of "kubectl config shell-init" loaded to run it before every prompt.`)
+
+	autoswitchExample = templates.Examples(`
+		# Use the office cluster on the corporate network
+		kubectl config autoswitch add --when dns-suffix=corp.example --use office-cluster
+
+		# Use the staging cluster when the VPN is up
+		kubectl config autoswitch add --when interface=utun* --when cidr=10.8.0.0/16 --use staging
+
+		# Show which context the rules select, without switching
+		kubectl config autoswitch run --dry-run
+
+		# Remove the second rule
+		kubectl config autoswitch remove 2`)
+)
+
+// NewCmdConfigAutoswitch returns a Command instance for 'config autoswitch' sub command
+func NewCmdConfigAutoswitch(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &AutoswitchOptions{ConfigAccess: configAccess, Interval: 30 * time.Second, Locate: currentNetworkLocation, IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "autoswitch SUBCOMMAND",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Switch contexts according to the network the machine is on"),
+		Long:                  autoswitchLong,
+		Example:               autoswitchExample,
+		Run:                   cmdutil.DefaultSubCommandRun(streams.ErrOut),
+	}
+
+	addCmd := &cobra.Command{
+		Use:   "add --when KEY=VALUE... --use CONTEXT_NAME",
+		Short: i18n.T("Add a rule switching to a context"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			cmdutil.CheckErr(o.Complete())
+			cmdutil.CheckErr(o.RunAdd())
+		},
+	}
+	addCmd.Flags().StringArrayVar(&o.When, "when", o.When, "Condition of the rule: "+strings.Join(autoswitchConditionKeys, "|")+"=VALUE. Can be repeated, all conditions must hold")
+	addCmd.Flags().StringVar(&o.Use, "use", o.Use, "Context switched to when the rule matches")
+	cmd.AddCommand(addCmd)
+
+	cmd.AddCommand(noWriteCommand(&cobra.Command{
+		Use:   "list",
+		Short: i18n.T("List the rules in the order they are evaluated"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			cmdutil.CheckErr(o.RunList())
+		},
+	}))
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "remove INDEX",
+		Short: i18n.T("Remove a rule, by its index in 'autoswitch list'"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			index, err := strconv.Atoi(args[0])
+			if err != nil {
+				cmdutil.CheckErr(helpErrorf(cmd, "invalid index %q", args[0]))
+			}
+			o.Index = index
+			cmdutil.CheckErr(o.RunRemove())
+		},
+	})
+
+	runCmd := &cobra.Command{
+		Use:   "run [--dry-run] [--daemon [--interval=DURATION]]",
+		Short: i18n.T("Switch to the context selected by the rules"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			if o.Daemon && o.Interval <= 0 {
+				cmdutil.CheckErr(errors.New("--interval must be positive"))
+			}
+			cmdutil.CheckErr(o.Complete())
+			cmdutil.CheckErr(o.RunRules(nil))
+		},
+	}
+	runCmd.Flags().BoolVar(&o.DryRun, "dry-run", o.DryRun, "If true, print the context the rules select without switching to it")
+	runCmd.Flags().BoolVar(&o.Daemon, "daemon", o.Daemon, "If true, keep evaluating the rules every --interval until stopped")
+	runCmd.Flags().DurationVar(&o.Interval, "interval", o.Interval, "Time between two evaluations of the rules with --daemon")
+	cmd.AddCommand(runCmd)
+	return cmd
+}
+
+// Complete sets the cooloff and confirm policies from the settings
+func (o *AutoswitchOptions) Complete() error {
+	settings, err := loadSettings(settingsFile())
+	if err != nil {
+		return err
+	}
+	settings = withSafeMode(settings)
+	o.Confirm = settings.Confirm
+	if o.Confirm == confirmProtected {
+		o.Protected = protectedContexts(settings)
+	}
+	o.Cooloff, err = newCooloffPolicy(settings)
+	return err
+}
+
+// RunAdd validates the rule and appends it to the rules
+func (o *AutoswitchOptions) RunAdd() error {
+	if len(o.When) == 0 {
+		return errors.New("a rule needs at least one condition, given with --when")
+	}
+	if len(o.Use) == 0 {
+		return errors.New("a rule needs the context it switches to, given with --use")
+	}
+	for _, condition := range o.When {
+		if err := validateAutoswitchCondition(condition); err != nil {
+			return err
+		}
+	}
+
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	if err := o.checkTarget(config, o.Use); err != nil {
+		return err
+	}
+	rules, err := readAutoswitchRules(config)
+	if err != nil {
+		return err
+	}
+	rule := autoswitchRule{When: o.When, Use: o.Use}
+	rules.Rules = append(rules.Rules, rule)
+	if err := writeAutoswitchRules(config, rules); err != nil {
+		return err
+	}
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "Rule %d added: %s.\n", len(rules.Rules), rule)
+	return nil
+}
+
+// RunList prints the rules with their index
+func (o *AutoswitchOptions) RunList() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	rules, err := readAutoswitchRules(config)
+	if err != nil {
+		return err
+	}
+	if len(rules.Rules) == 0 {
+		fmt.Fprintln(o.Out, "No autoswitch rules found.")
+		return nil
+	}
+	w := printers.GetNewTabWriter(o.Out)
+	fmt.Fprintf(w, "INDEX\tWHEN\tUSE\n")
+	for i, rule := range rules.Rules {
+		fmt.Fprintf(w, "%d\t%s\t%s\n", i+1, strings.Join(rule.When, ","), rule.Use)
+	}
+	return w.Flush()
+}
+
+// RunRemove removes the rule at the index, counted from 1
+func (o *AutoswitchOptions) RunRemove() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	rules, err := readAutoswitchRules(config)
+	if err != nil {
+		return err
+	}
+	if o.Index < 1 || o.Index > len(rules.Rules) {
+		return fmt.Errorf("no rule with index %d, there are %d rule(s)", o.Index, len(rules.Rules))
+	}
+	removed := rules.Rules[o.Index-1]
+	rules.Rules = append(rules.Rules[:o.Index-1], rules.Rules[o.Index:]...)
+	if rules.Matched == removed.String() {
+		rules.Matched = ""
+	}
+	if err := writeAutoswitchRules(config, rules); err != nil {
+		return err
+	}
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "Rule %d removed: %s.\n", o.Index, removed)
+	return nil
+}
+
+// RunRules switches to the context of the first matching rule once, or until stop is closed with
+// --daemon, which never happens for a nil channel
+func (o *AutoswitchOptions) RunRules(stop <-chan struct{}) error {
+	for {
+		if err := o.runOnce(); err != nil {
+			return err
+		}
+		if !o.Daemon {
+			return nil
+		}
+		select {
+		case <-stop:
+			return nil
+		case <-time.After(o.Interval):
+		}
+	}
+}
+
+// runOnce evaluates the rules and switches to the context of the first matching rule, if it
+// did not match the last time.
+func (o *AutoswitchOptions) runOnce() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	rules, err := readAutoswitchRules(config)
+	if err != nil || len(rules.Rules) == 0 {
+		return err
+	}
+	location, err := o.Locate()
+	if err != nil {
+		return err
+	}
+
+	var matched *autoswitchRule
+	for i := range rules.Rules {
+		ok, err := matchAutoswitchRule(rules.Rules[i], location)
+		if err != nil {
+			return err
+		}
+		if ok {
+			matched = &rules.Rules[i]
+			break
+		}
+	}
+	if o.DryRun {
+		if matched == nil {
+			fmt.Fprintln(o.Out, "No rule matches.")
+		} else {
+			fmt.Fprintf(o.Out, "Rule %s matches.\n", matched)
+		}
+		return nil
+	}
+
+	key := ""
+	if matched != nil {
+		key = matched.String()
+	}
+	if key == rules.Matched {
+		return nil
+	}
+	rules.Matched = key
+	if matched != nil && config.CurrentContext != matched.Use {
+		if err := o.checkTarget(config, matched.Use); err != nil {
+			return fmt.Errorf("rule %s: %v", matched, err)
+		}
+		config.CurrentContext = matched.Use
+		fmt.Fprintf(o.Out, "Switched to context %q (%s).\n", matched.Use, strings.Join(matched.When, ","))
+	}
+	if err := writeAutoswitchRules(config, rules); err != nil {
+		return err
+	}
+	return clientcmd.ModifyConfig(o.ConfigAccess, *config, true)
+}
+
+// checkTarget returns an error unless the context called name exists and can be switched to
+// without anyone at the terminal, which rules run before a prompt or by a daemon cannot count on:
+// it must need neither a touch of the security key nor a confirmation, and its cooloff
+// acknowledgement must be current.
+func (o *AutoswitchOptions) checkTarget(config *clientcmdapi.Config, name string) error {
+	context, ok := config.Contexts[name]
+	if !ok {
+		return fmt.Errorf("no context exists with the name: %q", name)
+	}
+	protection, err := readContextProtection(context)
+	if err != nil {
+		return err
+	}
+	if protection.FIDO2 != nil {
+		return fmt.Errorf("context %q requires a touch of the security key and cannot be switched to automatically", name)
+	}
+	switch o.Confirm {
+	case confirmAlways:
+		return fmt.Errorf("context %q needs a confirmation with the confirm setting %q and cannot be switched to automatically", name, o.Confirm)
+	case confirmProtected:
+		reason, err := o.Protected.reason(name, context)
+		if err != nil {
+			return err
+		}
+		if len(reason) > 0 {
+			return fmt.Errorf("context %q is %s and needs a confirmation, it cannot be switched to automatically", name, reason)
+		}
+	}
+	if o.Cooloff != nil {
+		return o.Cooloff.check(config, name, false)
+	}
+	return nil
+}
+
+// readAutoswitchRules returns the autoswitch rules of a kubeconfig.
+func readAutoswitchRules(config *clientcmdapi.Config) (*autoswitchRules, error) {
+	rules := &autoswitchRules{}
+	if _, err := readExtension(config.Preferences.Extensions, autoswitchExtension, rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// writeAutoswitchRules stores the autoswitch rules of a kubeconfig, removing the extension when
+// there are none.
+func writeAutoswitchRules(config *clientcmdapi.Config, rules *autoswitchRules) error {
+	if len(rules.Rules) == 0 {
+		delete(config.Preferences.Extensions, autoswitchExtension)
+		return nil
+	}
+	return writeExtension(&config.Preferences.Extensions, autoswitchExtension, rules)
+}
+
+// validateAutoswitchCondition makes sure a condition has a known key and a valid value.
+func validateAutoswitchCondition(condition string) error {
+	parts := strings.SplitN(condition, "=", 2)
+	if len(parts) != 2 || len(parts[1]) == 0 {
+		return fmt.Errorf("invalid condition %q, must be KEY=VALUE", condition)
+	}
+	switch parts[0] {
+	case autoswitchDNSSuffix:
+		return nil
+	case autoswitchInterface:
+		if _, err := path.Match(parts[1], ""); err != nil {
+			return fmt.Errorf("invalid interface pattern %q: %v", parts[1], err)
+		}
+		return nil
+	case autoswitchCIDR:
+		if _, _, err := net.ParseCIDR(parts[1]); err != nil {
+			return fmt.Errorf("invalid condition %q: %v", condition, err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown condition %q, must be one of: %s", parts[0], strings.Join(autoswitchConditionKeys, ", "))
+	}
+}
+
+// matchAutoswitchRule reports whether all the conditions of a rule hold at a location.
+func matchAutoswitchRule(rule autoswitchRule, location networkLocation) (bool, error) {
+	for _, condition := range rule.When {
+		if err := validateAutoswitchCondition(condition); err != nil {
+			return false, fmt.Errorf("rule %s: %v", rule, err)
+		}
+		parts := strings.SplitN(condition, "=", 2)
+		value := parts[1]
+		matched := false
+		switch parts[0] {
+		case autoswitchDNSSuffix:
+			value = strings.Trim(value, ".")
+			for _, domain := range location.SearchDomains {
+				domain = strings.Trim(domain, ".")
+				if domain == value || strings.HasSuffix(domain, "."+value) {
+					matched = true
+				}
+			}
+		case autoswitchInterface:
+			for _, name := range location.Interfaces {
+				if ok, _ := path.Match(value, name); ok {
+					matched = true
+				}
+			}
+		case autoswitchCIDR:
+			_, network, _ := net.ParseCIDR(value)
+			for _, address := range location.Addresses {
+				if network.Contains(address) {
+					matched = true
+				}
+			}
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// currentNetworkLocation reads the DNS search domains from resolv.conf, where it exists, and the
+// interfaces that are up with their addresses.
+func currentNetworkLocation() (networkLocation, error) {
+	location := networkLocation{}
+	f, err := os.Open(resolvConf)
+	if err != nil && !os.IsNotExist(err) {
+		return location, err
+	}
+	if err == nil {
+		location.SearchDomains = parseSearchDomains(f)
+		f.Close()
+	}
+
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return location, err
+	}
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+		location.Interfaces = append(location.Interfaces, iface.Name)
+		addresses, err := iface.Addrs()
+		if err != nil {
+			return location, err
+		}
+		for _, address := range addresses {
+			if ipNet, ok := address.(*net.IPNet); ok {
+				location.Addresses = append(location.Addresses, ipNet.IP)
+			}
+		}
+	}
+	return location, nil
+}
+
+// parseSearchDomains returns the domains of the search and domain lines of a resolv.conf file.
+func parseSearchDomains(r io.Reader) []string {
+	domains := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || (fields[0] != "search" && fields[0] != "domain") {
+			continue
+		}
+		for _, domain := range fields[1:] {
+			if !containsString(domains, domain) {
+				domains = append(domains, domain)
+			}
+		}
+	}
+	return domains
+}
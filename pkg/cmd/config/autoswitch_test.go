@@ -0,0 +1,207 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestMatchAutoswitchRule(t *testing.T) {
+	location := networkLocation{
+		SearchDomains: []string{"eu.corp.example."},
+		Interfaces:    []string{"lo", "en0", "utun3"},
+		Addresses:     []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("10.8.1.20")},
+	}
+	tests := []struct {
+		when     []string
+		expected bool
+	}{
+		{when: []string{"dns-suffix=corp.example"}, expected: true},
+		{when: []string{"dns-suffix=eu.corp.example"}, expected: true},
+		{when: []string{"dns-suffix=orp.example"}},
+		{when: []string{"interface=utun*"}, expected: true},
+		{when: []string{"interface=wg0"}},
+		{when: []string{"cidr=10.8.0.0/16"}, expected: true},
+		{when: []string{"cidr=192.168.0.0/16"}},
+		{when: []string{"interface=utun*", "cidr=10.8.0.0/16"}, expected: true},
+		{when: []string{"interface=utun*", "cidr=192.168.0.0/16"}},
+	}
+	for _, tt := range tests {
+		matched, err := matchAutoswitchRule(autoswitchRule{When: tt.when, Use: "office"}, location)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if matched != tt.expected {
+			t.Errorf("expected %v to match: %v, got %v", tt.when, tt.expected, matched)
+		}
+	}
+
+	for _, condition := range []string{"ssid=office", "cidr=10.8.0.0", "interface=utun[", "dns-suffix="} {
+		if err := validateAutoswitchCondition(condition); err == nil {
+			t.Errorf("expected %q to be invalid", condition)
+		}
+	}
+}
+
+func TestParseSearchDomains(t *testing.T) {
+	resolv := "# generated\ndomain corp.example\nsearch eu.corp.example corp.example\nnameserver 10.0.0.53\n"
+	expected := []string{"corp.example", "eu.corp.example"}
+	if domains := parseSearchDomains(strings.NewReader(resolv)); !reflect.DeepEqual(domains, expected) {
+		t.Errorf("expected %v, got %v", expected, domains)
+	}
+}
+
+func TestAutoswitch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "autoswitch")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	config := clientcmdapi.NewConfig()
+	for _, name := range []string{"office-cluster", "home", "staging"} {
+		config.Contexts[name] = &clientcmdapi.Context{Cluster: name}
+	}
+	config.CurrentContext = "home"
+	config.Contexts["vault"] = &clientcmdapi.Context{Cluster: "vault"}
+	if err := writeExtension(&config.Contexts["vault"].Extensions, protectionExtension, contextProtection{FIDO2: &fido2Credential{RelyingParty: "kubecfg"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := clientcmd.WriteToFile(*config, filepath.Join(dir, "config")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = filepath.Join(dir, "config")
+	pathOptions.EnvVar = ""
+
+	location := networkLocation{}
+	newOptions := func() (*AutoswitchOptions, func() string) {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		o := &AutoswitchOptions{
+			ConfigAccess: pathOptions,
+			Locate:       func() (networkLocation, error) { return location, nil },
+			IOStreams:    streams,
+		}
+		return o, out.String
+	}
+	currentContext := func() string {
+		config, err := clientcmd.LoadFromFile(pathOptions.GlobalFile)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return config.CurrentContext
+	}
+
+	for _, rule := range []autoswitchRule{
+		{When: []string{"dns-suffix=corp.example"}, Use: "office-cluster"},
+		{When: []string{"interface=utun*"}, Use: "staging"},
+	} {
+		o, _ := newOptions()
+		o.When, o.Use = rule.When, rule.Use
+		if err := o.RunAdd(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	o, _ := newOptions()
+	o.When, o.Use = []string{"cidr=10.0.0.0/8"}, "missing"
+	if err := o.RunAdd(); err == nil || !strings.Contains(err.Error(), "no context exists") {
+		t.Errorf("expected a rule for a missing context to fail, got %v", err)
+	}
+	o, _ = newOptions()
+	o.Cooloff = &cooloffPolicy{Period: time.Hour, Patterns: []string{"stag*"}, Filename: filepath.Join(dir, "cooloff.yaml"), Now: time.Now}
+	o.When, o.Use = []string{"cidr=10.0.0.0/8"}, "staging"
+	if err := o.RunAdd(); err == nil || !strings.Contains(err.Error(), "must be acknowledged") {
+		t.Errorf("expected a rule for a context not acknowledged to fail, got %v", err)
+	}
+	o, _ = newOptions()
+	o.When, o.Use = []string{"cidr=10.0.0.0/8"}, "vault"
+	if err := o.RunAdd(); err == nil || !strings.Contains(err.Error(), "security key") {
+		t.Errorf("expected a rule for a protected context to fail, got %v", err)
+	}
+	o, _ = newOptions()
+	o.Confirm, o.Protected = confirmProtected, &cooloffPolicy{Patterns: []string{"stag*"}}
+	o.When, o.Use = []string{"cidr=10.0.0.0/8"}, "staging"
+	if err := o.RunAdd(); err == nil || !strings.Contains(err.Error(), "needs a confirmation") {
+		t.Errorf("expected a rule for a context needing confirmation to fail, got %v", err)
+	}
+
+	o, out := newOptions()
+	if err := o.RunList(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "INDEX   WHEN                      USE\n1       dns-suffix=corp.example   office-cluster\n2       interface=utun*           staging\n"; out() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out())
+	}
+
+	// Nothing matches at home.
+	o, out = newOptions()
+	if err := o.RunRules(nil); err != nil || out() != "" || currentContext() != "home" {
+		t.Errorf("expected no switch, got %q, %v", out(), err)
+	}
+
+	// Joining the VPN at the office, the first rule wins.
+	location = networkLocation{SearchDomains: []string{"corp.example"}, Interfaces: []string{"utun0"}}
+	o, out = newOptions()
+	if err := o.RunRules(nil); err != nil || out() != "Switched to context \"office-cluster\" (dns-suffix=corp.example).\n" {
+		t.Errorf("unexpected output %q, %v", out(), err)
+	}
+	if current := currentContext(); current != "office-cluster" {
+		t.Errorf("expected to switch to office-cluster, got %q", current)
+	}
+
+	// A context switched to by hand is kept while the network stays the same.
+	if err := (&UseContextOptions{ConfigAccess: pathOptions, ContextName: "home"}).Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	o, out = newOptions()
+	if err := o.RunRules(nil); err != nil || out() != "" || currentContext() != "home" {
+		t.Errorf("expected the context switched to by hand to be kept, got %q, %v", out(), err)
+	}
+
+	o, out = newOptions()
+	o.DryRun = true
+	if err := o.RunRules(nil); err != nil || out() != "Rule dns-suffix=corp.example -> office-cluster matches.\n" {
+		t.Errorf("unexpected output %q, %v", out(), err)
+	}
+
+	// Leaving the office with the VPN still up.
+	location = networkLocation{Interfaces: []string{"utun0"}}
+	o, _ = newOptions()
+	if err := o.RunRules(nil); err != nil || currentContext() != "staging" {
+		t.Errorf("expected to switch to staging, got %q, %v", currentContext(), err)
+	}
+
+	o, out = newOptions()
+	o.Index = 3
+	if err := o.RunRemove(); err == nil {
+		t.Errorf("expected removing a missing rule to fail")
+	}
+	o.Index = 1
+	if err := o.RunRemove(); err != nil || out() != "Rule 1 removed: dns-suffix=corp.example -> office-cluster.\n" {
+		t.Errorf("unexpected output %q, %v", out(), err)
+	}
+}
@@ -0,0 +1,281 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/printers"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// contextBanner is stored in a context's bannerExtension.
+type contextBanner struct {
+	Text string `json:"text"`
+}
+
+// tagBanners is stored in the preferences' tagBannersExtension, by tag.
+type tagBanners map[string]string
+
+// readTagBanners returns the banners of the context tags of a kubeconfig.
+func readTagBanners(config *clientcmdapi.Config) (tagBanners, error) {
+	banners := tagBanners{}
+	if _, err := readExtension(config.Preferences.Extensions, tagBannersExtension, &banners); err != nil {
+		return nil, err
+	}
+	return banners, nil
+}
+
+// writeTagBanners stores the banners of context tags, removing the extension when there are none.
+func writeTagBanners(config *clientcmdapi.Config, banners tagBanners) error {
+	if len(banners) == 0 {
+		delete(config.Preferences.Extensions, tagBannersExtension)
+		return nil
+	}
+	return writeExtension(&config.Preferences.Extensions, tagBannersExtension, banners)
+}
+
+// contextBanners returns the banners to show when entering the context called name: its own, then
+// the ones of its tags.
+func contextBanners(config *clientcmdapi.Config, name string) ([]string, error) {
+	context, ok := config.Contexts[name]
+	if !ok {
+		return nil, nil
+	}
+	banners := []string{}
+	own := contextBanner{}
+	if _, err := readExtension(context.Extensions, bannerExtension, &own); err != nil {
+		return nil, fmt.Errorf("context %q: %v", name, err)
+	}
+	if len(own.Text) > 0 {
+		banners = append(banners, own.Text)
+	}
+	metadata, err := readContextMetadata(context)
+	if err != nil {
+		return nil, fmt.Errorf("context %q: %v", name, err)
+	}
+	byTag, err := readTagBanners(config)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range metadata.Tags {
+		if text, ok := byTag[tag]; ok && !containsString(banners, text) {
+			banners = append(banners, text)
+		}
+	}
+	return banners, nil
+}
+
+// printBanners prints the banners of a context framed, so they stand out of the usual output.
+func printBanners(out io.Writer, name string, banners []string) {
+	if len(banners) == 0 {
+		return
+	}
+	lines := []string{fmt.Sprintf("context %s:", name)}
+	for _, banner := range banners {
+		lines = append(lines, strings.Split(banner, "\n")...)
+	}
+	width := 0
+	for _, line := range lines {
+		if len(line) > width {
+			width = len(line)
+		}
+	}
+	frame := strings.Repeat("!", width+4)
+	fmt.Fprintln(out, frame)
+	for _, line := range lines {
+		fmt.Fprintf(out, "! %-*s !\n", width, line)
+	}
+	fmt.Fprintln(out, frame)
+}
+
+// BannerOptions holds the command-line options for 'config banner' sub commands
+type BannerOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	// Name is a context, or a tag when Tag is set.
+	Name string
+	Tag  bool
+	Text string
+
+	genericclioptions.IOStreams
+}
+
+var (
+	bannerLong = templates.LongDesc(`
+		Show a banner when entering a context, such as a change freeze or an ongoing incident.
+
+		The banner is set for a context, or with --tag for every context having the tag.
+		"kubectl config use-context" prints the banners of the context switched to, and "kubectl
+		config exec" the banners of the context it runs kubectl against, before kubectl runs.
+
+		Banners are kept in kubeconfig extensions, so they travel with the kubeconfig when it is
+		shared.`)
+
+	bannerExample = templates.Examples(`
+		# Warn everyone entering prod of a change freeze
+		kubectl config banner set prod 'CHANGE FREEZE until Friday'
+
+		# Remind of the tag of every production context
+		kubectl config banner set --tag prod 'Production: think twice'
+
+		# Lift the change freeze
+		kubectl config banner unset prod`)
+)
+
+// NewCmdConfigBanner returns a Command instance for 'config banner' sub commands
+func NewCmdConfigBanner(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &BannerOptions{ConfigAccess: configAccess, IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "banner SUBCOMMAND",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Show a banner when entering a context"),
+		Long:                  bannerLong,
+		Example:               bannerExample,
+		Run:                   cmdutil.DefaultSubCommandRun(streams.ErrOut),
+	}
+
+	setCmd := &cobra.Command{
+		Use:   "set (CONTEXT_NAME | --tag TAG) TEXT",
+		Short: i18n.T("Set the banner of a context or tag"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 2 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.Name, o.Text = args[0], args[1]
+			cmdutil.CheckErr(o.RunSet())
+		},
+	}
+	setCmd.Flags().BoolVar(&o.Tag, "tag", o.Tag, "If true, set the banner of the contexts having the tag named instead of a context")
+	unsetCmd := &cobra.Command{
+		Use:   "unset (CONTEXT_NAME | --tag TAG)",
+		Short: i18n.T("Remove the banner of a context or tag"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.Name, o.Text = args[0], ""
+			cmdutil.CheckErr(o.RunSet())
+		},
+	}
+	unsetCmd.Flags().BoolVar(&o.Tag, "tag", o.Tag, "If true, remove the banner of the tag named instead of a context")
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: i18n.T("List the banners of contexts and tags"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			cmdutil.CheckErr(o.RunList())
+		},
+	}
+
+	cmd.AddCommand(setCmd)
+	cmd.AddCommand(unsetCmd)
+	cmd.AddCommand(noWriteCommand(listCmd))
+	return cmd
+}
+
+// RunSet sets the banner of the context or tag, or removes it when Text is empty
+func (o *BannerOptions) RunSet() error {
+	if len(o.Name) == 0 {
+		return errors.New("you must specify a non-empty context or tag name")
+	}
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+
+	target := fmt.Sprintf("tag %q", o.Name)
+	if o.Tag {
+		banners, err := readTagBanners(config)
+		if err != nil {
+			return err
+		}
+		if len(o.Text) == 0 {
+			delete(banners, o.Name)
+		} else {
+			banners[o.Name] = o.Text
+		}
+		if err := writeTagBanners(config, banners); err != nil {
+			return err
+		}
+	} else {
+		name, err := resolveContextName(config, o.Name)
+		if err != nil {
+			return err
+		}
+		context, ok := config.Contexts[name]
+		if !ok {
+			return fmt.Errorf("no context exists with the name: %q, use --tag for the contexts having a tag", name)
+		}
+		if len(o.Text) == 0 {
+			delete(context.Extensions, bannerExtension)
+		} else if err := writeExtension(&context.Extensions, bannerExtension, contextBanner{Text: o.Text}); err != nil {
+			return err
+		}
+		target = fmt.Sprintf("context %q", name)
+	}
+
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+	if len(o.Text) == 0 {
+		fmt.Fprintf(o.Out, "Banner of %s removed.\n", target)
+	} else {
+		fmt.Fprintf(o.Out, "Banner of %s set.\n", target)
+	}
+	return nil
+}
+
+// RunList lists the banners of contexts and tags
+func (o *BannerOptions) RunList() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	w := printers.GetNewTabWriter(o.Out)
+	fmt.Fprintln(w, "CONTEXT\tTAG\tBANNER")
+	for _, name := range sortedContextNames(config.Contexts) {
+		banner := contextBanner{}
+		if _, err := readExtension(config.Contexts[name].Extensions, bannerExtension, &banner); err != nil {
+			return fmt.Errorf("context %q: %v", name, err)
+		}
+		if len(banner.Text) > 0 {
+			fmt.Fprintf(w, "%s\t\t%s\n", name, banner.Text)
+		}
+	}
+	banners, err := readTagBanners(config)
+	if err != nil {
+		return err
+	}
+	for _, tag := range sets.StringKeySet(banners).List() {
+		fmt.Fprintf(w, "\t%s\t%s\n", tag, banners[tag])
+	}
+	return w.Flush()
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestBanners(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	config := newRedFederalCowHammerConfig()
+	config.Contexts["staging"] = &clientcmdapi.Context{Cluster: "cow-cluster", AuthInfo: "red-user"}
+	if err := writeContextMetadata(config.Contexts["federal-context"], contextMetadata{Tags: []string{"prod", "eu"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := clientcmd.WriteToFile(config, fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	for _, o := range []*BannerOptions{
+		{Name: "federal-context", Text: "CHANGE FREEZE until Friday"},
+		{Name: "prod", Tag: true, Text: "Production: think twice"},
+		{Name: "dev", Tag: true, Text: "Shared dev cluster"},
+		{Name: "dev", Tag: true},
+	} {
+		o.ConfigAccess, o.IOStreams = pathOptions, streams
+		if err := o.RunSet(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	expected := `Banner of context "federal-context" set.
+Banner of tag "prod" set.
+Banner of tag "dev" set.
+Banner of tag "dev" removed.
+`
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+	if err := (&BannerOptions{ConfigAccess: pathOptions, Name: "prod", Text: "x", IOStreams: streams}).RunSet(); err == nil {
+		t.Errorf("expected a missing context to be refused")
+	}
+
+	modified, err := clientcmd.LoadFromFile(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	banners, err := contextBanners(modified, "federal-context")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"CHANGE FREEZE until Friday", "Production: think twice"}; !reflect.DeepEqual(banners, expected) {
+		t.Errorf("expected %v, got %v", expected, banners)
+	}
+	if banners, err := contextBanners(modified, "staging"); err != nil || len(banners) != 0 {
+		t.Errorf("expected no banner for staging, got %v, %v", banners, err)
+	}
+
+	out.Reset()
+	printBanners(out, "federal-context", banners)
+	expected = `!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!
+! context federal-context:   !
+! CHANGE FREEZE until Friday !
+! Production: think twice    !
+!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!
+`
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+
+	var ran bool
+	errOut := &bytes.Buffer{}
+	o := &ExecOptions{
+		ConfigAccess: pathOptions,
+		Context:      "federal-context",
+		Args:         []string{"get", "pods"},
+		Kubectl:      "kubectl",
+		RunKubectl: func(kubectl string, args []string) error {
+			ran = errOut.Len() > 0
+			return nil
+		},
+		IOStreams: genericclioptions.IOStreams{Out: &bytes.Buffer{}, ErrOut: errOut},
+	}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ran || errOut.String() != expected {
+		t.Errorf("expected the banners before kubectl runs, got %q", errOut.String())
+	}
+}
@@ -0,0 +1,303 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// Media types of the OCI artifacts built by "config bundle oci". The kubeconfig layer of an
+// encrypted bundle has the media type of the kubeconfig followed by the tool, .age or .gpg.
+const (
+	bundleArtifactType   = "application/vnd.kubecfg.bundle.v1"
+	kubeconfigMediaType  = "application/vnd.kubecfg.kubeconfig.v1+yaml"
+	ociManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	ociIndexMediaType    = "application/vnd.oci.image.index.v1+json"
+	ociEmptyMediaType    = "application/vnd.oci.empty.v1+json"
+
+	orasTool = "oras"
+)
+
+// ociDescriptor points at a blob of an OCI artifact.
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int               `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// ociManifest is an OCI image manifest describing an artifact.
+type ociManifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType"`
+	Config        ociDescriptor     `json:"config"`
+	Layers        []ociDescriptor   `json:"layers"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// ociIndex is the index.json of an OCI image layout.
+type ociIndex struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	MediaType     string          `json:"mediaType"`
+	Manifests     []ociDescriptor `json:"manifests"`
+}
+
+// BundleOCIOptions holds the command-line options for 'config bundle oci' sub command
+type BundleOCIOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Context      string
+	Reference    string
+	Recipients   []string
+	Output       string
+
+	Now        func() time.Time
+	RunCommand commandRunner
+
+	genericclioptions.IOStreams
+}
+
+var (
+	bundleLong = templates.LongDesc(`
+		Package contexts for the systems that consume configuration as artifacts.`)
+
+	bundleOCILong = templates.LongDesc(`
+		Build a minimal OCI artifact holding a single context, with its cluster and user, and push
+		it to a registry with oras.
+
+		The context is exported as with "kubectl config export", so the kubeconfig in the artifact
+		works without the files it refers to here. The artifact has the type ` + bundleArtifactType + `
+		and a single layer, titled kubeconfig, of the type ` + kubeconfigMediaType + `, which CI
+		systems pull with "oras pull REFERENCE".
+
+		With --recipient, the kubeconfig is encrypted with age, or GnuPG unless every recipient is
+		an age public key, and the media type of the layer ends with .age or .gpg. oras logs in to
+		the registry with the credentials of "oras login" or of Docker.
+
+		With --output, the artifact is written to an OCI image layout directory, under the tag of
+		the reference, instead of being pushed.`)
+
+	bundleOCIExample = templates.Examples(`
+		# Push the ops context for the pipelines of the ops team
+		kubectl config bundle oci ops -t registry.example.com/ops/ctx:prod
+
+		# Push the ops context encrypted for the age key of the CI system
+		kubectl config bundle oci ops -t registry.example.com/ops/ctx:prod --recipient age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p
+
+		# Write the artifact to an OCI layout to copy it with other tools
+		kubectl config bundle oci ops -t ctx:prod --output ./ctx-layout`)
+)
+
+// NewCmdConfigBundle returns a Command instance for 'config bundle' sub command
+func NewCmdConfigBundle(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "bundle SUBCOMMAND",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Package contexts as artifacts"),
+		Long:                  bundleLong,
+		Run:                   cmdutil.DefaultSubCommandRun(streams.ErrOut),
+	}
+	cmd.AddCommand(NewCmdConfigBundleOCI(streams, configAccess))
+	return cmd
+}
+
+// NewCmdConfigBundleOCI returns a Command instance for 'config bundle oci' sub command
+func NewCmdConfigBundleOCI(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &BundleOCIOptions{
+		ConfigAccess: configAccess,
+		Now:          time.Now,
+		RunCommand:   runCommand,
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "oci CONTEXT_NAME -t REFERENCE [--recipient=RECIPIENT...] [--output=DIR]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Push a context as an OCI artifact"),
+		Long:                  bundleOCILong,
+		Example:               bundleOCIExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.Context = args[0]
+			// Only pushing the artifact reaches the registry.
+			if len(o.Output) == 0 {
+				cmdutil.CheckErr(requireNetwork(cmd))
+			}
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.Reference, "tag", "t", o.Reference, "Reference of the artifact, REGISTRY/REPOSITORY:TAG")
+	cmd.Flags().StringArrayVar(&o.Recipients, "recipient", o.Recipients, "Recipient to encrypt the kubeconfig for, an age public key or a GnuPG key ID or email")
+	cmd.Flags().StringVar(&o.Output, "output", o.Output, "If set, write the artifact to this OCI image layout directory instead of pushing it")
+	cmd.MarkFlagFilename("output")
+	return noWriteCommand(cmd, "output")
+}
+
+// Validate makes sure the reference has a tag
+func (o *BundleOCIOptions) Validate() error {
+	if len(o.Reference) == 0 {
+		return errors.New("--tag is required")
+	}
+	if strings.Contains(o.Reference, "@") {
+		return fmt.Errorf("invalid reference %q, the digest of the artifact is only known once it is built", o.Reference)
+	}
+	return nil
+}
+
+// Run builds the artifact and pushes it, or writes it to the layout
+func (o *BundleOCIOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	exported, err := exportContext(config, o.Context, false)
+	if err != nil {
+		return err
+	}
+	kubeconfig, err := clientcmd.Write(*exported)
+	if err != nil {
+		return err
+	}
+	mediaType := kubeconfigMediaType
+	if len(o.Recipients) > 0 {
+		tool := encryptionTool(o.Recipients)
+		if kubeconfig, err = encryptForRecipients(tool, o.Recipients, kubeconfig, o.RunCommand); err != nil {
+			return err
+		}
+		mediaType += "." + tool
+	}
+
+	layout := o.Output
+	if len(layout) == 0 {
+		if layout, err = ioutil.TempDir("", "kubecfg-bundle"); err != nil {
+			return err
+		}
+		defer os.RemoveAll(layout)
+	}
+	tag := referenceTag(o.Reference)
+	digest, err := writeOCILayout(layout, tag, kubeconfig, mediaType, map[string]string{
+		"org.opencontainers.image.created": o.Now().UTC().Format(time.RFC3339),
+		"io.kubecfg.context":               exported.CurrentContext,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(o.Output) > 0 {
+		fmt.Fprintf(o.Out, "Context %q written to the OCI layout %s as %s.\n", exported.CurrentContext, o.Output, tag)
+	} else {
+		if _, err := o.RunCommand(nil, orasTool, "cp", "--from-oci-layout", layout+":"+tag, o.Reference); err != nil {
+			return fmt.Errorf("%s: %v", orasTool, err)
+		}
+		fmt.Fprintf(o.Out, "Context %q pushed to %s.\n", exported.CurrentContext, o.Reference)
+	}
+	fmt.Fprintf(o.Out, "Digest: %s\n", digest)
+	return nil
+}
+
+// referenceTag returns the tag of an image reference, latest when it has none.
+func referenceTag(reference string) string {
+	name := reference[strings.LastIndex(reference, "/")+1:]
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		return name[i+1:]
+	}
+	return "latest"
+}
+
+// writeOCILayout writes an artifact with a single layer to the OCI image layout in dir, tagged tag,
+// and returns the digest of its manifest. The blobs and tags the layout has already are kept.
+func writeOCILayout(dir, tag string, layer []byte, mediaType string, annotations map[string]string) (string, error) {
+	writeBlob := func(data []byte, mediaType string) (ociDescriptor, error) {
+		sum := sha256.Sum256(data)
+		descriptor := ociDescriptor{MediaType: mediaType, Digest: "sha256:" + hex.EncodeToString(sum[:]), Size: len(data)}
+		blobs := filepath.Join(dir, "blobs", "sha256")
+		// The kubeconfig holds credentials, unless it is encrypted.
+		if err := os.MkdirAll(blobs, 0700); err != nil {
+			return descriptor, err
+		}
+		return descriptor, ioutil.WriteFile(filepath.Join(blobs, hex.EncodeToString(sum[:])), data, 0600)
+	}
+
+	manifest := ociManifest{SchemaVersion: 2, MediaType: ociManifestMediaType, ArtifactType: bundleArtifactType, Annotations: annotations}
+	var err error
+	if manifest.Config, err = writeBlob([]byte("{}"), ociEmptyMediaType); err != nil {
+		return "", err
+	}
+	layerDescriptor, err := writeBlob(layer, mediaType)
+	if err != nil {
+		return "", err
+	}
+	layerDescriptor.Annotations = map[string]string{"org.opencontainers.image.title": "kubeconfig"}
+	manifest.Layers = []ociDescriptor{layerDescriptor}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return "", err
+	}
+	manifestDescriptor, err := writeBlob(data, ociManifestMediaType)
+	if err != nil {
+		return "", err
+	}
+	manifestDescriptor.Annotations = map[string]string{"org.opencontainers.image.ref.name": tag}
+
+	index := ociIndex{SchemaVersion: 2, MediaType: ociIndexMediaType}
+	if data, err := ioutil.ReadFile(filepath.Join(dir, "index.json")); err == nil {
+		if err := json.Unmarshal(data, &index); err != nil {
+			return "", fmt.Errorf("invalid OCI layout %s: %v", dir, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+	manifests := []ociDescriptor{}
+	for _, existing := range index.Manifests {
+		if existing.Annotations["org.opencontainers.image.ref.name"] != tag {
+			manifests = append(manifests, existing)
+		}
+	}
+	index.Manifests = append(manifests, manifestDescriptor)
+	if data, err = json.MarshalIndent(index, "", "  "); err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "index.json"), data, 0644); err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "oci-layout"), []byte(`{"imageLayoutVersion":"1.0.0"}`), 0644); err != nil {
+		return "", err
+	}
+	return manifestDescriptor.Digest, nil
+}
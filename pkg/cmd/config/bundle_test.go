@@ -0,0 +1,156 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// readOCILayout returns the manifest tagged tag in the OCI layout in dir, and its only layer.
+func readOCILayout(t *testing.T, dir, tag string) (*ociManifest, []byte) {
+	readBlob := func(digest string) []byte {
+		data, err := ioutil.ReadFile(filepath.Join(dir, "blobs", "sha256", strings.TrimPrefix(digest, "sha256:")))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return data
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "index.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	index := &ociIndex{}
+	if err := json.Unmarshal(data, index); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, descriptor := range index.Manifests {
+		if descriptor.Annotations["org.opencontainers.image.ref.name"] != tag {
+			continue
+		}
+		manifest := &ociManifest{}
+		if err := json.Unmarshal(readBlob(descriptor.Digest), manifest); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(manifest.Layers) != 1 {
+			t.Fatalf("expected a single layer, got %+v", manifest.Layers)
+		}
+		if config := string(readBlob(manifest.Config.Digest)); config != "{}" {
+			t.Errorf("expected an empty config, got %q", config)
+		}
+		return manifest, readBlob(manifest.Layers[0].Digest)
+	}
+	t.Fatalf("no manifest tagged %s in %s", tag, data)
+	return nil, nil
+}
+
+func TestBundleOCI(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	if err := clientcmd.WriteToFile(newRedFederalCowHammerConfig(), fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	layout, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(layout)
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := &BundleOCIOptions{
+		ConfigAccess: pathOptions,
+		Context:      "federal-context",
+		Reference:    "localhost:5000/ops/ctx:prod",
+		Output:       layout,
+		Now:          func() time.Time { return time.Date(2019, 8, 1, 12, 0, 0, 0, time.UTC) },
+		IOStreams:    streams,
+	}
+	if err := o.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "Context \"federal-context\" written to the OCI layout "+layout+" as prod.\nDigest: sha256:") {
+		t.Errorf("unexpected output %q", out.String())
+	}
+	manifest, layer := readOCILayout(t, layout, "prod")
+	if manifest.ArtifactType != bundleArtifactType || manifest.Layers[0].MediaType != kubeconfigMediaType || manifest.Annotations["io.kubecfg.context"] != "federal-context" || manifest.Annotations["org.opencontainers.image.created"] != "2019-08-01T12:00:00Z" {
+		t.Errorf("unexpected manifest %+v", manifest)
+	}
+	kubeconfig, err := clientcmd.Load(layer)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if kubeconfig.CurrentContext != "federal-context" || kubeconfig.AuthInfos["red-user"].Token != "red-token" {
+		t.Errorf("expected the exported context, got\n%s", layer)
+	}
+
+	// Pushing goes through a temporary layout, encrypted for the recipient.
+	pushed := false
+	o.Output = ""
+	o.Recipients = []string{"age1prod"}
+	o.RunCommand = func(env []string, name string, args ...string) ([]byte, error) {
+		if name == "age" {
+			return fakeAge("")(env, name, args...)
+		}
+		if name != orasTool || len(args) != 4 || strings.Join(args[:2], " ") != "cp --from-oci-layout" || args[3] != "localhost:5000/ops/ctx:prod" || !strings.HasSuffix(args[2], ":prod") {
+			return nil, fmt.Errorf("unexpected command %s %v", name, args)
+		}
+		manifest, layer := readOCILayout(t, strings.TrimSuffix(args[2], ":prod"), "prod")
+		if manifest.Layers[0].MediaType != kubeconfigMediaType+".age" || !strings.HasPrefix(string(layer), "-----BEGIN AGE ENCRYPTED FILE-----") {
+			t.Errorf("expected an encrypted layer, got %+v\n%s", manifest, layer)
+		}
+		pushed = true
+		return nil, nil
+	}
+	out.Reset()
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !pushed || !strings.HasPrefix(out.String(), "Context \"federal-context\" pushed to localhost:5000/ops/ctx:prod.\n") {
+		t.Errorf("expected the artifact to be pushed, got %q", out.String())
+	}
+}
+
+func TestReferenceTag(t *testing.T) {
+	for reference, expected := range map[string]string{
+		"registry.example.com/ops/ctx:prod": "prod",
+		"localhost:5000/ops/ctx":            "latest",
+		"ctx":                               "latest",
+	} {
+		if tag := referenceTag(reference); tag != expected {
+			t.Errorf("%s: expected %q, got %q", reference, expected, tag)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/util/homedir"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/printers"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// credentialCache is a file or directory where a credential plugin keeps tokens between runs.
+type credentialCache struct {
+	Provider string
+	Name     string
+	Path     string
+}
+
+// cacheProviders are the values of --provider, in the order caches are listed.
+var cacheProviders = []string{"aws", "gcp", "azure", "oidc", "kubecfg"}
+
+// credentialCaches returns the caches of the credential plugins commonly used by kubeconfig users.
+func credentialCaches(home, state string) []credentialCache {
+	return []credentialCache{
+		{Provider: "aws", Name: "aws CLI assumed role credentials", Path: filepath.Join(home, ".aws", "cli", "cache")},
+		{Provider: "aws", Name: "aws CLI SSO tokens", Path: filepath.Join(home, ".aws", "sso", "cache")},
+		{Provider: "gcp", Name: "gke-gcloud-auth-plugin", Path: filepath.Join(home, ".kube", "gke_gcloud_auth_plugin_cache")},
+		{Provider: "azure", Name: "Azure kubelogin", Path: filepath.Join(home, ".kube", "cache", "kubelogin")},
+		{Provider: "oidc", Name: "kubelogin (oidc-login)", Path: filepath.Join(home, ".kube", "cache", "oidc-login")},
+		{Provider: "kubecfg", Name: "config refresh credentials", Path: filepath.Join(state, "credentials")},
+		{Provider: "kubecfg", Name: "config context-info", Path: filepath.Join(state, "context-info.json")},
+		{Provider: "kubecfg", Name: "config get-contexts health", Path: filepath.Join(state, "health-cache.json")},
+		{Provider: "kubecfg", Name: "config ns namespaces", Path: filepath.Join(state, "namespace-cache.json")},
+	}
+}
+
+// cacheUsage describes what a cache holds.
+type cacheUsage struct {
+	Entries  int
+	Modified time.Time
+}
+
+// CacheOptions holds the command-line options for 'config cache' sub command
+type CacheOptions struct {
+	Provider string
+	HomeDir  string
+	StateDir string
+
+	genericclioptions.IOStreams
+}
+
+var (
+	cacheLong = templates.LongDesc(`
+		List or clear the token caches of credential plugins.
+
+		client-go only keeps the credentials returned by exec plugins in memory, but the plugins
+		themselves cache tokens on disk, and a stale cached token is a frequent cause of confusing
+		authentication failures: the aws CLI caches assumed role credentials and SSO tokens, the
+		gke-gcloud-auth-plugin, kubelogin for OIDC and Azure's kubelogin their tokens. The credentials
+		kept by "kubectl config refresh", the answers cached by "kubectl config context-info" and the
+		health checks cached by "kubectl config get-contexts -o wide" are cleared with --provider
+		kubecfg.
+
+		Clearing a cache makes the plugin fetch new tokens the next time it runs, which may mean
+		logging in again, for instance with "aws sso login".`)
+
+	cacheExample = templates.Examples(`
+		# List the credential caches and how many entries they hold
+		kubectl config cache list
+
+		# Clear the cached OIDC tokens
+		kubectl config cache clear --provider oidc`)
+)
+
+// NewCmdConfigCache returns a Command instance for 'config cache' sub command
+func NewCmdConfigCache(streams genericclioptions.IOStreams) *cobra.Command {
+	o := &CacheOptions{IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "cache SUBCOMMAND",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("List or clear the token caches of credential plugins"),
+		Long:                  cacheLong,
+		Example:               cacheExample,
+		Run:                   cmdutil.DefaultSubCommandRun(streams.ErrOut),
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list [--provider=" + strings.Join(cacheProviders, "|") + "]",
+		Short: i18n.T("List the token caches of credential plugins"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			cmdutil.CheckErr(o.Complete())
+			cmdutil.CheckErr(o.RunList())
+		},
+	}
+	clearCmd := &cobra.Command{
+		Use:   "clear [--provider=" + strings.Join(cacheProviders, "|") + "]",
+		Short: i18n.T("Remove the tokens cached by credential plugins"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			cmdutil.CheckErr(o.Complete())
+			cmdutil.CheckErr(o.RunClear())
+		},
+	}
+	for _, sub := range []*cobra.Command{noWriteCommand(listCmd), clearCmd} {
+		sub.Flags().StringVar(&o.Provider, "provider", o.Provider, "Only the caches of this provider. One of: "+strings.Join(cacheProviders, "|"))
+		cmd.AddCommand(sub)
+	}
+	return cmd
+}
+
+// Complete fills in the directories caches are looked for in and validates the provider
+func (o *CacheOptions) Complete() error {
+	if len(o.HomeDir) == 0 {
+		o.HomeDir = homedir.HomeDir()
+	}
+	if len(o.StateDir) == 0 {
+		o.StateDir = stateDir()
+	}
+	if len(o.Provider) > 0 && !containsString(cacheProviders, o.Provider) {
+		return fmt.Errorf("unknown provider %q, must be one of: %s", o.Provider, strings.Join(cacheProviders, ", "))
+	}
+	return nil
+}
+
+// caches returns the caches of the selected provider.
+func (o *CacheOptions) caches() []credentialCache {
+	caches := []credentialCache{}
+	for _, cache := range credentialCaches(o.HomeDir, o.StateDir) {
+		if len(o.Provider) == 0 || cache.Provider == o.Provider {
+			caches = append(caches, cache)
+		}
+	}
+	return caches
+}
+
+// RunList prints the caches that exist, with the number of entries they hold
+func (o *CacheOptions) RunList() error {
+	w := printers.GetNewTabWriter(o.Out)
+	found := false
+	for _, cache := range o.caches() {
+		usage, err := readCacheUsage(cache.Path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !found {
+			fmt.Fprintf(w, "PROVIDER\tCACHE\tENTRIES\tMODIFIED\tPATH\n")
+			found = true
+		}
+		modified := "<none>"
+		if !usage.Modified.IsZero() {
+			modified = duration.HumanDuration(time.Since(usage.Modified)) + " ago"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", cache.Provider, cache.Name, usage.Entries, modified, cache.Path)
+	}
+	if !found {
+		fmt.Fprintln(o.Out, "No credential caches found.")
+		return nil
+	}
+	return w.Flush()
+}
+
+// RunClear removes the entries of the caches that exist
+func (o *CacheOptions) RunClear() error {
+	cleared := 0
+	for _, cache := range o.caches() {
+		entries, err := clearCache(cache.Path)
+		if err != nil {
+			return fmt.Errorf("clearing the %s cache: %v", cache.Name, err)
+		}
+		if entries > 0 {
+			fmt.Fprintf(o.Out, "Removed %d file(s) from the %s cache.\n", entries, cache.Name)
+			cleared++
+		}
+	}
+	if cleared == 0 {
+		fmt.Fprintln(o.Out, "No cached credentials to clear.")
+	}
+	return nil
+}
+
+// readCacheUsage counts the files of a cache directory, or the cache file itself, and finds when
+// the cache was last written to.
+func readCacheUsage(path string) (cacheUsage, error) {
+	usage := cacheUsage{}
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			usage.Entries++
+			if info.ModTime().After(usage.Modified) {
+				usage.Modified = info.ModTime()
+			}
+		}
+		return nil
+	})
+	return usage, err
+}
+
+// clearCache removes a cache file, or what a cache directory contains while keeping the directory
+// with its permissions, and returns the number of files removed.
+func clearCache(path string) (int, error) {
+	usage, err := readCacheUsage(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	if !info.IsDir() {
+		return usage.Entries, os.Remove(path)
+	}
+	children, err := ioutil.ReadDir(path)
+	if err != nil {
+		return 0, err
+	}
+	for _, child := range children {
+		if err := os.RemoveAll(filepath.Join(path, child.Name())); err != nil {
+			return 0, err
+		}
+	}
+	return usage.Entries, nil
+}
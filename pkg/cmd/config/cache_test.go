@@ -0,0 +1,112 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestCredentialCaches(t *testing.T) {
+	home, err := ioutil.TempDir("", "home")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(home)
+	state := filepath.Join(home, "state")
+	for _, file := range []string{
+		".aws/sso/cache/a.json",
+		".aws/sso/cache/b.json",
+		".kube/cache/oidc-login/token",
+		".kube/gke_gcloud_auth_plugin_cache",
+		"state/context-info.json",
+	} {
+		path := filepath.Join(home, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := ioutil.WriteFile(path, []byte("{}"), 0600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	run := func(provider string, clear bool) string {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		o := &CacheOptions{Provider: provider, HomeDir: home, StateDir: state, IOStreams: streams}
+		if err := o.Complete(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if clear {
+			err = o.RunClear()
+		} else {
+			err = o.RunList()
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return out.String()
+	}
+
+	listed := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(run("", false)), "\n")[1:] {
+		fields := strings.Fields(line)
+		listed[fields[len(fields)-1]] = fields[len(fields)-4]
+	}
+	expected := map[string]string{
+		filepath.Join(home, ".aws", "sso", "cache"):                  "2",
+		filepath.Join(home, ".kube", "cache", "oidc-login"):          "1",
+		filepath.Join(home, ".kube", "gke_gcloud_auth_plugin_cache"): "1",
+		filepath.Join(state, "context-info.json"):                    "1",
+	}
+	if len(listed) != len(expected) {
+		t.Errorf("expected the existing caches to be listed, got %v", listed)
+	}
+	for path, entries := range expected {
+		if listed[path] != entries {
+			t.Errorf("expected %s entries in %s, got %q", entries, path, listed[path])
+		}
+	}
+
+	if out := run("aws", true); out != "Removed 2 file(s) from the aws CLI SSO tokens cache.\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".aws", "sso", "cache")); err != nil {
+		t.Errorf("expected the cache directory to be kept: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".kube", "cache", "oidc-login", "token")); err != nil {
+		t.Errorf("expected the caches of other providers to be kept: %v", err)
+	}
+	if out := run("aws", true); out != "No cached credentials to clear.\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+	if out := run("gcp", true); out != "Removed 1 file(s) from the gke-gcloud-auth-plugin cache.\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".kube", "gke_gcloud_auth_plugin_cache")); !os.IsNotExist(err) {
+		t.Errorf("expected the cache file to be removed, got %v", err)
+	}
+
+	o := &CacheOptions{Provider: "ibm"}
+	if err := o.Complete(); err == nil {
+		t.Errorf("expected an unknown provider to be rejected")
+	}
+}
@@ -0,0 +1,235 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// clientTuning is stored in a context's clientTuningExtension. Zero values keep the defaults of
+// the client.
+type clientTuning struct {
+	Timeout string  `json:"timeout,omitempty"`
+	QPS     float32 `json:"qps,omitempty"`
+	Burst   int     `json:"burst,omitempty"`
+}
+
+// String returns the settings of t as "timeout=30s qps=20 burst=40", leaving out the defaults.
+func (t clientTuning) String() string {
+	settings := []string{}
+	if len(t.Timeout) > 0 {
+		settings = append(settings, "timeout="+t.Timeout)
+	}
+	if t.QPS > 0 {
+		settings = append(settings, "qps="+strconv.FormatFloat(float64(t.QPS), 'g', -1, 32))
+	}
+	if t.Burst > 0 {
+		settings = append(settings, "burst="+strconv.Itoa(t.Burst))
+	}
+	if len(settings) == 0 {
+		return "<none>"
+	}
+	return strings.Join(settings, " ")
+}
+
+// ClientTuningOptions holds the command-line options for 'config tuning' sub command
+type ClientTuningOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Context      string
+	Timeout      time.Duration
+	QPS          float32
+	Burst        int
+	Clear        bool
+
+	timeoutSet, qpsSet, burstSet bool
+
+	genericclioptions.IOStreams
+}
+
+var (
+	clientTuningLong = templates.LongDesc(`
+		Show or set the request timeout, QPS and burst of the clients of a context.
+
+		A distant or busy cluster may need a longer timeout, and a large one more requests per second
+		than the defaults allow. The settings are honored by the clients this command makes, such as
+		the checks of "kubectl config health --serve", and "kubectl config exec" passes the timeout to
+		kubectl as --request-timeout, unless its command line or the flags of the context set one.
+		kubectl has no flags for QPS and burst. A setting of 0 goes back to the default.
+
+		Without flags, the settings of the context are printed. They are also shown by
+		"kubectl config context-info", and with the defaults filled in by "kubectl config effective".`)
+
+	clientTuningExample = templates.Examples(`
+		# Give the clients of prod a 2 minute timeout and 50 requests per second, bursting to 100
+		kubectl config tuning prod --timeout 2m --qps 50 --burst 100
+
+		# Show the settings of the current-context
+		kubectl config tuning .
+
+		# Go back to the defaults for prod
+		kubectl config tuning prod --clear`)
+)
+
+// NewCmdConfigTuning returns a Command instance for 'config tuning' sub command
+func NewCmdConfigTuning(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &ClientTuningOptions{ConfigAccess: configAccess, IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "tuning CONTEXT_NAME [--timeout=DURATION] [--qps=QPS] [--burst=BURST] [--clear]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Show or set the timeout, QPS and burst of the clients of a context"),
+		Long:                  clientTuningLong,
+		Example:               clientTuningExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	cmd.Flags().DurationVar(&o.Timeout, "timeout", o.Timeout, "Timeout of a request, such as 30s or 2m")
+	cmd.Flags().Float32Var(&o.QPS, "qps", o.QPS, "Requests per second a client sends at most")
+	cmd.Flags().IntVar(&o.Burst, "burst", o.Burst, "Requests a client may send at once over the QPS")
+	cmd.Flags().BoolVar(&o.Clear, "clear", o.Clear, "Remove the settings of the context")
+	return cmd
+}
+
+// Complete sets the context from the arguments and records which settings are changed
+func (o *ClientTuningOptions) Complete(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return helpErrorf(cmd, "Unexpected args: %v", args)
+	}
+	o.Context = args[0]
+	o.timeoutSet = cmd.Flags().Changed("timeout")
+	o.qpsSet = cmd.Flags().Changed("qps")
+	o.burstSet = cmd.Flags().Changed("burst")
+	return nil
+}
+
+// Validate makes sure the settings are not negative
+func (o *ClientTuningOptions) Validate() error {
+	if o.Clear && (o.timeoutSet || o.qpsSet || o.burstSet) {
+		return errors.New("--clear cannot be combined with settings")
+	}
+	if o.Timeout < 0 || o.QPS < 0 || o.Burst < 0 {
+		return errors.New("--timeout, --qps and --burst cannot be negative")
+	}
+	return nil
+}
+
+// Run prints or changes the settings of the context
+func (o *ClientTuningOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	name, err := resolveContextName(config, o.Context)
+	if err != nil {
+		return err
+	}
+	context, ok := config.Contexts[name]
+	if !ok {
+		return fmt.Errorf("no context exists with the name: %q", name)
+	}
+	tuning, err := readClientTuning(context)
+	if err != nil {
+		return err
+	}
+
+	if !o.Clear && !o.timeoutSet && !o.qpsSet && !o.burstSet {
+		fmt.Fprintln(o.Out, tuning)
+		return nil
+	}
+
+	switch {
+	case o.Clear:
+		tuning = clientTuning{}
+	default:
+		if o.timeoutSet {
+			tuning.Timeout = ""
+			if o.Timeout > 0 {
+				tuning.Timeout = o.Timeout.String()
+			}
+		}
+		if o.qpsSet {
+			tuning.QPS = o.QPS
+		}
+		if o.burstSet {
+			tuning.Burst = o.Burst
+		}
+	}
+	if err := writeClientTuning(context, tuning); err != nil {
+		return err
+	}
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "Client tuning of context %q set to %s.\n", name, tuning)
+	return nil
+}
+
+// readClientTuning returns the client tuning of a context.
+func readClientTuning(context *clientcmdapi.Context) (clientTuning, error) {
+	tuning := clientTuning{}
+	_, err := readExtension(context.Extensions, clientTuningExtension, &tuning)
+	return tuning, err
+}
+
+// writeClientTuning stores the client tuning of a context, removing the extension when it keeps
+// every default.
+func writeClientTuning(context *clientcmdapi.Context, tuning clientTuning) error {
+	if tuning == (clientTuning{}) {
+		delete(context.Extensions, clientTuningExtension)
+		return nil
+	}
+	return writeExtension(&context.Extensions, clientTuningExtension, tuning)
+}
+
+// applyClientTuning sets the timeout, QPS and burst of restConfig that the context tunes.
+func applyClientTuning(restConfig *rest.Config, context *clientcmdapi.Context) error {
+	tuning, err := readClientTuning(context)
+	if err != nil {
+		return err
+	}
+	if len(tuning.Timeout) > 0 {
+		timeout, err := time.ParseDuration(tuning.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid client timeout: %v", err)
+		}
+		restConfig.Timeout = timeout
+	}
+	if tuning.QPS > 0 {
+		restConfig.QPS = tuning.QPS
+	}
+	if tuning.Burst > 0 {
+		restConfig.Burst = tuning.Burst
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func TestClientTuning(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	if err := clientcmd.WriteToFile(newRedFederalCowHammerConfig(), fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	run := func(args ...string) (string, error) {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		o := &ClientTuningOptions{ConfigAccess: pathOptions, IOStreams: streams}
+		cmd := NewCmdConfigTuning(streams, pathOptions)
+		if err := cmd.ParseFlags(args); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// The command binds its flags to options of its own.
+		o.Timeout, _ = cmd.Flags().GetDuration("timeout")
+		o.QPS, _ = cmd.Flags().GetFloat32("qps")
+		o.Burst, _ = cmd.Flags().GetInt("burst")
+		o.Clear, _ = cmd.Flags().GetBool("clear")
+		err := o.Complete(cmd, cmd.Flags().Args())
+		if err == nil {
+			err = o.Validate()
+		}
+		if err == nil {
+			err = o.Run()
+		}
+		return out.String(), err
+	}
+
+	if out, err := run("federal-context"); err != nil || out != "<none>\n" {
+		t.Errorf("unexpected output %q, %v", out, err)
+	}
+	if out, err := run(".", "--timeout", "2m", "--qps", "50", "--burst", "100"); err != nil || out != "Client tuning of context \"federal-context\" set to timeout=2m0s qps=50 burst=100.\n" {
+		t.Errorf("unexpected output %q, %v", out, err)
+	}
+	if out, err := run("federal-context", "--qps", "0"); err != nil || out != "Client tuning of context \"federal-context\" set to timeout=2m0s burst=100.\n" {
+		t.Errorf("unexpected output %q, %v", out, err)
+	}
+
+	config, err := clientcmd.LoadFromFile(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	restConfig := &rest.Config{Timeout: 10 * time.Second, QPS: 5}
+	if err := applyClientTuning(restConfig, config.Contexts["federal-context"]); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if restConfig.Timeout != 2*time.Minute || restConfig.QPS != 5 || restConfig.Burst != 100 {
+		t.Errorf("unexpected tuned config %+v", restConfig)
+	}
+	info, err := describeContext(config, "federal-context")
+	if err != nil || info.Tuning == nil || info.Tuning.String() != "timeout=2m0s burst=100" {
+		t.Errorf("expected context-info to show the tuning, got %v, %v", info.Tuning, err)
+	}
+
+	if _, err := run("federal-context", "--clear", "--qps", "5"); err == nil {
+		t.Errorf("expected --clear with settings to fail")
+	}
+	if _, err := run("federal-context", "--burst", "-1"); err == nil {
+		t.Errorf("expected a negative burst to fail")
+	}
+	if out, err := run("federal-context", "--clear"); err != nil || out != "Client tuning of context \"federal-context\" set to <none>.\n" {
+		t.Errorf("unexpected output %q, %v", out, err)
+	}
+	if config, err = clientcmd.LoadFromFile(fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := config.Contexts["federal-context"].Extensions[clientTuningExtension]; ok {
+		t.Errorf("expected the extension to be removed")
+	}
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// clipboardCommands returns the commands copying their standard input to the clipboard, and
+// printing the clipboard, on goos. Linux desktops are told apart by the variables of their session,
+// and xsel stands in for xclip when only it is installed.
+func clipboardCommands(goos string, getenv func(string) string, lookPath func(string) (string, error)) (copyArgs, pasteArgs []string, err error) {
+	switch goos {
+	case "darwin":
+		return []string{"pbcopy"}, []string{"pbpaste"}, nil
+	case "windows":
+		return []string{"clip"}, []string{"powershell", "-NoProfile", "-Command", "Get-Clipboard -Raw"}, nil
+	}
+	if len(getenv("WAYLAND_DISPLAY")) > 0 {
+		return []string{"wl-copy"}, []string{"wl-paste", "--no-newline"}, nil
+	}
+	if len(getenv("DISPLAY")) > 0 {
+		if _, err := lookPath("xclip"); err != nil {
+			if _, err := lookPath("xsel"); err == nil {
+				return []string{"xsel", "--clipboard", "--input"}, []string{"xsel", "--clipboard", "--output"}, nil
+			}
+		}
+		return []string{"xclip", "-selection", "clipboard", "-in"}, []string{"xclip", "-selection", "clipboard", "-out"}, nil
+	}
+	return nil, nil, errors.New("no clipboard found, neither WAYLAND_DISPLAY nor DISPLAY is set")
+}
+
+// copyToClipboard puts data on the clipboard of the desktop.
+func copyToClipboard(data []byte) error {
+	copyArgs, _, err := clipboardCommands(runtime.GOOS, os.Getenv, exec.LookPath)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(copyArgs[0], copyArgs[1:]...)
+	cmd.Stdin = bytes.NewReader(data)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if message := strings.TrimSpace(string(out)); len(message) > 0 {
+			return fmt.Errorf("%s: %v: %s", copyArgs[0], err, message)
+		}
+		return fmt.Errorf("%s: %v", copyArgs[0], err)
+	}
+	return nil
+}
+
+// pasteFromClipboard returns the contents of the clipboard of the desktop.
+func pasteFromClipboard() ([]byte, error) {
+	_, pasteArgs, err := clipboardCommands(runtime.GOOS, os.Getenv, exec.LookPath)
+	if err != nil {
+		return nil, err
+	}
+	data, err := runCommand(nil, pasteArgs[0], pasteArgs[1:]...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", pasteArgs[0], err)
+	}
+	return data, nil
+}
+
+// usersWithCredentials returns the sorted names of the users of config holding secrets.
+func usersWithCredentials(config *clientcmdapi.Config) []string {
+	names := []string{}
+	for _, name := range sortedAuthInfoNames(config.AuthInfos) {
+		authInfo := config.AuthInfos[name]
+		if !reflect.DeepEqual(sanitizeAuthInfo(authInfo), authInfo) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// usersWithRedactedCredentials returns the sorted names of the users of config whose secrets were
+// replaced by REDACTED, such as in the output of "config export --sanitized".
+func usersWithRedactedCredentials(config *clientcmdapi.Config) []string {
+	names := []string{}
+	for _, name := range sortedAuthInfoNames(config.AuthInfos) {
+		authInfo := config.AuthInfos[name]
+		redacted := authInfo.Token == "REDACTED" || authInfo.Password == "REDACTED" || authInfo.TokenFile == "REDACTED" || string(authInfo.ClientKeyData) == "REDACTED"
+		if authInfo.AuthProvider != nil {
+			for _, value := range authInfo.AuthProvider.Config {
+				redacted = redacted || value == "REDACTED"
+			}
+		}
+		if authInfo.Exec != nil {
+			for _, env := range authInfo.Exec.Env {
+				redacted = redacted || env.Value == "REDACTED"
+			}
+		}
+		if redacted {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// runClipboard merges the kubeconfig on the clipboard.
+func (o *ImportOptions) runClipboard() error {
+	log := o.log
+	if log == nil {
+		log, _ = newCmdLogger(nil, o.ErrOut)
+	}
+	data, err := o.PasteFromClipboard()
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return errors.New("the clipboard is empty")
+	}
+	from, err := clientcmd.Load(data)
+	if err != nil {
+		return fmt.Errorf("the clipboard does not hold a kubeconfig: %v", err)
+	}
+	if len(from.Clusters)+len(from.AuthInfos)+len(from.Contexts) == 0 {
+		return errors.New("the clipboard does not hold a kubeconfig")
+	}
+	for _, name := range usersWithRedactedCredentials(from) {
+		log.Warningf("clipboard: user %q has redacted credentials, set them with \"kubectl config set-credentials\"", name)
+	}
+	if err := stampProvenance(from, "clipboard", "", time.Now()); err != nil {
+		return err
+	}
+	named, err := nameImportedContexts(o.naming, from)
+	if err != nil {
+		return fmt.Errorf("clipboard: %v", err)
+	}
+	for _, rename := range named {
+		log.Infof(0, "clipboard: named %s", rename)
+	}
+
+	resolve := o.resolve
+	if resolve == nil {
+		if resolve, err = newConflictStrategy(o.OnConflict, o.IOStreams); err != nil {
+			return err
+		}
+	}
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	resolve = confirmReplacements(resolve, config, o.Confirm)
+	if !o.AllowDuplicateServer {
+		for _, reused := range reuseDuplicateServers(config, from) {
+			log.Infof(0, "clipboard: reused %s", reused)
+		}
+	}
+	merged, err := mergeConfigWithStrategy(config, from, "clipboard", resolve)
+	if err != nil {
+		return err
+	}
+	for _, reason := range merged.Skipped {
+		log.Warningf("clipboard: skipped %s", reason)
+	}
+	for _, renamed := range merged.Renamed {
+		log.Infof(0, "clipboard: imported %s", renamed)
+	}
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "Imported %d entries from the clipboard.\n", merged.Added)
+	return nil
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestClipboardCommands(t *testing.T) {
+	tests := map[string]struct {
+		goos          string
+		env           map[string]string
+		installed     []string
+		expectedCopy  string
+		expectedPaste string
+		expectedErr   bool
+	}{
+		"macOS": {
+			goos:          "darwin",
+			expectedCopy:  "pbcopy",
+			expectedPaste: "pbpaste",
+		},
+		"Windows": {
+			goos:          "windows",
+			expectedCopy:  "clip",
+			expectedPaste: "powershell -NoProfile -Command Get-Clipboard -Raw",
+		},
+		"Wayland": {
+			goos:          "linux",
+			env:           map[string]string{"WAYLAND_DISPLAY": "wayland-0", "DISPLAY": ":0"},
+			expectedCopy:  "wl-copy",
+			expectedPaste: "wl-paste --no-newline",
+		},
+		"X11": {
+			goos:          "linux",
+			env:           map[string]string{"DISPLAY": ":0"},
+			installed:     []string{"xclip", "xsel"},
+			expectedCopy:  "xclip -selection clipboard -in",
+			expectedPaste: "xclip -selection clipboard -out",
+		},
+		"X11 with xsel": {
+			goos:          "linux",
+			env:           map[string]string{"DISPLAY": ":0"},
+			installed:     []string{"xsel"},
+			expectedCopy:  "xsel --clipboard --input",
+			expectedPaste: "xsel --clipboard --output",
+		},
+		"headless": {
+			goos:        "linux",
+			expectedErr: true,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			lookPath := func(file string) (string, error) {
+				for _, installed := range test.installed {
+					if installed == file {
+						return "/usr/bin/" + file, nil
+					}
+				}
+				return "", errors.New("not found")
+			}
+			copyArgs, pasteArgs, err := clipboardCommands(test.goos, func(name string) string { return test.env[name] }, lookPath)
+			if test.expectedErr {
+				if err == nil {
+					t.Errorf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strings.Join(copyArgs, " ") != test.expectedCopy || strings.Join(pasteArgs, " ") != test.expectedPaste {
+				t.Errorf("expected %q and %q, got %v and %v", test.expectedCopy, test.expectedPaste, copyArgs, pasteArgs)
+			}
+		})
+	}
+}
+
+func TestExportImportClipboard(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	if err := clientcmd.WriteToFile(newRedFederalCowHammerConfig(), fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	clipboard := []byte{}
+	export := func(sanitized bool) (string, string) {
+		streams, _, out, errOut := genericclioptions.NewTestIOStreams()
+		o := &ExportOptions{
+			ConfigAccess: pathOptions,
+			Context:      "federal-context",
+			Sanitized:    sanitized,
+			Clipboard:    true,
+			CopyToClipboard: func(data []byte) error {
+				clipboard = data
+				return nil
+			},
+			IOStreams: streams,
+		}
+		if err := o.Run(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return out.String(), errOut.String()
+	}
+
+	out, errOut := export(false)
+	if out != "Context \"federal-context\" copied to the clipboard.\n" || !strings.Contains(errOut, `credentials of user "red-user"`) {
+		t.Errorf("expected a warning about the credentials of red-user, got %q and %q", out, errOut)
+	}
+	if !strings.Contains(string(clipboard), "red-token") {
+		t.Errorf("expected the clipboard to hold the kubeconfig, got\n%s", clipboard)
+	}
+	if _, errOut = export(true); len(errOut) > 0 || strings.Contains(string(clipboard), "red-token") {
+		t.Errorf("expected a sanitized kubeconfig without warnings, got %q and\n%s", errOut, clipboard)
+	}
+
+	importFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(importFile.Name())
+	if err := clientcmd.WriteToFile(*clientcmdapi.NewConfig(), importFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	importOptions := clientcmd.NewDefaultPathOptions()
+	importOptions.GlobalFile = importFile.Name()
+	importOptions.EnvVar = ""
+
+	streams, _, importOut, importErrOut := genericclioptions.NewTestIOStreams()
+	o := &ImportOptions{
+		ConfigAccess:       importOptions,
+		BatchSize:          defaultImportBatchSize,
+		Clipboard:          true,
+		PasteFromClipboard: func() ([]byte, error) { return clipboard, nil },
+		IOStreams:          streams,
+	}
+	if err := o.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if importOut.String() != "Imported 3 entries from the clipboard.\n" || !strings.Contains(importErrOut.String(), `user "red-user" has redacted credentials`) {
+		t.Errorf("unexpected output %q and %q", importOut.String(), importErrOut.String())
+	}
+	imported, err := clientcmd.LoadFromFile(importFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if names := sortedContextNames(imported.Contexts); !reflect.DeepEqual(names, []string{"federal-context"}) {
+		t.Errorf("expected federal-context to be imported, got %v", names)
+	}
+
+	o.PasteFromClipboard = func() ([]byte, error) { return []byte("just some text\n"), nil }
+	if err := o.Run(); err == nil || !strings.Contains(err.Error(), "does not hold a kubeconfig") {
+		t.Errorf("expected text to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io"
+	"os"
+
+	"k8s.io/kubectl/pkg/util/term"
+)
+
+// NoColorEnvVar disables colors when the color setting is auto, following https://no-color.org.
+const NoColorEnvVar = "NO_COLOR"
+
+// severityColors are the ANSI escape sequences severities are printed with in colored output.
+var severityColors = map[doctorSeverity]string{
+	doctorOK:      "\033[32m",
+	doctorWarning: "\033[33m",
+	doctorError:   "\033[31m",
+}
+
+// colorEnabled reports whether output to out is colored, as the color setting tells: always, never,
+// or auto, the default, which colors the output of terminals unless $NO_COLOR is set.
+func colorEnabled(setting string, out io.Writer) bool {
+	switch setting {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if _, set := os.LookupEnv(NoColorEnvVar); set {
+		return false
+	}
+	return term.IsTerminal(out)
+}
+
+// severityLabel returns severity as it is printed in reports, colored with color.
+func severityLabel(severity doctorSeverity, color bool) string {
+	if !color {
+		return string(severity)
+	}
+	return severityColors[severity] + string(severity) + "\033[0m"
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestColorEnabled(t *testing.T) {
+	defer func(value string, set bool) {
+		if set {
+			os.Setenv(NoColorEnvVar, value)
+		} else {
+			os.Unsetenv(NoColorEnvVar)
+		}
+	}(os.LookupEnv(NoColorEnvVar))
+	os.Unsetenv(NoColorEnvVar)
+
+	out := &bytes.Buffer{}
+	if !colorEnabled("always", out) {
+		t.Errorf("expected color=always to color any output")
+	}
+	if colorEnabled("never", out) || colorEnabled("auto", out) || colorEnabled("", out) {
+		t.Errorf("expected color=never, and auto off a terminal, not to color the output")
+	}
+	os.Setenv(NoColorEnvVar, "1")
+	if colorEnabled("auto", os.Stdout) || !colorEnabled("always", out) {
+		t.Errorf("expected $%s to only disable color=auto", NoColorEnvVar)
+	}
+
+	if label := severityLabel(doctorError, false); label != "ERROR" {
+		t.Errorf("expected a plain label, got %q", label)
+	}
+	if label := severityLabel(doctorError, true); label != "\033[31mERROR\033[0m" {
+		t.Errorf("expected a red label, got %q", label)
+	}
+}
@@ -0,0 +1,296 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// kubectlGlobalFlags are the flags every kubectl command accepts, and whether they take a value.
+// Scripts pass them to "kubectl config" although most have no effect there.
+var kubectlGlobalFlags = map[string]bool{
+	"add-dir-header":           false,
+	"alsologtostderr":          false,
+	"as":                       true,
+	"as-group":                 true,
+	"cache-dir":                true,
+	"certificate-authority":    true,
+	"client-certificate":       true,
+	"client-key":               true,
+	"cluster":                  true,
+	"context":                  true,
+	"insecure-skip-tls-verify": false,
+	"kubeconfig":               true,
+	"log-backtrace-at":         true,
+	"log-dir":                  true,
+	"log-file":                 true,
+	"log-file-max-size":        true,
+	"log-flush-frequency":      true,
+	"logtostderr":              false,
+	"match-server-version":     false,
+	"namespace":                true,
+	"password":                 true,
+	"profile":                  true,
+	"profile-output":           true,
+	"request-timeout":          true,
+	"server":                   true,
+	"skip-headers":             false,
+	"skip-log-headers":         false,
+	"stderrthreshold":          true,
+	"tls-server-name":          true,
+	"token":                    true,
+	"user":                     true,
+	"username":                 true,
+	"v":                        true,
+	"vmodule":                  true,
+}
+
+// kubectlGlobalShorthands maps the shorthands of kubectlGlobalFlags to their names.
+var kubectlGlobalShorthands = map[string]string{
+	"n": "namespace",
+	"s": "server",
+	"v": "v",
+}
+
+// CompatOptions holds the command-line options for 'config compat' sub command
+type CompatOptions struct {
+	DryRun bool
+	Args   []string
+
+	// Command is the config command the translated arguments are looked up in.
+	Command *cobra.Command
+	// NewCommand builds a new config command the translated arguments are run with.
+	NewCommand func() *cobra.Command
+	// Translated and Ignored are set by Translate.
+	Translated []string
+	Ignored    []string
+
+	genericclioptions.IOStreams
+}
+
+var (
+	compatLong = templates.LongDesc(`
+		Run a "kubectl config" command line with this plugin.
+
+		The arguments are a complete invocation of "kubectl config", optionally starting with
+		"kubectl" and "config". Every subcommand of "kubectl config" exists here with the same
+		flags; global kubectl flags such as --request-timeout or --namespace, which scripts pass
+		along but which do not apply to the subcommand, are dropped with a warning. This allows
+		aliasing "kubectl config" to the plugin without breaking scripts.
+
+		With --dry-run, the command line that would run is printed instead, together with the
+		flags that would be dropped, to audit scripts before switching them over.`)
+
+	compatExample = templates.Examples(`
+		# Run a kubectl config command line
+		kubectl config compat -- kubectl --request-timeout=5s config use-context prod
+
+		# Show what a command line maps to without running it
+		kubectl config compat --dry-run -- kubectl config set-context --current -n kube-system`)
+)
+
+// NewCmdConfigCompat returns a Command instance for 'config compat' sub command
+func NewCmdConfigCompat(streams genericclioptions.IOStreams, newCommand func() *cobra.Command) *cobra.Command {
+	o := &CompatOptions{NewCommand: newCommand, IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "compat [--dry-run] -- [kubectl] [config] SUBCOMMAND [flags]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Run a kubectl config command line with this plugin"),
+		Long:                  compatLong,
+		Example:               compatExample,
+		// The command line runs with the hooks of a new config command, see Run.
+		PersistentPreRunE:  func(*cobra.Command, []string) error { return nil },
+		PersistentPostRunE: func(*cobra.Command, []string) error { return nil },
+		Run: func(cmd *cobra.Command, args []string) {
+			o.Args = args
+			o.Command = cmd.Parent()
+			cmdutil.CheckErr(o.Translate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	cmd.Flags().SetInterspersed(false)
+	cmd.Flags().BoolVar(&o.DryRun, "dry-run", o.DryRun, "Print the command line that would run instead of running it")
+	return cmd
+}
+
+// Translate maps the kubectl config command line to the arguments of the config command, dropping
+// global kubectl flags the subcommand does not have
+func (o *CompatOptions) Translate() error {
+	args := o.Args
+	if len(args) > 0 && strings.TrimSuffix(filepath.Base(args[0]), ".exe") == "kubectl" {
+		args = args[1:]
+	}
+
+	// "config" is the first argument that is not a flag or the value of one.
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--" {
+			break
+		}
+		if !strings.HasPrefix(args[i], "-") {
+			if args[i] == "config" {
+				args = append(append([]string{}, args[:i]...), args[i+1:]...)
+			}
+			break
+		}
+		if _, takesValue := globalFlag(args[i]); flagTakesSeparateValue(args[i], takesValue, true) {
+			i++
+		}
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("no kubectl config subcommand given")
+	}
+
+	target, _, err := o.Command.Find(args)
+	if err != nil {
+		return err
+	}
+	if target == o.Command {
+		return fmt.Errorf("unknown kubectl config subcommand in %q", strings.Join(o.Args, " "))
+	}
+
+	o.Translated, o.Ignored = []string{}, []string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			o.Translated = append(o.Translated, args[i:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			o.Translated = append(o.Translated, arg)
+			continue
+		}
+
+		flag := lookupFlag(target, arg)
+		if flag != nil {
+			o.Translated = append(o.Translated, arg)
+			if flagTakesSeparateValue(arg, len(flag.NoOptDefVal) == 0, len(flag.Shorthand) > 0) && i+1 < len(args) {
+				i++
+				o.Translated = append(o.Translated, args[i])
+			}
+			continue
+		}
+		name, takesValue := globalFlag(arg)
+		if len(name) == 0 {
+			return fmt.Errorf("unknown flag %s for %q", arg, target.CommandPath())
+		}
+		value := ""
+		if flagTakesSeparateValue(arg, takesValue, true) && i+1 < len(args) {
+			i++
+			value = args[i]
+		} else if parts := strings.SplitN(arg, "=", 2); len(parts) == 2 {
+			value = parts[1]
+		} else if takesValue && !strings.HasPrefix(arg, "--") {
+			value = arg[2:]
+		}
+		// A shorthand kubectl defines globally, such as -n, means the subcommand's flag of that name.
+		long := "--" + name
+		if takesValue {
+			long += "=" + value
+		}
+		if lookupFlag(target, "--"+name) != nil {
+			o.Translated = append(o.Translated, long)
+			continue
+		}
+		o.Ignored = append(o.Ignored, long)
+	}
+	return nil
+}
+
+// Run prints or runs the translated command line
+func (o *CompatOptions) Run() error {
+	for _, ignored := range o.Ignored {
+		fmt.Fprintf(o.ErrOut, "warning: ignoring %s, which has no effect on kubectl config\n", ignored)
+	}
+	if o.DryRun {
+		fmt.Fprintln(o.Out, strings.Join(append([]string{o.Command.CommandPath()}, o.Translated...), " "))
+		return nil
+	}
+
+	// The command line runs on a new config command, like a new invocation, so that its flags start
+	// from their defaults and the checks, confirmations, decryption and recording of the config
+	// command are done for it exactly as without compat. The flags of the config command given
+	// before compat, such as --no-write, are passed along.
+	args := []string{}
+	o.Command.PersistentFlags().Visit(func(flag *pflag.Flag) {
+		args = append(args, "--"+flag.Name+"="+flag.Value.String())
+	})
+	cmd := o.NewCommand()
+	cmd.SetArgs(append(args, o.Translated...))
+	return cmd.Execute()
+}
+
+// lookupFlag returns the flag of cmd, or of its parents, arg sets.
+func lookupFlag(cmd *cobra.Command, arg string) *pflag.Flag {
+	name := strings.SplitN(strings.TrimLeft(arg, "-"), "=", 2)[0]
+	if strings.HasPrefix(arg, "--") {
+		if flag := cmd.Flags().Lookup(name); flag != nil {
+			return flag
+		}
+		return cmd.InheritedFlags().Lookup(name)
+	}
+	if len(name) == 0 {
+		return nil
+	}
+	shorthand := name[:1]
+	if flag := cmd.Flags().ShorthandLookup(shorthand); flag != nil {
+		return flag
+	}
+	return cmd.InheritedFlags().ShorthandLookup(shorthand)
+}
+
+// globalFlag returns the name of the global kubectl flag arg sets and whether it takes a value, or
+// "" when arg is not a global flag.
+func globalFlag(arg string) (string, bool) {
+	name := strings.SplitN(strings.TrimLeft(arg, "-"), "=", 2)[0]
+	if !strings.HasPrefix(arg, "--") {
+		if len(name) == 0 {
+			return "", false
+		}
+		if name = kubectlGlobalShorthands[name[:1]]; len(name) == 0 {
+			return "", false
+		}
+	}
+	takesValue, ok := kubectlGlobalFlags[name]
+	if !ok {
+		return "", false
+	}
+	return name, takesValue
+}
+
+// flagTakesSeparateValue reports whether the value of the flag in arg is the next argument, as in
+// "--namespace default" or "-n default", rather than part of arg, as in "--namespace=default" or
+// "-ndefault".
+func flagTakesSeparateValue(arg string, takesValue, hasShorthand bool) bool {
+	if !takesValue || strings.Contains(arg, "=") {
+		return false
+	}
+	if strings.HasPrefix(arg, "--") {
+		return true
+	}
+	return hasShorthand && len(arg) == 2
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+func TestCompatTranslate(t *testing.T) {
+	tests := []struct {
+		args       []string
+		translated []string
+		ignored    []string
+		expectErr  bool
+	}{
+		{
+			args:       []string{"kubectl", "config", "use-context", "prod"},
+			translated: []string{"use-context", "prod"},
+			ignored:    []string{},
+		},
+		{
+			args:       []string{"/usr/local/bin/kubectl", "--request-timeout", "5s", "config", "--kubeconfig", "/tmp/config", "view", "--minify", "-o", "json"},
+			translated: []string{"--kubeconfig", "/tmp/config", "view", "--minify", "-o", "json"},
+			ignored:    []string{"--request-timeout=5s"},
+		},
+		{
+			args:       []string{"config", "set-context", "--current", "-n", "kube-system", "--insecure-skip-tls-verify"},
+			translated: []string{"set-context", "--current", "--namespace=kube-system"},
+			ignored:    []string{"--insecure-skip-tls-verify"},
+		},
+		{
+			args:       []string{"set-cluster", "e2e", "--server=https://1.2.3.4", "-v", "4", "--as=admin"},
+			translated: []string{"set-cluster", "e2e", "--server=https://1.2.3.4", "-v", "4"},
+			ignored:    []string{"--as=admin"},
+		},
+		{
+			args:      []string{"kubectl", "config", "use-context", "prod", "--no-such-flag"},
+			expectErr: true,
+		},
+		{
+			args:      []string{"kubectl", "config"},
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(strings.Join(test.args, " "), func(t *testing.T) {
+			streams, _, _, _ := genericclioptions.NewTestIOStreams()
+			o := &CompatOptions{
+				Args:      test.args,
+				Command:   NewCmdConfig(cmdutil.NewFactory(genericclioptions.NewTestConfigFlags()), clientcmd.NewDefaultPathOptions(), streams),
+				IOStreams: streams,
+			}
+			err := o.Translate()
+			if test.expectErr {
+				if err == nil {
+					t.Errorf("expected an error, got %v", o.Translated)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(o.Translated, test.translated) {
+				t.Errorf("expected %q, got %q", test.translated, o.Translated)
+			}
+			if !reflect.DeepEqual(o.Ignored, test.ignored) {
+				t.Errorf("expected %q to be ignored, got %q", test.ignored, o.Ignored)
+			}
+		})
+	}
+}
+
+func TestCompatRunsCommand(t *testing.T) {
+	startingConfig := newRedFederalCowHammerConfig()
+	startingConfig.Contexts["shaker-context"] = &clientcmdapi.Context{AuthInfo: "red-user", Cluster: "cow-cluster"}
+	expectedConfig := newRedFederalCowHammerConfig()
+	expectedConfig.Contexts["shaker-context"] = &clientcmdapi.Context{AuthInfo: "red-user", Cluster: "cow-cluster"}
+	expectedConfig.CurrentContext = "shaker-context"
+
+	test := configCommandTest{
+		args:            []string{"compat", "--", "kubectl", "--request-timeout=5s", "config", "use-context", "shaker-context"},
+		startingConfig:  startingConfig,
+		expectedConfig:  expectedConfig,
+		expectedOutputs: []string{`Switched to context "shaker-context".`},
+	}
+	test.run(t)
+}
+
+func TestCompatRunsHooks(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	config := newRedFederalCowHammerConfig()
+	config.Contexts["shaker-context"] = &clientcmdapi.Context{AuthInfo: "red-user", Cluster: "cow-cluster"}
+	if err := clientcmd.WriteToFile(config, fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	streams, _, _, _ := genericclioptions.NewTestIOStreams()
+	newCommand := func() *cobra.Command {
+		cmd := NewCmdConfig(cmdutil.NewFactory(genericclioptions.NewTestConfigFlags()), pathOptions, streams)
+		cmd.SetOutput(ioutil.Discard)
+		return cmd
+	}
+	root := newCommand()
+	if err := root.PersistentFlags().Set(FlagNoWrite, "true"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	o := &CompatOptions{
+		Args:       []string{"kubectl", "config", "use-context", "shaker-context"},
+		Command:    root,
+		NewCommand: newCommand,
+		IOStreams:  streams,
+	}
+	if err := o.Translate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = o.Run()
+	if err == nil || !strings.Contains(err.Error(), `"config use-context" may write files`) {
+		t.Errorf("expected --no-write given before compat to refuse the command, got %v", err)
+	}
+	after, err := clientcmd.LoadFromFile(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if after.CurrentContext != config.CurrentContext {
+		t.Errorf("expected the current context to stay %q, got %q", config.CurrentContext, after.CurrentContext)
+	}
+}
@@ -17,14 +17,19 @@ limitations under the License.
 package config
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"path"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 	"k8s.io/kubectl/pkg/util/i18n"
 	"k8s.io/kubectl/pkg/util/templates"
@@ -47,31 +52,217 @@ func NewCmdConfig(f cmdutil.Factory, pathOptions *clientcmd.PathOptions, streams
 
 			1. If the --` + pathOptions.ExplicitFileFlag + ` flag is set, then only that file is loaded. The flag may only be set once and no merging takes place.
 			2. If $` + pathOptions.EnvVar + ` environment variable is set, then it is used as a list of paths (normal path delimiting rules for your system). These paths are merged. When a value is modified, it is modified in the file that defines the stanza. When a value is created, it is created in the first file that exists. If no files in the chain exist, then it creates the last file in the list.
-			3. Otherwise, ` + path.Join("${HOME}", pathOptions.GlobalFileSubpath) + ` is used and no merging takes place.`),
+			3. Otherwise, ` + path.Join("${HOME}", pathOptions.GlobalFileSubpath) + ` is used and no merging takes place.
+
+			Files encrypted with SOPS are decrypted for the duration of a command and encrypted again with their original keys when the command changed them. This needs the sops binary, and cannot be done with --no-write.
+
+			On a terminal, long output such as the one of "kubectl config view" goes through $PAGER, or less or more when it is not set, like git does. A built-in pager is used when none is installed. Set $NOPAGER or pass --no-pager to print the output directly.
+
+			With --no-write, or $KUBECTL_CONFIG_NO_WRITE, only the subcommands that never write a file run, such as view, get-contexts, inventory or lint without --fix, and every other one is refused before it starts. This allows inspecting the kubeconfig files of other users on shared hosts, as in "kubectl config view -f /home/other/.kube/config --no-write", without changing them.`),
 		Run: cmdutil.DefaultSubCommandRun(streams.ErrOut),
 	}
 
 	// file paths are common to all sub commands
-	cmd.PersistentFlags().StringVar(&pathOptions.LoadingRules.ExplicitPath, pathOptions.ExplicitFileFlag, pathOptions.LoadingRules.ExplicitPath, "use a particular kubeconfig file")
+	cmd.PersistentFlags().StringVarP(&pathOptions.LoadingRules.ExplicitPath, pathOptions.ExplicitFileFlag, "f", pathOptions.LoadingRules.ExplicitPath, "use a particular kubeconfig file")
+	addNoNetworkFlag(cmd)
+	addNoWriteFlag(cmd)
+	addYesFlag(cmd)
+	addLoggingFlags(cmd)
+	addPagerFlag(cmd)
+
+	// "config compat" runs its command line on a new config command, which has its own pager
+	compatStreams := streams
+
+	// the output of the sub commands marked with pagedCommand goes through a pager on a terminal
+	pager := newPager(streams)
+	streams.Out = pager
+
+	// writes to the managed kubeconfig files of the loading chain go to the overlay file before them
+	configAccess := &overlayConfigAccess{PathOptions: pathOptions, SettingsFile: settingsFile()}
+
+	// SOPS encrypted kubeconfig files are decrypted before and encrypted again after every subcommand
+	var sops *sopsSession
+	// the metadata of kubeconfig is recorded for "config repair" when a subcommand wrote it
+	var modTimes map[string]time.Time
+	cmd.PersistentPreRunE = func(c *cobra.Command, args []string) error {
+		if err := checkNoWrite(c); err != nil {
+			return err
+		}
+		modTimes = kubeconfigModTimes(configAccess)
+		var err error
+		if sops, err = startSopsSession(pathOptions, writesDisabled(c)); err != nil {
+			return err
+		}
+		// changes to protected contexts are confirmed before the subcommand starts
+		if err := confirmChange(c, args, configAccess, settingsFile(), streams.In, streams.ErrOut); err != nil {
+			if sops != nil {
+				sops.cleanup()
+			}
+			return err
+		}
+		if err := pager.start(c); err != nil {
+			return err
+		}
+		if sops == nil && !pager.active() {
+			return nil
+		}
+		cmdutil.BehaviorOnFatal(func(msg string, code int) {
+			pager.finish()
+			if sops != nil {
+				sops.exit(msg, code)
+			}
+			exitWithMessage(msg, code)
+		})
+		return nil
+	}
+	cmd.PersistentPostRunE = func(c *cobra.Command, _ []string) error {
+		// encrypted files are encrypted again first, so that the metadata is recorded with their
+		// final modification times, while it is still read from the decrypted copies
+		var err error
+		if sops != nil {
+			err = sops.encrypt()
+		}
+		// the switches a subcommand made are recorded while "config record" is in progress
+		if !writesDisabled(c) {
+			if err := recordSwitch(recordingFile(), configAccess, time.Now()); err != nil {
+				fmt.Fprintf(streams.ErrOut, "warning: recording context switches: %v\n", err)
+			}
+			after := kubeconfigModTimes(configAccess)
+			if sops != nil {
+				after = sops.encryptedModTimes(after)
+			}
+			if err := updateMetadataSnapshot(metadataSnapshotFile(), configAccess, modTimes, after); err != nil {
+				fmt.Fprintf(streams.ErrOut, "warning: recording the metadata of kubeconfig: %v\n", err)
+			}
+		}
+		pager.finish()
+		cmdutil.DefaultBehaviorOnFatal()
+		if sops != nil {
+			sops.cleanup()
+		}
+		return err
+	}
 
 	// TODO(juanvallejo): update all subcommands to work with genericclioptions.IOStreams
-	cmd.AddCommand(NewCmdConfigView(f, streams, pathOptions))
-	cmd.AddCommand(NewCmdConfigSetCluster(streams.Out, pathOptions))
-	cmd.AddCommand(NewCmdConfigSetAuthInfo(streams.Out, pathOptions))
-	cmd.AddCommand(NewCmdConfigSetContext(streams.Out, pathOptions))
-	cmd.AddCommand(NewCmdConfigSet(streams.Out, pathOptions))
-	cmd.AddCommand(NewCmdConfigUnset(streams.Out, pathOptions))
-	cmd.AddCommand(NewCmdConfigCurrentContext(streams.Out, pathOptions))
-	cmd.AddCommand(NewCmdConfigUseContext(streams.Out, pathOptions))
-	cmd.AddCommand(NewCmdConfigGetContexts(streams, pathOptions))
-	cmd.AddCommand(NewCmdConfigGetClusters(streams.Out, pathOptions))
-	cmd.AddCommand(NewCmdConfigDeleteCluster(streams.Out, pathOptions))
-	cmd.AddCommand(NewCmdConfigDeleteContext(streams.Out, streams.ErrOut, pathOptions))
-	cmd.AddCommand(NewCmdConfigRenameContext(streams.Out, pathOptions))
+	cmd.AddCommand(noWriteCommand(pagedCommand(NewCmdConfigView(f, streams, configAccess))))
+	cmd.AddCommand(confirmCommand(NewCmdConfigSetCluster(streams.Out, configAccess), confirmCluster))
+	cmd.AddCommand(confirmCommand(NewCmdConfigSetAuthInfo(streams.Out, configAccess), confirmUser))
+	cmd.AddCommand(confirmCommand(NewCmdConfigSetContext(streams.Out, configAccess), confirmContext))
+	cmd.AddCommand(confirmCommand(NewCmdConfigSet(streams.Out, configAccess), confirmProperty))
+	cmd.AddCommand(confirmCommand(NewCmdConfigUnset(streams.Out, configAccess), confirmProperty))
+	cmd.AddCommand(noWriteCommand(NewCmdConfigCurrentContext(streams.Out, configAccess)))
+	cmd.AddCommand(confirmCommand(NewCmdConfigUseContext(streams.Out, configAccess), confirmContext))
+	cmd.AddCommand(noWriteCommand(pagedCommand(NewCmdConfigGetContexts(streams, configAccess))))
+	cmd.AddCommand(noWriteCommand(pagedCommand(NewCmdConfigGetClusters(streams.Out, configAccess))))
+	cmd.AddCommand(confirmCommand(NewCmdConfigDeleteCluster(streams.Out, configAccess), confirmCluster))
+	cmd.AddCommand(confirmCommand(NewCmdConfigDeleteContext(streams.Out, streams.ErrOut, configAccess), confirmContext))
+	cmd.AddCommand(confirmCommand(NewCmdConfigRenameContext(streams.Out, configAccess), confirmContext))
+	cmd.AddCommand(pagedCommand(NewCmdConfigMigrateAuth(streams, configAccess)))
+	cmd.AddCommand(NewCmdConfigMigrate(streams, configAccess))
+	cmd.AddCommand(noWriteCommand(NewCmdConfigDoctor(streams, pathOptions), "fix"))
+	cmd.AddCommand(NewCmdConfigSupportBundle(streams, pathOptions))
+	cmd.AddCommand(NewCmdConfigRepair(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigImport(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigSettings(streams))
+	cmd.AddCommand(NewCmdConfigProfile(streams))
+	cmd.AddCommand(noWriteCommand(NewCmdConfigShellInit(streams), "install"))
+	cmd.AddCommand(noWriteCommand(NewCmdConfigStats(streams, configAccess)))
+	cmd.AddCommand(noWriteCommand(NewCmdConfigInventory(streams, configAccess)))
+	cmd.AddCommand(NewCmdConfigInclude(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigSource(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigSign(streams))
+	cmd.AddCommand(noWriteCommand(NewCmdConfigVerify(streams)))
+	cmd.AddCommand(NewCmdConfigSession(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigAcknowledge(streams, configAccess))
+	cmd.AddCommand(noWriteCommand(NewCmdConfigCompat(streams, func() *cobra.Command {
+		return NewCmdConfig(f, pathOptions, compatStreams)
+	})))
+	cmd.AddCommand(NewCmdConfigVerifyIdentity(streams, configAccess))
+	cmd.AddCommand(pagedCommand(NewCmdConfigConvertKubelogin(streams, configAccess)))
+	cmd.AddCommand(pagedCommand(NewCmdConfigRewriteAWS(streams, configAccess)))
+	cmd.AddCommand(NewCmdConfigEnrich(streams, configAccess))
+	cmd.AddCommand(noWriteCommand(NewCmdConfigHealth(streams, configAccess)))
+	cmd.AddCommand(noWriteCommand(NewCmdConfigDiagnose(streams, configAccess)))
+	cmd.AddCommand(noWriteCommand(NewCmdConfigWatch(streams, configAccess)))
+	cmd.AddCommand(noWriteCommand(NewCmdConfigContextInfo(streams, configAccess)))
+	cmd.AddCommand(noWriteCommand(NewCmdConfigEffective(streams, configAccess)))
+	cmd.AddCommand(noWriteCommand(NewCmdConfigIDEServer(streams, configAccess)))
+	cmd.AddCommand(NewCmdConfigRefreshLocal(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigRefreshEndpoint(streams, configAccess))
+	cmd.AddCommand(noWriteCommand(NewCmdConfigEndpoint(streams, configAccess)))
+	cmd.AddCommand(noWriteCommand(NewCmdConfigExport(streams, configAccess), "to-secret"))
+	cmd.AddCommand(noWriteCommand(NewCmdConfigShare(streams, configAccess)))
+	cmd.AddCommand(NewCmdConfigReceive(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigBundle(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigInit(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigCache(streams))
+	cmd.AddCommand(NewCmdConfigRefresh(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigCredential(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigP12Credential(streams))
+	cmd.AddCommand(NewCmdConfigEncryptUser(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigEncryptedCredential(streams, configAccess))
+	cmd.AddCommand(noWriteCommand(NewCmdConfigFlags(streams, configAccess), "clear"))
+	cmd.AddCommand(NewCmdConfigExec(streams, configAccess))
+	cmd.AddCommand(noWriteCommand(NewCmdConfigReadOnly(streams, configAccess)))
+	cmd.AddCommand(noWriteCommand(NewCmdConfigPlugins(streams, configAccess), "allow", "deny", "clear"))
+	cmd.AddCommand(NewCmdConfigProtect(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigAlias(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigGroup(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigAutoswitch(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigPush(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigPull(streams, configAccess))
+	cmd.AddCommand(noWriteCommand(NewCmdConfigTuning(streams, configAccess), "timeout", "qps", "burst", "clear"))
+	cmd.AddCommand(NewCmdConfigDeprecate(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigGC(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigSetOwner(streams, configAccess))
+	cmd.AddCommand(noWriteCommand(NewCmdConfigOwner(streams, configAccess)))
+	cmd.AddCommand(noWriteCommand(NewCmdConfigLint(streams, configAccess), "fix"))
+	cmd.AddCommand(noWriteCommand(NewCmdConfigSchema(streams)))
+	cmd.AddCommand(noWriteCommand(NewCmdConfigExplainPrecedence(streams, pathOptions)))
+	cmd.AddCommand(NewCmdConfigSetNamespace(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigNamespace(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigState(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigOverlay(streams))
+	cmd.AddCommand(NewCmdConfigSetKubectlVersion(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigKubectl(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigAs(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigRecord(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigReplay(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigBanner(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigProvenance(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigLock(streams, configAccess))
+	noWriteParents(cmd)
 
 	return cmd
 }
 
+// currentContextShorthand may be passed wherever a context name is expected to refer to the current-context.
+const currentContextShorthand = "."
+
+// resolveContextName expands the currentContextShorthand into the name of the current-context.
+// Any other name is returned unchanged.
+func resolveContextName(config *clientcmdapi.Config, name string) (string, error) {
+	if name != currentContextShorthand {
+		return name, nil
+	}
+	if len(config.CurrentContext) == 0 {
+		return "", errors.New("current-context is not set")
+	}
+	return config.CurrentContext, nil
+}
+
+// exitWithMessage prints msg to stderr and exits with code, as kubectl does on fatal errors. It is
+// called by the behaviors on fatal errors that clean up before exiting.
+func exitWithMessage(msg string, code int) {
+	if len(msg) > 0 {
+		if !strings.HasSuffix(msg, "\n") {
+			msg += "\n"
+		}
+		fmt.Fprint(os.Stderr, msg)
+	}
+	os.Exit(code)
+}
+
 func toBool(propertyValue string) (bool, error) {
 	boolValue := false
 	if len(propertyValue) != 0 {
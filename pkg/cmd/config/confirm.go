@@ -0,0 +1,244 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	// FlagYes is the persistent flag that skips the confirmation of changes to protected contexts.
+	FlagYes = "yes"
+
+	// confirmAnnotation marks the subcommands changing kubeconfig that ask for confirmation. Its
+	// value tells what their first argument names, see changedContexts.
+	confirmAnnotation = "kubecfg.io/confirm"
+)
+
+// The values of the confirm setting.
+const (
+	confirmAlways    = "always"
+	confirmProtected = "protected"
+	confirmNever     = "never"
+)
+
+// What the first argument of a subcommand marked with confirmCommand names.
+const (
+	confirmContext  = "context"
+	confirmCluster  = "cluster"
+	confirmUser     = "user"
+	confirmProperty = "property"
+)
+
+// addYesFlag registers --yes on the root config command so every subcommand inherits it.
+func addYesFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().Bool(FlagYes, false, "Change protected contexts without asking for confirmation, see the confirm setting")
+}
+
+// confirmCommand marks cmd as changing the contexts its first argument names, as kind tells, which
+// asks for confirmation when the confirm setting covers them.
+func confirmCommand(cmd *cobra.Command, kind string) *cobra.Command {
+	if cmd.Annotations == nil {
+		cmd.Annotations = map[string]string{}
+	}
+	cmd.Annotations[confirmAnnotation] = kind
+	return cmd
+}
+
+// changedContexts returns the names of the contexts cmd changes when run with args: the context
+// it names, the contexts using the cluster or user it names, or the context a property path such
+// as contexts.prod.namespace belongs to.
+func changedContexts(cmd *cobra.Command, args []string, config *clientcmdapi.Config) []string {
+	kind := cmd.Annotations[confirmAnnotation]
+	if kind == confirmProperty {
+		if len(args) == 0 {
+			return nil
+		}
+		steps := strings.SplitN(args[0], ".", 3)
+		switch {
+		case steps[0] == "current-context" && len(args) > 1:
+			return []string{args[1]}
+		case len(steps) < 2:
+			return nil
+		case steps[0] == "contexts":
+			return []string{steps[1]}
+		case steps[0] == "clusters":
+			kind, args = confirmCluster, steps[1:2]
+		case steps[0] == "users":
+			kind, args = confirmUser, steps[1:2]
+		default:
+			return nil
+		}
+	}
+
+	switch kind {
+	case confirmContext:
+		if flag := cmd.Flags().Lookup("current"); flag != nil && flag.Changed {
+			return []string{config.CurrentContext}
+		}
+		if len(args) == 0 {
+			return nil
+		}
+		name, err := resolveContextName(config, args[0])
+		if err != nil {
+			return nil
+		}
+		return []string{name}
+	case confirmCluster, confirmUser:
+		if len(args) == 0 {
+			return nil
+		}
+		return contextsUsing(config, kind, args[0])
+	}
+	return nil
+}
+
+// contextsUsing returns the sorted names of the contexts using the cluster or user, as kind tells,
+// called name.
+func contextsUsing(config *clientcmdapi.Config, kind, name string) []string {
+	names := []string{}
+	for contextName, context := range config.Contexts {
+		if (kind == confirmCluster && context.Cluster == name) || (kind == confirmUser && context.AuthInfo == name) {
+			names = append(names, contextName)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// protectedContexts returns the policy whose reason tells whether a context is protected, for
+// confirm set to protected: tagged with one of cooloffTags, prod by default, or named like one of
+// protectedPatterns.
+func protectedContexts(settings *Settings) *cooloffPolicy {
+	tags := settings.CooloffTags
+	if len(tags) == 0 {
+		tags = []string{defaultCooloffTag}
+	}
+	return &cooloffPolicy{Tags: tags, Patterns: settings.ProtectedPatterns}
+}
+
+// confirmChange asks on out, reading the answer from in, whether cmd may change the contexts the
+// confirm setting of the settings file covers, and returns an error unless every change is
+// confirmed. With confirm set to protected, the contexts covered are those protectedContexts
+// tells.
+func confirmChange(cmd *cobra.Command, args []string, configAccess clientcmd.ConfigAccess, settingsFile string, in io.Reader, out io.Writer) error {
+	if _, ok := cmd.Annotations[confirmAnnotation]; !ok {
+		return nil
+	}
+	settings, err := confirmSettings(cmd, settingsFile)
+	if err != nil || settings == nil {
+		return err
+	}
+	config, err := configAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	return confirmContexts(cmd, settings, config, changedContexts(cmd, args, config), in, out)
+}
+
+// changeConfirmer returns an error unless changing the contexts called names is confirmed, for the
+// subcommands that only know which contexts they change while they run. They call it before
+// writing anything.
+type changeConfirmer func(names []string) error
+
+// check calls c, and returns nil when it is nil, as in tests.
+func (c changeConfirmer) check(names []string) error {
+	if c == nil {
+		return nil
+	}
+	return c(names)
+}
+
+// newChangeConfirmer returns the changeConfirmer of cmd, which asks as confirmChange does about
+// the contexts of configAccess.
+func newChangeConfirmer(cmd *cobra.Command, configAccess clientcmd.ConfigAccess, in io.Reader, out io.Writer) changeConfirmer {
+	return func(names []string) error {
+		if len(names) == 0 {
+			return nil
+		}
+		settings, err := confirmSettings(cmd, settingsFile())
+		if err != nil || settings == nil {
+			return err
+		}
+		config, err := configAccess.GetStartingConfig()
+		if err != nil {
+			return err
+		}
+		return confirmContexts(cmd, settings, config, names, in, out)
+	}
+}
+
+// confirmSettings returns the settings of the settings file when their confirm setting asks for
+// confirmation, and nil when it does not or --yes is given.
+func confirmSettings(cmd *cobra.Command, settingsFile string) (*Settings, error) {
+	if flag := cmd.Flags().Lookup(FlagYes); flag != nil && flag.Changed {
+		if yes, _ := toBool(flag.Value.String()); yes {
+			return nil, nil
+		}
+	}
+	settings, err := loadSettings(settingsFile)
+	if err != nil {
+		return nil, err
+	}
+	settings = withSafeMode(settings)
+	if settings.Confirm != confirmAlways && settings.Confirm != confirmProtected {
+		return nil, nil
+	}
+	return settings, nil
+}
+
+// confirmContexts asks whether cmd may change the contexts called names of config that the confirm
+// setting covers. The answers are read from in, which is used as is when it is buffered already.
+func confirmContexts(cmd *cobra.Command, settings *Settings, config *clientcmdapi.Config, names []string, in io.Reader, out io.Writer) error {
+	protected := protectedContexts(settings)
+	var answers *bufio.Reader
+	for _, name := range names {
+		context, ok := config.Contexts[name]
+		if !ok {
+			continue
+		}
+		reason := ""
+		if settings.Confirm == confirmProtected {
+			var err error
+			if reason, err = protected.reason(name, context); err != nil {
+				return err
+			}
+			if len(reason) == 0 {
+				continue
+			}
+			reason = " is " + reason + " and"
+		}
+		if answers == nil {
+			answers = bufio.NewReader(in)
+		}
+		fmt.Fprintf(out, "Context %q%s would be changed by %q. Continue? [y/N]: ", name, reason, cmd.CommandPath())
+		answer, _ := answers.ReadString('\n')
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+			return fmt.Errorf("%q was not confirmed for context %q, run again with --%s to skip confirmation", cmd.CommandPath(), name, FlagYes)
+		}
+	}
+	return nil
+}
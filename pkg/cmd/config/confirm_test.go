@@ -0,0 +1,154 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+func TestChangedContexts(t *testing.T) {
+	config := newRedFederalCowHammerConfig()
+	config.Contexts["prod"] = &clientcmdapi.Context{AuthInfo: "red-user", Cluster: "prod-cluster"}
+	tests := []struct {
+		kind     string
+		args     []string
+		expected []string
+	}{
+		{kind: confirmContext, args: []string{"prod"}, expected: []string{"prod"}},
+		{kind: confirmContext, args: []string{"."}, expected: []string{"federal-context"}},
+		{kind: confirmCluster, args: []string{"prod-cluster"}, expected: []string{"prod"}},
+		{kind: confirmUser, args: []string{"red-user"}, expected: []string{"federal-context", "prod"}},
+		{kind: confirmProperty, args: []string{"contexts.prod.namespace", "web"}, expected: []string{"prod"}},
+		{kind: confirmProperty, args: []string{"clusters.prod-cluster.server", "https://1.2.3.4"}, expected: []string{"prod"}},
+		{kind: confirmProperty, args: []string{"current-context", "prod"}, expected: []string{"prod"}},
+		{kind: confirmProperty, args: []string{"preferences.colors"}},
+	}
+	for _, tt := range tests {
+		cmd := confirmCommand(&cobra.Command{Use: "test"}, tt.kind)
+		if changed := changedContexts(cmd, tt.args, &config); !reflect.DeepEqual(tt.expected, changed) && len(tt.expected)+len(changed) > 0 {
+			t.Errorf("%s %v: expected %v, got %v", tt.kind, tt.args, tt.expected, changed)
+		}
+	}
+}
+
+func TestConfirmSafeMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "confirm")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.Setenv("XDG_CONFIG_HOME", os.Getenv("XDG_CONFIG_HOME"))
+	defer os.Setenv("XDG_STATE_HOME", os.Getenv("XDG_STATE_HOME"))
+	os.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	os.Setenv("XDG_STATE_HOME", filepath.Join(dir, "state"))
+	if err := saveSettings(settingsFile(), &Settings{SafeMode: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	kubeconfigFile := filepath.Join(dir, "kubeconfig")
+	tests := []struct {
+		name        string
+		args        []string
+		answer      string
+		expectedErr string
+		deleted     bool
+	}{
+		{name: "refused", args: []string{"delete-context", "shop-prod"}, answer: "n\n", expectedErr: "was not confirmed for context \"shop-prod\""},
+		{name: "no answer", args: []string{"delete-context", "shop-prod"}, expectedErr: "run again with --yes"},
+		{name: "confirmed", args: []string{"delete-context", "shop-prod"}, answer: "y\n", deleted: true},
+		{name: "yes", args: []string{"delete-context", "shop-prod", "--yes"}, deleted: true},
+		{name: "not protected", args: []string{"delete-context", "other-context"}},
+		{name: "set-namespace refused", args: []string{"set-namespace", "web", "shop-prod"}, answer: "n\n", expectedErr: "was not confirmed for context \"shop-prod\""},
+		{name: "set-namespace not protected", args: []string{"set-namespace", "web", "other-context"}},
+		{name: "readonly off refused", args: []string{"readonly", "shop-prod", "off"}, expectedErr: "run again with --yes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := newRedFederalCowHammerConfig()
+			config.Contexts["shop-prod"] = &clientcmdapi.Context{AuthInfo: "red-user", Cluster: "cow-cluster"}
+			config.Contexts["other-context"] = &clientcmdapi.Context{AuthInfo: "red-user", Cluster: "cow-cluster"}
+			if err := clientcmd.WriteToFile(config, kubeconfigFile); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			streams, in, _, errOut := genericclioptions.NewTestIOStreams()
+			in.WriteString(tt.answer)
+			cmd := NewCmdConfig(cmdutil.NewFactory(genericclioptions.NewTestConfigFlags()), clientcmd.NewDefaultPathOptions(), streams)
+			cmd.SetOutput(ioutil.Discard)
+			cmd.SetArgs(append([]string{"-f", kubeconfigFile}, tt.args...))
+			err := cmd.Execute()
+			if len(tt.expectedErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Errorf("expected an error containing %q, got %v", tt.expectedErr, err)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			asked := strings.Contains(errOut.String(), `Context "shop-prod" is named like *prod* and would be changed`)
+			if expected := containsString(tt.args, "shop-prod") && tt.name != "yes"; asked != expected {
+				t.Errorf("expected to be asked for confirmation: %v, got %q", expected, errOut.String())
+			}
+			after, err := clientcmd.LoadFromFile(kubeconfigFile)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, kept := after.Contexts["shop-prod"]; kept == tt.deleted {
+				t.Errorf("expected shop-prod to be deleted: %v, got %v", tt.deleted, after.Contexts)
+			}
+		})
+	}
+}
+
+func TestConfirmNever(t *testing.T) {
+	dir, err := ioutil.TempDir("", "confirm")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "config.yaml")
+	if err := saveSettings(filename, &Settings{SafeMode: true, Confirm: confirmNever}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	config := newRedFederalCowHammerConfig()
+	config.Contexts["shop-prod"] = &clientcmdapi.Context{AuthInfo: "red-user", Cluster: "cow-cluster"}
+	configFile := filepath.Join(dir, "kubeconfig")
+	if err := clientcmd.WriteToFile(config, configFile); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.LoadingRules.ExplicitPath = configFile
+
+	cmd := confirmCommand(&cobra.Command{Use: "delete-context"}, confirmContext)
+	out := &bytes.Buffer{}
+	if err := confirmChange(cmd, []string{"shop-prod"}, pathOptions, filename, &bytes.Buffer{}, out); err != nil || out.Len() > 0 {
+		t.Errorf("expected confirm=never to keep safe mode from asking, got %v, %q", err, out.String())
+	}
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// kubectlFlags is stored in a context's kubectlFlagsExtension.
+type kubectlFlags struct {
+	Flags []string `json:"flags,omitempty"`
+}
+
+// ContextFlagsOptions holds the command-line options for 'config flags' sub command
+type ContextFlagsOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Context      string
+	Flags        []string
+	Clear        bool
+
+	genericclioptions.IOStreams
+}
+
+var (
+	contextFlagsLong = templates.LongDesc(`
+		Show or set the kubectl flags added to every command run against a context with
+		"kubectl config exec".
+
+		The flags are global kubectl flags, such as --request-timeout, --as or --cluster, and give
+		each cluster its own defaults: a longer timeout for a distant cluster, impersonating a
+		read-only user in production. They follow the context name after "--" and replace the flags
+		the context had. A flag given on the command line of "kubectl config exec" wins over the
+		default of the same name. --context and --kubeconfig cannot be set, they are chosen by
+		"kubectl config exec" itself.
+
+		Without flags, the flags of the context are printed, one per line.`)
+
+	contextFlagsExample = templates.Examples(`
+		# Give kubectl commands run against prod a longer timeout and a read-only identity
+		kubectl config flags prod -- --request-timeout=2m --as=viewer
+
+		# Show the flags of the current-context
+		kubectl config flags .
+
+		# Remove the flags of prod
+		kubectl config flags prod --clear`)
+)
+
+// NewCmdConfigFlags returns a Command instance for 'config flags' sub command
+func NewCmdConfigFlags(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &ContextFlagsOptions{ConfigAccess: configAccess, IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "flags CONTEXT_NAME [--clear] [-- FLAG...]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Show or set the default kubectl flags of a context"),
+		Long:                  contextFlagsLong,
+		Example:               contextFlagsExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	cmd.Flags().BoolVar(&o.Clear, "clear", o.Clear, "Remove the flags of the context")
+	return cmd
+}
+
+// Complete sets the context and the flags from the arguments
+func (o *ContextFlagsOptions) Complete(cmd *cobra.Command, args []string) error {
+	dash := cmd.ArgsLenAtDash()
+	if len(args) == 0 || dash == 0 || (dash < 0 && len(args) != 1) || dash > 1 {
+		return helpErrorf(cmd, "Unexpected args: %v", args)
+	}
+	o.Context = args[0]
+	o.Flags = args[1:]
+	if len(o.Flags) > 0 {
+		return checkWritingArgs(cmd)
+	}
+	return nil
+}
+
+// Validate checks the flags are global kubectl flags and puts them in the form they are stored in
+func (o *ContextFlagsOptions) Validate() error {
+	if o.Clear && len(o.Flags) > 0 {
+		return fmt.Errorf("--clear cannot be combined with flags")
+	}
+	flags, err := normalizeKubectlFlags(o.Flags)
+	if err != nil {
+		return err
+	}
+	o.Flags = flags
+	return nil
+}
+
+// Run prints or sets the flags of the context
+func (o *ContextFlagsOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	name, err := resolveContextName(config, o.Context)
+	if err != nil {
+		return err
+	}
+	context, ok := config.Contexts[name]
+	if !ok {
+		return fmt.Errorf("no context exists with the name: %q", name)
+	}
+
+	if !o.Clear && len(o.Flags) == 0 {
+		flags, err := readKubectlFlags(context)
+		if err != nil {
+			return err
+		}
+		for _, flag := range flags {
+			fmt.Fprintln(o.Out, flag)
+		}
+		return nil
+	}
+
+	if err := writeKubectlFlags(context, o.Flags); err != nil {
+		return err
+	}
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+	if o.Clear {
+		fmt.Fprintf(o.Out, "Removed the kubectl flags of context %q.\n", name)
+	} else {
+		fmt.Fprintf(o.Out, "Set the kubectl flags of context %q to %s.\n", name, strings.Join(o.Flags, " "))
+	}
+	return nil
+}
+
+// readKubectlFlags returns the kubectl flags of a context.
+func readKubectlFlags(context *clientcmdapi.Context) ([]string, error) {
+	flags := kubectlFlags{}
+	_, err := readExtension(context.Extensions, kubectlFlagsExtension, &flags)
+	return flags.Flags, err
+}
+
+// writeKubectlFlags stores the kubectl flags of a context, removing the extension when there are none.
+func writeKubectlFlags(context *clientcmdapi.Context, flags []string) error {
+	if len(flags) == 0 {
+		delete(context.Extensions, kubectlFlagsExtension)
+		return nil
+	}
+	return writeExtension(&context.Extensions, kubectlFlagsExtension, kubectlFlags{Flags: flags})
+}
+
+// normalizeKubectlFlags checks args only holds global kubectl flags, other than --context and
+// --kubeconfig, and returns them in their long form, any value following "=".
+func normalizeKubectlFlags(args []string) ([]string, error) {
+	flags := []string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, takesValue := globalFlag(arg)
+		if !strings.HasPrefix(arg, "-") || len(name) == 0 {
+			return nil, fmt.Errorf("%q is not a global kubectl flag", arg)
+		}
+		if name == "context" || name == clientcmd.RecommendedConfigPathFlag {
+			return nil, fmt.Errorf("--%s cannot be set for a context", name)
+		}
+		if !takesValue {
+			if parts := strings.SplitN(arg, "=", 2); len(parts) == 2 {
+				name += "=" + parts[1]
+			}
+			flags = append(flags, "--"+name)
+			continue
+		}
+		value := ""
+		switch {
+		case flagTakesSeparateValue(arg, takesValue, true):
+			if i+1 == len(args) {
+				return nil, fmt.Errorf("flag needs an argument: %s", arg)
+			}
+			i++
+			value = args[i]
+		case strings.Contains(arg, "="):
+			value = strings.SplitN(arg, "=", 2)[1]
+		default:
+			value = arg[2:]
+		}
+		flags = append(flags, "--"+name+"="+value)
+	}
+	return flags, nil
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func TestNormalizeKubectlFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+		err      string
+	}{
+		{
+			name:     "long and short forms",
+			args:     []string{"--request-timeout", "2m", "--as=viewer", "-nshop", "-v", "4", "--insecure-skip-tls-verify", "--match-server-version=false"},
+			expected: []string{"--request-timeout=2m", "--as=viewer", "--namespace=shop", "--v=4", "--insecure-skip-tls-verify", "--match-server-version=false"},
+		},
+		{
+			name: "not a global flag",
+			args: []string{"--output=yaml"},
+			err:  `"--output=yaml" is not a global kubectl flag`,
+		},
+		{
+			name: "not a flag",
+			args: []string{"get"},
+			err:  `"get" is not a global kubectl flag`,
+		},
+		{
+			name: "context",
+			args: []string{"--context=prod"},
+			err:  "--context cannot be set",
+		},
+		{
+			name: "kubeconfig",
+			args: []string{"--kubeconfig", "/tmp/config"},
+			err:  "--kubeconfig cannot be set",
+		},
+		{
+			name: "missing value",
+			args: []string{"--as"},
+			err:  "flag needs an argument: --as",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags, err := normalizeKubectlFlags(tt.args)
+			if len(tt.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("expected an error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(flags, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, flags)
+			}
+		})
+	}
+}
+
+func TestContextFlags(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flags")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := clientcmd.WriteToFile(newRedFederalCowHammerConfig(), filepath.Join(dir, "config")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = filepath.Join(dir, "config")
+	pathOptions.EnvVar = ""
+
+	run := func(args ...string) (string, error) {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		cmd := NewCmdConfigFlags(streams, pathOptions)
+		if err := cmd.ParseFlags(args); err != nil {
+			return "", err
+		}
+		o := &ContextFlagsOptions{ConfigAccess: pathOptions, IOStreams: streams}
+		o.Clear, _ = cmd.Flags().GetBool("clear")
+		if err := o.Complete(cmd, cmd.Flags().Args()); err != nil {
+			return "", err
+		}
+		if err := o.Validate(); err != nil {
+			return "", err
+		}
+		err := o.Run()
+		return out.String(), err
+	}
+
+	if out, err := run("federal-context", "--", "--request-timeout", "2m", "--as=viewer"); err != nil || out != "Set the kubectl flags of context \"federal-context\" to --request-timeout=2m --as=viewer.\n" {
+		t.Errorf("unexpected output %q, %v", out, err)
+	}
+	if out, err := run("."); err != nil || out != "--request-timeout=2m\n--as=viewer\n" {
+		t.Errorf("expected the flags of the current-context to be printed, got %q, %v", out, err)
+	}
+	if _, err := run("federal-context", "--clear", "--", "--as=viewer"); err == nil {
+		t.Errorf("expected --clear with flags to fail")
+	}
+	if _, err := run("federal-context", "--as=viewer"); err == nil {
+		t.Errorf("expected flags outside of -- to fail")
+	}
+	if _, err := run("missing", "--", "--as=viewer"); err == nil || !strings.Contains(err.Error(), "no context exists") {
+		t.Errorf("expected a missing context to fail, got %v", err)
+	}
+	if out, err := run("federal-context", "--clear"); err != nil || out != "Removed the kubectl flags of context \"federal-context\".\n" {
+		t.Errorf("unexpected output %q, %v", out, err)
+	}
+
+	config, err := clientcmd.LoadFromFile(pathOptions.GlobalFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := config.Contexts["federal-context"].Extensions[kubectlFlagsExtension]; ok {
+		t.Errorf("expected the extension to be removed")
+	}
+}
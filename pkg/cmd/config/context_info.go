@@ -0,0 +1,281 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/printers"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// contextInfo is everything a prompt or an editor shows about a context.
+type contextInfo struct {
+	Context   string `json:"context"`
+	Namespace string `json:"namespace"`
+	Cluster   string `json:"cluster"`
+	User      string `json:"user"`
+	Server    string `json:"server,omitempty"`
+	AuthType  string `json:"authType"`
+	// Expiry is when the client certificate or the token of the user expires, when it is known
+	// without running a plugin.
+	Expiry *time.Time `json:"expiry,omitempty"`
+	// Tuning is the client tuning of the context, see "config tuning".
+	Tuning *clientTuning `json:"tuning,omitempty"`
+}
+
+// contextInfoCache holds the last answer of 'config context-info', valid as long as the
+// kubeconfig files are unchanged.
+type contextInfoCache struct {
+	Stamp   string      `json:"stamp"`
+	Context string      `json:"context"`
+	Info    contextInfo `json:"info"`
+}
+
+// ContextInfoOptions holds the command-line options for 'config context-info' sub command
+type ContextInfoOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Context      string
+	Output       string
+	NoCache      bool
+	// CacheFile is where the last answer is cached.
+	CacheFile string
+	// NoWrite reads the cached answer without updating it, as --no-write requires.
+	NoWrite bool
+
+	genericclioptions.IOStreams
+}
+
+var (
+	contextInfoLong = templates.LongDesc(`
+		Describe a context in one call: its namespace, cluster, user, server, how the user
+		authenticates, when the credentials expire and the client tuning set with "kubectl config
+		tuning".
+
+		The current context is described unless a context is named. The namespace is "default"
+		when the context sets none. The expiry is read from client certificates and from tokens
+		that are JWTs; it is left out for credentials only a plugin knows about.
+
+		The answer is cached and reused until a kubeconfig file changes, so shell prompts and
+		editors can call the command on every refresh.`)
+
+	contextInfoExample = templates.Examples(`
+		# Describe the current context
+		kubectl config context-info
+
+		# Describe a context as JSON, for a prompt or an editor
+		kubectl config context-info prod -o json`)
+)
+
+// NewCmdConfigContextInfo returns a Command instance for 'config context-info' sub command
+func NewCmdConfigContextInfo(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &ContextInfoOptions{
+		ConfigAccess: configAccess,
+		Output:       "text",
+		CacheFile:    filepath.Join(stateDir(), "context-info.json"),
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "context-info [CONTEXT_NAME] [-o text|json]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Describe a context for prompts and editors"),
+		Long:                  contextInfoLong,
+		Example:               contextInfoExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) > 1 {
+				cmdutil.CheckErr(cmdutil.UsageErrorf(cmd, "unexpected arguments: %v", args[1:]))
+			}
+			if len(args) == 1 {
+				o.Context = args[0]
+			}
+			o.NoWrite = writesDisabled(cmd)
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.Output, "output", "o", o.Output, "Output format. One of: text|json")
+	cmd.Flags().BoolVar(&o.NoCache, "no-cache", o.NoCache, "If true, neither read nor update the cached answer")
+	return cmd
+}
+
+// Validate makes sure the output format is supported
+func (o *ContextInfoOptions) Validate() error {
+	if o.Output != "text" && o.Output != "json" {
+		return fmt.Errorf("unsupported output format %q, must be one of: text, json", o.Output)
+	}
+	return nil
+}
+
+// Run performs the execution of 'config context-info' sub command
+func (o *ContextInfoOptions) Run() error {
+	stamp := kubeconfigFilesStamp(o.ConfigAccess)
+	cache := &contextInfoCache{}
+	if !o.NoCache && o.loadCache(cache) && cache.Stamp == stamp && cache.Context == o.Context {
+		return o.print(cache.Info)
+	}
+
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	info, err := describeContext(config, o.Context)
+	if err != nil {
+		return err
+	}
+	if !o.NoCache && !o.NoWrite {
+		// The cache only saves time, failing to write it is no reason to fail.
+		o.saveCache(&contextInfoCache{Stamp: stamp, Context: o.Context, Info: info})
+	}
+	return o.print(info)
+}
+
+func (o *ContextInfoOptions) print(info contextInfo) error {
+	if o.Output == "json" {
+		data, err := json.Marshal(info)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(o.Out, "%s\n", data)
+		return err
+	}
+
+	expiry := "<none>"
+	if info.Expiry != nil {
+		expiry = info.Expiry.UTC().Format(time.RFC3339)
+	}
+	w := printers.GetNewTabWriter(o.Out)
+	fmt.Fprintf(w, "Context:\t%s\n", info.Context)
+	fmt.Fprintf(w, "Namespace:\t%s\n", info.Namespace)
+	fmt.Fprintf(w, "Cluster:\t%s\n", valueOrNone(info.Cluster))
+	fmt.Fprintf(w, "User:\t%s\n", valueOrNone(info.User))
+	fmt.Fprintf(w, "Server:\t%s\n", valueOrNone(info.Server))
+	fmt.Fprintf(w, "Auth type:\t%s\n", info.AuthType)
+	fmt.Fprintf(w, "Expiry:\t%s\n", expiry)
+	if info.Tuning != nil {
+		fmt.Fprintf(w, "Client tuning:\t%s\n", info.Tuning)
+	}
+	return w.Flush()
+}
+
+func (o *ContextInfoOptions) loadCache(cache *contextInfoCache) bool {
+	data, err := ioutil.ReadFile(o.CacheFile)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, cache) == nil
+}
+
+func (o *ContextInfoOptions) saveCache(cache *contextInfoCache) {
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(o.CacheFile), 0700); err != nil {
+		return
+	}
+	ioutil.WriteFile(o.CacheFile, data, 0600)
+}
+
+// describeContext gathers what is known about a context without contacting its cluster. An empty
+// name describes the current context.
+func describeContext(config *clientcmdapi.Config, name string) (contextInfo, error) {
+	if len(name) == 0 {
+		name = currentContextShorthand
+	}
+	name, err := resolveContextName(config, name)
+	if err != nil {
+		return contextInfo{}, err
+	}
+	context, ok := config.Contexts[name]
+	if !ok {
+		return contextInfo{}, fmt.Errorf("no context exists with the name: %q", name)
+	}
+
+	info := contextInfo{
+		Context:   name,
+		Namespace: context.Namespace,
+		Cluster:   context.Cluster,
+		User:      context.AuthInfo,
+		AuthType:  "none",
+	}
+	if len(info.Namespace) == 0 {
+		info.Namespace = "default"
+	}
+	if cluster, ok := config.Clusters[context.Cluster]; ok {
+		info.Server = cluster.Server
+	}
+	if tuning, err := readClientTuning(context); err == nil && tuning != (clientTuning{}) {
+		info.Tuning = &tuning
+	}
+	if authInfo, ok := config.AuthInfos[context.AuthInfo]; ok {
+		info.AuthType = authInfoType(authInfo)
+		if expiry, ok := credentialExpiry(authInfo); ok {
+			info.Expiry = &expiry
+		}
+	}
+	return info, nil
+}
+
+// credentialExpiry returns when the first of the client certificate and the token of a user
+// expires, as far as can be told from the kubeconfig alone.
+func credentialExpiry(authInfo *clientcmdapi.AuthInfo) (time.Time, bool) {
+	expiries := []time.Time{}
+	certificate := authInfo.ClientCertificateData
+	if len(certificate) == 0 && len(authInfo.ClientCertificate) > 0 {
+		certificate, _ = ioutil.ReadFile(authInfo.ClientCertificate)
+	}
+	if len(certificate) > 0 {
+		if expiry, err := certificateExpiry(certificate); err == nil {
+			expiries = append(expiries, expiry)
+		}
+	}
+	tokens := []string{authInfo.Token}
+	if authInfo.AuthProvider != nil {
+		tokens = append(tokens, authInfo.AuthProvider.Config["id-token"])
+	}
+	for _, token := range tokens {
+		if expiry, ok := tokenExpiry(token); ok {
+			expiries = append(expiries, expiry)
+		}
+	}
+
+	if len(expiries) == 0 {
+		return time.Time{}, false
+	}
+	earliest := expiries[0]
+	for _, expiry := range expiries[1:] {
+		if expiry.Before(earliest) {
+			earliest = expiry
+		}
+	}
+	return earliest, true
+}
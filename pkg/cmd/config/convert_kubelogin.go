@@ -0,0 +1,326 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// kubeloginFlag is a flag of "kubelogin get-token" a login mode uses. EnvVar names the variable
+// kubelogin reads the value from when the flag is not given.
+type kubeloginFlag struct {
+	Name     string
+	Required bool
+	EnvVar   string
+}
+
+// kubeloginModes lists the flags each login mode of kubelogin uses, in the order they are written.
+var kubeloginModes = map[string][]kubeloginFlag{
+	"devicecode": {
+		{Name: "server-id", Required: true},
+		{Name: "client-id", Required: true},
+		{Name: "tenant-id", Required: true},
+		{Name: "environment"},
+		{Name: "legacy"},
+	},
+	"azurecli": {
+		{Name: "server-id", Required: true},
+		{Name: "tenant-id"},
+	},
+	"workloadidentity": {
+		{Name: "server-id", Required: true},
+		{Name: "client-id", Required: true, EnvVar: "AZURE_CLIENT_ID"},
+		{Name: "tenant-id", Required: true, EnvVar: "AZURE_TENANT_ID"},
+		{Name: "federated-token-file", Required: true, EnvVar: "AZURE_FEDERATED_TOKEN_FILE"},
+		{Name: "authority-host", Required: true, EnvVar: "AZURE_AUTHORITY_HOST"},
+	},
+	"msi": {
+		{Name: "server-id", Required: true},
+		{Name: "client-id"},
+	},
+}
+
+// kubeloginValueFlags are the flags of "kubelogin get-token" taking a value; any of them a mode
+// does not use is dropped when converting to it. Other flags, such as --token-cache-dir, are kept.
+var kubeloginValueFlags = map[string]bool{
+	"authority-host":       true,
+	"client-certificate":   true,
+	"client-id":            true,
+	"client-secret":        true,
+	"environment":          true,
+	"federated-token-file": true,
+	"login":                true,
+	"password":             true,
+	"server-id":            true,
+	"tenant-id":            true,
+	"username":             true,
+}
+
+// ConvertKubeloginOptions holds the command-line options for 'config convert-kubelogin' sub command
+type ConvertKubeloginOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Users        []string
+	Login        string
+	// Values of kubelogin flags to set, such as the client-id a mode needs.
+	Values map[string]string
+	DryRun bool
+
+	log *cmdLogger
+
+	genericclioptions.IOStreams
+}
+
+var (
+	convertKubeloginLong = templates.LongDesc(`
+		Switch AKS users between the login modes of kubelogin.
+
+		Users running "kubelogin get-token" are rewritten to use the login mode given with
+		--login: devicecode, azurecli, workloadidentity or msi. Flags the mode does not use are
+		dropped, values it needs are taken from the existing arguments or from --client-id,
+		--tenant-id, --server-id, --federated-token-file and --authority-host, and the conversion
+		fails when a required value is missing.
+
+		workloadidentity reads the client and tenant ids, the federated token file and the
+		authority host from AZURE_CLIENT_ID, AZURE_TENANT_ID, AZURE_FEDERATED_TOKEN_FILE and
+		AZURE_AUTHORITY_HOST when they are not given as flags. The conversion accepts these
+		variables in the env of the exec entry; otherwise it warns that the environment kubectl
+		runs in, such as a pod using workload identity, must provide them.
+
+		When no USER_NAME is given every user running kubelogin is converted.`)
+
+	convertKubeloginExample = templates.Examples(`
+		# Use the Azure CLI login for every AKS user
+		kubectl config convert-kubelogin --login azurecli
+
+		# Show how the "aks-ci" user would change for workload identity
+		kubectl config convert-kubelogin aks-ci --login workloadidentity --dry-run`)
+)
+
+// NewCmdConfigConvertKubelogin returns a Command instance for 'config convert-kubelogin' sub command
+func NewCmdConfigConvertKubelogin(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &ConvertKubeloginOptions{
+		ConfigAccess: configAccess,
+		Values:       map[string]string{},
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "convert-kubelogin [USER_NAME...] --login=devicecode|azurecli|workloadidentity|msi [--dry-run]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Switch AKS users between kubelogin login modes"),
+		Long:                  convertKubeloginLong,
+		Example:               convertKubeloginExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().StringVar(&o.Login, "login", o.Login, "Login mode to convert to. One of: devicecode|azurecli|workloadidentity|msi")
+	for _, name := range []string{"server-id", "client-id", "tenant-id", "federated-token-file", "authority-host"} {
+		cmd.Flags().String(name, "", fmt.Sprintf("Value of kubelogin's --%s to set", name))
+	}
+	cmd.Flags().BoolVar(&o.DryRun, "dry-run", o.DryRun, "If true, only print the changes that would be made")
+	return cmd
+}
+
+// Complete assigns the users and the kubelogin values given as flags
+func (o *ConvertKubeloginOptions) Complete(cmd *cobra.Command, args []string) error {
+	o.Users = args
+	for _, name := range []string{"server-id", "client-id", "tenant-id", "federated-token-file", "authority-host"} {
+		if value := cmdutil.GetFlagString(cmd, name); len(value) > 0 {
+			o.Values[name] = value
+		}
+	}
+	var err error
+	o.log, err = newCmdLogger(cmd, o.ErrOut)
+	return err
+}
+
+// Validate checks the login mode
+func (o *ConvertKubeloginOptions) Validate() error {
+	if _, ok := kubeloginModes[o.Login]; !ok {
+		return fmt.Errorf("--login must be one of devicecode, azurecli, workloadidentity or msi, got %q", o.Login)
+	}
+	return nil
+}
+
+// Run performs the execution of 'config convert-kubelogin' sub command
+func (o *ConvertKubeloginOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+
+	names := o.Users
+	if len(names) == 0 {
+		for name, authInfo := range config.AuthInfos {
+			if isKubeloginExec(authInfo.Exec) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+	}
+
+	allErrs := []error{}
+	converted := 0
+	for _, name := range names {
+		authInfo, exists := config.AuthInfos[name]
+		if !exists {
+			allErrs = append(allErrs, fmt.Errorf("user %q not found", name))
+			continue
+		}
+		if !isKubeloginExec(authInfo.Exec) {
+			allErrs = append(allErrs, fmt.Errorf("user %q does not run kubelogin get-token", name))
+			continue
+		}
+		exec, err := o.convert(name, authInfo.Exec)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("cannot convert user %q: %v", name, err))
+			continue
+		}
+
+		modified := *authInfo
+		modified.Exec = exec
+		if o.DryRun {
+			from, _ := parseKubeloginArgs(authInfo.Exec.Args[1:])
+			fromLabel := fmt.Sprintf("users/%s (login %s)", name, valueOrNone(from["login"]))
+			toLabel := fmt.Sprintf("users/%s (login %s)", name, o.Login)
+			if err := writeAuthInfoDiff(o.Out, fromLabel, toLabel, name, authInfo, &modified); err != nil {
+				return err
+			}
+		} else {
+			fmt.Fprintf(o.Out, "User %q converted to kubelogin login mode %q.\n", name, o.Login)
+		}
+		config.AuthInfos[name] = &modified
+		converted++
+	}
+
+	if converted > 0 && !o.DryRun {
+		if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+			return err
+		}
+	}
+	if converted == 0 && len(allErrs) == 0 {
+		fmt.Fprintln(o.Out, "No users run kubelogin.")
+	}
+	return utilerrors.NewAggregate(allErrs)
+}
+
+// convert returns a copy of exec running kubelogin in the login mode of the options.
+func (o *ConvertKubeloginOptions) convert(name string, exec *clientcmdapi.ExecConfig) (*clientcmdapi.ExecConfig, error) {
+	values, others := parseKubeloginArgs(exec.Args[1:])
+	for flag, value := range o.Values {
+		values[flag] = value
+	}
+
+	args := []string{"get-token", "--login", o.Login}
+	used := map[string]bool{"login": true}
+	missing := []string{}
+	for _, flag := range kubeloginModes[o.Login] {
+		used[flag.Name] = true
+		value, ok := values[flag.Name]
+		switch {
+		case ok && flag.Name == "legacy":
+			args = append(args, "--legacy")
+		case ok:
+			args = append(args, "--"+flag.Name, value)
+		case !flag.Required:
+		case len(flag.EnvVar) > 0 && hasExecEnv(exec, flag.EnvVar):
+		case len(flag.EnvVar) > 0:
+			o.log.Warningf("user %q: kubelogin reads --%s from $%s, which the environment kubectl runs in must set", name, flag.Name, flag.EnvVar)
+		default:
+			missing = append(missing, "--"+flag.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("login mode %s needs %s", o.Login, strings.Join(missing, ", "))
+	}
+	for flag := range values {
+		if !used[flag] {
+			o.log.Infof(1, "user %q: dropped --%s, which login mode %s does not use", name, flag, o.Login)
+		}
+	}
+
+	converted := exec.DeepCopy()
+	converted.Args = append(args, others...)
+	return converted, nil
+}
+
+// isKubeloginExec reports whether exec runs "kubelogin get-token".
+func isKubeloginExec(exec *clientcmdapi.ExecConfig) bool {
+	if exec == nil || len(exec.Args) == 0 || exec.Args[0] != "get-token" {
+		return false
+	}
+	return strings.TrimSuffix(filepath.Base(exec.Command), ".exe") == "kubelogin"
+}
+
+func hasExecEnv(exec *clientcmdapi.ExecConfig, name string) bool {
+	for _, env := range exec.Env {
+		if env.Name == name && len(env.Value) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// parseKubeloginArgs splits the arguments of "kubelogin get-token" into the values of the flags
+// login modes use, with "" for --legacy, and all other arguments.
+func parseKubeloginArgs(args []string) (map[string]string, []string) {
+	values := map[string]string{}
+	others := []string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name := strings.TrimLeft(arg, "-")
+		if arg == "-l" {
+			name = "login"
+		}
+		value, hasValue := "", false
+		if parts := strings.SplitN(name, "=", 2); len(parts) == 2 {
+			name, value, hasValue = parts[0], parts[1], true
+		}
+		switch {
+		case !strings.HasPrefix(arg, "-"):
+			others = append(others, arg)
+		case name == "legacy":
+			values[name] = ""
+		case kubeloginValueFlags[name] && hasValue:
+			values[name] = value
+		case kubeloginValueFlags[name] && i+1 < len(args):
+			i++
+			values[name] = args[i]
+		default:
+			others = append(others, arg)
+		}
+	}
+	return values, others
+}
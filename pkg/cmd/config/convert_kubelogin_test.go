@@ -0,0 +1,177 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestConvertKubelogin(t *testing.T) {
+	devicecode := &clientcmdapi.ExecConfig{
+		Command: "kubelogin",
+		Args: []string{
+			"get-token", "--login", "devicecode", "--environment", "AzurePublicCloud",
+			"--server-id", "server", "--client-id", "client", "--tenant-id", "tenant", "--legacy",
+			"--token-cache-dir", "/tmp/cache",
+		},
+		APIVersion: "client.authentication.k8s.io/v1beta1",
+	}
+
+	tests := []struct {
+		name         string
+		login        string
+		exec         *clientcmdapi.ExecConfig
+		values       map[string]string
+		expectedArgs []string
+		expectedErr  string
+		expectedWarn string
+	}{
+		{
+			name:  "devicecode to azurecli",
+			login: "azurecli",
+			exec:  devicecode,
+			expectedArgs: []string{
+				"get-token", "--login", "azurecli", "--server-id", "server", "--tenant-id", "tenant",
+				"--token-cache-dir", "/tmp/cache",
+			},
+		},
+		{
+			name:  "devicecode to msi",
+			login: "msi",
+			exec:  devicecode,
+			expectedArgs: []string{
+				"get-token", "--login", "msi", "--server-id", "server", "--client-id", "client",
+				"--token-cache-dir", "/tmp/cache",
+			},
+		},
+		{
+			name:  "azurecli to devicecode",
+			login: "devicecode",
+			exec: &clientcmdapi.ExecConfig{
+				Command: "/usr/local/bin/kubelogin",
+				Args:    []string{"get-token", "-l", "azurecli", "--server-id=server"},
+			},
+			values: map[string]string{"client-id": "client", "tenant-id": "tenant"},
+			expectedArgs: []string{
+				"get-token", "--login", "devicecode", "--server-id", "server", "--client-id", "client", "--tenant-id", "tenant",
+			},
+		},
+		{
+			name:        "azurecli to devicecode without a client",
+			login:       "devicecode",
+			exec:        &clientcmdapi.ExecConfig{Command: "kubelogin", Args: []string{"get-token", "--login", "azurecli", "--server-id", "server"}},
+			expectedErr: "login mode devicecode needs --client-id, --tenant-id",
+		},
+		{
+			name:  "devicecode to workloadidentity",
+			login: "workloadidentity",
+			exec: &clientcmdapi.ExecConfig{
+				Command: "kubelogin",
+				Args:    devicecode.Args,
+				Env:     []clientcmdapi.ExecEnvVar{{Name: "AZURE_FEDERATED_TOKEN_FILE", Value: "/var/run/secrets/token"}},
+			},
+			expectedArgs: []string{
+				"get-token", "--login", "workloadidentity", "--server-id", "server", "--client-id", "client", "--tenant-id", "tenant",
+				"--token-cache-dir", "/tmp/cache",
+			},
+			expectedWarn: "from $AZURE_AUTHORITY_HOST",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			streams, _, _, errOut := genericclioptions.NewTestIOStreams()
+			o := &ConvertKubeloginOptions{Login: test.login, Values: map[string]string{}, IOStreams: streams}
+			for name, value := range test.values {
+				o.Values[name] = value
+			}
+			var err error
+			if o.log, err = newCmdLogger(nil, errOut); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			exec, err := o.convert("aks-user", test.exec)
+			if len(test.expectedErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(exec.Args, test.expectedArgs) {
+				t.Errorf("expected %q\nbut got %q", test.expectedArgs, exec.Args)
+			}
+			if exec.Command != test.exec.Command || !reflect.DeepEqual(exec.Env, test.exec.Env) {
+				t.Errorf("expected the command and env to be kept, got %#v", exec)
+			}
+			if !strings.Contains(errOut.String(), test.expectedWarn) {
+				t.Errorf("expected a warning containing %q, got %q", test.expectedWarn, errOut.String())
+			}
+		})
+	}
+}
+
+func TestConvertKubeloginRun(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	startingConfig := clientcmdapi.Config{
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{
+			"aks-user":   {Exec: &clientcmdapi.ExecConfig{Command: "kubelogin", Args: []string{"get-token", "--login", "azurecli", "--server-id", "server"}}},
+			"token-user": {Token: "token"},
+		},
+	}
+	if err := clientcmd.WriteToFile(startingConfig, fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	streams, _, out, errOut := genericclioptions.NewTestIOStreams()
+	o := &ConvertKubeloginOptions{ConfigAccess: pathOptions, Login: "msi", Values: map[string]string{}, IOStreams: streams}
+	if o.log, err = newCmdLogger(nil, errOut); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := `User "aks-user" converted to kubelogin login mode "msi".` + "\n"; out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+	config, err := clientcmd.LoadFromFile(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if args := config.AuthInfos["aks-user"].Exec.Args; !reflect.DeepEqual(args, []string{"get-token", "--login", "msi", "--server-id", "server"}) {
+		t.Errorf("unexpected args %q", args)
+	}
+	if config.AuthInfos["token-user"].Token != "token" {
+		t.Errorf("expected token-user to be untouched, got %#v", config.AuthInfos["token-user"])
+	}
+}
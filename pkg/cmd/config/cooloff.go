@@ -0,0 +1,296 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// defaultCooloffTag is the tag the cooloff applies to when the settings name none.
+const defaultCooloffTag = "prod"
+
+// cooloffState records when contexts were last acknowledged. It is local state, not kept in
+// kubeconfig, so that every machine asks on its own.
+type cooloffState struct {
+	Acknowledged map[string]time.Time `json:"acknowledged,omitempty"`
+}
+
+// cooloffPolicy makes switching to contexts carrying one of Tags, or named like one of Patterns,
+// require an acknowledgement, which lasts for Period.
+type cooloffPolicy struct {
+	Period   time.Duration
+	Tags     []string
+	Patterns []string
+	// Filename is where the acknowledgements are recorded.
+	Filename string
+	Now      func() time.Time
+}
+
+// newCooloffPolicy returns the policy the settings configure, or nil when they configure none.
+func newCooloffPolicy(settings *Settings) (*cooloffPolicy, error) {
+	settings = withSafeMode(settings)
+	if len(settings.Cooloff) == 0 {
+		return nil, nil
+	}
+	period, err := time.ParseDuration(settings.Cooloff)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cooloff setting: %v", err)
+	}
+	tags := settings.CooloffTags
+	if len(tags) == 0 {
+		tags = []string{defaultCooloffTag}
+	}
+	policy := &cooloffPolicy{
+		Period:   period,
+		Tags:     tags,
+		Filename: filepath.Join(stateDir(), "cooloff.yaml"),
+		Now:      time.Now,
+	}
+	// Safe mode also protects the contexts named like production.
+	if settings.SafeMode {
+		policy.Patterns = settings.ProtectedPatterns
+	}
+	return policy, nil
+}
+
+// reason tells why the policy applies to the context called name, such as "tagged prod", and is
+// empty when it does not apply.
+func (p *cooloffPolicy) reason(name string, context *clientcmdapi.Context) (string, error) {
+	metadata, err := readContextMetadata(context)
+	if err != nil {
+		return "", err
+	}
+	for _, tag := range metadata.Tags {
+		if containsString(p.Tags, tag) {
+			return "tagged " + tag, nil
+		}
+	}
+	for _, pattern := range p.Patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return "named like " + pattern, nil
+		}
+	}
+	return "", nil
+}
+
+// check returns an error when the context needs an acknowledgement that has expired or was never
+// given. With acknowledge set, it records a new acknowledgement instead.
+func (p *cooloffPolicy) check(config *clientcmdapi.Config, name string, acknowledge bool) error {
+	context, ok := config.Contexts[name]
+	if !ok {
+		return nil
+	}
+	reason, err := p.reason(name, context)
+	if err != nil || len(reason) == 0 {
+		return err
+	}
+
+	state, err := p.load()
+	if err != nil {
+		return err
+	}
+	now := p.Now()
+	if acknowledge {
+		if state.Acknowledged == nil {
+			state.Acknowledged = map[string]time.Time{}
+		}
+		state.Acknowledged[name] = now
+		return p.save(state)
+	}
+	if last, ok := state.Acknowledged[name]; ok && now.Before(last.Add(p.Period)) {
+		return nil
+	}
+	return fmt.Errorf("context %q is %s and must be acknowledged every %s, run again with --acknowledge or run: kubectl config acknowledge %s", name, reason, p.Period, name)
+}
+
+// expires returns when the acknowledgement of a context runs out, which is the zero time when it
+// has none.
+func (p *cooloffPolicy) expires(name string) (time.Time, error) {
+	state, err := p.load()
+	if err != nil {
+		return time.Time{}, err
+	}
+	last, ok := state.Acknowledged[name]
+	if !ok {
+		return time.Time{}, nil
+	}
+	return last.Add(p.Period), nil
+}
+
+func (p *cooloffPolicy) load() (*cooloffState, error) {
+	state := &cooloffState{}
+	data, err := ioutil.ReadFile(p.Filename)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("error loading %s: %v", p.Filename, err)
+	}
+	return state, nil
+}
+
+func (p *cooloffPolicy) save(state *cooloffState) error {
+	// Acknowledgements that ran out are of no further use.
+	for name, last := range state.Acknowledged {
+		if !p.Now().Before(last.Add(p.Period)) {
+			delete(state.Acknowledged, name)
+		}
+	}
+	data, err := yaml.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p.Filename), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(p.Filename, data, 0600)
+}
+
+// AcknowledgeOptions holds the command-line options for 'config acknowledge' sub command
+type AcknowledgeOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Context      string
+	Check        bool
+	Cooloff      *cooloffPolicy
+
+	genericclioptions.IOStreams
+}
+
+var (
+	acknowledgeLong = templates.LongDesc(`
+		Acknowledge working in a context the cooloff setting applies to.
+
+		With the cooloff setting, switching to a context tagged with one of cooloffTags, prod by
+		default, is refused unless the context was acknowledged within the cooloff period. With
+		the safeMode setting, so is switching to a context named like one of protectedPatterns.
+		use-context and "session use" take --acknowledge; this command acknowledges a context, the
+		current one by default, without switching to it.
+
+		With --check, nothing is recorded and the command fails when the context needs to be
+		acknowledged again, which lets wrappers running commands against a cluster ask first.`)
+
+	acknowledgeExample = templates.Examples(`
+		# Require production contexts to be acknowledged every 30 minutes
+		kubectl config settings set cooloff 30m
+
+		# Acknowledge the current context
+		kubectl config acknowledge
+
+		# Fail if the current context needs to be acknowledged again
+		kubectl config acknowledge --check`)
+)
+
+// NewCmdConfigAcknowledge returns a Command instance for 'config acknowledge' sub command
+func NewCmdConfigAcknowledge(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &AcknowledgeOptions{ConfigAccess: configAccess, IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "acknowledge [CONTEXT] [--check]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Acknowledge a context the cooloff setting applies to"),
+		Long:                  acknowledgeLong,
+		Example:               acknowledgeExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(cmd, args))
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	cmd.Flags().BoolVar(&o.Check, "check", o.Check, "Fail if the context needs to be acknowledged again instead of acknowledging it")
+	return cmd
+}
+
+// Complete reads the context name and the cooloff settings
+func (o *AcknowledgeOptions) Complete(cmd *cobra.Command, args []string) error {
+	if len(args) > 1 {
+		return helpErrorf(cmd, "Unexpected args: %v", args)
+	}
+	if len(args) == 1 {
+		o.Context = args[0]
+	}
+	if o.Cooloff != nil {
+		return nil
+	}
+	settings, err := loadSettings(settingsFile())
+	if err != nil {
+		return err
+	}
+	o.Cooloff, err = newCooloffPolicy(settings)
+	return err
+}
+
+// Run records or checks the acknowledgement of the context
+func (o *AcknowledgeOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	name := config.CurrentContext
+	if len(o.Context) > 0 {
+		if name, err = resolveContextName(config, o.Context); err != nil {
+			return err
+		}
+	}
+	context, ok := config.Contexts[name]
+	if !ok {
+		return fmt.Errorf("no context exists with the name: %q", name)
+	}
+
+	reason := ""
+	if o.Cooloff != nil {
+		if reason, err = o.Cooloff.reason(name, context); err != nil {
+			return err
+		}
+	}
+	if len(reason) == 0 {
+		if !o.Check {
+			fmt.Fprintf(o.Out, "Context %q does not need to be acknowledged.\n", name)
+		}
+		return nil
+	}
+
+	if err := o.Cooloff.check(config, name, !o.Check); err != nil {
+		return err
+	}
+	expires, err := o.Cooloff.expires(name)
+	if err != nil {
+		return err
+	}
+	if o.Check {
+		fmt.Fprintf(o.Out, "Context %q is acknowledged until %s.\n", name, expires.Format("2006-01-02 15:04"))
+	} else {
+		fmt.Fprintf(o.Out, "Acknowledged context %q until %s.\n", name, expires.Format("2006-01-02 15:04"))
+	}
+	return nil
+}
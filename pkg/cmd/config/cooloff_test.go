@@ -0,0 +1,121 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestCooloffPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cooloff")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := newRedFederalCowHammerConfig()
+	config.Contexts["prod-context"] = &clientcmdapi.Context{AuthInfo: "red-user", Cluster: "cow-cluster"}
+	if err := writeExtension(&config.Contexts["prod-context"].Extensions, contextMetadataExtension, contextMetadata{Tags: []string{"eu", "prod"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	policy, err := newCooloffPolicy(&Settings{Cooloff: "30m"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := time.Date(2019, 8, 1, 12, 0, 0, 0, time.UTC)
+	policy.Filename = filepath.Join(dir, "cooloff.yaml")
+	policy.Now = func() time.Time { return now }
+
+	if err := policy.check(&config, "federal-context", false); err != nil {
+		t.Errorf("expected an untagged context not to need acknowledging, got %v", err)
+	}
+	if err := policy.check(&config, "prod-context", false); err == nil || !strings.Contains(err.Error(), "--acknowledge") {
+		t.Errorf("expected a context never acknowledged to be refused, got %v", err)
+	}
+	if err := policy.check(&config, "prod-context", true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now = now.Add(29 * time.Minute)
+	if err := policy.check(&config, "prod-context", false); err != nil {
+		t.Errorf("expected the acknowledgement to last 30m, got %v", err)
+	}
+	now = now.Add(time.Minute)
+	if err := policy.check(&config, "prod-context", false); err == nil {
+		t.Errorf("expected the acknowledgement to run out after 30m")
+	}
+
+	if policy, err := newCooloffPolicy(&Settings{}); err != nil || policy != nil {
+		t.Errorf("expected no policy without the cooloff setting, got %v, %v", policy, err)
+	}
+}
+
+func TestAcknowledge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cooloff")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := newRedFederalCowHammerConfig()
+	if err := writeExtension(&config.Contexts["federal-context"].Extensions, contextMetadataExtension, contextMetadata{Tags: []string{"critical"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	kubeconfig := filepath.Join(dir, "config")
+	if err := clientcmd.WriteToFile(config, kubeconfig); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = kubeconfig
+	pathOptions.EnvVar = ""
+
+	policy, err := newCooloffPolicy(&Settings{Cooloff: "1h", CooloffTags: []string{"critical"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	policy.Filename = filepath.Join(dir, "cooloff.yaml")
+	policy.Now = func() time.Time { return time.Date(2019, 8, 1, 12, 0, 0, 0, time.UTC) }
+
+	run := func(check bool) (string, error) {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		o := &AcknowledgeOptions{ConfigAccess: pathOptions, Check: check, Cooloff: policy, IOStreams: streams}
+		if err := o.Complete(nil, nil); err != nil {
+			return "", err
+		}
+		err := o.Run()
+		return out.String(), err
+	}
+
+	if _, err := run(true); err == nil {
+		t.Errorf("expected the check to fail before the context is acknowledged")
+	}
+	if out, err := run(false); err != nil || out != `Acknowledged context "federal-context" until 2019-08-01 13:00.`+"\n" {
+		t.Errorf("unexpected result %q, %v", out, err)
+	}
+	if out, err := run(true); err != nil || out != `Context "federal-context" is acknowledged until 2019-08-01 13:00.`+"\n" {
+		t.Errorf("unexpected result %q, %v", out, err)
+	}
+}
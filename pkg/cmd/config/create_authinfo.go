@@ -54,6 +54,15 @@ type CreateAuthInfoOptions struct {
 	ExecArgs        []string
 	ExecEnv         map[string]string
 	ExecEnvToRemove []string
+
+	P12         cliflag.StringFlag
+	P12Password cliflag.StringFlag
+	P12Exec     cliflag.Tristate
+	PKCS11      cliflag.StringFlag
+
+	// p12Certificate and p12Key are read from the P12 bundle by Run.
+	p12Certificate []byte
+	p12Key         []byte
 }
 
 const (
@@ -64,6 +73,15 @@ const (
 	FlagExecAPIVersion = "exec-api-version"
 	FlagExecArg        = "exec-arg"
 	FlagExecEnv        = "exec-env"
+
+	FlagP12         = "p12"
+	FlagP12Password = "p12-password"
+	FlagP12Exec     = "p12-exec"
+	FlagPKCS11      = "pkcs11"
+
+	// defaultExecAPIVersion is used for newly created exec stanzas when --exec-api-version is not given,
+	// since client-go refuses to run an exec plugin without an apiVersion.
+	defaultExecAPIVersion = "client.authentication.k8s.io/v1beta1"
 )
 
 var (
@@ -81,7 +99,33 @@ var (
 		    Basic auth flags:
 			  --%v=basic_user --%v=basic_password
 
-		Bearer token and basic auth are mutually exclusive.`), clientcmd.FlagCertFile, clientcmd.FlagKeyFile, clientcmd.FlagBearerToken, clientcmd.FlagUsername, clientcmd.FlagPassword)
+		    PKCS#12 bundle flags:
+			  --%v=bundle.p12 --%v=password [--%v]
+
+		    PKCS#11 token flags:
+			  --%v='module=path/to/module.so;slot=slot;id=key_id[;cert=certfile]'
+
+		    Exec credential plugin flags:
+			  --%v=command [--%v=api_version] [--%v=arg...] [--%v=key=value...]
+
+		Bearer token and basic auth are mutually exclusive.
+
+		The interactiveMode and provideClusterInfo settings of exec credential plugins cannot be
+		set: the kubeconfig format of this kubectl has no such fields, and drops them when it
+		writes kubeconfig. Its plugins behave as with interactiveMode IfAvailable, getting the
+		terminal when there is one, and provideClusterInfo false.
+
+		The client certificate and key of a PKCS#12 bundle are embedded in the user entry. With
+		--%v, the bundle is read each time the user is used instead, by "kubectl config
+		p12-credential" set as the exec credential plugin of the user, taking the password from
+		the %s environment variable when --%v is not given.
+
+		With --%v, the client certificate and key are held by a PKCS#11 token, such as a PIV smart
+		card or a YubiKey, and the private key never lands in kubeconfig. kubectl cannot use such a
+		user itself: "kubectl config exec" serves the cluster to kubectl through a proxy on the
+		loopback interface, which authenticates with the token by running pkcs11-tool of OpenSC.
+		The certificate is read from the token unless cert is given, and the PIN from the %s
+		environment variable, or asked for on the terminal. An empty --%v removes the token.`), clientcmd.FlagCertFile, clientcmd.FlagKeyFile, clientcmd.FlagBearerToken, clientcmd.FlagUsername, clientcmd.FlagPassword, FlagP12, FlagP12Password, FlagP12Exec, FlagPKCS11, FlagExecCommand, FlagExecAPIVersion, FlagExecArg, FlagExecEnv, FlagP12Exec, p12PasswordEnv, FlagP12Password, FlagPKCS11, pkcs11PINEnv, FlagPKCS11)
 
 	createAuthInfoExample = templates.Examples(`
 		# Set only the "client-key" field on the "cluster-admin"
@@ -91,6 +135,16 @@ var (
 		# Set basic auth for the "cluster-admin" entry
 		kubectl config set-credentials cluster-admin --username=admin --password=uXFGweU9l35qcif
 
+		# Embed the client certificate and key of a PKCS#12 bundle in the "cluster-admin" entry
+		kubectl config set-credentials cluster-admin --p12=~/admin.p12 --p12-password=changeit
+
+		# Read the PKCS#12 bundle each time the "cluster-admin" entry is used
+		kubectl config set-credentials cluster-admin --p12=~/admin.p12 --p12-exec
+
+		# Use the key of the PIV authentication slot of a YubiKey for the "cluster-admin" entry
+		kubectl config set-credentials cluster-admin --pkcs11='module=/usr/lib/libykcs11.so;id=01'
+		kubectl config exec -- get pods
+
 		# Embed client certificate data in the "cluster-admin" entry
 		kubectl config set-credentials cluster-admin --client-certificate=~/.kube/admin.crt --embed-certs=true
 
@@ -104,7 +158,10 @@ var (
 		kubectl config set-credentials cluster-admin --auth-provider=oidc --auth-provider-arg=client-secret-
 
 		# Enable new exec auth plugin for the "cluster-admin" entry
-		kubectl config set-credentials cluster-admin --exec-command=/path/to/the/executable --exec-api-version=client.authentication.k8s.io/v1beta
+		kubectl config set-credentials cluster-admin --exec-command=/path/to/the/executable --exec-api-version=client.authentication.k8s.io/v1beta1
+
+		# Configure the aws CLI as the exec auth plugin for the "eks-admin" entry in one step
+		kubectl config set-credentials eks-admin --exec-command=aws --exec-arg=eks --exec-arg=get-token --exec-arg=--cluster-name --exec-arg=prod --exec-env=AWS_PROFILE=prod
 
 		# Define new exec auth plugin args for the "cluster-admin" entry
 		kubectl config set-credentials cluster-admin --exec-arg=arg1 --exec-arg=arg2
@@ -135,7 +192,11 @@ func newCmdConfigSetAuthInfo(out io.Writer, options *CreateAuthInfoOptions) *cob
 				"[--%v=exec_command] "+
 				"[--%v=exec_api_version] "+
 				"[--%v=arg] "+
-				"[--%v=key=value]",
+				"[--%v=key=value] "+
+				"[--%v=path/to/bundle.p12] "+
+				"[--%v=password] "+
+				"[--%v=true|false] "+
+				"[--%v='module=...;slot=...;id=...']",
 			clientcmd.FlagCertFile,
 			clientcmd.FlagKeyFile,
 			clientcmd.FlagBearerToken,
@@ -147,6 +208,10 @@ func newCmdConfigSetAuthInfo(out io.Writer, options *CreateAuthInfoOptions) *cob
 			FlagExecAPIVersion,
 			FlagExecArg,
 			FlagExecEnv,
+			FlagP12,
+			FlagP12Password,
+			FlagP12Exec,
+			FlagPKCS11,
 		),
 		DisableFlagsInUseLine: true,
 		Short:                 i18n.T("Sets a user entry in kubeconfig"),
@@ -178,6 +243,12 @@ func newCmdConfigSetAuthInfo(out io.Writer, options *CreateAuthInfoOptions) *cob
 	cmd.Flags().StringArray(FlagExecEnv, nil, "'key=value' environment values for the exec credential plugin")
 	f := cmd.Flags().VarPF(&options.EmbedCertData, clientcmd.FlagEmbedCerts, "", "Embed client cert/key for the user entry in kubeconfig")
 	f.NoOptDefVal = "true"
+	cmd.Flags().Var(&options.P12, FlagP12, "Path to a PKCS#12 bundle holding the client certificate and key for the user entry in kubeconfig")
+	cmd.MarkFlagFilename(FlagP12, "p12", "pfx")
+	cmd.Flags().Var(&options.P12Password, FlagP12Password, "Password of the PKCS#12 bundle")
+	p12Exec := cmd.Flags().VarPF(&options.P12Exec, FlagP12Exec, "", "Read the PKCS#12 bundle each time the user entry is used instead of embedding its certificate and key")
+	p12Exec.NoOptDefVal = "true"
+	cmd.Flags().Var(&options.PKCS11, FlagPKCS11, "PKCS#11 token holding the client certificate and key for the user entry in kubeconfig, as 'module=...;slot=...;id=...'")
 
 	return cmd
 }
@@ -188,6 +259,12 @@ func (o CreateAuthInfoOptions) Run() error {
 		return err
 	}
 
+	if o.P12.Provided() && !o.P12Exec.Value() {
+		if o.p12Certificate, o.p12Key, err = readP12Bundle(o.P12.Value(), o.P12Password.Value()); err != nil {
+			return err
+		}
+	}
+
 	config, err := o.ConfigAccess.GetStartingConfig()
 	if err != nil {
 		return err
@@ -197,7 +274,15 @@ func (o CreateAuthInfoOptions) Run() error {
 	if !exists {
 		startingStanza = clientcmdapi.NewAuthInfo()
 	}
+	if startingStanza.Exec == nil && !o.ExecCommand.Provided() && o.execSettingsProvided() {
+		return fmt.Errorf("user %q has no exec credential plugin configured, set one with --%s", o.Name, FlagExecCommand)
+	}
 	authInfo := o.modifyAuthInfo(*startingStanza)
+	if o.PKCS11.Provided() {
+		if err := o.setPKCS11Key(&authInfo); err != nil {
+			return err
+		}
+	}
 	config.AuthInfos[o.Name] = &authInfo
 
 	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
@@ -240,6 +325,21 @@ func (o *CreateAuthInfoOptions) modifyAuthInfo(existingAuthInfo clientcmdapi.Aut
 		}
 	}
 
+	if o.P12.Provided() {
+		if o.P12Exec.Value() {
+			bundle, _ := filepath.Abs(o.P12.Value())
+			modifiedAuthInfo.Exec = p12Exec(bundle, o.P12Password.Value())
+			modifiedAuthInfo.ClientCertificate, modifiedAuthInfo.ClientCertificateData = "", nil
+			modifiedAuthInfo.ClientKey, modifiedAuthInfo.ClientKeyData = "", nil
+		} else {
+			if isP12Exec(modifiedAuthInfo.Exec) {
+				modifiedAuthInfo.Exec = nil
+			}
+			modifiedAuthInfo.ClientCertificate, modifiedAuthInfo.ClientCertificateData = "", o.p12Certificate
+			modifiedAuthInfo.ClientKey, modifiedAuthInfo.ClientKeyData = "", o.p12Key
+		}
+	}
+
 	if o.Token.Provided() {
 		modifiedAuthInfo.Token = o.Token.Value()
 		setToken = len(modifiedAuthInfo.Token) > 0
@@ -282,7 +382,8 @@ func (o *CreateAuthInfoOptions) modifyAuthInfo(existingAuthInfo clientcmdapi.Aut
 		// create new Exec if doesn't exist, otherwise just modify the command
 		if modifiedAuthInfo.Exec == nil {
 			modifiedAuthInfo.Exec = &clientcmdapi.ExecConfig{
-				Command: newExecCommand,
+				Command:    newExecCommand,
+				APIVersion: defaultExecAPIVersion,
 			}
 		} else {
 			modifiedAuthInfo.Exec.Command = newExecCommand
@@ -356,6 +457,27 @@ func (o *CreateAuthInfoOptions) modifyAuthInfo(existingAuthInfo clientcmdapi.Aut
 	return modifiedAuthInfo
 }
 
+// setPKCS11Key sets the PKCS#11 token of --pkcs11 as the one holding the client certificate and
+// key of a user, or removes it when --pkcs11 is empty.
+func (o *CreateAuthInfoOptions) setPKCS11Key(authInfo *clientcmdapi.AuthInfo) error {
+	if len(o.PKCS11.Value()) == 0 {
+		delete(authInfo.Extensions, pkcs11Extension)
+		return nil
+	}
+	key, err := parsePKCS11Key(o.PKCS11.Value())
+	if err != nil {
+		return err
+	}
+	authInfo.ClientCertificate, authInfo.ClientCertificateData = "", nil
+	authInfo.ClientKey, authInfo.ClientKeyData = "", nil
+	return writeExtension(&authInfo.Extensions, pkcs11Extension, key)
+}
+
+// execSettingsProvided reports whether any flag modifying an existing exec stanza was given.
+func (o *CreateAuthInfoOptions) execSettingsProvided() bool {
+	return o.ExecAPIVersion.Provided() || o.ExecArgs != nil || o.ExecEnv != nil || o.ExecEnvToRemove != nil
+}
+
 func (o *CreateAuthInfoOptions) Complete(cmd *cobra.Command, out io.Writer) error {
 	args := cmd.Flags().Args()
 	if len(args) != 1 {
@@ -415,6 +537,27 @@ func (o CreateAuthInfoOptions) validate() error {
 	if len(methods) > 1 {
 		return fmt.Errorf("you cannot specify more than one authentication method at the same time: %v", strings.Join(methods, ", "))
 	}
+	if o.P12.Provided() {
+		if len(o.P12.Value()) == 0 {
+			return fmt.Errorf("you must specify a non-empty --%s", FlagP12)
+		}
+		if o.ClientCertificate.Provided() || o.ClientKey.Provided() {
+			return fmt.Errorf("--%s cannot be combined with --%s or --%s", FlagP12, clientcmd.FlagCertFile, clientcmd.FlagKeyFile)
+		}
+		if o.P12Exec.Value() && (o.ExecCommand.Provided() || o.execSettingsProvided()) {
+			return fmt.Errorf("--%s sets the exec credential plugin, it cannot be combined with the exec flags", FlagP12Exec)
+		}
+	} else if o.P12Password.Provided() || o.P12Exec.Provided() {
+		return fmt.Errorf("--%s and --%s need a --%s bundle", FlagP12Password, FlagP12Exec, FlagP12)
+	}
+	if len(o.PKCS11.Value()) > 0 {
+		if o.ClientCertificate.Provided() || o.ClientKey.Provided() || o.P12.Provided() {
+			return fmt.Errorf("--%s cannot be combined with --%s, --%s or --%s", FlagPKCS11, clientcmd.FlagCertFile, clientcmd.FlagKeyFile, FlagP12)
+		}
+		if _, err := parsePKCS11Key(o.PKCS11.Value()); err != nil {
+			return fmt.Errorf("invalid --%s: %v", FlagPKCS11, err)
+		}
+	}
 	if o.EmbedCertData.Value() {
 		certPath := o.ClientCertificate.Value()
 		keyPath := o.ClientKey.Value()
@@ -21,6 +21,7 @@ import (
 	"io/ioutil"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 
 	"k8s.io/client-go/tools/clientcmd"
@@ -42,15 +43,15 @@ func TestCreateAuthInfoOptions(t *testing.T) {
 		wantCompleteErr bool
 		wantValidateErr bool
 
-		wantOptions *createAuthInfoOptions
+		wantOptions *CreateAuthInfoOptions
 	}{
 		{
 			name: "test1",
 			flags: []string{
 				"me",
 			},
-			wantOptions: &createAuthInfoOptions{
-				name: "me",
+			wantOptions: &CreateAuthInfoOptions{
+				Name: "me",
 			},
 		},
 		{
@@ -59,9 +60,9 @@ func TestCreateAuthInfoOptions(t *testing.T) {
 				"me",
 				"--token=foo",
 			},
-			wantOptions: &createAuthInfoOptions{
-				name:  "me",
-				token: stringFlagFor("foo"),
+			wantOptions: &CreateAuthInfoOptions{
+				Name:  "me",
+				Token: stringFlagFor("foo"),
 			},
 		},
 		{
@@ -71,10 +72,10 @@ func TestCreateAuthInfoOptions(t *testing.T) {
 				"--username=jane",
 				"--password=bar",
 			},
-			wantOptions: &createAuthInfoOptions{
-				name:     "me",
-				username: stringFlagFor("jane"),
-				password: stringFlagFor("bar"),
+			wantOptions: &CreateAuthInfoOptions{
+				Name:     "me",
+				Username: stringFlagFor("jane"),
+				Password: stringFlagFor("bar"),
 			},
 		},
 		{
@@ -96,14 +97,14 @@ func TestCreateAuthInfoOptions(t *testing.T) {
 				"--auth-provider-arg=client-secret=bar",
 				"me",
 			},
-			wantOptions: &createAuthInfoOptions{
-				name:         "me",
-				authProvider: stringFlagFor("oidc"),
-				authProviderArgs: map[string]string{
+			wantOptions: &CreateAuthInfoOptions{
+				Name:         "me",
+				AuthProvider: stringFlagFor("oidc"),
+				AuthProviderArgs: map[string]string{
 					"client-id":     "foo",
 					"client-secret": "bar",
 				},
-				authProviderArgsToRemove: []string{},
+				AuthProviderArgsToRemove: []string{},
 			},
 		},
 		{
@@ -114,11 +115,11 @@ func TestCreateAuthInfoOptions(t *testing.T) {
 				"--auth-provider-arg=client-secret-",
 				"me",
 			},
-			wantOptions: &createAuthInfoOptions{
-				name:             "me",
-				authProvider:     stringFlagFor("oidc"),
-				authProviderArgs: map[string]string{},
-				authProviderArgsToRemove: []string{
+			wantOptions: &CreateAuthInfoOptions{
+				Name:             "me",
+				AuthProvider:     stringFlagFor("oidc"),
+				AuthProviderArgs: map[string]string{},
+				AuthProviderArgsToRemove: []string{
 					"client-id",
 					"client-secret",
 				},
@@ -131,10 +132,10 @@ func TestCreateAuthInfoOptions(t *testing.T) {
 				"--auth-provider-arg=client-secret-",
 				"me",
 			},
-			wantOptions: &createAuthInfoOptions{
-				name:             "me",
-				authProviderArgs: map[string]string{},
-				authProviderArgsToRemove: []string{
+			wantOptions: &CreateAuthInfoOptions{
+				Name:             "me",
+				AuthProviderArgs: map[string]string{},
+				AuthProviderArgsToRemove: []string{
 					"client-id",
 					"client-secret",
 				},
@@ -162,9 +163,9 @@ func TestCreateAuthInfoOptions(t *testing.T) {
 				"--exec-command=example-client-go-exec-plugin",
 				"me",
 			},
-			wantOptions: &createAuthInfoOptions{
-				name:        "me",
-				execCommand: stringFlagFor("example-client-go-exec-plugin"),
+			wantOptions: &CreateAuthInfoOptions{
+				Name:        "me",
+				ExecCommand: stringFlagFor("example-client-go-exec-plugin"),
 			},
 		},
 		{
@@ -175,10 +176,10 @@ func TestCreateAuthInfoOptions(t *testing.T) {
 				"--exec-arg=arg2",
 				"me",
 			},
-			wantOptions: &createAuthInfoOptions{
-				name:        "me",
-				execCommand: stringFlagFor("example-client-go-exec-plugin"),
-				execArgs:    []string{"arg1", "arg2"},
+			wantOptions: &CreateAuthInfoOptions{
+				Name:        "me",
+				ExecCommand: stringFlagFor("example-client-go-exec-plugin"),
+				ExecArgs:    []string{"arg1", "arg2"},
 			},
 		},
 		{
@@ -191,20 +192,32 @@ func TestCreateAuthInfoOptions(t *testing.T) {
 				"--exec-env=env-remove2-",
 				"me",
 			},
-			wantOptions: &createAuthInfoOptions{
-				name:            "me",
-				execCommand:     stringFlagFor("example-client-go-exec-plugin"),
-				execEnv:         map[string]string{"key1": "val1", "key2": "val2"},
-				execEnvToRemove: []string{"env-remove1", "env-remove2"},
+			wantOptions: &CreateAuthInfoOptions{
+				Name:            "me",
+				ExecCommand:     stringFlagFor("example-client-go-exec-plugin"),
+				ExecEnv:         map[string]string{"key1": "val1", "key2": "val2"},
+				ExecEnvToRemove: []string{"env-remove1", "env-remove2"},
 			},
 		},
+		{
+			name: "test13",
+			// interactiveMode and provideClusterInfo are not in the exec stanza of this client-go,
+			// so there are no flags for them rather than flags whose values would be dropped.
+			flags: []string{
+				"--exec-command=example-client-go-exec-plugin",
+				"--exec-interactive-mode=Never",
+				"--exec-provide-cluster-info",
+				"me",
+			},
+			wantParseErr: true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			buff := new(bytes.Buffer)
 
-			opts := new(createAuthInfoOptions)
+			opts := new(CreateAuthInfoOptions)
 			cmd := newCmdConfigSetAuthInfo(buff, opts)
 			if err := cmd.ParseFlags(tt.flags); err != nil {
 				if !tt.wantParseErr {
@@ -217,7 +230,7 @@ func TestCreateAuthInfoOptions(t *testing.T) {
 				return
 			}
 
-			if err := opts.complete(cmd, buff); err != nil {
+			if err := opts.Complete(cmd, buff); err != nil {
 				if !tt.wantCompleteErr {
 					t.Errorf("case %s: complete() error for flags %q: %s", tt.name, tt.flags, buff)
 				}
@@ -273,6 +286,27 @@ func TestModifyExistingAuthInfo(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "1a. create new exec config with the default api version",
+			flags: []string{
+				"--exec-command=aws",
+				"--exec-arg=eks",
+				"--exec-arg=get-token",
+				"--exec-env=AWS_PROFILE=prod",
+				"me",
+			},
+			existingAuthInfo: clientcmdapi.AuthInfo{},
+			wantAuthInfo: clientcmdapi.AuthInfo{
+				Exec: &clientcmdapi.ExecConfig{
+					Command:    "aws",
+					APIVersion: "client.authentication.k8s.io/v1beta1",
+					Args:       []string{"eks", "get-token"},
+					Env: []clientcmdapi.ExecEnvVar{
+						{Name: "AWS_PROFILE", Value: "prod"},
+					},
+				},
+			},
+		},
 		{
 			name: "2. redefine exec args",
 			flags: []string{
@@ -381,7 +415,7 @@ func TestModifyExistingAuthInfo(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			buff := new(bytes.Buffer)
 
-			opts := new(createAuthInfoOptions)
+			opts := new(CreateAuthInfoOptions)
 			cmd := newCmdConfigSetAuthInfo(buff, opts)
 			if err := cmd.ParseFlags(tt.flags); err != nil {
 				if !tt.wantParseErr {
@@ -394,7 +428,7 @@ func TestModifyExistingAuthInfo(t *testing.T) {
 				return
 			}
 
-			if err := opts.complete(cmd, buff); err != nil {
+			if err := opts.Complete(cmd, buff); err != nil {
 				if !tt.wantCompleteErr {
 					t.Errorf("case %s: complete() error for flags %q: %s", tt.name, tt.flags, buff)
 				}
@@ -453,6 +487,32 @@ func TestCreateAuthInfo(t *testing.T) {
 	}
 	test.run(t)
 }
+
+func TestCreateAuthInfoExecArgsWithoutCommand(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile(os.TempDir(), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	err = clientcmd.WriteToFile(clientcmdapi.Config{}, fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+	options := &CreateAuthInfoOptions{
+		ConfigAccess: pathOptions,
+		Name:         "me",
+		ExecArgs:     []string{"eks", "get-token"},
+	}
+	err = options.Run()
+	if err == nil || !strings.Contains(err.Error(), "--exec-command") {
+		t.Errorf("expected an error asking for --exec-command, got %v", err)
+	}
+}
+
 func (test createAuthInfoTest) run(t *testing.T) {
 	fakeKubeFile, err := ioutil.TempFile(os.TempDir(), "")
 	if err != nil {
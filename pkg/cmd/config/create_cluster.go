@@ -40,13 +40,24 @@ type CreateClusterOptions struct {
 	InsecureSkipTLSVerify cliflag.Tristate
 	CertificateAuthority  cliflag.StringFlag
 	EmbedCAData           cliflag.Tristate
+	PreferIPv6            cliflag.Tristate
+	AllowDuplicateServer  bool
 }
 
 var (
 	createClusterLong = templates.LongDesc(`
 		Sets a cluster entry in kubeconfig.
 
-		Specifying a name that already exists will merge new fields on top of existing values for those fields.`)
+		Specifying a name that already exists will merge new fields on top of existing values for those fields.
+
+		A cluster entry pointing at the same server, with the same certificate authority, as another
+		entry is refused unless --allow-duplicate-server is given, so that contexts share a single
+		entry per cluster.
+
+		With --prefer-ipv6, the server of a dual-stack cluster is dialed over IPv6 first, falling back
+		to IPv4, by the checks of the config subcommands and by "kubectl config exec", which passes
+		kubectl a local HTTPS proxy doing so unless HTTPS_PROXY is set. --prefer-ipv6=false
+		removes the hint.`)
 
 	createClusterExample = templates.Examples(`
 		# Set only the server field on the e2e cluster entry without touching other values.
@@ -56,7 +67,10 @@ var (
 		kubectl config set-cluster e2e --certificate-authority=~/.kube/e2e/kubernetes.ca.crt
 
 		# Disable cert checking for the dev cluster entry
-		kubectl config set-cluster e2e --insecure-skip-tls-verify=true`)
+		kubectl config set-cluster e2e --insecure-skip-tls-verify=true
+
+		# Reach the e2e cluster over IPv6 first
+		kubectl config set-cluster e2e --prefer-ipv6`)
 )
 
 // NewCmdConfigSetCluster returns a Command instance for 'config set-cluster' sub command
@@ -85,6 +99,9 @@ func NewCmdConfigSetCluster(out io.Writer, configAccess clientcmd.ConfigAccess)
 	cmd.MarkFlagFilename(clientcmd.FlagCAFile)
 	f = cmd.Flags().VarPF(&options.EmbedCAData, clientcmd.FlagEmbedCerts, "", clientcmd.FlagEmbedCerts+" for the cluster entry in kubeconfig")
 	f.NoOptDefVal = "true"
+	f = cmd.Flags().VarPF(&options.PreferIPv6, "prefer-ipv6", "", "If true, dial the server of the cluster over IPv6 first; false removes the hint")
+	f.NoOptDefVal = "true"
+	cmd.Flags().BoolVar(&options.AllowDuplicateServer, flagAllowDuplicateServer, options.AllowDuplicateServer, "If true, allow the cluster entry to point at the same server and certificate authority as another entry")
 
 	return cmd
 }
@@ -105,6 +122,20 @@ func (o CreateClusterOptions) Run() error {
 		startingStanza = clientcmdapi.NewCluster()
 	}
 	cluster := o.modifyCluster(*startingStanza)
+	if o.PreferIPv6.Provided() {
+		if o.PreferIPv6.Value() {
+			if err := writeExtension(&cluster.Extensions, preferIPv6Extension, true); err != nil {
+				return err
+			}
+		} else {
+			delete(cluster.Extensions, preferIPv6Extension)
+		}
+	}
+	if !o.AllowDuplicateServer && (o.Server.Provided() || o.CertificateAuthority.Provided()) {
+		if existing := findDuplicateServer(config.Clusters, o.Name, &cluster); len(existing) > 0 {
+			return fmt.Errorf("cluster %q already points at %s with the same certificate authority, use it with \"kubectl config set-context --cluster=%s\" or pass --%s", existing, cluster.Server, existing, flagAllowDuplicateServer)
+		}
+	}
 	config.Clusters[o.Name] = &cluster
 
 	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
@@ -20,9 +20,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	cliflag "k8s.io/component-base/cli/flag"
@@ -38,27 +42,40 @@ type CreateContextOptions struct {
 	Cluster      cliflag.StringFlag
 	AuthInfo     cliflag.StringFlag
 	Namespace    cliflag.StringFlag
+	// Validate checks the context against its cluster with Validator before writing it.
+	Validate  bool
+	Validator func(config *clientcmdapi.Config, name string) error
 }
 
 var (
 	createContextLong = templates.LongDesc(`
 		Sets a context entry in kubeconfig
 
-		Specifying a name that already exists will merge new fields on top of existing values for those fields.`)
+		Specifying a name that already exists will merge new fields on top of existing values for those fields.
+
+		With --validate, the entry is only written once the user of the context authenticated to its
+		cluster and the cluster reported the namespace of the context, if any, as existing.`)
 
 	createContextExample = templates.Examples(`
 		# Set the user field on the gce context entry without touching other values
-		kubectl config set-context gce --user=cluster-admin`)
+		kubectl config set-context gce --user=cluster-admin
+
+		# Set the namespace field on the current context entry
+		kubectl config set-context . --namespace=kube-system
+
+		# Create the dev context, making sure the user can log in and the namespace exists
+		kubectl config create-context dev --cluster=dev --user=dev-admin --namespace=web --validate`)
 )
 
 // NewCmdConfigSetContext returns a Command instance for 'config set-context' sub command
 func NewCmdConfigSetContext(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
-	options := &CreateContextOptions{ConfigAccess: configAccess}
+	options := &CreateContextOptions{ConfigAccess: configAccess, Validator: validateContext}
 
 	cmd := &cobra.Command{
 		Use:                   fmt.Sprintf("set-context [NAME | --current] [--%v=cluster_nickname] [--%v=user_nickname] [--%v=namespace]", clientcmd.FlagClusterName, clientcmd.FlagAuthInfoName, clientcmd.FlagNamespace),
 		DisableFlagsInUseLine: true,
 		Short:                 i18n.T("Sets a context entry in kubeconfig"),
+		Aliases:               []string{"create-context"},
 		Long:                  createContextLong,
 		Example:               createContextExample,
 		Run: func(cmd *cobra.Command, args []string) {
@@ -77,6 +94,7 @@ func NewCmdConfigSetContext(out io.Writer, configAccess clientcmd.ConfigAccess)
 	cmd.Flags().Var(&options.Cluster, clientcmd.FlagClusterName, clientcmd.FlagClusterName+" for the context entry in kubeconfig")
 	cmd.Flags().Var(&options.AuthInfo, clientcmd.FlagAuthInfoName, clientcmd.FlagAuthInfoName+" for the context entry in kubeconfig")
 	cmd.Flags().Var(&options.Namespace, clientcmd.FlagNamespace, clientcmd.FlagNamespace+" for the context entry in kubeconfig")
+	cmd.Flags().BoolVar(&options.Validate, "validate", options.Validate, "If true, only write the entry once the user authenticated to the cluster and the namespace was found there")
 
 	return cmd
 }
@@ -99,6 +117,10 @@ func (o CreateContextOptions) Run() (string, bool, error) {
 		}
 		name = config.CurrentContext
 	}
+	name, err = resolveContextName(config, name)
+	if err != nil {
+		return "", false, err
+	}
 
 	startingStanza, exists := config.Contexts[name]
 	if !exists {
@@ -107,6 +129,11 @@ func (o CreateContextOptions) Run() (string, bool, error) {
 	context := o.modifyContext(*startingStanza)
 	config.Contexts[name] = &context
 
+	if o.Validate {
+		if err := o.Validator(config, name); err != nil {
+			return name, exists, err
+		}
+	}
 	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
 		return name, exists, err
 	}
@@ -138,6 +165,9 @@ func (o *CreateContextOptions) Complete(cmd *cobra.Command) error {
 	if len(args) == 1 {
 		o.Name = args[0]
 	}
+	if o.Validate {
+		return requireNetwork(cmd)
+	}
 	return nil
 }
 
@@ -151,3 +181,37 @@ func (o CreateContextOptions) validate() error {
 
 	return nil
 }
+
+// validateContext makes sure the user of the context called name authenticates to its cluster, and
+// that the cluster reports the namespace of the context as existing.
+func validateContext(config *clientcmdapi.Config, name string) error {
+	health := checkContextHealth(config, name)
+	if !health.Authenticated {
+		message := fmt.Sprintf("context %q failed validation: %s", name, health.Status())
+		if len(health.Error) > 0 {
+			message += ": " + health.Error
+		}
+		return errors.New(message)
+	}
+	context := config.Contexts[name]
+	if len(context.Namespace) == 0 {
+		return nil
+	}
+
+	restConfig, err := newProbeConfig(config, name, nil, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+	_, err = clientset.CoreV1().Namespaces().Get(context.Namespace, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		return fmt.Errorf("context %q failed validation: namespace %q does not exist", name, context.Namespace)
+	case err != nil:
+		return fmt.Errorf("context %q failed validation: cannot tell whether namespace %q exists: %v", name, context.Namespace, err)
+	}
+	return nil
+}
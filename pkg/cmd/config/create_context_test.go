@@ -19,7 +19,10 @@ package config
 import (
 	"bytes"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 
 	"k8s.io/client-go/tools/clientcmd"
@@ -106,6 +109,85 @@ func TestModifyCurrentContext(t *testing.T) {
 	test.run(t)
 }
 
+func TestCreateContextValidate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Header.Get("Authorization") != "Bearer good-token":
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"Unauthorized","code":401}`))
+		case r.URL.Path == "/api":
+			w.Write([]byte(`{"kind":"APIVersions","versions":["v1"],"serverAddressByClientCIDRs":[]}`))
+		case r.URL.Path == "/api/v1/namespaces/web":
+			w.Write([]byte(`{"kind":"Namespace","apiVersion":"v1","metadata":{"name":"web"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		user        string
+		namespace   string
+		expectedErr string
+	}{
+		{name: "valid", user: "good-user", namespace: "web"},
+		{name: "no namespace", user: "good-user"},
+		{name: "missing namespace", user: "good-user", namespace: "missing", expectedErr: `namespace "missing" does not exist`},
+		{name: "bad credentials", user: "bad-user", namespace: "web", expectedErr: `context "dev" failed validation`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fakeKubeFile, err := ioutil.TempFile("", "")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer os.Remove(fakeKubeFile.Name())
+			config := clientcmdapi.Config{
+				Clusters: map[string]*clientcmdapi.Cluster{"dev": {Server: server.URL}},
+				AuthInfos: map[string]*clientcmdapi.AuthInfo{
+					"good-user": {Token: "good-token"},
+					"bad-user":  {Token: "bad-token"}},
+			}
+			if err := clientcmd.WriteToFile(config, fakeKubeFile.Name()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			pathOptions := clientcmd.NewDefaultPathOptions()
+			pathOptions.GlobalFile = fakeKubeFile.Name()
+			pathOptions.EnvVar = ""
+
+			o := CreateContextOptions{ConfigAccess: pathOptions, Name: "dev", Validate: true, Validator: validateContext}
+			o.Cluster.Set("dev")
+			o.AuthInfo.Set(test.user)
+			if len(test.namespace) > 0 {
+				o.Namespace.Set(test.namespace)
+			}
+			_, _, err = o.Run()
+			loaded, loadErr := clientcmd.LoadFromFile(fakeKubeFile.Name())
+			if loadErr != nil {
+				t.Fatalf("unexpected error: %v", loadErr)
+			}
+			if len(test.expectedErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Errorf("expected an error containing %q, got %v", test.expectedErr, err)
+				}
+				if _, ok := loaded.Contexts["dev"]; ok {
+					t.Errorf("expected the context not to be written")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if context, ok := loaded.Contexts["dev"]; !ok || context.Namespace != test.namespace {
+				t.Errorf("expected the context to be written, got %v", loaded.Contexts)
+			}
+		})
+	}
+}
+
 func (test createContextTest) run(t *testing.T) {
 	fakeKubeFile, err := ioutil.TempFile(os.TempDir(), "")
 	if err != nil {
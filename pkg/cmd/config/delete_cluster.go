@@ -71,6 +71,9 @@ func RunDeleteCluster(out io.Writer, configAccess clientcmd.ConfigAccess, cmd *c
 	if !ok {
 		return fmt.Errorf("cannot delete cluster %s, not in %s", name, configFile)
 	}
+	if err := checkDeletable(configAccess, "cluster", name); err != nil {
+		return err
+	}
 
 	delete(config.Clusters, name)
 
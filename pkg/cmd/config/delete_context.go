@@ -30,7 +30,10 @@ import (
 var (
 	deleteContextExample = templates.Examples(`
 		# Delete the context for the minikube cluster
-		kubectl config delete-context minikube`)
+		kubectl config delete-context minikube
+
+		# Delete the current-context
+		kubectl config delete-context .`)
 )
 
 // NewCmdConfigDeleteContext returns a Command instance for 'config delete-context' sub command
@@ -66,11 +69,17 @@ func RunDeleteContext(out, errOut io.Writer, configAccess clientcmd.ConfigAccess
 		configFile = configAccess.GetExplicitFile()
 	}
 
-	name := args[0]
+	name, err := resolveContextName(config, args[0])
+	if err != nil {
+		return err
+	}
 	_, ok := config.Contexts[name]
 	if !ok {
 		return fmt.Errorf("cannot delete context %s, not in %s", name, configFile)
 	}
+	if err := checkDeletable(configAccess, "context", name); err != nil {
+		return err
+	}
 
 	if config.CurrentContext == name {
 		fmt.Fprint(errOut, "warning: this removed your active context, use \"kubectl config use-context\" to select a different one\n")
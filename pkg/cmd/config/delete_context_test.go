@@ -52,6 +52,24 @@ func TestDeleteContext(t *testing.T) {
 	test.run(t)
 }
 
+func TestDeleteCurrentContextShorthand(t *testing.T) {
+	conf := clientcmdapi.Config{
+		Contexts: map[string]*clientcmdapi.Context{
+			"minikube":  {Cluster: "minikube"},
+			"otherkube": {Cluster: "otherkube"},
+		},
+		CurrentContext: "minikube",
+	}
+	test := deleteContextTest{
+		config:           conf,
+		contextToDelete:  ".",
+		expectedContexts: []string{"otherkube"},
+		expectedOut:      "deleted context minikube from %s\n",
+	}
+
+	test.run(t)
+}
+
 func (test deleteContextTest) run(t *testing.T) {
 	fakeKubeFile, err := ioutil.TempFile("", "")
 	if err != nil {
@@ -0,0 +1,184 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// sunsetDateLayout is the layout of the sunset dates of deprecated contexts.
+const sunsetDateLayout = "2006-01-02"
+
+// contextDeprecation is stored in a context's deprecationExtension. After is the sunset date,
+// after which "config gc" removes the context, none if empty.
+type contextDeprecation struct {
+	After   string `json:"after,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// sunset returns the time the context is removed from, the day after its sunset date.
+func (d contextDeprecation) sunset() (time.Time, bool) {
+	after, err := time.Parse(sunsetDateLayout, d.After)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return after.AddDate(0, 0, 1), true
+}
+
+// notice describes the deprecation of the context called name.
+func (d contextDeprecation) notice(name string) string {
+	notice := fmt.Sprintf("context %q is deprecated", name)
+	if len(d.After) > 0 {
+		notice += fmt.Sprintf(" and will be removed after %s", d.After)
+	}
+	if len(d.Message) > 0 {
+		notice += ": " + d.Message
+	}
+	return notice
+}
+
+// DeprecateOptions holds the command-line options for 'config deprecate' sub command
+type DeprecateOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Context      string
+	After        string
+	Message      string
+	Undo         bool
+
+	genericclioptions.IOStreams
+}
+
+var (
+	deprecateLong = templates.LongDesc(`
+		Mark a context as deprecated, with a sunset date and a message telling what to use instead.
+
+		"kubectl config get-contexts" marks the deprecated contexts it lists and warns about them,
+		and "kubectl config use-context" prints the message when switching to one. Once the sunset
+		date given with --after has passed, "kubectl config gc" removes the context.
+
+		The deprecation is kept in a kubeconfig extension, so it travels with the context when the
+		kubeconfig is shared. --undo removes it.`)
+
+	deprecateExample = templates.Examples(`
+		# Deprecate prod-v1 in favor of prod-v2, to be removed in 2025
+		kubectl config deprecate prod-v1 --after 2025-01-01 --message 'migrate to prod-v2'
+
+		# Keep using prod-v1
+		kubectl config deprecate prod-v1 --undo`)
+)
+
+// NewCmdConfigDeprecate returns a Command instance for 'config deprecate' sub command
+func NewCmdConfigDeprecate(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &DeprecateOptions{ConfigAccess: configAccess, IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "deprecate CONTEXT_NAME [--after=DATE] [--message=MESSAGE] [--undo]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Mark a context as deprecated, with a sunset date"),
+		Long:                  deprecateLong,
+		Example:               deprecateExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.Context = args[0]
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	cmd.Flags().StringVar(&o.After, "after", o.After, "Sunset date, as YYYY-MM-DD, after which 'kubectl config gc' removes the context")
+	cmd.Flags().StringVar(&o.Message, "message", o.Message, "Message shown to users of the context, such as what to use instead")
+	cmd.Flags().BoolVar(&o.Undo, "undo", o.Undo, "Remove the deprecation of the context")
+	return cmd
+}
+
+// Validate checks the sunset date
+func (o *DeprecateOptions) Validate() error {
+	if o.Undo && (len(o.After) > 0 || len(o.Message) > 0) {
+		return errors.New("--undo cannot be combined with --after or --message")
+	}
+	if len(o.After) > 0 {
+		if _, err := time.Parse(sunsetDateLayout, o.After); err != nil {
+			return fmt.Errorf("invalid --after date %q, must be YYYY-MM-DD", o.After)
+		}
+	}
+	return nil
+}
+
+// Run deprecates the context, or removes its deprecation
+func (o *DeprecateOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	name, err := resolveContextName(config, o.Context)
+	if err != nil {
+		return err
+	}
+	context, ok := config.Contexts[name]
+	if !ok {
+		return fmt.Errorf("no context exists with the name: %q", name)
+	}
+
+	if o.Undo {
+		if _, deprecated, err := readContextDeprecation(context); err != nil {
+			return err
+		} else if !deprecated {
+			return fmt.Errorf("context %q is not deprecated", name)
+		}
+		delete(context.Extensions, deprecationExtension)
+	} else if err := writeExtension(&context.Extensions, deprecationExtension, contextDeprecation{After: o.After, Message: o.Message}); err != nil {
+		return err
+	}
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+	if o.Undo {
+		fmt.Fprintf(o.Out, "Context %q is no longer deprecated.\n", name)
+	} else {
+		fmt.Fprintf(o.Out, "Context %q deprecated.\n", name)
+	}
+	return nil
+}
+
+// readContextDeprecation returns the deprecation of a context, and whether it is deprecated.
+func readContextDeprecation(context *clientcmdapi.Context) (contextDeprecation, bool, error) {
+	deprecation := contextDeprecation{}
+	deprecated, err := readExtension(context.Extensions, deprecationExtension, &deprecation)
+	return deprecation, deprecated, err
+}
+
+// warnDeprecatedContext returns a warning when the context called name is deprecated, and an empty
+// string otherwise.
+func warnDeprecatedContext(name string, context *clientcmdapi.Context) string {
+	deprecation, deprecated, err := readContextDeprecation(context)
+	if err != nil || !deprecated {
+		return ""
+	}
+	return deprecation.notice(name)
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestDeprecate(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	config := newRedFederalCowHammerConfig()
+	config.Contexts["prod-v1"] = &clientcmdapi.Context{AuthInfo: "red-user", Cluster: "cow-cluster"}
+	if err := clientcmd.WriteToFile(config, fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := &DeprecateOptions{ConfigAccess: pathOptions, Context: "prod-v1", After: "2025-01-01", Message: "migrate to prod-v2", IOStreams: streams}
+	if err := o.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.Run(); err != nil || out.String() != "Context \"prod-v1\" deprecated.\n" {
+		t.Errorf("unexpected output %q, %v", out.String(), err)
+	}
+
+	warning := `context "prod-v1" is deprecated and will be removed after 2025-01-01: migrate to prod-v2`
+	use := &UseContextOptions{ConfigAccess: pathOptions, ContextName: "prod-v1"}
+	if err := use.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if use.DeprecationWarning != warning {
+		t.Errorf("expected use-context to warn %q, got %q", warning, use.DeprecationWarning)
+	}
+
+	streams, _, _, errOut := genericclioptions.NewTestIOStreams()
+	get := NewCmdConfigGetContexts(streams, pathOptions)
+	get.Run(get, nil)
+	if errOut.String() != "warning: "+warning+"\n" {
+		t.Errorf("expected get-contexts to warn about prod-v1 only, got %q", errOut.String())
+	}
+
+	streams, _, out, _ = genericclioptions.NewTestIOStreams()
+	o = &DeprecateOptions{ConfigAccess: pathOptions, Context: ".", Undo: true, IOStreams: streams}
+	if err := o.Run(); err != nil || out.String() != "Context \"prod-v1\" is no longer deprecated.\n" {
+		t.Errorf("unexpected output %q, %v", out.String(), err)
+	}
+	if err := o.Run(); err == nil {
+		t.Errorf("expected undoing a context that is not deprecated to fail")
+	}
+
+	for _, invalid := range []*DeprecateOptions{{After: "01/01/2025"}, {Undo: true, Message: "x"}} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", invalid)
+		}
+	}
+}
@@ -0,0 +1,447 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	certutil "k8s.io/client-go/util/cert"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// interceptingProxyVendors are words found in the issuers of the certificates TLS inspecting
+// proxies and debugging tools forge for the servers they intercept.
+var interceptingProxyVendors = []string{
+	"Zscaler", "Netskope", "Blue Coat", "Symantec Web", "Palo Alto", "Fortinet", "FortiGate",
+	"Cisco Umbrella", "Forcepoint", "McAfee Web Gateway", "Sophos", "Check Point",
+	"mitmproxy", "Charles Proxy", "Fiddler", "PortSwigger",
+}
+
+// DiagnoseOptions holds the command-line options for 'config diagnose' sub command
+type DiagnoseOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Context      string
+	Timeout      time.Duration
+
+	Now func() time.Time
+	// LookupHost resolves the host of the server, it defaults to the resolver of the system.
+	LookupHost func(ctx context.Context, host string) ([]net.IPAddr, error)
+
+	genericclioptions.IOStreams
+}
+
+var (
+	diagnoseLong = templates.LongDesc(`
+		Explain why kubectl cannot connect to the cluster of a context.
+
+		The server is resolved, dialed, through the proxy of the environment as kubectl does, and
+		its certificates are read without verifying them, then checked one by one, so that the
+		cryptic x509 errors of kubectl become a precise problem and what to do about it:
+
+		    * the name of the server does not resolve
+		    * the server cannot be dialed
+		    * the certificate of the server is not for the name of the server, with the names it
+		      is for
+		    * a certificate of the chain expired, or is not valid yet because a clock is wrong
+		    * the chain is issued by a publicly trusted CA while the cluster pins another one
+		    * the certificate was forged by a proxy inspecting TLS
+		    * the chain is not signed by the CA of the cluster, which was rotated for instance
+		    * the client certificate of the user expired
+
+		The command fails when a problem is found.`)
+
+	diagnoseExample = templates.Examples(`
+		# Explain why the current-context fails with x509 errors
+		kubectl config diagnose
+
+		# Diagnose the connection to the prod cluster
+		kubectl config diagnose prod`)
+)
+
+// NewCmdConfigDiagnose returns a Command instance for 'config diagnose' sub command
+func NewCmdConfigDiagnose(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &DiagnoseOptions{
+		ConfigAccess: configAccess,
+		Context:      currentContextShorthand,
+		Timeout:      10 * time.Second,
+		Now:          time.Now,
+		LookupHost:   net.DefaultResolver.LookupIPAddr,
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "diagnose [CONTEXT_NAME]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Explain why kubectl cannot connect to the cluster of a context"),
+		Long:                  diagnoseLong,
+		Example:               diagnoseExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) > 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			if len(args) == 1 {
+				o.Context = args[0]
+			}
+			cmdutil.CheckErr(requireNetwork(cmd))
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().DurationVar(&o.Timeout, "timeout", o.Timeout, "How long to wait for the server to be resolved and to answer")
+	return cmd
+}
+
+// Run performs the execution of 'config diagnose' sub command
+func (o *DiagnoseOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	name, err := resolveContextName(config, o.Context)
+	if err != nil {
+		return err
+	}
+	context, ok := config.Contexts[name]
+	if !ok {
+		return fmt.Errorf("no context exists with the name: %q", name)
+	}
+	cluster, ok := config.Clusters[context.Cluster]
+	if !ok {
+		return fmt.Errorf("context %q has no cluster %q", name, context.Cluster)
+	}
+	restConfig, err := newProbeConfig(config, name, nil, o.Timeout)
+	if err != nil {
+		return err
+	}
+	if err := rest.LoadTLSFiles(restConfig); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(o.Out, "Context %q, cluster %q, server %s\n", name, context.Cluster, cluster.Server)
+	problems := 0
+	for _, finding := range o.diagnose(restConfig, name, context.Cluster) {
+		fmt.Fprintf(o.Out, "[%s]\t%s\n", finding.Severity, finding.Message)
+		if finding.Severity != doctorOK && len(finding.Fix) > 0 {
+			fmt.Fprintf(o.Out, "\tfix: %s\n", finding.Fix)
+		}
+		if finding.Severity == doctorError {
+			problems++
+		}
+	}
+	if problems > 0 {
+		return fmt.Errorf("found %d problem(s) with the connection to cluster %q", problems, context.Cluster)
+	}
+	return nil
+}
+
+// diagnose checks the connection to the server of restConfig, the configuration of the context
+// called name and its cluster called cluster, step by step, and stops at the first step that fails.
+func (o *DiagnoseOptions) diagnose(restConfig *rest.Config, name, cluster string) []doctorFinding {
+	findings := []doctorFinding{}
+	now := o.Now()
+
+	server, err := url.Parse(restConfig.Host)
+	if err != nil || len(server.Hostname()) == 0 {
+		return append(findings, doctorFinding{
+			Severity: doctorError,
+			Message:  fmt.Sprintf("invalid server %q", restConfig.Host),
+			Fix:      fmt.Sprintf("kubectl config set-cluster %s --server=https://HOST:PORT", cluster),
+		})
+	}
+	host := server.Hostname()
+
+	if net.ParseIP(host) == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), o.Timeout)
+		addrs, err := o.LookupHost(ctx, host)
+		cancel()
+		if err == nil && len(addrs) == 0 {
+			err = fmt.Errorf("no address found")
+		}
+		if err != nil {
+			return append(findings, doctorFinding{
+				Severity: doctorError,
+				Message:  fmt.Sprintf("%s does not resolve: %v", host, err),
+				Fix:      "check the server name for typos, and that the VPN or DNS servers the cluster is only known to are in use",
+			})
+		}
+		ips := []string{}
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP.String())
+		}
+		findings = append(findings, doctorFinding{Severity: doctorOK, Message: fmt.Sprintf("%s resolves to %s", host, strings.Join(ips, ", "))})
+	}
+
+	address := server.Host
+	if len(server.Port()) == 0 {
+		port := "443"
+		if server.Scheme == "http" {
+			port = "80"
+		}
+		address = net.JoinHostPort(host, port)
+	}
+	proxy, _ := http.ProxyFromEnvironment(&http.Request{URL: server})
+	dialer := &net.Dialer{Timeout: o.Timeout}
+	direct := dialer.DialContext
+	if restConfig.Dial != nil {
+		direct = restConfig.Dial
+	}
+	conn, err := dialThroughProxy(dialer, direct, server, address)
+	if err != nil {
+		finding := doctorFinding{
+			Severity: doctorError,
+			Message:  fmt.Sprintf("cannot connect to %s: %v", address, err),
+			Fix:      "check that the server is up, and that no firewall or security group blocks this machine",
+		}
+		if proxy != nil {
+			finding.Fix = fmt.Sprintf("check that the proxy %s lets this machine reach %s, or add %s to NO_PROXY", proxy.Host, address, host)
+		}
+		return append(findings, finding)
+	}
+	defer conn.Close()
+	via := ""
+	if proxy != nil {
+		via = " through the proxy " + proxy.Host
+	}
+	findings = append(findings, doctorFinding{Severity: doctorOK, Message: fmt.Sprintf("connected to %s%s", address, via)})
+	if server.Scheme != "https" {
+		return append(findings, doctorFinding{
+			Severity: doctorWarning,
+			Message:  "the server is plain HTTP, requests and credentials are sent unencrypted",
+			Fix:      fmt.Sprintf("kubectl config set-cluster %s --server=https://%s", cluster, server.Host),
+		})
+	}
+
+	// The certificates are read without verifying them, they are checked one by one below.
+	tlsConfig, err := rest.TLSConfigFor(restConfig)
+	if err != nil {
+		return append(findings, doctorFinding{Severity: doctorError, Message: fmt.Sprintf("invalid TLS configuration: %v", err)})
+	}
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+	tlsConfig = tlsConfig.Clone()
+	tlsConfig.InsecureSkipVerify = true
+	if len(tlsConfig.ServerName) == 0 {
+		tlsConfig.ServerName = host
+	}
+	conn.SetDeadline(time.Now().Add(o.Timeout))
+	tlsConn := tls.Client(conn, tlsConfig)
+	if err := tlsConn.Handshake(); err != nil {
+		return append(findings, doctorFinding{
+			Severity: doctorError,
+			Message:  fmt.Sprintf("TLS handshake with %s failed: %v", address, err),
+			Fix:      "check that the port is the one of the API server and not of another service, and that the server URL starts with https only if the server speaks TLS",
+		})
+	}
+	chain := tlsConn.ConnectionState().PeerCertificates
+	if len(chain) == 0 {
+		return append(findings, doctorFinding{Severity: doctorError, Message: fmt.Sprintf("%s presented no certificate", address)})
+	}
+	findings = append(findings, diagnoseHostname(chain[0], host, server.Host, cluster))
+	findings = append(findings, diagnoseExpiry(chain, now)...)
+	findings = append(findings, diagnoseTrust(restConfig, chain, proxy != nil, now, name, cluster)...)
+	if len(restConfig.CertData) > 0 {
+		if expiry, err := certificateExpiry(restConfig.CertData); err == nil && !expiry.After(now) {
+			findings = append(findings, doctorFinding{
+				Severity: doctorError,
+				Message:  fmt.Sprintf("the client certificate of the user expired on %s", expiry.UTC().Format(time.RFC3339)),
+				Fix:      "get a new client certificate from the administrators of the cluster, or re-import the context",
+			})
+		}
+	}
+	return findings
+}
+
+// diagnoseHostname checks that the certificate of the server is for host.
+func diagnoseHostname(leaf *x509.Certificate, host, hostPort, cluster string) doctorFinding {
+	names := append([]string{}, leaf.DNSNames...)
+	for _, ip := range leaf.IPAddresses {
+		names = append(names, ip.String())
+	}
+	if err := leaf.VerifyHostname(host); err == nil {
+		return doctorFinding{Severity: doctorOK, Message: fmt.Sprintf("the certificate of the server is for %s", host)}
+	}
+	if len(names) == 0 {
+		return doctorFinding{
+			Severity: doctorError,
+			Message:  fmt.Sprintf("the certificate of the server has no subject alternative names, its common name %q is ignored", leaf.Subject.CommonName),
+			Fix:      fmt.Sprintf("reissue the serving certificate with %s as a subject alternative name", host),
+		}
+	}
+	fix := fmt.Sprintf("reissue the serving certificate with %s among its names", host)
+	_, port, _ := net.SplitHostPort(hostPort)
+	for _, name := range names {
+		if !strings.HasPrefix(name, "*") {
+			target := name
+			if len(port) > 0 {
+				target = net.JoinHostPort(name, port)
+			}
+			fix = fmt.Sprintf("use a name of the certificate as server, as in \"kubectl config set-cluster %s --server=https://%s\", or %s", cluster, target, fix)
+			break
+		}
+	}
+	return doctorFinding{
+		Severity: doctorError,
+		Message:  fmt.Sprintf("the certificate of the server is not for %s, it is for: %s", host, strings.Join(names, ", ")),
+		Fix:      fix,
+	}
+}
+
+// diagnoseExpiry checks that every certificate of chain is valid at now.
+func diagnoseExpiry(chain []*x509.Certificate, now time.Time) []doctorFinding {
+	findings := []doctorFinding{}
+	for _, certificate := range chain {
+		switch {
+		case now.After(certificate.NotAfter):
+			findings = append(findings, doctorFinding{
+				Severity: doctorError,
+				Message:  fmt.Sprintf("the certificate %q expired on %s", certificate.Subject.CommonName, certificate.NotAfter.UTC().Format(time.RFC3339)),
+				Fix:      fmt.Sprintf("renew the certificates of the cluster, or set the clock of this machine if it is not %s", now.UTC().Format(time.RFC3339)),
+			})
+		case now.Before(certificate.NotBefore):
+			findings = append(findings, doctorFinding{
+				Severity: doctorError,
+				Message:  fmt.Sprintf("the certificate %q is only valid from %s", certificate.Subject.CommonName, certificate.NotBefore.UTC().Format(time.RFC3339)),
+				Fix:      fmt.Sprintf("the clock of this machine, %s, or of the server is wrong; synchronize them with NTP", now.UTC().Format(time.RFC3339)),
+			})
+		}
+	}
+	if len(findings) == 0 {
+		findings = append(findings, doctorFinding{Severity: doctorOK, Message: fmt.Sprintf("the certificates are valid until %s", chain[0].NotAfter.UTC().Format(time.RFC3339))})
+	}
+	return findings
+}
+
+// diagnoseTrust checks that chain is signed by the certificate authority of the cluster, or tells
+// why it is not: another CA is pinned, or a proxy forged the certificate.
+func diagnoseTrust(restConfig *rest.Config, chain []*x509.Certificate, proxied bool, now time.Time, context, cluster string) []doctorFinding {
+	if restConfig.Insecure {
+		return []doctorFinding{{
+			Severity: doctorWarning,
+			Message:  "the certificate of the server is not verified, insecure-skip-tls-verify is set",
+			Fix:      fmt.Sprintf("kubectl config set-cluster %s --certificate-authority=CA_FILE --embed-certs", cluster),
+		}}
+	}
+	leaf := chain[0]
+	verify := func(roots *x509.CertPool) ([][]*x509.Certificate, error) {
+		intermediates := x509.NewCertPool()
+		for _, certificate := range chain[1:] {
+			intermediates.AddCert(certificate)
+		}
+		// Expiry is diagnosed on its own, trust is verified at a time the leaf is valid.
+		at := now
+		if at.After(leaf.NotAfter) {
+			at = leaf.NotAfter
+		} else if at.Before(leaf.NotBefore) {
+			at = leaf.NotBefore
+		}
+		return leaf.Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates, CurrentTime: at})
+	}
+	system, _ := x509.SystemCertPool()
+	issuer := leaf.Issuer.String()
+
+	if len(restConfig.CAData) == 0 {
+		if _, err := verify(system); err == nil {
+			return []doctorFinding{{Severity: doctorOK, Message: fmt.Sprintf("the certificate is issued by %s, which this machine trusts", issuer)}}
+		}
+		if finding, intercepted := diagnoseInterception(chain, proxied); intercepted {
+			return []doctorFinding{finding}
+		}
+		return []doctorFinding{{
+			Severity: doctorError,
+			Message:  fmt.Sprintf("the certificate is issued by %s, which this machine does not trust, and the cluster has no certificate authority", issuer),
+			Fix:      fmt.Sprintf("kubectl config set-cluster %s --certificate-authority=CA_FILE --embed-certs, with the CA of the cluster", cluster),
+		}}
+	}
+
+	pinned := x509.NewCertPool()
+	cas, err := certutil.ParseCertsPEM(restConfig.CAData)
+	if err != nil {
+		return []doctorFinding{{
+			Severity: doctorError,
+			Message:  fmt.Sprintf("the certificate authority of the cluster is invalid: %v", err),
+			Fix:      fmt.Sprintf("kubectl config refresh-endpoint %s, or set a valid CA with kubectl config set-cluster %s --certificate-authority=CA_FILE --embed-certs", context, cluster),
+		}}
+	}
+	pinnedNames := []string{}
+	for _, ca := range cas {
+		pinned.AddCert(ca)
+		pinnedNames = append(pinnedNames, ca.Subject.String())
+	}
+	if chains, err := verify(pinned); err == nil {
+		root := chains[0][len(chains[0])-1]
+		return []doctorFinding{{Severity: doctorOK, Message: fmt.Sprintf("the certificate chains to the certificate authority of the cluster, %s", root.Subject.String())}}
+	}
+	if finding, intercepted := diagnoseInterception(chain, proxied); intercepted {
+		return []doctorFinding{finding}
+	}
+	if system != nil {
+		if _, err := verify(system); err == nil {
+			return []doctorFinding{{
+				Severity: doctorError,
+				Message:  fmt.Sprintf("the cluster pins the certificate authority %s, but the server uses a publicly trusted certificate issued by %s", strings.Join(pinnedNames, ", "), issuer),
+				Fix:      fmt.Sprintf("pin the CA issuing the certificate, or remove the certificate authority of cluster %s to trust the CAs of this machine", cluster),
+			}}
+		}
+	}
+	return []doctorFinding{{
+		Severity: doctorError,
+		Message:  fmt.Sprintf("the certificate is issued by %s, which the certificate authority of the cluster, %s, did not sign", issuer, strings.Join(pinnedNames, ", ")),
+		Fix:      fmt.Sprintf("the CA of the cluster may have been rotated: kubectl config refresh-endpoint %s, or re-import the context", context),
+	}}
+}
+
+// diagnoseInterception reports a certificate a TLS inspecting proxy forged: one issued by a known
+// inspecting product, or any certificate not signed by the expected CA when a proxy is used.
+func diagnoseInterception(chain []*x509.Certificate, proxied bool) (doctorFinding, bool) {
+	for _, certificate := range chain {
+		issuer := certificate.Issuer.String()
+		for _, vendor := range interceptingProxyVendors {
+			if strings.Contains(strings.ToLower(issuer), strings.ToLower(vendor)) {
+				return doctorFinding{
+					Severity: doctorError,
+					Message:  fmt.Sprintf("the connection is intercepted, the certificate was forged by %s (issuer %s)", vendor, issuer),
+					Fix:      "ask for the server to be excluded from TLS inspection, or add its host to NO_PROXY",
+				}, true
+			}
+		}
+	}
+	if proxied {
+		return doctorFinding{
+			Severity: doctorError,
+			Message:  fmt.Sprintf("the certificate is issued by %s, likely by the proxy inspecting TLS", chain[0].Issuer.String()),
+			Fix:      "add the host of the server to NO_PROXY, or ask for it to be excluded from TLS inspection",
+		}, true
+	}
+	return doctorFinding{}, false
+}
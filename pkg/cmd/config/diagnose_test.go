@@ -0,0 +1,174 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	certutil "k8s.io/client-go/util/cert"
+)
+
+// newTestCA returns a serving certificate and key for kube.example.com and 127.0.0.1, and the PEM
+// encoded CA that signed it.
+func newTestCA(t *testing.T) (tls.Certificate, []byte) {
+	certPEM, keyPEM, err := certutil.GenerateSelfSignedCertKey("kube.example.com", []net.IP{net.ParseIP("127.0.0.1")}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	certificate, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	chain, err := certutil.ParseCertsPEM(certPEM)
+	if err != nil || len(chain) != 2 {
+		t.Fatalf("expected a certificate and its CA, got %d, %v", len(chain), err)
+	}
+	return certificate, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: chain[1].Raw})
+}
+
+func TestDiagnose(t *testing.T) {
+	certificate, ca := newTestCA(t)
+	_, otherCA := newTestCA(t)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{certificate}}
+	server.StartTLS()
+	defer server.Close()
+	address, _ := url.Parse(server.URL)
+	_, port, _ := net.SplitHostPort(address.Host)
+
+	tests := map[string]struct {
+		server   string
+		ca       []byte
+		now      time.Time
+		lookup   func(ctx context.Context, host string) ([]net.IPAddr, error)
+		expected []string
+		problems bool
+	}{
+		"healthy": {
+			server:   server.URL,
+			ca:       ca,
+			expected: []string{"[OK]\tconnected to " + address.Host, "[OK]\tthe certificate of the server is for 127.0.0.1", "[OK]\tthe certificate chains to the certificate authority of the cluster"},
+		},
+		"name mismatch": {
+			server:   "https://localhost:" + port,
+			ca:       ca,
+			expected: []string{"[ERROR]\tthe certificate of the server is not for localhost, it is for: kube.example.com, 127.0.0.1", "--server=https://kube.example.com:" + port},
+			problems: true,
+		},
+		"other CA": {
+			server:   server.URL,
+			ca:       otherCA,
+			expected: []string{"did not sign", "fix: the CA of the cluster may have been rotated: kubectl config refresh-endpoint test"},
+			problems: true,
+		},
+		"expired": {
+			server:   server.URL,
+			ca:       ca,
+			now:      time.Now().AddDate(200, 0, 0),
+			expected: []string{"[ERROR]\tthe certificate \"kube.example.com@", "renew the certificates of the cluster", "[OK]\tthe certificate chains"},
+			problems: true,
+		},
+		"unresolved": {
+			server: "https://kube.example.invalid",
+			lookup: func(ctx context.Context, host string) ([]net.IPAddr, error) {
+				return nil, errors.New("no such host")
+			},
+			expected: []string{"[ERROR]\tkube.example.invalid does not resolve: no such host"},
+			problems: true,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := clientcmdapi.NewConfig()
+			config.Clusters["test"] = &clientcmdapi.Cluster{Server: test.server, CertificateAuthorityData: test.ca}
+			config.AuthInfos["test"] = &clientcmdapi.AuthInfo{Token: "token"}
+			config.Contexts["test"] = &clientcmdapi.Context{Cluster: "test", AuthInfo: "test"}
+			config.CurrentContext = "test"
+			fakeKubeFile, err := ioutil.TempFile("", "")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer os.Remove(fakeKubeFile.Name())
+			if err := clientcmd.WriteToFile(*config, fakeKubeFile.Name()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			pathOptions := clientcmd.NewDefaultPathOptions()
+			pathOptions.GlobalFile = fakeKubeFile.Name()
+			pathOptions.EnvVar = ""
+
+			now := test.now
+			if now.IsZero() {
+				now = time.Now()
+			}
+			lookup := test.lookup
+			if lookup == nil {
+				lookup = net.DefaultResolver.LookupIPAddr
+			}
+			streams, _, out, _ := genericclioptions.NewTestIOStreams()
+			o := &DiagnoseOptions{
+				ConfigAccess: pathOptions,
+				Context:      currentContextShorthand,
+				Timeout:      5 * time.Second,
+				Now:          func() time.Time { return now },
+				LookupHost:   lookup,
+				IOStreams:    streams,
+			}
+			err = o.Run()
+			if test.problems != (err != nil) {
+				t.Errorf("expected problems: %v, got %v\n%s", test.problems, err, out.String())
+			}
+			for _, expected := range test.expected {
+				if !strings.Contains(out.String(), expected) {
+					t.Errorf("expected %q in the output:\n%s", expected, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestDiagnoseInterception(t *testing.T) {
+	certificate, _ := newTestCA(t)
+	chain, err := certutil.ParseCertsPEM(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Certificate[0]}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, intercepted := diagnoseInterception(chain, false); intercepted {
+		t.Errorf("expected a certificate of no inspecting product, without proxy, not to be intercepted")
+	}
+	if finding, intercepted := diagnoseInterception(chain, true); !intercepted || finding.Severity != doctorError {
+		t.Errorf("expected a certificate of another CA through a proxy to be intercepted, got %+v", finding)
+	}
+	chain[0].Issuer.Organization = []string{"Zscaler Inc."}
+	if finding, _ := diagnoseInterception(chain, false); !strings.Contains(finding.Message, "forged by Zscaler") {
+		t.Errorf("expected the inspecting product to be named, got %+v", finding)
+	}
+}
@@ -0,0 +1,435 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/homedir"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// DoctorOptions holds the command-line options for 'config doctor' sub command
+type DoctorOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	EnvVar       string
+	Fix          bool
+	// NoWrite keeps the checks from writing probe files, as --no-write requires.
+	NoWrite bool
+	// Color prints the severities of findings in color, see the color setting.
+	Color bool
+
+	// LookPath and Getenv default to exec.LookPath and os.Getenv, they are fields so tests
+	// can describe an environment without touching the real one.
+	LookPath func(file string) (string, error)
+	Getenv   func(key string) string
+	HomeDir  string
+
+	genericclioptions.IOStreams
+}
+
+type doctorSeverity string
+
+const (
+	doctorOK      doctorSeverity = "OK"
+	doctorWarning doctorSeverity = "WARN"
+	doctorError   doctorSeverity = "ERROR"
+)
+
+// doctorFinding is the result of a single doctor check. Fix is an actionable suggestion
+// printed below the message when the finding is not OK, and Repair applies it for --fix.
+type doctorFinding struct {
+	Severity doctorSeverity
+	Message  string
+	Fix      string
+	Repair   func() error
+}
+
+// doctorCheck inspects one aspect of the environment.
+type doctorCheck func(o *DoctorOptions) []doctorFinding
+
+// execPluginInstallHints tells users how to install the exec credential plugins in common use.
+var execPluginInstallHints = map[string]string{
+	"aws":                    "install the AWS CLI: https://docs.aws.amazon.com/cli/latest/userguide/getting-started-install.html",
+	"aws-iam-authenticator":  "install aws-iam-authenticator: https://github.com/kubernetes-sigs/aws-iam-authenticator",
+	"gke-gcloud-auth-plugin": "run: gcloud components install gke-gcloud-auth-plugin",
+	"kubelogin":              "run: az aks install-cli, or see https://github.com/Azure/kubelogin",
+	"kubectl-oidc_login":     "run: kubectl krew install oidc-login",
+}
+
+var (
+	doctorLong = templates.LongDesc(`
+		Checks the environment kubectl runs in and suggests fixes for the problems found.
+
+		The following is validated:
+
+		    * the syntax of the KUBECONFIG environment variable and the files it lists
+		    * every kubeconfig file of the loading chain parses
+		    * kubeconfig files can only be read by their owner, judged by the file mode or, on
+		      Windows, by the access control list of the file
+		    * the exec credential plugins referenced by users are installed and on the PATH
+		    * the PATH environment variable itself
+		    * the kubectl cache directories are usable, judged by their mode with --no-write
+		    * the entries locked with "kubectl config lock" were not changed by other tools
+
+		With --fix, the problems that can be fixed without a decision of the user are repaired.`)
+
+	doctorExample = templates.Examples(`
+		# Check the kubectl environment
+		kubectl config doctor
+
+		# Check the kubectl environment and restrict access to kubeconfig files
+		kubectl config doctor --fix`)
+)
+
+// NewCmdConfigDoctor returns a Command instance for 'config doctor' sub command
+func NewCmdConfigDoctor(streams genericclioptions.IOStreams, pathOptions *clientcmd.PathOptions) *cobra.Command {
+	o := &DoctorOptions{
+		ConfigAccess: pathOptions,
+		EnvVar:       pathOptions.EnvVar,
+		LookPath:     exec.LookPath,
+		Getenv:       os.Getenv,
+		HomeDir:      homedir.HomeDir(),
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "doctor",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Check the kubectl environment for common problems"),
+		Long:                  doctorLong,
+		Example:               doctorExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(cmdutil.UsageErrorf(cmd, "unexpected arguments: %v", args))
+			}
+			o.NoWrite = writesDisabled(cmd)
+			settings, err := loadSettings(settingsFile())
+			cmdutil.CheckErr(err)
+			o.Color = colorEnabled(settings.Color, o.Out)
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().BoolVar(&o.Fix, "fix", o.Fix, "If true, repair the problems found where possible")
+	return cmd
+}
+
+// doctorChecks returns the checks run by 'config doctor', in order.
+func (o *DoctorOptions) doctorChecks() []doctorCheck {
+	return []doctorCheck{
+		checkKubeconfigEnv,
+		checkKubeconfigFiles,
+		checkKubeconfigPermissions,
+		checkExecPlugins,
+		checkPathEnv,
+		checkCacheDirs,
+		checkLockedEntries,
+	}
+}
+
+// Run performs the execution of 'config doctor' sub command
+func (o *DoctorOptions) Run() error {
+	problems := 0
+	for _, check := range o.doctorChecks() {
+		for _, finding := range check(o) {
+			fmt.Fprintf(o.Out, "[%s]\t%s\n", severityLabel(finding.Severity, o.Color), finding.Message)
+			if finding.Severity == doctorOK {
+				continue
+			}
+			if o.Fix && finding.Repair != nil {
+				if err := finding.Repair(); err != nil {
+					fmt.Fprintf(o.Out, "\tfix failed: %v\n", err)
+				} else {
+					fmt.Fprintf(o.Out, "\tfixed\n")
+					continue
+				}
+			} else if len(finding.Fix) > 0 {
+				fmt.Fprintf(o.Out, "\tfix: %s\n", finding.Fix)
+			}
+			if finding.Severity == doctorError {
+				problems++
+			}
+		}
+	}
+
+	if problems > 0 {
+		return fmt.Errorf("found %d problem(s) with the kubectl environment", problems)
+	}
+	return nil
+}
+
+func checkKubeconfigEnv(o *DoctorOptions) []doctorFinding {
+	if len(o.EnvVar) == 0 {
+		return nil
+	}
+	value := o.Getenv(o.EnvVar)
+	if len(value) == 0 {
+		return []doctorFinding{{Severity: doctorOK, Message: fmt.Sprintf("$%s is not set, the default kubeconfig file is used", o.EnvVar)}}
+	}
+
+	findings := []doctorFinding{}
+	seen := map[string]bool{}
+	for _, entry := range filepath.SplitList(value) {
+		switch {
+		case len(entry) == 0:
+			findings = append(findings, doctorFinding{
+				Severity: doctorWarning,
+				Message:  fmt.Sprintf("$%s contains an empty entry", o.EnvVar),
+				Fix:      fmt.Sprintf("remove the doubled or trailing %q separator", string(os.PathListSeparator)),
+			})
+		case strings.HasPrefix(entry, "~"):
+			findings = append(findings, doctorFinding{
+				Severity: doctorError,
+				Message:  fmt.Sprintf("$%s entry %q starts with ~, which is not expanded", o.EnvVar, entry),
+				Fix:      "use $HOME or an absolute path instead of ~",
+			})
+		case windowsEnvVarReference.MatchString(entry):
+			findings = append(findings, doctorFinding{
+				Severity: doctorError,
+				Message:  fmt.Sprintf("$%s entry %q contains a %%VAR%% reference, which is not expanded", o.EnvVar, entry),
+				Fix:      fmt.Sprintf("use the expanded path %q instead", expandPath(entry)),
+			})
+		case strings.HasPrefix(entry, `"`) || strings.HasPrefix(entry, "'"):
+			findings = append(findings, doctorFinding{
+				Severity: doctorError,
+				Message:  fmt.Sprintf("$%s entry %s is quoted, the quotes are part of the file name", o.EnvVar, entry),
+				Fix:      "remove the quotes around the path",
+			})
+		case seen[entry]:
+			findings = append(findings, doctorFinding{
+				Severity: doctorWarning,
+				Message:  fmt.Sprintf("$%s lists %q more than once", o.EnvVar, entry),
+				Fix:      "remove the duplicate entry",
+			})
+		case os.PathListSeparator == ':' && strings.Contains(entry, ";"):
+			findings = append(findings, doctorFinding{
+				Severity: doctorError,
+				Message:  fmt.Sprintf("$%s entry %q contains ';'", o.EnvVar, entry),
+				Fix:      "separate kubeconfig files with ':' on this platform",
+			})
+		default:
+			if _, err := os.Stat(entry); os.IsNotExist(err) {
+				findings = append(findings, doctorFinding{
+					Severity: doctorWarning,
+					Message:  fmt.Sprintf("$%s entry %q does not exist and is ignored", o.EnvVar, entry),
+					Fix:      "remove the entry or create the file",
+				})
+			}
+		}
+		seen[entry] = true
+	}
+
+	if len(findings) == 0 {
+		findings = append(findings, doctorFinding{Severity: doctorOK, Message: fmt.Sprintf("$%s is well formed", o.EnvVar)})
+	}
+	return findings
+}
+
+func checkKubeconfigFiles(o *DoctorOptions) []doctorFinding {
+	findings := []doctorFinding{}
+	for _, filename := range o.ConfigAccess.GetLoadingPrecedence() {
+		if _, err := os.Stat(filename); os.IsNotExist(err) {
+			continue
+		}
+		if _, err := clientcmd.LoadFromFile(filename); err != nil {
+			findings = append(findings, doctorFinding{
+				Severity: doctorError,
+				Message:  fmt.Sprintf("%s cannot be loaded: %v", filename, err),
+				Fix:      "fix the syntax error, or run \"kubectl config migrate --kubeconfig=" + filename + "\" for legacy files",
+			})
+			continue
+		}
+		findings = append(findings, doctorFinding{Severity: doctorOK, Message: fmt.Sprintf("%s is a valid kubeconfig file", filename)})
+	}
+	return findings
+}
+
+func checkKubeconfigPermissions(o *DoctorOptions) []doctorFinding {
+	findings := []doctorFinding{}
+	for _, filename := range o.ConfigAccess.GetLoadingPrecedence() {
+		if _, err := os.Stat(filename); os.IsNotExist(err) {
+			continue
+		}
+		problem, err := privateFileProblem(filename)
+		switch {
+		case err != nil:
+			findings = append(findings, doctorFinding{Severity: doctorWarning, Message: fmt.Sprintf("cannot check who can access %s: %v", filename, err)})
+		case len(problem) > 0:
+			filename := filename
+			findings = append(findings, doctorFinding{
+				Severity: doctorWarning,
+				Message:  fmt.Sprintf("%s holds credentials and %s", filename, problem),
+				Fix:      makeFilePrivateHint(filename),
+				Repair:   func() error { return makeFilePrivate(filename) },
+			})
+		default:
+			findings = append(findings, doctorFinding{Severity: doctorOK, Message: fmt.Sprintf("%s can only be accessed by its owner", filename)})
+		}
+	}
+	return findings
+}
+
+func checkExecPlugins(o *DoctorOptions) []doctorFinding {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return []doctorFinding{{Severity: doctorError, Message: fmt.Sprintf("cannot load kubeconfig: %v", err)}}
+	}
+
+	// Group users by command so a missing plugin is reported once.
+	users := map[string][]string{}
+	for name, authInfo := range config.AuthInfos {
+		if authInfo.Exec == nil || len(authInfo.Exec.Command) == 0 {
+			continue
+		}
+		command := authInfo.Exec.Command
+		if command == "kubectl" && len(authInfo.Exec.Args) > 0 && authInfo.Exec.Args[0] == "oidc-login" {
+			command = "kubectl-oidc_login"
+		}
+		users[command] = append(users[command], name)
+	}
+	commands := make([]string, 0, len(users))
+	for command := range users {
+		commands = append(commands, command)
+	}
+	sort.Strings(commands)
+
+	findings := []doctorFinding{}
+	for _, command := range commands {
+		names := users[command]
+		sort.Strings(names)
+		path, err := o.LookPath(command)
+		if err != nil {
+			fix := execPluginInstallHints[command]
+			if len(fix) == 0 {
+				fix = fmt.Sprintf("install %q or add the directory containing it to your PATH", command)
+			}
+			findings = append(findings, doctorFinding{
+				Severity: doctorError,
+				Message:  fmt.Sprintf("exec plugin %q used by %s was not found", command, strings.Join(names, ", ")),
+				Fix:      fix,
+			})
+			continue
+		}
+		findings = append(findings, doctorFinding{Severity: doctorOK, Message: fmt.Sprintf("exec plugin %q found at %s", command, path)})
+	}
+	return findings
+}
+
+func checkPathEnv(o *DoctorOptions) []doctorFinding {
+	value := o.Getenv("PATH")
+	if len(value) == 0 {
+		return []doctorFinding{{
+			Severity: doctorError,
+			Message:  "$PATH is empty, exec credential plugins cannot be found",
+			Fix:      "set PATH in your shell profile",
+		}}
+	}
+
+	findings := []doctorFinding{}
+	for _, dir := range filepath.SplitList(value) {
+		if strings.HasPrefix(dir, "~") {
+			findings = append(findings, doctorFinding{
+				Severity: doctorWarning,
+				Message:  fmt.Sprintf("$PATH entry %q starts with ~, which kubectl does not expand when running exec plugins", dir),
+				Fix:      "use $HOME instead of ~ in PATH",
+			})
+		}
+	}
+	if len(findings) == 0 {
+		findings = append(findings, doctorFinding{Severity: doctorOK, Message: "$PATH is well formed"})
+	}
+	return findings
+}
+
+func checkCacheDirs(o *DoctorOptions) []doctorFinding {
+	if len(o.HomeDir) == 0 {
+		return nil
+	}
+
+	findings := []doctorFinding{}
+	for _, dir := range []string{
+		filepath.Join(o.HomeDir, ".kube", "cache"),
+		filepath.Join(o.HomeDir, ".kube", "http-cache"),
+	} {
+		info, err := os.Stat(dir)
+		switch {
+		case os.IsNotExist(err):
+			continue
+		case err != nil:
+			findings = append(findings, doctorFinding{Severity: doctorError, Message: fmt.Sprintf("cannot access cache directory %s: %v", dir, err), Fix: "check the permissions of " + dir})
+		case !info.IsDir():
+			findings = append(findings, doctorFinding{Severity: doctorError, Message: fmt.Sprintf("cache directory %s is not a directory", dir), Fix: "remove " + dir})
+		case o.NoWrite:
+			// Without writing a probe file, the mode of the directory tells whether it is writable.
+			if info.Mode().Perm()&0200 == 0 {
+				findings = append(findings, doctorFinding{Severity: doctorError, Message: fmt.Sprintf("cache directory %s is not writable (mode %04o)", dir, info.Mode().Perm()), Fix: "fix the permissions of " + dir + ", or remove it so kubectl recreates it"})
+				continue
+			}
+			findings = append(findings, doctorFinding{Severity: doctorOK, Message: fmt.Sprintf("cache directory %s is writable, judged by its mode", dir)})
+		default:
+			probe, err := ioutil.TempFile(dir, ".doctor")
+			if err != nil {
+				findings = append(findings, doctorFinding{Severity: doctorError, Message: fmt.Sprintf("cache directory %s is not writable: %v", dir, err), Fix: "fix the ownership of " + dir + ", or remove it so kubectl recreates it"})
+				continue
+			}
+			probe.Close()
+			os.Remove(probe.Name())
+			findings = append(findings, doctorFinding{Severity: doctorOK, Message: fmt.Sprintf("cache directory %s is writable", dir)})
+		}
+	}
+	return findings
+}
+
+func checkLockedEntries(o *DoctorOptions) []doctorFinding {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return []doctorFinding{{Severity: doctorError, Message: fmt.Sprintf("cannot load kubeconfig: %v", err)}}
+	}
+	locked, drift, err := lockDrift(config)
+	switch {
+	case err != nil:
+		return []doctorFinding{{Severity: doctorWarning, Message: fmt.Sprintf("cannot check the locked entries: %v", err)}}
+	case locked == 0:
+		return nil
+	case len(drift) == 0:
+		return []doctorFinding{{Severity: doctorOK, Message: fmt.Sprintf("%d locked entries are unchanged", locked)}}
+	}
+
+	findings := []doctorFinding{}
+	for _, message := range drift {
+		findings = append(findings, doctorFinding{
+			Severity: doctorWarning,
+			Message:  message,
+			Fix:      "review the change, then run \"kubectl config lock\" on the entry to accept it, or restore the entry",
+		})
+	}
+	return findings
+}
@@ -0,0 +1,245 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+type doctorTest struct {
+	description    string
+	config         clientcmdapi.Config
+	env            map[string]string
+	installed      []string
+	expectedErr    bool
+	expectedOutput []string
+}
+
+func TestDoctorHealthy(t *testing.T) {
+	config := newRedFederalCowHammerConfig()
+	config.AuthInfos["eks-user"] = &clientcmdapi.AuthInfo{Exec: &clientcmdapi.ExecConfig{Command: "aws", Args: []string{"eks", "get-token"}}}
+	test := doctorTest{
+		description: "all plugins installed",
+		config:      config,
+		env:         map[string]string{"PATH": "/usr/bin"},
+		installed:   []string{"aws"},
+		expectedOutput: []string{
+			"[OK]\t$KUBECONFIG is not set",
+			`[OK]	exec plugin "aws" found at /usr/bin/aws`,
+			"[OK]\t$PATH is well formed",
+		},
+	}
+	test.run(t)
+}
+
+func TestDoctorMissingPlugin(t *testing.T) {
+	config := newRedFederalCowHammerConfig()
+	config.AuthInfos["gke-user"] = &clientcmdapi.AuthInfo{Exec: &clientcmdapi.ExecConfig{Command: "gke-gcloud-auth-plugin"}}
+	test := doctorTest{
+		description: "missing gke-gcloud-auth-plugin",
+		config:      config,
+		env:         map[string]string{"PATH": "/usr/bin"},
+		expectedErr: true,
+		expectedOutput: []string{
+			`[ERROR]	exec plugin "gke-gcloud-auth-plugin" used by gke-user was not found`,
+			"\tfix: run: gcloud components install gke-gcloud-auth-plugin",
+		},
+	}
+	test.run(t)
+}
+
+func TestDoctorKubeconfigEnv(t *testing.T) {
+	sep := string(os.PathListSeparator)
+	test := doctorTest{
+		description: "malformed KUBECONFIG",
+		config:      newRedFederalCowHammerConfig(),
+		env: map[string]string{
+			"PATH":       "/usr/bin",
+			"KUBECONFIG": "~/.kube/config" + sep + sep + "/does/not/exist",
+		},
+		expectedErr: true,
+		expectedOutput: []string{
+			`[ERROR]	$KUBECONFIG entry "~/.kube/config" starts with ~, which is not expanded`,
+			"[WARN]\t$KUBECONFIG contains an empty entry",
+			`[WARN]	$KUBECONFIG entry "/does/not/exist" does not exist and is ignored`,
+		},
+	}
+	test.run(t)
+}
+
+func TestDoctorUnexpandedWindowsVariable(t *testing.T) {
+	test := doctorTest{
+		description: "%USERPROFILE% in KUBECONFIG",
+		config:      newRedFederalCowHammerConfig(),
+		env: map[string]string{
+			"PATH":       "/usr/bin",
+			"KUBECONFIG": `%USERPROFILE%\.kube\config`,
+		},
+		expectedErr:    true,
+		expectedOutput: []string{`[ERROR]	$KUBECONFIG entry "%USERPROFILE%\\.kube\\config" contains a %VAR% reference, which is not expanded`},
+	}
+	test.run(t)
+}
+
+func TestDoctorFixesKubeconfigPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes do not control access on Windows")
+	}
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	if err := os.Chmod(fakeKubeFile.Name(), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	findings := checkKubeconfigPermissions(&DoctorOptions{ConfigAccess: pathOptions})
+	if len(findings) != 1 || findings[0].Severity != doctorWarning || !strings.Contains(findings[0].Message, "accessible by other users (mode 0644)") {
+		t.Fatalf("unexpected findings: %#v", findings)
+	}
+	if findings[0].Fix != "run: chmod 600 "+fakeKubeFile.Name() {
+		t.Errorf("unexpected fix %q", findings[0].Fix)
+	}
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	options := &DoctorOptions{
+		ConfigAccess: pathOptions,
+		Fix:          true,
+		Getenv:       func(key string) string { return "/usr/bin" },
+		IOStreams:    streams,
+	}
+	if err := options.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "accessible by other users (mode 0644)\n\tfixed\n") {
+		t.Errorf("expected the problem to be reported as fixed, got\n%s", out.String())
+	}
+	info, err := os.Stat(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600 after the fix, got %04o", info.Mode().Perm())
+	}
+}
+
+func TestDoctorEmptyPath(t *testing.T) {
+	test := doctorTest{
+		description:    "empty PATH",
+		config:         newRedFederalCowHammerConfig(),
+		expectedErr:    true,
+		expectedOutput: []string{"[ERROR]\t$PATH is empty"},
+	}
+	test.run(t)
+}
+
+func TestDoctorUnwritableCacheDir(t *testing.T) {
+	home, err := ioutil.TempDir("", "doctor")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(home)
+	if err := os.MkdirAll(filepath.Join(home, ".kube"), 0755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// A regular file where kubectl expects its discovery cache directory.
+	if err := ioutil.WriteFile(filepath.Join(home, ".kube", "cache"), nil, 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	findings := checkCacheDirs(&DoctorOptions{HomeDir: home})
+	if len(findings) != 1 || findings[0].Severity != doctorError || !strings.Contains(findings[0].Message, "is not a directory") {
+		t.Errorf("unexpected findings: %#v", findings)
+	}
+}
+
+func TestDoctorCacheDirsNoWrite(t *testing.T) {
+	home, err := ioutil.TempDir("", "doctor")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(home)
+	cache := filepath.Join(home, ".kube", "cache")
+	if err := os.MkdirAll(cache, 0755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(home, ".kube", "http-cache"), 0555); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	findings := checkCacheDirs(&DoctorOptions{HomeDir: home, NoWrite: true})
+	if len(findings) != 2 || findings[0].Severity != doctorOK || findings[1].Severity != doctorError {
+		t.Errorf("unexpected findings: %#v", findings)
+	}
+	if entries, err := ioutil.ReadDir(cache); err != nil || len(entries) > 0 {
+		t.Errorf("expected no probe file with --no-write, got %v, %v", entries, err)
+	}
+}
+
+func (test doctorTest) run(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	if err := clientcmd.WriteToFile(test.config, fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+	streams, _, buf, _ := genericclioptions.NewTestIOStreams()
+	options := &DoctorOptions{
+		ConfigAccess: pathOptions,
+		EnvVar:       "KUBECONFIG",
+		Getenv:       func(key string) string { return test.env[key] },
+		LookPath: func(file string) (string, error) {
+			for _, installed := range test.installed {
+				if installed == file {
+					return "/usr/bin/" + file, nil
+				}
+			}
+			return "", fmt.Errorf("%s: not found", file)
+		},
+		IOStreams: streams,
+	}
+
+	err = options.Run()
+	if test.expectedErr != (err != nil) {
+		t.Errorf("%s: expected error %v, got %v", test.description, test.expectedErr, err)
+	}
+	for _, expected := range test.expectedOutput {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("%s: expected %q in output, got\n%s", test.description, expected, buf.String())
+		}
+	}
+}
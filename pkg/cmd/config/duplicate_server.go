@@ -0,0 +1,72 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// flagAllowDuplicateServer lets set-cluster and import add a cluster entry pointing at the same
+// server, with the same certificate authority, as another entry.
+const flagAllowDuplicateServer = "allow-duplicate-server"
+
+// sameServer reports whether a and b reach the same API server and trust it the same way.
+func sameServer(a, b *clientcmdapi.Cluster) bool {
+	return len(a.Server) > 0 &&
+		strings.TrimSuffix(a.Server, "/") == strings.TrimSuffix(b.Server, "/") &&
+		a.CertificateAuthority == b.CertificateAuthority &&
+		bytes.Equal(a.CertificateAuthorityData, b.CertificateAuthorityData)
+}
+
+// findDuplicateServer returns the name of the first cluster of clusters, other than name, that
+// points at the same server as cluster, or an empty string if there is none.
+func findDuplicateServer(clusters map[string]*clientcmdapi.Cluster, name string, cluster *clientcmdapi.Cluster) string {
+	for _, other := range sortedClusterNames(clusters) {
+		if other != name && sameServer(clusters[other], cluster) {
+			return other
+		}
+	}
+	return ""
+}
+
+// reuseDuplicateServers drops the clusters of from whose name is free in into but whose server is
+// already reached by another cluster of into, and points the contexts of from at that cluster
+// instead. It returns a description of every cluster reused.
+func reuseDuplicateServers(into, from *clientcmdapi.Config) []string {
+	reused := []string{}
+	for _, name := range sortedClusterNames(from.Clusters) {
+		if into.Clusters[name] != nil {
+			continue
+		}
+		existing := findDuplicateServer(into.Clusters, name, from.Clusters[name])
+		if len(existing) == 0 {
+			continue
+		}
+		delete(from.Clusters, name)
+		for _, context := range from.Contexts {
+			if context.Cluster == name {
+				context.Cluster = existing
+			}
+		}
+		reused = append(reused, fmt.Sprintf("cluster %q as %q, which points at the same server", name, existing))
+	}
+	return reused
+}
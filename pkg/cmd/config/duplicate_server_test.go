@@ -0,0 +1,105 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestSameServer(t *testing.T) {
+	tests := []struct {
+		a, b     clientcmdapi.Cluster
+		expected bool
+	}{
+		{a: clientcmdapi.Cluster{Server: "https://k8s:6443"}, b: clientcmdapi.Cluster{Server: "https://k8s:6443/"}, expected: true},
+		{a: clientcmdapi.Cluster{Server: "https://k8s:6443", CertificateAuthorityData: []byte("ca")}, b: clientcmdapi.Cluster{Server: "https://k8s:6443", CertificateAuthorityData: []byte("ca")}, expected: true},
+		{a: clientcmdapi.Cluster{Server: "https://k8s:6443", CertificateAuthorityData: []byte("ca")}, b: clientcmdapi.Cluster{Server: "https://k8s:6443", CertificateAuthorityData: []byte("other")}, expected: false},
+		{a: clientcmdapi.Cluster{Server: "https://k8s:6443", CertificateAuthority: "/ca.crt"}, b: clientcmdapi.Cluster{Server: "https://k8s:6443"}, expected: false},
+		{a: clientcmdapi.Cluster{Server: "https://k8s:6443"}, b: clientcmdapi.Cluster{Server: "https://other:6443"}, expected: false},
+		{a: clientcmdapi.Cluster{}, b: clientcmdapi.Cluster{}, expected: false},
+	}
+	for _, test := range tests {
+		if actual := sameServer(&test.a, &test.b); actual != test.expected {
+			t.Errorf("sameServer(%+v, %+v): expected %v, got %v", test.a, test.b, test.expected, actual)
+		}
+	}
+}
+
+func TestReuseDuplicateServers(t *testing.T) {
+	into := clientcmdapi.NewConfig()
+	into.Clusters["prod"] = &clientcmdapi.Cluster{Server: "https://prod:6443", CertificateAuthorityData: []byte("ca")}
+	from := clientcmdapi.NewConfig()
+	from.Clusters["prod-copy"] = &clientcmdapi.Cluster{Server: "https://prod:6443", CertificateAuthorityData: []byte("ca")}
+	from.Clusters["staging"] = &clientcmdapi.Cluster{Server: "https://staging:6443"}
+	from.Contexts["admin@prod"] = &clientcmdapi.Context{Cluster: "prod-copy", AuthInfo: "admin"}
+	from.Contexts["admin@staging"] = &clientcmdapi.Context{Cluster: "staging", AuthInfo: "admin"}
+
+	reused := reuseDuplicateServers(into, from)
+	if len(reused) != 1 || !strings.Contains(reused[0], `"prod-copy" as "prod"`) {
+		t.Errorf("unexpected reused clusters: %v", reused)
+	}
+	if _, ok := from.Clusters["prod-copy"]; ok {
+		t.Errorf("expected the duplicate cluster to be dropped")
+	}
+	if from.Contexts["admin@prod"].Cluster != "prod" {
+		t.Errorf("expected the context to use cluster prod, got %q", from.Contexts["admin@prod"].Cluster)
+	}
+	if from.Contexts["admin@staging"].Cluster != "staging" || from.Clusters["staging"] == nil {
+		t.Errorf("expected the staging cluster to be left alone")
+	}
+}
+
+func TestSetClusterRefusesDuplicateServer(t *testing.T) {
+	kubeconfig, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(kubeconfig.Name())
+	config := clientcmdapi.NewConfig()
+	config.Clusters["prod"] = &clientcmdapi.Cluster{Server: "https://prod:6443"}
+	if err := clientcmd.WriteToFile(*config, kubeconfig.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = kubeconfig.Name()
+	pathOptions.EnvVar = ""
+
+	o := CreateClusterOptions{ConfigAccess: pathOptions, Name: "prod-2"}
+	o.Server.Set("https://prod:6443/")
+	err = o.Run()
+	if err == nil || !strings.Contains(err.Error(), `cluster "prod" already points at https://prod:6443/`) {
+		t.Fatalf("expected the duplicate server to be refused, got %v", err)
+	}
+
+	o.AllowDuplicateServer = true
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	config, err = clientcmd.LoadFromFile(kubeconfig.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Clusters["prod-2"] == nil {
+		t.Errorf("expected cluster prod-2 to be added with --%s", flagAllowDuplicateServer)
+	}
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/printers"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// effectiveConfig is the client configuration a context resolves to, with the defaults filled in.
+type effectiveConfig struct {
+	Context      string   `json:"context"`
+	Namespace    string   `json:"namespace"`
+	Cluster      string   `json:"cluster"`
+	User         string   `json:"user"`
+	Server       string   `json:"server,omitempty"`
+	KubectlFlags []string `json:"kubectlFlags,omitempty"`
+	// Timeout is empty when requests do not time out.
+	Timeout string  `json:"timeout,omitempty"`
+	QPS     float32 `json:"qps"`
+	Burst   int     `json:"burst"`
+}
+
+// EffectiveOptions holds the command-line options for 'config effective' sub command
+type EffectiveOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Context      string
+	Output       string
+
+	genericclioptions.IOStreams
+}
+
+var (
+	effectiveLong = templates.LongDesc(`
+		Show the client configuration a context resolves to: its namespace, cluster, user and
+		server, the kubectl flags set with "kubectl config flags", and the request timeout, QPS and
+		burst of its clients.
+
+		The current context is shown unless a context is named. Settings the context leaves out
+		are shown with their defaults: the "default" namespace, no timeout, and the QPS and burst
+		of client-go. The timeout, QPS and burst are those set with "kubectl config tuning"; a
+		--request-timeout among the kubectl flags wins over the timeout for "kubectl config exec".`)
+
+	effectiveExample = templates.Examples(`
+		# Show the client configuration of the current context
+		kubectl config effective
+
+		# Show the client configuration of prod as JSON
+		kubectl config effective prod -o json`)
+)
+
+// NewCmdConfigEffective returns a Command instance for 'config effective' sub command
+func NewCmdConfigEffective(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &EffectiveOptions{ConfigAccess: configAccess, Output: "text", IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "effective [CONTEXT_NAME] [-o text|json]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Show the client configuration a context resolves to"),
+		Long:                  effectiveLong,
+		Example:               effectiveExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) > 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args[1:]))
+			}
+			if len(args) == 1 {
+				o.Context = args[0]
+			}
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	cmd.Flags().StringVarP(&o.Output, "output", "o", o.Output, "Output format. One of: text|json")
+	return cmd
+}
+
+// Validate makes sure the output format is supported
+func (o *EffectiveOptions) Validate() error {
+	if o.Output != "text" && o.Output != "json" {
+		return fmt.Errorf("unsupported output format %q, must be one of: text, json", o.Output)
+	}
+	return nil
+}
+
+// Run performs the execution of 'config effective' sub command
+func (o *EffectiveOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	effective, err := effectiveContextConfig(config, o.Context)
+	if err != nil {
+		return err
+	}
+
+	if o.Output == "json" {
+		data, err := json.Marshal(effective)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(o.Out, "%s\n", data)
+		return err
+	}
+	w := printers.GetNewTabWriter(o.Out)
+	fmt.Fprintf(w, "Context:\t%s\n", effective.Context)
+	fmt.Fprintf(w, "Namespace:\t%s\n", effective.Namespace)
+	fmt.Fprintf(w, "Cluster:\t%s\n", valueOrNone(effective.Cluster))
+	fmt.Fprintf(w, "User:\t%s\n", valueOrNone(effective.User))
+	fmt.Fprintf(w, "Server:\t%s\n", valueOrNone(effective.Server))
+	fmt.Fprintf(w, "Kubectl flags:\t%s\n", valueOrNone(strings.Join(effective.KubectlFlags, " ")))
+	fmt.Fprintf(w, "Timeout:\t%s\n", valueOrNone(effective.Timeout))
+	fmt.Fprintf(w, "QPS:\t%s\n", strconv.FormatFloat(float64(effective.QPS), 'g', -1, 32))
+	fmt.Fprintf(w, "Burst:\t%d\n", effective.Burst)
+	return w.Flush()
+}
+
+// effectiveContextConfig resolves the client configuration of the context called name, the current
+// context when name is empty. The client tuning is applied the way the clients of the config
+// subcommands apply it.
+func effectiveContextConfig(config *clientcmdapi.Config, name string) (effectiveConfig, error) {
+	info, err := describeContext(config, name)
+	if err != nil {
+		return effectiveConfig{}, err
+	}
+	context := config.Contexts[info.Context]
+	flags, err := readKubectlFlags(context)
+	if err != nil {
+		return effectiveConfig{}, err
+	}
+	restConfig := &rest.Config{QPS: rest.DefaultQPS, Burst: rest.DefaultBurst}
+	if err := applyClientTuning(restConfig, context); err != nil {
+		return effectiveConfig{}, fmt.Errorf("context %q: %v", info.Context, err)
+	}
+
+	effective := effectiveConfig{
+		Context:      info.Context,
+		Namespace:    info.Namespace,
+		Cluster:      info.Cluster,
+		User:         info.User,
+		Server:       info.Server,
+		KubectlFlags: flags,
+		QPS:          restConfig.QPS,
+		Burst:        restConfig.Burst,
+	}
+	if restConfig.Timeout > 0 {
+		effective.Timeout = restConfig.Timeout.String()
+	}
+	return effective, nil
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestEffective(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	config := newRedFederalCowHammerConfig()
+	config.Contexts["prod"] = &clientcmdapi.Context{AuthInfo: "red-user", Cluster: "cow-cluster", Namespace: "web"}
+	if err := writeClientTuning(config.Contexts["prod"], clientTuning{Timeout: "2m0s", QPS: 50}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := writeKubectlFlags(config.Contexts["prod"], []string{"--as=admin"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := clientcmd.WriteToFile(config, fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	tests := []struct {
+		context  string
+		output   string
+		expected []string
+	}{
+		{
+			context:  "",
+			output:   "text",
+			expected: []string{"Context: federal-context\n", "Namespace: default\n", "Kubectl flags: <none>\n", "Timeout: <none>\n", "QPS: 5\n", "Burst: 10\n"},
+		},
+		{
+			context:  "prod",
+			output:   "text",
+			expected: []string{"Namespace: web\n", "Server: http://cow.org:8080\n", "Kubectl flags: --as=admin\n", "Timeout: 2m0s\n", "QPS: 50\n", "Burst: 10\n"},
+		},
+		{
+			context:  "prod",
+			output:   "json",
+			expected: []string{`"kubectlFlags":["--as=admin"],"timeout":"2m0s","qps":50,"burst":10}`},
+		},
+	}
+	for _, test := range tests {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		o := &EffectiveOptions{ConfigAccess: pathOptions, Context: test.context, Output: test.output, IOStreams: streams}
+		if err := o.Validate(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := o.Run(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// The columns are aligned with spaces, whose number depends on the widest label.
+		output := strings.Join(strings.FieldsFunc(out.String(), func(r rune) bool { return r == ' ' }), " ")
+		for _, expected := range test.expected {
+			if !strings.Contains(output, expected) {
+				t.Errorf("expected %q in the output of %q, got:\n%s", expected, test.context, out.String())
+			}
+		}
+	}
+
+	o := &EffectiveOptions{ConfigAccess: pathOptions, Context: "missing", Output: "text"}
+	if err := o.Run(); err == nil || !strings.Contains(err.Error(), `no context exists with the name: "missing"`) {
+		t.Errorf("expected a missing context to fail, got %v", err)
+	}
+}
@@ -0,0 +1,456 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	clientauthv1beta1 "k8s.io/client-go/pkg/apis/clientauthentication/v1beta1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// ageIdentityEnv is the environment variable naming the age identity file the credentials of
+// encrypted users are decrypted with, when none was given to "config encrypt-user".
+const ageIdentityEnv = "KUBECTL_CONFIG_AGE_IDENTITY"
+
+var validEncryptionTools = []string{"age", "gpg"}
+
+// encryptedUser is stored in the encryptedUserExtension of a user whose credentials were
+// encrypted by "config encrypt-user".
+type encryptedUser struct {
+	// Tool is the command the credentials were encrypted with, age or gpg.
+	Tool       string   `json:"tool"`
+	Recipients []string `json:"recipients"`
+	// Identity is the age identity file to decrypt the credentials with.
+	Identity string `json:"identity,omitempty"`
+	// Data is the ASCII armored encryption of the JSON of the userCredentials.
+	Data string `json:"data"`
+}
+
+// userCredentials are the fields of a user encrypted by "config encrypt-user".
+type userCredentials struct {
+	Token                 string                   `json:"token,omitempty"`
+	ClientCertificateData []byte                   `json:"clientCertificateData,omitempty"`
+	ClientKeyData         []byte                   `json:"clientKeyData,omitempty"`
+	Exec                  *clientcmdapi.ExecConfig `json:"exec,omitempty"`
+}
+
+// EncryptUserOptions holds the command-line options for 'config encrypt-user' sub command
+type EncryptUserOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	User         string
+	Recipients   []string
+	Tool         string
+	Identity     string
+	Decrypt      bool
+
+	RunCommand commandRunner
+
+	genericclioptions.IOStreams
+}
+
+var (
+	encryptUserLong = templates.LongDesc(`
+		Encrypt the credentials of a user with age or GnuPG, keeping the rest of kubeconfig in
+		plain text.
+
+		The token, client certificate and key, and exec credential plugin of the user are
+		encrypted for the recipients given with --recipient, and the user gets "kubectl config
+		encrypted-credential" as its exec credential plugin, which decrypts them each time the
+		user is used. Files named by the user are read and embedded first, they are left on disk.
+		Users with a password or an auth provider cannot be encrypted, since an exec credential
+		plugin cannot give them to kubectl.
+
+		The tool is age when the recipients are age public keys, starting with age1, and gpg
+		otherwise. age decrypts with the identity file given with --identity, or named by the
+		` + ageIdentityEnv + ` environment variable. gpg decrypts with the keys of its agent.
+
+		With --decrypt, the credentials of the user are decrypted back into kubeconfig.`)
+
+	encryptUserExample = templates.Examples(`
+		# Encrypt the credentials of prod-admin for an age key
+		kubectl config encrypt-user prod-admin --recipient age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p --identity ~/.config/age/keys.txt
+
+		# Encrypt the credentials of prod-admin for two GnuPG keys
+		kubectl config encrypt-user prod-admin --recipient alice@example.com --recipient bob@example.com
+
+		# Store the credentials of prod-admin in plain text again
+		kubectl config encrypt-user prod-admin --decrypt`)
+)
+
+// NewCmdConfigEncryptUser returns a Command instance for 'config encrypt-user' sub command
+func NewCmdConfigEncryptUser(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &EncryptUserOptions{ConfigAccess: configAccess, RunCommand: runCommand, IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "encrypt-user USER_NAME (--recipient=RECIPIENT... [--tool=age|gpg] [--identity=FILE] | --decrypt)",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Encrypt the credentials of a user with age or GnuPG"),
+		Long:                  encryptUserLong,
+		Example:               encryptUserExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.User = args[0]
+			cmdutil.CheckErr(o.Complete())
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&o.Recipients, "recipient", o.Recipients, "Recipient to encrypt the credentials for, an age public key or a GnuPG key ID or email")
+	cmd.Flags().StringVar(&o.Tool, "tool", o.Tool, "Tool to encrypt with, one of: "+strings.Join(validEncryptionTools, ", ")+", guessed from the recipients by default")
+	cmd.Flags().StringVar(&o.Identity, "identity", o.Identity, "age identity file to decrypt the credentials with")
+	cmd.MarkFlagFilename("identity")
+	cmd.Flags().BoolVar(&o.Decrypt, "decrypt", o.Decrypt, "If true, decrypt the credentials of the user back into kubeconfig")
+	return cmd
+}
+
+// Complete guesses the tool from the recipients, and makes the identity file absolute
+func (o *EncryptUserOptions) Complete() error {
+	if len(o.Tool) == 0 && len(o.Recipients) > 0 {
+		o.Tool = encryptionTool(o.Recipients)
+	}
+	if len(o.Identity) > 0 {
+		identity, err := filepath.Abs(o.Identity)
+		if err != nil {
+			return err
+		}
+		o.Identity = identity
+	}
+	return nil
+}
+
+// Validate makes sure there are recipients to encrypt for
+func (o *EncryptUserOptions) Validate() error {
+	if o.Decrypt {
+		if len(o.Recipients) > 0 || len(o.Tool) > 0 || len(o.Identity) > 0 {
+			return errors.New("--recipient, --tool and --identity cannot be combined with --decrypt")
+		}
+		return nil
+	}
+	if len(o.Recipients) == 0 {
+		return errors.New("at least one --recipient is required")
+	}
+	if !sets.NewString(validEncryptionTools...).Has(o.Tool) {
+		return fmt.Errorf("invalid --tool %q, must be one of: %s", o.Tool, strings.Join(validEncryptionTools, ", "))
+	}
+	if len(o.Identity) > 0 && o.Tool != "age" {
+		return errors.New("--identity is only used by age")
+	}
+	return nil
+}
+
+// Run encrypts or decrypts the credentials of the user
+func (o *EncryptUserOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	authInfo, ok := config.AuthInfos[o.User]
+	if !ok {
+		return fmt.Errorf("no user exists with the name: %q", o.User)
+	}
+	encrypted := &encryptedUser{}
+	found, err := readExtension(authInfo.Extensions, encryptedUserExtension, encrypted)
+	if err != nil {
+		return err
+	}
+
+	if o.Decrypt {
+		if !found {
+			return fmt.Errorf("the credentials of user %q are not encrypted", o.User)
+		}
+		credentials, err := decryptUserCredentials(encrypted, o.RunCommand)
+		if err != nil {
+			return err
+		}
+		authInfo.Token = credentials.Token
+		authInfo.ClientCertificateData = credentials.ClientCertificateData
+		authInfo.ClientKeyData = credentials.ClientKeyData
+		authInfo.Exec = credentials.Exec
+		delete(authInfo.Extensions, encryptedUserExtension)
+	} else {
+		if found {
+			return fmt.Errorf("the credentials of user %q are encrypted already, decrypt them first with --decrypt", o.User)
+		}
+		credentials, err := readUserCredentials(authInfo)
+		if err != nil {
+			return fmt.Errorf("user %q: %v", o.User, err)
+		}
+		encrypted = &encryptedUser{Tool: o.Tool, Recipients: o.Recipients, Identity: o.Identity}
+		if err := encryptUserCredentials(encrypted, credentials, o.RunCommand); err != nil {
+			return err
+		}
+		if err := writeExtension(&authInfo.Extensions, encryptedUserExtension, encrypted); err != nil {
+			return err
+		}
+		authInfo.Token, authInfo.TokenFile = "", ""
+		authInfo.ClientCertificate, authInfo.ClientCertificateData = "", nil
+		authInfo.ClientKey, authInfo.ClientKeyData = "", nil
+		args := []string{"config", "encrypted-credential", o.User}
+		if o.ConfigAccess.IsExplicitFile() {
+			args = append(args, "--"+clientcmd.RecommendedConfigPathFlag, o.ConfigAccess.GetExplicitFile())
+		}
+		authInfo.Exec = &clientcmdapi.ExecConfig{
+			APIVersion: clientauthv1beta1.SchemeGroupVersion.String(),
+			Command:    "kubectl",
+			Args:       args,
+		}
+	}
+
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+	if o.Decrypt {
+		fmt.Fprintf(o.Out, "Credentials of user %q decrypted.\n", o.User)
+	} else {
+		fmt.Fprintf(o.Out, "Credentials of user %q encrypted with %s.\n", o.User, o.Tool)
+	}
+	return nil
+}
+
+// readUserCredentials returns the credentials of a user to encrypt, embedding the files it names.
+func readUserCredentials(authInfo *clientcmdapi.AuthInfo) (*userCredentials, error) {
+	if len(authInfo.Username) > 0 || len(authInfo.Password) > 0 || authInfo.AuthProvider != nil {
+		return nil, errors.New("a password or an auth provider cannot be given by an exec credential plugin")
+	}
+	credentials := &userCredentials{
+		Token:                 authInfo.Token,
+		ClientCertificateData: authInfo.ClientCertificateData,
+		ClientKeyData:         authInfo.ClientKeyData,
+		Exec:                  authInfo.Exec,
+	}
+	var err error
+	if len(credentials.Token) == 0 && len(authInfo.TokenFile) > 0 {
+		data, err := ioutil.ReadFile(authInfo.TokenFile)
+		if err != nil {
+			return nil, err
+		}
+		credentials.Token = strings.TrimSpace(string(data))
+	}
+	if len(credentials.ClientCertificateData) == 0 && len(authInfo.ClientCertificate) > 0 {
+		if credentials.ClientCertificateData, err = ioutil.ReadFile(authInfo.ClientCertificate); err != nil {
+			return nil, err
+		}
+	}
+	if len(credentials.ClientKeyData) == 0 && len(authInfo.ClientKey) > 0 {
+		if credentials.ClientKeyData, err = ioutil.ReadFile(authInfo.ClientKey); err != nil {
+			return nil, err
+		}
+	}
+	if len(credentials.Token) == 0 && len(credentials.ClientKeyData) == 0 && credentials.Exec == nil {
+		return nil, errors.New("the user has no credentials to encrypt")
+	}
+	return credentials, nil
+}
+
+// encryptUserCredentials encrypts credentials for the recipients of encrypted, into its data.
+func encryptUserCredentials(encrypted *encryptedUser, credentials *userCredentials, run commandRunner) error {
+	plaintext, err := json.Marshal(credentials)
+	if err != nil {
+		return err
+	}
+	data, err := encryptForRecipients(encrypted.Tool, encrypted.Recipients, plaintext, run)
+	if err != nil {
+		return err
+	}
+	encrypted.Data = string(data)
+	return nil
+}
+
+// encryptionTool returns the tool encrypting for recipients: age when they are all age public
+// keys, gpg otherwise.
+func encryptionTool(recipients []string) string {
+	for _, recipient := range recipients {
+		if !strings.HasPrefix(recipient, "age1") {
+			return "gpg"
+		}
+	}
+	return "age"
+}
+
+// encryptForRecipients returns the ASCII armored encryption of plaintext for recipients with tool,
+// age or gpg.
+func encryptForRecipients(tool string, recipients []string, plaintext []byte, run commandRunner) ([]byte, error) {
+	// Neither tool takes its input as an argument, the plaintext only lives in a private
+	// temporary directory while it is encrypted.
+	dir, err := ioutil.TempDir("", "kubecfg-encrypt")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	input, output := filepath.Join(dir, "plaintext"), filepath.Join(dir, "encrypted.asc")
+	if err := ioutil.WriteFile(input, plaintext, 0600); err != nil {
+		return nil, err
+	}
+
+	var args []string
+	switch tool {
+	case "age":
+		args = []string{"--encrypt", "--armor"}
+	case "gpg":
+		args = []string{"--batch", "--yes", "--encrypt", "--armor"}
+	default:
+		return nil, fmt.Errorf("unknown encryption tool %q", tool)
+	}
+	for _, recipient := range recipients {
+		args = append(args, "--recipient", recipient)
+	}
+	if _, err := run(nil, tool, append(args, "--output", output, input)...); err != nil {
+		return nil, fmt.Errorf("%s: %v", tool, err)
+	}
+	return ioutil.ReadFile(output)
+}
+
+// decryptUserCredentials decrypts the credentials of an encrypted user.
+func decryptUserCredentials(encrypted *encryptedUser, run commandRunner) (*userCredentials, error) {
+	dir, err := ioutil.TempDir("", "kubecfg-decrypt")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	input := filepath.Join(dir, "credentials.asc")
+	if err := ioutil.WriteFile(input, []byte(encrypted.Data), 0600); err != nil {
+		return nil, err
+	}
+
+	var args []string
+	switch encrypted.Tool {
+	case "age":
+		identity := encrypted.Identity
+		if len(identity) == 0 {
+			identity = os.Getenv(ageIdentityEnv)
+		}
+		if len(identity) == 0 {
+			return nil, fmt.Errorf("no age identity to decrypt with, set %s", ageIdentityEnv)
+		}
+		args = []string{"--decrypt", "--identity", identity, input}
+	case "gpg":
+		args = []string{"--quiet", "--decrypt", input}
+	default:
+		return nil, fmt.Errorf("unknown encryption tool %q", encrypted.Tool)
+	}
+	plaintext, err := run(nil, encrypted.Tool, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", encrypted.Tool, err)
+	}
+	credentials := &userCredentials{}
+	if err := json.Unmarshal(plaintext, credentials); err != nil {
+		return nil, fmt.Errorf("invalid encrypted credentials: %v", err)
+	}
+	return credentials, nil
+}
+
+// EncryptedCredentialOptions holds the command-line options for 'config encrypted-credential' sub
+// command
+type EncryptedCredentialOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	User         string
+
+	RunCommand commandRunner
+	RunExec    execRunner
+
+	genericclioptions.IOStreams
+}
+
+// NewCmdConfigEncryptedCredential returns a Command instance for 'config encrypted-credential' sub
+// command
+func NewCmdConfigEncryptedCredential(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &EncryptedCredentialOptions{
+		ConfigAccess: configAccess,
+		RunCommand:   runCommand,
+		RunExec:      runExecPlugin,
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "encrypted-credential USER_NAME",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Print the decrypted credentials of a user encrypted by 'config encrypt-user'"),
+		Long: templates.LongDesc(`
+			Print the decrypted credentials of a user as an exec credential plugin does.
+
+			It is the exec plugin of the users encrypted by "kubectl config encrypt-user". When the
+			encrypted credentials are an exec plugin, that plugin is run and what it prints is
+			printed.`),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.User = args[0]
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	return noWriteCommand(cmd)
+}
+
+// Run decrypts the credentials of the user and prints them as an ExecCredential
+func (o *EncryptedCredentialOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	authInfo, ok := config.AuthInfos[o.User]
+	if !ok {
+		return fmt.Errorf("no user exists with the name: %q", o.User)
+	}
+	encrypted := &encryptedUser{}
+	found, err := readExtension(authInfo.Extensions, encryptedUserExtension, encrypted)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("user %q is not encrypted by 'kubectl config encrypt-user'", o.User)
+	}
+	credentials, err := decryptUserCredentials(encrypted, o.RunCommand)
+	if err != nil {
+		return err
+	}
+
+	credential := &clientauthv1beta1.ExecCredential{}
+	if credentials.Exec != nil {
+		if credential, err = o.RunExec(credentials.Exec); err != nil {
+			return err
+		}
+	} else {
+		credential.Status = &clientauthv1beta1.ExecCredentialStatus{
+			Token:                 credentials.Token,
+			ClientCertificateData: string(credentials.ClientCertificateData),
+			ClientKeyData:         string(credentials.ClientKeyData),
+		}
+	}
+	credential.APIVersion = clientauthv1beta1.SchemeGroupVersion.String()
+	credential.Kind = "ExecCredential"
+	return json.NewEncoder(o.Out).Encode(credential)
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	clientauthv1beta1 "k8s.io/client-go/pkg/apis/clientauthentication/v1beta1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// fakeAge "encrypts" with base64, and only decrypts with the identity it expects.
+func fakeAge(identity string) commandRunner {
+	return func(env []string, name string, args ...string) ([]byte, error) {
+		if name != "age" {
+			return nil, fmt.Errorf("unexpected command %s %v", name, args)
+		}
+		input := args[len(args)-1]
+		data, err := ioutil.ReadFile(input)
+		if err != nil {
+			return nil, err
+		}
+		switch args[0] {
+		case "--encrypt":
+			if strings.Join(args[:4], " ") != "--encrypt --armor --recipient age1prod" {
+				return nil, fmt.Errorf("unexpected arguments %v", args)
+			}
+			armored := "-----BEGIN AGE ENCRYPTED FILE-----\n" + base64.StdEncoding.EncodeToString(data) + "\n-----END AGE ENCRYPTED FILE-----\n"
+			return nil, ioutil.WriteFile(args[len(args)-2], []byte(armored), 0600)
+		case "--decrypt":
+			if args[2] != identity {
+				return nil, fmt.Errorf("no identity matched any of the recipients")
+			}
+			lines := strings.Split(string(data), "\n")
+			return base64.StdEncoding.DecodeString(lines[1])
+		}
+		return nil, fmt.Errorf("unexpected arguments %v", args)
+	}
+}
+
+func TestEncryptUser(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	config := newRedFederalCowHammerConfig()
+	config.AuthInfos["red-user"] = &clientcmdapi.AuthInfo{Token: "red-token"}
+	config.AuthInfos["password-user"] = &clientcmdapi.AuthInfo{Username: "admin", Password: "secret"}
+	if err := clientcmd.WriteToFile(config, fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := &EncryptUserOptions{ConfigAccess: pathOptions, User: "red-user", Recipients: []string{"age1prod"}, Identity: "/keys.txt", RunCommand: fakeAge("/keys.txt"), IOStreams: streams}
+	if err := o.Complete(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "Credentials of user \"red-user\" encrypted with age.\n"; out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+	contents, err := ioutil.ReadFile(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(string(contents), "red-token") {
+		t.Errorf("expected the token to be encrypted, got\n%s", contents)
+	}
+	saved, err := clientcmd.LoadFromFile(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if exec := saved.AuthInfos["red-user"].Exec; exec == nil || strings.Join(exec.Args, " ") != "config encrypted-credential red-user" {
+		t.Errorf("expected the user to decrypt its credentials with an exec plugin, got %+v", exec)
+	}
+	if err := o.Run(); err == nil || !strings.Contains(err.Error(), "encrypted already") {
+		t.Errorf("expected encrypting twice to fail, got %v", err)
+	}
+
+	credentialStreams, _, credentialOut, _ := genericclioptions.NewTestIOStreams()
+	credentialOptions := &EncryptedCredentialOptions{ConfigAccess: pathOptions, User: "red-user", RunCommand: fakeAge("/keys.txt"), IOStreams: credentialStreams}
+	if err := credentialOptions.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	credential := &clientauthv1beta1.ExecCredential{}
+	if err := json.Unmarshal(credentialOut.Bytes(), credential); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if credential.Kind != "ExecCredential" || credential.Status == nil || credential.Status.Token != "red-token" {
+		t.Errorf("expected the decrypted token, got %s", credentialOut.String())
+	}
+	credentialOptions.RunCommand = fakeAge("/other-keys.txt")
+	if err := credentialOptions.Run(); err == nil {
+		t.Errorf("expected decrypting with another identity to fail")
+	}
+
+	decryptOptions := &EncryptUserOptions{ConfigAccess: pathOptions, User: "red-user", Decrypt: true, RunCommand: fakeAge("/keys.txt"), IOStreams: streams}
+	if err := decryptOptions.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := decryptOptions.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	saved, err = clientcmd.LoadFromFile(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if user := saved.AuthInfos["red-user"]; user.Token != "red-token" || user.Exec != nil || len(user.Extensions) > 0 {
+		t.Errorf("expected the credentials to be decrypted, got %+v", user)
+	}
+
+	o.User = "password-user"
+	if err := o.Run(); err == nil || !strings.Contains(err.Error(), "password") {
+		t.Errorf("expected a user with a password to be refused, got %v", err)
+	}
+}
+
+func TestEncryptUserValidate(t *testing.T) {
+	tests := map[string]struct {
+		options      EncryptUserOptions
+		expectedTool string
+		expectedErr  string
+	}{
+		"age": {
+			options:      EncryptUserOptions{Recipients: []string{"age1prod", "age1backup"}},
+			expectedTool: "age",
+		},
+		"gpg": {
+			options:      EncryptUserOptions{Recipients: []string{"age1prod", "alice@example.com"}},
+			expectedTool: "gpg",
+		},
+		"no recipient": {
+			options:     EncryptUserOptions{},
+			expectedErr: "--recipient",
+		},
+		"gpg identity": {
+			options:     EncryptUserOptions{Recipients: []string{"alice@example.com"}, Identity: "keys.txt"},
+			expectedErr: "only used by age",
+		},
+		"decrypt for": {
+			options:     EncryptUserOptions{Recipients: []string{"age1prod"}, Decrypt: true},
+			expectedErr: "--decrypt",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			o := test.options
+			if err := o.Complete(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			err := o.Validate()
+			if len(test.expectedErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Errorf("expected error containing %q, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if o.Tool != test.expectedTool {
+				t.Errorf("expected tool %q, got %q", test.expectedTool, o.Tool)
+			}
+		})
+	}
+}
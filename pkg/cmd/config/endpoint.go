@@ -0,0 +1,309 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/printers"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// The endpoints a cluster can expose its API server at.
+const (
+	publicEndpoint  = "public"
+	privateEndpoint = "private"
+)
+
+// clusterEndpoint is the server and certificate authority of one endpoint of a cluster.
+type clusterEndpoint struct {
+	Server                   string `json:"server"`
+	CertificateAuthorityData []byte `json:"certificateAuthorityData,omitempty"`
+}
+
+// clusterEndpoints is stored in a cluster's clusterEndpointsExtension. Active names the endpoint
+// the server and certificate authority of the cluster currently are.
+type clusterEndpoints struct {
+	Active    string                     `json:"active,omitempty"`
+	Endpoints map[string]clusterEndpoint `json:"endpoints,omitempty"`
+}
+
+// EndpointOptions holds the command-line options for 'config endpoint' sub command
+type EndpointOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Context      string
+	// Action is set, use, or empty to list the endpoints of the cluster.
+	Action   string
+	Endpoint string
+	// Server and CertificateAuthority are the endpoint set records, the current ones of the
+	// cluster when empty.
+	Server               string
+	CertificateAuthority string
+	// Confirm asks before the contexts the confirm setting covers are changed.
+	Confirm changeConfirmer
+
+	genericclioptions.IOStreams
+}
+
+var (
+	endpointLong = templates.LongDesc(`
+		Switch the cluster of a context between its public and private endpoints.
+
+		Managed clusters such as EKS, GKE and AKS can expose their API server at a public and a
+		private endpoint, each with its own address and possibly its own certificate authority.
+		Both are recorded in the cluster, and "use" replaces its server and certificate authority
+		with the ones of the other endpoint in a single write of the kubeconfig, instead of
+		keeping a duplicate context per endpoint.
+
+		"set" records an endpoint, from --server and --certificate-authority or, without them,
+		from the current server and certificate authority of the cluster. Every context of the
+		cluster switches along with it.
+
+		Without an action, lists the endpoints recorded for the cluster.`)
+
+	endpointExample = templates.Examples(`
+		# Record the current server of the cluster of prod as its public endpoint
+		kubectl config endpoint prod set public
+
+		# Record its private endpoint
+		kubectl config endpoint prod set private --server=https://10.0.0.10 --certificate-authority=private-ca.crt
+
+		# Reach prod through its private endpoint, from inside the VPC
+		kubectl config endpoint prod use private
+
+		# List the endpoints of the cluster of the current-context
+		kubectl config endpoint .`)
+)
+
+// NewCmdConfigEndpoint returns a Command instance for 'config endpoint' sub command
+func NewCmdConfigEndpoint(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &EndpointOptions{ConfigAccess: configAccess, IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "endpoint CONTEXT_NAME [set|use public|private] [--server=SERVER] [--certificate-authority=FILE]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Switch the cluster of a context between its public and private endpoints"),
+		Long:                  endpointLong,
+		Example:               endpointExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().StringVar(&o.Server, "server", o.Server, "Server of the endpoint set records")
+	cmd.Flags().StringVar(&o.CertificateAuthority, "certificate-authority", o.CertificateAuthority, "Path to the certificate authority of the endpoint set records")
+	cmd.MarkFlagFilename("certificate-authority")
+	return cmd
+}
+
+// Complete sets the context, the action and the endpoint from the arguments
+func (o *EndpointOptions) Complete(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 && len(args) != 3 {
+		return helpErrorf(cmd, "Unexpected args: %v", args)
+	}
+	o.Context = args[0]
+	o.Confirm = newChangeConfirmer(cmd, o.ConfigAccess, o.In, o.ErrOut)
+	if len(args) == 3 {
+		o.Action, o.Endpoint = args[1], args[2]
+		return checkWritingArgs(cmd)
+	}
+	return nil
+}
+
+// Validate makes sure the action, the endpoint and the flags go together
+func (o *EndpointOptions) Validate() error {
+	switch o.Action {
+	case "":
+		if len(o.Server) > 0 || len(o.CertificateAuthority) > 0 {
+			return fmt.Errorf("--server and --certificate-authority can only be used with set")
+		}
+		return nil
+	case "set", "use":
+	default:
+		return fmt.Errorf("the action must be set or use, got %q", o.Action)
+	}
+	if o.Endpoint != publicEndpoint && o.Endpoint != privateEndpoint {
+		return fmt.Errorf("the endpoint must be %s or %s, got %q", publicEndpoint, privateEndpoint, o.Endpoint)
+	}
+	if o.Action == "use" && (len(o.Server) > 0 || len(o.CertificateAuthority) > 0) {
+		return fmt.Errorf("--server and --certificate-authority can only be used with set")
+	}
+	if len(o.Server) > 0 {
+		if u, err := url.Parse(o.Server); err != nil || len(u.Scheme) == 0 || len(u.Host) == 0 {
+			return fmt.Errorf("invalid server %q", o.Server)
+		}
+	}
+	return nil
+}
+
+// Run lists, records or switches the endpoints of the cluster of the context
+func (o *EndpointOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	name, err := resolveContextName(config, o.Context)
+	if err != nil {
+		return err
+	}
+	context, ok := config.Contexts[name]
+	if !ok {
+		return fmt.Errorf("no context exists with the name: %q", name)
+	}
+	cluster, ok := config.Clusters[context.Cluster]
+	if !ok {
+		return fmt.Errorf("context %q has no cluster %q", name, context.Cluster)
+	}
+	endpoints, err := readClusterEndpoints(cluster)
+	if err != nil {
+		return err
+	}
+
+	switch o.Action {
+	case "set":
+		endpoint := clusterEndpoint{Server: o.Server}
+		if len(endpoint.Server) == 0 {
+			if endpoint, err = currentClusterEndpoint(cluster); err != nil {
+				return err
+			}
+		}
+		if len(o.CertificateAuthority) > 0 {
+			if endpoint.CertificateAuthorityData, err = ioutil.ReadFile(o.CertificateAuthority); err != nil {
+				return err
+			}
+		}
+		endpoints.Endpoints[o.Endpoint] = endpoint
+		active, err := currentClusterEndpoint(cluster)
+		if err != nil {
+			return err
+		}
+		// The endpoint the cluster uses is the active one, and changing it changes the cluster.
+		if endpoints.Active == o.Endpoint || (len(endpoints.Active) == 0 && sameEndpoint(active, endpoint)) {
+			endpoints.Active = o.Endpoint
+			useClusterEndpoint(cluster, endpoint)
+		}
+		if err := writeExtension(&cluster.Extensions, clusterEndpointsExtension, endpoints); err != nil {
+			return err
+		}
+		if err := o.Confirm.check(contextsUsing(config, confirmCluster, context.Cluster)); err != nil {
+			return err
+		}
+		if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+			return err
+		}
+		fmt.Fprintf(o.Out, "Cluster %q: %s endpoint %s recorded.\n", context.Cluster, o.Endpoint, endpoint.Server)
+		return nil
+
+	case "use":
+		endpoint, ok := endpoints.Endpoints[o.Endpoint]
+		if !ok {
+			return fmt.Errorf("cluster %q has no %s endpoint, record it with: kubectl config endpoint %s set %s --server=SERVER", context.Cluster, o.Endpoint, name, o.Endpoint)
+		}
+		if endpoints.Active == o.Endpoint && cluster.Server == endpoint.Server {
+			fmt.Fprintf(o.Out, "Cluster %q already uses its %s endpoint %s.\n", context.Cluster, o.Endpoint, endpoint.Server)
+			return nil
+		}
+		endpoints.Active = o.Endpoint
+		useClusterEndpoint(cluster, endpoint)
+		if err := writeExtension(&cluster.Extensions, clusterEndpointsExtension, endpoints); err != nil {
+			return err
+		}
+		if err := o.Confirm.check(contextsUsing(config, confirmCluster, context.Cluster)); err != nil {
+			return err
+		}
+		if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+			return err
+		}
+		fmt.Fprintf(o.Out, "Cluster %q now uses its %s endpoint %s.\n", context.Cluster, o.Endpoint, endpoint.Server)
+		return nil
+	}
+
+	if len(endpoints.Endpoints) == 0 {
+		fmt.Fprintf(o.Out, "No endpoints recorded for cluster %q.\n", context.Cluster)
+		return nil
+	}
+	w := printers.GetNewTabWriter(o.Out)
+	defer w.Flush()
+	fmt.Fprintln(w, "ACTIVE\tENDPOINT\tSERVER\tCERTIFICATE-AUTHORITY")
+	for _, key := range []string{publicEndpoint, privateEndpoint} {
+		endpoint, ok := endpoints.Endpoints[key]
+		if !ok {
+			continue
+		}
+		active, ca := "", "cluster"
+		if key == endpoints.Active {
+			active = "*"
+		}
+		if len(endpoint.CertificateAuthorityData) == 0 {
+			ca = "system"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", active, key, endpoint.Server, ca)
+	}
+	return nil
+}
+
+// readClusterEndpoints returns the endpoints recorded for a cluster, which are empty when it has
+// none.
+func readClusterEndpoints(cluster *clientcmdapi.Cluster) (clusterEndpoints, error) {
+	endpoints := clusterEndpoints{}
+	if _, err := readExtension(cluster.Extensions, clusterEndpointsExtension, &endpoints); err != nil {
+		return endpoints, err
+	}
+	if endpoints.Endpoints == nil {
+		endpoints.Endpoints = map[string]clusterEndpoint{}
+	}
+	return endpoints, nil
+}
+
+// currentClusterEndpoint returns the server and certificate authority the cluster uses, reading
+// the certificate authority file it may reference.
+func currentClusterEndpoint(cluster *clientcmdapi.Cluster) (clusterEndpoint, error) {
+	endpoint := clusterEndpoint{Server: cluster.Server, CertificateAuthorityData: cluster.CertificateAuthorityData}
+	if len(endpoint.CertificateAuthorityData) == 0 && len(cluster.CertificateAuthority) > 0 {
+		data, err := ioutil.ReadFile(cluster.CertificateAuthority)
+		if err != nil {
+			return endpoint, err
+		}
+		endpoint.CertificateAuthorityData = data
+	}
+	return endpoint, nil
+}
+
+// useClusterEndpoint replaces the server and certificate authority of a cluster with the ones of
+// an endpoint.
+func useClusterEndpoint(cluster *clientcmdapi.Cluster, endpoint clusterEndpoint) {
+	cluster.Server = endpoint.Server
+	cluster.CertificateAuthority = ""
+	cluster.CertificateAuthorityData = endpoint.CertificateAuthorityData
+}
+
+// sameEndpoint reports whether two endpoints have the same server and certificate authority.
+func sameEndpoint(a, b clusterEndpoint) bool {
+	return a.Server == b.Server && bytes.Equal(a.CertificateAuthorityData, b.CertificateAuthorityData)
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func TestEndpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "endpoint")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := clientcmd.WriteToFile(newRedFederalCowHammerConfig(), filepath.Join(dir, "config")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	caFile := filepath.Join(dir, "private-ca.crt")
+	if err := ioutil.WriteFile(caFile, []byte("private-ca"), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = filepath.Join(dir, "config")
+	pathOptions.EnvVar = ""
+
+	run := func(context, action, endpoint, server, ca string) (string, error) {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		o := &EndpointOptions{
+			ConfigAccess:         pathOptions,
+			Context:              context,
+			Action:               action,
+			Endpoint:             endpoint,
+			Server:               server,
+			CertificateAuthority: ca,
+			IOStreams:            streams,
+		}
+		if err := o.Validate(); err != nil {
+			return "", err
+		}
+		err := o.Run()
+		return out.String(), err
+	}
+	cluster := func() (string, string) {
+		config, err := clientcmd.LoadFromFile(pathOptions.GlobalFile)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		cluster := config.Clusters["cow-cluster"]
+		return cluster.Server, string(cluster.CertificateAuthorityData)
+	}
+
+	if out, err := run("federal-context", "", "", "", ""); err != nil || out != "No endpoints recorded for cluster \"cow-cluster\".\n" {
+		t.Errorf("unexpected output %q, %v", out, err)
+	}
+	if _, err := run("federal-context", "use", "private", "", ""); err == nil {
+		t.Errorf("expected using an unknown endpoint to fail")
+	}
+	if _, err := run("federal-context", "set", "internal", "https://10.0.0.10", ""); err == nil {
+		t.Errorf("expected an endpoint other than public or private to be rejected")
+	}
+	if _, err := run("federal-context", "use", "private", "https://10.0.0.10", ""); err == nil {
+		t.Errorf("expected --server with use to be rejected")
+	}
+
+	if _, err := run("federal-context", "set", "public", "", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := run(".", "set", "private", "https://10.0.0.10", caFile); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if server, _ := cluster(); server != "http://cow.org:8080" {
+		t.Errorf("expected recording an inactive endpoint to keep the server, got %s", server)
+	}
+
+	out, err := run("federal-context", "use", "private", "", "")
+	if err != nil || out != "Cluster \"cow-cluster\" now uses its private endpoint https://10.0.0.10.\n" {
+		t.Errorf("unexpected output %q, %v", out, err)
+	}
+	if server, ca := cluster(); server != "https://10.0.0.10" || ca != "private-ca" {
+		t.Errorf("expected the private endpoint, got %s %q", server, ca)
+	}
+	if out, _ := run("federal-context", "use", "private", "", ""); !strings.Contains(out, "already uses") {
+		t.Errorf("expected the private endpoint to be in use, got %q", out)
+	}
+
+	out, err = run("federal-context", "", "", "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[2], "*") || !strings.Contains(lines[1], "http://cow.org:8080") {
+		t.Errorf("unexpected list %q", out)
+	}
+
+	if _, err := run("federal-context", "use", "public", "", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if server, ca := cluster(); server != "http://cow.org:8080" || ca != "" {
+		t.Errorf("expected the public endpoint, got %s %q", server, ca)
+	}
+}
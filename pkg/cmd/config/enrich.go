@@ -0,0 +1,240 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// Keys of the structured tags describing where a GKE cluster lives.
+const (
+	gcpProjectTag  = "gcp-project"
+	gcpLocationTag = "gcp-location"
+	gkeClusterTag  = "gke-cluster"
+)
+
+var (
+	// gkeEntryName matches the names gcloud gives the entries it writes: gke_PROJECT_LOCATION_CLUSTER.
+	gkeEntryName = regexp.MustCompile(`^gke_([^_]+)_([^_]+)_(.+)$`)
+	// gkeMembershipPath matches the server path of clusters reached through the Connect gateway.
+	gkeMembershipPath = regexp.MustCompile(`/projects/([^/]+)/locations/([^/]+)/(?:gkeMemberships|memberships)/([^/]+)`)
+)
+
+// EnrichOptions holds the command-line options for 'config enrich' sub commands
+type EnrichOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Contexts     []string
+
+	genericclioptions.IOStreams
+}
+
+var (
+	enrichLong = templates.LongDesc(`
+		Tag contexts with metadata derived from their entries.
+
+		"enrich gke" tags GKE contexts with gcp-project=PROJECT, gcp-location=LOCATION and
+		gke-cluster=CLUSTER. They are read from the gke_PROJECT_LOCATION_CLUSTER names gcloud
+		gives contexts and clusters, from --project, --location, --zone, --region and --cluster
+		arguments of the exec plugin, or from the server URL of clusters reached through the
+		Connect gateway. "kubectl config import" tags the GKE contexts it imports the same way.
+
+		The tags are stored in the kubecfg.io/metadata extension of the contexts and can be used
+		in selectors, such as --selector gcp-project=shop.`)
+
+	enrichExample = templates.Examples(`
+		# Tag every GKE context with its project, location and cluster
+		kubectl config enrich gke
+
+		# Tag a single context
+		kubectl config enrich gke gke_shop_europe-west1_prod`)
+)
+
+// NewCmdConfigEnrich returns a Command instance for 'config enrich' sub command
+func NewCmdConfigEnrich(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &EnrichOptions{
+		ConfigAccess: configAccess,
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "enrich SUBCOMMAND",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Tag contexts with metadata derived from their entries"),
+		Long:                  enrichLong,
+		Example:               enrichExample,
+		Run:                   cmdutil.DefaultSubCommandRun(streams.ErrOut),
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "gke [CONTEXT...]",
+		Short: i18n.T("Tag GKE contexts with their project, location and cluster"),
+		Run: func(cmd *cobra.Command, args []string) {
+			o.Contexts = args
+			cmdutil.CheckErr(o.RunGKE())
+		},
+	})
+	return cmd
+}
+
+// RunGKE tags the GKE contexts
+func (o *EnrichOptions) RunGKE() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	names := o.Contexts
+	if len(names) == 0 {
+		names = sortedContextNames(config.Contexts)
+	}
+
+	tagged := 0
+	for _, name := range names {
+		if _, ok := config.Contexts[name]; !ok {
+			return fmt.Errorf("no context exists with the name: %q", name)
+		}
+		changed, err := enrichGKEContext(config, name)
+		if err != nil {
+			return fmt.Errorf("context %q: %v", name, err)
+		}
+		if changed {
+			tagged++
+		}
+	}
+	if tagged > 0 {
+		if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(o.Out, "Tagged %d GKE context(s).\n", tagged)
+	return nil
+}
+
+// gkeLocation is where a GKE cluster lives.
+type gkeLocation struct {
+	Project  string
+	Location string
+	Cluster  string
+}
+
+// tags returns the structured tags describing the location.
+func (l gkeLocation) tags() map[string]string {
+	return map[string]string{
+		gcpProjectTag:  l.Project,
+		gcpLocationTag: l.Location,
+		gkeClusterTag:  l.Cluster,
+	}
+}
+
+// findGKELocation derives where the cluster of a context lives from the names of the context and
+// its cluster, the arguments of its exec plugin, or its server. It reports false for contexts that
+// do not look like GKE.
+func findGKELocation(config *clientcmdapi.Config, name string) (gkeLocation, bool) {
+	context := config.Contexts[name]
+	for _, entry := range []string{name, context.Cluster} {
+		if match := gkeEntryName.FindStringSubmatch(entry); match != nil {
+			return gkeLocation{Project: match[1], Location: match[2], Cluster: match[3]}, true
+		}
+	}
+
+	if authInfo, ok := config.AuthInfos[context.AuthInfo]; ok && authInfo.Exec != nil {
+		location := gkeLocation{
+			Project:  execFlagValue(authInfo.Exec.Args, "project"),
+			Location: execFlagValue(authInfo.Exec.Args, "location", "zone", "region"),
+			Cluster:  execFlagValue(authInfo.Exec.Args, "cluster"),
+		}
+		if len(location.Project) > 0 && len(location.Cluster) > 0 {
+			return location, true
+		}
+	}
+
+	if cluster, ok := config.Clusters[context.Cluster]; ok {
+		if server, err := url.Parse(cluster.Server); err == nil && strings.HasSuffix(server.Hostname(), "connectgateway.googleapis.com") {
+			if match := gkeMembershipPath.FindStringSubmatch(server.Path); match != nil {
+				return gkeLocation{Project: match[1], Location: match[2], Cluster: match[3]}, true
+			}
+		}
+	}
+	return gkeLocation{}, false
+}
+
+// enrichGKEContext tags a GKE context with its location, replacing older values of the same tags,
+// and reports whether the tags changed.
+func enrichGKEContext(config *clientcmdapi.Config, name string) (bool, error) {
+	location, ok := findGKELocation(config, name)
+	if !ok {
+		return false, nil
+	}
+	context := config.Contexts[name]
+	metadata, err := readContextMetadata(context)
+	if err != nil {
+		return false, err
+	}
+	tags := setStructuredTags(metadata.Tags, location.tags())
+	if strings.Join(tags, ",") == strings.Join(metadata.Tags, ",") {
+		return false, nil
+	}
+	metadata.Tags = tags
+	return true, writeContextMetadata(context, metadata)
+}
+
+// setStructuredTags returns tags with a key=value tag for every non-empty value, replacing the tags
+// of the same keys, sorted.
+func setStructuredTags(tags []string, values map[string]string) []string {
+	result := []string{}
+	for _, tag := range tags {
+		if _, replaced := values[strings.SplitN(tag, "=", 2)[0]]; !replaced {
+			result = append(result, tag)
+		}
+	}
+	for key, value := range values {
+		if len(value) > 0 {
+			result = append(result, key+"="+value)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// execFlagValue returns the value of the first of the named flags in args, in either the
+// "--name value" or the "--name=value" form.
+func execFlagValue(args []string, names ...string) string {
+	for _, name := range names {
+		for i, arg := range args {
+			if arg == "--"+name && i+1 < len(args) {
+				return args[i+1]
+			}
+			if strings.HasPrefix(arg, "--"+name+"=") {
+				return strings.TrimPrefix(arg, "--"+name+"=")
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestFindGKELocation(t *testing.T) {
+	config := &clientcmdapi.Config{
+		Clusters: map[string]*clientcmdapi.Cluster{
+			"gke_shop_europe-west1_prod": {Server: "https://10.0.0.1"},
+			"gateway":                    {Server: "https://connectgateway.googleapis.com/v1/projects/1234/locations/global/gkeMemberships/edge"},
+			"on-prem":                    {Server: "https://10.0.0.2"},
+		},
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{
+			"gke-plugin": {Exec: &clientcmdapi.ExecConfig{Command: "gke-gcloud-auth-plugin"}},
+			"wrapper":    {Exec: &clientcmdapi.ExecConfig{Command: "gke-token", Args: []string{"--project=billing", "--zone", "us-east1-b", "--cluster", "batch"}}},
+		},
+		Contexts: map[string]*clientcmdapi.Context{
+			"prod":    {Cluster: "gke_shop_europe-west1_prod", AuthInfo: "gke-plugin"},
+			"batch":   {Cluster: "on-prem", AuthInfo: "wrapper"},
+			"edge":    {Cluster: "gateway", AuthInfo: "gke-plugin"},
+			"on-prem": {Cluster: "on-prem", AuthInfo: "gke-plugin"},
+		},
+	}
+
+	tests := map[string]*gkeLocation{
+		"prod":    {Project: "shop", Location: "europe-west1", Cluster: "prod"},
+		"batch":   {Project: "billing", Location: "us-east1-b", Cluster: "batch"},
+		"edge":    {Project: "1234", Location: "global", Cluster: "edge"},
+		"on-prem": nil,
+	}
+	for name, expected := range tests {
+		location, ok := findGKELocation(config, name)
+		if expected == nil {
+			if ok {
+				t.Errorf("%s: expected no location, got %#v", name, location)
+			}
+			continue
+		}
+		if !ok || location != *expected {
+			t.Errorf("%s: expected %#v, got %#v", name, *expected, location)
+		}
+	}
+}
+
+func TestEnrichGKE(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	startingConfig := newRedFederalCowHammerConfig()
+	startingConfig.Contexts["gke_shop_europe-west1_prod"] = &clientcmdapi.Context{AuthInfo: "red-user", Cluster: "cow-cluster"}
+	if err := writeExtension(&startingConfig.Contexts["gke_shop_europe-west1_prod"].Extensions, contextMetadataExtension, contextMetadata{Tags: []string{"prod", "gcp-project=old"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := clientcmd.WriteToFile(startingConfig, fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	for _, expected := range []string{"Tagged 1 GKE context(s).\n", "Tagged 0 GKE context(s).\n"} {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		o := &EnrichOptions{ConfigAccess: pathOptions, IOStreams: streams}
+		if err := o.RunGKE(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if out.String() != expected {
+			t.Errorf("expected %q, got %q", expected, out.String())
+		}
+	}
+
+	config, err := clientcmd.LoadFromFile(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	metadata, err := readContextMetadata(config.Contexts["gke_shop_europe-west1_prod"])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedTags := []string{"gcp-location=europe-west1", "gcp-project=shop", "gke-cluster=prod", "prod"}
+	if !reflect.DeepEqual(metadata.Tags, expectedTags) {
+		t.Errorf("expected tags %v, got %v", expectedTags, metadata.Tags)
+	}
+	if selected, err := selectContexts(config, "gcp-project=shop"); err != nil || !reflect.DeepEqual(selected, []string{"gke_shop_europe-west1_prod"}) {
+		t.Errorf("expected the tags to be selectable, got %v, %v", selected, err)
+	}
+	if _, ok := config.Contexts["federal-context"].Extensions[contextMetadataExtension]; ok {
+		t.Errorf("expected the non-GKE context to be left alone")
+	}
+}
@@ -0,0 +1,514 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+	utilexec "k8s.io/utils/exec"
+)
+
+// KubectlEnvVar names the kubectl binary "config exec" runs, kubectl from the PATH by default.
+const KubectlEnvVar = "KUBECTL"
+
+// kubectlRunner runs kubectl with args, and returns a utilexec.ExitError when it fails.
+type kubectlRunner func(kubectl string, args []string) error
+
+// ExecOptions holds the command-line options for 'config exec' sub command
+type ExecOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Context      string
+	Args         []string
+	// Tool is kubectl, or helm to run a helm command line instead.
+	Tool        string
+	Acknowledge bool
+	// Cooloff is the cooloff policy of the settings, nil when they configure none.
+	Cooloff *cooloffPolicy
+
+	// Helm is the helm binary run with the helm tool.
+	Helm string
+	// Kubectl is the kubectl binary run, and RunKubectl runs it attached to the terminal. They are
+	// fields so tests can replace them.
+	Kubectl    string
+	RunKubectl kubectlRunner
+	// FindKubectls lists the installed kubectl binaries and KubectlVersion returns the version of
+	// one, to pick the one matching the kubectl version range of the context.
+	FindKubectls   func() []string
+	KubectlVersion func(kubectl string) (*utilversion.Version, error)
+	// RunCommand runs pkcs11-tool for the users whose key is held by a PKCS#11 token, and the
+	// libfido2 tools for the contexts protected with "config protect".
+	RunCommand commandRunner
+	// Audit records the kubectl commands run, with the auditExec setting.
+	Audit *execAuditLog
+	// FindPlugins lists the kubectl plugins, to refuse those the context does not allow.
+	FindPlugins func() []kubectlPlugin
+
+	genericclioptions.IOStreams
+}
+
+var (
+	execLong = templates.LongDesc(`
+		Run a kubectl command against a context, with the default flags of the context.
+
+		The kubectl command line follows "--". It runs against the context given before "--", or the
+		current-context, and with the kubeconfig file this command uses, so "kubectl config exec" can
+		be aliased to kubectl. The kubectl flags set for the context with "kubectl config flags" are
+		added to it, except those the command line sets itself, and so is the timeout set with
+		"kubectl config tuning". Commands that would change the
+		cluster of a context made read-only with "kubectl config readonly" are refused, and so are
+		the kubectl plugins the context does not allow with "kubectl config plugins". A context
+		protected with "kubectl config protect --require-fido2" needs a touch of the security key
+		first.
+
+		With the auditExec setting, every kubectl command run is recorded with its context, cluster,
+		user, exit status and duration as a JSON line of audit.log below $XDG_STATE_HOME/kubecfg,
+		or ~/.local/state/kubecfg, leaving out the values of --token and --password. With the
+		auditWebhook setting, the records are also posted to that URL, except with --no-network. A
+		record that cannot be written or posted is warned about, and does not change the exit status.
+
+		The server of a cluster set with "kubectl config set-cluster --prefer-ipv6" is dialed by
+		kubectl over IPv6 first, through an HTTPS proxy on the loopback interface which only picks
+		the address: the TLS connection still goes from kubectl to the server.
+
+		kubectl is looked for in the PATH, or named by the KUBECTL environment variable. Its exit
+		status is the one of this command. When the context has a kubectl version range set with
+		"kubectl config set-kubectl-version" that this kubectl is not in, the newest kubectl in the
+		range runs instead, among those named like kubectl-1.29 in the PATH and those installed
+		with "kubectl config kubectl install".
+
+		When the user of the context has its key held by a PKCS#11 token, set with "kubectl config
+		set-credentials --pkcs11", kubectl is pointed at a proxy on the loopback interface that
+		authenticates to the cluster with the token, for as long as kubectl runs. kubectl reaches
+		the proxy through a temporary kubeconfig file only readable by the user, which holds the
+		bearer token of the proxy.
+
+		The banners set with "kubectl config banner" for the context or its tags are printed on
+		the standard error before kubectl runs.
+
+		With the cooloff setting, or the safeMode setting, commands against a context tagged or
+		named like production need --acknowledge, as use-context does, once the last
+		acknowledgement of the context is older than the cooloff.
+
+		With --tool=helm, the command line following "--" is a helm one, run with --kube-context
+		and the helm equivalents of the kubectl flags of the context, such as --namespace or
+		--kube-as-user for --as; the kubectl flags helm has no equivalent of are left out. helm
+		install, upgrade, uninstall, rollback and test are refused against a read-only context.
+		helm is looked for in the PATH, or named by the HELM environment variable.`)
+
+	execExample = templates.Examples(`
+		# List the pods of prod, with the flags set for prod
+		kubectl config exec prod -- get pods
+
+		# Run a command against the current-context, overriding its default timeout
+		kubectl config exec -- --request-timeout=5s get nodes
+
+		# Run a command against prod, whose acknowledgement ran out
+		kubectl config exec prod --acknowledge -- get pods
+
+		# List the helm releases of prod, in the namespace set for prod
+		kubectl config exec prod --tool helm -- list`)
+)
+
+// NewCmdConfigExec returns a Command instance for 'config exec' sub command
+func NewCmdConfigExec(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &ExecOptions{
+		ConfigAccess:   configAccess,
+		Tool:           toolKubectl,
+		RunKubectl:     runKubectl,
+		FindKubectls:   findKubectlBinaries,
+		KubectlVersion: kubectlClientVersion,
+		RunCommand:     runCommand,
+		FindPlugins:    findKubectlPlugins,
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "exec [CONTEXT_NAME] [--tool=kubectl|helm] [--acknowledge] -- KUBECTL_ARGS...",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Run a kubectl command against a context with its default flags"),
+		Long:                  execLong,
+		Example:               execExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().StringVar(&o.Tool, "tool", o.Tool, "Tool the command line following -- is run with: kubectl or helm")
+	cmd.Flags().BoolVar(&o.Acknowledge, "acknowledge", o.Acknowledge, "Acknowledge running a command against a context the cooloff setting applies to")
+	return cmd
+}
+
+// Complete sets the context and the kubectl command line from the arguments, and finds kubectl
+func (o *ExecOptions) Complete(cmd *cobra.Command, args []string) error {
+	dash := cmd.ArgsLenAtDash()
+	if dash < 0 {
+		return helpErrorf(cmd, "the kubectl command line must follow --")
+	}
+	if dash > 1 {
+		return helpErrorf(cmd, "Unexpected args: %v", args[:dash])
+	}
+	o.Context = currentContextShorthand
+	if dash == 1 {
+		o.Context = args[0]
+	}
+	o.Args = args[dash:]
+	if len(o.Tool) == 0 {
+		o.Tool = toolKubectl
+	}
+
+	if o.Tool == toolHelm && len(o.Helm) == 0 {
+		o.Helm = os.Getenv(HelmEnvVar)
+		if len(o.Helm) == 0 {
+			path, err := exec.LookPath("helm")
+			if err != nil {
+				return fmt.Errorf("helm not found, install it or set %s: %v", HelmEnvVar, err)
+			}
+			o.Helm = path
+		}
+	}
+	if o.Tool == toolKubectl && len(o.Kubectl) == 0 {
+		o.Kubectl = os.Getenv(KubectlEnvVar)
+		if len(o.Kubectl) == 0 {
+			path, err := exec.LookPath("kubectl")
+			if err != nil {
+				return fmt.Errorf("kubectl not found, install it or set %s: %v", KubectlEnvVar, err)
+			}
+			o.Kubectl = path
+		}
+	}
+
+	settings, err := loadSettings(settingsFile())
+	if err != nil {
+		return err
+	}
+	if settings.AuditExec {
+		o.Audit = &execAuditLog{
+			Filename:  auditLogFile(),
+			Webhook:   settings.AuditWebhook,
+			Client:    &http.Client{Timeout: 5 * time.Second},
+			NoNetwork: networkDisabled(cmd),
+		}
+	}
+	if o.Cooloff == nil {
+		o.Cooloff, err = newCooloffPolicy(settings)
+	}
+	return err
+}
+
+// Validate makes sure the command line does not choose another context or kubeconfig
+func (o *ExecOptions) Validate() error {
+	if o.Tool != toolKubectl && o.Tool != toolHelm {
+		return fmt.Errorf("the tool must be %s or %s, got %q", toolKubectl, toolHelm, o.Tool)
+	}
+	if len(o.Args) == 0 {
+		return fmt.Errorf("no %s command given", o.Tool)
+	}
+	if o.Tool == toolHelm {
+		for name := range helmFlagsSet(o.Args) {
+			if name == "kube-context" || name == "kubeconfig" {
+				return fmt.Errorf("--%s cannot be passed to helm, the context goes before --", name)
+			}
+		}
+		return nil
+	}
+	for name := range kubectlFlagsSet(o.Args) {
+		if name == "context" || name == clientcmd.RecommendedConfigPathFlag {
+			return fmt.Errorf("--%s cannot be passed to kubectl, the context goes before --", name)
+		}
+	}
+	return nil
+}
+
+// Run runs kubectl against the context
+func (o *ExecOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	name, err := resolveContextName(config, o.Context)
+	if err != nil {
+		return err
+	}
+	context, ok := config.Contexts[name]
+	if !ok {
+		return fmt.Errorf("no context exists with the name: %q", name)
+	}
+	if o.Cooloff != nil {
+		if err := o.Cooloff.check(config, name, o.Acknowledge); err != nil {
+			return err
+		}
+	}
+	if o.Tool == toolHelm {
+		err = guardHelmCommand(name, context, o.Args)
+	} else if err = guardKubectlCommand(name, context, o.Args); err == nil {
+		err = guardKubectlPlugin(name, context, o.Args, o.FindPlugins)
+	}
+	if err != nil {
+		return err
+	}
+	if err := requireFIDO2Touch(config, name, o.RunCommand, o.ErrOut); err != nil {
+		return err
+	}
+	defaults, err := readKubectlFlags(context)
+	if err != nil {
+		return err
+	}
+
+	var binary string
+	var args []string
+	var set map[string]bool
+	if o.Tool == toolHelm {
+		binary, args, set = o.Helm, []string{"--kube-context=" + name}, helmFlagsSet(o.Args)
+		if o.ConfigAccess.IsExplicitFile() {
+			args = append(args, "--kubeconfig="+o.ConfigAccess.GetExplicitFile())
+		}
+		for _, flag := range defaults {
+			if flagName, _ := globalFlag(flag); !set[flagName] {
+				args = append(args, helmFlags([]string{flag})...)
+			}
+		}
+	} else {
+		if binary, err = o.selectKubectl(name, context); err != nil {
+			return err
+		}
+		args = []string{"--context=" + name}
+		if o.ConfigAccess.IsExplicitFile() {
+			args = append(args, "--"+clientcmd.RecommendedConfigPathFlag+"="+o.ConfigAccess.GetExplicitFile())
+		}
+		set = kubectlFlagsSet(o.Args)
+		for _, flag := range defaults {
+			if flagName, _ := globalFlag(flag); !set[flagName] {
+				args = append(args, flag)
+			}
+		}
+		tuning, err := readClientTuning(context)
+		if err != nil {
+			return err
+		}
+		if len(tuning.Timeout) > 0 && !set["request-timeout"] && !kubectlFlagsSet(defaults)["request-timeout"] {
+			args = append(args, "--request-timeout="+tuning.Timeout)
+		}
+	}
+	banners, err := contextBanners(config, name)
+	if err != nil {
+		return err
+	}
+	printBanners(o.ErrOut, name, banners)
+
+	key, found, err := readPKCS11Key(config.AuthInfos[context.AuthInfo])
+	if err != nil {
+		return fmt.Errorf("user %q: %v", context.AuthInfo, err)
+	}
+	if found {
+		kubeconfig, stop, err := o.startPKCS11Proxy(config, name, context, key, set)
+		if err != nil {
+			return err
+		}
+		defer stop()
+		// The token of the proxy is only in the kubeconfig, which replaces the one of this command:
+		// on the command line, other users could read it.
+		proxyArgs := []string{}
+		for _, arg := range args {
+			if !strings.HasPrefix(arg, "--"+clientcmd.RecommendedConfigPathFlag+"=") {
+				proxyArgs = append(proxyArgs, arg)
+			}
+		}
+		args = append(proxyArgs, "--"+clientcmd.RecommendedConfigPathFlag+"="+kubeconfig)
+	} else if cluster := config.Clusters[context.Cluster]; prefersIPv6(cluster) && !set["server"] {
+		stop, err := o.preferIPv6(cluster)
+		if err != nil {
+			return err
+		}
+		defer stop()
+	}
+	started := time.Now()
+	err = o.RunKubectl(binary, append(args, o.Args...))
+	if o.Audit != nil {
+		server := ""
+		if cluster, ok := config.Clusters[context.Cluster]; ok {
+			server = cluster.Server
+		}
+		command := o.Args
+		if o.Tool == toolHelm {
+			command = append([]string{toolHelm}, o.Args...)
+		}
+		if auditErr := o.Audit.record(newExecAuditRecord(name, context.Cluster, server, command, started, err)); auditErr != nil {
+			fmt.Fprintf(o.ErrOut, "warning: recording the command in the audit log: %v\n", auditErr)
+		}
+	}
+	return err
+}
+
+// startPKCS11Proxy starts the proxy authenticating to the cluster of a context with the PKCS#11
+// token of its user, and returns a private kubeconfig file whose context of the same name sends
+// requests through it, with the token of the proxy.
+func (o *ExecOptions) startPKCS11Proxy(config *clientcmdapi.Config, name string, context *clientcmdapi.Context, key *pkcs11Key, set map[string]bool) (string, func(), error) {
+	for _, flag := range []string{"server", "token", "user"} {
+		if set[flag] {
+			return "", nil, fmt.Errorf("--%s cannot be passed to kubectl, user %q authenticates with a PKCS#11 token", flag, context.AuthInfo)
+		}
+	}
+	cluster, ok := config.Clusters[context.Cluster]
+	if !ok {
+		return "", nil, fmt.Errorf("no cluster exists with the name: %q", context.Cluster)
+	}
+	signer, err := newPKCS11Signer(key, os.Getenv(pkcs11PINEnv), o.RunCommand)
+	if err != nil {
+		return "", nil, fmt.Errorf("user %q: %v", context.AuthInfo, err)
+	}
+	token, err := randomToken()
+	if err != nil {
+		return "", nil, err
+	}
+	server, stopProxy, err := startPKCS11Proxy(cluster, signer, token)
+	if err != nil {
+		return "", nil, err
+	}
+
+	dir, err := ioutil.TempDir("", "kubecfg-pkcs11")
+	if err != nil {
+		stopProxy()
+		return "", nil, err
+	}
+	stop := func() {
+		stopProxy()
+		os.RemoveAll(dir)
+	}
+	proxyConfig := clientcmdapi.NewConfig()
+	proxyConfig.Clusters[context.Cluster] = &clientcmdapi.Cluster{Server: server}
+	proxyConfig.AuthInfos[context.AuthInfo] = &clientcmdapi.AuthInfo{Token: token}
+	proxyConfig.Contexts[name] = &clientcmdapi.Context{Cluster: context.Cluster, AuthInfo: context.AuthInfo, Namespace: context.Namespace}
+	proxyConfig.CurrentContext = name
+	kubeconfig := filepath.Join(dir, "config")
+	// WriteToFile creates the file readable by its owner only.
+	if err := clientcmd.WriteToFile(*proxyConfig, kubeconfig); err != nil {
+		stop()
+		return "", nil, err
+	}
+	return kubeconfig, stop, nil
+}
+
+// preferIPv6 has kubectl dial the server of cluster over IPv6 first, through the proxy of
+// startIPv6Proxy, and returns a function undoing it. The hint is ignored when the environment
+// sets an HTTPS proxy, which dials the server itself, and for plain HTTP servers.
+func (o *ExecOptions) preferIPv6(cluster *clientcmdapi.Cluster) (func(), error) {
+	server, err := url.Parse(cluster.Server)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server %q: %v", cluster.Server, err)
+	}
+	if server.Scheme != "https" {
+		return func() {}, nil
+	}
+	if environmentProxySet() {
+		fmt.Fprintf(o.ErrOut, "warning: HTTPS_PROXY is set, %s is not dialed over IPv6 first\n", server.Host)
+		return func() {}, nil
+	}
+	proxy, stop, err := startIPv6Proxy(server)
+	if err != nil {
+		return nil, err
+	}
+	// kubectl, and the credential plugins it runs, inherit the environment of this process.
+	restore := setEnv(map[string]string{"HTTPS_PROXY": proxy, "NO_PROXY": "", "no_proxy": ""})
+	return func() {
+		restore()
+		stop()
+	}, nil
+}
+
+// setEnv sets the environment variables of vars, unsetting those set to "", and returns a function
+// restoring them.
+func setEnv(vars map[string]string) func() {
+	previous := map[string]*string{}
+	for name, value := range vars {
+		if old, ok := os.LookupEnv(name); ok {
+			previous[name] = &old
+		} else {
+			previous[name] = nil
+		}
+		if len(value) > 0 {
+			os.Setenv(name, value)
+		} else {
+			os.Unsetenv(name)
+		}
+	}
+	return func() {
+		for name, old := range previous {
+			if old != nil {
+				os.Setenv(name, *old)
+			} else {
+				os.Unsetenv(name)
+			}
+		}
+	}
+}
+
+// kubectlFlagsSet returns the names of the global kubectl flags a kubectl command line sets.
+func kubectlFlagsSet(args []string) map[string]bool {
+	set := map[string]bool{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name, takesValue := globalFlag(arg)
+		if len(name) == 0 {
+			continue
+		}
+		set[name] = true
+		if flagTakesSeparateValue(arg, takesValue, true) {
+			i++
+		}
+	}
+	return set
+}
+
+// runKubectl runs kubectl on the terminal of this command, rather than its possibly paged streams,
+// so that interactive commands such as "kubectl exec -it" work.
+func runKubectl(kubectl string, args []string) error {
+	cmd := exec.Command(kubectl, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		// kubectl printed why it failed, only its exit status is left to pass on.
+		return utilexec.CodeExitError{Err: errors.New(""), Code: exitErr.ExitCode()}
+	}
+	return err
+}
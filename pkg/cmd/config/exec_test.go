@@ -0,0 +1,210 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestExec(t *testing.T) {
+	dir, err := ioutil.TempDir("", "exec")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	config := newRedFederalCowHammerConfig()
+	config.Contexts["shaker-context"] = &clientcmdapi.Context{AuthInfo: "red-user", Cluster: "cow-cluster"}
+	if err := writeKubectlFlags(config.Contexts["shaker-context"], []string{"--request-timeout=2m", "--as=viewer"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	config.Contexts["tuned-context"] = &clientcmdapi.Context{AuthInfo: "red-user", Cluster: "cow-cluster"}
+	// The timeout of the flags of shaker-context wins over its tuning.
+	for _, name := range []string{"shaker-context", "tuned-context"} {
+		if err := writeClientTuning(config.Contexts[name], clientTuning{Timeout: "1m30s"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	configFile := filepath.Join(dir, "config")
+	if err := clientcmd.WriteToFile(config, configFile); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.LoadingRules.ExplicitPath = configFile
+	pathOptions.EnvVar = ""
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+		err      string
+	}{
+		{
+			name:     "defaults of the context",
+			args:     []string{"shaker-context", "--", "get", "pods"},
+			expected: []string{"--context=shaker-context", "--kubeconfig=" + configFile, "--request-timeout=2m", "--as=viewer", "get", "pods"},
+		},
+		{
+			name:     "command line wins",
+			args:     []string{"shaker-context", "--", "--request-timeout", "5s", "get", "pods", "--", "--as=x"},
+			expected: []string{"--context=shaker-context", "--kubeconfig=" + configFile, "--as=viewer", "--request-timeout", "5s", "get", "pods", "--", "--as=x"},
+		},
+		{
+			name:     "tuned timeout",
+			args:     []string{"tuned-context", "--", "get", "pods"},
+			expected: []string{"--context=tuned-context", "--kubeconfig=" + configFile, "--request-timeout=1m30s", "get", "pods"},
+		},
+		{
+			name:     "command line wins over the tuned timeout",
+			args:     []string{"tuned-context", "--", "--request-timeout=5s", "get", "pods"},
+			expected: []string{"--context=tuned-context", "--kubeconfig=" + configFile, "--request-timeout=5s", "get", "pods"},
+		},
+		{
+			name:     "current-context",
+			args:     []string{"--", "get", "nodes", "-n", "kube-system"},
+			expected: []string{"--context=federal-context", "--kubeconfig=" + configFile, "get", "nodes", "-n", "kube-system"},
+		},
+		{
+			name: "missing --",
+			args: []string{"get", "pods"},
+			err:  "must follow --",
+		},
+		{
+			name: "no command",
+			args: []string{"shaker-context", "--"},
+			err:  "no kubectl command given",
+		},
+		{
+			name: "context on the command line",
+			args: []string{"--", "--context=prod", "get", "pods"},
+			err:  "--context cannot be passed to kubectl",
+		},
+		{
+			name: "missing context",
+			args: []string{"missing", "--", "get", "pods"},
+			err:  `no context exists with the name: "missing"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ran []string
+			streams, _, _, _ := genericclioptions.NewTestIOStreams()
+			o := &ExecOptions{
+				ConfigAccess: pathOptions,
+				Kubectl:      "kubectl",
+				RunKubectl: func(kubectl string, args []string) error {
+					ran = args
+					return nil
+				},
+				IOStreams: streams,
+			}
+			cmd := NewCmdConfigExec(streams, pathOptions)
+			if err := cmd.ParseFlags(tt.args); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			err := o.Complete(cmd, cmd.Flags().Args())
+			if err == nil {
+				err = o.Validate()
+			}
+			if err == nil {
+				err = o.Run()
+			}
+			if len(tt.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("expected an error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(ran, tt.expected) {
+				t.Errorf("expected kubectl to run with %v, got %v", tt.expected, ran)
+			}
+		})
+	}
+}
+
+func TestExecCooloff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "exec")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	config := newRedFederalCowHammerConfig()
+	config.Contexts["prod"] = &clientcmdapi.Context{AuthInfo: "red-user", Cluster: "cow-cluster"}
+	if err := writeContextMetadata(config.Contexts["prod"], contextMetadata{Tags: []string{"prod"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	configFile := filepath.Join(dir, "config")
+	if err := clientcmd.WriteToFile(config, configFile); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.LoadingRules.ExplicitPath = configFile
+	pathOptions.EnvVar = ""
+
+	now := time.Date(2019, 8, 1, 12, 0, 0, 0, time.UTC)
+	policy := &cooloffPolicy{
+		Period:   30 * time.Minute,
+		Tags:     []string{"prod"},
+		Filename: filepath.Join(dir, "cooloff.yaml"),
+		Now:      func() time.Time { return now },
+	}
+	// The acknowledgement given 29 minutes before has expired an hour later.
+	if err := policy.save(&cooloffState{Acknowledged: map[string]time.Time{"prod": now.Add(-29 * time.Minute)}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now = now.Add(time.Hour)
+
+	ran := false
+	streams, _, _, _ := genericclioptions.NewTestIOStreams()
+	o := &ExecOptions{
+		ConfigAccess: pathOptions,
+		Context:      "prod",
+		Args:         []string{"delete", "namespace", "shop"},
+		Tool:         toolKubectl,
+		Kubectl:      "kubectl",
+		Cooloff:      policy,
+		RunKubectl: func(kubectl string, args []string) error {
+			ran = true
+			return nil
+		},
+		IOStreams: streams,
+	}
+	if err := o.Run(); err == nil || !strings.Contains(err.Error(), "--acknowledge") || ran {
+		t.Fatalf("expected the expired acknowledgement to refuse running kubectl, got %v", err)
+	}
+
+	o.Acknowledge = true
+	if err := o.Run(); err != nil || !ran {
+		t.Fatalf("expected --acknowledge to run kubectl, got %v", err)
+	}
+	o.Acknowledge, ran = false, false
+	if err := o.Run(); err != nil || !ran {
+		t.Errorf("expected the new acknowledgement to last, got %v", err)
+	}
+}
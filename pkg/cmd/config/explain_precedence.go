@@ -0,0 +1,265 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/printers"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// inClusterTokenFile is the service account token whose presence, with $KUBERNETES_SERVICE_HOST and
+// $KUBERNETES_SERVICE_PORT, makes client-go consider the in-cluster config.
+const inClusterTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// ExplainPrecedenceOptions holds the command-line options for 'config explain-precedence' sub command
+type ExplainPrecedenceOptions struct {
+	PathOptions *clientcmd.PathOptions
+	// Context, Cluster, User and Namespace are the overrides of a kubectl command line to explain.
+	Context   string
+	Cluster   string
+	User      string
+	Namespace string
+
+	// Getenv and TokenFile are used to tell whether kubectl runs in a pod. They default to
+	// os.Getenv and inClusterTokenFile, and are fields so tests can describe a pod.
+	Getenv    func(key string) string
+	TokenFile string
+
+	genericclioptions.IOStreams
+}
+
+// precedenceRule is one of the sources a setting may come from, in the order they are considered.
+type precedenceRule struct {
+	Name  string
+	Value string
+}
+
+// loadedKubeconfig is a kubeconfig file in the loading order, nil when missing or broken.
+type loadedKubeconfig struct {
+	Filename string
+	Config   *clientcmdapi.Config
+	Err      error
+}
+
+var (
+	explainPrecedenceLong = templates.LongDesc(`
+		Show step by step how kubectl picks the kubeconfig files, the current context and the
+		cluster, user and namespace it uses, and which rule won at every step.
+
+		Files come from --kubeconfig, else from $KUBECONFIG, else from the default file. When
+		several files are loaded, the first one setting current-context wins, and so does the first
+		one defining a context, cluster or user of the same name. --context, --cluster, --user and
+		--namespace override what kubeconfig says, as they would on a kubectl command line. When the
+		kubeconfig does not define a usable context and kubectl runs in a pod, kubectl falls back to
+		the in-cluster config of the pod's service account.`)
+
+	explainPrecedenceExample = templates.Examples(`
+		# Why does kubectl talk to this cluster?
+		kubectl config explain-precedence
+
+		# What would "kubectl --context prod -n web" use?
+		kubectl config explain-precedence --context prod --namespace web`)
+)
+
+// NewCmdConfigExplainPrecedence returns a Command instance for 'config explain-precedence' sub command
+func NewCmdConfigExplainPrecedence(streams genericclioptions.IOStreams, pathOptions *clientcmd.PathOptions) *cobra.Command {
+	o := &ExplainPrecedenceOptions{PathOptions: pathOptions, Getenv: os.Getenv, TokenFile: inClusterTokenFile, IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "explain-precedence [--context=CONTEXT] [--cluster=CLUSTER] [--user=USER] [--namespace=NAMESPACE]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Explain how the kubeconfig files and current context are picked"),
+		Long:                  explainPrecedenceLong,
+		Example:               explainPrecedenceExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	cmd.Flags().StringVar(&o.Context, "context", o.Context, "The --context of the kubectl command line to explain")
+	cmd.Flags().StringVar(&o.Cluster, "cluster", o.Cluster, "The --cluster of the kubectl command line to explain")
+	cmd.Flags().StringVar(&o.User, "user", o.User, "The --user of the kubectl command line to explain")
+	cmd.Flags().StringVarP(&o.Namespace, "namespace", "n", o.Namespace, "The --namespace of the kubectl command line to explain")
+	return cmd
+}
+
+// Run prints every step of the resolution
+func (o *ExplainPrecedenceOptions) Run() error {
+	w := printers.GetNewTabWriter(o.Out)
+
+	fmt.Fprintln(w, "Kubeconfig files:")
+	envFiles := o.PathOptions.GetEnvVarFiles()
+	rules := []precedenceRule{
+		{Name: "--" + o.PathOptions.ExplicitFileFlag + " flag", Value: o.PathOptions.LoadingRules.ExplicitPath},
+		{Name: "$" + o.PathOptions.EnvVar, Value: strings.Join(envFiles, string(filepath.ListSeparator))},
+		{Name: "default file", Value: o.PathOptions.GlobalFile},
+	}
+	switch {
+	case o.PathOptions.IsExplicitFile():
+		printPrecedenceRules(w, rules, 0)
+	case len(envFiles) > 0:
+		printPrecedenceRules(w, rules, 1)
+	default:
+		printPrecedenceRules(w, rules, 2)
+	}
+
+	fmt.Fprintln(w, "Files loaded, in order:")
+	files := []loadedKubeconfig{}
+	for _, filename := range o.PathOptions.GetLoadingPrecedence() {
+		file := loadedKubeconfig{Filename: filename}
+		file.Config, file.Err = clientcmd.LoadFromFile(filename)
+		switch {
+		case os.IsNotExist(file.Err):
+			fmt.Fprintf(w, "  %s\tmissing, skipped\n", filename)
+		case file.Err != nil:
+			fmt.Fprintf(w, "  %s\tbroken: %v\n", filename, file.Err)
+		default:
+			fmt.Fprintf(w, "  %s\t\n", filename)
+		}
+		files = append(files, file)
+	}
+
+	fmt.Fprintln(w, "Current context:")
+	rules = []precedenceRule{{Name: "--context flag", Value: o.Context}}
+	won, contextName := -1, o.Context
+	if len(o.Context) > 0 {
+		won = 0
+	}
+	for _, file := range files {
+		if file.Config == nil || len(file.Config.CurrentContext) == 0 {
+			continue
+		}
+		rules = append(rules, precedenceRule{Name: "current-context of " + file.Filename, Value: file.Config.CurrentContext})
+		if won < 0 {
+			won, contextName = len(rules)-1, file.Config.CurrentContext
+		}
+	}
+	printPrecedenceRules(w, rules, won)
+
+	var context *clientcmdapi.Context
+	if len(contextName) > 0 {
+		if file, found := firstDefinition(files, func(c *clientcmdapi.Config) bool { return c.Contexts[contextName] != nil }); found {
+			context = file.Config.Contexts[contextName]
+			fmt.Fprintf(w, "Context %q:\tdefined in %s\n", contextName, file.Filename)
+		} else {
+			fmt.Fprintf(w, "Context %q:\tnot defined in any file\n", contextName)
+		}
+	}
+	if context == nil {
+		context = &clientcmdapi.Context{}
+	}
+	o.explainEntry(w, files, "cluster", o.Cluster, context.Cluster, func(c *clientcmdapi.Config, name string) bool { return c.Clusters[name] != nil })
+	o.explainEntry(w, files, "user", o.User, context.AuthInfo, func(c *clientcmdapi.Config, name string) bool { return c.AuthInfos[name] != nil })
+	fmt.Fprintln(w, "Namespace:")
+	printPrecedenceRules(w, []precedenceRule{
+		{Name: "--namespace flag", Value: o.Namespace},
+		{Name: "namespace of the context", Value: context.Namespace},
+		{Name: "default", Value: "default"},
+	}, firstSet(o.Namespace, context.Namespace, "default"))
+
+	fmt.Fprintln(w, "In-cluster config:")
+	usable := context.Cluster != "" || o.Cluster != ""
+	switch {
+	case !o.inClusterPossible():
+		fmt.Fprintf(w, "  not used\tnot running in a pod\n")
+	case usable:
+		fmt.Fprintf(w, "  not used\tkubeconfig defines a cluster\n")
+	default:
+		fmt.Fprintf(w, "  used\tkubeconfig defines no usable context\t<- won\n")
+	}
+	return w.Flush()
+}
+
+// explainEntry prints where the cluster or user, as named by kind, comes from.
+func (o *ExplainPrecedenceOptions) explainEntry(w io.Writer, files []loadedKubeconfig, kind, flag, fromContext string, defines func(*clientcmdapi.Config, string) bool) {
+	fmt.Fprintf(w, "%s:\n", strings.Title(kind))
+	name := flag
+	if len(name) == 0 {
+		name = fromContext
+	}
+	printPrecedenceRules(w, []precedenceRule{
+		{Name: "--" + kind + " flag", Value: flag},
+		{Name: kind + " of the context", Value: fromContext},
+	}, firstSet(flag, fromContext))
+	if len(name) == 0 {
+		return
+	}
+	if file, found := firstDefinition(files, func(c *clientcmdapi.Config) bool { return defines(c, name) }); found {
+		fmt.Fprintf(w, "  %s %q\tdefined in %s\n", kind, name, file.Filename)
+	} else {
+		fmt.Fprintf(w, "  %s %q\tnot defined in any file\n", kind, name)
+	}
+}
+
+func (o *ExplainPrecedenceOptions) inClusterPossible() bool {
+	info, err := os.Stat(o.TokenFile)
+	return len(o.Getenv("KUBERNETES_SERVICE_HOST")) > 0 && len(o.Getenv("KUBERNETES_SERVICE_PORT")) > 0 && err == nil && !info.IsDir()
+}
+
+// printPrecedenceRules prints rules in order, marking the one at index won.
+func printPrecedenceRules(w io.Writer, rules []precedenceRule, won int) {
+	for i, rule := range rules {
+		value := rule.Value
+		if len(value) == 0 {
+			value = "not set"
+		}
+		marker := ""
+		switch {
+		case i == won:
+			marker = "<- won"
+		case won >= 0 && i > won && len(rule.Value) > 0:
+			marker = "ignored"
+		}
+		fmt.Fprintf(w, "  %d. %s\t%s\t%s\n", i+1, rule.Name, value, marker)
+	}
+}
+
+// firstSet returns the index of the first non-empty value, -1 if none is.
+func firstSet(values ...string) int {
+	for i, value := range values {
+		if len(value) > 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// firstDefinition returns the first loaded file for which defines is true, which is the one whose
+// definition wins when several files define the same name.
+func firstDefinition(files []loadedKubeconfig, defines func(*clientcmdapi.Config) bool) (loadedKubeconfig, bool) {
+	for _, file := range files {
+		if file.Config != nil && defines(file.Config) {
+			return file, true
+		}
+	}
+	return loadedKubeconfig{}, false
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestExplainPrecedence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "precedence")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	first, second, missing := filepath.Join(dir, "first"), filepath.Join(dir, "second"), filepath.Join(dir, "missing")
+	if err := clientcmd.WriteToFile(clientcmdapi.Config{
+		CurrentContext: "dev",
+		Contexts:       map[string]*clientcmdapi.Context{"dev": {Cluster: "dev-cluster", AuthInfo: "dev-user", Namespace: "web"}},
+	}, first); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := clientcmd.WriteToFile(clientcmdapi.Config{
+		CurrentContext: "prod",
+		Contexts:       map[string]*clientcmdapi.Context{"dev": {Cluster: "other-cluster"}, "prod": {Cluster: "dev-cluster"}},
+		Clusters:       map[string]*clientcmdapi.Cluster{"dev-cluster": {Server: "https://dev.example.com"}},
+		AuthInfos:      map[string]*clientcmdapi.AuthInfo{"dev-user": {Token: "dev-token"}},
+	}, second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	envVar := "KUBECFG_TEST_KUBECONFIG"
+	os.Setenv(envVar, strings.Join([]string{first, second, missing}, string(filepath.ListSeparator)))
+	defer os.Unsetenv(envVar)
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.EnvVar = envVar
+	pathOptions.ExplicitFileFlag = clientcmd.RecommendedConfigPathFlag
+
+	run := func(o *ExplainPrecedenceOptions) []string {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		o.PathOptions, o.IOStreams = pathOptions, streams
+		if o.Getenv == nil {
+			o.Getenv = func(string) string { return "" }
+		}
+		if err := o.Run(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return strings.Split(out.String(), "\n")
+	}
+	// expectLine fails unless a line holds every field, in order, separated by any space.
+	expectLine := func(lines []string, fields ...string) {
+		t.Helper()
+		for _, line := range lines {
+			if strings.Join(strings.Fields(line), " ") == strings.Join(fields, " ") {
+				return
+			}
+		}
+		t.Errorf("expected a line with %q in\n%s", fields, strings.Join(lines, "\n"))
+	}
+
+	lines := run(&ExplainPrecedenceOptions{})
+	expectLine(lines, "1.", "--kubeconfig", "flag", "not", "set")
+	expectLine(lines, "2.", "$"+envVar, strings.Join([]string{first, second, missing}, string(filepath.ListSeparator)), "<-", "won")
+	expectLine(lines, missing, "missing,", "skipped")
+	expectLine(lines, "2.", "current-context", "of", first, "dev", "<-", "won")
+	expectLine(lines, "3.", "current-context", "of", second, "prod", "ignored")
+	expectLine(lines, "Context", `"dev":`, "defined", "in", first)
+	expectLine(lines, "2.", "cluster", "of", "the", "context", "dev-cluster", "<-", "won")
+	expectLine(lines, "cluster", `"dev-cluster"`, "defined", "in", second)
+	expectLine(lines, "2.", "namespace", "of", "the", "context", "web", "<-", "won")
+	expectLine(lines, "not", "used", "not", "running", "in", "a", "pod")
+
+	lines = run(&ExplainPrecedenceOptions{Context: "prod", User: "admin", Namespace: "kube-system"})
+	expectLine(lines, "1.", "--context", "flag", "prod", "<-", "won")
+	expectLine(lines, "1.", "--user", "flag", "admin", "<-", "won")
+	expectLine(lines, "user", `"admin"`, "not", "defined", "in", "any", "file")
+	expectLine(lines, "1.", "--namespace", "flag", "kube-system", "<-", "won")
+
+	token := filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(token, []byte("token"), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	inPod := func(key string) string {
+		return map[string]string{"KUBERNETES_SERVICE_HOST": "10.0.0.1", "KUBERNETES_SERVICE_PORT": "443"}[key]
+	}
+	lines = run(&ExplainPrecedenceOptions{Context: "none", Getenv: inPod, TokenFile: token})
+	expectLine(lines, "Context", `"none":`, "not", "defined", "in", "any", "file")
+	expectLine(lines, "used", "kubeconfig", "defines", "no", "usable", "context", "<-", "won")
+}
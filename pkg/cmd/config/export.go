@@ -0,0 +1,510 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// The optional fields of the exported entries, which --fields selects.
+const (
+	exportFieldNamespace            = "namespace"
+	exportFieldExtensions           = "extensions"
+	exportFieldCertificateAuthority = "certificate-authority"
+)
+
+var validExportFields = sets.NewString(exportFieldNamespace, exportFieldExtensions, exportFieldCertificateAuthority)
+
+// exportPreset is a named shape of the kubeconfig 'config export' prints, kept in the exportPresets
+// of the settings. Its keys are the flags of the same name.
+type exportPreset struct {
+	Fields    []string `json:"fields,omitempty"`
+	Sanitized bool     `json:"sanitized,omitempty"`
+	NoFlatten bool     `json:"noFlatten,omitempty"`
+	Template  string   `json:"template,omitempty"`
+	Format    string   `json:"format,omitempty"`
+}
+
+// ExportOptions holds the command-line options for 'config export' sub command
+type ExportOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	SettingsFile string
+	Context      string
+	// Preset names the exportPreset the flags not given are taken from.
+	Preset    string
+	Sanitized bool
+	Fields    []string
+	NoFlatten bool
+	Template  string
+	// Format is kubeconfig, terraform, pulumi, argocd, flux-secret or helm-env.
+	Format    string
+	Clipboard bool
+	// ToSecret is the [NAMESPACE/]NAME of the Secret the kubeconfig is written to, on the cluster
+	// of TargetContext, under SecretKey.
+	ToSecret      string
+	TargetContext string
+	SecretKey     string
+	// Name and Namespace are those of the Secret printed with the flux-secret format.
+	Name      string
+	Namespace string
+
+	// CopyToClipboard puts the exported kubeconfig on the clipboard with Clipboard.
+	CopyToClipboard func(data []byte) error
+	// Clientset and SecretNamespace are set by Complete with ToSecret.
+	Clientset       kubernetes.Interface
+	SecretNamespace string
+
+	genericclioptions.IOStreams
+}
+
+var (
+	exportLong = templates.LongDesc(`
+		Print a self-contained kubeconfig holding a single context, with its cluster and user.
+
+		Files the entries refer to, such as certificate authorities, are embedded, so the output
+		works on another machine.
+
+		With --sanitized, the credentials of the user are replaced by REDACTED while the names,
+		servers, certificate authorities and the kind of authentication stay, which makes the output
+		safe to attach to a support ticket about a configuration problem. Client certificates and
+		keys, token files and the values of exec plugin environment variables that look like
+		secrets are replaced by REDACTED as well.
+
+		With --clipboard, the kubeconfig is copied to the clipboard instead of printed, with pbcopy
+		on macOS, wl-copy on Wayland, xclip or xsel on X11 and clip on Windows, to be pasted
+		elsewhere or imported with "kubectl config import --clipboard". The users whose credentials
+		are copied along are reported, since clipboard managers and synced clipboards keep what is
+		copied.
+
+		With --to-secret, the kubeconfig is written to a Secret of the cluster of --context instead,
+		under --secret-key, for the operators and controllers that read kubeconfig Secrets. The
+		Secret is created, or its key is replaced, and it is in the namespace of --context unless
+		one is given as NAMESPACE/NAME.
+
+		The optional fields of the entries are kept unless --fields lists the ones to keep:
+		namespace, extensions and certificate-authority. With --no-flatten, the files the entries
+		refer to are kept as paths instead of embedded. --template is a Go template renaming the
+		exported context, such as ci-{{.Cluster}}, with the fields of the namingTemplate setting.
+
+		With --format=terraform, the kubernetes provider block of Terraform reaching the cluster
+		with the user of the context is printed instead, with its host, certificate authority,
+		credentials and exec block, aliased after the context. With --format=pulumi, a Pulumi YAML
+		program declaring the kubernetes provider with the kubeconfig embedded is printed. Both keep
+		infrastructure code in sync with the credentials that work locally; with --sanitized,
+		static credentials are REDACTED for a variable to be put in their place.
+
+		With --format=argocd, the manifest of the Secret declaring the cluster of the context to
+		ArgoCD is printed, in the argocd namespace, with the server and the config JSON holding the
+		TLS settings, bearer token or basic auth and exec provider of the user. Files the entries
+		refer to are read into it, as ArgoCD runs elsewhere. Auth providers are not supported.
+
+		With --format=flux-secret, the manifest of a Secret holding the kubeconfig under
+		--secret-key is printed, as the kubeConfig.secretRef of Flux Kustomizations and HelmReleases
+		applying to a remote cluster expects it and Cluster API names it. It is named --name,
+		CONTEXT-kubeconfig by default, in the --namespace namespace, flux-system by default, and can
+		be committed, once encrypted, to the repository Flux syncs.
+
+		With --format=helm-env, the shell commands pointing helm at the context of the kubeconfig
+		files in use are printed, setting KUBECONFIG, HELM_KUBECONTEXT and HELM_NAMESPACE, along
+		with how to log in to the OCI chart registries of EKS, GKE and AKS clusters. Nothing is
+		exported then, so the flags shaping the exported kubeconfig do not apply.
+
+		--preset takes the flags not given from a preset of the exportPresets of the settings file,
+		whose keys are the flags of the same name:
+
+		    exportPresets:
+		      ci:
+		        sanitized: false
+		        fields: [certificate-authority]
+		        template: ci-{{.Cluster}}
+		        format: terraform`)
+
+	exportExample = templates.Examples(`
+		# Export the current context to use it on another machine
+		kubectl config export . > staging.kubeconfig
+
+		# Export the prod context without credentials for a support ticket
+		kubectl config export prod --sanitized > prod-sanitized.kubeconfig
+
+		# Copy the staging context without credentials to paste it in a chat
+		kubectl config export staging --sanitized --clipboard
+
+		# Give the controllers of the mgmt cluster access to the edge-1 cluster
+		kubectl config export edge-1 --to-secret flux-system/edge-1-kubeconfig --context mgmt
+
+		# Export the staging context the way the ci preset of the settings says
+		kubectl config export staging --preset ci > ci.kubeconfig
+
+		# Declare the kubernetes provider of Terraform for the prod context
+		kubectl config export prod --format terraform > provider.tf
+
+		# Register the prod cluster with the ArgoCD instance of the mgmt cluster
+		kubectl config export prod --format argocd | kubectl apply --context mgmt -f -
+
+		# Write the Secret Flux applies to the edge-1 cluster with to the fleet repository
+		kubectl config export edge-1 --format flux-secret --name edge-1-kubeconfig -n flux-system > clusters/edge-1/kubeconfig.yaml
+
+		# Point helm at the staging context in the current shell
+		eval "$(kubectl config export staging --format helm-env)"`)
+)
+
+// NewCmdConfigExport returns a Command instance for 'config export' sub command
+func NewCmdConfigExport(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &ExportOptions{
+		ConfigAccess:    configAccess,
+		SettingsFile:    settingsFile(),
+		SecretKey:       capiKubeconfigKey,
+		CopyToClipboard: copyToClipboard,
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "export CONTEXT_NAME [--preset=NAME] [--sanitized] [--fields=FIELD,...] [--no-flatten] [--template=TEMPLATE] [--format=kubeconfig|terraform|pulumi|argocd|flux-secret|helm-env] [--name=NAME] [--namespace=NAMESPACE] [--clipboard | --to-secret=[NAMESPACE/]NAME --context=CONTEXT [--secret-key=KEY]]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Print a self-contained kubeconfig for a single context"),
+		Long:                  exportLong,
+		Example:               exportExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.Context = args[0]
+			cmdutil.CheckErr(o.Complete(cmd))
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().StringVar(&o.Preset, "preset", o.Preset, "Name of the export preset of the settings the flags not given are taken from")
+	cmd.Flags().BoolVar(&o.Sanitized, "sanitized", o.Sanitized, "If true, strip the credentials so the output can be shared")
+	cmd.Flags().StringSliceVar(&o.Fields, "fields", o.Fields, "Optional fields of the entries to keep, among namespace, extensions and certificate-authority. All of them when empty")
+	cmd.Flags().BoolVar(&o.NoFlatten, "no-flatten", o.NoFlatten, "If true, keep the files the entries refer to as paths instead of embedding them")
+	cmd.Flags().StringVar(&o.Template, "template", o.Template, "Go template renaming the exported context")
+	cmd.Flags().StringVar(&o.Format, "format", o.Format, "Format to print in: kubeconfig, terraform, pulumi, argocd, flux-secret or helm-env")
+	cmd.Flags().BoolVar(&o.Clipboard, "clipboard", o.Clipboard, "If true, copy the kubeconfig to the clipboard instead of printing it")
+	cmd.Flags().StringVar(&o.ToSecret, "to-secret", o.ToSecret, "If set, write the kubeconfig to this Secret, [NAMESPACE/]NAME, instead of printing it")
+	cmd.Flags().StringVar(&o.TargetContext, "context", o.TargetContext, "With --to-secret, the context of the cluster the Secret is written to")
+	cmd.Flags().StringVar(&o.SecretKey, "secret-key", o.SecretKey, "With --to-secret or --format=flux-secret, the key of the kubeconfig in the Secret")
+	cmd.Flags().StringVar(&o.Name, "name", o.Name, "With --format=flux-secret, the name of the Secret. Defaults to CONTEXT-kubeconfig")
+	cmd.Flags().StringVarP(&o.Namespace, "namespace", "n", o.Namespace, "With --format=flux-secret, the namespace of the Secret. Defaults to flux-system")
+	return cmd
+}
+
+// Complete takes the flags not given from the preset, and connects to the cluster the Secret is
+// written to with ToSecret
+func (o *ExportOptions) Complete(cmd *cobra.Command) error {
+	if len(o.Preset) > 0 {
+		settings, err := loadSettings(o.SettingsFile)
+		if err != nil {
+			return err
+		}
+		if err := o.applyPreset(settings, cmd.Flags().Changed); err != nil {
+			return err
+		}
+	}
+	if err := o.Validate(); err != nil {
+		return err
+	}
+	if o.Format != exportFormatFluxSecret && (len(o.Name) > 0 || len(o.Namespace) > 0) {
+		return helpErrorf(cmd, "--name and --namespace require --format=flux-secret")
+	}
+	if o.Format == exportFormatFluxSecret && len(o.SecretKey) == 0 {
+		return helpErrorf(cmd, "--secret-key cannot be empty")
+	}
+	if len(o.ToSecret) == 0 {
+		if len(o.TargetContext) > 0 {
+			return helpErrorf(cmd, "--context requires --to-secret")
+		}
+		return nil
+	}
+	if o.Clipboard {
+		return helpErrorf(cmd, "--to-secret cannot be combined with --clipboard")
+	}
+	if len(o.Format) > 0 && o.Format != exportFormatKubeconfig {
+		return helpErrorf(cmd, "--to-secret writes a kubeconfig, it cannot be combined with --format=%s", o.Format)
+	}
+	if len(o.TargetContext) == 0 {
+		return helpErrorf(cmd, "--to-secret requires --context, the cluster to write the Secret to")
+	}
+	if len(o.SecretKey) == 0 {
+		return helpErrorf(cmd, "--secret-key cannot be empty")
+	}
+	if err := requireNetwork(cmd); err != nil {
+		return err
+	}
+
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	if o.TargetContext, err = resolveContextName(config, o.TargetContext); err != nil {
+		return err
+	}
+	if _, ok := config.Contexts[o.TargetContext]; !ok {
+		return fmt.Errorf("no context exists with the name: %q", o.TargetContext)
+	}
+	clientConfig := clientcmd.NewNonInteractiveClientConfig(*config, o.TargetContext, &clientcmd.ConfigOverrides{}, nil)
+	if parts := strings.SplitN(o.ToSecret, "/", 2); len(parts) == 2 {
+		o.SecretNamespace, o.ToSecret = parts[0], parts[1]
+	} else if o.SecretNamespace, _, err = clientConfig.Namespace(); err != nil {
+		return err
+	}
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return err
+	}
+	restConfig.Timeout = 30 * time.Second
+	o.Clientset, err = kubernetes.NewForConfig(restConfig)
+	return err
+}
+
+// applyPreset sets the fields of the options whose flag was not changed from the preset of the
+// settings.
+func (o *ExportOptions) applyPreset(settings *Settings, changed func(flag string) bool) error {
+	preset, ok := settings.ExportPresets[o.Preset]
+	if !ok {
+		return fmt.Errorf("no export preset named %q, the settings have: %s", o.Preset, strings.Join(sets.StringKeySet(settings.ExportPresets).List(), ", "))
+	}
+	if !changed("fields") {
+		o.Fields = preset.Fields
+	}
+	if !changed("sanitized") {
+		o.Sanitized = preset.Sanitized
+	}
+	if !changed("no-flatten") {
+		o.NoFlatten = preset.NoFlatten
+	}
+	if !changed("template") {
+		o.Template = preset.Template
+	}
+	if !changed("format") {
+		o.Format = preset.Format
+	}
+	return nil
+}
+
+// Validate makes sure the fields, the template and the format are valid
+func (o *ExportOptions) Validate() error {
+	if len(o.Format) > 0 && !validExportFormats.Has(o.Format) {
+		return fmt.Errorf("unknown format %q, expected one of %s", o.Format, strings.Join(validExportFormats.List(), ", "))
+	}
+	for _, field := range o.Fields {
+		if !validExportFields.Has(field) {
+			return fmt.Errorf("unknown field %q, expected one of %s", field, strings.Join(validExportFields.List(), ", "))
+		}
+	}
+	if _, err := template.New("naming").Parse(o.Template); err != nil {
+		return fmt.Errorf("invalid template: %v", err)
+	}
+	return nil
+}
+
+// Run performs the execution of 'config export' sub command
+func (o *ExportOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	if o.Format == exportFormatHelmEnv {
+		data, err := helmEnv(o.ConfigAccess, config, o.Context)
+		if err != nil {
+			return err
+		}
+		_, err = o.Out.Write(data)
+		return err
+	}
+	exported, err := exportContextAs(config, o.Context, exportPreset{Fields: o.Fields, Sanitized: o.Sanitized, NoFlatten: o.NoFlatten, Template: o.Template})
+	if err != nil {
+		return err
+	}
+	var data []byte
+	if o.Format == exportFormatFluxSecret {
+		data, err = fluxKubeconfigSecret(exported, o.Name, o.Namespace, o.SecretKey)
+	} else {
+		data, err = formatExport(exported, o.Format)
+	}
+	if err != nil {
+		return err
+	}
+	if len(o.ToSecret) > 0 {
+		created, err := writeKubeconfigSecret(o.Clientset, o.SecretNamespace, o.ToSecret, o.SecretKey, data)
+		if err != nil {
+			return fmt.Errorf("writing secret %s/%s to %q: %v", o.SecretNamespace, o.ToSecret, o.TargetContext, err)
+		}
+		action := "updated"
+		if created {
+			action = "created"
+		}
+		fmt.Fprintf(o.Out, "Context %q written to secret %s/%s on %q, %s.\n", exported.CurrentContext, o.SecretNamespace, o.ToSecret, o.TargetContext, action)
+		return nil
+	}
+	if !o.Clipboard {
+		_, err = o.Out.Write(data)
+		return err
+	}
+
+	if err := o.CopyToClipboard(data); err != nil {
+		return err
+	}
+	for _, name := range usersWithCredentials(exported) {
+		fmt.Fprintf(o.ErrOut, "warning: the clipboard holds the credentials of user %q, copy it with --sanitized to leave them out\n", name)
+	}
+	fmt.Fprintf(o.Out, "Context %q copied to the clipboard.\n", exported.CurrentContext)
+	return nil
+}
+
+// exportContext returns a kubeconfig holding only a context and the entries it uses, with the files
+// they refer to embedded, and without credentials if sanitized.
+func exportContext(config *clientcmdapi.Config, name string, sanitized bool) (*clientcmdapi.Config, error) {
+	return exportContextAs(config, name, exportPreset{Sanitized: sanitized})
+}
+
+// exportContextAs returns a kubeconfig holding only a context and the entries it uses, shaped by
+// preset.
+func exportContextAs(config *clientcmdapi.Config, name string, preset exportPreset) (*clientcmdapi.Config, error) {
+	name, err := resolveContextName(config, name)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := config.Contexts[name]; !ok {
+		return nil, fmt.Errorf("no context exists with the name: %q", name)
+	}
+
+	exported := config.DeepCopy()
+	exported.CurrentContext = name
+	if err := clientcmdapi.MinifyConfig(exported); err != nil {
+		return nil, err
+	}
+	// The preferences and extensions of the kubeconfig belong to the machine it is on.
+	exported.Preferences = *clientcmdapi.NewPreferences()
+	exported.Extensions = nil
+	if !preset.NoFlatten {
+		if err := clientcmdapi.FlattenConfig(exported); err != nil {
+			return nil, err
+		}
+	}
+	if preset.Sanitized {
+		for authInfoName, authInfo := range exported.AuthInfos {
+			exported.AuthInfos[authInfoName] = sanitizeAuthInfo(authInfo)
+		}
+	}
+
+	if len(preset.Template) > 0 {
+		tmpl, err := template.New("naming").Option("missingkey=error").Parse(preset.Template)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template: %v", err)
+		}
+		newName, err := lintContextName(tmpl, exported, name)
+		if err != nil {
+			return nil, err
+		}
+		if len(newName) == 0 {
+			return nil, fmt.Errorf("the template gives an empty name for context %q", name)
+		}
+		renameContext(exported, name, newName)
+	}
+
+	if len(preset.Fields) > 0 {
+		fields := sets.NewString(preset.Fields...)
+		for _, context := range exported.Contexts {
+			if !fields.Has(exportFieldNamespace) {
+				context.Namespace = ""
+			}
+			if !fields.Has(exportFieldExtensions) {
+				context.Extensions = nil
+			}
+		}
+		for _, cluster := range exported.Clusters {
+			if !fields.Has(exportFieldCertificateAuthority) {
+				cluster.CertificateAuthority = ""
+				cluster.CertificateAuthorityData = nil
+			}
+			if !fields.Has(exportFieldExtensions) {
+				cluster.Extensions = nil
+			}
+		}
+		for _, authInfo := range exported.AuthInfos {
+			if !fields.Has(exportFieldExtensions) {
+				authInfo.Extensions = nil
+			}
+		}
+	}
+	return exported, nil
+}
+
+// sanitizeAuthInfo returns a copy of the user without its credentials, which it still tells the kind
+// of. Unlike redactAuthInfo, which shows a user that stays on this machine, it also drops what only
+// makes sense here, such as the files credentials are read from.
+func sanitizeAuthInfo(authInfo *clientcmdapi.AuthInfo) *clientcmdapi.AuthInfo {
+	sanitized := redactAuthInfo(authInfo)
+	if len(sanitized.TokenFile) > 0 {
+		sanitized.TokenFile = "REDACTED"
+	}
+	if len(sanitized.ClientCertificateData) > 0 {
+		sanitized.ClientCertificateData = []byte("REDACTED")
+	}
+	if len(sanitized.ClientKeyData) > 0 {
+		sanitized.ClientKeyData = []byte("REDACTED")
+	}
+	return sanitized
+}
+
+// writeKubeconfigSecret writes data under key in the Secret namespace/name, creating it if it does
+// not exist, and reports whether it was created. The other keys of an existing Secret are kept.
+func writeKubeconfigSecret(clientset kubernetes.Interface, namespace, name, key string, data []byte) (bool, error) {
+	if clientset == nil {
+		return false, errors.New("not connected to the cluster of the secret")
+	}
+	secrets := clientset.CoreV1().Secrets(namespace)
+	secret, err := secrets.Get(name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Type:       corev1.SecretTypeOpaque,
+			Data:       map[string][]byte{key: data},
+		}
+		_, err = secrets.Create(secret)
+		return err == nil, err
+	}
+	if err != nil {
+		return false, err
+	}
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data[key] = data
+	_, err = secrets.Update(secret)
+	return false, err
+}
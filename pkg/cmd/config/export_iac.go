@@ -0,0 +1,373 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// The formats 'config export' prints in.
+const (
+	exportFormatKubeconfig = "kubeconfig"
+	exportFormatTerraform  = "terraform"
+	exportFormatPulumi     = "pulumi"
+	exportFormatArgoCD     = "argocd"
+	// exportFormatFluxSecret prints the kubeconfig in a Secret, see fluxKubeconfigSecret.
+	exportFormatFluxSecret = "flux-secret"
+	// exportFormatHelmEnv prints the environment pointing helm at the context, see helmEnv.
+	exportFormatHelmEnv = "helm-env"
+)
+
+var validExportFormats = sets.NewString(exportFormatKubeconfig, exportFormatTerraform, exportFormatPulumi, exportFormatArgoCD, exportFormatFluxSecret, exportFormatHelmEnv)
+
+// invalidProviderNameCharacters are the characters Terraform and Pulumi do not accept in the name
+// of a provider.
+var invalidProviderNameCharacters = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// invalidSecretNameCharacters are the characters Kubernetes does not accept in the name of a Secret
+// once lowercased.
+var invalidSecretNameCharacters = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// The namespace Flux is installed in by default, where the Kustomizations and HelmReleases applying
+// to remote clusters read their kubeconfig Secret from by default.
+const fluxNamespace = "flux-system"
+
+// The namespace ArgoCD is installed in by default, and the label of the Secrets it reads clusters
+// from.
+const (
+	argocdNamespace       = "argocd"
+	argocdSecretTypeLabel = "argocd.argoproj.io/secret-type"
+)
+
+// argocdClusterConfig is the config of an ArgoCD cluster Secret, telling how to authenticate to the
+// cluster.
+type argocdClusterConfig struct {
+	Username           string                    `json:"username,omitempty"`
+	Password           string                    `json:"password,omitempty"`
+	BearerToken        string                    `json:"bearerToken,omitempty"`
+	TLSClientConfig    argocdTLSClientConfig     `json:"tlsClientConfig"`
+	ExecProviderConfig *argocdExecProviderConfig `json:"execProviderConfig,omitempty"`
+}
+
+type argocdTLSClientConfig struct {
+	Insecure   bool   `json:"insecure"`
+	ServerName string `json:"serverName,omitempty"`
+	CAData     []byte `json:"caData,omitempty"`
+	CertData   []byte `json:"certData,omitempty"`
+	KeyData    []byte `json:"keyData,omitempty"`
+}
+
+type argocdExecProviderConfig struct {
+	Command    string            `json:"command,omitempty"`
+	Args       []string          `json:"args,omitempty"`
+	Env        map[string]string `json:"env,omitempty"`
+	APIVersion string            `json:"apiVersion,omitempty"`
+}
+
+// hclAttribute is an argument of a Terraform block, whose value is an HCL expression.
+type hclAttribute struct {
+	name, value string
+}
+
+// formatExport returns the exported kubeconfig in format, kubeconfig when empty.
+func formatExport(exported *clientcmdapi.Config, format string) ([]byte, error) {
+	switch format {
+	case exportFormatTerraform:
+		return terraformProvider(exported)
+	case exportFormatPulumi:
+		return pulumiProvider(exported)
+	case exportFormatArgoCD:
+		return argocdClusterSecret(exported)
+	}
+	return clientcmd.Write(*exported)
+}
+
+// providerName returns the name of the provider of a context, such as the alias of a Terraform
+// provider.
+func providerName(context string) string {
+	name := strings.Trim(invalidProviderNameCharacters.ReplaceAllString(context, "_"), "_-")
+	if len(name) == 0 || (name[0] >= '0' && name[0] <= '9') {
+		name = "k8s_" + name
+	}
+	return name
+}
+
+// terraformProvider returns the kubernetes provider block of Terraform reaching the cluster of the
+// current-context of exported with its user.
+func terraformProvider(exported *clientcmdapi.Config) ([]byte, error) {
+	name := exported.CurrentContext
+	context := exported.Contexts[name]
+	cluster, ok := exported.Clusters[context.Cluster]
+	if !ok {
+		return nil, fmt.Errorf("context %q has no cluster %q", name, context.Cluster)
+	}
+
+	attributes := []hclAttribute{
+		{"alias", hclString(providerName(name))},
+		{"host", hclString(cluster.Server)},
+	}
+	if value := hclFileOrData(cluster.CertificateAuthority, cluster.CertificateAuthorityData); len(value) > 0 {
+		attributes = append(attributes, hclAttribute{"cluster_ca_certificate", value})
+	}
+	if cluster.InsecureSkipTLSVerify {
+		attributes = append(attributes, hclAttribute{"insecure", "true"})
+	}
+
+	authInfo := exported.AuthInfos[context.AuthInfo]
+	if authInfo == nil {
+		authInfo = clientcmdapi.NewAuthInfo()
+	}
+	if authInfo.AuthProvider != nil {
+		return nil, fmt.Errorf("user %q authenticates with the %s auth provider, which the kubernetes provider of Terraform does not support", context.AuthInfo, authInfo.AuthProvider.Name)
+	}
+	if value := hclFileOrData(authInfo.ClientCertificate, authInfo.ClientCertificateData); len(value) > 0 {
+		attributes = append(attributes, hclAttribute{"client_certificate", value})
+	}
+	if value := hclFileOrData(authInfo.ClientKey, authInfo.ClientKeyData); len(value) > 0 {
+		attributes = append(attributes, hclAttribute{"client_key", value})
+	}
+	switch {
+	case len(authInfo.Token) > 0:
+		attributes = append(attributes, hclAttribute{"token", hclString(authInfo.Token)})
+	case len(authInfo.TokenFile) > 0:
+		attributes = append(attributes, hclAttribute{"token", "trimspace(file(" + hclString(authInfo.TokenFile) + "))"})
+	}
+	if len(authInfo.Username) > 0 {
+		attributes = append(attributes, hclAttribute{"username", hclString(authInfo.Username)})
+	}
+	if len(authInfo.Password) > 0 {
+		attributes = append(attributes, hclAttribute{"password", hclString(authInfo.Password)})
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "provider \"kubernetes\" {\n")
+	writeHCLAttributes(buf, "  ", attributes)
+	if exec := authInfo.Exec; exec != nil {
+		fmt.Fprintf(buf, "\n  exec {\n")
+		execAttributes := []hclAttribute{
+			{"api_version", hclString(exec.APIVersion)},
+			{"command", hclString(exec.Command)},
+		}
+		if len(exec.Args) > 0 {
+			args := []string{}
+			for _, arg := range exec.Args {
+				args = append(args, hclString(arg))
+			}
+			execAttributes = append(execAttributes, hclAttribute{"args", "[" + strings.Join(args, ", ") + "]"})
+		}
+		writeHCLAttributes(buf, "    ", execAttributes)
+		if len(exec.Env) > 0 {
+			env := []hclAttribute{}
+			for _, variable := range exec.Env {
+				env = append(env, hclAttribute{hclString(variable.Name), hclString(variable.Value)})
+			}
+			sort.Slice(env, func(i, j int) bool { return env[i].name < env[j].name })
+			fmt.Fprintf(buf, "    env = {\n")
+			writeHCLAttributes(buf, "      ", env)
+			fmt.Fprintf(buf, "    }\n")
+		}
+		fmt.Fprintf(buf, "  }\n")
+	}
+	fmt.Fprintf(buf, "}\n")
+	return buf.Bytes(), nil
+}
+
+// writeHCLAttributes writes attributes with their equal signs aligned, as terraform fmt does.
+func writeHCLAttributes(buf *bytes.Buffer, indent string, attributes []hclAttribute) {
+	width := 0
+	for _, attribute := range attributes {
+		if len(attribute.name) > width {
+			width = len(attribute.name)
+		}
+	}
+	for _, attribute := range attributes {
+		fmt.Fprintf(buf, "%s%-*s = %s\n", indent, width, attribute.name, attribute.value)
+	}
+}
+
+// hclFileOrData returns the HCL expression reading the file, or decoding the embedded data, or an
+// empty string when there is neither.
+func hclFileOrData(file string, data []byte) string {
+	switch {
+	case len(data) > 0:
+		return "base64decode(" + hclString(base64.StdEncoding.EncodeToString(data)) + ")"
+	case len(file) > 0:
+		return "file(" + hclString(file) + ")"
+	}
+	return ""
+}
+
+// hclString returns s as an HCL string literal, in which template sequences are escaped.
+func hclString(s string) string {
+	quoted := strconv.Quote(s)
+	quoted = strings.Replace(quoted, "${", "$${", -1)
+	return strings.Replace(quoted, "%{", "%%{", -1)
+}
+
+// pulumiProvider returns a Pulumi YAML program declaring the kubernetes provider of the
+// current-context of exported, with the kubeconfig embedded.
+func pulumiProvider(exported *clientcmdapi.Config) ([]byte, error) {
+	kubeconfig, err := clientcmd.Write(*exported)
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "resources:\n")
+	fmt.Fprintf(buf, "  %s:\n", providerName(exported.CurrentContext))
+	fmt.Fprintf(buf, "    type: pulumi:providers:kubernetes\n")
+	fmt.Fprintf(buf, "    properties:\n")
+	fmt.Fprintf(buf, "      context: %s\n", strconv.Quote(exported.CurrentContext))
+	fmt.Fprintf(buf, "      kubeconfig: |\n")
+	for _, line := range strings.SplitAfter(string(kubeconfig), "\n") {
+		if len(line) > 0 {
+			fmt.Fprintf(buf, "        %s", line)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// argocdClusterSecret returns the manifest of the Secret declaring the cluster of the
+// current-context of exported to ArgoCD, authenticating with its user. Files the entries refer to
+// are read, as ArgoCD runs elsewhere.
+func argocdClusterSecret(exported *clientcmdapi.Config) ([]byte, error) {
+	name := exported.CurrentContext
+	context := exported.Contexts[name]
+	cluster, ok := exported.Clusters[context.Cluster]
+	if !ok {
+		return nil, fmt.Errorf("context %q has no cluster %q", name, context.Cluster)
+	}
+	authInfo := exported.AuthInfos[context.AuthInfo]
+	if authInfo == nil {
+		authInfo = clientcmdapi.NewAuthInfo()
+	}
+	if authInfo.AuthProvider != nil {
+		return nil, fmt.Errorf("user %q authenticates with the %s auth provider, which ArgoCD does not support", context.AuthInfo, authInfo.AuthProvider.Name)
+	}
+
+	config := argocdClusterConfig{
+		Username:        authInfo.Username,
+		Password:        authInfo.Password,
+		BearerToken:     authInfo.Token,
+		TLSClientConfig: argocdTLSClientConfig{Insecure: cluster.InsecureSkipTLSVerify},
+	}
+	var err error
+	if config.TLSClientConfig.CAData, err = fileOrData(cluster.CertificateAuthority, cluster.CertificateAuthorityData); err != nil {
+		return nil, err
+	}
+	if config.TLSClientConfig.CertData, err = fileOrData(authInfo.ClientCertificate, authInfo.ClientCertificateData); err != nil {
+		return nil, err
+	}
+	if config.TLSClientConfig.KeyData, err = fileOrData(authInfo.ClientKey, authInfo.ClientKeyData); err != nil {
+		return nil, err
+	}
+	if len(config.BearerToken) == 0 && len(authInfo.TokenFile) > 0 {
+		token, err := ioutil.ReadFile(authInfo.TokenFile)
+		if err != nil {
+			return nil, err
+		}
+		config.BearerToken = strings.TrimSpace(string(token))
+	}
+	if exec := authInfo.Exec; exec != nil {
+		config.ExecProviderConfig = &argocdExecProviderConfig{
+			Command:    exec.Command,
+			Args:       exec.Args,
+			APIVersion: exec.APIVersion,
+		}
+		if len(exec.Env) > 0 {
+			config.ExecProviderConfig.Env = map[string]string{}
+			for _, variable := range exec.Env {
+				config.ExecProviderConfig.Env[variable.Name] = variable.Value
+			}
+		}
+	}
+	configJSON, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	secret := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretName("cluster-" + name),
+			Namespace: argocdNamespace,
+			Labels:    map[string]string{argocdSecretTypeLabel: "cluster"},
+		},
+		Type: corev1.SecretTypeOpaque,
+		StringData: map[string]string{
+			"name":   name,
+			"server": cluster.Server,
+			"config": string(configJSON) + "\n",
+		},
+	}
+	return yaml.Marshal(secret)
+}
+
+// fileOrData returns the embedded data, or the content of the file.
+func fileOrData(file string, data []byte) ([]byte, error) {
+	if len(data) > 0 || len(file) == 0 {
+		return data, nil
+	}
+	return ioutil.ReadFile(file)
+}
+
+// fluxKubeconfigSecret returns the manifest of the Secret holding exported under key, as Flux reads
+// it to apply to a remote cluster and Cluster API names it. name defaults to CONTEXT-kubeconfig,
+// and namespace to flux-system.
+func fluxKubeconfigSecret(exported *clientcmdapi.Config, name, namespace, key string) ([]byte, error) {
+	kubeconfig, err := clientcmd.Write(*exported)
+	if err != nil {
+		return nil, err
+	}
+	if len(name) == 0 {
+		name = secretName(exported.CurrentContext + "-kubeconfig")
+	}
+	if len(namespace) == 0 {
+		namespace = fluxNamespace
+	}
+	secret := &corev1.Secret{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Type:       corev1.SecretTypeOpaque,
+		StringData: map[string]string{key: string(kubeconfig)},
+	}
+	return yaml.Marshal(secret)
+}
+
+// secretName turns name into a valid name of a Secret.
+func secretName(name string) string {
+	name = strings.Trim(invalidSecretNameCharacters.ReplaceAllString(strings.ToLower(name), "-"), "-.")
+	if len(name) > 253 {
+		name = strings.TrimRight(name[:253], "-.")
+	}
+	return name
+}
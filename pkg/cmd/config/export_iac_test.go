@@ -0,0 +1,207 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestTerraformProvider(t *testing.T) {
+	exported := &clientcmdapi.Config{
+		CurrentContext: "arn:aws:eks:eu-west-1:123:cluster/prod",
+		Contexts: map[string]*clientcmdapi.Context{
+			"arn:aws:eks:eu-west-1:123:cluster/prod": {Cluster: "prod", AuthInfo: "prod"},
+		},
+		Clusters: map[string]*clientcmdapi.Cluster{
+			"prod": {Server: "https://prod.example.com", CertificateAuthorityData: []byte("ca")},
+		},
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{
+			"prod": {Exec: &clientcmdapi.ExecConfig{
+				APIVersion: "client.authentication.k8s.io/v1beta1",
+				Command:    "aws",
+				Args:       []string{"eks", "get-token", "--cluster-name", "prod"},
+				Env:        []clientcmdapi.ExecEnvVar{{Name: "AWS_PROFILE", Value: "prod"}, {Name: "AWS_REGION", Value: "${region}"}},
+			}},
+		},
+	}
+	expected := `provider "kubernetes" {
+  alias                  = "arn_aws_eks_eu-west-1_123_cluster_prod"
+  host                   = "https://prod.example.com"
+  cluster_ca_certificate = base64decode("Y2E=")
+
+  exec {
+    api_version = "client.authentication.k8s.io/v1beta1"
+    command     = "aws"
+    args        = ["eks", "get-token", "--cluster-name", "prod"]
+    env = {
+      "AWS_PROFILE" = "prod"
+      "AWS_REGION"  = "$${region}"
+    }
+  }
+}
+`
+	data, err := formatExport(exported, exportFormatTerraform)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data) != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, data)
+	}
+
+	exported.Clusters["prod"] = &clientcmdapi.Cluster{Server: "https://prod.example.com", CertificateAuthority: "/etc/ca.crt"}
+	exported.AuthInfos["prod"] = &clientcmdapi.AuthInfo{Token: "REDACTED"}
+	data, err = formatExport(exported, exportFormatTerraform)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, line := range []string{`cluster_ca_certificate = file("/etc/ca.crt")`, `token                  = "REDACTED"`} {
+		if !strings.Contains(string(data), line) {
+			t.Errorf("expected %q in:\n%s", line, data)
+		}
+	}
+
+	exported.AuthInfos["prod"] = &clientcmdapi.AuthInfo{AuthProvider: &clientcmdapi.AuthProviderConfig{Name: "gcp"}}
+	if _, err := formatExport(exported, exportFormatTerraform); err == nil {
+		t.Errorf("expected an auth provider to be refused")
+	}
+}
+
+func TestPulumiProvider(t *testing.T) {
+	config := newRedFederalCowHammerConfig()
+	data, err := formatExport(&config, exportFormatPulumi)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	program := struct {
+		Resources map[string]struct {
+			Type       string `json:"type"`
+			Properties struct {
+				Context    string `json:"context"`
+				Kubeconfig string `json:"kubeconfig"`
+			} `json:"properties"`
+		} `json:"resources"`
+	}{}
+	if err := yaml.Unmarshal(data, &program); err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, data)
+	}
+	provider, ok := program.Resources["federal-context"]
+	if !ok || provider.Type != "pulumi:providers:kubernetes" || provider.Properties.Context != "federal-context" {
+		t.Fatalf("unexpected program:\n%s", data)
+	}
+	kubeconfig, err := clientcmd.Load([]byte(provider.Properties.Kubeconfig))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if kubeconfig.Clusters["cow-cluster"].Server != "http://cow.org:8080" {
+		t.Errorf("expected the kubeconfig to be embedded, got:\n%s", provider.Properties.Kubeconfig)
+	}
+}
+
+func TestArgoCDClusterSecret(t *testing.T) {
+	exported := &clientcmdapi.Config{
+		CurrentContext: "Prod_EU",
+		Contexts: map[string]*clientcmdapi.Context{
+			"Prod_EU": {Cluster: "prod", AuthInfo: "prod"},
+		},
+		Clusters: map[string]*clientcmdapi.Cluster{
+			"prod": {Server: "https://prod.example.com", CertificateAuthorityData: []byte("ca")},
+		},
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{
+			"prod": {Exec: &clientcmdapi.ExecConfig{
+				APIVersion: "client.authentication.k8s.io/v1beta1",
+				Command:    "argocd-k8s-auth",
+				Args:       []string{"aws", "--cluster-name", "prod"},
+				Env:        []clientcmdapi.ExecEnvVar{{Name: "AWS_REGION", Value: "eu-west-1"}},
+			}},
+		},
+	}
+	data, err := formatExport(exported, exportFormatArgoCD)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	secret := &corev1.Secret{}
+	if err := yaml.Unmarshal(data, secret); err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, data)
+	}
+	if secret.Name != "cluster-prod-eu" || secret.Namespace != argocdNamespace || secret.Labels[argocdSecretTypeLabel] != "cluster" {
+		t.Errorf("unexpected metadata:\n%s", data)
+	}
+	if secret.StringData["name"] != "Prod_EU" || secret.StringData["server"] != "https://prod.example.com" {
+		t.Errorf("unexpected name or server:\n%s", data)
+	}
+	config := argocdClusterConfig{}
+	if err := json.Unmarshal([]byte(secret.StringData["config"]), &config); err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, data)
+	}
+	expected := argocdClusterConfig{
+		TLSClientConfig: argocdTLSClientConfig{CAData: []byte("ca")},
+		ExecProviderConfig: &argocdExecProviderConfig{
+			Command:    "argocd-k8s-auth",
+			Args:       []string{"aws", "--cluster-name", "prod"},
+			Env:        map[string]string{"AWS_REGION": "eu-west-1"},
+			APIVersion: "client.authentication.k8s.io/v1beta1",
+		},
+	}
+	if !reflect.DeepEqual(expected, config) {
+		t.Errorf("expected %#v, got %#v", expected, config)
+	}
+
+	exported.AuthInfos["prod"] = &clientcmdapi.AuthInfo{AuthProvider: &clientcmdapi.AuthProviderConfig{Name: "gcp"}}
+	if _, err := formatExport(exported, exportFormatArgoCD); err == nil {
+		t.Errorf("expected an auth provider to be refused")
+	}
+}
+
+func TestFluxKubeconfigSecret(t *testing.T) {
+	config := newRedFederalCowHammerConfig()
+	tests := []struct {
+		name, namespace          string
+		expectedName, expectedNS string
+	}{
+		{expectedName: "federal-context-kubeconfig", expectedNS: fluxNamespace},
+		{name: "cow-kubeconfig", namespace: "apps", expectedName: "cow-kubeconfig", expectedNS: "apps"},
+	}
+	for _, tt := range tests {
+		data, err := fluxKubeconfigSecret(&config, tt.name, tt.namespace, capiKubeconfigKey)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		secret := &corev1.Secret{}
+		if err := yaml.Unmarshal(data, secret); err != nil {
+			t.Fatalf("unexpected error: %v\n%s", err, data)
+		}
+		if secret.Name != tt.expectedName || secret.Namespace != tt.expectedNS || secret.Kind != "Secret" {
+			t.Errorf("expected the Secret %s/%s, got:\n%s", tt.expectedNS, tt.expectedName, data)
+		}
+		kubeconfig, err := clientcmd.Load([]byte(secret.StringData[capiKubeconfigKey]))
+		if err != nil {
+			t.Fatalf("unexpected error: %v\n%s", err, data)
+		}
+		if kubeconfig.CurrentContext != "federal-context" || kubeconfig.Clusters["cow-cluster"].Server != "http://cow.org:8080" {
+			t.Errorf("expected the kubeconfig under %q, got:\n%s", capiKubeconfigKey, data)
+		}
+	}
+}
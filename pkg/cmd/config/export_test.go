@@ -0,0 +1,256 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestExport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "export")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	caFile := filepath.Join(dir, "ca.crt")
+	if err := ioutil.WriteFile(caFile, []byte("certificate authority"), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	kubeconfig := filepath.Join(dir, "config")
+	startingConfig := newRedFederalCowHammerConfig()
+	startingConfig.Clusters["prod-cluster"] = &clientcmdapi.Cluster{Server: "https://prod.example.com", CertificateAuthority: caFile}
+	startingConfig.AuthInfos["prod-user"] = &clientcmdapi.AuthInfo{
+		ClientCertificateData: []byte("client certificate"),
+		ClientKeyData:         []byte("private key"),
+		Exec: &clientcmdapi.ExecConfig{
+			APIVersion: "client.authentication.k8s.io/v1beta1",
+			Command:    "prod-login",
+			Env:        []clientcmdapi.ExecEnvVar{{Name: "LOGIN_SECRET", Value: "hunter2"}, {Name: "REGION", Value: "eu"}},
+		},
+	}
+	startingConfig.Contexts["prod"] = &clientcmdapi.Context{Cluster: "prod-cluster", AuthInfo: "prod-user", Namespace: "web"}
+	if err := clientcmd.WriteToFile(startingConfig, kubeconfig); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = kubeconfig
+	pathOptions.EnvVar = ""
+
+	export := func(context string, sanitized bool) *clientcmdapi.Config {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		o := &ExportOptions{ConfigAccess: pathOptions, Context: context, Sanitized: sanitized, IOStreams: streams}
+		if err := o.Run(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if sanitized {
+			for _, secret := range []string{"hunter2", "red-token"} {
+				if strings.Contains(out.String(), secret) {
+					t.Errorf("expected %q to be stripped from\n%s", secret, out.String())
+				}
+			}
+		}
+		exported, err := clientcmd.Load(out.Bytes())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return exported
+	}
+
+	exported := export("prod", true)
+	if exported.CurrentContext != "prod" || len(exported.Contexts) != 1 || len(exported.Clusters) != 1 || len(exported.AuthInfos) != 1 {
+		t.Fatalf("expected only the prod entries, got %#v", exported)
+	}
+	cluster := exported.Clusters["prod-cluster"]
+	if cluster.Server != "https://prod.example.com" || string(cluster.CertificateAuthorityData) != "certificate authority" || len(cluster.CertificateAuthority) != 0 {
+		t.Errorf("expected the server and embedded certificate authority, got %#v", cluster)
+	}
+	authInfo := exported.AuthInfos["prod-user"]
+	if string(authInfo.ClientCertificateData) != "REDACTED" || string(authInfo.ClientKeyData) != "REDACTED" {
+		t.Errorf("expected the client certificate and key to be redacted, got %q, %q", authInfo.ClientCertificateData, authInfo.ClientKeyData)
+	}
+	if authInfo.Exec == nil || authInfo.Exec.Command != "prod-login" || authInfo.Exec.Env[1].Value != "eu" {
+		t.Errorf("expected the exec plugin to be kept, got %#v", authInfo.Exec)
+	}
+	if exported.Contexts["prod"].Namespace != "web" {
+		t.Errorf("expected the namespace to be kept, got %#v", exported.Contexts["prod"])
+	}
+
+	if exported := export(".", false); exported.AuthInfos["red-user"].Token != "red-token" {
+		t.Errorf("expected the credentials without --sanitized, got %#v", exported.AuthInfos)
+	}
+	if exported := export("federal-context", true); exported.AuthInfos["red-user"].Token != "REDACTED" {
+		t.Errorf("expected the token to be redacted, got %q", exported.AuthInfos["red-user"].Token)
+	}
+
+	o := &ExportOptions{ConfigAccess: pathOptions, Context: "missing", IOStreams: genericclioptions.NewTestIOStreamsDiscard()}
+	if err := o.Run(); err == nil {
+		t.Errorf("expected an error for a missing context")
+	}
+}
+
+func TestExportToSecret(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	if err := clientcmd.WriteToFile(newRedFederalCowHammerConfig(), fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	clientset := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "flux-system", Name: "existing"},
+		Data:       map[string][]byte{"other": []byte("kept"), "value": []byte("stale")},
+	})
+	export := func(name string) string {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		o := &ExportOptions{
+			ConfigAccess:    pathOptions,
+			Context:         "federal-context",
+			ToSecret:        name,
+			TargetContext:   "mgmt",
+			SecretKey:       "value",
+			Clientset:       clientset,
+			SecretNamespace: "flux-system",
+			IOStreams:       streams,
+		}
+		if err := o.Run(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return out.String()
+	}
+
+	if out := export("federal-kubeconfig"); out != "Context \"federal-context\" written to secret flux-system/federal-kubeconfig on \"mgmt\", created.\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+	if out := export("existing"); !strings.HasSuffix(out, ", updated.\n") {
+		t.Errorf("unexpected output %q", out)
+	}
+	for _, name := range []string{"federal-kubeconfig", "existing"} {
+		secret, err := clientset.CoreV1().Secrets("flux-system").Get(name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		config, err := clientcmd.Load(secret.Data["value"])
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if config.CurrentContext != "federal-context" || len(config.Contexts) != 1 {
+			t.Errorf("%s: expected the exported context, got\n%s", name, secret.Data["value"])
+		}
+	}
+	secret, _ := clientset.CoreV1().Secrets("flux-system").Get("existing", metav1.GetOptions{})
+	if string(secret.Data["other"]) != "kept" {
+		t.Errorf("expected the other keys of the secret to be kept, got %v", secret.Data)
+	}
+}
+
+func TestExportPreset(t *testing.T) {
+	dir, err := ioutil.TempDir("", "export")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	caFile := filepath.Join(dir, "ca.crt")
+	if err := ioutil.WriteFile(caFile, []byte("certificate authority"), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	startingConfig := newRedFederalCowHammerConfig()
+	startingConfig.Clusters["cow-cluster"].CertificateAuthority = caFile
+	startingConfig.Contexts["federal-context"].Namespace = "web"
+	if err := writeExtension(&startingConfig.Contexts["federal-context"].Extensions, readOnlyExtension, readOnly{Enabled: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := clientcmd.WriteToFile(startingConfig, filepath.Join(dir, "config")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = filepath.Join(dir, "config")
+	pathOptions.EnvVar = ""
+	settings := &Settings{ExportPresets: map[string]exportPreset{
+		"ci":    {Sanitized: true, Fields: []string{"certificate-authority"}, Template: "ci-{{.Cluster}}"},
+		"local": {NoFlatten: true},
+	}}
+
+	export := func(preset string, changed ...string) *clientcmdapi.Config {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		o := &ExportOptions{ConfigAccess: pathOptions, Context: "federal-context", Preset: preset, Sanitized: true, IOStreams: streams}
+		if err := o.applyPreset(settings, sets.NewString(changed...).Has); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := o.Validate(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := o.Run(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		exported, err := clientcmd.Load(out.Bytes())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return exported
+	}
+
+	exported := export("ci")
+	context, ok := exported.Contexts["ci-cow-cluster"]
+	if !ok || exported.CurrentContext != "ci-cow-cluster" {
+		t.Fatalf("expected the context to be renamed by the template, got %v", exported.Contexts)
+	}
+	if len(context.Namespace) > 0 || len(context.Extensions) > 0 {
+		t.Errorf("expected only the certificate authority to be kept, got %#v", context)
+	}
+	if string(exported.Clusters["cow-cluster"].CertificateAuthorityData) != "certificate authority" {
+		t.Errorf("expected the embedded certificate authority, got %#v", exported.Clusters["cow-cluster"])
+	}
+	if exported.AuthInfos["red-user"].Token != "REDACTED" {
+		t.Errorf("expected the token to be redacted, got %q", exported.AuthInfos["red-user"].Token)
+	}
+
+	// a flag given wins over the preset
+	exported = export("local", "sanitized")
+	if exported.Clusters["cow-cluster"].CertificateAuthority != caFile || exported.AuthInfos["red-user"].Token != "REDACTED" {
+		t.Errorf("expected the file reference and the redacted token, got %#v %#v", exported.Clusters["cow-cluster"], exported.AuthInfos["red-user"])
+	}
+	if exported.Contexts["federal-context"].Namespace != "web" {
+		t.Errorf("expected every field without --fields, got %#v", exported.Contexts["federal-context"])
+	}
+
+	o := &ExportOptions{Preset: "missing"}
+	if err := o.applyPreset(settings, sets.NewString().Has); err == nil || !strings.Contains(err.Error(), "ci, local") {
+		t.Errorf("expected the presets to be listed, got %v", err)
+	}
+	o = &ExportOptions{Fields: []string{"token"}}
+	if err := o.Validate(); err == nil {
+		t.Errorf("expected an unknown field to be rejected")
+	}
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// Names of the kubeconfig extensions the config subcommands keep their metadata in. Extensions are
+// preserved by every tool that reads and writes kubeconfig files through client-go.
+const (
+	lastNamespaceExtension   = "kubecfg.io/last-namespace"
+	contextMetadataExtension = "kubecfg.io/metadata"
+	includeExtension         = "kubecfg.io/include"
+	sourcesExtension         = "kubecfg.io/sources"
+	identityExtension        = "kubecfg.io/identity"
+	localClustersExtension   = "kubecfg.io/local-clusters"
+	capiClustersExtension    = "kubecfg.io/capi-clusters"
+	vclustersExtension       = "kubecfg.io/vclusters"
+	portForwardExtension     = "kubecfg.io/port-forward"
+	// credentialRefreshExtension keeps the exec plugin of a user replaced by "config refresh --wrap".
+	credentialRefreshExtension = "kubecfg.io/credential-refresh"
+	// kubectlFlagsExtension keeps the kubectl flags "config exec" adds for a context.
+	kubectlFlagsExtension = "kubecfg.io/kubectl-flags"
+	// readOnlyExtension marks a context "config exec" runs only reading kubectl commands against.
+	readOnlyExtension = "kubecfg.io/read-only"
+	// contextGroupsExtension keeps the context groups of "config group" in the preferences.
+	contextGroupsExtension = "kubecfg.io/context-groups"
+	// autoswitchExtension keeps the rules of "config autoswitch" in the preferences.
+	autoswitchExtension = "kubecfg.io/autoswitch"
+	// clientTuningExtension keeps the timeout, QPS and burst of clients of a context.
+	clientTuningExtension = "kubecfg.io/client-tuning"
+	// deprecationExtension keeps the sunset date and message of a context "config deprecate" set.
+	deprecationExtension = "kubecfg.io/deprecation"
+	// kubectlVersionExtension keeps the range of kubectl versions "config exec" runs for a context.
+	kubectlVersionExtension = "kubecfg.io/kubectl-version"
+	// ephemeralExtension marks a context "config as" created, which "config gc" removes once expired.
+	ephemeralExtension = "kubecfg.io/ephemeral"
+	// bannerExtension keeps the banner shown when entering a context.
+	bannerExtension = "kubecfg.io/banner"
+	// tagBannersExtension keeps the banners of context tags in the preferences.
+	tagBannersExtension = "kubecfg.io/tag-banners"
+	// provenanceExtension records where an imported cluster, user or context comes from.
+	provenanceExtension = "kubecfg.io/provenance"
+	// locksExtension keeps the checksums of the entries "config lock" locked in the preferences.
+	locksExtension = "kubecfg.io/locks"
+	// pkcs11Extension keeps the PKCS#11 token holding the client certificate and key of a user.
+	pkcs11Extension = "kubecfg.io/pkcs11"
+	// encryptedUserExtension keeps the credentials of a user encrypted by "config encrypt-user".
+	encryptedUserExtension = "kubecfg.io/encrypted-user"
+	// protectionExtension keeps the security key "config protect" requires a touch of to use a context.
+	protectionExtension = "kubecfg.io/protection"
+	// preferIPv6Extension marks a cluster whose server is dialed over IPv6 first.
+	preferIPv6Extension = "kubecfg.io/prefer-ipv6"
+	// pluginPolicyExtension keeps the kubectl plugins "config exec" allows or denies for a context.
+	pluginPolicyExtension = "kubecfg.io/plugins"
+	// namespaceBookmarksExtension keeps the namespaces "config ns" offers first for a context.
+	namespaceBookmarksExtension = "kubecfg.io/namespace-bookmarks"
+	// clusterEndpointsExtension keeps the public and private endpoints "config endpoint" switches a
+	// cluster between.
+	clusterEndpointsExtension = "kubecfg.io/endpoints"
+)
+
+// ownerAnnotation is the annotation of a context naming the team or person responsible for it. The
+// other owner annotations tell how to reach them, see "config set-owner".
+const (
+	ownerAnnotation      = "owner"
+	ownerSlackAnnotation = "owner-slack"
+	ownerEmailAnnotation = "owner-email"
+	ownerURLAnnotation   = "owner-url"
+)
+
+// lastNamespace is stored in a context's lastNamespaceExtension.
+type lastNamespace struct {
+	Namespace string `json:"namespace"`
+}
+
+// contextMetadata is stored in a context's contextMetadataExtension. Annotations are free-form
+// key/value pairs, tags are labels used to group contexts.
+type contextMetadata struct {
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+}
+
+// managedEntries names the entries a subcommand merged into the kubeconfig from elsewhere, which
+// its next run replaces or removes.
+type managedEntries struct {
+	Clusters  []string `json:"clusters,omitempty"`
+	AuthInfos []string `json:"users,omitempty"`
+	Contexts  []string `json:"contexts,omitempty"`
+}
+
+// managedEntriesByContext names, for every context of a cluster entries are imported from, the
+// entries imported from it, such as the workload clusters "config import capi" finds through a
+// management cluster.
+type managedEntriesByContext map[string]managedEntries
+
+// includes is stored in the preferences' includeExtension. Patterns name the kubeconfig files merged
+// by "config include sync".
+type includes struct {
+	Patterns []string `json:"patterns,omitempty"`
+	managedEntries
+}
+
+// readContextMetadata returns the metadata of a context, which is empty when it has none.
+func readContextMetadata(context *clientcmdapi.Context) (contextMetadata, error) {
+	metadata := contextMetadata{}
+	_, err := readExtension(context.Extensions, contextMetadataExtension, &metadata)
+	return metadata, err
+}
+
+// writeContextMetadata stores the metadata of a context, removing the extension when it is empty.
+func writeContextMetadata(context *clientcmdapi.Context, metadata contextMetadata) error {
+	if len(metadata.Annotations) == 0 && len(metadata.Tags) == 0 {
+		delete(context.Extensions, contextMetadataExtension)
+		return nil
+	}
+	return writeExtension(&context.Extensions, contextMetadataExtension, metadata)
+}
+
+// readExtension decodes the extension called name into value, and reports whether it was present.
+func readExtension(extensions map[string]runtime.Object, name string, value interface{}) (bool, error) {
+	obj, ok := extensions[name]
+	if !ok || obj == nil {
+		return false, nil
+	}
+
+	var data []byte
+	if unknown, ok := obj.(*runtime.Unknown); ok {
+		data = unknown.Raw
+	} else {
+		var err error
+		if data, err = json.Marshal(obj); err != nil {
+			return true, err
+		}
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		return true, fmt.Errorf("invalid %s extension: %v", name, err)
+	}
+	return true, nil
+}
+
+// writeExtension stores value as the JSON extension called name, allocating the map if needed.
+func writeExtension(extensions *map[string]runtime.Object, name string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if *extensions == nil {
+		*extensions = map[string]runtime.Object{}
+	}
+	(*extensions)[name] = &runtime.Unknown{Raw: data, ContentType: runtime.ContentTypeJSON}
+	return nil
+}
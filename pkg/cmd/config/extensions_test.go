@@ -0,0 +1,54 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestExtensionRoundTrip(t *testing.T) {
+	config := newRedFederalCowHammerConfig()
+	context := config.Contexts["federal-context"]
+	if err := writeExtension(&context.Extensions, lastNamespaceExtension, lastNamespace{Namespace: "saw-ns"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := clientcmd.Write(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	loaded, err := clientcmd.Load(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	last := lastNamespace{}
+	found, err := readExtension(loaded.Contexts["federal-context"].Extensions, lastNamespaceExtension, &last)
+	if err != nil || !found {
+		t.Fatalf("expected the extension to survive a round trip, got %v, %v", found, err)
+	}
+	if last.Namespace != "saw-ns" {
+		t.Errorf("expected namespace %q, got %q", "saw-ns", last.Namespace)
+	}
+
+	if found, err := readExtension(clientcmdapi.NewContext().Extensions, lastNamespaceExtension, &last); found || err != nil {
+		t.Errorf("expected a missing extension to be reported as not found, got %v, %v", found, err)
+	}
+}
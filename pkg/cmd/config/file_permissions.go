@@ -0,0 +1,47 @@
+// +build !windows
+
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+// privateFileProblem describes why a file holding credentials can be accessed by other users than
+// its owner, or returns an empty string when it cannot.
+func privateFileProblem(filename string) (string, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return "", err
+	}
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		return fmt.Sprintf("is accessible by other users (mode %04o)", perm), nil
+	}
+	return "", nil
+}
+
+// makeFilePrivate restricts access to a file to its owner.
+func makeFilePrivate(filename string) error {
+	return os.Chmod(filename, 0600)
+}
+
+// makeFilePrivateHint is the command a user would run to do what makeFilePrivate does.
+func makeFilePrivateHint(filename string) string {
+	return "run: chmod 600 " + filename
+}
@@ -0,0 +1,238 @@
+// +build windows
+
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// File modes mean nothing on NTFS, where access is granted by the entries of the file's
+// discretionary access control list (DACL). The functions below are not wrapped by
+// golang.org/x/sys/windows, so they are called directly.
+var (
+	modadvapi32               = windows.NewLazySystemDLL("advapi32.dll")
+	procGetNamedSecurityInfoW = modadvapi32.NewProc("GetNamedSecurityInfoW")
+	procSetNamedSecurityInfoW = modadvapi32.NewProc("SetNamedSecurityInfoW")
+	procSetEntriesInAclW      = modadvapi32.NewProc("SetEntriesInAclW")
+	procGetAclInformation     = modadvapi32.NewProc("GetAclInformation")
+	procGetAce                = modadvapi32.NewProc("GetAce")
+	procEqualSid              = modadvapi32.NewProc("EqualSid")
+)
+
+const (
+	seFileObject = 1
+
+	ownerSecurityInformation         = 0x1
+	daclSecurityInformation          = 0x4
+	protectedDaclSecurityInformation = 0x80000000
+
+	aclSizeInformation = 2
+
+	accessAllowedAceType = 0
+	inheritOnlyAce       = 0x8
+
+	genericAll    = 0x10000000
+	setAccess     = 2
+	noInheritance = 0
+	trusteeIsSid  = 0
+
+	systemSID         = "S-1-5-18"
+	administratorsSID = "S-1-5-32-544"
+)
+
+type aclSizeInfo struct {
+	AceCount      uint32
+	AclBytesInUse uint32
+	AclBytesFree  uint32
+}
+
+type aceHeader struct {
+	AceType  uint8
+	AceFlags uint8
+	AceSize  uint16
+}
+
+// accessAllowedAce is followed in memory by the rest of the SID starting at SidStart.
+type accessAllowedAce struct {
+	Header   aceHeader
+	Mask     uint32
+	SidStart uint32
+}
+
+type trustee struct {
+	MultipleTrustee          uintptr
+	MultipleTrusteeOperation uint32
+	TrusteeForm              uint32
+	TrusteeType              uint32
+	Name                     uintptr
+}
+
+type explicitAccess struct {
+	AccessPermissions uint32
+	AccessMode        uint32
+	Inheritance       uint32
+	Trustee           trustee
+}
+
+// privateFileProblem describes why a file holding credentials can be accessed by other users than
+// its owner, SYSTEM and the Administrators group, or returns an empty string when it cannot.
+func privateFileProblem(filename string) (string, error) {
+	name, err := windows.UTF16PtrFromString(filename)
+	if err != nil {
+		return "", err
+	}
+	var owner *windows.SID
+	var dacl uintptr
+	var descriptor windows.Handle
+	if ret, _, _ := procGetNamedSecurityInfoW.Call(
+		uintptr(unsafe.Pointer(name)), seFileObject, ownerSecurityInformation|daclSecurityInformation,
+		uintptr(unsafe.Pointer(&owner)), 0, uintptr(unsafe.Pointer(&dacl)), 0, uintptr(unsafe.Pointer(&descriptor)),
+	); ret != 0 {
+		return "", fmt.Errorf("reading the access control list of %s: %v", filename, syscall.Errno(ret))
+	}
+	defer windows.LocalFree(descriptor)
+
+	if dacl == 0 {
+		return "has no access control list and is accessible by everyone", nil
+	}
+	trusted, err := privateFileTrustees()
+	if err != nil {
+		return "", err
+	}
+	trusted = append(trusted, owner)
+
+	info := aclSizeInfo{}
+	if ret, _, err := procGetAclInformation.Call(dacl, uintptr(unsafe.Pointer(&info)), unsafe.Sizeof(info), aclSizeInformation); ret == 0 {
+		return "", fmt.Errorf("reading the access control list of %s: %v", filename, err)
+	}
+	others := []string{}
+	for i := uint32(0); i < info.AceCount; i++ {
+		var ace *accessAllowedAce
+		if ret, _, err := procGetAce.Call(dacl, uintptr(i), uintptr(unsafe.Pointer(&ace))); ret == 0 {
+			return "", fmt.Errorf("reading the access control list of %s: %v", filename, err)
+		}
+		if ace.Header.AceType != accessAllowedAceType || ace.Header.AceFlags&inheritOnlyAce != 0 || ace.Mask == 0 {
+			continue
+		}
+		sid := (*windows.SID)(unsafe.Pointer(&ace.SidStart))
+		if containsSID(trusted, sid) {
+			continue
+		}
+		account, domain, _, err := sid.LookupAccount("")
+		if err != nil {
+			s, _ := sid.String()
+			others = append(others, s)
+			continue
+		}
+		if len(domain) > 0 {
+			account = domain + `\` + account
+		}
+		others = append(others, account)
+	}
+	if len(others) > 0 {
+		return "is accessible by " + strings.Join(others, ", "), nil
+	}
+	return "", nil
+}
+
+// makeFilePrivate replaces the access control list of a file by one granting full control to the
+// current user, SYSTEM and the Administrators group only, and stops inheriting entries from the
+// parent directory.
+func makeFilePrivate(filename string) error {
+	trusted, err := privateFileTrustees()
+	if err != nil {
+		return err
+	}
+	entries := make([]explicitAccess, 0, len(trusted))
+	for _, sid := range trusted {
+		entries = append(entries, explicitAccess{
+			AccessPermissions: genericAll,
+			AccessMode:        setAccess,
+			Inheritance:       noInheritance,
+			Trustee:           trustee{TrusteeForm: trusteeIsSid, Name: uintptr(unsafe.Pointer(sid))},
+		})
+	}
+
+	var acl uintptr
+	if ret, _, _ := procSetEntriesInAclW.Call(uintptr(len(entries)), uintptr(unsafe.Pointer(&entries[0])), 0, uintptr(unsafe.Pointer(&acl))); ret != 0 {
+		return fmt.Errorf("building the access control list of %s: %v", filename, syscall.Errno(ret))
+	}
+	defer windows.LocalFree(windows.Handle(acl))
+
+	name, err := windows.UTF16PtrFromString(filename)
+	if err != nil {
+		return err
+	}
+	if ret, _, _ := procSetNamedSecurityInfoW.Call(
+		uintptr(unsafe.Pointer(name)), seFileObject, daclSecurityInformation|protectedDaclSecurityInformation,
+		0, 0, acl, 0,
+	); ret != 0 {
+		return fmt.Errorf("writing the access control list of %s: %v", filename, syscall.Errno(ret))
+	}
+	return nil
+}
+
+// makeFilePrivateHint is the command a user would run to do what makeFilePrivate does.
+func makeFilePrivateHint(filename string) string {
+	return fmt.Sprintf(`run: icacls "%s" /inheritance:r /grant:r "%%USERNAME%%:F" SYSTEM:F Administrators:F`, filename)
+}
+
+// privateFileTrustees returns the current user, SYSTEM and the Administrators group.
+func privateFileTrustees() ([]*windows.SID, error) {
+	token, err := windows.OpenCurrentProcessToken()
+	if err != nil {
+		return nil, err
+	}
+	defer token.Close()
+	user, err := token.GetTokenUser()
+	if err != nil {
+		return nil, err
+	}
+	current, err := user.User.Sid.Copy()
+	if err != nil {
+		return nil, err
+	}
+
+	sids := []*windows.SID{current}
+	for _, s := range []string{systemSID, administratorsSID} {
+		sid, err := windows.StringToSid(s)
+		if err != nil {
+			return nil, err
+		}
+		sids = append(sids, sid)
+	}
+	return sids, nil
+}
+
+func containsSID(sids []*windows.SID, sid *windows.SID) bool {
+	for _, candidate := range sids {
+		if candidate == nil {
+			continue
+		}
+		if equal, _, _ := procEqualSid.Call(uintptr(unsafe.Pointer(candidate)), uintptr(unsafe.Pointer(sid))); equal != 0 {
+			return true
+		}
+	}
+	return false
+}
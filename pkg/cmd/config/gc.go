@@ -0,0 +1,171 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// GCOptions holds the command-line options for 'config gc' sub command
+type GCOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	DryRun       bool
+	Now          func() time.Time
+	// Confirm asks before the contexts the confirm setting covers are removed.
+	Confirm changeConfirmer
+
+	genericclioptions.IOStreams
+}
+
+var (
+	gcLong = templates.LongDesc(`
+		Remove the contexts whose sunset date has passed, and the expired temporary contexts.
+
+		The contexts deprecated with "kubectl config deprecate --after DATE" are removed once DATE
+		has passed, and the temporary contexts made by "kubectl config as" once they expire, along
+		with the clusters and users no remaining context uses. Deprecated contexts without a sunset
+		date are kept.`)
+
+	gcExample = templates.Examples(`
+		# Show what would be removed
+		kubectl config gc --dry-run
+
+		# Remove the contexts past their sunset date
+		kubectl config gc`)
+)
+
+// NewCmdConfigGC returns a Command instance for 'config gc' sub command
+func NewCmdConfigGC(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &GCOptions{ConfigAccess: configAccess, Now: time.Now, IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "gc [--dry-run]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Remove the contexts past their sunset date and expired temporary contexts"),
+		Long:                  gcLong,
+		Example:               gcExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.Confirm = newChangeConfirmer(cmd, configAccess, streams.In, streams.ErrOut)
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	cmd.Flags().BoolVar(&o.DryRun, "dry-run", o.DryRun, "If true, only print what would be removed")
+	return cmd
+}
+
+// Run removes the contexts past their sunset date and the entries only they used
+func (o *GCOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	verb := "Removed"
+	if o.DryRun {
+		verb = "Would remove"
+	}
+
+	now := o.Now()
+	clusters, authInfos := map[string]bool{}, map[string]bool{}
+	removed := []string{}
+	// The removals are reported once confirmed.
+	report := &bytes.Buffer{}
+	for _, name := range sortedContextNames(config.Contexts) {
+		context := config.Contexts[name]
+		deprecation, deprecated, err := readContextDeprecation(context)
+		if err != nil {
+			return fmt.Errorf("context %q: %v", name, err)
+		}
+		ephemeral, temporary, err := readEphemeralContext(context)
+		if err != nil {
+			return fmt.Errorf("context %q: %v", name, err)
+		}
+		var reason string
+		if sunset, ok := deprecation.sunset(); deprecated && ok && !now.Before(sunset) {
+			reason = fmt.Sprintf("past its sunset date %s", deprecation.After)
+		} else if temporary && ephemeral.expired(now) {
+			reason = fmt.Sprintf("a temporary context expired at %s", ephemeral.Expires)
+		} else {
+			continue
+		}
+		if err := checkDeletable(o.ConfigAccess, "context", name); err != nil {
+			fmt.Fprintf(o.ErrOut, "warning: %v\n", err)
+			continue
+		}
+		delete(config.Contexts, name)
+		clusters[context.Cluster] = true
+		authInfos[context.AuthInfo] = true
+		removed = append(removed, name)
+		fmt.Fprintf(report, "%s context %q, %s.\n", verb, name, reason)
+		if config.CurrentContext == name {
+			config.CurrentContext = ""
+			fmt.Fprintf(o.ErrOut, "warning: context %q is the current-context, use \"kubectl config use-context\" to select a different one\n", name)
+		}
+	}
+	if len(removed) == 0 {
+		fmt.Fprintln(o.Out, "No context is past its sunset date or expired.")
+		return nil
+	}
+
+	for _, context := range config.Contexts {
+		delete(clusters, context.Cluster)
+		delete(authInfos, context.AuthInfo)
+	}
+	for _, name := range sortedClusterNames(config.Clusters) {
+		if clusters[name] {
+			if err := checkDeletable(o.ConfigAccess, "cluster", name); err != nil {
+				fmt.Fprintf(o.ErrOut, "warning: %v\n", err)
+				continue
+			}
+			delete(config.Clusters, name)
+			fmt.Fprintf(report, "%s cluster %q, no longer used.\n", verb, name)
+		}
+	}
+	for _, name := range sortedAuthInfoNames(config.AuthInfos) {
+		if authInfos[name] {
+			if err := checkDeletable(o.ConfigAccess, "user", name); err != nil {
+				fmt.Fprintf(o.ErrOut, "warning: %v\n", err)
+				continue
+			}
+			delete(config.AuthInfos, name)
+			fmt.Fprintf(report, "%s user %q, no longer used.\n", verb, name)
+		}
+	}
+	if !o.DryRun {
+		if err := o.Confirm.check(removed); err != nil {
+			return err
+		}
+		if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+			return err
+		}
+	}
+	_, err = io.Copy(o.Out, report)
+	return err
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestGC(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	config := newRedFederalCowHammerConfig()
+	config.Clusters["old-cluster"] = &clientcmdapi.Cluster{Server: "https://old.example.com"}
+	config.AuthInfos["old-user"] = &clientcmdapi.AuthInfo{Token: "old-token"}
+	contexts := map[string]contextDeprecation{
+		"old-context":    {After: "2019-08-01"},
+		"shared-context": {After: "2019-08-01"},
+		"later-context":  {After: "2019-09-01"},
+		"kept-context":   {Message: "no sunset date"},
+	}
+	for name, deprecation := range contexts {
+		context := &clientcmdapi.Context{Cluster: "cow-cluster", AuthInfo: "red-user"}
+		if name == "old-context" {
+			context = &clientcmdapi.Context{Cluster: "old-cluster", AuthInfo: "old-user"}
+		}
+		if err := writeExtension(&context.Extensions, deprecationExtension, deprecation); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		config.Contexts[name] = context
+	}
+	config.CurrentContext = "old-context"
+	if err := clientcmd.WriteToFile(config, fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	now := func() time.Time { return time.Date(2019, 8, 2, 0, 0, 0, 0, time.UTC) }
+	expected := `Removed context "old-context", past its sunset date 2019-08-01.
+Removed context "shared-context", past its sunset date 2019-08-01.
+Removed cluster "old-cluster", no longer used.
+Removed user "old-user", no longer used.
+`
+	streams, _, out, errOut := genericclioptions.NewTestIOStreams()
+	o := &GCOptions{ConfigAccess: pathOptions, DryRun: true, Now: now, IOStreams: streams}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dryRun := strings.Replace(expected, "Removed", "Would remove", -1); out.String() != dryRun {
+		t.Errorf("expected\n%s\ngot\n%s", dryRun, out.String())
+	}
+	if unchanged, err := clientcmd.LoadFromFile(fakeKubeFile.Name()); err != nil || len(unchanged.Contexts) != 5 {
+		t.Errorf("expected a dry run to change nothing, got %v, %v", unchanged, err)
+	}
+
+	streams, _, out, errOut = genericclioptions.NewTestIOStreams()
+	o = &GCOptions{ConfigAccess: pathOptions, Now: now, IOStreams: streams}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+	if errOut.String() == "" {
+		t.Errorf("expected a warning about removing the current-context")
+	}
+	remaining, err := clientcmd.LoadFromFile(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, name := range []string{"federal-context", "later-context", "kept-context"} {
+		if _, ok := remaining.Contexts[name]; !ok {
+			t.Errorf("expected context %q to be kept", name)
+		}
+	}
+	if _, ok := remaining.Clusters["cow-cluster"]; !ok || len(remaining.CurrentContext) > 0 {
+		t.Errorf("expected the clusters still used to be kept and the current-context unset, got %v", remaining)
+	}
+
+	streams, _, out, _ = genericclioptions.NewTestIOStreams()
+	o = &GCOptions{ConfigAccess: pathOptions, Now: now, IOStreams: streams}
+	if err := o.Run(); err != nil || out.String() != "No context is past its sunset date or expired.\n" {
+		t.Errorf("unexpected output %q, %v", out.String(), err)
+	}
+}
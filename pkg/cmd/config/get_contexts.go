@@ -21,10 +21,12 @@ import (
 	"io"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/liggitt/tabwriter"
 	"github.com/spf13/cobra"
 
+	"k8s.io/apimachinery/pkg/util/duration"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
@@ -42,19 +44,45 @@ type GetContextsOptions struct {
 	nameOnly     bool
 	showHeaders  bool
 	contextNames []string
+	// from is the kind of source the contexts listed were imported from, see "config provenance".
+	from string
+	// health is set with -o wide, to show the health of the contexts.
+	health *healthCache
 
 	genericclioptions.IOStreams
 }
 
 var (
-	getContextsLong = templates.LongDesc(`Displays one or many contexts from the kubeconfig file.`)
+	getContextsLong = templates.LongDesc(`
+		Displays one or many contexts from the kubeconfig file.
+
+		With -o wide, the health of every context is shown as well, as checked by "kubectl config
+		health". The results of checks are cached for the healthCacheTTL setting, 5m by default, so
+		that listing again is instant; --refresh checks every context again. A context is checked
+		again as soon as it, its cluster or its user change in kubeconfig. With --no-network, the
+		cached results are shown, and listing fails when a context has no fresh one.
+
+		With --from, only the contexts imported from a kind of source are listed, such as eks or
+		kind, see "kubectl config provenance".
+
+		Contexts deprecated with "kubectl config deprecate" are marked (deprecated), and their
+		deprecation is warned about on the standard error.`)
 
 	getContextsExample = templates.Examples(`
 		# List all the contexts in your kubeconfig file
 		kubectl config get-contexts
 
 		# Describe one context in your kubeconfig file.
-		kubectl config get-contexts my-context`)
+		kubectl config get-contexts my-context
+
+		# Describe the current context.
+		kubectl config get-contexts .
+
+		# List all the contexts with their health, checking them again
+		kubectl config get-contexts -o wide --refresh
+
+		# List the contexts imported from Amazon EKS
+		kubectl config get-contexts --from eks`)
 )
 
 // NewCmdConfigGetContexts creates a command object for the "get-contexts" action, which
@@ -67,17 +95,26 @@ func NewCmdConfigGetContexts(streams genericclioptions.IOStreams, configAccess c
 	}
 
 	cmd := &cobra.Command{
-		Use:                   "get-contexts [(-o|--output=)name)]",
+		Use:                   "get-contexts [(-o|--output=)name|wide)] [--refresh] [--from=SOURCE]",
 		DisableFlagsInUseLine: true,
 		Short:                 i18n.T("Describe one or many contexts"),
 		Long:                  getContextsLong,
 		Example:               getContextsExample,
 		Run: func(cmd *cobra.Command, args []string) {
 			validOutputTypes := sets.NewString("", "json", "yaml", "wide", "name", "custom-columns", "custom-columns-file", "go-template", "go-template-file", "jsonpath", "jsonpath-file")
-			supportedOutputTypes := sets.NewString("", "name")
+			supportedOutputTypes := sets.NewString("", "name", "wide")
+			if !cmd.Flags().Changed("output") {
+				settings, err := loadSettings(settingsFile())
+				cmdutil.CheckErr(err)
+				// Listing never needs the network: without it, the wide output of the settings
+				// falls back to the default one, only an explicit -o wide asks for it.
+				if supportedOutputTypes.Has(settings.Output) && !(settings.Output == "wide" && networkDisabled(cmd)) {
+					cmd.Flags().Set("output", settings.Output)
+				}
+			}
 			outputFormat := cmdutil.GetFlagString(cmd, "output")
 			if !validOutputTypes.Has(outputFormat) {
-				cmdutil.CheckErr(fmt.Errorf("output must be one of '' or 'name': %v", outputFormat))
+				cmdutil.CheckErr(fmt.Errorf("output must be one of '', 'name' or 'wide': %v", outputFormat))
 			}
 			if !supportedOutputTypes.Has(outputFormat) {
 				fmt.Fprintf(options.Out, "--output %v is not available in kubectl config get-contexts; resetting to default output format\n", outputFormat)
@@ -89,13 +126,16 @@ func NewCmdConfigGetContexts(streams genericclioptions.IOStreams, configAccess c
 	}
 
 	cmd.Flags().Bool("no-headers", false, "When using the default or custom-column output format, don't print headers (default print headers).")
-	cmd.Flags().StringP("output", "o", "", "Output format. One of: name")
+	cmd.Flags().StringP("output", "o", "", "Output format. One of: name|wide")
+	cmd.Flags().Bool("refresh", false, "With -o wide, check every context again instead of using cached results")
+	cmd.Flags().String("from", "", "Only list the contexts imported from this kind of source, such as file, eks, gke, aks or kind")
 	return cmd
 }
 
 // Complete assigns GetContextsOptions from the args.
 func (o *GetContextsOptions) Complete(cmd *cobra.Command, args []string) error {
 	o.contextNames = args
+	o.from = cmdutil.GetFlagString(cmd, "from")
 	o.nameOnly = false
 	if cmdutil.GetFlagString(cmd, "output") == "name" {
 		o.nameOnly = true
@@ -104,17 +144,71 @@ func (o *GetContextsOptions) Complete(cmd *cobra.Command, args []string) error {
 	if cmdutil.GetFlagBool(cmd, "no-headers") || o.nameOnly {
 		o.showHeaders = false
 	}
+	if cmdutil.GetFlagString(cmd, "output") == "wide" && o.health == nil {
+		settings, err := loadSettings(settingsFile())
+		if err != nil {
+			return err
+		}
+		ttl := defaultHealthCacheTTL
+		if len(settings.HealthCacheTTL) > 0 {
+			if ttl, err = time.ParseDuration(settings.HealthCacheTTL); err != nil {
+				return err
+			}
+		}
+		o.health = &healthCache{
+			Filename: healthCacheFile(),
+			TTL:      ttl,
+			Refresh:  cmdutil.GetFlagBool(cmd, "refresh"),
+			ReadOnly: writesDisabled(cmd),
+			RequireNetwork: func() error {
+				return requireNetwork(cmd)
+			},
+			Concurrency: settings.HealthConcurrency,
+			Check:       checkContextHealth,
+			Now:         time.Now,
+		}
+	}
 
 	return nil
 }
 
 // RunGetContexts implements all the necessary functionality for context retrieval.
 func (o GetContextsOptions) RunGetContexts() error {
+	// A single kubeconfig file is read through an index that only decodes the contexts being
+	// printed, which keeps listing fast for kubeconfigs with thousands of entries.
+	// Checking the health of contexts needs their clusters and users, which the index does not
+	// decode.
+	if filename, ok := singleKubeconfigFile(o.configAccess); ok && o.health == nil {
+		index, err := loadKubeconfigIndex(filename)
+		if err != nil {
+			return err
+		}
+		return o.printContexts(index.currentContext, index.contextNames(), index.context, nil)
+	}
+
 	config, err := o.configAccess.GetStartingConfig()
 	if err != nil {
 		return err
 	}
+	names := make([]string, 0, len(config.Contexts))
+	for name := range config.Contexts {
+		names = append(names, name)
+	}
+	var checkHealth func(names []string) ([]contextHealth, error)
+	if o.health != nil {
+		checkHealth = func(names []string) ([]contextHealth, error) {
+			return o.health.checkAll(config, names)
+		}
+	}
+	return o.printContexts(config.CurrentContext, names, func(name string) (*clientcmdapi.Context, bool, error) {
+		context, ok := config.Contexts[name]
+		return context, ok, nil
+	}, checkHealth)
+}
 
+// printContexts prints the requested contexts, or all of allNames when none were requested, along
+// with their health when checkHealth is set.
+func (o GetContextsOptions) printContexts(currentContext string, allNames []string, getContext func(name string) (*clientcmdapi.Context, bool, error), checkHealth func(names []string) ([]contextHealth, error)) error {
 	out, found := o.Out.(*tabwriter.Writer)
 	if !found {
 		out = printers.GetNewTabWriter(o.Out)
@@ -126,47 +220,96 @@ func (o GetContextsOptions) RunGetContexts() error {
 	allErrs := []error{}
 	toPrint := []string{}
 	if len(o.contextNames) == 0 {
-		for name := range config.Contexts {
-			toPrint = append(toPrint, name)
-		}
+		toPrint = append(toPrint, allNames...)
 	} else {
+		current := &clientcmdapi.Config{CurrentContext: currentContext}
 		for _, name := range o.contextNames {
-			_, ok := config.Contexts[name]
-			if ok {
+			name, err := resolveContextName(current, name)
+			if err != nil {
+				allErrs = append(allErrs, err)
+				continue
+			}
+			_, ok, err := getContext(name)
+			if err != nil {
+				allErrs = append(allErrs, err)
+			} else if ok {
 				toPrint = append(toPrint, name)
 			} else {
 				allErrs = append(allErrs, fmt.Errorf("context %v not found", name))
 			}
 		}
 	}
+	if len(o.from) > 0 {
+		imported := []string{}
+		for _, name := range toPrint {
+			context, _, err := getContext(name)
+			if err != nil {
+				allErrs = append(allErrs, err)
+				continue
+			}
+			if ok, err := importedFrom(context.Extensions, o.from); err != nil {
+				allErrs = append(allErrs, fmt.Errorf("context %q: %v", name, err))
+			} else if ok {
+				imported = append(imported, name)
+			}
+		}
+		toPrint = imported
+	}
 	if o.showHeaders {
-		err = printContextHeaders(out, o.nameOnly)
+		err := printContextHeaders(out, o.nameOnly, checkHealth != nil)
 		if err != nil {
 			allErrs = append(allErrs, err)
 		}
 	}
 
 	sort.Strings(toPrint)
-	for _, name := range toPrint {
-		err = printContext(name, config.Contexts[name], out, o.nameOnly, config.CurrentContext == name)
+	var health []contextHealth
+	if checkHealth != nil {
+		var err error
+		if health, err = checkHealth(toPrint); err != nil {
+			return err
+		}
+	}
+	for i, name := range toPrint {
+		var context *clientcmdapi.Context
+		if !o.nameOnly {
+			var err error
+			if context, _, err = getContext(name); err != nil {
+				allErrs = append(allErrs, err)
+				continue
+			}
+		}
+		var h *contextHealth
+		if health != nil {
+			h = &health[i]
+		}
+		err := printContext(name, context, out, o.nameOnly, currentContext == name, h)
 		if err != nil {
 			allErrs = append(allErrs, err)
 		}
+		if context != nil {
+			if warning := warnDeprecatedContext(name, context); len(warning) > 0 {
+				fmt.Fprintf(o.ErrOut, "warning: %s\n", warning)
+			}
+		}
 	}
 
 	return utilerrors.NewAggregate(allErrs)
 }
 
-func printContextHeaders(out io.Writer, nameOnly bool) error {
+func printContextHeaders(out io.Writer, nameOnly, wide bool) error {
 	columnNames := []string{"CURRENT", "NAME", "CLUSTER", "AUTHINFO", "NAMESPACE"}
 	if nameOnly {
 		columnNames = columnNames[:1]
+	} else if wide {
+		columnNames = append(columnNames, "STATUS", "CHECKED")
 	}
 	_, err := fmt.Fprintf(out, "%s\n", strings.Join(columnNames, "\t"))
 	return err
 }
 
-func printContext(name string, context *clientcmdapi.Context, w io.Writer, nameOnly, current bool) error {
+// printContext prints a row of the context, with the health columns when health is set.
+func printContext(name string, context *clientcmdapi.Context, w io.Writer, nameOnly, current bool, health *contextHealth) error {
 	if nameOnly {
 		_, err := fmt.Fprintf(w, "%s\n", name)
 		return err
@@ -175,6 +318,16 @@ func printContext(name string, context *clientcmdapi.Context, w io.Writer, nameO
 	if current {
 		prefix = "*"
 	}
-	_, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", prefix, name, context.Cluster, context.AuthInfo, context.Namespace)
+	// Deprecated contexts are marked after the last column, which keeps the columns of the other
+	// rows unchanged.
+	marker := ""
+	if _, deprecated, err := readContextDeprecation(context); err == nil && deprecated {
+		marker = "\t(deprecated)"
+	}
+	if health != nil {
+		_, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s ago%s\n", prefix, name, context.Cluster, context.AuthInfo, context.Namespace, health.Status(), duration.HumanDuration(time.Since(health.Checked)), marker)
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s%s\n", prefix, name, context.Cluster, context.AuthInfo, context.Namespace, marker)
 	return err
 }
@@ -19,7 +19,10 @@ package config
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/client-go/tools/clientcmd"
@@ -143,6 +146,138 @@ func TestGetContextsSelectOneOfTwo(t *testing.T) {
 	test.run(t)
 }
 
+func TestGetContextsSettingsWideWithoutNetwork(t *testing.T) {
+	dir, err := ioutil.TempDir("", "get-contexts")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.Setenv("XDG_CONFIG_HOME", os.Getenv("XDG_CONFIG_HOME"))
+	defer os.Setenv(NoNetworkEnvVar, os.Getenv(NoNetworkEnvVar))
+	os.Setenv("XDG_CONFIG_HOME", dir)
+	os.Setenv(NoNetworkEnvVar, "true")
+	if err := saveSettings(settingsFile(), &Settings{Output: "wide"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tconf := clientcmdapi.Config{
+		CurrentContext: "shaker-context",
+		Contexts: map[string]*clientcmdapi.Context{
+			"shaker-context": {AuthInfo: "blue-user", Cluster: "big-cluster", Namespace: "saw-ns"}}}
+	test := getContextsTest{
+		startingConfig: tconf,
+		expectedOut: `CURRENT   NAME             CLUSTER       AUTHINFO    NAMESPACE
+*         shaker-context   big-cluster   blue-user   saw-ns
+`,
+	}
+	test.run(t)
+}
+
+func TestGetContextsDeprecated(t *testing.T) {
+	deprecated := &clientcmdapi.Context{AuthInfo: "blue-user", Cluster: "big-cluster", Namespace: "saw-ns"}
+	if err := writeExtension(&deprecated.Extensions, deprecationExtension, contextDeprecation{After: "2025-01-01", Message: "migrate to shaker-v2"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	config := clientcmdapi.Config{
+		CurrentContext: "shaker-context",
+		Contexts: map[string]*clientcmdapi.Context{
+			"shaker-context": deprecated,
+			"shaker-v2":      {AuthInfo: "blue-user", Cluster: "big-cluster", Namespace: "saw-ns"}}}
+	dir, err := ioutil.TempDir("", "get-contexts")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	kubeconfig := filepath.Join(dir, "config")
+	if err := clientcmd.WriteToFile(config, kubeconfig); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = kubeconfig
+	pathOptions.EnvVar = ""
+
+	for _, wide := range []bool{false, true} {
+		streams, _, out, errOut := genericclioptions.NewTestIOStreams()
+		o := GetContextsOptions{configAccess: pathOptions, showHeaders: true, IOStreams: streams}
+		if wide {
+			o.health = &healthCache{
+				Filename: filepath.Join(dir, "health-cache.json"),
+				TTL:      time.Minute,
+				ReadOnly: true,
+				Check: func(config *clientcmdapi.Config, name string) contextHealth {
+					return contextHealth{Context: name, Reachable: true, Authenticated: true, Checked: time.Now()}
+				},
+				Now: time.Now,
+			}
+		}
+		if err := o.RunGetContexts(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("unexpected output:\n%s", out.String())
+		}
+		if fields := strings.Fields(lines[1]); fields[1] != "shaker-context" || fields[len(fields)-1] != "(deprecated)" {
+			t.Errorf("wide=%v: expected shaker-context to be marked deprecated, got %q", wide, lines[1])
+		}
+		if strings.Contains(lines[2], "deprecated") {
+			t.Errorf("wide=%v: expected shaker-v2 not to be marked deprecated, got %q", wide, lines[2])
+		}
+		if !strings.Contains(errOut.String(), "migrate to shaker-v2") {
+			t.Errorf("wide=%v: expected a deprecation warning, got %q", wide, errOut.String())
+		}
+	}
+}
+
+func TestGetContextsThroughIndex(t *testing.T) {
+	// The user of the other context cannot be decoded by clientcmd, which loading the whole
+	// kubeconfig would fail on: listing only decodes the contexts it prints.
+	data := []byte(`apiVersion: v1
+kind: Config
+current-context: shaker-context
+contexts:
+- name: shaker-context
+  context:
+    cluster: big-cluster
+    user: blue-user
+    namespace: saw-ns
+- name: broken-context
+  context:
+    cluster: big-cluster
+    user: broken-user
+users:
+- name: broken-user
+  user:
+    token: [not, a, string]
+`)
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	if err := ioutil.WriteFile(fakeKubeFile.Name(), data, 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+	if _, err := pathOptions.GetStartingConfig(); err == nil {
+		t.Fatalf("expected clientcmd to fail to load the kubeconfig")
+	}
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := GetContextsOptions{configAccess: pathOptions, contextNames: []string{"shaker-context"}, showHeaders: true, IOStreams: streams}
+	if err := o.RunGetContexts(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `CURRENT   NAME             CLUSTER       AUTHINFO    NAMESPACE
+*         shaker-context   big-cluster   blue-user   saw-ns
+`
+	if out.String() != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, out.String())
+	}
+}
+
 func (test getContextsTest) run(t *testing.T) {
 	fakeKubeFile, err := ioutil.TempFile("", "")
 	if err != nil {
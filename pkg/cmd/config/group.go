@@ -0,0 +1,291 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/printers"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// Strategies "config use-context --group" picks a member of a group with.
+const (
+	groupStrategyFirstHealthy = "first-healthy"
+	groupStrategyRoundRobin   = "round-robin"
+)
+
+var groupStrategies = []string{groupStrategyFirstHealthy, groupStrategyRoundRobin}
+
+// contextGroup is a group of contexts reaching the same cluster, or replicas of it. Last is the
+// member the round-robin strategy picked last.
+type contextGroup struct {
+	Contexts []string `json:"contexts"`
+	Last     string   `json:"last,omitempty"`
+}
+
+// contextGroups is stored in the preferences' contextGroupsExtension, by group name.
+type contextGroups map[string]*contextGroup
+
+// GroupOptions holds the command-line options for 'config group' sub command
+type GroupOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Name         string
+	Contexts     []string
+	Overwrite    bool
+
+	genericclioptions.IOStreams
+}
+
+var (
+	groupLong = templates.LongDesc(`
+		Create, list or delete groups of contexts.
+
+		A group names contexts that are interchangeable, such as the contexts of the API endpoints
+		of a cluster or of its regional replicas. "kubectl config use-context --group" switches to
+		one of them: with --strategy first-healthy, the first member, in the order of the group,
+		whose server accepts the credentials of the context, and with --strategy round-robin, the
+		member following the one it switched to last.
+
+		Groups are kept in a kubeconfig extension.`)
+
+	groupExample = templates.Examples(`
+		# Group the contexts of the US and EU endpoints of the API cluster
+		kubectl config group create api-prod ctx-us ctx-eu
+
+		# Switch to the first member of the group that works
+		kubectl config use-context --group api-prod --strategy first-healthy
+
+		# List the groups
+		kubectl config group list`)
+)
+
+// NewCmdConfigGroup returns a Command instance for 'config group' sub command
+func NewCmdConfigGroup(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &GroupOptions{ConfigAccess: configAccess, IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "group SUBCOMMAND",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Manage groups of interchangeable contexts"),
+		Long:                  groupLong,
+		Example:               groupExample,
+		Run:                   cmdutil.DefaultSubCommandRun(streams.ErrOut),
+	}
+
+	createCmd := &cobra.Command{
+		Use:   "create NAME CONTEXT_NAME... [--overwrite]",
+		Short: i18n.T("Create a group of contexts"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) < 2 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.Name, o.Contexts = args[0], args[1:]
+			cmdutil.CheckErr(o.RunCreate())
+		},
+	}
+	createCmd.Flags().BoolVar(&o.Overwrite, "overwrite", o.Overwrite, "Replace the members of the group if it exists")
+	cmd.AddCommand(createCmd)
+
+	cmd.AddCommand(noWriteCommand(&cobra.Command{
+		Use:   "list",
+		Short: i18n.T("List the groups of contexts"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			cmdutil.CheckErr(o.RunList())
+		},
+	}))
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "delete NAME",
+		Short: i18n.T("Delete a group of contexts, keeping the contexts"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.Name = args[0]
+			cmdutil.CheckErr(o.RunDelete())
+		},
+	})
+	return cmd
+}
+
+// RunCreate creates the group, making sure its members exist
+func (o *GroupOptions) RunCreate() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	groups, err := readContextGroups(config)
+	if err != nil {
+		return err
+	}
+	if _, exists := groups[o.Name]; exists && !o.Overwrite {
+		return fmt.Errorf("group %q already exists, use --overwrite to replace its members", o.Name)
+	}
+
+	members := []string{}
+	for _, name := range o.Contexts {
+		if name, err = resolveContextName(config, name); err != nil {
+			return err
+		}
+		if _, ok := config.Contexts[name]; !ok {
+			return fmt.Errorf("no context exists with the name: %q", name)
+		}
+		if !containsString(members, name) {
+			members = append(members, name)
+		}
+	}
+	groups[o.Name] = &contextGroup{Contexts: members}
+	if err := writeContextGroups(config, groups); err != nil {
+		return err
+	}
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "Group %q created with %d context(s).\n", o.Name, len(members))
+	return nil
+}
+
+// RunList prints the groups with their members
+func (o *GroupOptions) RunList() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	groups, err := readContextGroups(config)
+	if err != nil {
+		return err
+	}
+	if len(groups) == 0 {
+		fmt.Fprintln(o.Out, "No groups found.")
+		return nil
+	}
+
+	names := []string{}
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	w := printers.GetNewTabWriter(o.Out)
+	fmt.Fprintf(w, "NAME\tCONTEXTS\n")
+	for _, name := range names {
+		fmt.Fprintf(w, "%s\t%s\n", name, strings.Join(groups[name].Contexts, ","))
+	}
+	return w.Flush()
+}
+
+// RunDelete deletes the group
+func (o *GroupOptions) RunDelete() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	groups, err := readContextGroups(config)
+	if err != nil {
+		return err
+	}
+	if _, ok := groups[o.Name]; !ok {
+		return fmt.Errorf("no group exists with the name: %q", o.Name)
+	}
+	delete(groups, o.Name)
+	if err := writeContextGroups(config, groups); err != nil {
+		return err
+	}
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "Group %q deleted.\n", o.Name)
+	return nil
+}
+
+// readContextGroups returns the context groups of a kubeconfig.
+func readContextGroups(config *clientcmdapi.Config) (contextGroups, error) {
+	groups := contextGroups{}
+	if _, err := readExtension(config.Preferences.Extensions, contextGroupsExtension, &groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// writeContextGroups stores the context groups of a kubeconfig, removing the extension when there
+// are none.
+func writeContextGroups(config *clientcmdapi.Config, groups contextGroups) error {
+	if len(groups) == 0 {
+		delete(config.Preferences.Extensions, contextGroupsExtension)
+		return nil
+	}
+	return writeExtension(&config.Preferences.Extensions, contextGroupsExtension, groups)
+}
+
+// pickGroupMember returns the member of a group strategy picks, and updates the group for the
+// round-robin strategy. check is used by first-healthy to find a working member.
+func pickGroupMember(config *clientcmdapi.Config, name, strategy string, check func(*clientcmdapi.Config, string) contextHealth) (string, error) {
+	groups, err := readContextGroups(config)
+	if err != nil {
+		return "", err
+	}
+	group, ok := groups[name]
+	if !ok {
+		return "", fmt.Errorf("no group exists with the name: %q", name)
+	}
+	members := []string{}
+	for _, member := range group.Contexts {
+		if _, ok := config.Contexts[member]; ok {
+			members = append(members, member)
+		}
+	}
+	if len(members) == 0 {
+		return "", fmt.Errorf("none of the contexts of group %q exists", name)
+	}
+
+	switch strategy {
+	case groupStrategyFirstHealthy:
+		failures := []string{}
+		for _, member := range members {
+			health := check(config, member)
+			if health.Healthy() {
+				return member, nil
+			}
+			failures = append(failures, fmt.Sprintf("%s: %s", member, health.Status()))
+		}
+		return "", fmt.Errorf("no healthy context in group %q (%s)", name, strings.Join(failures, ", "))
+	case groupStrategyRoundRobin:
+		next := members[0]
+		for i, member := range members {
+			if member == group.Last {
+				next = members[(i+1)%len(members)]
+				break
+			}
+		}
+		group.Last = next
+		return next, writeContextGroups(config, groups)
+	default:
+		return "", fmt.Errorf("unknown strategy %q, must be one of: %s", strategy, strings.Join(groupStrategies, ", "))
+	}
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func newGroupTestConfig(t *testing.T) (string, *clientcmd.PathOptions) {
+	dir, err := ioutil.TempDir("", "group")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	config := clientcmdapi.NewConfig()
+	config.Clusters["api"] = &clientcmdapi.Cluster{Server: "https://api.example.com"}
+	for _, name := range []string{"ctx-us", "ctx-eu", "ctx-ap"} {
+		config.Contexts[name] = &clientcmdapi.Context{Cluster: "api", AuthInfo: "admin"}
+	}
+	config.CurrentContext = "ctx-ap"
+	if err := clientcmd.WriteToFile(*config, filepath.Join(dir, "config")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = filepath.Join(dir, "config")
+	pathOptions.EnvVar = ""
+	return dir, pathOptions
+}
+
+func TestGroup(t *testing.T) {
+	dir, pathOptions := newGroupTestConfig(t)
+	defer os.RemoveAll(dir)
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := &GroupOptions{ConfigAccess: pathOptions, Name: "api-prod", Contexts: []string{"ctx-us", ".", "ctx-us"}, IOStreams: streams}
+	if err := o.RunCreate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "Group \"api-prod\" created with 2 context(s).\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+	if err := o.RunCreate(); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected creating an existing group to fail, got %v", err)
+	}
+	o.Contexts = []string{"ctx-us", "missing"}
+	o.Overwrite = true
+	if err := o.RunCreate(); err == nil || !strings.Contains(err.Error(), `no context exists with the name: "missing"`) {
+		t.Errorf("expected a missing member to fail, got %v", err)
+	}
+
+	streams, _, out, _ = genericclioptions.NewTestIOStreams()
+	o = &GroupOptions{ConfigAccess: pathOptions, IOStreams: streams}
+	if err := o.RunList(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "NAME       CONTEXTS\napi-prod   ctx-us,ctx-ap\n"; out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+
+	o.Name = "api-prod"
+	if err := o.RunDelete(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.RunDelete(); err == nil {
+		t.Errorf("expected deleting a missing group to fail")
+	}
+	config, err := clientcmd.LoadFromFile(pathOptions.GlobalFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := config.Preferences.Extensions[contextGroupsExtension]; ok {
+		t.Errorf("expected the extension to be removed with the last group")
+	}
+}
+
+func TestUseContextGroup(t *testing.T) {
+	dir, pathOptions := newGroupTestConfig(t)
+	defer os.RemoveAll(dir)
+
+	streams, _, _, _ := genericclioptions.NewTestIOStreams()
+	if err := (&GroupOptions{ConfigAccess: pathOptions, Name: "api-prod", Contexts: []string{"ctx-us", "ctx-eu", "ctx-ap"}, IOStreams: streams}).RunCreate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	checked := []string{}
+	check := func(config *clientcmdapi.Config, context string) contextHealth {
+		checked = append(checked, context)
+		if context == "ctx-us" {
+			return contextHealth{Context: context}
+		}
+		return contextHealth{Context: context, Reachable: true, Authenticated: true}
+	}
+	use := func(strategy string) string {
+		o := &UseContextOptions{ConfigAccess: pathOptions, Group: "api-prod", Strategy: strategy, Check: check}
+		if strategy == groupStrategyFirstHealthy {
+			// The member picked is not checked a second time.
+			o.Verify = verifyAbort
+		}
+		if err := o.Run(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		config, err := clientcmd.LoadFromFile(pathOptions.GlobalFile)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if config.CurrentContext != o.ContextName {
+			t.Errorf("expected current-context %q, got %q", o.ContextName, config.CurrentContext)
+		}
+		return o.ContextName
+	}
+
+	if picked := use(groupStrategyFirstHealthy); picked != "ctx-eu" {
+		t.Errorf("expected the first healthy member, got %q", picked)
+	}
+	if !reflect.DeepEqual(checked, []string{"ctx-us", "ctx-eu"}) {
+		t.Errorf("expected the members to be checked in order once, checked %v", checked)
+	}
+
+	picked := []string{}
+	for i := 0; i < 4; i++ {
+		picked = append(picked, use(groupStrategyRoundRobin))
+	}
+	if expected := []string{"ctx-us", "ctx-eu", "ctx-ap", "ctx-us"}; !reflect.DeepEqual(picked, expected) {
+		t.Errorf("expected round-robin to pick %v, got %v", expected, picked)
+	}
+
+	unhealthy := func(config *clientcmdapi.Config, context string) contextHealth {
+		return contextHealth{Context: context}
+	}
+	o := &UseContextOptions{ConfigAccess: pathOptions, Group: "api-prod", Strategy: groupStrategyFirstHealthy, Check: unhealthy}
+	if err := o.Run(); err == nil || err.Error() != `no healthy context in group "api-prod" (ctx-us: unreachable, ctx-eu: unreachable, ctx-ap: unreachable)` {
+		t.Errorf("expected no healthy member to fail, got %v", err)
+	}
+	o = &UseContextOptions{ConfigAccess: pathOptions, Group: "missing", Strategy: groupStrategyRoundRobin}
+	if err := o.Run(); err == nil || !strings.Contains(err.Error(), `no group exists with the name: "missing"`) {
+		t.Errorf("expected a missing group to fail, got %v", err)
+	}
+}
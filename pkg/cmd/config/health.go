@@ -0,0 +1,580 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/transport"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/printers"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// contextHealth is the result of checking a context.
+type contextHealth struct {
+	Context string
+	Cluster string
+	Server  string
+
+	Reachable     bool
+	Authenticated bool
+	// The expiry times are zero when there is no certificate, or when the expiry of the bearer
+	// token cannot be read because it is not a JWT.
+	ServerCertificateExpiry time.Time
+	ClientCertificateExpiry time.Time
+	TokenExpiry             time.Time
+	Error                   string
+	// Families is whether the server answers over IPv4 and over IPv6, with --ip-families.
+	Families []familyReachability
+
+	Checked  time.Time
+	Duration time.Duration
+}
+
+// Status summarizes the health of the context in a word.
+func (h contextHealth) Status() string {
+	switch {
+	case !h.Reachable:
+		return "unreachable"
+	case certificateExpired(h.ServerCertificateExpiry, h.Checked):
+		return "certificate expired"
+	case certificateExpired(h.CredentialExpiry(), h.Checked):
+		return "credential expired"
+	case !h.Authenticated:
+		return "unauthenticated"
+	case len(h.Error) > 0:
+		return "error"
+	}
+	return "ok"
+}
+
+// Healthy reports whether the context can be used.
+func (h contextHealth) Healthy() bool {
+	return h.Status() == "ok"
+}
+
+// CredentialExpiry returns when the first of the client certificate and the token expires.
+func (h contextHealth) CredentialExpiry() time.Time {
+	if h.TokenExpiry.IsZero() || (!h.ClientCertificateExpiry.IsZero() && h.ClientCertificateExpiry.Before(h.TokenExpiry)) {
+		return h.ClientCertificateExpiry
+	}
+	return h.TokenExpiry
+}
+
+func certificateExpired(expiry, now time.Time) bool {
+	return !expiry.IsZero() && !expiry.After(now)
+}
+
+// HealthOptions holds the command-line options for 'config health' sub command
+type HealthOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Selector     string
+	From         string
+	Serve        string
+	Interval     time.Duration
+	IPFamilies   bool
+	// Concurrency is how many contexts are checked at once.
+	Concurrency int
+
+	// Check checks a context, it defaults to checkContextHealth.
+	Check func(config *clientcmdapi.Config, context string) contextHealth
+	// ProbeFamilies checks a server over each address family, it defaults to probeAddressFamilies.
+	ProbeFamilies func(server string, timeout time.Duration) []familyReachability
+	log           *cmdLogger
+
+	genericclioptions.IOStreams
+}
+
+var (
+	healthLong = templates.LongDesc(`
+		Check that contexts can reach their cluster and authenticate.
+
+		For every context, the server is dialed, an authenticated request is made with the
+		credentials of the context, and the expiry of the serving certificate, of the client
+		certificate and of bearer tokens that are JWTs is read. Without --serve, the contexts are checked once and the command fails
+		when any of them is unhealthy.
+
+		Dialing and requests that fail to reach the server, or that a proxy or load balancer in
+		front of it answers with 502, 503 or 504, are tried again after half a second and a
+		second, so that a flaky network does not make healthy contexts fail. Requests to the same
+		server are rate limited together, at the qps and burst set with "kubectl config tuning",
+		or 5 and 10. The server is dialed through the proxy HTTPS_PROXY and NO_PROXY choose, as
+		kubectl does.
+
+		Contexts are checked --concurrency at a time, the healthConcurrency setting or 10 by
+		default, so that a kubeconfig with hundreds of contexts does not open as many connections
+		at once.
+
+		With --ip-families, the host of every server is resolved, and its IPv4 and IPv6 addresses
+		are dialed separately, so that a dual-stack endpoint broken over one family is found even
+		though clients fall back to the other. Those dials do not go through a proxy.
+
+		With --serve, the contexts are checked every --interval and the results are served over
+		HTTP: the root path is an HTML page, and /metrics exposes them in the Prometheus text
+		format: kubeconfig_context_reachable and kubeconfig_context_authenticated alert on lost
+		fleet access, kubeconfig_credential_expiry_seconds, the seconds left before the
+		credentials of a context expire, pages before they do. The kubeconfig is reloaded for
+		every round, so contexts added later are picked up.`)
+
+	healthExample = templates.Examples(`
+		# Check every context once
+		kubectl config health
+
+		# Check the production contexts
+		kubectl config health --selector prod
+
+		# Check whether the servers answer over both IPv4 and IPv6
+		kubectl config health --ip-families
+
+		# Check the contexts imported from Amazon EKS
+		kubectl config health --from eks
+
+		# Check every context every 5 minutes and serve the results on port 8080
+		kubectl config health --serve :8080 --interval 5m`)
+)
+
+// NewCmdConfigHealth returns a Command instance for 'config health' sub command
+func NewCmdConfigHealth(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &HealthOptions{
+		ConfigAccess: configAccess,
+		Interval:     time.Minute,
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "health [--selector=SELECTOR] [--from=SOURCE] [--ip-families] [--serve=ADDRESS] [--interval=DURATION]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Check that contexts can reach and authenticate to their cluster"),
+		Long:                  healthLong,
+		Example:               healthExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(cmdutil.UsageErrorf(cmd, "unexpected arguments: %v", args))
+			}
+			cmdutil.CheckErr(o.Complete(cmd))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(requireNetwork(cmd))
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, "Only check the contexts whose tags match this selector, such as prod or region=eu")
+	cmd.Flags().StringVar(&o.From, "from", o.From, "Only check the contexts imported from this kind of source, such as file, eks, gke, aks or kind")
+	cmd.Flags().StringVar(&o.Serve, "serve", o.Serve, "Check the contexts periodically and serve the results on this address, such as :8080")
+	cmd.Flags().DurationVar(&o.Interval, "interval", o.Interval, "Time between two rounds of checks with --serve")
+	cmd.Flags().BoolVar(&o.IPFamilies, "ip-families", o.IPFamilies, "If true, also dial the IPv4 and the IPv6 addresses of every server separately and show the result of each")
+	cmd.Flags().IntVar(&o.Concurrency, "concurrency", o.Concurrency, "How many contexts are checked at once. Defaults to the healthConcurrency setting, or 10")
+	return cmd
+}
+
+// Complete sets up logging and the default way of checking contexts
+func (o *HealthOptions) Complete(cmd *cobra.Command) error {
+	if o.Check == nil {
+		o.Check = checkContextHealth
+	}
+	if o.ProbeFamilies == nil {
+		o.ProbeFamilies = probeAddressFamilies
+	}
+	if o.Concurrency == 0 {
+		settings, err := loadSettings(settingsFile())
+		if err != nil {
+			return err
+		}
+		o.Concurrency = settings.HealthConcurrency
+	}
+	if o.Concurrency == 0 {
+		o.Concurrency = defaultHealthConcurrency
+	}
+	var err error
+	o.log, err = newCmdLogger(cmd, o.ErrOut)
+	return err
+}
+
+// Validate checks the interval between rounds of checks and the number of concurrent checks
+func (o *HealthOptions) Validate() error {
+	if o.Interval <= 0 {
+		return errors.New("--interval must be positive")
+	}
+	if o.Concurrency < 0 {
+		return errors.New("--concurrency must be positive")
+	}
+	return nil
+}
+
+// Run performs the execution of 'config health' sub command
+func (o *HealthOptions) Run() error {
+	if len(o.Serve) > 0 {
+		return o.serve()
+	}
+
+	results, err := o.checkAll()
+	if err != nil {
+		return err
+	}
+	if err := writeHealthTable(o.Out, results, o.IPFamilies); err != nil {
+		return err
+	}
+	unhealthy := 0
+	for _, result := range results {
+		if !result.Healthy() {
+			unhealthy++
+		}
+	}
+	if unhealthy > 0 {
+		return fmt.Errorf("%d of %d context(s) are unhealthy", unhealthy, len(results))
+	}
+	return nil
+}
+
+// checkAll checks the selected contexts, Concurrency at a time.
+func (o *HealthOptions) checkAll() ([]contextHealth, error) {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return nil, err
+	}
+	names, err := selectContextsFrom(config, o.Selector, o.From)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]contextHealth, len(names))
+	checkConcurrently(len(names), o.Concurrency, func(i int) {
+		o.log.Infof(2, "checking context %q", names[i])
+		results[i] = o.Check(config, names[i])
+		if o.IPFamilies && len(results[i].Server) > 0 {
+			results[i].Families = o.ProbeFamilies(results[i].Server, 10*time.Second)
+		}
+	})
+	return results, nil
+}
+
+// defaultHealthConcurrency is how many contexts are checked at once when neither --concurrency
+// nor the healthConcurrency setting is set.
+const defaultHealthConcurrency = 10
+
+// checkConcurrently calls check with every index below n, from at most workers goroutines at a
+// time, and returns once all calls returned. workers defaults to defaultHealthConcurrency.
+func checkConcurrently(n, workers int, check func(i int)) {
+	if workers <= 0 {
+		workers = defaultHealthConcurrency
+	}
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				check(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// serve checks the contexts every interval and serves the latest results until the server fails.
+func (o *HealthOptions) serve() error {
+	monitor := &healthMonitor{}
+	go func() {
+		for {
+			results, err := o.checkAll()
+			if err != nil {
+				o.log.Warningf("checking contexts: %v", err)
+			} else {
+				monitor.update(results, time.Now())
+				o.log.Infof(1, "checked %d context(s)", len(results))
+			}
+			time.Sleep(o.Interval)
+		}
+	}()
+
+	fmt.Fprintf(o.Out, "Serving the health of contexts on %s.\n", o.Serve)
+	return http.ListenAndServe(o.Serve, monitor.handler())
+}
+
+// healthMonitor holds the results of the latest round of checks.
+type healthMonitor struct {
+	lock    sync.RWMutex
+	results []contextHealth
+	updated time.Time
+}
+
+func (m *healthMonitor) update(results []contextHealth, now time.Time) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.results = results
+	m.updated = now
+}
+
+func (m *healthMonitor) latest() ([]contextHealth, time.Time) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.results, m.updated
+}
+
+// handler serves the results as an HTML page on / and as Prometheus metrics on /metrics.
+func (m *healthMonitor) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		results, _ := m.latest()
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writeHealthMetrics(w, results, time.Now())
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		results, updated := m.latest()
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := healthPage.Execute(w, struct {
+			Results []contextHealth
+			Updated time.Time
+		}{results, updated}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+	return mux
+}
+
+func writeHealthTable(out io.Writer, results []contextHealth, families bool) error {
+	w := printers.GetNewTabWriter(out)
+	if families {
+		fmt.Fprintf(w, "CONTEXT\tSERVER\tSTATUS\tIPV4\tIPV6\tSERVER CERT EXPIRES\tCREDENTIALS EXPIRE\tERROR\n")
+	} else {
+		fmt.Fprintf(w, "CONTEXT\tSERVER\tSTATUS\tSERVER CERT EXPIRES\tCREDENTIALS EXPIRE\tERROR\n")
+	}
+	for _, result := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\t", result.Context, result.Server, result.Status())
+		if families {
+			ipv4, ipv6 := "<none>", "<none>"
+			for _, family := range result.Families {
+				switch family.Family {
+				case familyIPv4:
+					ipv4 = family.Status()
+				case familyIPv6:
+					ipv6 = family.Status()
+				}
+			}
+			fmt.Fprintf(w, "%s\t%s\t", ipv4, ipv6)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", formatExpiry(result.ServerCertificateExpiry), formatExpiry(result.CredentialExpiry()), valueOrNone(result.Error))
+	}
+	return w.Flush()
+}
+
+func formatExpiry(expiry time.Time) string {
+	if expiry.IsZero() {
+		return "<none>"
+	}
+	return expiry.UTC().Format("2006-01-02 15:04")
+}
+
+// healthMetrics are the gauges exposed on /metrics, in order.
+var healthMetrics = []struct {
+	name  string
+	help  string
+	value func(h contextHealth, now time.Time) (float64, bool)
+}{
+	{"kubeconfig_context_reachable", "Whether the server of the context could be dialed.", func(h contextHealth, now time.Time) (float64, bool) { return boolGauge(h.Reachable), true }},
+	{"kubeconfig_context_authenticated", "Whether the credentials of the context were accepted.", func(h contextHealth, now time.Time) (float64, bool) { return boolGauge(h.Authenticated), true }},
+	{"kubeconfig_context_healthy", "Whether the context can be used.", func(h contextHealth, now time.Time) (float64, bool) { return boolGauge(h.Healthy()), true }},
+	{"kubeconfig_context_server_certificate_expiry_timestamp_seconds", "When the serving certificate of the server expires.", func(h contextHealth, now time.Time) (float64, bool) {
+		return float64(h.ServerCertificateExpiry.Unix()), !h.ServerCertificateExpiry.IsZero()
+	}},
+	{"kubeconfig_credential_expiry_seconds", "Seconds before the client certificate or the token of the context expires, negative once expired.", func(h contextHealth, now time.Time) (float64, bool) {
+		// Counted from the scrape rather than the check, so it keeps going down between rounds.
+		expiry := h.CredentialExpiry()
+		return expiry.Sub(now).Seconds(), !expiry.IsZero()
+	}},
+	{"kubeconfig_context_check_duration_seconds", "How long checking the context took.", func(h contextHealth, now time.Time) (float64, bool) { return h.Duration.Seconds(), true }},
+	{"kubeconfig_context_last_check_timestamp_seconds", "When the context was last checked.", func(h contextHealth, now time.Time) (float64, bool) { return float64(h.Checked.Unix()), true }},
+}
+
+func boolGauge(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+var metricLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writeHealthMetrics writes the results in the Prometheus text exposition format, as scraped at now.
+func writeHealthMetrics(w io.Writer, results []contextHealth, now time.Time) {
+	for _, metric := range healthMetrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", metric.name, metric.help, metric.name)
+		for _, result := range results {
+			if value, ok := metric.value(result, now); ok {
+				fmt.Fprintf(w, "%s{context=\"%s\",cluster=\"%s\"} %g\n", metric.name,
+					metricLabelEscaper.Replace(result.Context), metricLabelEscaper.Replace(result.Cluster), value)
+			}
+		}
+	}
+}
+
+var healthPage = template.Must(template.New("health").Funcs(template.FuncMap{"expiry": formatExpiry}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="30">
+<title>Context health</title>
+<style>
+body { font-family: sans-serif; }
+td, th { padding: 0.2em 0.8em; text-align: left; }
+.ok { color: green; }
+.unhealthy { color: red; }
+</style>
+</head>
+<body>
+<h1>Context health</h1>
+{{if .Updated.IsZero}}<p>The first round of checks is running.</p>{{else}}<p>Checked {{.Updated.UTC.Format "2006-01-02 15:04:05 MST"}}.</p>
+<table>
+<tr><th>Context</th><th>Server</th><th>Status</th><th>Server certificate expires</th><th>Credentials expire</th><th>Error</th></tr>
+{{range .Results}}<tr><td>{{.Context}}</td><td>{{.Server}}</td><td class="{{if .Healthy}}ok{{else}}unhealthy{{end}}">{{.Status}}</td><td>{{expiry .ServerCertificateExpiry}}</td><td>{{expiry .CredentialExpiry}}</td><td>{{.Error}}</td></tr>
+{{end}}</table>{{end}}
+</body>
+</html>
+`))
+
+// checkContextHealth dials the server of a context, reads the expiry of the certificates involved
+// and makes an authenticated request with the credentials of the context.
+func checkContextHealth(config *clientcmdapi.Config, name string) contextHealth {
+	health := contextHealth{Context: name, Checked: time.Now()}
+	defer func() { health.Duration = time.Since(health.Checked) }()
+	if context, ok := config.Contexts[name]; ok {
+		health.Cluster = context.Cluster
+		if cluster, ok := config.Clusters[context.Cluster]; ok {
+			health.Server = cluster.Server
+		}
+	}
+
+	restConfig, err := newProbeConfig(config, name, nil, 10*time.Second)
+	if err != nil {
+		health.Error = err.Error()
+		return health
+	}
+	if err := rest.LoadTLSFiles(restConfig); err != nil {
+		health.Error = err.Error()
+		return health
+	}
+	if len(restConfig.CertData) > 0 {
+		if health.ClientCertificateExpiry, err = certificateExpiry(restConfig.CertData); err != nil {
+			health.Error = fmt.Sprintf("client certificate: %v", err)
+		}
+	}
+
+	state, err := dialServer(restConfig)
+	if err != nil {
+		health.Error = err.Error()
+		return health
+	}
+	health.Reachable = true
+	if state != nil && len(state.PeerCertificates) > 0 {
+		health.ServerCertificateExpiry = state.PeerCertificates[0].NotAfter
+	}
+
+	// The wrapper is the innermost one, it sees the token set by auth providers and exec plugins.
+	var token string
+	restConfig.WrapTransport = transport.Wrappers(restConfig.WrapTransport, func(rt http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if auth := req.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+				token = strings.TrimPrefix(auth, "Bearer ")
+			}
+			return rt.RoundTrip(req)
+		})
+	})
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		health.Error = err.Error()
+		return health
+	}
+	// Discovery is only allowed to authenticated users, a forbidden request was still authenticated.
+	err = clientset.Discovery().RESTClient().Get().AbsPath("/api").Do().Error()
+	if err == nil || apierrors.IsForbidden(err) {
+		health.Authenticated = true
+	} else {
+		health.Error = err.Error()
+	}
+	health.TokenExpiry, _ = tokenExpiry(token)
+	return health
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// tokenExpiry returns the exp claim of a JWT, and false for tokens that are not JWTs or do not
+// expire.
+func tokenExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, false
+	}
+	claims := struct {
+		Expiry float64 `json:"exp"`
+	}{}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Expiry == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(claims.Expiry), 0), true
+}
+
+// certificateExpiry returns when the first certificate of PEM data expires.
+func certificateExpiry(data []byte) (time.Time, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return time.Time{}, errors.New("no PEM data found")
+	}
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return certificate.NotAfter, nil
+}
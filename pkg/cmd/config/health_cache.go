@@ -0,0 +1,168 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// defaultHealthCacheTTL is how long a health check is reused when the healthCacheTTL setting is
+// not set.
+const defaultHealthCacheTTL = 5 * time.Minute
+
+// healthCacheFile returns the file the results of health checks are cached in.
+func healthCacheFile() string {
+	return filepath.Join(stateDir(), "health-cache.json")
+}
+
+// healthCacheEntry is the last check of a context, with the fingerprint of the context, cluster
+// and user it was made with.
+type healthCacheEntry struct {
+	Fingerprint string        `json:"fingerprint"`
+	Health      contextHealth `json:"health"`
+}
+
+// healthCache answers health checks from the cache file while they are younger than TTL and the
+// context, its cluster and its user are unchanged, so that changing kubeconfig invalidates them.
+type healthCache struct {
+	Filename string
+	TTL      time.Duration
+	// Refresh checks every context again, and caches the new results.
+	Refresh bool
+	// ReadOnly leaves the cache file alone, for --no-write.
+	ReadOnly bool
+	// RequireNetwork is called before checking the contexts without a usable cached result, so
+	// that cached results are still served under --no-network.
+	RequireNetwork func() error
+	// Concurrency is how many contexts are checked at once, defaultHealthConcurrency when 0.
+	Concurrency int
+	Check       func(config *clientcmdapi.Config, context string) contextHealth
+	Now         func() time.Time
+}
+
+// checkAll returns the health of the contexts names of config, checking Concurrency at a time the ones
+// without a usable cached result, and writes the new results to the cache file.
+func (c healthCache) checkAll(config *clientcmdapi.Config, names []string) ([]contextHealth, error) {
+	entries, err := readHealthCache(c.Filename)
+	if err != nil {
+		return nil, err
+	}
+	now := c.Now()
+	results := make([]contextHealth, len(names))
+	fingerprints := make([]string, len(names))
+	stale := []int{}
+	for i, name := range names {
+		fingerprints[i] = healthFingerprint(config, name)
+		entry, ok := entries[name]
+		if ok && !c.Refresh && len(entry.Fingerprint) > 0 && entry.Fingerprint == fingerprints[i] && now.Sub(entry.Health.Checked) < c.TTL {
+			results[i] = entry.Health
+			continue
+		}
+		stale = append(stale, i)
+	}
+	if len(stale) > 0 && c.RequireNetwork != nil {
+		if err := c.RequireNetwork(); err != nil {
+			staleNames := make([]string, 0, len(stale))
+			for _, i := range stale {
+				staleNames = append(staleNames, names[i])
+			}
+			return nil, fmt.Errorf("%v, and the health of %s is not cached or older than %v", err, strings.Join(staleNames, ", "), c.TTL)
+		}
+	}
+	checked := len(stale) > 0
+	checkConcurrently(len(stale), c.Concurrency, func(j int) {
+		i := stale[j]
+		results[i] = c.Check(config, names[i])
+	})
+	if !checked || c.ReadOnly {
+		return results, nil
+	}
+
+	for name := range entries {
+		if _, ok := config.Contexts[name]; !ok {
+			delete(entries, name)
+		}
+	}
+	for i, name := range names {
+		entries[name] = healthCacheEntry{Fingerprint: fingerprints[i], Health: results[i]}
+	}
+	return results, writeHealthCache(c.Filename, entries)
+}
+
+// healthFingerprint identifies what the health of a context depends on: the context, its cluster
+// and its user. Extensions, which hold tags and notes, are left out.
+func healthFingerprint(config *clientcmdapi.Config, name string) string {
+	entries := []interface{}{nil, nil, nil}
+	if context, ok := config.Contexts[name]; ok {
+		context = context.DeepCopy()
+		context.Extensions = nil
+		entries[0] = context
+		if cluster, ok := config.Clusters[context.Cluster]; ok {
+			cluster = cluster.DeepCopy()
+			cluster.Extensions = nil
+			entries[1] = cluster
+		}
+		if authInfo, ok := config.AuthInfos[context.AuthInfo]; ok {
+			authInfo = authInfo.DeepCopy()
+			authInfo.Extensions = nil
+			entries[2] = authInfo
+		}
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// readHealthCache reads the cached checks by context name. A missing or broken cache is empty.
+func readHealthCache(filename string) (map[string]healthCacheEntry, error) {
+	entries := map[string]healthCacheEntry{}
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return map[string]healthCacheEntry{}, nil
+	}
+	return entries, nil
+}
+
+func writeHealthCache(filename string, entries map[string]healthCacheEntry) error {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0600)
+}
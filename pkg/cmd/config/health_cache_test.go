@@ -0,0 +1,139 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestHealthCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "health-cache")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := newRedFederalCowHammerConfig()
+	var mu sync.Mutex
+	checks := 0
+	now := time.Date(2019, 8, 1, 12, 0, 0, 0, time.UTC)
+	cache := healthCache{
+		Filename: filepath.Join(dir, "health-cache.json"),
+		TTL:      5 * time.Minute,
+		Check: func(config *clientcmdapi.Config, name string) contextHealth {
+			mu.Lock()
+			defer mu.Unlock()
+			checks++
+			return contextHealth{Context: name, Reachable: true, Authenticated: true, Checked: now}
+		},
+		Now: func() time.Time { return now },
+	}
+	check := func(expectedChecks int, description string) {
+		t.Helper()
+		results, err := cache.checkAll(&config, []string{"federal-context"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(results) != 1 || results[0].Status() != "ok" {
+			t.Errorf("unexpected results %+v", results)
+		}
+		if checks != expectedChecks {
+			t.Errorf("%s: expected %d check(s), got %d", description, expectedChecks, checks)
+		}
+	}
+
+	check(1, "first listing")
+	check(1, "listing again")
+	now = now.Add(6 * time.Minute)
+	check(2, "listing after the TTL")
+	cache.Refresh = true
+	check(3, "listing with --refresh")
+	cache.Refresh = false
+	config.Clusters["cow-cluster"].Server = "https://cow.example.com"
+	check(4, "listing after changing the cluster")
+	if err := writeContextMetadata(config.Contexts["federal-context"], contextMetadata{Tags: []string{"dev"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	check(4, "listing after tagging the context")
+
+	cache.RequireNetwork = func() error { return errors.New("network access is disabled") }
+	check(4, "listing without network")
+	now = now.Add(6 * time.Minute)
+	if _, err := cache.checkAll(&config, []string{"federal-context"}); err == nil || !strings.Contains(err.Error(), "federal-context") {
+		t.Errorf("expected a stale check without network to fail, got %v", err)
+	}
+	if checks != 4 {
+		t.Errorf("expected no check without network, got %d", checks)
+	}
+}
+
+func TestGetContextsWide(t *testing.T) {
+	dir, err := ioutil.TempDir("", "get-contexts-wide")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	kubeconfig := filepath.Join(dir, "config")
+	if err := clientcmd.WriteToFile(newRedFederalCowHammerConfig(), kubeconfig); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = kubeconfig
+	pathOptions.EnvVar = ""
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := GetContextsOptions{
+		configAccess: pathOptions,
+		showHeaders:  true,
+		health: &healthCache{
+			Filename: filepath.Join(dir, "health-cache.json"),
+			TTL:      time.Minute,
+			Check: func(config *clientcmdapi.Config, name string) contextHealth {
+				return contextHealth{Context: name, Checked: time.Now()}
+			},
+			Now: time.Now,
+		},
+		IOStreams: streams,
+	}
+	if err := o.RunGetContexts(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+	if fields := strings.Fields(lines[0]); strings.Join(fields, " ") != "CURRENT NAME CLUSTER AUTHINFO NAMESPACE STATUS CHECKED" {
+		t.Errorf("unexpected headers %q", lines[0])
+	}
+	if fields := strings.Fields(lines[1]); len(fields) < 6 || fields[1] != "federal-context" || fields[4] != "unreachable" {
+		t.Errorf("unexpected row %q", lines[1])
+	}
+	if _, err := os.Stat(o.health.Filename); err != nil {
+		t.Errorf("expected the health checks to be cached: %v", err)
+	}
+}
@@ -0,0 +1,222 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// HelmEnvVar names the helm binary "config exec --tool=helm" runs, helm from the PATH by default.
+const HelmEnvVar = "HELM"
+
+// The tools "config exec" runs.
+const (
+	toolKubectl = "kubectl"
+	toolHelm    = "helm"
+)
+
+// helmFlagNames maps the global kubectl flags helm has an equivalent of to the name of the latter.
+// The other kubectl flags of a context are left out of helm command lines.
+var helmFlagNames = map[string]string{
+	"namespace":                "namespace",
+	"as":                       "kube-as-user",
+	"as-group":                 "kube-as-group",
+	"server":                   "kube-apiserver",
+	"token":                    "kube-token",
+	"certificate-authority":    "kube-ca-file",
+	"insecure-skip-tls-verify": "kube-insecure-skip-tls-verify",
+	"tls-server-name":          "kube-tls-server-name",
+}
+
+// helmValueFlags are the global helm flags whose value may follow as a separate argument.
+var helmValueFlags = sets.NewString("n", "namespace", "kube-context", "kubeconfig", "kube-apiserver", "kube-token",
+	"kube-as-user", "kube-as-group", "kube-ca-file", "kube-tls-server-name", "registry-config",
+	"repository-cache", "repository-config", "burst-limit")
+
+// helmMutatingCommands are the helm commands that change the cluster.
+var helmMutatingCommands = sets.NewString("install", "upgrade", "uninstall", "delete", "del", "un", "rollback", "test")
+
+// helmFlags returns the helm equivalents of kubectl flags in their long form, as "config flags"
+// keeps them, leaving out those helm has none of.
+func helmFlags(kubectlFlags []string) []string {
+	flags := []string{}
+	for _, flag := range kubectlFlags {
+		parts := strings.SplitN(strings.TrimPrefix(flag, "--"), "=", 2)
+		name, ok := helmFlagNames[parts[0]]
+		if !ok {
+			continue
+		}
+		if len(parts) == 2 {
+			name += "=" + parts[1]
+		}
+		flags = append(flags, "--"+name)
+	}
+	return flags
+}
+
+// helmFlagsSet returns the kubectl names of the helm flags a helm command line sets, such as
+// server for --kube-apiserver.
+func helmFlagsSet(args []string) map[string]bool {
+	kubectlNames := map[string]string{}
+	for kubectlName, helmName := range helmFlagNames {
+		kubectlNames[helmName] = kubectlName
+	}
+	set := map[string]bool{}
+	for i := 0; i < len(args); i++ {
+		name, separate := helmFlag(args[i])
+		if len(name) == 0 {
+			continue
+		}
+		if name == "n" {
+			name = "namespace"
+		}
+		if kubectlName, ok := kubectlNames[name]; ok {
+			set[kubectlName] = true
+		} else {
+			set[name] = true
+		}
+		if separate {
+			i++
+		}
+	}
+	return set
+}
+
+// helmFlag returns the name of the flag arg is, and whether its value is the next argument. The
+// name is empty when arg is not a flag.
+func helmFlag(arg string) (string, bool) {
+	if !strings.HasPrefix(arg, "-") || arg == "-" || arg == "--" {
+		return "", false
+	}
+	name := strings.TrimLeft(arg, "-")
+	if i := strings.Index(name, "="); i >= 0 {
+		return name[:i], false
+	}
+	return name, helmValueFlags.Has(name)
+}
+
+// helmCommand returns the first argument of a helm command line that is neither a flag nor its
+// value, which names the helm command.
+func helmCommand(args []string) string {
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--" {
+			return ""
+		}
+		name, separate := helmFlag(args[i])
+		if len(name) == 0 {
+			return args[i]
+		}
+		if separate {
+			i++
+		}
+	}
+	return ""
+}
+
+// guardHelmCommand returns an error when a helm command line would change the cluster of a
+// read-only context.
+func guardHelmCommand(name string, context *clientcmdapi.Context, args []string) error {
+	enabled, err := isReadOnly(context)
+	if err != nil || !enabled {
+		return err
+	}
+	if command := helmCommand(args); helmMutatingCommands.Has(command) {
+		return fmt.Errorf("context %q is read-only, refusing to run \"helm %s\"; run \"kubectl config readonly %s off\" to allow changes",
+			name, command, name)
+	}
+	return nil
+}
+
+// helmEnv returns the shell commands pointing helm at a context of the kubeconfig files of
+// configAccess, followed by hints to log in to the chart registries of the cloud the user of the
+// context authenticates with.
+func helmEnv(configAccess clientcmd.ConfigAccess, config *clientcmdapi.Config, name string) ([]byte, error) {
+	name, err := resolveContextName(config, name)
+	if err != nil {
+		return nil, err
+	}
+	context, ok := config.Contexts[name]
+	if !ok {
+		return nil, fmt.Errorf("no context exists with the name: %q", name)
+	}
+	kubeconfig := configAccess.GetExplicitFile()
+	if !configAccess.IsExplicitFile() {
+		kubeconfig = strings.Join(configAccess.GetLoadingPrecedence(), string(filepath.ListSeparator))
+	}
+
+	buf := &bytes.Buffer{}
+	vars := [][2]string{
+		{clientcmd.RecommendedConfigPathEnvVar, kubeconfig},
+		{"HELM_KUBECONTEXT", name},
+	}
+	if len(context.Namespace) > 0 {
+		vars = append(vars, [2]string{"HELM_NAMESPACE", context.Namespace})
+	}
+	for _, v := range vars {
+		export, err := shellExport("sh", v[0], v[1])
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(export)
+	}
+	if hints := helmRegistryHints(config.AuthInfos[context.AuthInfo]); len(hints) > 0 {
+		buf.WriteString("# The OCI chart registries of this cloud can be logged in to with:\n")
+		for _, hint := range hints {
+			fmt.Fprintf(buf, "#   %s\n", hint)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// helmRegistryHints returns the commands logging helm in to the OCI chart registries of the cloud
+// whose credential plugin the user runs, if it is one of EKS, GKE or AKS.
+func helmRegistryHints(authInfo *clientcmdapi.AuthInfo) []string {
+	if authInfo == nil || authInfo.Exec == nil {
+		return nil
+	}
+	exec := authInfo.Exec
+	switch strings.TrimSuffix(filepath.Base(exec.Command), ".exe") {
+	case "aws", "aws-iam-authenticator":
+		// --region wins over AWS_REGION, which wins over AWS_DEFAULT_REGION
+		region := "REGION"
+		for _, name := range []string{"AWS_DEFAULT_REGION", "AWS_REGION"} {
+			for _, variable := range exec.Env {
+				if variable.Name == name {
+					region = variable.Value
+				}
+			}
+		}
+		for i, arg := range exec.Args {
+			if arg == "--region" && i+1 < len(exec.Args) {
+				region = exec.Args[i+1]
+			}
+		}
+		return []string{fmt.Sprintf("aws ecr get-login-password --region %s | helm registry login --username AWS --password-stdin ACCOUNT_ID.dkr.ecr.%s.amazonaws.com", region, region)}
+	case "gke-gcloud-auth-plugin", "gcloud":
+		return []string{"gcloud auth print-access-token | helm registry login --username oauth2accesstoken --password-stdin REGION-docker.pkg.dev"}
+	case "kubelogin":
+		return []string{"az acr login --name REGISTRY --expose-token --output tsv --query accessToken | helm registry login REGISTRY.azurecr.io --username 00000000-0000-0000-0000-000000000000 --password-stdin"}
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestHelmFlags(t *testing.T) {
+	flags := helmFlags([]string{"--namespace=web", "--as=admin", "--request-timeout=5s", "--insecure-skip-tls-verify"})
+	if expected := []string{"--namespace=web", "--kube-as-user=admin", "--kube-insecure-skip-tls-verify"}; !reflect.DeepEqual(expected, flags) {
+		t.Errorf("expected %v, got %v", expected, flags)
+	}
+	set := helmFlagsSet([]string{"-n", "shop", "--kube-as-user=ci", "upgrade", "web", "./chart", "--kube-apiserver", "https://1.2.3.4"})
+	for _, name := range []string{"namespace", "as", "server"} {
+		if !set[name] {
+			t.Errorf("expected %s to be set in %v", name, set)
+		}
+	}
+}
+
+func TestGuardHelmCommand(t *testing.T) {
+	context := &clientcmdapi.Context{}
+	if err := writeExtension(&context.Extensions, readOnlyExtension, readOnly{Enabled: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests := []struct {
+		args    []string
+		refused bool
+	}{
+		{args: []string{"list"}},
+		{args: []string{"-n", "install", "status", "web"}},
+		{args: []string{"template", "web", "./chart"}},
+		{args: []string{"upgrade", "--install", "web", "./chart"}, refused: true},
+		{args: []string{"--namespace", "shop", "uninstall", "web"}, refused: true},
+		{args: []string{"rollback", "web", "2"}, refused: true},
+	}
+	for _, tt := range tests {
+		err := guardHelmCommand("prod", context, tt.args)
+		if tt.refused && (err == nil || !strings.Contains(err.Error(), `refusing to run "helm`)) {
+			t.Errorf("expected %v to be refused, got %v", tt.args, err)
+		}
+		if !tt.refused && err != nil {
+			t.Errorf("expected %v to be allowed, got %v", tt.args, err)
+		}
+	}
+}
+
+func TestHelmEnv(t *testing.T) {
+	config := newRedFederalCowHammerConfig()
+	config.Contexts["federal-context"].Namespace = "web"
+	config.AuthInfos["red-user"] = &clientcmdapi.AuthInfo{Exec: &clientcmdapi.ExecConfig{
+		Command: "aws",
+		Args:    []string{"--region", "eu-west-1", "eks", "get-token", "--cluster-name", "cow"},
+		Env:     []clientcmdapi.ExecEnvVar{{Name: "AWS_REGION", Value: "us-east-1"}},
+	}}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.LoadingRules.ExplicitPath = "/home/me/.kube/config"
+
+	data, err := helmEnv(pathOptions, &config, currentContextShorthand)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "export KUBECONFIG='/home/me/.kube/config'\nexport HELM_KUBECONTEXT='federal-context'\nexport HELM_NAMESPACE='web'\n"
+	if !strings.HasPrefix(string(data), expected) {
+		t.Errorf("expected the exports:\n%s\ngot:\n%s", expected, data)
+	}
+	if !strings.Contains(string(data), "# The OCI chart registries") || !strings.Contains(string(data), "ACCOUNT_ID.dkr.ecr.eu-west-1.amazonaws.com") {
+		t.Errorf("expected the ECR login hint in the region of --region, got:\n%s", data)
+	}
+
+	if hints := helmRegistryHints(&clientcmdapi.AuthInfo{Token: "token"}); len(hints) != 0 {
+		t.Errorf("expected no hints for a token, got %v", hints)
+	}
+}
+
+func TestExecHelm(t *testing.T) {
+	dir, err := ioutil.TempDir("", "helm")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	config := newRedFederalCowHammerConfig()
+	if err := writeKubectlFlags(config.Contexts["federal-context"], []string{"--namespace=web", "--as=admin", "--request-timeout=5s"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	configFile := filepath.Join(dir, "config")
+	if err := clientcmd.WriteToFile(config, configFile); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.LoadingRules.ExplicitPath = configFile
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+		err      string
+	}{
+		{
+			name:     "flags of the context",
+			args:     []string{"list"},
+			expected: []string{"--kube-context=federal-context", "--kubeconfig=" + configFile, "--namespace=web", "--kube-as-user=admin", "list"},
+		},
+		{
+			name:     "command line wins",
+			args:     []string{"-n", "shop", "status", "web"},
+			expected: []string{"--kube-context=federal-context", "--kubeconfig=" + configFile, "--kube-as-user=admin", "-n", "shop", "status", "web"},
+		},
+		{
+			name: "context on the command line",
+			args: []string{"--kube-context=prod", "list"},
+			err:  "--kube-context cannot be passed to helm",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ran []string
+			var binary string
+			streams, _, _, _ := genericclioptions.NewTestIOStreams()
+			o := &ExecOptions{
+				ConfigAccess: pathOptions,
+				Context:      currentContextShorthand,
+				Args:         tt.args,
+				Tool:         toolHelm,
+				Helm:         "helm",
+				RunKubectl: func(name string, args []string) error {
+					binary, ran = name, args
+					return nil
+				},
+				IOStreams: streams,
+			}
+			err := o.Validate()
+			if err == nil {
+				err = o.Run()
+			}
+			if len(tt.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("expected an error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if binary != "helm" || !reflect.DeepEqual(ran, tt.expected) {
+				t.Errorf("expected helm to run with %v, got %s %v", tt.expected, binary, ran)
+			}
+		})
+	}
+}
+
+func TestExecHelmCompleteWithoutKubectl(t *testing.T) {
+	dir, err := ioutil.TempDir("", "helm")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.Setenv("XDG_CONFIG_HOME", os.Getenv("XDG_CONFIG_HOME"))
+	os.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", dir)
+	defer os.Setenv(KubectlEnvVar, os.Getenv(KubectlEnvVar))
+	os.Unsetenv(KubectlEnvVar)
+
+	streams, _, _, _ := genericclioptions.NewTestIOStreams()
+	o := &ExecOptions{Helm: "helm", IOStreams: streams}
+	cmd := NewCmdConfigExec(streams, clientcmd.NewDefaultPathOptions())
+	if err := cmd.ParseFlags([]string{"--tool=helm", "--", "list"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	o.Tool = toolHelm
+	if err := o.Complete(cmd, cmd.Flags().Args()); err != nil {
+		t.Fatalf("expected helm to run without kubectl installed, got %v", err)
+	}
+	if len(o.Kubectl) > 0 {
+		t.Errorf("expected kubectl not to be looked for, got %q", o.Kubectl)
+	}
+}
@@ -0,0 +1,309 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// JSON-RPC 2.0 error codes used by the IDE server.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcServerError    = -32000
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// rpcMessage is a response when ID is set, and a notification otherwise.
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  interface{}     `json:"params,omitempty"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// IDEServerOptions holds the command-line options for 'config ide-server' sub command
+type IDEServerOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Stdio        bool
+	Interval     time.Duration
+
+	log       *cmdLogger
+	noNetwork bool
+	noWrite   bool
+
+	// lock serializes writes to Out, which responses and watch notifications share.
+	lock     sync.Mutex
+	watching bool
+	stop     chan struct{}
+
+	genericclioptions.IOStreams
+}
+
+var (
+	ideServerLong = templates.LongDesc(`
+		Serve kubeconfig operations to editor extensions over JSON-RPC 2.0.
+
+		With --stdio, requests are read from standard input and responses are written to standard
+		output, one JSON object per line. The methods are:
+
+		    * list: the current context and every context, described as by "config context-info"
+		    * switch {"context": NAME, "acknowledge": BOOL}: make a context current, like
+		      "config use-context" with the verifyOnUse setting; the described context is returned.
+		      Switching is refused under --no-write
+		    * watch: send a "changed" notification, with an event as printed by
+		      "config watch -o json", for every later change of the kubeconfig
+		    * validate: the problems that make the kubeconfig unusable
+
+		Editor extensions can manage contexts through these methods instead of parsing and
+		writing kubeconfig files themselves. The server stops at the end of its input.`)
+
+	ideServerExample = templates.Examples(`
+		# Serve an editor extension that started kubectl as a child process
+		kubectl config ide-server --stdio
+
+		# A session: the second line is the response to the first
+		{"jsonrpc":"2.0","id":1,"method":"switch","params":{"context":"staging"}}
+		{"jsonrpc":"2.0","id":1,"result":{"context":"staging","namespace":"default",...}}`)
+)
+
+// NewCmdConfigIDEServer returns a Command instance for 'config ide-server' sub command
+func NewCmdConfigIDEServer(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &IDEServerOptions{
+		ConfigAccess: configAccess,
+		Interval:     time.Second,
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "ide-server --stdio",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Serve kubeconfig operations to editor extensions over JSON-RPC"),
+		Long:                  ideServerLong,
+		Example:               ideServerExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(cmdutil.UsageErrorf(cmd, "unexpected arguments: %v", args))
+			}
+			cmdutil.CheckErr(o.Complete(cmd))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().BoolVar(&o.Stdio, "stdio", o.Stdio, "Communicate over standard input and output")
+	cmd.Flags().DurationVar(&o.Interval, "interval", o.Interval, "Time between two checks of the kubeconfig files for watch")
+	return cmd
+}
+
+// Complete sets up logging
+func (o *IDEServerOptions) Complete(cmd *cobra.Command) error {
+	var err error
+	o.noNetwork = networkDisabled(cmd)
+	o.noWrite = writesDisabled(cmd)
+	o.log, err = newCmdLogger(cmd, o.ErrOut)
+	return err
+}
+
+// Validate makes sure a supported transport was chosen
+func (o *IDEServerOptions) Validate() error {
+	if !o.Stdio {
+		return errors.New("--stdio is required, it is the only supported transport")
+	}
+	if o.Interval <= 0 {
+		return errors.New("--interval must be positive")
+	}
+	return nil
+}
+
+// Run answers requests until the end of the input
+func (o *IDEServerOptions) Run() error {
+	o.stop = make(chan struct{})
+	defer close(o.stop)
+
+	scanner := bufio.NewScanner(o.In)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		request := rpcRequest{}
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			if err := o.send(rpcMessage{ID: json.RawMessage("null"), Error: &rpcError{Code: rpcParseError, Message: err.Error()}}); err != nil {
+				return err
+			}
+			continue
+		}
+		result, rpcErr := o.handle(request)
+		if len(request.ID) == 0 {
+			// Notifications get no response.
+			continue
+		}
+		response := rpcMessage{ID: request.ID, Result: result, Error: rpcErr}
+		if rpcErr == nil && result == nil {
+			response.Result = struct{}{}
+		}
+		if err := o.send(response); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func (o *IDEServerOptions) send(message rpcMessage) error {
+	message.JSONRPC = "2.0"
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	_, err = fmt.Fprintf(o.Out, "%s\n", data)
+	return err
+}
+
+// handle runs a method and returns its result or error.
+func (o *IDEServerOptions) handle(request rpcRequest) (interface{}, *rpcError) {
+	if request.JSONRPC != "2.0" {
+		return nil, &rpcError{Code: rpcInvalidRequest, Message: `jsonrpc must be "2.0"`}
+	}
+	serverError := func(err error) *rpcError {
+		return &rpcError{Code: rpcServerError, Message: err.Error()}
+	}
+
+	switch request.Method {
+	case "list":
+		config, err := o.ConfigAccess.GetStartingConfig()
+		if err != nil {
+			return nil, serverError(err)
+		}
+		contexts := []contextInfo{}
+		for _, name := range sortedContextNames(config.Contexts) {
+			info, err := describeContext(config, name)
+			if err != nil {
+				return nil, serverError(err)
+			}
+			contexts = append(contexts, info)
+		}
+		return struct {
+			Current  string        `json:"current"`
+			Contexts []contextInfo `json:"contexts"`
+		}{config.CurrentContext, contexts}, nil
+
+	case "switch":
+		params := struct {
+			Context     string `json:"context"`
+			Acknowledge bool   `json:"acknowledge"`
+		}{}
+		if err := json.Unmarshal(request.Params, &params); err != nil || len(params.Context) == 0 {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: "params must name a context"}
+		}
+		if o.noWrite {
+			return nil, serverError(fmt.Errorf("switching contexts writes the kubeconfig, which is forbidden by --%s or $%s", FlagNoWrite, NoWriteEnvVar))
+		}
+		use := &UseContextOptions{ConfigAccess: o.ConfigAccess, ContextName: params.Context, Acknowledge: params.Acknowledge, Check: checkContextHealth}
+		if err := use.applySettings(false); err != nil {
+			return nil, serverError(err)
+		}
+		if o.noNetwork {
+			use.Verify = verifyNever
+		}
+		if err := use.Run(); err != nil {
+			return nil, serverError(err)
+		}
+		if len(use.VerifyWarning) > 0 {
+			o.log.Warningf("%s", use.VerifyWarning)
+		}
+		config, err := o.ConfigAccess.GetStartingConfig()
+		if err != nil {
+			return nil, serverError(err)
+		}
+		info, err := describeContext(config, use.ContextName)
+		if err != nil {
+			return nil, serverError(err)
+		}
+		return info, nil
+
+	case "watch":
+		o.lock.Lock()
+		started := o.watching
+		o.watching = true
+		o.lock.Unlock()
+		if !started {
+			go func() {
+				err := watchConfig(o.ConfigAccess, o.Interval, o.stop, o.log, func(event configEvent) error {
+					return o.send(rpcMessage{Method: "changed", Params: event})
+				})
+				if err != nil {
+					o.log.Warningf("watch stopped: %v", err)
+				}
+			}()
+		}
+		return nil, nil
+
+	case "validate":
+		config, err := o.ConfigAccess.GetStartingConfig()
+		if err != nil {
+			return nil, serverError(err)
+		}
+		problems := []string{}
+		if err := clientcmd.Validate(*config); err != nil {
+			if aggregate, ok := err.(utilerrors.Aggregate); ok {
+				for _, err := range aggregate.Errors() {
+					problems = append(problems, err.Error())
+				}
+			} else {
+				problems = append(problems, err.Error())
+			}
+		}
+		return struct {
+			Valid    bool     `json:"valid"`
+			Problems []string `json:"problems"`
+		}{len(problems) == 0, problems}, nil
+	}
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("unknown method %q", request.Method)}
+}
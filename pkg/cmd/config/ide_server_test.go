@@ -0,0 +1,146 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestIDEServer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ide-server")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.Setenv("XDG_CONFIG_HOME", os.Getenv("XDG_CONFIG_HOME"))
+	os.Setenv("XDG_CONFIG_HOME", dir)
+
+	kubeconfig := filepath.Join(dir, "config")
+	startingConfig := newRedFederalCowHammerConfig()
+	startingConfig.Contexts["shaker-context"] = &clientcmdapi.Context{AuthInfo: "red-user", Cluster: "cow-cluster", Namespace: "saw-ns"}
+	startingConfig.Contexts["broken-context"] = &clientcmdapi.Context{AuthInfo: "red-user", Cluster: "missing-cluster"}
+	if err := clientcmd.WriteToFile(startingConfig, kubeconfig); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = kubeconfig
+	pathOptions.EnvVar = ""
+
+	streams, in, out, _ := genericclioptions.NewTestIOStreams()
+	in.WriteString(strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"list"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"switch","params":{"context":"shaker-context"}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"switch","params":{}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"validate"}`,
+		`{"jsonrpc":"2.0","id":"five","method":"explode"}`,
+		`{"jsonrpc":"2.0","method":"list"}`,
+		`not json`,
+	}, "\n"))
+	o := &IDEServerOptions{ConfigAccess: pathOptions, Stdio: true, Interval: time.Second, IOStreams: streams}
+	if err := o.Complete(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	responses := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	expected := []string{
+		`{"jsonrpc":"2.0","id":1,"result":{"current":"federal-context","contexts":[{"context":"broken-context","namespace":"default","cluster":"missing-cluster","user":"red-user","authType":"token"},{"context":"federal-context","namespace":"default","cluster":"cow-cluster","user":"red-user","server":"http://cow.org:8080","authType":"token"},{"context":"shaker-context","namespace":"saw-ns","cluster":"cow-cluster","user":"red-user","server":"http://cow.org:8080","authType":"token"}]}}`,
+		`{"jsonrpc":"2.0","id":2,"result":{"context":"shaker-context","namespace":"saw-ns","cluster":"cow-cluster","user":"red-user","server":"http://cow.org:8080","authType":"token"}}`,
+		`{"jsonrpc":"2.0","id":3,"error":{"code":-32602,"message":"params must name a context"}}`,
+		``,
+		`{"jsonrpc":"2.0","id":"five","error":{"code":-32601,"message":"unknown method \"explode\""}}`,
+		``,
+	}
+	if len(responses) != len(expected) {
+		t.Fatalf("expected %d responses, got %d:\n%s", len(expected), len(responses), out.String())
+	}
+	for i := range expected {
+		switch {
+		case i == 3:
+			if !strings.HasPrefix(responses[i], `{"jsonrpc":"2.0","id":4,"result":{"valid":false,"problems":[`) || !strings.Contains(responses[i], "missing-cluster") {
+				t.Errorf("expected the missing cluster to be reported, got %s", responses[i])
+			}
+		case i == 5:
+			if !strings.HasPrefix(responses[i], `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,`) {
+				t.Errorf("expected a parse error, got %s", responses[i])
+			}
+		case responses[i] != expected[i]:
+			t.Errorf("expected\n%s\ngot\n%s", expected[i], responses[i])
+		}
+	}
+
+	config, err := clientcmd.LoadFromFile(kubeconfig)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.CurrentContext != "shaker-context" {
+		t.Errorf("expected switch to change the current context, got %q", config.CurrentContext)
+	}
+	if err := (&IDEServerOptions{Interval: time.Second}).Validate(); err == nil {
+		t.Errorf("expected an error without --stdio")
+	}
+}
+
+func TestIDEServerNoWrite(t *testing.T) {
+	kubeconfig, err := ioutil.TempFile("", "ide-server")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(kubeconfig.Name())
+	startingConfig := newRedFederalCowHammerConfig()
+	startingConfig.Contexts["shaker-context"] = &clientcmdapi.Context{AuthInfo: "red-user", Cluster: "cow-cluster"}
+	if err := clientcmd.WriteToFile(startingConfig, kubeconfig.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = kubeconfig.Name()
+	pathOptions.EnvVar = ""
+
+	defer os.Setenv(NoWriteEnvVar, os.Getenv(NoWriteEnvVar))
+	os.Setenv(NoWriteEnvVar, "true")
+	streams, in, out, _ := genericclioptions.NewTestIOStreams()
+	in.WriteString(`{"jsonrpc":"2.0","id":1,"method":"switch","params":{"context":"shaker-context"}}`)
+	o := &IDEServerOptions{ConfigAccess: pathOptions, Stdio: true, Interval: time.Second, IOStreams: streams}
+	if err := o.Complete(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), `"error"`) || !strings.Contains(out.String(), "--no-write") {
+		t.Errorf("expected switch to be refused, got %s", out.String())
+	}
+
+	config, err := clientcmd.LoadFromFile(kubeconfig.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.CurrentContext != "federal-context" {
+		t.Errorf("expected the current context to be kept, got %q", config.CurrentContext)
+	}
+}
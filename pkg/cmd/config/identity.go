@@ -0,0 +1,384 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/printers"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// clusterIdentity is stored in a cluster's identityExtension. It fingerprints the cluster found at
+// Server by the CA its serving certificate chains to and the UID of its kube-system namespace,
+// which is created once with the cluster and never changes.
+type clusterIdentity struct {
+	Server               string `json:"server"`
+	CertificateAuthority string `json:"caSHA256,omitempty"`
+	UID                  string `json:"uid,omitempty"`
+}
+
+// Equal reports whether both identities fingerprint the same cluster.
+func (i clusterIdentity) Equal(other clusterIdentity) bool {
+	return i.CertificateAuthority == other.CertificateAuthority && i.UID == other.UID
+}
+
+func (i clusterIdentity) String() string {
+	ca := i.CertificateAuthority
+	if len(ca) > 16 {
+		ca = ca[:16]
+	}
+	return fmt.Sprintf("CA %s, UID %s", valueOrNone(ca), valueOrNone(i.UID))
+}
+
+func valueOrNone(value string) string {
+	if len(value) == 0 {
+		return "<none>"
+	}
+	return value
+}
+
+// VerifyIdentityOptions holds the command-line options for 'config verify-identity' sub command
+type VerifyIdentityOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Clusters     []string
+	Update       bool
+
+	// Identify connects to a cluster with the credentials of a context using it.
+	Identify func(config *clientcmdapi.Config, context string) (clusterIdentity, error)
+	log      *cmdLogger
+
+	genericclioptions.IOStreams
+}
+
+var (
+	verifyIdentityLong = templates.LongDesc(`
+		Check that the servers of clusters are still the clusters they were.
+
+		The identity of a cluster is the SHA-256 hash of the CA its serving certificate chains
+		to, together with the UID of its kube-system namespace. The first time a cluster is
+		checked, its identity is recorded in the kubecfg.io/identity extension of the cluster
+		entry. Later checks fail when the server at the same URL presents a different identity,
+		which happens when DNS is hijacked or an endpoint is reused for a new cluster. After
+		making sure the change is legitimate, --update records the new identity.
+
+		The credentials of the first context using a cluster are used to read the namespace.
+		Clusters no context uses are skipped.`)
+
+	verifyIdentityExample = templates.Examples(`
+		# Record or check the identity of every cluster
+		kubectl config verify-identity
+
+		# Accept the new identity of a rebuilt cluster
+		kubectl config verify-identity prod-eu --update`)
+)
+
+// NewCmdConfigVerifyIdentity returns a Command instance for 'config verify-identity' sub command
+func NewCmdConfigVerifyIdentity(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &VerifyIdentityOptions{ConfigAccess: configAccess, IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "verify-identity [CLUSTER...] [--update]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Check that cluster servers still present the identity recorded for them"),
+		Long:                  verifyIdentityLong,
+		Example:               verifyIdentityExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			o.Clusters = args
+			cmdutil.CheckErr(o.Complete(cmd))
+			cmdutil.CheckErr(requireNetwork(cmd))
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	cmd.Flags().BoolVar(&o.Update, "update", o.Update, "Record the identity clusters present even if it changed")
+	return cmd
+}
+
+// Complete sets up logging and the default way of identifying clusters
+func (o *VerifyIdentityOptions) Complete(cmd *cobra.Command) error {
+	if o.Identify == nil {
+		o.Identify = identifyCluster
+	}
+	var err error
+	o.log, err = newCmdLogger(cmd, o.ErrOut)
+	return err
+}
+
+// Run identifies the clusters, records new identities and reports changed ones
+func (o *VerifyIdentityOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	names := o.Clusters
+	if len(names) == 0 {
+		names = sortedClusterNames(config.Clusters)
+	}
+
+	// The first context using a cluster provides the credentials.
+	contexts := map[string]string{}
+	contextNames := sortedContextNames(config.Contexts)
+	for i := len(contextNames) - 1; i >= 0; i-- {
+		contexts[config.Contexts[contextNames[i]].Cluster] = contextNames[i]
+	}
+
+	changed, modified := 0, false
+	w := printers.GetNewTabWriter(o.Out)
+	fmt.Fprintf(w, "CLUSTER\tSERVER\tSTATUS\n")
+	for _, name := range names {
+		cluster, ok := config.Clusters[name]
+		if !ok {
+			return fmt.Errorf("no cluster exists with the name: %q", name)
+		}
+		context, ok := contexts[name]
+		if !ok {
+			o.log.Warningf("skipped cluster %q, which no context uses", name)
+			continue
+		}
+
+		recorded := clusterIdentity{}
+		found, err := readExtension(cluster.Extensions, identityExtension, &recorded)
+		if err != nil {
+			return fmt.Errorf("cluster %q: %v", name, err)
+		}
+		o.log.Infof(1, "identifying cluster %q through context %q", name, context)
+		current, err := o.Identify(config, context)
+		if err != nil {
+			fmt.Fprintf(w, "%s\t%s\terror: %v\n", name, cluster.Server, err)
+			continue
+		}
+		current.Server = cluster.Server
+
+		status := "verified"
+		switch {
+		case !found:
+			status = "recorded"
+		case recorded.Server != cluster.Server:
+			// The entry was pointed at another server on purpose.
+			status = "recorded for new server"
+		case recorded.Equal(current):
+		case o.Update:
+			status = fmt.Sprintf("updated, was %s", recorded)
+		default:
+			changed++
+			fmt.Fprintf(w, "%s\t%s\tCHANGED: %s, recorded %s\n", name, cluster.Server, current, recorded)
+			continue
+		}
+		if status != "verified" {
+			if err := writeExtension(&cluster.Extensions, identityExtension, current); err != nil {
+				return err
+			}
+			modified = true
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", name, cluster.Server, status)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if modified {
+		if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+			return err
+		}
+	}
+	if changed > 0 {
+		return fmt.Errorf("%d cluster(s) present a different identity than recorded; check them and run again with --update if the change is expected", changed)
+	}
+	return nil
+}
+
+// identifyCluster connects to the cluster of a context and returns its identity.
+func identifyCluster(config *clientcmdapi.Config, context string) (clusterIdentity, error) {
+	restConfig, err := newProbeConfig(config, context, nil, 10*time.Second)
+	if err != nil {
+		return clusterIdentity{}, err
+	}
+
+	identity := clusterIdentity{}
+	if identity.CertificateAuthority, err = servingCAFingerprint(restConfig); err != nil {
+		return clusterIdentity{}, err
+	}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return clusterIdentity{}, err
+	}
+	namespace, err := clientset.CoreV1().Namespaces().Get(metav1.NamespaceSystem, metav1.GetOptions{})
+	if err != nil {
+		return clusterIdentity{}, err
+	}
+	identity.UID = string(namespace.UID)
+	return identity, nil
+}
+
+// servingCAFingerprint returns the SHA-256 hash of the CA certificate the server's certificate chains
+// to: the root of the verified chain, or the last certificate the server sent when verification is
+// skipped. It is empty for plain HTTP servers.
+func servingCAFingerprint(restConfig *rest.Config) (string, error) {
+	state, err := dialServer(restConfig)
+	if err != nil || state == nil {
+		return "", err
+	}
+	chain := state.PeerCertificates
+	if len(state.VerifiedChains) > 0 {
+		chain = state.VerifiedChains[0]
+	}
+	if len(chain) == 0 {
+		return "", fmt.Errorf("%s presented no certificate", restConfig.Host)
+	}
+	sum := sha256.Sum256(chain[len(chain)-1].Raw)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// dialServer opens a connection to the server with the TLS settings of restConfig and returns the
+// state of the TLS handshake, which is nil for plain HTTP servers. The connection goes through the
+// proxy the environment sets for the server, and is tried again with defaultProbeBackoff.
+func dialServer(restConfig *rest.Config) (*tls.ConnectionState, error) {
+	var state *tls.ConnectionState
+	err := defaultProbeBackoff.retry(func() error {
+		var err error
+		state, err = dialServerOnce(restConfig)
+		return err
+	})
+	return state, err
+}
+
+func dialServerOnce(restConfig *rest.Config) (*tls.ConnectionState, error) {
+	server, err := url.Parse(restConfig.Host)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: restConfig.Timeout}
+	address := server.Host
+	if len(server.Port()) == 0 {
+		port := "443"
+		if server.Scheme == "http" {
+			port = "80"
+		}
+		address = net.JoinHostPort(server.Hostname(), port)
+	}
+	direct := dialer.DialContext
+	if restConfig.Dial != nil {
+		direct = restConfig.Dial
+	}
+	conn, err := dialThroughProxy(dialer, direct, server, address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if server.Scheme != "https" {
+		return nil, nil
+	}
+
+	tlsConfig, err := rest.TLSConfigFor(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+	if len(tlsConfig.ServerName) == 0 {
+		tlsConfig = tlsConfig.Clone()
+		tlsConfig.ServerName = server.Hostname()
+	}
+	if dialer.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(dialer.Timeout))
+	}
+	tlsConn := tls.Client(conn, tlsConfig)
+	if err := tlsConn.Handshake(); err != nil {
+		return nil, err
+	}
+	state := tlsConn.ConnectionState()
+	return &state, nil
+}
+
+// dialThroughProxy connects to address, the one of server, through the HTTP proxy HTTPS_PROXY,
+// HTTP_PROXY and NO_PROXY choose for server, as kubectl does, or directly with direct when there is
+// none.
+func dialThroughProxy(dialer *net.Dialer, direct dialFunc, server *url.URL, address string) (net.Conn, error) {
+	proxy, err := http.ProxyFromEnvironment(&http.Request{URL: server})
+	if err != nil {
+		return nil, err
+	}
+	if proxy == nil {
+		return direct(context.Background(), "tcp", address)
+	}
+	if proxy.Scheme != "http" && proxy.Scheme != "https" {
+		return nil, fmt.Errorf("proxy %s: only http and https proxies are supported", proxy.Host)
+	}
+	proxyAddress := proxy.Host
+	if len(proxy.Port()) == 0 {
+		port := "80"
+		if proxy.Scheme == "https" {
+			port = "443"
+		}
+		proxyAddress = net.JoinHostPort(proxy.Hostname(), port)
+	}
+	conn, err := dialer.Dial("tcp", proxyAddress)
+	if err != nil {
+		return nil, err
+	}
+	if proxy.Scheme == "https" {
+		conn = tls.Client(conn, &tls.Config{ServerName: proxy.Hostname()})
+	}
+	if dialer.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(dialer.Timeout))
+	}
+
+	connect := &http.Request{Method: http.MethodConnect, URL: &url.URL{Opaque: address}, Host: address, Header: http.Header{}}
+	if proxy.User != nil {
+		password, _ := proxy.User.Password()
+		connect.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(proxy.User.Username()+":"+password)))
+	}
+	if err := connect.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s: %v", proxy.Host, err)
+	}
+	// The server sends nothing before the TLS handshake, so the reader buffers no more than the
+	// response of the proxy.
+	resp, err := http.ReadResponse(bufio.NewReader(conn), connect)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s: %v", proxy.Host, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s: %s", proxy.Host, resp.Status)
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
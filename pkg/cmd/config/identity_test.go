@@ -0,0 +1,96 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestVerifyIdentity(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	config := newRedFederalCowHammerConfig()
+	config.Clusters["unused-cluster"] = &clientcmdapi.Cluster{Server: "https://unused.org"}
+	if err := clientcmd.WriteToFile(config, fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	identity := clusterIdentity{CertificateAuthority: "0123456789abcdef0123", UID: "uid-1"}
+	run := func(update bool) (string, string, error) {
+		streams, _, out, errOut := genericclioptions.NewTestIOStreams()
+		o := &VerifyIdentityOptions{
+			ConfigAccess: pathOptions,
+			Update:       update,
+			Identify: func(config *clientcmdapi.Config, context string) (clusterIdentity, error) {
+				if context != "federal-context" {
+					t.Errorf("expected the cluster to be identified through federal-context, got %q", context)
+				}
+				return identity, nil
+			},
+			IOStreams: streams,
+		}
+		if err := o.Complete(nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		err := o.Run()
+		return out.String(), errOut.String(), err
+	}
+
+	out, errOut, err := run(false)
+	if err != nil || !strings.Contains(out, "cow-cluster") || !strings.Contains(out, "recorded") {
+		t.Errorf("expected the identity to be recorded, got %q, %v", out, err)
+	}
+	if !strings.Contains(errOut, `skipped cluster "unused-cluster"`) {
+		t.Errorf("expected the unused cluster to be skipped, got %q", errOut)
+	}
+	recorded := clusterIdentity{}
+	loaded, err := clientcmd.LoadFromFile(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if found, err := readExtension(loaded.Clusters["cow-cluster"].Extensions, identityExtension, &recorded); !found || err != nil || recorded != (clusterIdentity{Server: "http://cow.org:8080", CertificateAuthority: identity.CertificateAuthority, UID: "uid-1"}) {
+		t.Errorf("unexpected recorded identity %#v, %v", recorded, err)
+	}
+
+	if out, _, err := run(false); err != nil || !strings.Contains(out, "verified") {
+		t.Errorf("expected the identity to be verified, got %q, %v", out, err)
+	}
+
+	identity.UID = "uid-2"
+	if out, _, err := run(false); err == nil || !strings.Contains(out, "CHANGED: CA 0123456789abcdef, UID uid-2, recorded CA 0123456789abcdef, UID uid-1") {
+		t.Errorf("expected the changed identity to be reported, got %q, %v", out, err)
+	}
+	if out, _, err := run(true); err != nil || !strings.Contains(out, "updated, was CA 0123456789abcdef, UID uid-1") {
+		t.Errorf("expected the identity to be updated, got %q, %v", out, err)
+	}
+	if out, _, err := run(false); err != nil || !strings.Contains(out, "verified") {
+		t.Errorf("expected the updated identity to be verified, got %q, %v", out, err)
+	}
+}
@@ -0,0 +1,710 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// ImportOptions holds the command-line options for 'config import' sub command
+type ImportOptions struct {
+	ConfigAccess   clientcmd.ConfigAccess
+	Sources        []string
+	BatchSize      int
+	Resume         bool
+	Overwrite      bool
+	OnConflict     string
+	CheckpointFile string
+	SignatureKey   string
+	// AllowDuplicateServer imports clusters pointing at the same server as an existing cluster
+	// instead of reusing the existing one.
+	AllowDuplicateServer bool
+	// Sync imports again the sources the entries of the kubeconfig were imported from, only those of
+	// the kind From when it is set.
+	Sync bool
+	From string
+	// Clipboard imports the kubeconfig on the clipboard instead of files.
+	Clipboard bool
+	// NamingTemplate is the Go template imported contexts are renamed with, see lintContextName.
+	NamingTemplate string
+
+	// PasteFromClipboard returns the contents of the clipboard with Clipboard.
+	PasteFromClipboard func() ([]byte, error)
+
+	// RunCommand and RunSSH run the CLIs and ssh to import the sources again with Sync.
+	RunCommand commandRunner
+	RunSSH     sshRunner
+	// Confirm asks before the entries of the contexts the confirm setting covers are replaced.
+	Confirm changeConfirmer
+
+	log          *cmdLogger
+	signatureKey crypto.PublicKey
+	resolve      conflictStrategy
+	naming       *template.Template
+
+	genericclioptions.IOStreams
+}
+
+// importCheckpoint records which sources of an interrupted or partially failed import have already
+// been written, so that --resume only processes the rest.
+type importCheckpoint struct {
+	Destination string   `json:"destination"`
+	Completed   []string `json:"completed"`
+}
+
+// importedSource is a single parsed source travelling through the import pipeline.
+type importedSource struct {
+	name   string
+	config *clientcmdapi.Config
+	err    error
+}
+
+const defaultImportBatchSize = 50
+
+var (
+	importLong = templates.LongDesc(`
+		Merges clusters, users and contexts from other kubeconfig files into the current kubeconfig.
+
+		Sources are read and merged one at a time, and the kubeconfig is written every --batch-size
+		entries, so importing hundreds of clusters never holds all of them in memory. Directories are
+		expanded to the files they contain.
+
+		A source that cannot be read is reported and skipped. Which sources were written is recorded
+		in a checkpoint file, and --resume continues a failed or interrupted import from there.
+
+		What becomes of an imported entry whose name is taken by a different entry is chosen with
+		--on-conflict, or the onConflict setting of "kubectl config settings":
+
+		    * skip, the default: the existing entry is kept
+		    * overwrite: the existing entry is replaced, like --overwrite
+		    * suffix: the entry is imported as NAME-2, NAME-3...
+		    * source-prefix: the entry is imported as SOURCE-NAME, SOURCE being the file name
+		    * hash: the entry is imported as NAME-HASH, HASH being made of its content
+		    * prompt: ask for every conflict
+
+		Renamed clusters and users are renamed in the contexts imported along with them. Apart from
+		prompt, the strategies give the same result every time, which makes them fit for CI.
+
+		An imported cluster pointing at the same server, with the same certificate authority, as a
+		cluster of another name is not imported: the contexts imported along with it use the
+		existing cluster instead, unless --allow-duplicate-server is given.
+
+		Imported GKE contexts
+		are tagged with their project, location and cluster, see "kubectl config enrich". The
+		clusters of kind, k3d and minikube are imported with "kubectl config import local", Talos,
+		k0s and kubeadm clusters with "kubectl config import talos", "kubectl config import k0s" and
+		"kubectl config import kubeadm", and the
+		workload clusters of a Cluster API management cluster with "kubectl config import capi",
+		vclusters with "kubectl config import vcluster" and the kubeconfigs kept in Secrets of
+		another cluster with "kubectl config import secret".
+
+		With --signature-key, a source is only imported if it comes with a valid signature made by
+		"kubectl config sign", in a file named like the source followed by .sig.
+
+		With --sync, the sources the entries of the kubeconfig were imported from, as recorded in
+		their provenance (see "kubectl config provenance"), are imported again to reconcile drift:
+		imported clusters get the current server and certificate authority of their source, new
+		entries are added, such as the EKS clusters created since in the same region, and the
+		entries of clusters or files that are gone are removed. Entries that were not imported
+		from the source are left alone, as are the entries of sources that cannot be reached.
+		--from only syncs one kind of source. The clusters of kind, k3d and minikube, Cluster API,
+		vclusters and the sources of "kubectl config source" are synced by their own import.
+
+		With --clipboard, the kubeconfig on the clipboard is imported, as copied by "kubectl config
+		export --clipboard", with pbpaste on macOS, wl-paste on Wayland, xclip or xsel on X11 and
+		PowerShell on Windows. Users whose credentials were redacted are reported, their
+		credentials have to be set again.
+
+		With --naming-template, or the namingTemplate setting, imported contexts are renamed
+		before they are merged, like "kubectl config lint --fix" renames them. Contexts the
+		template gives an empty or taken name keep theirs.`)
+
+	importExample = templates.Examples(`
+		# Import every kubeconfig in a directory
+		kubectl config import ~/Downloads/clusters/
+
+		# Retry the sources that failed during the previous import
+		kubectl config import ~/Downloads/clusters/ --resume
+
+		# Update the endpoints of the EKS clusters imported earlier and remove the deleted ones
+		kubectl config import --sync --from eks
+
+		# Import the kubeconfig a colleague pasted in a chat, after copying it
+		kubectl config import --clipboard --on-conflict=suffix`)
+)
+
+// NewCmdConfigImport returns a Command instance for 'config import' sub command
+func NewCmdConfigImport(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &ImportOptions{
+		ConfigAccess: configAccess,
+		BatchSize:    defaultImportBatchSize,
+		RunCommand:   runCommand,
+		RunSSH:       runSSH,
+
+		PasteFromClipboard: pasteFromClipboard,
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "import (SOURCE... [--batch-size=N] [--resume] [--on-conflict=STRATEGY] [--naming-template=TEMPLATE] | --clipboard [--on-conflict=STRATEGY] [--naming-template=TEMPLATE] | --sync [--from=SOURCE])",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Merge clusters, users and contexts from other kubeconfig files"),
+		Long:                  importLong,
+		Example:               importExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().IntVar(&o.BatchSize, "batch-size", o.BatchSize, "Number of merged entries after which the kubeconfig is written")
+	cmd.Flags().BoolVar(&o.Resume, "resume", o.Resume, "If true, skip the sources the previous import already wrote")
+	cmd.Flags().BoolVar(&o.Overwrite, "overwrite", o.Overwrite, "If true, replace existing entries of the same name. Same as --on-conflict=overwrite")
+	cmd.Flags().StringVar(&o.OnConflict, "on-conflict", o.OnConflict, "What to do with entries whose name is taken: skip, overwrite, suffix, source-prefix, hash or prompt. Defaults to the onConflict setting, or skip")
+	cmd.Flags().StringVar(&o.CheckpointFile, "checkpoint-file", o.CheckpointFile, "Where import progress is recorded. Defaults to a file in the kubecfg state directory")
+	cmd.Flags().BoolVar(&o.AllowDuplicateServer, flagAllowDuplicateServer, o.AllowDuplicateServer, "If true, import clusters pointing at the same server and certificate authority as an existing cluster instead of reusing it")
+	cmd.Flags().StringVar(&o.SignatureKey, "signature-key", o.SignatureKey, "If set, only import sources with a valid signature in SOURCE.sig made with the private key of this PEM encoded public key")
+	cmd.Flags().BoolVar(&o.Sync, "sync", o.Sync, "If true, import again the sources existing entries were imported from, updating, adding and removing entries")
+	cmd.Flags().StringVar(&o.From, "from", o.From, "With --sync, only sync the sources of this kind, such as file, eks, gke, aks or kind")
+	cmd.Flags().BoolVar(&o.Clipboard, "clipboard", o.Clipboard, "If true, import the kubeconfig on the clipboard")
+	cmd.Flags().StringVar(&o.NamingTemplate, "naming-template", o.NamingTemplate, "Go template imported contexts are renamed with, the namingTemplate setting by default")
+
+	cmd.AddCommand(NewCmdConfigImportLocal(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigImportTalos(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigImportK0s(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigImportKubeadm(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigImportCAPI(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigImportVCluster(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigImportSecret(streams, configAccess))
+	cmd.AddCommand(NewCmdConfigImportKubectxState(streams, configAccess))
+	return cmd
+}
+
+// Complete expands the source arguments
+func (o *ImportOptions) Complete(cmd *cobra.Command, args []string) error {
+	if o.Sync {
+		if len(args) > 0 {
+			return helpErrorf(cmd, "--sync imports again the sources entries were imported from, it takes no SOURCE")
+		}
+		if o.Clipboard {
+			return helpErrorf(cmd, "--clipboard cannot be combined with --sync")
+		}
+		if o.From != "file" {
+			if err := requireNetwork(cmd); err != nil {
+				return err
+			}
+		}
+		var err error
+		o.log, err = newCmdLogger(cmd, o.ErrOut)
+		return err
+	}
+	if len(o.From) > 0 {
+		return helpErrorf(cmd, "--from requires --sync")
+	}
+	o.Confirm = newChangeConfirmer(cmd, o.ConfigAccess, o.In, o.ErrOut)
+	var err error
+	if o.Clipboard {
+		if len(args) > 0 || o.Resume || len(o.SignatureKey) > 0 {
+			return helpErrorf(cmd, "--clipboard takes no SOURCE, and cannot be combined with --resume or --signature-key")
+		}
+	} else {
+		if len(args) == 0 {
+			return helpErrorf(cmd, "Unexpected args: %v", args)
+		}
+		if o.Sources, err = expandImportSources(args); err != nil {
+			return err
+		}
+	}
+	if o.log, err = newCmdLogger(cmd, o.ErrOut); err != nil {
+		return err
+	}
+	if len(o.CheckpointFile) == 0 {
+		o.CheckpointFile = filepath.Join(stateDir(), "import-checkpoint.json")
+	}
+	if o.Overwrite {
+		if len(o.OnConflict) > 0 && o.OnConflict != conflictOverwrite {
+			return helpErrorf(cmd, "--overwrite cannot be combined with --on-conflict=%s", o.OnConflict)
+		}
+		o.OnConflict = conflictOverwrite
+	}
+	if len(o.OnConflict) == 0 || len(o.NamingTemplate) == 0 {
+		settings, err := loadSettings(settingsFile())
+		if err != nil {
+			return err
+		}
+		if len(o.OnConflict) == 0 {
+			o.OnConflict = settings.OnConflict
+		}
+		if len(o.NamingTemplate) == 0 {
+			o.NamingTemplate = settings.NamingTemplate
+		}
+	}
+	if len(o.SignatureKey) > 0 {
+		data, err := ioutil.ReadFile(o.SignatureKey)
+		if err != nil {
+			return err
+		}
+		if o.signatureKey, err = parseVerificationKey(data); err != nil {
+			return fmt.Errorf("%s: %v", o.SignatureKey, err)
+		}
+	}
+	return nil
+}
+
+// Validate makes sure that provided values for command-line options are valid
+func (o *ImportOptions) Validate() error {
+	if o.Sync {
+		return nil
+	}
+	if len(o.Sources) == 0 && !o.Clipboard {
+		return errors.New("no kubeconfig files to import")
+	}
+	if o.BatchSize < 1 {
+		return fmt.Errorf("--batch-size must be at least 1, got %d", o.BatchSize)
+	}
+	if len(o.NamingTemplate) > 0 {
+		var err error
+		if o.naming, err = template.New("naming").Option("missingkey=error").Parse(o.NamingTemplate); err != nil {
+			return fmt.Errorf("invalid naming template: %v", err)
+		}
+	}
+	var err error
+	o.resolve, err = newConflictStrategy(o.OnConflict, o.IOStreams)
+	return err
+}
+
+// nameImportedContexts renames the contexts of an imported kubeconfig with the naming template
+// before they are merged, and returns the renames made. Contexts the template gives an empty
+// name, or the name of another context of the kubeconfig, keep theirs.
+func nameImportedContexts(tmpl *template.Template, from *clientcmdapi.Config) ([]string, error) {
+	if tmpl == nil {
+		return nil, nil
+	}
+	renamed := []string{}
+	for _, name := range sortedContextNames(from.Contexts) {
+		newName, err := lintContextName(tmpl, from, name)
+		if err != nil {
+			return nil, fmt.Errorf("naming context %q: %v", name, err)
+		}
+		if len(newName) == 0 || newName == name || from.Contexts[newName] != nil {
+			continue
+		}
+		renameContext(from, name, newName)
+		renamed = append(renamed, fmt.Sprintf("context %q as %q", name, newName))
+	}
+	return renamed, nil
+}
+
+// Run performs the execution of 'config import' sub command
+func (o *ImportOptions) Run() error {
+	if o.Sync {
+		return o.RunSync()
+	}
+	if o.Clipboard {
+		return o.runClipboard()
+	}
+	log := o.log
+	if log == nil {
+		log, _ = newCmdLogger(nil, o.ErrOut)
+	}
+	destination := o.ConfigAccess.GetDefaultFilename()
+	if o.ConfigAccess.IsExplicitFile() {
+		destination = o.ConfigAccess.GetExplicitFile()
+	}
+
+	checkpoint := &importCheckpoint{Destination: destination}
+	if o.Resume {
+		previous, err := readImportCheckpoint(o.CheckpointFile)
+		if err != nil {
+			return err
+		}
+		if previous != nil {
+			if previous.Destination != destination {
+				return fmt.Errorf("the checkpoint in %s belongs to an import into %s, not %s", o.CheckpointFile, previous.Destination, destination)
+			}
+			checkpoint = previous
+		}
+	}
+	completed := map[string]bool{}
+	for _, source := range checkpoint.Completed {
+		completed[source] = true
+	}
+	pending := []string{}
+	for _, source := range o.Sources {
+		if !completed[source] {
+			pending = append(pending, source)
+		}
+	}
+	if skipped := len(o.Sources) - len(pending); skipped > 0 {
+		log.Infof(0, "Skipping %d source(s) already imported.", skipped)
+	}
+
+	resolve := o.resolve
+	if resolve == nil {
+		strategy := o.OnConflict
+		if o.Overwrite {
+			strategy = conflictOverwrite
+		}
+		var err error
+		if resolve, err = newConflictStrategy(strategy, o.IOStreams); err != nil {
+			return err
+		}
+	}
+
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	resolve = confirmReplacements(resolve, config, o.Confirm)
+
+	done := make(chan struct{})
+	defer close(done)
+	var verify func(string) error
+	if o.signatureKey != nil {
+		verify = func(source string) error {
+			return verifyBundleSignature(source, source+signatureSuffix, o.signatureKey)
+		}
+	}
+	parsed := streamImportSources(pending, verify, done)
+
+	unwritten := []string{}
+	unwrittenEntries := 0
+	flush := func() error {
+		if len(unwritten) == 0 {
+			return nil
+		}
+		log.Infof(2, "Writing %d entries from %d source(s) to %s", unwrittenEntries, len(unwritten), destination)
+		if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+			return err
+		}
+		checkpoint.Completed = append(checkpoint.Completed, unwritten...)
+		unwritten, unwrittenEntries = nil, 0
+		return writeImportCheckpoint(o.CheckpointFile, checkpoint)
+	}
+
+	failed := []string{}
+	progress := log.newProgressBar(len(pending))
+	defer progress.Finish()
+	for source := range parsed {
+		if source.err != nil {
+			failed = append(failed, source.name)
+			progress.Step(source.name, source.err.Error())
+			continue
+		}
+
+		if err := stampProvenance(source.config, "file", source.name, time.Now()); err != nil {
+			return err
+		}
+		named, err := nameImportedContexts(o.naming, source.config)
+		if err != nil {
+			return fmt.Errorf("%s: %v", source.name, err)
+		}
+		for _, rename := range named {
+			log.Infof(0, "%s: named %s", source.name, rename)
+		}
+		existing := map[string]*clientcmdapi.Context{}
+		for name := range source.config.Contexts {
+			existing[name] = config.Contexts[name]
+		}
+		if !o.AllowDuplicateServer {
+			for _, reused := range reuseDuplicateServers(config, source.config) {
+				log.Infof(0, "%s: reused %s", source.name, reused)
+			}
+		}
+		merged, err := mergeConfigWithStrategy(config, source.config, source.name, resolve)
+		if err != nil {
+			return err
+		}
+		for _, reason := range merged.Skipped {
+			log.Warningf("%s: skipped %s", source.name, reason)
+		}
+		for _, renamed := range merged.Renamed {
+			log.Infof(0, "%s: imported %s", source.name, renamed)
+		}
+		// Imported GKE contexts are tagged with their project, location and cluster.
+		for name, previous := range existing {
+			if config.Contexts[name] == previous {
+				continue
+			}
+			if _, err := enrichGKEContext(config, name); err != nil {
+				log.Warningf("%s: context %q: %v", source.name, name, err)
+			}
+		}
+		progress.Step(source.name, fmt.Sprintf("merged %d entries", merged.Added))
+
+		unwritten = append(unwritten, source.name)
+		unwrittenEntries += merged.Added
+		if unwrittenEntries >= o.BatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	if len(failed) > 0 {
+		if err := writeImportCheckpoint(o.CheckpointFile, checkpoint); err != nil {
+			return err
+		}
+		return fmt.Errorf("%d of %d sources could not be imported, fix them and rerun with --resume: %s", len(failed), len(pending), strings.Join(failed, ", "))
+	}
+	if err := os.Remove(o.CheckpointFile); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// streamImportSources parses sources in the background, one at a time, so that the next source is
+// read while the previous one is merged. A source verify rejects is reported as failed without being
+// parsed. Closing done stops the producer early.
+func streamImportSources(sources []string, verify func(string) error, done <-chan struct{}) <-chan importedSource {
+	out := make(chan importedSource, 1)
+	go func() {
+		defer close(out)
+		for _, name := range sources {
+			var config *clientcmdapi.Config
+			var err error
+			if verify != nil {
+				err = verify(name)
+			}
+			if err == nil {
+				config, err = clientcmd.LoadFromFile(name)
+			}
+			select {
+			case out <- importedSource{name: name, config: config, err: err}:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// mergeImportedConfig adds the clusters, users and contexts of from to into. Entries whose name is
+// taken are replaced with overwrite and left out otherwise. It returns the number of entries added or
+// replaced, and a reason for every entry left out.
+func mergeImportedConfig(into, from *clientcmdapi.Config, overwrite bool) (int, []string) {
+	resolve := skipConflicts
+	if overwrite {
+		resolve = overwriteConflicts
+	}
+	// Neither strategy fails.
+	result, _ := mergeConfigWithStrategy(into, from, "", resolve)
+	return result.Added, result.Skipped
+}
+
+// syncManagedEntries replaces the entries merged into config earlier, as recorded in managed, by the
+// entries of from and records the new set in managed. Entries config had of its own are never
+// replaced. It returns the number of merged entries and the number of entries removed because from
+// no longer defines them.
+func syncManagedEntries(config, from *clientcmdapi.Config, managed *managedEntries, log *cmdLogger) (int, int) {
+	removed := 0
+	for _, name := range managed.Clusters {
+		if _, ok := from.Clusters[name]; !ok {
+			removed++
+		}
+		delete(config.Clusters, name)
+	}
+	for _, name := range managed.AuthInfos {
+		if _, ok := from.AuthInfos[name]; !ok {
+			removed++
+		}
+		delete(config.AuthInfos, name)
+	}
+	for _, name := range managed.Contexts {
+		if _, ok := from.Contexts[name]; !ok {
+			removed++
+		}
+		delete(config.Contexts, name)
+	}
+
+	// Names still present belong to the kubeconfig itself and are left alone by the merge below.
+	*managed = managedEntries{}
+	for _, name := range sortedClusterNames(from.Clusters) {
+		if _, exists := config.Clusters[name]; !exists {
+			managed.Clusters = append(managed.Clusters, name)
+		}
+	}
+	for _, name := range sortedAuthInfoNames(from.AuthInfos) {
+		if _, exists := config.AuthInfos[name]; !exists {
+			managed.AuthInfos = append(managed.AuthInfos, name)
+		}
+	}
+	for _, name := range sortedContextNames(from.Contexts) {
+		if _, exists := config.Contexts[name]; !exists {
+			managed.Contexts = append(managed.Contexts, name)
+		}
+	}
+
+	added, skipped := mergeImportedConfig(config, from, false)
+	for _, reason := range skipped {
+		log.Warningf("skipped %s", reason)
+	}
+	return added, removed
+}
+
+// importManagedEntries merges the entries imported through a context into config, keeping track of
+// them per context in the named extension of the preferences. With sync, the entries imported
+// earlier are replaced and those from no longer defines are removed, see syncManagedEntries;
+// otherwise only new entries are added. It returns the number of merged and removed entries.
+func importManagedEntries(config *clientcmdapi.Config, extension, context string, from *clientcmdapi.Config, sync bool, log *cmdLogger) (int, int, error) {
+	all := managedEntriesByContext{}
+	if _, err := readExtension(config.Preferences.Extensions, extension, &all); err != nil {
+		return 0, 0, err
+	}
+	managed := all[context]
+	added, removed := 0, 0
+	if sync {
+		added, removed = syncManagedEntries(config, from, &managed, log)
+	} else {
+		var skipped []string
+		added, skipped = addManagedEntries(config, from, &managed)
+		for _, reason := range skipped {
+			log.Warningf("skipped %s, use --sync to replace it", reason)
+		}
+	}
+	all[context] = managed
+	return added, removed, writeExtension(&config.Preferences.Extensions, extension, all)
+}
+
+// addManagedEntries merges the entries of from whose name config does not use yet into config and
+// records them in managed, leaving the entries already merged earlier alone. It returns the number of
+// entries added and a reason for every entry left out.
+func addManagedEntries(config, from *clientcmdapi.Config, managed *managedEntries) (int, []string) {
+	for _, name := range sortedClusterNames(from.Clusters) {
+		if _, exists := config.Clusters[name]; !exists && !containsString(managed.Clusters, name) {
+			managed.Clusters = append(managed.Clusters, name)
+		}
+	}
+	for _, name := range sortedAuthInfoNames(from.AuthInfos) {
+		if _, exists := config.AuthInfos[name]; !exists && !containsString(managed.AuthInfos, name) {
+			managed.AuthInfos = append(managed.AuthInfos, name)
+		}
+	}
+	for _, name := range sortedContextNames(from.Contexts) {
+		if _, exists := config.Contexts[name]; !exists && !containsString(managed.Contexts, name) {
+			managed.Contexts = append(managed.Contexts, name)
+		}
+	}
+	return mergeImportedConfig(config, from, false)
+}
+
+func sortedClusterNames(clusters map[string]*clientcmdapi.Cluster) []string {
+	names := make([]string, 0, len(clusters))
+	for name := range clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedAuthInfoNames(authInfos map[string]*clientcmdapi.AuthInfo) []string {
+	names := make([]string, 0, len(authInfos))
+	for name := range authInfos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedContextNames(contexts map[string]*clientcmdapi.Context) []string {
+	names := make([]string, 0, len(contexts))
+	for name := range contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// expandImportSources turns the arguments into absolute file names, replacing a directory by the
+// regular files it contains.
+func expandImportSources(args []string) ([]string, error) {
+	sources := []string{}
+	for _, arg := range args {
+		path, err := filepath.Abs(expandPath(arg))
+		if err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			sources = append(sources, path)
+			continue
+		}
+		entries, err := ioutil.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.Mode().IsRegular() && !strings.HasPrefix(entry.Name(), ".") && !strings.HasSuffix(entry.Name(), signatureSuffix) {
+				sources = append(sources, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+	return sources, nil
+}
+
+func readImportCheckpoint(filename string) (*importCheckpoint, error) {
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	checkpoint := &importCheckpoint{}
+	if err := json.Unmarshal(data, checkpoint); err != nil {
+		return nil, fmt.Errorf("error reading import checkpoint %s: %v", filename, err)
+	}
+	return checkpoint, nil
+}
+
+func writeImportCheckpoint(filename string, checkpoint *importCheckpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0600)
+}
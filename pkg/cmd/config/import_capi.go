@@ -0,0 +1,201 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// capiClustersResource is the Cluster API resource describing a workload cluster.
+var capiClustersResource = schema.GroupVersionResource{Group: "cluster.x-k8s.io", Version: "v1beta1", Resource: "clusters"}
+
+// capiKubeconfigKey is the key of the kubeconfig in the secret Cluster API creates for every
+// workload cluster, named CLUSTER-kubeconfig.
+const capiKubeconfigKey = "value"
+
+// ImportCAPIOptions holds the command-line options for 'config import capi' sub command
+type ImportCAPIOptions struct {
+	ConfigAccess      clientcmd.ConfigAccess
+	ManagementContext string
+	Namespace         string
+	Sync              bool
+
+	DynamicClient dynamic.Interface
+	Clientset     kubernetes.Interface
+	log           *cmdLogger
+
+	genericclioptions.IOStreams
+}
+
+var (
+	importCAPILong = templates.LongDesc(`
+		Import the workload clusters of a Cluster API management cluster.
+
+		The Cluster resources of the management cluster are listed, in every namespace unless
+		--namespace is given, and the kubeconfig Secret Cluster API keeps for each of them is merged
+		under a single name, capi-CLUSTER for clusters in the default namespace and
+		capi-NAMESPACE-CLUSTER otherwise. Clusters whose kubeconfig does not exist yet are skipped.
+
+		The imported entries are remembered for the management context. With --sync, they are
+		replaced by the current kubeconfigs, which picks up rotated credentials, and the entries of
+		deleted clusters are removed. Entries of the same name the kubeconfig already had are left
+		alone.`)
+
+	importCAPIExample = templates.Examples(`
+		# Import the workload clusters managed from the mgmt context
+		kubectl config import capi --management-context mgmt
+
+		# Update rotated credentials and remove deleted clusters
+		kubectl config import capi --management-context mgmt --sync`)
+)
+
+// NewCmdConfigImportCAPI returns a Command instance for 'config import capi' sub command
+func NewCmdConfigImportCAPI(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &ImportCAPIOptions{
+		ConfigAccess: configAccess,
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "capi --management-context=CONTEXT [--namespace=NAMESPACE] [--sync]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Import the workload clusters of a Cluster API management cluster"),
+		Long:                  importCAPILong,
+		Example:               importCAPIExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(cmdutil.UsageErrorf(cmd, "unexpected arguments: %v", args))
+			}
+			cmdutil.CheckErr(requireNetwork(cmd))
+			cmdutil.CheckErr(o.Complete(cmd))
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().StringVar(&o.ManagementContext, "management-context", o.ManagementContext, "The context of the management cluster")
+	cmd.Flags().StringVarP(&o.Namespace, "namespace", "n", o.Namespace, "Only import the clusters of this namespace")
+	cmd.Flags().BoolVar(&o.Sync, "sync", o.Sync, "If true, update the imported entries and remove those of deleted clusters")
+	return cmd
+}
+
+// Complete connects to the management cluster
+func (o *ImportCAPIOptions) Complete(cmd *cobra.Command) error {
+	if len(o.ManagementContext) == 0 {
+		return helpErrorf(cmd, "--management-context is required")
+	}
+	var err error
+	if o.log, err = newCmdLogger(cmd, o.ErrOut); err != nil {
+		return err
+	}
+
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	if _, ok := config.Contexts[o.ManagementContext]; !ok {
+		return fmt.Errorf("no context exists with the name: %q", o.ManagementContext)
+	}
+	restConfig, err := clientcmd.NewNonInteractiveClientConfig(*config, o.ManagementContext, &clientcmd.ConfigOverrides{}, nil).ClientConfig()
+	if err != nil {
+		return err
+	}
+	restConfig.Timeout = 30 * time.Second
+	if o.DynamicClient, err = dynamic.NewForConfig(restConfig); err != nil {
+		return err
+	}
+	o.Clientset, err = kubernetes.NewForConfig(restConfig)
+	return err
+}
+
+// Run performs the execution of 'config import capi' sub command
+func (o *ImportCAPIOptions) Run() error {
+	if o.DynamicClient == nil || o.Clientset == nil {
+		return errors.New("not connected to the management cluster")
+	}
+	log := o.log
+	if log == nil {
+		log, _ = newCmdLogger(nil, o.ErrOut)
+	}
+
+	clusters, err := o.DynamicClient.Resource(capiClustersResource).Namespace(o.Namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("listing the clusters of %q: %v", o.ManagementContext, err)
+	}
+	imported := clientcmdapi.NewConfig()
+	found := 0
+	for _, cluster := range clusters.Items {
+		namespace, name := cluster.GetNamespace(), cluster.GetName()
+		secret, err := o.Clientset.CoreV1().Secrets(namespace).Get(name+"-kubeconfig", metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			log.Infof(0, "cluster %s/%s has no kubeconfig yet, skipped", namespace, name)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("getting the kubeconfig of cluster %s/%s: %v", namespace, name, err)
+		}
+		from, err := clientcmd.Load(secret.Data[capiKubeconfigKey])
+		if err != nil {
+			return fmt.Errorf("loading the kubeconfig of cluster %s/%s: %v", namespace, name, err)
+		}
+		if err := stampProvenance(from, "capi", o.ManagementContext+"/"+namespace+"/"+name, time.Now()); err != nil {
+			return err
+		}
+		if err := renameLocalClusterEntries(imported, from, capiEntryName(namespace, name)); err != nil {
+			return fmt.Errorf("cluster %s/%s: %v", namespace, name, err)
+		}
+		found++
+	}
+
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	added, removed, err := importManagedEntries(config, capiClustersExtension, o.ManagementContext, imported, o.Sync, log)
+	if err != nil {
+		return err
+	}
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "Merged %d entries from %d workload cluster(s), removed %d.\n", added, found, removed)
+	return nil
+}
+
+// capiEntryName is the name of the entries of a workload cluster.
+func capiEntryName(namespace, cluster string) string {
+	if namespace == metav1.NamespaceDefault {
+		return "capi-" + cluster
+	}
+	return "capi-" + namespace + "-" + cluster
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func newCAPICluster(namespace, name string) *unstructured.Unstructured {
+	cluster := &unstructured.Unstructured{}
+	cluster.SetAPIVersion("cluster.x-k8s.io/v1beta1")
+	cluster.SetKind("Cluster")
+	cluster.SetNamespace(namespace)
+	cluster.SetName(name)
+	return cluster
+}
+
+func newCAPIKubeconfigSecret(namespace, name, server string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name + "-kubeconfig"},
+		Data:       map[string][]byte{capiKubeconfigKey: []byte(localClusterKubeconfig(name+"-admin@"+name, server))},
+	}
+}
+
+func TestImportCAPI(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	if err := clientcmd.WriteToFile(newRedFederalCowHammerConfig(), fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	importCAPI := func(sync bool, clusters []runtime.Object, secrets ...runtime.Object) string {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		o := &ImportCAPIOptions{
+			ConfigAccess:      pathOptions,
+			ManagementContext: "federal-context",
+			Sync:              sync,
+			DynamicClient:     dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), clusters...),
+			Clientset:         fake.NewSimpleClientset(secrets...),
+			IOStreams:         streams,
+		}
+		if err := o.Run(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return out.String()
+	}
+	load := func() *clientcmdapi.Config {
+		config, err := clientcmd.LoadFromFile(fakeKubeFile.Name())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return config
+	}
+
+	// The cluster being provisioned has no kubeconfig yet.
+	out := importCAPI(false,
+		[]runtime.Object{newCAPICluster("default", "dev"), newCAPICluster("team-a", "prod"), newCAPICluster("team-a", "new")},
+		newCAPIKubeconfigSecret("default", "dev", "https://dev.example.com:6443"),
+		newCAPIKubeconfigSecret("team-a", "prod", "https://prod.example.com:6443"))
+	if out != "Merged 6 entries from 2 workload cluster(s), removed 0.\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+	config := load()
+	if names := sortedContextNames(config.Contexts); !reflect.DeepEqual(names, []string{"capi-dev", "capi-team-a-prod", "federal-context"}) {
+		t.Errorf("unexpected contexts %v", names)
+	}
+
+	// The credentials of dev were rotated and prod was deleted.
+	out = importCAPI(true,
+		[]runtime.Object{newCAPICluster("default", "dev")},
+		newCAPIKubeconfigSecret("default", "dev", "https://dev-2.example.com:6443"))
+	if out != "Merged 3 entries from 1 workload cluster(s), removed 3.\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+	config = load()
+	if names := sortedContextNames(config.Contexts); !reflect.DeepEqual(names, []string{"capi-dev", "federal-context"}) {
+		t.Errorf("unexpected contexts %v", names)
+	}
+	if server := config.Clusters["capi-dev"].Server; server != "https://dev-2.example.com:6443" {
+		t.Errorf("expected the rotated kubeconfig, got server %q", server)
+	}
+}
+
+func TestCAPIEntryName(t *testing.T) {
+	if name := capiEntryName("default", "dev"); name != "capi-dev" {
+		t.Errorf("unexpected name %q", name)
+	}
+	if name := capiEntryName("team-a", "dev"); name != "capi-team-a-dev" {
+		t.Errorf("unexpected name %q", name)
+	}
+}
@@ -0,0 +1,310 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// ImportTalosOptions holds the command-line options for 'config import talos' sub command
+type ImportTalosOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Talosconfig  string
+	Nodes        []string
+	Name         string
+	Overwrite    bool
+
+	RunCommand commandRunner
+	log        *cmdLogger
+
+	genericclioptions.IOStreams
+}
+
+// ImportK0sOptions holds the command-line options for 'config import k0s' sub command
+type ImportK0sOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	SSH          string
+	Name         string
+	Overwrite    bool
+
+	RunCommand commandRunner
+	log        *cmdLogger
+
+	genericclioptions.IOStreams
+}
+
+var (
+	importTalosLong = templates.LongDesc(`
+		Import the admin kubeconfig of a Talos Linux cluster.
+
+		The kubeconfig is retrieved from the Talos API with talosctl, using the given talosconfig
+		and nodes, and its cluster, user and context are merged under a single name, talos-CLUSTER
+		by default. Entries whose name already exists are kept unless --overwrite is given.`)
+
+	importTalosExample = templates.Examples(`
+		# Import the cluster a control plane node belongs to
+		kubectl config import talos --talosconfig ./talosconfig --nodes 10.5.0.2`)
+
+	importK0sLong = templates.LongDesc(`
+		Import the admin kubeconfig of a k0s cluster.
+
+		The kubeconfig is generated with "k0s kubeconfig admin" on a controller reached over ssh,
+		and the server, which k0s sets to localhost, is replaced by the address of the controller.
+		Its cluster, user and context are merged under a single name, k0s-HOST by default. Entries
+		whose name already exists are kept unless --overwrite is given.`)
+
+	importK0sExample = templates.Examples(`
+		# Import the cluster of a controller, named k0s-prod
+		kubectl config import k0s --ssh admin@controller-1.example.com --name k0s-prod`)
+)
+
+// NewCmdConfigImportTalos returns a Command instance for 'config import talos' sub command
+func NewCmdConfigImportTalos(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &ImportTalosOptions{
+		ConfigAccess: configAccess,
+		RunCommand:   runCommand,
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "talos --talosconfig=FILE [--nodes=NODE] [--name=NAME] [--overwrite]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Import the kubeconfig of a Talos Linux cluster"),
+		Long:                  importTalosLong,
+		Example:               importTalosExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(cmdutil.UsageErrorf(cmd, "unexpected arguments: %v", args))
+			}
+			cmdutil.CheckErr(requireNetwork(cmd))
+			var err error
+			o.log, err = newCmdLogger(cmd, o.ErrOut)
+			cmdutil.CheckErr(err)
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().StringVar(&o.Talosconfig, "talosconfig", o.Talosconfig, "Path to the talosconfig of the cluster")
+	cmd.Flags().StringSliceVar(&o.Nodes, "nodes", o.Nodes, "Control plane nodes to retrieve the kubeconfig from. Defaults to the nodes of the talosconfig")
+	cmd.Flags().StringVar(&o.Name, "name", o.Name, "Name of the imported entries. Defaults to talos-CLUSTER")
+	cmd.Flags().BoolVar(&o.Overwrite, "overwrite", o.Overwrite, "If true, replace existing entries of the same name")
+	return cmd
+}
+
+// Validate makes sure a talosconfig was given
+func (o *ImportTalosOptions) Validate() error {
+	if len(o.Talosconfig) == 0 {
+		return errors.New("--talosconfig is required")
+	}
+	return nil
+}
+
+// Run performs the execution of 'config import talos' sub command
+func (o *ImportTalosOptions) Run() error {
+	from, err := o.fetch()
+	if err != nil {
+		return err
+	}
+
+	name := o.Name
+	if len(name) == 0 {
+		context, ok := from.Contexts[from.CurrentContext]
+		if !ok {
+			return fmt.Errorf("the kubeconfig has no current context")
+		}
+		name = localClusterEntryName("talos", context.Cluster)
+	}
+	// The talosconfig is recorded absolute, so that "config import --sync" finds it from anywhere.
+	talosconfig, err := filepath.Abs(o.Talosconfig)
+	if err != nil {
+		return err
+	}
+	if err := stampProvenance(from, "talos", talosconfig, time.Now()); err != nil {
+		return err
+	}
+	return importClusterEntries(o.ConfigAccess, from, name, o.Overwrite, o.log, o.IOStreams)
+}
+
+// fetch retrieves the kubeconfig of the cluster with talosctl.
+func (o *ImportTalosOptions) fetch() (*clientcmdapi.Config, error) {
+	args := []string{"kubeconfig", "-", "--talosconfig", o.Talosconfig}
+	if len(o.Nodes) > 0 {
+		args = append(args, "--nodes", strings.Join(o.Nodes, ","))
+	}
+	data, err := o.RunCommand(nil, "talosctl", args...)
+	if err != nil {
+		return nil, fmt.Errorf("retrieving the kubeconfig with talosctl: %v", err)
+	}
+	return clientcmd.Load(data)
+}
+
+// NewCmdConfigImportK0s returns a Command instance for 'config import k0s' sub command
+func NewCmdConfigImportK0s(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &ImportK0sOptions{
+		ConfigAccess: configAccess,
+		RunCommand:   runCommand,
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "k0s --ssh=[USER@]HOST [--name=NAME] [--overwrite]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Import the kubeconfig of a k0s cluster over ssh"),
+		Long:                  importK0sLong,
+		Example:               importK0sExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(cmdutil.UsageErrorf(cmd, "unexpected arguments: %v", args))
+			}
+			cmdutil.CheckErr(requireNetwork(cmd))
+			var err error
+			o.log, err = newCmdLogger(cmd, o.ErrOut)
+			cmdutil.CheckErr(err)
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().StringVar(&o.SSH, "ssh", o.SSH, "The controller to connect to with ssh, as [USER@]HOST")
+	cmd.Flags().StringVar(&o.Name, "name", o.Name, "Name of the imported entries. Defaults to k0s-HOST")
+	cmd.Flags().BoolVar(&o.Overwrite, "overwrite", o.Overwrite, "If true, replace existing entries of the same name")
+	return cmd
+}
+
+// Validate makes sure a controller was given
+func (o *ImportK0sOptions) Validate() error {
+	if len(o.SSH) == 0 {
+		return errors.New("--ssh is required")
+	}
+	return checkSSHDestination(o.SSH)
+}
+
+// Run performs the execution of 'config import k0s' sub command
+func (o *ImportK0sOptions) Run() error {
+	from, host, err := o.fetch()
+	if err != nil {
+		return err
+	}
+
+	name := o.Name
+	if len(name) == 0 {
+		name = localClusterEntryName("k0s", host)
+	}
+	if err := stampProvenance(from, "k0s", o.SSH, time.Now()); err != nil {
+		return err
+	}
+	return importClusterEntries(o.ConfigAccess, from, name, o.Overwrite, o.log, o.IOStreams)
+}
+
+// fetch generates an admin kubeconfig on the controller, pointing its servers at the controller. It
+// returns the kubeconfig and the host of the controller.
+func (o *ImportK0sOptions) fetch() (*clientcmdapi.Config, string, error) {
+	if err := checkSSHDestination(o.SSH); err != nil {
+		return nil, "", err
+	}
+	data, err := o.RunCommand(nil, "ssh", o.SSH, "sudo", "k0s", "kubeconfig", "admin")
+	if err != nil {
+		return nil, "", fmt.Errorf("generating the kubeconfig on %s: %v", o.SSH, err)
+	}
+	from, err := clientcmd.Load(data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	host := o.SSH[strings.LastIndex(o.SSH, "@")+1:]
+	for name, cluster := range from.Clusters {
+		server, err := replaceServerHost(cluster.Server, host)
+		if err != nil {
+			return nil, "", fmt.Errorf("cluster %q: %v", name, err)
+		}
+		cluster.Server = server
+	}
+	return from, host, nil
+}
+
+// replaceServerHost replaces the host of a server URL, keeping its scheme, port and path.
+func replaceServerHost(server, host string) (string, error) {
+	u, err := url.Parse(server)
+	if err != nil {
+		return "", err
+	}
+	if port := u.Port(); len(port) > 0 {
+		u.Host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		u.Host = "[" + host + "]"
+	} else {
+		u.Host = host
+	}
+	return u.String(), nil
+}
+
+// importClusterEntries merges the current context of from, with its cluster and user, into the
+// kubeconfig under name.
+func importClusterEntries(configAccess clientcmd.ConfigAccess, from *clientcmdapi.Config, name string, overwrite bool, log *cmdLogger, streams genericclioptions.IOStreams) error {
+	if log == nil {
+		log, _ = newCmdLogger(nil, streams.ErrOut)
+	}
+	renamed := clientcmdapi.NewConfig()
+	if err := renameLocalClusterEntries(renamed, from, name); err != nil {
+		return err
+	}
+
+	config, err := configAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	added, skipped := mergeImportedConfig(config, renamed, overwrite)
+	for _, reason := range skipped {
+		log.Warningf("skipped %s, use --overwrite to replace it", reason)
+	}
+	if added == 0 {
+		fmt.Fprintf(streams.Out, "Nothing imported for %q.\n", name)
+		return nil
+	}
+	if err := clientcmd.ModifyConfig(configAccess, *config, true); err != nil {
+		return err
+	}
+	fmt.Fprintf(streams.Out, "Imported %d entries as %q.\n", added, name)
+	return nil
+}
+
+// checkSSHDestination returns an error unless destination is a [USER@]HOST ssh cannot take for one
+// of its options, such as -oProxyCommand=..., which would run a local command.
+func checkSSHDestination(destination string) error {
+	if len(destination) == 0 || strings.HasPrefix(destination, "-") || strings.ContainsAny(destination, " \t\r\n") {
+		return fmt.Errorf("invalid ssh destination %q, must be [USER@]HOST", destination)
+	}
+	return nil
+}
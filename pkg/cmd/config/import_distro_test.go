@@ -0,0 +1,119 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func TestImportTalosAndK0s(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	if err := clientcmd.WriteToFile(newRedFederalCowHammerConfig(), fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	talosKubeconfig := strings.Replace(localClusterKubeconfig("homelab", "https://10.5.0.2:6443"), "current-context: homelab", "current-context: admin@homelab", 1)
+	talosKubeconfig = strings.Replace(talosKubeconfig, "contexts:\n- name: homelab", "contexts:\n- name: admin@homelab", 1)
+	outputs := map[string]string{
+		"talosctl kubeconfig - --talosconfig ./talosconfig --nodes 10.5.0.2,10.5.0.3": talosKubeconfig,
+		"ssh root@controller-1 sudo k0s kubeconfig admin":                             localClusterKubeconfig("Default", "https://localhost:6443"),
+	}
+	run := func(env []string, name string, args ...string) ([]byte, error) {
+		command := strings.Join(append([]string{name}, args...), " ")
+		output, ok := outputs[command]
+		if !ok {
+			return nil, fmt.Errorf("unexpected command %q", command)
+		}
+		return []byte(output), nil
+	}
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	talos := &ImportTalosOptions{ConfigAccess: pathOptions, Talosconfig: "./talosconfig", Nodes: []string{"10.5.0.2", "10.5.0.3"}, RunCommand: run, IOStreams: streams}
+	if err := talos.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "Imported 3 entries as \"talos-homelab\".\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+
+	streams, _, out, _ = genericclioptions.NewTestIOStreams()
+	k0s := &ImportK0sOptions{ConfigAccess: pathOptions, SSH: "root@controller-1", RunCommand: run, IOStreams: streams}
+	if err := k0s.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "Imported 3 entries as \"k0s-controller-1\".\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+
+	config, err := clientcmd.LoadFromFile(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if context := config.Contexts["talos-homelab"]; context == nil || context.Cluster != "talos-homelab" || context.AuthInfo != "talos-homelab" {
+		t.Errorf("expected the talos entries under one name, got %#v", context)
+	}
+	if server := config.Clusters["k0s-controller-1"].Server; server != "https://controller-1:6443" {
+		t.Errorf("expected the server to point at the controller, got %q", server)
+	}
+
+	// Importing again changes nothing.
+	streams, _, out, _ = genericclioptions.NewTestIOStreams()
+	k0s.IOStreams = streams
+	if err := k0s.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "Nothing imported for \"k0s-controller-1\".\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+
+	if err := (&ImportTalosOptions{}).Validate(); err == nil {
+		t.Errorf("expected an error without --talosconfig")
+	}
+	if err := (&ImportK0sOptions{}).Validate(); err == nil {
+		t.Errorf("expected an error without --ssh")
+	}
+}
+
+func TestReplaceServerHost(t *testing.T) {
+	for _, test := range []struct{ server, host, expected string }{
+		{"https://localhost:6443", "10.0.0.1", "https://10.0.0.1:6443"},
+		{"https://localhost:6443/k8s", "fd00::1", "https://[fd00::1]:6443/k8s"},
+		{"https://localhost", "fd00::1", "https://[fd00::1]"},
+	} {
+		server, err := replaceServerHost(test.server, test.host)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if server != test.expected {
+			t.Errorf("expected %q, got %q", test.expected, server)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	certificatesv1beta1 "k8s.io/api/certificates/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// kubeadmAdminConf is where kubeadm writes the admin kubeconfig on control plane nodes.
+const kubeadmAdminConf = "/etc/kubernetes/admin.conf"
+
+// kubeadmCSRTimeout is how long the certificate of a scoped user is waited for once its request is
+// approved.
+const kubeadmCSRTimeout = time.Minute
+
+// ImportKubeadmOptions holds the command-line options for 'config import kubeadm' sub command
+type ImportKubeadmOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	SSH          string
+	Name         string
+	User         string
+	Groups       []string
+	Overwrite    bool
+
+	RunCommand commandRunner
+	// Clientset is connected with the admin credentials when nil.
+	Clientset kubernetes.Interface
+	log       *cmdLogger
+
+	genericclioptions.IOStreams
+}
+
+var (
+	importKubeadmLong = templates.LongDesc(`
+		Import the admin kubeconfig of a kubeadm cluster.
+
+		The kubeconfig is read from /etc/kubernetes/admin.conf on a control plane node reached over
+		ssh, and a server on the loopback address is replaced by the address of the node. Its
+		cluster, user and context are merged under a single name, kubeadm-HOST by default. Entries
+		whose name already exists are kept unless --overwrite is given.
+
+		With --user, the admin credentials are only used to get a client certificate for that user,
+		and for the --group groups, through a certificate signing request approved right away, and
+		that certificate is imported instead. The user has no permissions until they are granted
+		with RBAC, to the user or to one of its groups.`)
+
+	importKubeadmExample = templates.Examples(`
+		# Import the admin credentials of a kubeadm cluster
+		kubectl config import kubeadm --ssh root@node-1.example.com
+
+		# Import the credentials of a user in the developers group instead, named lab
+		kubectl config import kubeadm --ssh root@node-1.example.com --user jane --group developers --name lab`)
+)
+
+// NewCmdConfigImportKubeadm returns a Command instance for 'config import kubeadm' sub command
+func NewCmdConfigImportKubeadm(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &ImportKubeadmOptions{
+		ConfigAccess: configAccess,
+		RunCommand:   runCommand,
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "kubeadm --ssh=[USER@]HOST [--user=USER [--group=GROUP...]] [--name=NAME] [--overwrite]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Import the kubeconfig of a kubeadm cluster over ssh"),
+		Long:                  importKubeadmLong,
+		Example:               importKubeadmExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(cmdutil.UsageErrorf(cmd, "unexpected arguments: %v", args))
+			}
+			cmdutil.CheckErr(requireNetwork(cmd))
+			var err error
+			o.log, err = newCmdLogger(cmd, o.ErrOut)
+			cmdutil.CheckErr(err)
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().StringVar(&o.SSH, "ssh", o.SSH, "The control plane node to connect to with ssh, as [USER@]HOST")
+	cmd.Flags().StringVar(&o.Name, "name", o.Name, "Name of the imported entries. Defaults to kubeadm-HOST")
+	cmd.Flags().StringVar(&o.User, "user", o.User, "If set, import a client certificate for this user instead of the admin credentials")
+	cmd.Flags().StringSliceVar(&o.Groups, "group", o.Groups, "Groups of the user given with --user")
+	cmd.Flags().BoolVar(&o.Overwrite, "overwrite", o.Overwrite, "If true, replace existing entries of the same name")
+	return cmd
+}
+
+// Validate makes sure a node was given, and groups only with a user
+func (o *ImportKubeadmOptions) Validate() error {
+	if len(o.SSH) == 0 {
+		return errors.New("--ssh is required")
+	}
+	if err := checkSSHDestination(o.SSH); err != nil {
+		return err
+	}
+	if len(o.Groups) > 0 && len(o.User) == 0 {
+		return errors.New("--group requires --user")
+	}
+	return nil
+}
+
+// Run performs the execution of 'config import kubeadm' sub command
+func (o *ImportKubeadmOptions) Run() error {
+	from, host, err := o.fetch()
+	if err != nil {
+		return err
+	}
+	if len(o.User) > 0 {
+		if err := o.replaceAdminCredentials(from); err != nil {
+			return err
+		}
+	}
+	name := o.Name
+	if len(name) == 0 {
+		name = localClusterEntryName("kubeadm", host)
+	}
+	if err := stampProvenance(from, "kubeadm", o.SSH, time.Now()); err != nil {
+		return err
+	}
+	return importClusterEntries(o.ConfigAccess, from, name, o.Overwrite, o.log, o.IOStreams)
+}
+
+// fetch reads the admin kubeconfig of the control plane node, pointing its loopback servers at the
+// node. It returns the kubeconfig and the host of the node.
+func (o *ImportKubeadmOptions) fetch() (*clientcmdapi.Config, string, error) {
+	if err := checkSSHDestination(o.SSH); err != nil {
+		return nil, "", err
+	}
+	data, err := o.RunCommand(nil, "ssh", o.SSH, "sudo", "cat", kubeadmAdminConf)
+	if err != nil {
+		return nil, "", fmt.Errorf("reading %s on %s: %v", kubeadmAdminConf, o.SSH, err)
+	}
+	from, err := clientcmd.Load(data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	host := o.SSH[strings.LastIndex(o.SSH, "@")+1:]
+	for name, cluster := range from.Clusters {
+		if !isLoopbackServer(cluster.Server) {
+			continue
+		}
+		server, err := replaceServerHost(cluster.Server, host)
+		if err != nil {
+			return nil, "", fmt.Errorf("cluster %q: %v", name, err)
+		}
+		cluster.Server = server
+	}
+	return from, host, nil
+}
+
+// replaceAdminCredentials replaces the user of the current context of the admin kubeconfig by a
+// client certificate for o.User, signed by the cluster through a certificate signing request.
+func (o *ImportKubeadmOptions) replaceAdminCredentials(admin *clientcmdapi.Config) error {
+	context, ok := admin.Contexts[admin.CurrentContext]
+	if !ok {
+		return fmt.Errorf("the kubeconfig has no current context")
+	}
+	clientset := o.Clientset
+	if clientset == nil {
+		restConfig, err := clientcmd.NewNonInteractiveClientConfig(*admin, admin.CurrentContext, &clientcmd.ConfigOverrides{}, nil).ClientConfig()
+		if err != nil {
+			return err
+		}
+		restConfig.Timeout = 30 * time.Second
+		if clientset, err = kubernetes.NewForConfig(restConfig); err != nil {
+			return err
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	request, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: o.User, Organization: o.Groups},
+	}, key)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	csrs := clientset.CertificatesV1beta1().CertificateSigningRequests()
+	csr, err := csrs.Create(&certificatesv1beta1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: kubeadmCSRName(o.User, time.Now())},
+		Spec: certificatesv1beta1.CertificateSigningRequestSpec{
+			Request: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: request}),
+			Usages: []certificatesv1beta1.KeyUsage{
+				certificatesv1beta1.UsageDigitalSignature,
+				certificatesv1beta1.UsageKeyEncipherment,
+				certificatesv1beta1.UsageClientAuth,
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("requesting a certificate for %q: %v", o.User, err)
+	}
+	csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1beta1.CertificateSigningRequestCondition{
+		Type:           certificatesv1beta1.CertificateApproved,
+		Reason:         "KubectlConfigImport",
+		Message:        "This CSR was approved by kubectl config import kubeadm.",
+		LastUpdateTime: metav1.Now(),
+	})
+	if _, err := csrs.UpdateApproval(csr); err != nil {
+		return fmt.Errorf("approving the certificate of %q: %v", o.User, err)
+	}
+
+	var certificate []byte
+	err = wait.PollImmediate(time.Second, kubeadmCSRTimeout, func() (bool, error) {
+		csr, err := csrs.Get(csr.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, condition := range csr.Status.Conditions {
+			if condition.Type == certificatesv1beta1.CertificateDenied {
+				return false, fmt.Errorf("the certificate of %q was denied: %s", o.User, condition.Message)
+			}
+		}
+		certificate = csr.Status.Certificate
+		return len(certificate) > 0, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return fmt.Errorf("the certificate signing request %s was not signed within %v", csr.Name, kubeadmCSRTimeout)
+	}
+	if err != nil {
+		return err
+	}
+
+	admin.AuthInfos[context.AuthInfo] = &clientcmdapi.AuthInfo{
+		ClientCertificateData: certificate,
+		ClientKeyData:         pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+	return nil
+}
+
+// kubeadmCSRName is the name of the certificate signing request of a user, which must be a DNS
+// subdomain.
+func kubeadmCSRName(user string, now time.Time) string {
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '.' {
+			return r
+		}
+		return '-'
+	}, strings.ToLower(user))
+	return fmt.Sprintf("kubecfg-%s-%d", strings.Trim(name, "-."), now.Unix())
+}
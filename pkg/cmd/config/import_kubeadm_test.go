@@ -0,0 +1,120 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	certificatesv1beta1 "k8s.io/api/certificates/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func TestImportKubeadm(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	if err := clientcmd.WriteToFile(newRedFederalCowHammerConfig(), fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	run := func(env []string, name string, args ...string) ([]byte, error) {
+		if command := strings.Join(append([]string{name}, args...), " "); command != "ssh root@node-1 sudo cat /etc/kubernetes/admin.conf" {
+			return nil, fmt.Errorf("unexpected command %q", command)
+		}
+		return []byte(localClusterKubeconfig("kubernetes-admin@kubernetes", "https://127.0.0.1:6443")), nil
+	}
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := &ImportKubeadmOptions{ConfigAccess: pathOptions, SSH: "root@node-1", RunCommand: run, IOStreams: streams}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "Imported 3 entries as \"kubeadm-node-1\".\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+
+	// The cluster signs the request once it is approved.
+	var request *x509.CertificateRequest
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("update", "certificatesigningrequests", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		csr := action.(clienttesting.UpdateAction).GetObject().(*certificatesv1beta1.CertificateSigningRequest)
+		block, _ := pem.Decode(csr.Spec.Request)
+		if request, err = x509.ParseCertificateRequest(block.Bytes); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		csr.Status.Certificate = []byte("signed certificate")
+		return false, nil, nil
+	})
+	streams, _, out, _ = genericclioptions.NewTestIOStreams()
+	o = &ImportKubeadmOptions{ConfigAccess: pathOptions, SSH: "root@node-1", Name: "lab", User: "jane", Groups: []string{"developers"}, RunCommand: run, Clientset: clientset, IOStreams: streams}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "Imported 3 entries as \"lab\".\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+	if request == nil || request.Subject.CommonName != "jane" || !reflect.DeepEqual(request.Subject.Organization, []string{"developers"}) {
+		t.Errorf("unexpected certificate request %v", request)
+	}
+
+	config, err := clientcmd.LoadFromFile(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cluster := config.Clusters["kubeadm-node-1"]; cluster == nil || cluster.Server != "https://node-1:6443" {
+		t.Errorf("expected the loopback server to be replaced by the node, got %v", cluster)
+	}
+	if authInfo := config.AuthInfos["kubeadm-node-1"]; authInfo == nil || authInfo.Token != "secret" {
+		t.Errorf("expected the admin credentials, got %v", authInfo)
+	}
+	authInfo := config.AuthInfos["lab"]
+	if authInfo == nil || string(authInfo.ClientCertificateData) != "signed certificate" || len(authInfo.Token) > 0 {
+		t.Fatalf("expected the signed certificate of the user, got %v", authInfo)
+	}
+	if block, _ := pem.Decode(authInfo.ClientKeyData); block == nil || block.Type != "EC PRIVATE KEY" {
+		t.Errorf("expected the private key of the user, got %q", authInfo.ClientKeyData)
+	}
+
+	o = &ImportKubeadmOptions{SSH: "root@node-1", Groups: []string{"developers"}}
+	if err := o.Validate(); err == nil {
+		t.Errorf("expected --group without --user to fail")
+	}
+}
+
+func TestKubeadmCSRName(t *testing.T) {
+	now := time.Unix(1565000000, 0)
+	if name := kubeadmCSRName("system:Jane_Doe", now); name != "kubecfg-system-jane-doe-1565000000" {
+		t.Errorf("unexpected name %q", name)
+	}
+}
@@ -0,0 +1,297 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// localClusterTool is a tool running Kubernetes clusters on this machine.
+type localClusterTool struct {
+	// Name is the tool, which is also its command and the prefix of the entries of its clusters.
+	Name string
+	// Clusters lists the clusters of the tool.
+	Clusters func(run commandRunner) ([]string, error)
+	// Kubeconfig returns a kubeconfig for a cluster.
+	Kubeconfig func(run commandRunner, cluster string) ([]byte, error)
+}
+
+// commandRunner runs a command with extra environment variables and returns its standard output.
+type commandRunner func(env []string, name string, args ...string) ([]byte, error)
+
+var localClusterTools = []localClusterTool{
+	{
+		Name: "kind",
+		Clusters: func(run commandRunner) ([]string, error) {
+			out, err := run(nil, "kind", "get", "clusters")
+			if err != nil {
+				return nil, err
+			}
+			return strings.Fields(string(out)), nil
+		},
+		Kubeconfig: func(run commandRunner, cluster string) ([]byte, error) {
+			return run(nil, "kind", "get", "kubeconfig", "--name", cluster)
+		},
+	},
+	{
+		Name: "k3d",
+		Clusters: func(run commandRunner) ([]string, error) {
+			out, err := run(nil, "k3d", "cluster", "list", "-o", "json")
+			if err != nil {
+				return nil, err
+			}
+			clusters := []struct {
+				Name string `json:"name"`
+			}{}
+			if err := json.Unmarshal(out, &clusters); err != nil {
+				return nil, err
+			}
+			names := []string{}
+			for _, cluster := range clusters {
+				names = append(names, cluster.Name)
+			}
+			return names, nil
+		},
+		Kubeconfig: func(run commandRunner, cluster string) ([]byte, error) {
+			return run(nil, "k3d", "kubeconfig", "get", cluster)
+		},
+	},
+	{
+		Name: "minikube",
+		Clusters: func(run commandRunner) ([]string, error) {
+			out, err := run(nil, "minikube", "profile", "list", "-o", "json")
+			if err != nil {
+				return nil, err
+			}
+			profiles := struct {
+				Valid []struct {
+					Name string `json:"Name"`
+				} `json:"valid"`
+			}{}
+			if err := json.Unmarshal(out, &profiles); err != nil {
+				return nil, err
+			}
+			names := []string{}
+			for _, profile := range profiles.Valid {
+				names = append(names, profile.Name)
+			}
+			return names, nil
+		},
+		// minikube cannot print a kubeconfig, it writes its entry into the file $KUBECONFIG names.
+		Kubeconfig: func(run commandRunner, cluster string) ([]byte, error) {
+			file, err := ioutil.TempFile("", "minikube-kubeconfig")
+			if err != nil {
+				return nil, err
+			}
+			file.Close()
+			defer os.Remove(file.Name())
+			if _, err := run([]string{"KUBECONFIG=" + file.Name()}, "minikube", "update-context", "--profile", cluster); err != nil {
+				return nil, err
+			}
+			return ioutil.ReadFile(file.Name())
+		},
+	},
+}
+
+// ImportLocalOptions holds the command-line options for 'config import local' sub command
+type ImportLocalOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Tools        []localClusterTool
+
+	// LookPath and RunCommand default to exec.LookPath and running the command, they are fields
+	// so tests can describe the tools installed.
+	LookPath   func(file string) (string, error)
+	RunCommand commandRunner
+	log        *cmdLogger
+
+	genericclioptions.IOStreams
+}
+
+var (
+	importLocalLong = templates.LongDesc(`
+		Import the clusters kind, k3d and minikube run on this machine.
+
+		The clusters are listed with the command line tool of each of them, and the cluster, user
+		and context of every cluster are merged under a single name made of the tool and the
+		cluster, such as kind-dev or k3d-test; the default minikube cluster is named minikube.
+		Tools that are not installed are skipped.
+
+		The imported entries are remembered, and the entries of clusters that no longer exist
+		are removed the next time the command runs. Entries of the same name the kubeconfig
+		already had are left alone.`)
+
+	importLocalExample = templates.Examples(`
+		# Import the local clusters and remove the ones that were deleted
+		kubectl config import local`)
+)
+
+// NewCmdConfigImportLocal returns a Command instance for 'config import local' sub command
+func NewCmdConfigImportLocal(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &ImportLocalOptions{
+		ConfigAccess: configAccess,
+		Tools:        localClusterTools,
+		LookPath:     exec.LookPath,
+		RunCommand:   runCommand,
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "local",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Import the clusters of kind, k3d and minikube"),
+		Long:                  importLocalLong,
+		Example:               importLocalExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(cmdutil.UsageErrorf(cmd, "unexpected arguments: %v", args))
+			}
+			var err error
+			o.log, err = newCmdLogger(cmd, o.ErrOut)
+			cmdutil.CheckErr(err)
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	return cmd
+}
+
+// Run performs the execution of 'config import local' sub command
+func (o *ImportLocalOptions) Run() error {
+	log := o.log
+	if log == nil {
+		log, _ = newCmdLogger(nil, o.ErrOut)
+	}
+
+	local := clientcmdapi.NewConfig()
+	found := 0
+	for _, tool := range o.Tools {
+		if _, err := o.LookPath(tool.Name); err != nil {
+			log.Infof(1, "%s is not installed, skipped", tool.Name)
+			continue
+		}
+		clusters, err := tool.Clusters(o.RunCommand)
+		if err != nil {
+			// Pruning the entries of a tool that could not list its clusters would lose them.
+			return fmt.Errorf("listing %s clusters: %v", tool.Name, err)
+		}
+		for _, cluster := range clusters {
+			data, err := tool.Kubeconfig(o.RunCommand, cluster)
+			if err != nil {
+				return fmt.Errorf("getting the kubeconfig of %s cluster %q: %v", tool.Name, cluster, err)
+			}
+			from, err := clientcmd.Load(data)
+			if err != nil {
+				return fmt.Errorf("loading the kubeconfig of %s cluster %q: %v", tool.Name, cluster, err)
+			}
+			if err := stampProvenance(from, tool.Name, cluster, time.Now()); err != nil {
+				return err
+			}
+			name := localClusterEntryName(tool.Name, cluster)
+			if err := renameLocalClusterEntries(local, from, name); err != nil {
+				return fmt.Errorf("%s cluster %q: %v", tool.Name, cluster, err)
+			}
+			found++
+		}
+	}
+
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	managed := &managedEntries{}
+	if _, err := readExtension(config.Preferences.Extensions, localClustersExtension, managed); err != nil {
+		return err
+	}
+	added, removed := syncManagedEntries(config, local, managed, log)
+	if err := writeExtension(&config.Preferences.Extensions, localClustersExtension, managed); err != nil {
+		return err
+	}
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "Merged %d entries from %d local cluster(s), removed %d.\n", added, found, removed)
+	return nil
+}
+
+// localClusterEntryName is the name of the entries of a local cluster.
+func localClusterEntryName(tool, cluster string) string {
+	if cluster == tool {
+		return cluster
+	}
+	return tool + "-" + cluster
+}
+
+// renameLocalClusterEntries adds the current context of from, or its only context, with its cluster
+// and user to into, all three under name.
+func renameLocalClusterEntries(into, from *clientcmdapi.Config, name string) error {
+	contextName := from.CurrentContext
+	if len(contextName) == 0 && len(from.Contexts) == 1 {
+		contextName = sortedContextNames(from.Contexts)[0]
+	}
+	context, ok := from.Contexts[contextName]
+	if !ok {
+		return fmt.Errorf("the kubeconfig has no current context")
+	}
+	cluster, ok := from.Clusters[context.Cluster]
+	if !ok {
+		return fmt.Errorf("the kubeconfig has no cluster %q", context.Cluster)
+	}
+	authInfo, ok := from.AuthInfos[context.AuthInfo]
+	if !ok {
+		return fmt.Errorf("the kubeconfig has no user %q", context.AuthInfo)
+	}
+
+	into.Clusters[name] = cluster
+	into.AuthInfos[name] = authInfo
+	renamed := context.DeepCopy()
+	renamed.Cluster, renamed.AuthInfo = name, name
+	into.Contexts[name] = renamed
+	return nil
+}
+
+// runCommand runs a command with extra environment variables and returns its standard output, or
+// an error including its standard error.
+func runCommand(env []string, name string, args ...string) ([]byte, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Env = append(os.Environ(), env...)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); len(message) > 0 {
+			return nil, fmt.Errorf("%v: %s", err, message)
+		}
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func localClusterKubeconfig(name, server string) string {
+	return fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: %[1]s
+  cluster:
+    server: %[2]s
+users:
+- name: %[1]s
+  user:
+    token: secret
+contexts:
+- name: %[1]s
+  context:
+    cluster: %[1]s
+    user: %[1]s
+current-context: %[1]s
+`, name, server)
+}
+
+func TestImportLocal(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	if err := clientcmd.WriteToFile(newRedFederalCowHammerConfig(), fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	kindClusters := "dev\ntest\n"
+	outputs := map[string]string{
+		"kind get kubeconfig --name dev":             localClusterKubeconfig("kind-dev", "https://127.0.0.1:40001"),
+		"kind get kubeconfig --name test":            localClusterKubeconfig("kind-test", "https://127.0.0.1:40002"),
+		"minikube profile list -o json":              `{"invalid":[{"Name":"broken"}],"valid":[{"Name":"minikube"}]}`,
+		"k3d cluster list -o json":                   `[]`,
+		"minikube update-context --profile minikube": localClusterKubeconfig("minikube", "https://192.168.49.2:8443"),
+	}
+	run := func(env []string, name string, args ...string) ([]byte, error) {
+		command := strings.Join(append([]string{name}, args...), " ")
+		if command == "kind get clusters" {
+			return []byte(kindClusters), nil
+		}
+		output, ok := outputs[command]
+		if !ok {
+			return nil, fmt.Errorf("unexpected command %q", command)
+		}
+		if name == "minikube" && args[0] == "update-context" {
+			// minikube writes to the file named by $KUBECONFIG.
+			if len(env) != 1 || !strings.HasPrefix(env[0], "KUBECONFIG=") {
+				return nil, fmt.Errorf("expected $KUBECONFIG, got %v", env)
+			}
+			return nil, ioutil.WriteFile(strings.TrimPrefix(env[0], "KUBECONFIG="), []byte(output), 0600)
+		}
+		return []byte(output), nil
+	}
+	lookPath := func(file string) (string, error) {
+		if file == "k3d" {
+			return "", errors.New("not found")
+		}
+		return "/usr/bin/" + file, nil
+	}
+
+	importLocal := func() string {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		o := &ImportLocalOptions{ConfigAccess: pathOptions, Tools: localClusterTools, LookPath: lookPath, RunCommand: run, IOStreams: streams}
+		if err := o.Run(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return out.String()
+	}
+
+	if out := importLocal(); out != "Merged 9 entries from 3 local cluster(s), removed 0.\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+	config, err := clientcmd.LoadFromFile(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if names := sortedContextNames(config.Contexts); !reflect.DeepEqual(names, []string{"federal-context", "kind-dev", "kind-test", "minikube"}) {
+		t.Errorf("unexpected contexts %v", names)
+	}
+	if server := config.Clusters["minikube"].Server; server != "https://192.168.49.2:8443" {
+		t.Errorf("unexpected minikube server %q", server)
+	}
+
+	kindClusters = "dev\n"
+	if out := importLocal(); out != "Merged 6 entries from 2 local cluster(s), removed 3.\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+	config, err = clientcmd.LoadFromFile(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := config.Contexts["kind-test"]; ok {
+		t.Errorf("expected the deleted kind cluster to be pruned")
+	}
+	if _, ok := config.Contexts["federal-context"]; !ok {
+		t.Errorf("expected entries of the kubeconfig to be kept")
+	}
+}
+
+func TestRenameLocalClusterEntries(t *testing.T) {
+	from, err := clientcmd.Load([]byte(localClusterKubeconfig("k3d-test", "https://0.0.0.0:6443")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	from.Contexts["admin@k3d-test"] = from.Contexts["k3d-test"]
+	from.CurrentContext = "admin@k3d-test"
+	delete(from.Contexts, "k3d-test")
+
+	into := clientcmdapi.NewConfig()
+	if err := renameLocalClusterEntries(into, from, localClusterEntryName("k3d", "test")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	context := into.Contexts["k3d-test"]
+	if context == nil || context.Cluster != "k3d-test" || context.AuthInfo != "k3d-test" || into.Clusters["k3d-test"] == nil || into.AuthInfos["k3d-test"] == nil {
+		t.Errorf("expected every entry to be named k3d-test, got %#v", into)
+	}
+
+	from.CurrentContext = ""
+	from.Contexts["other"] = context
+	if err := renameLocalClusterEntries(clientcmdapi.NewConfig(), from, "k3d-test"); err == nil {
+		t.Errorf("expected an error without a current context to choose")
+	}
+}
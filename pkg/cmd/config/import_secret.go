@@ -0,0 +1,238 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// secretKubeconfigKeys are the keys kubeconfig Secrets commonly keep the kubeconfig in, tried in
+// turn when --key is not given: Cluster API and Flux use value, others kubeconfig or config.
+var secretKubeconfigKeys = []string{"value", "value.yaml", "kubeconfig", "config"}
+
+// ImportSecretOptions holds the command-line options for 'config import secret' sub command
+type ImportSecretOptions struct {
+	ConfigAccess         clientcmd.ConfigAccess
+	Context              string
+	Namespace            string
+	Secret               string
+	Key                  string
+	OnConflict           string
+	AllowDuplicateServer bool
+
+	Clientset kubernetes.Interface
+	log       *cmdLogger
+
+	genericclioptions.IOStreams
+}
+
+var (
+	importSecretLong = templates.LongDesc(`
+		Import the kubeconfig kept in a Secret of another cluster, as GitOps tools such as Flux and
+		Argo CD do for the clusters of a fleet.
+
+		The Secret is read with --context, in --namespace or the namespace of that context. The
+		kubeconfig is taken from --key, or else from the first of the keys value, value.yaml,
+		kubeconfig and config the Secret has, or from its only key.
+
+		What becomes of an imported entry whose name is taken is chosen with --on-conflict, as for
+		"kubectl config import".`)
+
+	importSecretExample = templates.Examples(`
+		# Import the kubeconfig Flux uses for a cluster of the fleet, from the mgmt cluster
+		kubectl config import secret --context mgmt -n flux-system secret/cluster-kubeconfig --key value
+
+		# Import it under other names when they are taken
+		kubectl config import secret --context mgmt -n flux-system cluster-kubeconfig --on-conflict=suffix`)
+)
+
+// NewCmdConfigImportSecret returns a Command instance for 'config import secret' sub command
+func NewCmdConfigImportSecret(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &ImportSecretOptions{
+		ConfigAccess: configAccess,
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "secret [secret/]NAME --context=CONTEXT [--namespace=NAMESPACE] [--key=KEY] [--on-conflict=STRATEGY]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Import the kubeconfig kept in a Secret of another cluster"),
+		Long:                  importSecretLong,
+		Example:               importSecretExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			cmdutil.CheckErr(requireNetwork(cmd))
+			cmdutil.CheckErr(o.Complete(cmd, args[0]))
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().StringVar(&o.Context, "context", o.Context, "The context of the cluster holding the Secret")
+	cmd.Flags().StringVarP(&o.Namespace, "namespace", "n", o.Namespace, "The namespace of the Secret, the namespace of the context by default")
+	cmd.Flags().StringVar(&o.Key, "key", o.Key, "The key of the kubeconfig in the Secret")
+	cmd.Flags().StringVar(&o.OnConflict, "on-conflict", o.OnConflict, "What to do with entries whose name is taken: skip, overwrite, suffix, source-prefix, hash or prompt. Defaults to the onConflict setting, or skip")
+	cmd.Flags().BoolVar(&o.AllowDuplicateServer, flagAllowDuplicateServer, o.AllowDuplicateServer, "If true, import clusters pointing at the same server and certificate authority as an existing cluster instead of reusing it")
+	return cmd
+}
+
+// Complete parses the name of the Secret and connects to the cluster holding it
+func (o *ImportSecretOptions) Complete(cmd *cobra.Command, secret string) error {
+	if len(o.Context) == 0 {
+		return helpErrorf(cmd, "--context is required")
+	}
+	o.Secret = secret
+	if parts := strings.SplitN(secret, "/", 2); len(parts) == 2 {
+		if parts[0] != "secret" && parts[0] != "secrets" {
+			return helpErrorf(cmd, "%q is not a Secret", secret)
+		}
+		o.Secret = parts[1]
+	}
+	var err error
+	if o.log, err = newCmdLogger(cmd, o.ErrOut); err != nil {
+		return err
+	}
+	if len(o.OnConflict) == 0 {
+		settings, err := loadSettings(settingsFile())
+		if err != nil {
+			return err
+		}
+		o.OnConflict = settings.OnConflict
+	}
+
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	if o.Context, err = resolveContextName(config, o.Context); err != nil {
+		return err
+	}
+	if _, ok := config.Contexts[o.Context]; !ok {
+		return fmt.Errorf("no context exists with the name: %q", o.Context)
+	}
+	clientConfig := clientcmd.NewNonInteractiveClientConfig(*config, o.Context, &clientcmd.ConfigOverrides{}, nil)
+	if len(o.Namespace) == 0 {
+		if o.Namespace, _, err = clientConfig.Namespace(); err != nil {
+			return err
+		}
+	}
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return err
+	}
+	restConfig.Timeout = 30 * time.Second
+	o.Clientset, err = kubernetes.NewForConfig(restConfig)
+	return err
+}
+
+// Run performs the execution of 'config import secret' sub command
+func (o *ImportSecretOptions) Run() error {
+	if o.Clientset == nil {
+		return errors.New("not connected to the cluster holding the Secret")
+	}
+	log := o.log
+	if log == nil {
+		log, _ = newCmdLogger(nil, o.ErrOut)
+	}
+	resolve, err := newConflictStrategy(o.OnConflict, o.IOStreams)
+	if err != nil {
+		return err
+	}
+
+	source := o.Namespace + "/" + o.Secret
+	secret, err := o.Clientset.CoreV1().Secrets(o.Namespace).Get(o.Secret, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("getting secret %s from %q: %v", source, o.Context, err)
+	}
+	key, err := secretKubeconfigKey(secret, o.Key)
+	if err != nil {
+		return fmt.Errorf("secret %s: %v", source, err)
+	}
+	from, err := clientcmd.Load(secret.Data[key])
+	if err != nil {
+		return fmt.Errorf("loading the kubeconfig of secret %s, key %s: %v", source, key, err)
+	}
+	if err := stampProvenance(from, "secret", o.Context+"/"+source, time.Now()); err != nil {
+		return err
+	}
+
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	if !o.AllowDuplicateServer {
+		for _, reused := range reuseDuplicateServers(config, from) {
+			log.Infof(0, "%s: reused %s", source, reused)
+		}
+	}
+	merged, err := mergeConfigWithStrategy(config, from, o.Secret, resolve)
+	if err != nil {
+		return err
+	}
+	for _, reason := range merged.Skipped {
+		log.Warningf("%s: skipped %s", source, reason)
+	}
+	for _, renamed := range merged.Renamed {
+		log.Infof(0, "%s: imported %s", source, renamed)
+	}
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "Merged %d entries from secret %s.\n", merged.Added, source)
+	return nil
+}
+
+// secretKubeconfigKey returns the key of the kubeconfig in secret: key if it is set, else the first
+// of secretKubeconfigKeys the secret has, or its only key.
+func secretKubeconfigKey(secret *corev1.Secret, key string) (string, error) {
+	keys := sets.StringKeySet(secret.Data)
+	if len(key) > 0 {
+		if !keys.Has(key) {
+			return "", fmt.Errorf("no key %q, the keys are: %s", key, strings.Join(keys.List(), ", "))
+		}
+		return key, nil
+	}
+	for _, candidate := range secretKubeconfigKeys {
+		if keys.Has(candidate) {
+			return candidate, nil
+		}
+	}
+	if keys.Len() == 1 {
+		return keys.List()[0], nil
+	}
+	if keys.Len() == 0 {
+		return "", errors.New("the secret is empty")
+	}
+	return "", fmt.Errorf("cannot tell which key holds the kubeconfig, give one of %s with --key", strings.Join(keys.List(), ", "))
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func TestImportSecret(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	if err := clientcmd.WriteToFile(newRedFederalCowHammerConfig(), fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	clientset := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "flux-system", Name: "cluster-kubeconfig"},
+		Data:       map[string][]byte{"value": []byte(localClusterKubeconfig("edge-1", "https://edge-1.example.com:6443"))},
+	})
+	importSecret := func(name, key string) (string, error) {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		o := &ImportSecretOptions{
+			ConfigAccess: pathOptions,
+			Context:      "federal-context",
+			Namespace:    "flux-system",
+			Secret:       name,
+			Key:          key,
+			Clientset:    clientset,
+			IOStreams:    streams,
+		}
+		err := o.Run()
+		return out.String(), err
+	}
+
+	if _, err := importSecret("missing", ""); err == nil {
+		t.Errorf("expected a missing secret to fail")
+	}
+	if _, err := importSecret("cluster-kubeconfig", "kubeconfig"); err == nil || !strings.Contains(err.Error(), `no key "kubeconfig", the keys are: value`) {
+		t.Errorf("expected a missing key to fail, got %v", err)
+	}
+	out, err := importSecret("cluster-kubeconfig", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "Merged 3 entries from secret flux-system/cluster-kubeconfig.\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+	config, err := clientcmd.LoadFromFile(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if names := sortedContextNames(config.Contexts); !reflect.DeepEqual(names, []string{"edge-1", "federal-context"}) {
+		t.Errorf("unexpected contexts %v", names)
+	}
+	stamp := provenance{}
+	if found, err := readExtension(config.Clusters["edge-1"].Extensions, provenanceExtension, &stamp); err != nil || !found || stamp.Source != "secret" || stamp.Identity != "federal-context/flux-system/cluster-kubeconfig" {
+		t.Errorf("unexpected provenance %+v, %v", stamp, err)
+	}
+}
+
+func TestSecretKubeconfigKey(t *testing.T) {
+	tests := map[string]struct {
+		data        []string
+		key         string
+		expected    string
+		expectedErr string
+	}{
+		"given":    {data: []string{"value", "kubeconfig"}, key: "kubeconfig", expected: "kubeconfig"},
+		"value":    {data: []string{"config", "value"}, expected: "value"},
+		"only key": {data: []string{"admin.conf"}, expected: "admin.conf"},
+		"ambiguous": {
+			data:        []string{"a.conf", "b.conf"},
+			expectedErr: "give one of a.conf, b.conf with --key",
+		},
+		"empty": {expectedErr: "empty"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			secret := &corev1.Secret{Data: map[string][]byte{}}
+			for _, key := range test.data {
+				secret.Data[key] = []byte("apiVersion: v1")
+			}
+			key, err := secretKubeconfigKey(secret, test.key)
+			if len(test.expectedErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Errorf("expected error containing %q, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if key != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, key)
+			}
+		})
+	}
+}
@@ -0,0 +1,610 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// syncedSource is a source entries were imported from, as recorded in their provenance.
+type syncedSource struct {
+	Kind     string
+	Identity string
+}
+
+// syncResult describes the changes made to the entries of a source by "config import --sync".
+type syncResult struct {
+	Added   []string
+	Updated []string
+	Removed []string
+	Skipped []string
+}
+
+// importSyncer re-imports the sources of a kind for "config import --sync".
+type importSyncer struct {
+	// scope returns what is queried to sync the source of an identity: the source itself, or the
+	// region, project or resource group listing its cluster along with the others.
+	scope func(identity string) (string, error)
+	// fetch returns the entries the sources of a scope have now, by identity. A source missing from
+	// the result is gone.
+	fetch func(o *ImportOptions, scope string) (map[string]*clientcmdapi.Config, error)
+	// renamed is set for sources whose current context is imported with its cluster and user under
+	// a single name, such as "config pull". The entries keep the name they were imported under.
+	renamed bool
+}
+
+var importSyncers = map[string]importSyncer{
+	"file":    {scope: identityScope, fetch: fetchFileSource},
+	"eks":     {scope: eksScope, fetch: fetchEKSClusters},
+	"gke":     {scope: gkeScope, fetch: fetchGKEClusters},
+	"aks":     {scope: aksScope, fetch: fetchAKSClusters},
+	"talos":   {scope: identityScope, fetch: fetchTalosSource, renamed: true},
+	"k0s":     {scope: sshDestinationScope, fetch: fetchK0sSource, renamed: true},
+	"kubeadm": {scope: sshDestinationScope, fetch: fetchKubeadmSource, renamed: true},
+	"ssh":     {scope: pullScope, fetch: fetchSSHSource, renamed: true},
+}
+
+// The kinds of sources kept up to date by running their import again, which removes the entries of
+// the clusters that are gone by itself.
+var localSourceKinds = sets.NewString("kind", "k3d", "minikube")
+
+// importedSources returns the sources the entries of config were imported from, with the names of
+// the entries imported from each.
+func importedSources(config *clientcmdapi.Config) (map[syncedSource]*managedEntries, error) {
+	sources := map[syncedSource]*managedEntries{}
+	entries := func(extensions map[string]runtime.Object) (*managedEntries, error) {
+		stamp := provenance{}
+		if found, err := readExtension(extensions, provenanceExtension, &stamp); err != nil || !found {
+			return nil, err
+		}
+		source := syncedSource{Kind: stamp.Source, Identity: stamp.Identity}
+		if sources[source] == nil {
+			sources[source] = &managedEntries{}
+		}
+		return sources[source], nil
+	}
+	for _, name := range sortedClusterNames(config.Clusters) {
+		managed, err := entries(config.Clusters[name].Extensions)
+		if err != nil {
+			return nil, fmt.Errorf("cluster %q: %v", name, err)
+		}
+		if managed != nil {
+			managed.Clusters = append(managed.Clusters, name)
+		}
+	}
+	for _, name := range sortedAuthInfoNames(config.AuthInfos) {
+		managed, err := entries(config.AuthInfos[name].Extensions)
+		if err != nil {
+			return nil, fmt.Errorf("user %q: %v", name, err)
+		}
+		if managed != nil {
+			managed.AuthInfos = append(managed.AuthInfos, name)
+		}
+	}
+	for _, name := range sortedContextNames(config.Contexts) {
+		managed, err := entries(config.Contexts[name].Extensions)
+		if err != nil {
+			return nil, fmt.Errorf("context %q: %v", name, err)
+		}
+		if managed != nil {
+			managed.Contexts = append(managed.Contexts, name)
+		}
+	}
+	return sources, nil
+}
+
+// reconcileImportedSource brings the entries imported from source in line with fresh, the entries
+// the source has now, or removes them all when fresh is nil because the source is gone. Clusters
+// imported earlier get the server and certificate authority of the source, entries the kubeconfig
+// does not have yet are added and entries the source no longer has are removed. Entries of the
+// same name that were not imported from the source are left alone.
+func reconcileImportedSource(config *clientcmdapi.Config, source syncedSource, fresh *clientcmdapi.Config, now time.Time) (syncResult, error) {
+	result := syncResult{}
+	if fresh == nil {
+		fresh = clientcmdapi.NewConfig()
+	}
+	if err := stampProvenance(fresh, source.Kind, source.Identity, now); err != nil {
+		return result, err
+	}
+	importedFromSource := func(extensions map[string]runtime.Object) bool {
+		stamp := provenance{}
+		_, err := readExtension(extensions, provenanceExtension, &stamp)
+		return err == nil && stamp.Source == source.Kind && stamp.Identity == source.Identity
+	}
+
+	for _, name := range sortedClusterNames(config.Clusters) {
+		if _, ok := fresh.Clusters[name]; !ok && importedFromSource(config.Clusters[name].Extensions) {
+			delete(config.Clusters, name)
+			result.Removed = append(result.Removed, fmt.Sprintf("cluster %q", name))
+		}
+	}
+	for _, name := range sortedAuthInfoNames(config.AuthInfos) {
+		if _, ok := fresh.AuthInfos[name]; !ok && importedFromSource(config.AuthInfos[name].Extensions) {
+			delete(config.AuthInfos, name)
+			result.Removed = append(result.Removed, fmt.Sprintf("user %q", name))
+		}
+	}
+	for _, name := range sortedContextNames(config.Contexts) {
+		if _, ok := fresh.Contexts[name]; !ok && importedFromSource(config.Contexts[name].Extensions) {
+			delete(config.Contexts, name)
+			result.Removed = append(result.Removed, fmt.Sprintf("context %q", name))
+		}
+	}
+
+	for _, name := range sortedClusterNames(fresh.Clusters) {
+		cluster := fresh.Clusters[name]
+		existing, ok := config.Clusters[name]
+		switch {
+		case !ok:
+			cluster.LocationOfOrigin = ""
+			config.Clusters[name] = cluster
+			result.Added = append(result.Added, fmt.Sprintf("cluster %q", name))
+		case !importedFromSource(existing.Extensions):
+			result.Skipped = append(result.Skipped, fmt.Sprintf("cluster %q, which was not imported from it", name))
+		case !sameClusterAccess(existing, cluster):
+			existing.Server = cluster.Server
+			existing.CertificateAuthority = cluster.CertificateAuthority
+			existing.CertificateAuthorityData = cluster.CertificateAuthorityData
+			existing.InsecureSkipTLSVerify = cluster.InsecureSkipTLSVerify
+			existing.Extensions[provenanceExtension] = cluster.Extensions[provenanceExtension]
+			result.Updated = append(result.Updated, fmt.Sprintf("cluster %q", name))
+		}
+	}
+	for _, name := range sortedAuthInfoNames(fresh.AuthInfos) {
+		authInfo := fresh.AuthInfos[name]
+		if existing, ok := config.AuthInfos[name]; !ok {
+			authInfo.LocationOfOrigin = ""
+			config.AuthInfos[name] = authInfo
+			result.Added = append(result.Added, fmt.Sprintf("user %q", name))
+		} else if !importedFromSource(existing.Extensions) {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("user %q, which was not imported from it", name))
+		}
+	}
+	for _, name := range sortedContextNames(fresh.Contexts) {
+		context := fresh.Contexts[name]
+		if existing, ok := config.Contexts[name]; !ok {
+			context.LocationOfOrigin = ""
+			config.Contexts[name] = context
+			result.Added = append(result.Added, fmt.Sprintf("context %q", name))
+		} else if !importedFromSource(existing.Extensions) {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("context %q, which was not imported from it", name))
+		}
+	}
+	return result, nil
+}
+
+// RunSync imports again the sources the entries of the kubeconfig were imported from
+func (o *ImportOptions) RunSync() error {
+	log := o.log
+	if log == nil {
+		log, _ = newCmdLogger(nil, o.ErrOut)
+	}
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	sources, err := importedSources(config)
+	if err != nil {
+		return err
+	}
+	identities := map[string][]string{}
+	for source := range sources {
+		if len(o.From) == 0 || source.Kind == o.From {
+			identities[source.Kind] = append(identities[source.Kind], source.Identity)
+		}
+	}
+	if len(identities) == 0 {
+		fmt.Fprintln(o.Out, "No imported entries to sync.")
+		return nil
+	}
+	kinds := sets.StringKeySet(identities).List()
+
+	// The sources with an import of their own write the kubeconfig themselves, before it is read
+	// again for the others.
+	failed := []string{}
+	synced := 0
+	localSynced := false
+	for _, kind := range kinds {
+		if _, ok := importSyncers[kind]; ok {
+			continue
+		}
+		sort.Strings(identities[kind])
+		if localSourceKinds.Has(kind) {
+			if localSynced {
+				continue
+			}
+			localSynced = true
+		}
+		n, err := o.syncWithImporter(kind, identities[kind])
+		synced += n
+		if err != nil {
+			log.Warningf("%s: %v", kind, err)
+			failed = append(failed, kind)
+		}
+	}
+	if config, err = o.ConfigAccess.GetStartingConfig(); err != nil {
+		return err
+	}
+
+	total := syncResult{}
+	now := time.Now()
+	for _, kind := range kinds {
+		syncer, ok := importSyncers[kind]
+		if !ok {
+			continue
+		}
+		scopes := map[string][]string{}
+		for _, identity := range identities[kind] {
+			scope, err := syncer.scope(identity)
+			if err != nil {
+				log.Warningf("%s %s: %v", kind, identity, err)
+				failed = append(failed, kind+" "+identity)
+				continue
+			}
+			scopes[scope] = append(scopes[scope], identity)
+		}
+		for _, scope := range sets.StringKeySet(scopes).List() {
+			fresh, err := syncer.fetch(o, scope)
+			if err != nil {
+				log.Warningf("%s %s: %v", kind, scope, err)
+				failed = append(failed, kind+" "+scope)
+				continue
+			}
+			for _, identity := range sets.NewString(scopes[scope]...).Union(sets.StringKeySet(fresh)).List() {
+				source := syncedSource{Kind: kind, Identity: identity}
+				from := fresh[identity]
+				if syncer.renamed && from != nil {
+					imported := sources[source]
+					if imported == nil || len(imported.Contexts) == 0 {
+						log.Warningf("%s %s: skipped, its context was removed", kind, identity)
+						continue
+					}
+					renamed := clientcmdapi.NewConfig()
+					if err := renameLocalClusterEntries(renamed, from, imported.Contexts[0]); err != nil {
+						log.Warningf("%s %s: %v", kind, identity, err)
+						failed = append(failed, kind+" "+identity)
+						continue
+					}
+					from = renamed
+				}
+				result, err := reconcileImportedSource(config, source, from, now)
+				if err != nil {
+					return err
+				}
+				for _, change := range result.Added {
+					log.Infof(0, "%s %s: added %s", kind, identity, change)
+				}
+				for _, change := range result.Updated {
+					log.Infof(0, "%s %s: updated %s", kind, identity, change)
+				}
+				for _, change := range result.Removed {
+					log.Infof(0, "%s %s: removed %s", kind, identity, change)
+				}
+				for _, reason := range result.Skipped {
+					log.Warningf("%s %s: skipped %s", kind, identity, reason)
+				}
+				total.Added = append(total.Added, result.Added...)
+				total.Updated = append(total.Updated, result.Updated...)
+				total.Removed = append(total.Removed, result.Removed...)
+				synced++
+			}
+		}
+	}
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "Synced %d source(s): %d entries added, %d updated, %d removed.\n", synced, len(total.Added), len(total.Updated), len(total.Removed))
+	if len(failed) > 0 {
+		return fmt.Errorf("%d source(s) could not be synced, their entries were left alone: %s", len(failed), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// syncWithImporter runs the import of a kind of source again, for the sources that keep track of
+// their entries themselves and remove those of the clusters that are gone. It returns the number of
+// sources synced.
+func (o *ImportOptions) syncWithImporter(kind string, identities []string) (int, error) {
+	switch {
+	case localSourceKinds.Has(kind):
+		local := &ImportLocalOptions{
+			ConfigAccess: o.ConfigAccess,
+			Tools:        localClusterTools,
+			LookPath:     exec.LookPath,
+			RunCommand:   o.RunCommand,
+			log:          o.log,
+			IOStreams:    o.IOStreams,
+		}
+		return 1, local.Run()
+	case kind == "capi":
+		contexts := managingContexts(identities)
+		for i, context := range contexts {
+			capi := &ImportCAPIOptions{ConfigAccess: o.ConfigAccess, ManagementContext: context, Sync: true, IOStreams: o.IOStreams}
+			if err := capi.Complete(nil); err != nil {
+				return i, fmt.Errorf("management context %q: %v", context, err)
+			}
+			if err := capi.Run(); err != nil {
+				return i, fmt.Errorf("management context %q: %v", context, err)
+			}
+		}
+		return len(contexts), nil
+	case kind == "vcluster":
+		contexts := managingContexts(identities)
+		for i, context := range contexts {
+			vcluster := &ImportVClusterOptions{
+				ConfigAccess: o.ConfigAccess,
+				HostContext:  context,
+				Connect:      "auto",
+				LocalPort:    defaultVClusterLocalPort,
+				Sync:         true,
+				IOStreams:    o.IOStreams,
+			}
+			if err := vcluster.Complete(nil); err != nil {
+				return i, fmt.Errorf("host context %q: %v", context, err)
+			}
+			if err := vcluster.Run(); err != nil {
+				return i, fmt.Errorf("host context %q: %v", context, err)
+			}
+		}
+		return len(contexts), nil
+	case kind == "source":
+		source := &SourceOptions{ConfigAccess: o.ConfigAccess, Force: true, IOStreams: o.IOStreams}
+		if err := source.Complete(nil); err != nil {
+			return 0, err
+		}
+		return len(identities), source.RunSync(true)
+	}
+	return 0, fmt.Errorf("entries imported from %s sources cannot be synced", kind)
+}
+
+// managingContexts returns the contexts of the clusters listing the clusters of the identities
+// CONTEXT/NAMESPACE/NAME given, each once.
+func managingContexts(identities []string) []string {
+	contexts := sets.NewString()
+	for _, identity := range identities {
+		i := strings.LastIndex(identity, "/")
+		if i < 0 {
+			continue
+		}
+		if j := strings.LastIndex(identity[:i], "/"); j > 0 {
+			contexts.Insert(identity[:j])
+		}
+	}
+	return contexts.List()
+}
+
+func identityScope(identity string) (string, error) {
+	return identity, nil
+}
+
+// sshDestinationScope checks the ssh destination of a source before ssh is run with it, as the
+// identity comes from the kubeconfig and could otherwise be an option of ssh.
+func sshDestinationScope(identity string) (string, error) {
+	return identity, checkSSHDestination(identity)
+}
+
+// pullScope checks the target of a source "config pull" imported before ssh is run with it.
+func pullScope(identity string) (string, error) {
+	source, _, err := parsePullIdentity(identity)
+	if err != nil {
+		return "", err
+	}
+	if _, _, err := parseSSHTarget(source); err != nil {
+		return "", err
+	}
+	return identity, nil
+}
+
+// eksScope returns the region of an EKS cluster identified as REGION/NAME.
+func eksScope(identity string) (string, error) {
+	parts := strings.Split(identity, "/")
+	if len(parts) != 2 {
+		return "", fmt.Errorf("expected REGION/NAME, got %q", identity)
+	}
+	return parts[0], nil
+}
+
+// gkeScope returns the project of a GKE cluster identified as [PROJECT/]LOCATION/NAME, which is
+// empty for the project gcloud is configured with.
+func gkeScope(identity string) (string, error) {
+	parts := strings.Split(identity, "/")
+	switch len(parts) {
+	case 2:
+		return "", nil
+	case 3:
+		return parts[0], nil
+	}
+	return "", fmt.Errorf("expected [PROJECT/]LOCATION/NAME, got %q", identity)
+}
+
+// aksScope returns the resource group of an AKS cluster identified as GROUP/NAME.
+func aksScope(identity string) (string, error) {
+	parts := strings.Split(identity, "/")
+	if len(parts) != 2 {
+		return "", fmt.Errorf("expected RESOURCE_GROUP/NAME, got %q", identity)
+	}
+	return parts[0], nil
+}
+
+func fetchFileSource(o *ImportOptions, filename string) (map[string]*clientcmdapi.Config, error) {
+	config, err := clientcmd.LoadFromFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return map[string]*clientcmdapi.Config{filename: config}, nil
+}
+
+// fetchEKSClusters lists the EKS clusters of a region with the aws CLI and writes their credentials.
+func fetchEKSClusters(o *ImportOptions, region string) (map[string]*clientcmdapi.Config, error) {
+	out, err := o.RunCommand(nil, "aws", "eks", "list-clusters", "--region", region, "--output", "json")
+	if err != nil {
+		return nil, fmt.Errorf("listing the EKS clusters: %v", err)
+	}
+	list := struct {
+		Clusters []string `json:"clusters"`
+	}{}
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("listing the EKS clusters: %v", err)
+	}
+	fresh := map[string]*clientcmdapi.Config{}
+	for _, name := range list.Clusters {
+		config, err := loadCLIKubeconfig(o.RunCommand, func(kubeconfig string) ([]string, string, []string) {
+			return nil, "aws", []string{"eks", "update-kubeconfig", "--name", name, "--region", region, "--kubeconfig", kubeconfig}
+		})
+		if err != nil {
+			return nil, err
+		}
+		fresh[region+"/"+name] = config
+	}
+	return fresh, nil
+}
+
+// fetchGKEClusters lists the GKE clusters of a project with the gcloud CLI and writes their
+// credentials.
+func fetchGKEClusters(o *ImportOptions, project string) (map[string]*clientcmdapi.Config, error) {
+	args := []string{"container", "clusters", "list", "--format", "json"}
+	if len(project) > 0 {
+		args = append(args, "--project", project)
+	}
+	out, err := o.RunCommand(nil, "gcloud", args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing the GKE clusters: %v", err)
+	}
+	list := []struct {
+		Name     string `json:"name"`
+		Location string `json:"location"`
+	}{}
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("listing the GKE clusters: %v", err)
+	}
+	fresh := map[string]*clientcmdapi.Config{}
+	for _, cluster := range list {
+		config, err := loadCLIKubeconfig(o.RunCommand, func(kubeconfig string) ([]string, string, []string) {
+			// gcloud has no flag for the kubeconfig it writes to.
+			args := []string{"container", "clusters", "get-credentials", cluster.Name, "--location", cluster.Location}
+			if len(project) > 0 {
+				args = append(args, "--project", project)
+			}
+			return []string{"KUBECONFIG=" + kubeconfig}, "gcloud", args
+		})
+		if err != nil {
+			return nil, err
+		}
+		identity := cluster.Location + "/" + cluster.Name
+		if len(project) > 0 {
+			identity = project + "/" + identity
+		}
+		fresh[identity] = config
+	}
+	return fresh, nil
+}
+
+// fetchAKSClusters lists the AKS clusters of a resource group with the az CLI and writes their
+// credentials.
+func fetchAKSClusters(o *ImportOptions, group string) (map[string]*clientcmdapi.Config, error) {
+	out, err := o.RunCommand(nil, "az", "aks", "list", "--resource-group", group, "--output", "json")
+	if err != nil {
+		return nil, fmt.Errorf("listing the AKS clusters: %v", err)
+	}
+	list := []struct {
+		Name string `json:"name"`
+	}{}
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("listing the AKS clusters: %v", err)
+	}
+	fresh := map[string]*clientcmdapi.Config{}
+	for _, cluster := range list {
+		config, err := loadCLIKubeconfig(o.RunCommand, func(kubeconfig string) ([]string, string, []string) {
+			return nil, "az", []string{"aks", "get-credentials", "--name", cluster.Name, "--resource-group", group, "--file", kubeconfig}
+		})
+		if err != nil {
+			return nil, err
+		}
+		fresh[group+"/"+cluster.Name] = config
+	}
+	return fresh, nil
+}
+
+// loadCLIKubeconfig runs the CLI of a cloud provider, as returned by command for a temporary
+// kubeconfig, and loads the kubeconfig it wrote.
+func loadCLIKubeconfig(run commandRunner, command func(kubeconfig string) ([]string, string, []string)) (*clientcmdapi.Config, error) {
+	dir, err := ioutil.TempDir("", "kubectl-config-sync")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	kubeconfig := filepath.Join(dir, "config")
+
+	env, name, args := command(kubeconfig)
+	if _, err := run(env, name, args...); err != nil {
+		return nil, fmt.Errorf("%s failed: %v", name, err)
+	}
+	return clientcmd.LoadFromFile(kubeconfig)
+}
+
+func fetchTalosSource(o *ImportOptions, talosconfig string) (map[string]*clientcmdapi.Config, error) {
+	config, err := (&ImportTalosOptions{Talosconfig: talosconfig, RunCommand: o.RunCommand}).fetch()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]*clientcmdapi.Config{talosconfig: config}, nil
+}
+
+func fetchK0sSource(o *ImportOptions, ssh string) (map[string]*clientcmdapi.Config, error) {
+	config, _, err := (&ImportK0sOptions{SSH: ssh, RunCommand: o.RunCommand}).fetch()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]*clientcmdapi.Config{ssh: config}, nil
+}
+
+func fetchKubeadmSource(o *ImportOptions, ssh string) (map[string]*clientcmdapi.Config, error) {
+	config, _, err := (&ImportKubeadmOptions{SSH: ssh, RunCommand: o.RunCommand}).fetch()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]*clientcmdapi.Config{ssh: config}, nil
+}
+
+// fetchSSHSource reads a kubeconfig "config pull" imported, with sudo only when it was pulled with
+// --sudo.
+func fetchSSHSource(o *ImportOptions, identity string) (map[string]*clientcmdapi.Config, error) {
+	source, sudo, err := parsePullIdentity(identity)
+	if err != nil {
+		return nil, err
+	}
+	config, _, err := (&PullOptions{Source: source, Sudo: sudo, RunSSH: o.RunSSH}).fetch()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]*clientcmdapi.Config{identity: config}, nil
+}
@@ -0,0 +1,233 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestImportSyncFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "import-sync")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	source := writeImportSource(t, dir, "a-cluster")
+	kubeconfig := filepath.Join(dir, "config")
+	if err := clientcmd.WriteToFile(newRedFederalCowHammerConfig(), kubeconfig); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = kubeconfig
+	pathOptions.EnvVar = ""
+
+	streams, _, _, _ := genericclioptions.NewTestIOStreams()
+	o := &ImportOptions{ConfigAccess: pathOptions, BatchSize: 1, CheckpointFile: filepath.Join(dir, "checkpoint.json"), IOStreams: streams}
+	if err := o.Complete(nil, []string{source}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The source moves its cluster and gains a context, while the kubeconfig has a context of its
+	// own named like one of the source.
+	drifted, err := clientcmd.LoadFromFile(source)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	drifted.Clusters["a-cluster"].Server = "https://a-cluster.example.org"
+	drifted.Contexts["a-admin"] = &clientcmdapi.Context{Cluster: "a-cluster", AuthInfo: "a-cluster"}
+	drifted.Contexts["federal-context"] = &clientcmdapi.Context{Cluster: "a-cluster", AuthInfo: "a-cluster"}
+	if err := clientcmd.WriteToFile(*drifted, source); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	streams, _, out, errOut := genericclioptions.NewTestIOStreams()
+	o = &ImportOptions{ConfigAccess: pathOptions, Sync: true, From: "file", IOStreams: streams}
+	if err := o.Complete(nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "Synced 1 source(s): 1 entries added, 1 updated, 0 removed.\n"; out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+	if !strings.Contains(errOut.String(), `skipped context "federal-context", which was not imported from it`) {
+		t.Errorf("expected the context of the kubeconfig to be left alone, got %q", errOut.String())
+	}
+	config, err := clientcmd.LoadFromFile(kubeconfig)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if server := config.Clusters["a-cluster"].Server; server != "https://a-cluster.example.org" {
+		t.Errorf("expected the server to be updated, got %q", server)
+	}
+	if _, ok := config.Contexts["a-admin"]; !ok {
+		t.Errorf("expected the new context to be added")
+	}
+	if config.Contexts["federal-context"].Cluster != "cow-cluster" {
+		t.Errorf("expected the context of the kubeconfig to be kept, got %#v", config.Contexts["federal-context"])
+	}
+
+	if err := os.Remove(source); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	streams, _, out, _ = genericclioptions.NewTestIOStreams()
+	o.IOStreams = streams
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "Synced 1 source(s): 0 entries added, 0 updated, 4 removed.\n"; out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+	config, err = clientcmd.LoadFromFile(kubeconfig)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if names := sortedContextNames(config.Contexts); !reflect.DeepEqual(names, []string{"federal-context"}) {
+		t.Errorf("expected only the entries of the kubeconfig to be left, got %v", names)
+	}
+}
+
+func TestImportSyncEKS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "import-sync")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	config := newRedFederalCowHammerConfig()
+	deleted, err := newImportedConfig("shop", "eks", "eu-west-1/shop", time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mergeImportedConfig(&config, deleted, false)
+	kubeconfig := filepath.Join(dir, "config")
+	if err := clientcmd.WriteToFile(config, kubeconfig); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = kubeconfig
+	pathOptions.EnvVar = ""
+
+	commands := []string{}
+	run := func(env []string, name string, args ...string) ([]byte, error) {
+		commands = append(commands, name+" "+strings.Join(args, " "))
+		switch {
+		case name == "aws" && args[1] == "list-clusters":
+			return []byte(`{"clusters": ["web"]}`), nil
+		case name == "aws" && args[1] == "update-kubeconfig":
+			created := clientcmdapi.NewConfig()
+			created.Clusters["web"] = &clientcmdapi.Cluster{Server: "https://web.eks.amazonaws.com"}
+			created.AuthInfos["web"] = &clientcmdapi.AuthInfo{Token: "web-token"}
+			created.Contexts["web"] = &clientcmdapi.Context{Cluster: "web", AuthInfo: "web"}
+			return nil, clientcmd.WriteToFile(*created, args[len(args)-1])
+		}
+		return nil, fmt.Errorf("unexpected command %s %v", name, args)
+	}
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := &ImportOptions{ConfigAccess: pathOptions, Sync: true, RunCommand: run, IOStreams: streams}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "Synced 2 source(s): 3 entries added, 0 updated, 3 removed.\n"; out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+	expected := []string{
+		"aws eks list-clusters --region eu-west-1 --output json",
+		"aws eks update-kubeconfig --name web --region eu-west-1 --kubeconfig " + commands[1][strings.LastIndex(commands[1], " ")+1:],
+	}
+	if !reflect.DeepEqual(commands, expected) {
+		t.Errorf("expected %v, got %v", expected, commands)
+	}
+
+	synced, err := clientcmd.LoadFromFile(kubeconfig)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if names := sortedContextNames(synced.Contexts); !reflect.DeepEqual(names, []string{"federal-context", "web"}) {
+		t.Errorf("expected the deleted cluster to be replaced by the new one, got %v", names)
+	}
+	stamp := provenance{}
+	if _, err := readExtension(synced.Clusters["web"].Extensions, provenanceExtension, &stamp); err != nil || stamp.Source != "eks" || stamp.Identity != "eu-west-1/web" {
+		t.Errorf("expected the new cluster to be stamped, got %+v, %v", stamp, err)
+	}
+}
+
+func TestImportSyncRefusesSSHOptions(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	config := newRedFederalCowHammerConfig()
+	for kind, identity := range map[string]string{"kubeadm": "-oProxyCommand=touch /tmp/owned", "k0s": "-F/tmp/ssh_config", "ssh": "ssh://-oProxyCommand=id/config"} {
+		from := clientcmdapi.NewConfig()
+		from.Clusters[kind] = &clientcmdapi.Cluster{Server: "https://" + kind + ".example.com"}
+		if err := stampProvenance(from, kind, identity, time.Now()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		config.Clusters[kind] = from.Clusters[kind]
+	}
+	if err := clientcmd.WriteToFile(config, fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	ran := []string{}
+	streams, _, _, _ := genericclioptions.NewTestIOStreams()
+	o := &ImportOptions{
+		ConfigAccess: pathOptions,
+		Sync:         true,
+		RunCommand: func(env []string, name string, args ...string) ([]byte, error) {
+			ran = append(ran, name+" "+strings.Join(args, " "))
+			return nil, fmt.Errorf("unexpected command")
+		},
+		RunSSH: func(args []string, stdin []byte) ([]byte, error) {
+			ran = append(ran, "ssh "+strings.Join(args, " "))
+			return nil, fmt.Errorf("unexpected command")
+		},
+		IOStreams: streams,
+	}
+	if err := o.Complete(nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.Run(); err == nil || !strings.Contains(err.Error(), "3 source(s) could not be synced") {
+		t.Errorf("expected the three sources to fail, got %v", err)
+	}
+	if len(ran) > 0 {
+		t.Errorf("expected ssh to never run with an option as destination, ran %q", ran)
+	}
+}
@@ -0,0 +1,290 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func writeImportSource(t *testing.T, dir, name string) string {
+	config := clientcmdapi.NewConfig()
+	config.Clusters[name] = &clientcmdapi.Cluster{Server: fmt.Sprintf("https://%s.example.com", name)}
+	config.AuthInfos[name] = &clientcmdapi.AuthInfo{Token: name}
+	config.Contexts[name] = &clientcmdapi.Context{Cluster: name, AuthInfo: name}
+	filename := filepath.Join(dir, name+".yaml")
+	if err := clientcmd.WriteToFile(*config, filename); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return filename
+}
+
+func TestImportResumesAfterPartialFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "import")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	sourceDir := filepath.Join(dir, "sources")
+	if err := os.Mkdir(sourceDir, 0700); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	first := writeImportSource(t, sourceDir, "a-cluster")
+	broken := filepath.Join(sourceDir, "b-broken.yaml")
+	if err := ioutil.WriteFile(broken, []byte("clusters: [this is not a kubeconfig"), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	last := writeImportSource(t, sourceDir, "c-cluster")
+
+	kubeconfig := filepath.Join(dir, "config")
+	if err := clientcmd.WriteToFile(newRedFederalCowHammerConfig(), kubeconfig); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = kubeconfig
+	pathOptions.EnvVar = ""
+	checkpointFile := filepath.Join(dir, "state", "checkpoint.json")
+
+	streams, _, _, errOut := genericclioptions.NewTestIOStreams()
+	options := &ImportOptions{
+		ConfigAccess:   pathOptions,
+		BatchSize:      1,
+		CheckpointFile: checkpointFile,
+		IOStreams:      streams,
+	}
+	if err := options.Complete(nil, []string{sourceDir}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := options.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = options.Run()
+	if err == nil || !strings.Contains(err.Error(), "1 of 3 sources could not be imported") {
+		t.Fatalf("expected a partial failure, got %v", err)
+	}
+	if !strings.Contains(errOut.String(), "[3/3] "+last+": merged 3 entries") {
+		t.Errorf("expected progress for every source, got %q", errOut.String())
+	}
+	if !strings.Contains(errOut.String(), "[2/3] "+broken) {
+		t.Errorf("expected the broken source to be reported, got %q", errOut.String())
+	}
+
+	config, err := clientcmd.LoadFromFile(kubeconfig)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, name := range []string{"a-cluster", "c-cluster", "cow-cluster"} {
+		if _, ok := config.Clusters[name]; !ok {
+			t.Errorf("expected cluster %q to be in the kubeconfig", name)
+		}
+	}
+	if config.CurrentContext != "federal-context" {
+		t.Errorf("expected the current context to be kept, got %q", config.CurrentContext)
+	}
+	if source, err := clientcmd.LoadFromFile(first); err != nil || len(source.Clusters) != 1 {
+		t.Errorf("expected the source file to be left alone, got %v, %v", source, err)
+	}
+
+	// The imported sources must not be read again on resume.
+	os.Remove(first)
+	os.Remove(last)
+	fixed := writeImportSource(t, sourceDir, "b-broken")
+
+	streams, _, _, errOut = genericclioptions.NewTestIOStreams()
+	options = &ImportOptions{
+		ConfigAccess:   pathOptions,
+		Sources:        []string{first, fixed, last},
+		BatchSize:      1,
+		Resume:         true,
+		CheckpointFile: checkpointFile,
+		IOStreams:      streams,
+	}
+	if err := options.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(errOut.String(), "Skipping 2 source(s) already imported.") {
+		t.Errorf("expected completed sources to be skipped, got %q", errOut.String())
+	}
+	config, err = clientcmd.LoadFromFile(kubeconfig)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := config.Contexts["b-broken"]; !ok {
+		t.Errorf("expected the fixed source to be imported")
+	}
+	if _, err := os.Stat(checkpointFile); !os.IsNotExist(err) {
+		t.Errorf("expected the checkpoint to be removed after a complete import, got %v", err)
+	}
+}
+
+func TestImportNamingTemplate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "import")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	source := writeImportSource(t, dir, "A.Cluster")
+	kubeconfig := filepath.Join(dir, "config")
+	if err := clientcmd.WriteToFile(newRedFederalCowHammerConfig(), kubeconfig); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = kubeconfig
+	pathOptions.EnvVar = ""
+
+	streams, _, _, errOut := genericclioptions.NewTestIOStreams()
+	options := &ImportOptions{
+		ConfigAccess:   pathOptions,
+		BatchSize:      defaultImportBatchSize,
+		CheckpointFile: filepath.Join(dir, "checkpoint.json"),
+		NamingTemplate: "team-{{.Cluster}}",
+		IOStreams:      streams,
+	}
+	if err := options.Complete(nil, []string{source}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := options.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := options.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	config, err := clientcmd.LoadFromFile(kubeconfig)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if context, ok := config.Contexts["team-a-cluster"]; !ok || context.Cluster != "A.Cluster" {
+		t.Errorf("expected the context to be named by the template, got %v", config.Contexts)
+	}
+	if _, ok := config.Contexts["A.Cluster"]; ok {
+		t.Errorf("expected the context not to keep its name")
+	}
+	if !strings.Contains(errOut.String(), `named context "A.Cluster" as "team-a-cluster"`) {
+		t.Errorf("expected the rename to be reported, got %q", errOut.String())
+	}
+
+	options.NamingTemplate = "{{.Missing"
+	if err := options.Validate(); err == nil {
+		t.Errorf("expected an invalid naming template to be rejected")
+	}
+}
+
+func TestMergeImportedConfig(t *testing.T) {
+	into := newRedFederalCowHammerConfig()
+	from := clientcmdapi.NewConfig()
+	from.Clusters["cow-cluster"] = &clientcmdapi.Cluster{Server: "https://other.example.com", LocationOfOrigin: "/tmp/source"}
+	from.Clusters["new-cluster"] = &clientcmdapi.Cluster{Server: "https://new.example.com", LocationOfOrigin: "/tmp/source"}
+
+	added, skipped := mergeImportedConfig(&into, from, false)
+	if added != 1 || len(skipped) != 1 || !strings.Contains(skipped[0], `cluster "cow-cluster"`) {
+		t.Errorf("expected one added and one skipped entry, got %d, %v", added, skipped)
+	}
+	if into.Clusters["new-cluster"].LocationOfOrigin != "" {
+		t.Errorf("expected imported entries to lose their origin, got %q", into.Clusters["new-cluster"].LocationOfOrigin)
+	}
+	if into.Clusters["cow-cluster"].Server != "http://cow.org:8080" {
+		t.Errorf("expected the existing cluster to be kept, got %q", into.Clusters["cow-cluster"].Server)
+	}
+
+	added, skipped = mergeImportedConfig(&into, from, true)
+	if added != 2 || len(skipped) != 0 {
+		t.Errorf("expected both entries to be merged with overwrite, got %d, %v", added, skipped)
+	}
+	if into.Clusters["cow-cluster"].Server != "https://other.example.com" {
+		t.Errorf("expected the existing cluster to be replaced, got %q", into.Clusters["cow-cluster"].Server)
+	}
+}
+
+func TestImportRequiresSignatures(t *testing.T) {
+	dir, err := ioutil.TempDir("", "import")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	privateFile, publicFile := writeSigningKeys(t, dir, key)
+	sourceDir := filepath.Join(dir, "sources")
+	if err := os.Mkdir(sourceDir, 0700); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	signed := writeImportSource(t, sourceDir, "signed")
+	unsigned := writeImportSource(t, sourceDir, "unsigned")
+	sign := &SignOptions{Bundle: signed, Key: privateFile, IOStreams: genericclioptions.NewTestIOStreamsDiscard()}
+	if err := sign.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := sign.RunSign(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	kubeconfig := filepath.Join(dir, "config")
+	if err := clientcmd.WriteToFile(newRedFederalCowHammerConfig(), kubeconfig); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = kubeconfig
+	pathOptions.EnvVar = ""
+
+	streams, _, _, errOut := genericclioptions.NewTestIOStreams()
+	options := &ImportOptions{
+		ConfigAccess:   pathOptions,
+		BatchSize:      defaultImportBatchSize,
+		CheckpointFile: filepath.Join(dir, "checkpoint.json"),
+		SignatureKey:   publicFile,
+		IOStreams:      streams,
+	}
+	if err := options.Complete(nil, []string{sourceDir}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(options.Sources) != 2 {
+		t.Fatalf("expected the signature file not to be a source, got %v", options.Sources)
+	}
+	err = options.Run()
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 sources could not be imported") {
+		t.Fatalf("expected the unsigned source to fail, got %v", err)
+	}
+	if !strings.Contains(errOut.String(), unsigned+".sig") {
+		t.Errorf("expected the missing signature to be reported, got %q", errOut.String())
+	}
+
+	config, err := clientcmd.LoadFromFile(kubeconfig)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := config.Clusters["signed"]; !ok {
+		t.Errorf("expected the signed source to be imported")
+	}
+	if _, ok := config.Clusters["unsigned"]; ok {
+		t.Errorf("expected the unsigned source not to be imported")
+	}
+}
@@ -0,0 +1,322 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+const (
+	// vclusterSelector selects the StatefulSets running vcluster control planes.
+	vclusterSelector = "app=vcluster"
+	// vclusterKubeconfigKey is the key of the kubeconfig in the secret vc-NAME of a vcluster.
+	vclusterKubeconfigKey = "config"
+	// vclusterServicePort is the port of the service vcluster creates for its API server.
+	vclusterServicePort = 443
+
+	defaultVClusterLocalPort = 11443
+)
+
+var validVClusterConnections = sets.NewString("auto", "ingress", "port-forward")
+
+// portForward is stored in the portForwardExtension of a cluster only reachable through a port
+// forwarded from another cluster. It holds what "kubectl port-forward" needs.
+type portForward struct {
+	Context   string `json:"context"`
+	Namespace string `json:"namespace"`
+	Service   string `json:"service"`
+	Port      int    `json:"port"`
+	LocalPort int    `json:"localPort"`
+}
+
+// ImportVClusterOptions holds the command-line options for 'config import vcluster' sub command
+type ImportVClusterOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	HostContext  string
+	Namespace    string
+	Connect      string
+	LocalPort    int
+	Sync         bool
+
+	Clientset kubernetes.Interface
+	log       *cmdLogger
+
+	genericclioptions.IOStreams
+}
+
+var (
+	importVClusterLong = templates.LongDesc(`
+		Import the vclusters running in a host cluster.
+
+		The vclusters of the host cluster are found by their StatefulSets, in every namespace
+		unless --namespace is given, and the kubeconfig each of them keeps in its vc-NAME secret
+		is merged under the name vcluster uses itself, vcluster_NAME_NAMESPACE_HOSTCONTEXT.
+
+		The kubeconfig of a vcluster points at the vcluster itself, so its server is replaced:
+
+		    * ingress: the host of the Ingress routing to the vcluster service
+		    * port-forward: localhost, on a port forwarded from the vcluster service; the
+		      "kubectl port-forward" command to run is printed, and recorded in the cluster
+		    * auto, the default: ingress when there is such an Ingress, port-forward otherwise
+
+		Every vcluster forwarded to gets its own local port starting at --local-port, which stays
+		the same across imports.
+
+		The imported entries are remembered for the host context. With --sync, they are replaced
+		by the current kubeconfigs and the entries of deleted vclusters are removed.`)
+
+	importVClusterExample = templates.Examples(`
+		# Import the vclusters of the host context
+		kubectl config import vcluster --host-context host
+
+		# Update the vclusters of a namespace and remove the deleted ones
+		kubectl config import vcluster --host-context host -n team-a --sync`)
+)
+
+// NewCmdConfigImportVCluster returns a Command instance for 'config import vcluster' sub command
+func NewCmdConfigImportVCluster(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &ImportVClusterOptions{
+		ConfigAccess: configAccess,
+		Connect:      "auto",
+		LocalPort:    defaultVClusterLocalPort,
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "vcluster --host-context=CONTEXT [--namespace=NAMESPACE] [--connect=auto|ingress|port-forward] [--sync]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Import the vclusters of a host cluster"),
+		Long:                  importVClusterLong,
+		Example:               importVClusterExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(cmdutil.UsageErrorf(cmd, "unexpected arguments: %v", args))
+			}
+			cmdutil.CheckErr(requireNetwork(cmd))
+			cmdutil.CheckErr(o.Complete(cmd))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().StringVar(&o.HostContext, "host-context", o.HostContext, "The context of the cluster the vclusters run in")
+	cmd.Flags().StringVarP(&o.Namespace, "namespace", "n", o.Namespace, "Only import the vclusters of this namespace")
+	cmd.Flags().StringVar(&o.Connect, "connect", o.Connect, "How to reach the vclusters: auto, ingress or port-forward")
+	cmd.Flags().IntVar(&o.LocalPort, "local-port", o.LocalPort, "The first local port vclusters are forwarded to")
+	cmd.Flags().BoolVar(&o.Sync, "sync", o.Sync, "If true, update the imported entries and remove those of deleted vclusters")
+	return cmd
+}
+
+// Complete connects to the host cluster
+func (o *ImportVClusterOptions) Complete(cmd *cobra.Command) error {
+	if len(o.HostContext) == 0 {
+		return helpErrorf(cmd, "--host-context is required")
+	}
+	var err error
+	if o.log, err = newCmdLogger(cmd, o.ErrOut); err != nil {
+		return err
+	}
+
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	if _, ok := config.Contexts[o.HostContext]; !ok {
+		return fmt.Errorf("no context exists with the name: %q", o.HostContext)
+	}
+	restConfig, err := clientcmd.NewNonInteractiveClientConfig(*config, o.HostContext, &clientcmd.ConfigOverrides{}, nil).ClientConfig()
+	if err != nil {
+		return err
+	}
+	restConfig.Timeout = 30 * time.Second
+	o.Clientset, err = kubernetes.NewForConfig(restConfig)
+	return err
+}
+
+// Validate checks the connection method and local port
+func (o *ImportVClusterOptions) Validate() error {
+	if !validVClusterConnections.Has(o.Connect) {
+		return fmt.Errorf("--connect must be one of %v, got %q", validVClusterConnections.List(), o.Connect)
+	}
+	if o.LocalPort <= 0 || o.LocalPort > 65535 {
+		return fmt.Errorf("--local-port must be a port number, got %d", o.LocalPort)
+	}
+	return nil
+}
+
+// Run performs the execution of 'config import vcluster' sub command
+func (o *ImportVClusterOptions) Run() error {
+	if o.Clientset == nil {
+		return errors.New("not connected to the host cluster")
+	}
+	log := o.log
+	if log == nil {
+		log, _ = newCmdLogger(nil, o.ErrOut)
+	}
+
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	statefulSets, err := o.Clientset.AppsV1().StatefulSets(o.Namespace).List(metav1.ListOptions{LabelSelector: vclusterSelector})
+	if err != nil {
+		return fmt.Errorf("listing the vclusters of %q: %v", o.HostContext, err)
+	}
+
+	imported := clientcmdapi.NewConfig()
+	ports := usedLocalPorts(config)
+	forwards := []portForward{}
+	for _, statefulSet := range statefulSets.Items {
+		namespace, name := statefulSet.Namespace, statefulSet.Name
+		secret, err := o.Clientset.CoreV1().Secrets(namespace).Get("vc-"+name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			log.Infof(0, "vcluster %s/%s has no kubeconfig yet, skipped", namespace, name)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("getting the kubeconfig of vcluster %s/%s: %v", namespace, name, err)
+		}
+		from, err := clientcmd.Load(secret.Data[vclusterKubeconfigKey])
+		if err != nil {
+			return fmt.Errorf("loading the kubeconfig of vcluster %s/%s: %v", namespace, name, err)
+		}
+		if err := stampProvenance(from, "vcluster", o.HostContext+"/"+namespace+"/"+name, time.Now()); err != nil {
+			return err
+		}
+		entryName := vclusterEntryName(name, namespace, o.HostContext)
+		if err := renameLocalClusterEntries(imported, from, entryName); err != nil {
+			return fmt.Errorf("vcluster %s/%s: %v", namespace, name, err)
+		}
+		cluster := imported.Clusters[entryName]
+
+		host := ""
+		if o.Connect != "port-forward" {
+			if host, err = o.vclusterIngressHost(namespace, name); err != nil {
+				return err
+			}
+			if len(host) == 0 && o.Connect == "ingress" {
+				return fmt.Errorf("no Ingress routes to vcluster %s/%s", namespace, name)
+			}
+		}
+		if len(host) > 0 {
+			cluster.Server = "https://" + host
+			delete(cluster.Extensions, portForwardExtension)
+			continue
+		}
+
+		forward := portForward{Context: o.HostContext, Namespace: namespace, Service: name, Port: vclusterServicePort}
+		existing := portForward{}
+		if previous, ok := config.Clusters[entryName]; ok {
+			if found, err := readExtension(previous.Extensions, portForwardExtension, &existing); err == nil && found {
+				forward.LocalPort = existing.LocalPort
+			}
+		}
+		if forward.LocalPort == 0 {
+			forward.LocalPort = o.LocalPort
+			for ports.Has(forward.LocalPort) {
+				forward.LocalPort++
+			}
+			ports.Insert(forward.LocalPort)
+		}
+		cluster.Server = fmt.Sprintf("https://localhost:%d", forward.LocalPort)
+		if forward == existing {
+			// The extension as loaded, so that an unchanged entry compares equal.
+			if cluster.Extensions == nil {
+				cluster.Extensions = map[string]runtime.Object{}
+			}
+			cluster.Extensions[portForwardExtension] = config.Clusters[entryName].Extensions[portForwardExtension]
+		} else if err := writeExtension(&cluster.Extensions, portForwardExtension, forward); err != nil {
+			return err
+		}
+		forwards = append(forwards, forward)
+	}
+
+	added, removed, err := importManagedEntries(config, vclustersExtension, o.HostContext, imported, o.Sync, log)
+	if err != nil {
+		return err
+	}
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "Merged %d entries from %d vcluster(s), removed %d.\n", added, len(imported.Contexts), removed)
+	for _, forward := range forwards {
+		fmt.Fprintf(o.Out, "  kubectl --context %s -n %s port-forward svc/%s %d:%d\n", forward.Context, forward.Namespace, forward.Service, forward.LocalPort, forward.Port)
+	}
+	return nil
+}
+
+// vclusterIngressHost returns the host of an Ingress routing to the service of a vcluster, or an
+// empty string if there is none.
+func (o *ImportVClusterOptions) vclusterIngressHost(namespace, name string) (string, error) {
+	ingresses, err := o.Clientset.NetworkingV1beta1().Ingresses(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return "", fmt.Errorf("listing the ingresses of %s: %v", namespace, err)
+	}
+	for _, ingress := range ingresses.Items {
+		for _, rule := range ingress.Spec.Rules {
+			if len(rule.Host) == 0 {
+				continue
+			}
+			if ingress.Spec.Backend != nil && ingress.Spec.Backend.ServiceName == name {
+				return rule.Host, nil
+			}
+			if rule.HTTP == nil {
+				continue
+			}
+			for _, path := range rule.HTTP.Paths {
+				if path.Backend.ServiceName == name {
+					return rule.Host, nil
+				}
+			}
+		}
+	}
+	return "", nil
+}
+
+// usedLocalPorts returns the local ports clusters of the kubeconfig are forwarded to.
+func usedLocalPorts(config *clientcmdapi.Config) sets.Int {
+	ports := sets.NewInt()
+	for _, cluster := range config.Clusters {
+		forward := portForward{}
+		if found, err := readExtension(cluster.Extensions, portForwardExtension, &forward); err == nil && found {
+			ports.Insert(forward.LocalPort)
+		}
+	}
+	return ports
+}
+
+// vclusterEntryName is the name the vcluster command line gives the entries of a vcluster.
+func vclusterEntryName(name, namespace, hostContext string) string {
+	return fmt.Sprintf("vcluster_%s_%s_%s", name, namespace, hostContext)
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func newVCluster(namespace, name string) []runtime.Object {
+	return []runtime.Object{
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: map[string]string{"app": "vcluster", "release": name}}},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "vc-" + name},
+			Data:       map[string][]byte{vclusterKubeconfigKey: []byte(localClusterKubeconfig("my-vcluster", "https://localhost:8443"))},
+		},
+	}
+}
+
+func TestImportVCluster(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	if err := clientcmd.WriteToFile(newRedFederalCowHammerConfig(), fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	ingress := &networkingv1beta1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-b", Name: "vcluster"},
+		Spec: networkingv1beta1.IngressSpec{Rules: []networkingv1beta1.IngressRule{{
+			Host: "ci.vcluster.example.com",
+			IngressRuleValue: networkingv1beta1.IngressRuleValue{HTTP: &networkingv1beta1.HTTPIngressRuleValue{
+				Paths: []networkingv1beta1.HTTPIngressPath{{Backend: networkingv1beta1.IngressBackend{ServiceName: "ci"}}},
+			}},
+		}}},
+	}
+	importVCluster := func(sync bool, objects ...runtime.Object) string {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		o := &ImportVClusterOptions{
+			ConfigAccess: pathOptions,
+			HostContext:  "federal-context",
+			Connect:      "auto",
+			LocalPort:    defaultVClusterLocalPort,
+			Sync:         sync,
+			Clientset:    fake.NewSimpleClientset(objects...),
+			IOStreams:    streams,
+		}
+		if err := o.Run(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return out.String()
+	}
+
+	objects := append(append(newVCluster("team-a", "dev"), newVCluster("team-a", "test")...), append(newVCluster("team-b", "ci"), ingress)...)
+	expected := "Merged 9 entries from 3 vcluster(s), removed 0.\n" +
+		"  kubectl --context federal-context -n team-a port-forward svc/dev 11443:443\n" +
+		"  kubectl --context federal-context -n team-a port-forward svc/test 11444:443\n"
+	if out := importVCluster(false, objects...); out != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out)
+	}
+	config, err := clientcmd.LoadFromFile(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for name, server := range map[string]string{
+		"vcluster_dev_team-a_federal-context":  "https://localhost:11443",
+		"vcluster_test_team-a_federal-context": "https://localhost:11444",
+		"vcluster_ci_team-b_federal-context":   "https://ci.vcluster.example.com",
+	} {
+		if cluster := config.Clusters[name]; cluster == nil || cluster.Server != server {
+			t.Errorf("expected %s to point at %s, got %#v", name, server, cluster)
+		}
+	}
+	forward := portForward{}
+	if found, err := readExtension(config.Clusters["vcluster_test_team-a_federal-context"].Extensions, portForwardExtension, &forward); err != nil || !found {
+		t.Fatalf("expected the port forward to be recorded, got %v", err)
+	}
+	if !reflect.DeepEqual(forward, portForward{Context: "federal-context", Namespace: "team-a", Service: "test", Port: 443, LocalPort: 11444}) {
+		t.Errorf("unexpected port forward %#v", forward)
+	}
+
+	// dev was deleted: test keeps its port, and the freed port is not reassigned to test.
+	expected = "Merged 6 entries from 2 vcluster(s), removed 3.\n" +
+		"  kubectl --context federal-context -n team-a port-forward svc/test 11444:443\n"
+	objects = append(newVCluster("team-a", "test"), append(newVCluster("team-b", "ci"), ingress)...)
+	if out := importVCluster(true, objects...); out != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out)
+	}
+	config, err = clientcmd.LoadFromFile(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := config.Contexts["vcluster_dev_team-a_federal-context"]; ok {
+		t.Errorf("expected the deleted vcluster to be removed")
+	}
+
+	if err := (&ImportVClusterOptions{Connect: "vpn", LocalPort: 1}).Validate(); err == nil {
+		t.Errorf("expected an error for an unknown connection method")
+	}
+}
@@ -0,0 +1,291 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/printers"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// IncludeOptions holds the command-line options for 'config include' sub commands
+type IncludeOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Patterns     []string
+
+	log *cmdLogger
+
+	genericclioptions.IOStreams
+}
+
+var (
+	includeLong = templates.LongDesc(`
+		Include other kubeconfig files in the kubeconfig, like the Include directive of ssh_config.
+
+		The included files or glob patterns are recorded in the kubecfg.io/include extension of the
+		kubeconfig preferences. "include sync" merges the entries of every matching file into the
+		kubeconfig, replacing the entries written by the previous sync and removing those whose
+		file is gone, so that a directory of kubeconfig files can be used without listing each of
+		them in $KUBECONFIG. Entries of the kubeconfig itself are never replaced by included ones.
+
+		"include add" and "include remove" sync right away; run "include sync" after changing the
+		included files.`)
+
+	includeExample = templates.Examples(`
+		# Include every kubeconfig file of a directory, including ones added later
+		kubectl config include add '~/.kube/configs/*.yaml'
+
+		# Pick up changes of the included files
+		kubectl config include sync
+
+		# Stop including a pattern and remove its entries
+		kubectl config include remove '~/.kube/configs/*.yaml'`)
+)
+
+// NewCmdConfigInclude returns a Command instance for 'config include' sub command
+func NewCmdConfigInclude(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &IncludeOptions{
+		ConfigAccess: configAccess,
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "include SUBCOMMAND",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Merge other kubeconfig files into the kubeconfig and keep them up to date"),
+		Long:                  includeLong,
+		Example:               includeExample,
+		Run:                   cmdutil.DefaultSubCommandRun(streams.ErrOut),
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "add PATTERN...",
+		Short: i18n.T("Include kubeconfig files and sync"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			cmdutil.CheckErr(o.Complete(cmd, args))
+			cmdutil.CheckErr(o.RunAdd())
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "remove PATTERN...",
+		Short: i18n.T("Stop including kubeconfig files and sync"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			cmdutil.CheckErr(o.Complete(cmd, args))
+			cmdutil.CheckErr(o.RunRemove())
+		},
+	})
+	cmd.AddCommand(noWriteCommand(&cobra.Command{
+		Use:   "list",
+		Short: i18n.T("List the included patterns and the files they match"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			cmdutil.CheckErr(o.RunList())
+		},
+	}))
+	cmd.AddCommand(&cobra.Command{
+		Use:   "sync",
+		Short: i18n.T("Merge the included files into the kubeconfig again"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			cmdutil.CheckErr(o.Complete(cmd, nil))
+			cmdutil.CheckErr(o.RunSync())
+		},
+	})
+	return cmd
+}
+
+// Complete makes the patterns absolute and sets up logging
+func (o *IncludeOptions) Complete(cmd *cobra.Command, args []string) error {
+	o.Patterns = nil
+	for _, arg := range args {
+		pattern, err := filepath.Abs(expandPath(arg))
+		if err != nil {
+			return err
+		}
+		o.Patterns = append(o.Patterns, pattern)
+	}
+	var err error
+	o.log, err = newCmdLogger(cmd, o.ErrOut)
+	return err
+}
+
+// RunAdd records the patterns and syncs
+func (o *IncludeOptions) RunAdd() error {
+	return o.update(func(state *includes) error {
+		for _, pattern := range o.Patterns {
+			if !containsString(state.Patterns, pattern) {
+				state.Patterns = append(state.Patterns, pattern)
+			}
+		}
+		return nil
+	})
+}
+
+// RunRemove forgets the patterns and syncs, which removes the entries of files no longer included
+func (o *IncludeOptions) RunRemove() error {
+	return o.update(func(state *includes) error {
+		for _, pattern := range o.Patterns {
+			if !containsString(state.Patterns, pattern) {
+				return fmt.Errorf("%s is not included", pattern)
+			}
+			state.Patterns = removeString(state.Patterns, pattern)
+		}
+		return nil
+	})
+}
+
+// RunSync merges the included files into the kubeconfig again
+func (o *IncludeOptions) RunSync() error {
+	return o.update(func(*includes) error { return nil })
+}
+
+// RunList prints the included patterns with the number of files each matches
+func (o *IncludeOptions) RunList() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	state := &includes{}
+	if _, err := readExtension(config.Preferences.Extensions, includeExtension, state); err != nil {
+		return err
+	}
+
+	w := printers.GetNewTabWriter(o.Out)
+	fmt.Fprintf(w, "PATTERN\tFILES\n")
+	for _, pattern := range state.Patterns {
+		files, err := expandProfileFiles([]string{pattern})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\t%d\n", pattern, len(files))
+	}
+	return w.Flush()
+}
+
+// update applies change to the include state, syncs the included entries and writes the kubeconfig.
+func (o *IncludeOptions) update(change func(*includes) error) error {
+	log := o.log
+	if log == nil {
+		log, _ = newCmdLogger(nil, o.ErrOut)
+	}
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	state := &includes{}
+	if _, err := readExtension(config.Preferences.Extensions, includeExtension, state); err != nil {
+		return err
+	}
+	if err := change(state); err != nil {
+		return err
+	}
+
+	included, err := o.loadIncluded(state.Patterns, log)
+	if err != nil {
+		return err
+	}
+	added, removed := syncManagedEntries(config, included, &state.managedEntries, log)
+	if len(state.Patterns) == 0 {
+		delete(config.Preferences.Extensions, includeExtension)
+	} else if err := writeExtension(&config.Preferences.Extensions, includeExtension, state); err != nil {
+		return err
+	}
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "Included %d entries, removed %d.\n", added, removed)
+	return nil
+}
+
+// loadIncluded merges the files matching patterns, the first file defining a name winning. Files of
+// the kubeconfig chain are skipped, as their entries are loaded anyway.
+func (o *IncludeOptions) loadIncluded(patterns []string, log *cmdLogger) (*clientcmdapi.Config, error) {
+	chain := map[string]bool{}
+	for _, filename := range append(o.ConfigAccess.GetLoadingPrecedence(), o.ConfigAccess.GetExplicitFile()) {
+		if abs, err := filepath.Abs(filename); err == nil && len(filename) > 0 {
+			chain[abs] = true
+		}
+	}
+
+	files, err := expandProfileFiles(patterns)
+	if err != nil {
+		return nil, err
+	}
+	included := clientcmdapi.NewConfig()
+	for _, filename := range files {
+		if chain[filename] {
+			log.Warningf("%s: skipped, the file is part of the kubeconfig already", filename)
+			continue
+		}
+		from, err := clientcmd.LoadFromFile(filename)
+		if os.IsNotExist(err) {
+			log.Infof(1, "%s: skipped, the file does not exist", filename)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := clientcmd.ResolveLocalPaths(from); err != nil {
+			return nil, err
+		}
+		_, skipped := mergeImportedConfig(included, from, false)
+		for _, reason := range skipped {
+			log.Warningf("%s: skipped %s", filename, reason)
+		}
+	}
+	return included, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func removeString(values []string, value string) []string {
+	result := []string{}
+	for _, v := range values {
+		if v != value {
+			result = append(result, v)
+		}
+	}
+	return result
+}
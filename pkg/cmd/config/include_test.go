@@ -0,0 +1,131 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestIncludeSync(t *testing.T) {
+	dir, err := ioutil.TempDir("", "include")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	kubeconfig := filepath.Join(dir, "config")
+	if err := clientcmd.WriteToFile(newRedFederalCowHammerConfig(), kubeconfig); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	included := filepath.Join(dir, "configs")
+	if err := os.Mkdir(included, 0700); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	writeIncluded := func(name string, config clientcmdapi.Config) string {
+		filename := filepath.Join(included, name)
+		if err := clientcmd.WriteToFile(config, filename); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return filename
+	}
+	writeIncluded("blue.yaml", clientcmdapi.Config{
+		Clusters: map[string]*clientcmdapi.Cluster{"blue-cluster": {Server: "https://blue.org"}},
+		Contexts: map[string]*clientcmdapi.Context{"blue-context": {Cluster: "blue-cluster"}},
+	})
+	green := writeIncluded("green.yaml", clientcmdapi.Config{
+		Clusters: map[string]*clientcmdapi.Cluster{
+			"green-cluster": {Server: "https://green.org"},
+			"cow-cluster":   {Server: "https://impostor.org"},
+		},
+	})
+
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = kubeconfig
+	pathOptions.EnvVar = ""
+	streams, _, out, errOut := genericclioptions.NewTestIOStreams()
+	options := &IncludeOptions{ConfigAccess: pathOptions, IOStreams: streams}
+	if err := options.Complete(nil, []string{filepath.Join(included, "*.yaml")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := options.RunAdd(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "Included 3 entries, removed 0.\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+	if !strings.Contains(errOut.String(), `skipped cluster "cow-cluster", which already exists`) {
+		t.Errorf("expected a warning about cow-cluster, got %q", errOut.String())
+	}
+
+	config, err := clientcmd.LoadFromFile(kubeconfig)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Clusters["cow-cluster"].Server != "http://cow.org:8080" {
+		t.Errorf("expected the kubeconfig's own cow-cluster to be kept, got %q", config.Clusters["cow-cluster"].Server)
+	}
+	state := includes{}
+	if _, err := readExtension(config.Preferences.Extensions, includeExtension, &state); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := includes{
+		Patterns: []string{filepath.Join(included, "*.yaml")},
+		managedEntries: managedEntries{
+			Clusters: []string{"blue-cluster", "green-cluster"},
+			Contexts: []string{"blue-context"},
+		},
+	}
+	if !reflect.DeepEqual(state, expected) {
+		t.Errorf("expected include state %#v, got %#v", expected, state)
+	}
+
+	if err := os.Remove(green); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out.Reset()
+	if err := options.Complete(nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := options.RunSync(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "Included 2 entries, removed 1.\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+	config, err = clientcmd.LoadFromFile(kubeconfig)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := config.Clusters["green-cluster"]; ok {
+		t.Errorf("expected green-cluster to be removed with its file")
+	}
+	if _, ok := config.Clusters["cow-cluster"]; !ok {
+		t.Errorf("expected cow-cluster to be kept")
+	}
+	if _, ok := config.Contexts["blue-context"]; !ok {
+		t.Errorf("expected blue-context to be kept")
+	}
+}
@@ -0,0 +1,497 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// InitOptions holds the command-line options for 'config init' sub command
+type InitOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Shell        string
+	RCFile       string
+	// SettingsFile is where safe mode is enabled for new installs, none when empty.
+	SettingsFile string
+
+	// RunCommand runs the CLIs of the cloud providers.
+	RunCommand commandRunner
+	// Check validates the connectivity of the new context.
+	Check func(config *clientcmdapi.Config, context string) contextHealth
+
+	cmd *cobra.Command
+	in  *bufio.Reader
+
+	genericclioptions.IOStreams
+}
+
+// Where the cluster set up by "config init" comes from, in the order they are offered.
+const (
+	initManual = iota
+	initEKS
+	initGKE
+	initAKS
+)
+
+var initSources = []string{
+	initManual: "enter its server and credentials",
+	initEKS:    "import it from Amazon EKS, with the aws CLI",
+	initGKE:    "import it from Google GKE, with the gcloud CLI",
+	initAKS:    "import it from Azure AKS, with the az CLI",
+}
+
+var (
+	initLong = templates.LongDesc(`
+		Walks through setting up a first cluster, user and context, which becomes the
+		current-context.
+
+		The cluster is either entered by hand, from its server, its certificate authority and the
+		credentials of the user, or imported with the CLI of a cloud provider: aws for EKS, gcloud for
+		GKE and az for AKS. Entries already in the kubeconfig are kept.
+
+		When there is no settings file yet, the safeMode setting is enabled, which asks to
+		acknowledge switching to contexts tagged or named like production every 30 minutes and
+		warns about contexts whose credentials do not work.
+
+		At the end, the connectivity of the new context is checked, unless network access is
+		disabled, and the completion of kubectl and the shell integration of the config
+		subcommands, see "kubectl config shell-init", can be added to the startup file of the
+		shell.`)
+
+	initExample = templates.Examples(`
+		# Set up a first cluster
+		kubectl config init
+
+		# Set up a cluster in a new kubeconfig file
+		kubectl config init --kubeconfig ~/.kube/lab`)
+)
+
+// NewCmdConfigInit returns a Command instance for 'config init' sub command
+func NewCmdConfigInit(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &InitOptions{
+		ConfigAccess: configAccess,
+		SettingsFile: settingsFile(),
+		RunCommand:   runCommand,
+		Check:        checkContextHealth,
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "init [--shell=bash|zsh|fish|pwsh]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Set up a first cluster, user and context interactively"),
+		Long:                  initLong,
+		Example:               initExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(cmd, args))
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().StringVar(&o.Shell, "shell", o.Shell, "Shell to install the completion and the shell integration for. Defaults to the shell in $SHELL")
+	cmd.Flags().StringVar(&o.RCFile, "rc-file", o.RCFile, "Startup file the completion and the shell integration are added to. Defaults to the usual file of the shell")
+	return cmd
+}
+
+// Complete defaults the shell to the login shell of the user
+func (o *InitOptions) Complete(cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return helpErrorf(cmd, "Unexpected args: %v", args)
+	}
+	o.cmd = cmd
+	if len(o.Shell) == 0 {
+		o.Shell = filepath.Base(os.Getenv("SHELL"))
+	}
+	return nil
+}
+
+// Run performs the execution of 'config init' sub command
+func (o *InitOptions) Run() error {
+	o.in = bufio.NewReader(o.In)
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "This sets up a cluster, a user and a context in %s.\n", o.ConfigAccess.GetDefaultFilename())
+	if len(config.Contexts) > 0 {
+		fmt.Fprintf(o.Out, "The %d context(s) it has already are kept.\n", len(config.Contexts))
+	}
+
+	source, err := o.choose("Where is the cluster?", initSources)
+	if err != nil {
+		return err
+	}
+	var context string
+	if source == initManual {
+		context, err = o.enterCluster(config)
+	} else {
+		context, err = o.importCluster(config, source)
+	}
+	if err != nil {
+		return err
+	}
+	config.CurrentContext = context
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "Switched to context %q.\n", context)
+	if err := o.enableSafeMode(); err != nil {
+		return err
+	}
+
+	o.checkConnectivity(config, context)
+	return o.installShellIntegration()
+}
+
+// enableSafeMode turns on the safeMode setting when there is no settings file yet, which is the
+// case of new installs.
+func (o *InitOptions) enableSafeMode() error {
+	if len(o.SettingsFile) == 0 {
+		return nil
+	}
+	if _, err := os.Stat(o.SettingsFile); !os.IsNotExist(err) {
+		return err
+	}
+	if err := saveSettings(o.SettingsFile, &Settings{SafeMode: true}); err != nil {
+		return err
+	}
+	fmt.Fprintln(o.Out, `Enabled safe mode, which protects the contexts tagged or named like production. Turn it off with "kubectl config settings set safeMode false".`)
+	return nil
+}
+
+// enterCluster adds the cluster, user and context the user describes, and returns the name of the
+// context.
+func (o *InitOptions) enterCluster(config *clientcmdapi.Config) (string, error) {
+	cluster := clientcmdapi.NewCluster()
+	for {
+		server, err := o.ask("Server of the cluster, such as https://1.2.3.4:6443", "")
+		if err != nil {
+			return "", err
+		}
+		if u, err := url.Parse(server); err == nil && (u.Scheme == "https" || u.Scheme == "http") && len(u.Host) > 0 {
+			cluster.Server = server
+			break
+		}
+		fmt.Fprintf(o.Out, "%q is not the URL of a server.\n", server)
+	}
+
+	var name string
+	for {
+		u, _ := url.Parse(cluster.Server)
+		answer, err := o.ask("Name of the cluster, user and context", u.Hostname())
+		if err != nil {
+			return "", err
+		}
+		if config.Clusters[answer] == nil && config.AuthInfos[answer] == nil && config.Contexts[answer] == nil {
+			name = answer
+			break
+		}
+		fmt.Fprintf(o.Out, "%q is already used.\n", answer)
+	}
+
+	ca, err := o.askFile("Certificate authority file of the server, empty to trust the system's")
+	if err != nil {
+		return "", err
+	}
+	cluster.CertificateAuthority = ca
+
+	authInfo := clientcmdapi.NewAuthInfo()
+	credentials, err := o.choose("How does the user authenticate?", []string{"with a bearer token", "with a client certificate", "not yet, I will set the credentials later"})
+	if err != nil {
+		return "", err
+	}
+	switch credentials {
+	case 0:
+		// The token is read from a file rather than typed, so that it is not echoed on the terminal.
+		tokenFile, err := o.askFile("File holding the bearer token")
+		if err != nil {
+			return "", err
+		}
+		if len(tokenFile) > 0 {
+			token, err := ioutil.ReadFile(tokenFile)
+			if err != nil {
+				return "", err
+			}
+			authInfo.Token = strings.TrimSpace(string(token))
+		}
+	case 1:
+		if authInfo.ClientCertificate, err = o.askFile("Client certificate file"); err != nil {
+			return "", err
+		}
+		if authInfo.ClientKey, err = o.askFile("Client key file"); err != nil {
+			return "", err
+		}
+	}
+
+	namespace, err := o.ask("Namespace", "default")
+	if err != nil {
+		return "", err
+	}
+	context := clientcmdapi.NewContext()
+	context.Cluster, context.AuthInfo = name, name
+	if namespace != "default" {
+		context.Namespace = namespace
+	}
+
+	config.Clusters[name] = cluster
+	config.AuthInfos[name] = authInfo
+	config.Contexts[name] = context
+	return name, nil
+}
+
+// importCluster writes the credentials of a cloud cluster to a temporary kubeconfig with the CLI of
+// its provider, merges them and returns the name of the imported context.
+func (o *InitOptions) importCluster(config *clientcmdapi.Config, source int) (string, error) {
+	if err := requireNetwork(o.cmd); err != nil {
+		return "", err
+	}
+	dir, err := ioutil.TempDir("", "kubectl-config-init")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	kubeconfig := filepath.Join(dir, "config")
+
+	var env []string
+	var command string
+	var args []string
+	// kind and identity of the source, for the provenance of the imported entries.
+	var kind, identity string
+	switch source {
+	case initEKS:
+		name, err := o.ask("Name of the EKS cluster", "")
+		if err != nil {
+			return "", err
+		}
+		region, err := o.ask("Region", os.Getenv("AWS_REGION"))
+		if err != nil {
+			return "", err
+		}
+		profile, err := o.ask("AWS profile, empty for the default one", "")
+		if err != nil {
+			return "", err
+		}
+		command, args = "aws", []string{"eks", "update-kubeconfig", "--name", name, "--region", region, "--kubeconfig", kubeconfig}
+		kind, identity = "eks", region+"/"+name
+		if len(profile) > 0 {
+			args = append(args, "--profile", profile)
+		}
+	case initGKE:
+		name, err := o.ask("Name of the GKE cluster", "")
+		if err != nil {
+			return "", err
+		}
+		location, err := o.ask("Region or zone", "")
+		if err != nil {
+			return "", err
+		}
+		project, err := o.ask("Project, empty for the one gcloud is configured with", "")
+		if err != nil {
+			return "", err
+		}
+		// gcloud has no flag for the kubeconfig it writes to.
+		env = []string{"KUBECONFIG=" + kubeconfig}
+		command, args = "gcloud", []string{"container", "clusters", "get-credentials", name, "--location", location}
+		kind, identity = "gke", location+"/"+name
+		if len(project) > 0 {
+			args = append(args, "--project", project)
+			identity = project + "/" + identity
+		}
+	case initAKS:
+		name, err := o.ask("Name of the AKS cluster", "")
+		if err != nil {
+			return "", err
+		}
+		group, err := o.ask("Resource group", "")
+		if err != nil {
+			return "", err
+		}
+		command, args = "az", []string{"aks", "get-credentials", "--name", name, "--resource-group", group, "--file", kubeconfig}
+		kind, identity = "aks", group+"/"+name
+	}
+
+	fmt.Fprintf(o.Out, "Running %s %s\n", command, strings.Join(args, " "))
+	if _, err := o.RunCommand(env, command, args...); err != nil {
+		return "", fmt.Errorf("%s failed: %v", command, err)
+	}
+	from, err := clientcmd.LoadFromFile(kubeconfig)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := from.Contexts[from.CurrentContext]; !ok {
+		return "", fmt.Errorf("%s did not write a context", command)
+	}
+	if err := stampProvenance(from, kind, identity, time.Now()); err != nil {
+		return "", err
+	}
+	// The CLIs replace the entries they wrote before, so does importing them again.
+	added, _ := mergeImportedConfig(config, from, true)
+	fmt.Fprintf(o.Out, "Imported %d entries.\n", added)
+	return from.CurrentContext, nil
+}
+
+// checkConnectivity reports whether the new context can be used. Failing to reach the cluster does
+// not fail the command, the entries can still be fixed afterwards.
+func (o *InitOptions) checkConnectivity(config *clientcmdapi.Config, context string) {
+	if networkDisabled(o.cmd) {
+		fmt.Fprintln(o.Out, "Network access is disabled, the connectivity of the context was not checked.")
+		return
+	}
+	health := o.Check(config, context)
+	if health.Healthy() {
+		fmt.Fprintf(o.Out, "Connected to %s.\n", health.Server)
+		return
+	}
+	fmt.Fprintf(o.Out, "The context cannot be used yet: %s", health.Status())
+	if len(health.Error) > 0 {
+		fmt.Fprintf(o.Out, ", %s", health.Error)
+	}
+	fmt.Fprintln(o.Out, `. "kubectl config doctor" helps finding out why.`)
+}
+
+// installShellIntegration offers to load the completion of kubectl and the shell integration from
+// the startup file of the shell.
+func (o *InitOptions) installShellIntegration() error {
+	integration, ok := shellIntegrations[o.Shell]
+	if !ok {
+		return nil
+	}
+	rcFile := o.RCFile
+	if len(rcFile) == 0 {
+		rcFile = integration.rcFile()
+	}
+	for _, item := range []struct {
+		question, comment, line string
+	}{
+		{"Install the completion of kubectl for " + o.Shell, "kubectl completion", integration.completion},
+		{"Install the shell integration, with kcfg and $KCFG_PROMPT, for " + o.Shell, "kubectl config shell integration", integration.loader},
+	} {
+		install, err := o.confirm(item.question)
+		if err != nil {
+			return err
+		}
+		if !install {
+			continue
+		}
+		added, err := appendToRCFile(rcFile, item.comment, item.line)
+		if err != nil {
+			return err
+		}
+		if added {
+			fmt.Fprintf(o.Out, "Added to %s, start a new shell to load it.\n", rcFile)
+		} else {
+			fmt.Fprintf(o.Out, "Already loaded by %s.\n", rcFile)
+		}
+	}
+	return nil
+}
+
+// ask prints question and returns the answer, or defaultValue when the answer is empty. A question
+// without default value is asked again until it is answered.
+func (o *InitOptions) ask(question, defaultValue string) (string, error) {
+	prompt := question + ": "
+	if len(defaultValue) > 0 {
+		prompt = fmt.Sprintf("%s [%s]: ", question, defaultValue)
+	}
+	for {
+		answer, err := o.readAnswer(prompt)
+		if err != nil {
+			return "", err
+		}
+		if len(answer) == 0 {
+			answer = defaultValue
+		}
+		if len(answer) > 0 {
+			return answer, nil
+		}
+	}
+}
+
+// askFile asks for the path of an existing file, which may be left empty, and returns it absolute.
+func (o *InitOptions) askFile(question string) (string, error) {
+	for {
+		path, err := o.readAnswer(question + ": ")
+		if err != nil || len(path) == 0 {
+			return "", err
+		}
+		if _, err := os.Stat(path); err != nil {
+			fmt.Fprintf(o.Out, "%v\n", err)
+			continue
+		}
+		return filepath.Abs(path)
+	}
+}
+
+// confirm asks a yes or no question, no being the default.
+func (o *InitOptions) confirm(question string) (bool, error) {
+	answer, err := o.readAnswer(question + "? [y/N]: ")
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
+
+// readAnswer prints prompt and returns the line answered, without surrounding spaces.
+func (o *InitOptions) readAnswer(prompt string) (string, error) {
+	fmt.Fprint(o.Out, prompt)
+	answer, err := o.in.ReadString('\n')
+	if err != nil && (err != io.EOF || len(answer) == 0) {
+		return "", fmt.Errorf("no answer to %q: %v", strings.TrimSuffix(prompt, ": "), err)
+	}
+	return strings.TrimSpace(answer), nil
+}
+
+// choose asks to pick one of choices, and returns its index.
+func (o *InitOptions) choose(question string, choices []string) (int, error) {
+	for {
+		fmt.Fprintln(o.Out, question)
+		for i, choice := range choices {
+			fmt.Fprintf(o.Out, "  %d) %s\n", i+1, choice)
+		}
+		answer, err := o.ask("Choice", "1")
+		if err != nil {
+			return 0, err
+		}
+		if i, err := strconv.Atoi(answer); err == nil && i >= 1 && i <= len(choices) {
+			return i - 1, nil
+		}
+		fmt.Fprintf(o.Out, "%q is not one of the choices.\n", answer)
+	}
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func newInitTestDir(t *testing.T) (string, *clientcmd.PathOptions) {
+	dir, err := ioutil.TempDir("", "init")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := clientcmd.WriteToFile(newRedFederalCowHammerConfig(), filepath.Join(dir, "config")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = filepath.Join(dir, "config")
+	pathOptions.EnvVar = ""
+	return dir, pathOptions
+}
+
+func TestInitManual(t *testing.T) {
+	dir, pathOptions := newInitTestDir(t)
+	defer os.RemoveAll(dir)
+	for name, content := range map[string]string{"ca.crt": "ca", "token": "s3cr3t\n"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	rcFile := filepath.Join(dir, ".bashrc")
+
+	streams, in, out, _ := genericclioptions.NewTestIOStreams()
+	fmt.Fprint(in, strings.Join([]string{
+		"1",
+		"cow.org:8080",
+		"https://10.0.0.1:6443",
+		"",
+		filepath.Join(dir, "missing.crt"),
+		filepath.Join(dir, "ca.crt"),
+		"1",
+		filepath.Join(dir, "token"),
+		"dev",
+		"y",
+		"",
+	}, "\n")+"\n")
+	checked := ""
+	o := &InitOptions{
+		ConfigAccess: pathOptions,
+		Shell:        "bash",
+		RCFile:       rcFile,
+		Check: func(config *clientcmdapi.Config, context string) contextHealth {
+			checked = context
+			return contextHealth{Context: context, Server: config.Clusters[context].Server, Reachable: true, Authenticated: true}
+		},
+		IOStreams: streams,
+	}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, expected := range []string{
+		`"cow.org:8080" is not the URL of a server.`,
+		"Switched to context \"10.0.0.1\".\n",
+		"Connected to https://10.0.0.1:6443.\n",
+		"Added to " + rcFile,
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected the output to contain %q, got\n%s", expected, out.String())
+		}
+	}
+	if checked != "10.0.0.1" {
+		t.Errorf("expected the new context to be checked, got %q", checked)
+	}
+
+	config, err := clientcmd.LoadFromFile(pathOptions.GlobalFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.CurrentContext != "10.0.0.1" {
+		t.Errorf("expected the new context to be the current-context, got %q", config.CurrentContext)
+	}
+	if _, ok := config.Contexts["federal-context"]; !ok {
+		t.Errorf("expected the existing contexts to be kept")
+	}
+	cluster := config.Clusters["10.0.0.1"]
+	if cluster == nil || cluster.Server != "https://10.0.0.1:6443" || cluster.CertificateAuthority != filepath.Join(dir, "ca.crt") {
+		t.Errorf("unexpected cluster %#v", cluster)
+	}
+	if authInfo := config.AuthInfos["10.0.0.1"]; authInfo == nil || authInfo.Token != "s3cr3t" {
+		t.Errorf("expected the token to be read from its file, got %#v", authInfo)
+	}
+	if context := config.Contexts["10.0.0.1"]; context == nil || context.Namespace != "dev" || context.Cluster != "10.0.0.1" {
+		t.Errorf("unexpected context %#v", context)
+	}
+
+	data, err := ioutil.ReadFile(rcFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data) != "# kubectl completion\nsource <(kubectl completion bash)\n" {
+		t.Errorf("expected only the completion to be installed, got %q", string(data))
+	}
+}
+
+func TestInitGKE(t *testing.T) {
+	dir, pathOptions := newInitTestDir(t)
+	defer os.RemoveAll(dir)
+
+	streams, in, out, _ := genericclioptions.NewTestIOStreams()
+	fmt.Fprint(in, "3\nprod\neurope-west1\nshop\n")
+	o := &InitOptions{
+		ConfigAccess: pathOptions,
+		Shell:        "tcsh",
+		RunCommand: func(env []string, name string, args ...string) ([]byte, error) {
+			command := strings.Join(append([]string{name}, args...), " ")
+			if command != "gcloud container clusters get-credentials prod --location europe-west1 --project shop" {
+				return nil, fmt.Errorf("unexpected command %q", command)
+			}
+			if len(env) != 1 || !strings.HasPrefix(env[0], "KUBECONFIG=") {
+				return nil, fmt.Errorf("expected the kubeconfig in the environment, got %v", env)
+			}
+			return nil, ioutil.WriteFile(strings.TrimPrefix(env[0], "KUBECONFIG="), []byte(localClusterKubeconfig("gke_shop_europe-west1_prod", "https://34.1.2.3")), 0600)
+		},
+		Check: func(config *clientcmdapi.Config, context string) contextHealth {
+			return contextHealth{Context: context, Reachable: true, Error: "Unauthorized"}
+		},
+		IOStreams: streams,
+	}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, expected := range []string{
+		"Imported 3 entries.\n",
+		"Switched to context \"gke_shop_europe-west1_prod\".\n",
+		"The context cannot be used yet: unauthenticated, Unauthorized.",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected the output to contain %q, got\n%s", expected, out.String())
+		}
+	}
+	config, err := clientcmd.LoadFromFile(pathOptions.GlobalFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.CurrentContext != "gke_shop_europe-west1_prod" || config.Clusters["gke_shop_europe-west1_prod"] == nil {
+		t.Errorf("expected the GKE entries to be imported, got %#v", config)
+	}
+}
+
+func TestInitNoAnswer(t *testing.T) {
+	dir, pathOptions := newInitTestDir(t)
+	defer os.RemoveAll(dir)
+
+	streams, in, _, _ := genericclioptions.NewTestIOStreams()
+	fmt.Fprint(in, "1\n")
+	o := &InitOptions{ConfigAccess: pathOptions, IOStreams: streams}
+	if err := o.Run(); err == nil || !strings.Contains(err.Error(), "no answer") {
+		t.Errorf("expected a missing answer to fail, got %v", err)
+	}
+}
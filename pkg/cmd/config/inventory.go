@@ -0,0 +1,161 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// InventoryOptions holds the command-line options for 'config inventory' sub command
+type InventoryOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Output       string
+
+	genericclioptions.IOStreams
+}
+
+var inventoryColumns = []string{"CONTEXT", "CLUSTER", "SERVER", "NAMESPACE", "AUTH TYPE", "OWNER", "TAGS"}
+
+var (
+	inventoryLong = templates.LongDesc(`
+		Prints a table of all contexts meant for documentation, such as wikis and runbooks.
+
+		Each row shows the cluster and server a context points at, the way its user authenticates,
+		and the owner annotation and tags kept in the context's kubecfg.io/metadata extension.
+		Credentials are never printed.`)
+
+	inventoryExample = templates.Examples(`
+		# Print the inventory as a Markdown table
+		kubectl config inventory
+
+		# Save the inventory for a spreadsheet
+		kubectl config inventory -o csv > clusters.csv`)
+)
+
+// NewCmdConfigInventory returns a Command instance for 'config inventory' sub command
+func NewCmdConfigInventory(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &InventoryOptions{
+		ConfigAccess: configAccess,
+		Output:       "markdown",
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "inventory [-o csv|markdown]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Print a documentation-friendly table of all contexts"),
+		Long:                  inventoryLong,
+		Example:               inventoryExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(cmdutil.UsageErrorf(cmd, "unexpected arguments: %v", args))
+			}
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.Output, "output", "o", o.Output, "Output format. One of: csv|markdown")
+	return cmd
+}
+
+// Validate makes sure the output format is supported
+func (o *InventoryOptions) Validate() error {
+	if o.Output != "csv" && o.Output != "markdown" {
+		return fmt.Errorf("unsupported output format %q, must be one of: csv, markdown", o.Output)
+	}
+	return nil
+}
+
+// Run performs the execution of 'config inventory' sub command
+func (o *InventoryOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(config.Contexts))
+	for name := range config.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rows := [][]string{}
+	for _, name := range names {
+		context := config.Contexts[name]
+		metadata, err := readContextMetadata(context)
+		if err != nil {
+			return fmt.Errorf("context %q: %v", name, err)
+		}
+		server := ""
+		if cluster, ok := config.Clusters[context.Cluster]; ok {
+			server = cluster.Server
+		}
+		authType := ""
+		if authInfo, ok := config.AuthInfos[context.AuthInfo]; ok {
+			authType = authInfoType(authInfo)
+		}
+		tags := append([]string(nil), metadata.Tags...)
+		sort.Strings(tags)
+		rows = append(rows, []string{
+			name,
+			context.Cluster,
+			server,
+			context.Namespace,
+			authType,
+			metadata.Annotations[ownerAnnotation],
+			strings.Join(tags, ", "),
+		})
+	}
+
+	if o.Output == "csv" {
+		w := csv.NewWriter(o.Out)
+		w.Write(inventoryColumns)
+		w.WriteAll(rows)
+		return w.Error()
+	}
+
+	writeMarkdownRow := func(cells []string) {
+		escaped := make([]string, 0, len(cells))
+		for _, cell := range cells {
+			escaped = append(escaped, strings.Replace(cell, "|", `\|`, -1))
+		}
+		fmt.Fprintf(o.Out, "| %s |\n", strings.Join(escaped, " | "))
+	}
+	writeMarkdownRow(inventoryColumns)
+	separators := make([]string, 0, len(inventoryColumns))
+	for range inventoryColumns {
+		separators = append(separators, "---")
+	}
+	writeMarkdownRow(separators)
+	for _, row := range rows {
+		writeMarkdownRow(row)
+	}
+	return nil
+}
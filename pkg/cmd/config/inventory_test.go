@@ -0,0 +1,93 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestInventory(t *testing.T) {
+	config := newRedFederalCowHammerConfig()
+	config.Contexts["federal-context"].Namespace = "saw-ns"
+	metadata := contextMetadata{
+		Annotations: map[string]string{ownerAnnotation: "platform|infra"},
+		Tags:        []string{"prod", "eu"},
+	}
+	if err := writeExtension(&config.Contexts["federal-context"].Extensions, contextMetadataExtension, metadata); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	config.Contexts["shaker-context"] = &clientcmdapi.Context{AuthInfo: "blue-user", Cluster: "big-cluster"}
+
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	if err := clientcmd.WriteToFile(config, fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	tests := []struct {
+		output   string
+		expected string
+	}{
+		{
+			output: "markdown",
+			expected: "| CONTEXT | CLUSTER | SERVER | NAMESPACE | AUTH TYPE | OWNER | TAGS |\n" +
+				"| --- | --- | --- | --- | --- | --- | --- |\n" +
+				"| federal-context | cow-cluster | http://cow.org:8080 | saw-ns | token | platform\\|infra | eu, prod |\n" +
+				"| shaker-context | big-cluster |  |  |  |  |  |\n",
+		},
+		{
+			output: "csv",
+			expected: "CONTEXT,CLUSTER,SERVER,NAMESPACE,AUTH TYPE,OWNER,TAGS\n" +
+				"federal-context,cow-cluster,http://cow.org:8080,saw-ns,token,platform|infra,\"eu, prod\"\n" +
+				"shaker-context,big-cluster,,,,,\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.output, func(t *testing.T) {
+			streams, _, out, _ := genericclioptions.NewTestIOStreams()
+			options := &InventoryOptions{ConfigAccess: pathOptions, Output: test.output, IOStreams: streams}
+			if err := options.Validate(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := options.Run(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.String() != test.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", test.expected, out.String())
+			}
+		})
+	}
+}
+
+func TestInventoryInvalidOutput(t *testing.T) {
+	options := &InventoryOptions{Output: "html"}
+	if err := options.Validate(); err == nil {
+		t.Errorf("expected an error for an unsupported output format")
+	}
+}
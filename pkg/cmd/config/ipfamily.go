@@ -0,0 +1,245 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// The address families the servers of clusters are reached over.
+const (
+	familyIPv4 = "ipv4"
+	familyIPv6 = "ipv6"
+)
+
+// dialFunc opens connections, as net.Dialer.DialContext does.
+type dialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// prefersIPv6 reports whether the server of cluster is to be dialed over IPv6 first, as set with
+// "config set-cluster --prefer-ipv6".
+func prefersIPv6(cluster *clientcmdapi.Cluster) bool {
+	if cluster == nil {
+		return false
+	}
+	prefer := false
+	found, err := readExtension(cluster.Extensions, preferIPv6Extension, &prefer)
+	return found && err == nil && prefer
+}
+
+// addressFamily returns the family of ip.
+func addressFamily(ip net.IP) string {
+	if ip.To4() != nil {
+		return familyIPv4
+	}
+	return familyIPv6
+}
+
+// dialPreferringIPv6 returns a dialFunc trying the IPv6 addresses of a host before its IPv4 ones,
+// one after the other, rather than racing them as dialer does by default.
+func dialPreferringIPv6(dialer *net.Dialer) dialFunc {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		if net.ParseIP(host) != nil {
+			return dialer.DialContext(ctx, network, address)
+		}
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		sort.SliceStable(addrs, func(i, j int) bool {
+			return addressFamily(addrs[i].IP) == familyIPv6 && addressFamily(addrs[j].IP) == familyIPv4
+		})
+		var firstErr error
+		for _, addr := range addrs {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(addr.IP.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		if firstErr == nil {
+			firstErr = fmt.Errorf("no address found for %s", host)
+		}
+		return nil, firstErr
+	}
+}
+
+// clusterDialer returns the dialFunc for the server of cluster: dialPreferringIPv6 when the
+// cluster prefers IPv6, else dialer's own.
+func clusterDialer(cluster *clientcmdapi.Cluster, dialer *net.Dialer) dialFunc {
+	if prefersIPv6(cluster) {
+		return dialPreferringIPv6(dialer)
+	}
+	return dialer.DialContext
+}
+
+// familyReachability is whether the server of a cluster answers over one address family.
+type familyReachability struct {
+	Family    string
+	Addresses []string
+	// Reachable is the address that could be dialed, empty when none could.
+	Reachable string
+	Error     string
+}
+
+// Status summarizes the reachability in a word, or with the address reached.
+func (r familyReachability) Status() string {
+	switch {
+	case len(r.Addresses) == 0:
+		return "no address"
+	case len(r.Reachable) > 0:
+		return "ok " + r.Reachable
+	}
+	return "unreachable"
+}
+
+// probeAddressFamilies resolves the host of server, and dials the addresses of each family
+// separately, so that an endpoint only broken over one of them is found even though dialing the
+// host falls back to the other.
+func probeAddressFamilies(server string, timeout time.Duration) []familyReachability {
+	results := []familyReachability{{Family: familyIPv4}, {Family: familyIPv6}}
+	setError := func(err error) []familyReachability {
+		for i := range results {
+			results[i].Error = err.Error()
+		}
+		return results
+	}
+	u, err := url.Parse(server)
+	if err != nil {
+		return setError(err)
+	}
+	port := u.Port()
+	if len(port) == 0 {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	ips := []net.IP{}
+	if ip := net.ParseIP(u.Hostname()); ip != nil {
+		ips = append(ips, ip)
+	} else {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+		if err != nil {
+			return setError(err)
+		}
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	for i := range results {
+		result := &results[i]
+		for _, ip := range ips {
+			if addressFamily(ip) != result.Family {
+				continue
+			}
+			address := net.JoinHostPort(ip.String(), port)
+			result.Addresses = append(result.Addresses, address)
+			if len(result.Reachable) > 0 {
+				continue
+			}
+			conn, err := dialer.Dial("tcp", address)
+			if err != nil {
+				result.Error = err.Error()
+				continue
+			}
+			conn.Close()
+			result.Reachable, result.Error = address, ""
+		}
+	}
+	return results
+}
+
+// startIPv6Proxy starts an HTTP CONNECT proxy on the loopback interface dialing the host of server
+// over IPv6 first, and other hosts as they would be dialed without a proxy. It is given to
+// kubectl as HTTPS_PROXY, so that the TLS connection to the server still goes from kubectl to the
+// server and is verified as usual. It returns the URL of the proxy and a function stopping it.
+func startIPv6Proxy(server *url.URL) (string, func(), error) {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	preferIPv6, direct := dialPreferringIPv6(dialer), dialer.DialContext
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "only CONNECT is supported", http.StatusMethodNotAllowed)
+			return
+		}
+		dial := direct
+		if host, _, err := net.SplitHostPort(r.Host); err == nil && strings.EqualFold(host, server.Hostname()) {
+			dial = preferIPv6
+		}
+		upstream, err := dial(r.Context(), "tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		hijacker, ok := w.(http.Hijacker)
+		if !ok {
+			upstream.Close()
+			http.Error(w, "hijacking is not supported", http.StatusInternalServerError)
+			return
+		}
+		conn, buffered, err := hijacker.Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		if _, err := conn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+			conn.Close()
+			upstream.Close()
+			return
+		}
+		go func() {
+			io.Copy(upstream, buffered)
+			upstream.Close()
+		}()
+		io.Copy(conn, upstream)
+		conn.Close()
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", nil, err
+	}
+	proxy := &http.Server{Handler: handler}
+	go proxy.Serve(listener)
+	return "http://" + listener.Addr().String(), func() { proxy.Close() }, nil
+}
+
+// environmentProxySet reports whether the environment sets an HTTPS proxy, which kubectl uses
+// instead of the one of startIPv6Proxy.
+func environmentProxySet() bool {
+	return len(os.Getenv("HTTPS_PROXY")) > 0 || len(os.Getenv("https_proxy")) > 0
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestSetClusterPreferIPv6(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	if err := clientcmd.WriteToFile(newRedFederalCowHammerConfig(), fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	for _, prefer := range []bool{true, false} {
+		cmd := NewCmdConfigSetCluster(&bytes.Buffer{}, pathOptions)
+		flag := "--prefer-ipv6"
+		if !prefer {
+			flag += "=false"
+		}
+		cmd.SetArgs([]string{"cow-cluster", flag})
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		config, err := clientcmd.LoadFromFile(fakeKubeFile.Name())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		cluster := config.Clusters["cow-cluster"]
+		if prefersIPv6(cluster) != prefer {
+			t.Errorf("expected the cluster to prefer IPv6: %v, got %v", prefer, cluster.Extensions)
+		}
+		if cluster.Server != "http://cow.org:8080" {
+			t.Errorf("expected the server to be kept, got %q", cluster.Server)
+		}
+	}
+}
+
+func TestProbeAddressFamilies(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer listener.Close()
+
+	results := probeAddressFamilies("https://"+listener.Addr().String(), time.Second)
+	if len(results) != 2 || results[0].Family != familyIPv4 || results[1].Family != familyIPv6 {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	if status := results[0].Status(); status != "ok "+listener.Addr().String() {
+		t.Errorf("expected IPv4 to be reachable, got %q", status)
+	}
+	if status := results[1].Status(); status != "no address" {
+		t.Errorf("expected no IPv6 address, got %q", status)
+	}
+
+	closed := listener.Addr().String()
+	listener.Close()
+	if status := probeAddressFamilies("https://"+closed, time.Second)[0].Status(); status != "unreachable" {
+		t.Errorf("expected a closed port to be unreachable, got %q", status)
+	}
+}
+
+func TestHealthIPFamilies(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	if err := clientcmd.WriteToFile(newRedFederalCowHammerConfig(), fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := &HealthOptions{
+		ConfigAccess: pathOptions,
+		Interval:     time.Minute,
+		IPFamilies:   true,
+		Check: func(config *clientcmdapi.Config, context string) contextHealth {
+			return contextHealth{Context: context, Server: "https://api.example.com", Reachable: true, Authenticated: true}
+		},
+		ProbeFamilies: func(server string, timeout time.Duration) []familyReachability {
+			return []familyReachability{
+				{Family: familyIPv4, Addresses: []string{"192.0.2.1:443"}, Reachable: "192.0.2.1:443"},
+				{Family: familyIPv6, Addresses: []string{"[2001:db8::1]:443"}, Error: "connection refused"},
+			}
+		},
+		IOStreams: streams,
+	}
+	if err := o.Complete(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(out.String(), "\n")
+	if fields := strings.Fields(lines[0]); fields[3] != "IPV4" || fields[4] != "IPV6" {
+		t.Errorf("expected the address families to be shown, got %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "ok 192.0.2.1:443") || !strings.Contains(lines[1], "unreachable") {
+		t.Errorf("expected the reachability of each family, got %q", lines[1])
+	}
+}
+
+func TestIPv6Proxy(t *testing.T) {
+	upstream, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer upstream.Close()
+	go func() {
+		conn, err := upstream.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		conn.Write([]byte("echo " + line))
+	}()
+
+	proxy, stop, err := startIPv6Proxy(&url.URL{Scheme: "https", Host: "api.example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer stop()
+	proxyURL, _ := url.Parse(proxy)
+	conn, err := net.Dial("tcp", proxyURL.Host)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+	connect := &http.Request{Method: http.MethodConnect, URL: &url.URL{Opaque: upstream.Addr().String()}, Host: upstream.Addr().String(), Header: http.Header{}}
+	if err := connect.Write(conn); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, connect)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the tunnel to be established, got %s", resp.Status)
+	}
+	conn.Write([]byte("hello\n"))
+	if line, _ := reader.ReadString('\n'); line != "echo hello\n" {
+		t.Errorf("expected the tunnel to reach the upstream, got %q", line)
+	}
+}
@@ -0,0 +1,334 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"sigs.k8s.io/yaml"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/tools/clientcmd/api/latest"
+	clientcmdapiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+)
+
+// kubeconfigIndex is a view of a single kubeconfig file. Building it only splits the file into named
+// stanzas; a stanza is decoded the first time it is asked for. For files with thousands of entries
+// this avoids the conversion, merge and deep-copy work clientcmd performs on every load, most of
+// which is spent on users and clusters a command never looks at. The contexts a command changed are
+// written back with write, leaving the other stanzas as they were read.
+type kubeconfigIndex struct {
+	currentContext string
+	contexts       map[string]json.RawMessage
+	decoded        map[string]*clientcmdapi.Context
+
+	// document holds the top-level fields of the file, and clusters and users their named stanzas
+	// whole, all undecoded.
+	document map[string]json.RawMessage
+	clusters map[string]json.RawMessage
+	users    map[string]json.RawMessage
+}
+
+// rawKubeconfig mirrors the v1 kubeconfig layout, keeping every stanza undecoded.
+type rawKubeconfig struct {
+	CurrentContext string            `json:"current-context"`
+	Contexts       []rawNamedContext `json:"contexts"`
+	Clusters       []json.RawMessage `json:"clusters"`
+	Users          []json.RawMessage `json:"users"`
+}
+
+type rawNamedContext struct {
+	Name    string          `json:"name"`
+	Context json.RawMessage `json:"context"`
+}
+
+// rawContextRefs holds the names a context refers to.
+type rawContextRefs struct {
+	Cluster  string `json:"cluster"`
+	AuthInfo string `json:"user"`
+}
+
+// singleKubeconfigFile returns the only file configAccess would load, or false when the configuration
+// is merged from several files or the file does not exist yet. Only then can kubeconfigIndex stand in
+// for GetStartingConfig.
+func singleKubeconfigFile(configAccess clientcmd.ConfigAccess) (string, bool) {
+	precedence := configAccess.GetLoadingPrecedence()
+	if configAccess.IsExplicitFile() {
+		precedence = []string{configAccess.GetExplicitFile()}
+	}
+	if len(precedence) != 1 {
+		return "", false
+	}
+	if _, err := os.Stat(precedence[0]); err != nil {
+		return "", false
+	}
+	return precedence[0], true
+}
+
+// writableKubeconfigFile returns the only file configAccess would load, like singleKubeconfigFile,
+// when it can also be written to directly, which managed kubeconfig files cannot.
+func writableKubeconfigFile(configAccess clientcmd.ConfigAccess) (string, bool) {
+	filename, ok := singleKubeconfigFile(configAccess)
+	if !ok {
+		return "", false
+	}
+	if overlay, ok := configAccess.(*overlayConfigAccess); ok {
+		if _, managed, err := overlay.overlay(); err != nil || managed.Len() > 0 {
+			return "", false
+		}
+	}
+	return filename, true
+}
+
+// loadContexts returns the kubeconfig of configAccess for a command that only looks at the
+// current-context and the contexts called names, with their clusters and users. A single writable
+// kubeconfig file is read through an index which only decodes those, and which is returned to write
+// the changes back with saveContexts. Otherwise the whole kubeconfig is loaded.
+func loadContexts(configAccess clientcmd.ConfigAccess, names ...string) (*clientcmdapi.Config, *kubeconfigIndex, error) {
+	filename, ok := writableKubeconfigFile(configAccess)
+	if !ok {
+		config, err := configAccess.GetStartingConfig()
+		return config, nil, err
+	}
+	index, err := loadKubeconfigIndex(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	config, err := index.subset(append([]string{index.currentContext}, names...)...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error loading config file %q: %v", filename, err)
+	}
+	return config, index, nil
+}
+
+// saveContexts writes the current-context of config and the contexts called names, removing those
+// config no longer has, through the index loadContexts returned when there is one.
+func saveContexts(configAccess clientcmd.ConfigAccess, config *clientcmdapi.Config, index *kubeconfigIndex, names ...string) error {
+	if index == nil {
+		return clientcmd.ModifyConfig(configAccess, *config, true)
+	}
+	filename, _ := writableKubeconfigFile(configAccess)
+	return index.write(filename, config, names...)
+}
+
+func loadKubeconfigIndex(filename string) (*kubeconfigIndex, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	index, err := newKubeconfigIndex(data)
+	if err != nil {
+		return nil, fmt.Errorf("error loading config file %q: %v", filename, err)
+	}
+	return index, nil
+}
+
+func newKubeconfigIndex(data []byte) (*kubeconfigIndex, error) {
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	raw := rawKubeconfig{}
+	if err := json.Unmarshal(jsonData, &raw); err != nil {
+		return nil, err
+	}
+
+	index := &kubeconfigIndex{
+		currentContext: raw.CurrentContext,
+		contexts:       make(map[string]json.RawMessage, len(raw.Contexts)),
+		decoded:        map[string]*clientcmdapi.Context{},
+		document:       map[string]json.RawMessage{},
+	}
+	for _, named := range raw.Contexts {
+		if _, exists := index.contexts[named.Name]; exists {
+			return nil, fmt.Errorf("duplicate context name %q", named.Name)
+		}
+		index.contexts[named.Name] = named.Context
+	}
+	if index.clusters, err = indexNamedStanzas("cluster", raw.Clusters); err != nil {
+		return nil, err
+	}
+	if index.users, err = indexNamedStanzas("user", raw.Users); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(jsonData, &index.document); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// indexNamedStanzas keys the named stanzas of a kubeconfig list by their name.
+func indexNamedStanzas(kind string, stanzas []json.RawMessage) (map[string]json.RawMessage, error) {
+	indexed := make(map[string]json.RawMessage, len(stanzas))
+	for _, stanza := range stanzas {
+		named := struct {
+			Name string `json:"name"`
+		}{}
+		if err := json.Unmarshal(stanza, &named); err != nil {
+			return nil, err
+		}
+		if _, exists := indexed[named.Name]; exists {
+			return nil, fmt.Errorf("duplicate %s name %q", kind, named.Name)
+		}
+		indexed[named.Name] = stanza
+	}
+	return indexed, nil
+}
+
+// contextNames returns the sorted names of all contexts without decoding any of them.
+func (i *kubeconfigIndex) contextNames() []string {
+	names := make([]string, 0, len(i.contexts))
+	for name := range i.contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// context decodes and returns a single context.
+func (i *kubeconfigIndex) context(name string) (*clientcmdapi.Context, bool, error) {
+	if context, ok := i.decoded[name]; ok {
+		return context, true, nil
+	}
+	raw, ok := i.contexts[name]
+	if !ok {
+		return nil, false, nil
+	}
+
+	external := clientcmdapiv1.Context{}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &external); err != nil {
+			return nil, true, fmt.Errorf("error decoding context %q: %v", name, err)
+		}
+	}
+	context := clientcmdapi.NewContext()
+	if err := latest.Scheme.Convert(&external, context, nil); err != nil {
+		return nil, true, fmt.Errorf("error decoding context %q: %v", name, err)
+	}
+	i.decoded[name] = context
+	return context, true, nil
+}
+
+// subset decodes the contexts called names that exist, with their clusters and users, the
+// current-context, the preferences and the extensions of the file into a Config.
+func (i *kubeconfigIndex) subset(names ...string) (*clientcmdapi.Config, error) {
+	document := map[string]interface{}{"apiVersion": "v1", "kind": "Config", "current-context": i.currentContext}
+	for _, key := range []string{"preferences", "extensions"} {
+		if raw, ok := i.document[key]; ok {
+			document[key] = raw
+		}
+	}
+	contexts, clusters, users := []interface{}{}, []interface{}{}, []interface{}{}
+	seen, seenClusters, seenUsers := sets.NewString(), sets.NewString(), sets.NewString()
+	for _, name := range names {
+		raw, ok := i.contexts[name]
+		if !ok || seen.Has(name) {
+			continue
+		}
+		seen.Insert(name)
+		contexts = append(contexts, rawNamedContext{Name: name, Context: raw})
+		refs := rawContextRefs{}
+		if len(raw) > 0 {
+			if err := json.Unmarshal(raw, &refs); err != nil {
+				return nil, fmt.Errorf("error decoding context %q: %v", name, err)
+			}
+		}
+		if cluster, ok := i.clusters[refs.Cluster]; ok && !seenClusters.Has(refs.Cluster) {
+			seenClusters.Insert(refs.Cluster)
+			clusters = append(clusters, cluster)
+		}
+		if user, ok := i.users[refs.AuthInfo]; ok && !seenUsers.Has(refs.AuthInfo) {
+			seenUsers.Insert(refs.AuthInfo)
+			users = append(users, user)
+		}
+	}
+	document["contexts"], document["clusters"], document["users"] = contexts, clusters, users
+	data, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	return clientcmd.Load(data)
+}
+
+// write writes the current-context of config and its contexts called names back to filename, the
+// file the index was read from, removing the contexts called names that config does not have.
+// The other stanzas are written as they were read, in the format clientcmd writes kubeconfig in.
+func (i *kubeconfigIndex) write(filename string, config *clientcmdapi.Config, names ...string) error {
+	for _, name := range names {
+		delete(i.decoded, name)
+		context, ok := config.Contexts[name]
+		if !ok {
+			delete(i.contexts, name)
+			continue
+		}
+		external := clientcmdapiv1.Context{}
+		if err := latest.Scheme.Convert(context, &external, nil); err != nil {
+			return fmt.Errorf("error encoding context %q: %v", name, err)
+		}
+		raw, err := json.Marshal(external)
+		if err != nil {
+			return err
+		}
+		i.contexts[name] = raw
+	}
+	i.currentContext = config.CurrentContext
+
+	// clientcmd writes the contexts sorted by name.
+	contexts := make([]rawNamedContext, 0, len(i.contexts))
+	for _, name := range i.contextNames() {
+		contexts = append(contexts, rawNamedContext{Name: name, Context: i.contexts[name]})
+	}
+	var err error
+	if i.document["contexts"], err = json.Marshal(contexts); err != nil {
+		return err
+	}
+	if i.document["current-context"], err = json.Marshal(i.currentContext); err != nil {
+		return err
+	}
+	data, err := json.Marshal(i.document)
+	if err != nil {
+		return err
+	}
+	if data, err = yaml.JSONToYAML(data); err != nil {
+		return err
+	}
+
+	unlock, err := lockKubeconfig(filename)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return ioutil.WriteFile(filename, data, 0600)
+}
+
+// lockKubeconfig takes the lock clientcmd takes before writing filename, so that the two exclude
+// each other, and returns the function releasing it.
+func lockKubeconfig(filename string) (func(), error) {
+	lock := filename + ".lock"
+	file, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL, 0)
+	if err != nil {
+		return nil, err
+	}
+	file.Close()
+	return func() { os.Remove(lock) }, nil
+}
@@ -0,0 +1,324 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestKubeconfigIndex(t *testing.T) {
+	config := newRedFederalCowHammerConfig()
+	config.Contexts["shaker-context"] = &clientcmdapi.Context{AuthInfo: "blue-user", Cluster: "big-cluster", Namespace: "saw-ns"}
+	data, err := clientcmd.Write(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	index, err := newKubeconfigIndex(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if index.currentContext != "federal-context" {
+		t.Errorf("expected current-context %q, got %q", "federal-context", index.currentContext)
+	}
+	if names := index.contextNames(); !reflect.DeepEqual(names, []string{"federal-context", "shaker-context"}) {
+		t.Errorf("unexpected context names %v", names)
+	}
+	if len(index.decoded) != 0 {
+		t.Errorf("expected no context to be decoded before it is asked for")
+	}
+
+	context, ok, err := index.context("shaker-context")
+	if err != nil || !ok {
+		t.Fatalf("expected shaker-context to be found, got %v, %v", ok, err)
+	}
+	if context.AuthInfo != "blue-user" || context.Cluster != "big-cluster" || context.Namespace != "saw-ns" {
+		t.Errorf("unexpected context %#v", context)
+	}
+
+	if _, ok, err := index.context("missing"); ok || err != nil {
+		t.Errorf("expected missing context to be reported as not found, got %v, %v", ok, err)
+	}
+}
+
+func TestKubeconfigIndexDuplicateContext(t *testing.T) {
+	data := []byte(`
+contexts:
+- name: twice
+  context: {cluster: a}
+- name: twice
+  context: {cluster: b}
+`)
+	if _, err := newKubeconfigIndex(data); err == nil {
+		t.Errorf("expected an error for duplicate context names")
+	}
+}
+
+func TestKubeconfigIndexWrite(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	config := newRedFederalCowHammerConfig()
+	config.AuthInfos["blue-user"] = &clientcmdapi.AuthInfo{Token: "blue-token"}
+	config.Clusters["big-cluster"] = &clientcmdapi.Cluster{Server: "https://big.org"}
+	config.Contexts["shaker-context"] = &clientcmdapi.Context{AuthInfo: "blue-user", Cluster: "big-cluster", Namespace: "saw-ns"}
+	config.Contexts["hammer-context"] = &clientcmdapi.Context{AuthInfo: "red-user", Cluster: "big-cluster"}
+	if err := clientcmd.WriteToFile(config, fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	before, err := clientcmd.LoadFromFile(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	subset, index, err := loadContexts(pathOptions, "shaker-context", "missing")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if index == nil {
+		t.Fatalf("expected a single kubeconfig file to be indexed")
+	}
+	if names := sortedContextNames(subset.Contexts); !reflect.DeepEqual(names, []string{"federal-context", "shaker-context"}) {
+		t.Errorf("expected only the current and named contexts to be decoded, got %v", names)
+	}
+	if names := sortedClusterNames(subset.Clusters); !reflect.DeepEqual(names, []string{"big-cluster", "cow-cluster"}) {
+		t.Errorf("expected only the clusters of the contexts to be decoded, got %v", names)
+	}
+	if names := sortedAuthInfoNames(subset.AuthInfos); !reflect.DeepEqual(names, []string{"blue-user", "red-user"}) {
+		t.Errorf("expected only the users of the contexts to be decoded, got %v", names)
+	}
+
+	renameContext(subset, "shaker-context", "mixer-context")
+	subset.Contexts["mixer-context"].Namespace = "drill-ns"
+	subset.CurrentContext = "mixer-context"
+	if err := saveContexts(pathOptions, subset, index, "shaker-context", "mixer-context"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(fakeKubeFile.Name() + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("expected the lock to be released, got %v", err)
+	}
+
+	after, err := clientcmd.LoadFromFile(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if after.CurrentContext != "mixer-context" {
+		t.Errorf("expected current-context %q, got %q", "mixer-context", after.CurrentContext)
+	}
+	if names := sortedContextNames(after.Contexts); !reflect.DeepEqual(names, []string{"federal-context", "hammer-context", "mixer-context"}) {
+		t.Errorf("unexpected contexts %v", names)
+	}
+	if context := after.Contexts["mixer-context"]; context.Cluster != "big-cluster" || context.AuthInfo != "blue-user" || context.Namespace != "drill-ns" {
+		t.Errorf("unexpected context %#v", context)
+	}
+	if !reflect.DeepEqual(before.Contexts["hammer-context"], after.Contexts["hammer-context"]) {
+		t.Errorf("expected the other contexts to be kept, got %#v", after.Contexts["hammer-context"])
+	}
+	if !reflect.DeepEqual(before.Clusters, after.Clusters) || !reflect.DeepEqual(before.AuthInfos, after.AuthInfos) {
+		t.Errorf("expected the clusters and users to be kept")
+	}
+}
+
+func TestKubeconfigIndexWriteKeepsOtherStanzas(t *testing.T) {
+	// exec-user and hammer-context have fields this clientcmd does not know, which loading and
+	// writing the file through clientcmd would drop.
+	data := []byte(`apiVersion: v1
+kind: Config
+current-context: shaker-context
+preferences: {}
+clusters:
+- name: big-cluster
+  cluster:
+    server: https://big.org
+contexts:
+- name: shaker-context
+  context:
+    cluster: big-cluster
+    user: blue-user
+- name: hammer-context
+  context:
+    cluster: big-cluster
+    user: exec-user
+    namespace: saw-ns
+    future-field: kept
+users:
+- name: exec-user
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1
+      command: aws
+      args: [eks, get-token]
+      interactiveMode: Never
+      provideClusterInfo: true
+- name: blue-user
+  user:
+    token: blue-token
+`)
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	if err := ioutil.WriteFile(fakeKubeFile.Name(), data, 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	before, err := newKubeconfigIndex(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	index, err := loadKubeconfigIndex(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	config, err := index.subset("shaker-context")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	config.Contexts["shaker-context"].Namespace = "drill-ns"
+	if err := index.write(fakeKubeFile.Name(), config, "shaker-context"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	written, err := ioutil.ReadFile(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	after, err := newKubeconfigIndex(written)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if context, _, err := after.context("shaker-context"); err != nil || context.Namespace != "drill-ns" {
+		t.Errorf("expected the namespace of shaker-context to be written, got %#v, %v", context, err)
+	}
+	stanzas := []struct {
+		kind          string
+		before, after json.RawMessage
+	}{
+		{kind: "context hammer-context", before: before.contexts["hammer-context"], after: after.contexts["hammer-context"]},
+		{kind: "cluster big-cluster", before: before.clusters["big-cluster"], after: after.clusters["big-cluster"]},
+		{kind: "user exec-user", before: before.users["exec-user"], after: after.users["exec-user"]},
+		{kind: "user blue-user", before: before.users["blue-user"], after: after.users["blue-user"]},
+	}
+	for _, stanza := range stanzas {
+		var expected, actual interface{}
+		if err := json.Unmarshal(stanza.before, &expected); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := json.Unmarshal(stanza.after, &actual); err != nil {
+			t.Fatalf("%s: unexpected error: %v", stanza.kind, err)
+		}
+		if !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected %s to be written unchanged, got %s", stanza.kind, stanza.after)
+		}
+	}
+}
+
+// newFleetConfig returns a kubeconfig the size of a fleet operator's, with one cluster, user and
+// context per member cluster.
+func newFleetConfig(size int) clientcmdapi.Config {
+	config := clientcmdapi.NewConfig()
+	for i := 0; i < size; i++ {
+		name := fmt.Sprintf("cluster-%04d", i)
+		config.Clusters[name] = &clientcmdapi.Cluster{Server: fmt.Sprintf("https://%s.example.com", name), CertificateAuthorityData: bytes.Repeat([]byte("c"), 1024)}
+		config.AuthInfos[name] = &clientcmdapi.AuthInfo{Token: name}
+		config.Contexts[name] = &clientcmdapi.Context{Cluster: name, AuthInfo: name, Namespace: "default"}
+	}
+	config.CurrentContext = "cluster-0000"
+	return *config
+}
+
+func writeFleetConfig(b *testing.B, size int) (*clientcmd.PathOptions, func()) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		b.Fatalf("unexpected error: %v", err)
+	}
+	if err := clientcmd.WriteToFile(newFleetConfig(size), fakeKubeFile.Name()); err != nil {
+		b.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+	return pathOptions, func() { os.Remove(fakeKubeFile.Name()) }
+}
+
+// The benchmarks below measure the commands reading a fleet kubeconfig through kubeconfigIndex. They
+// enforce no time budget: parsing the YAML of the file, which the index cannot skip, dominates and
+// depends on the machine.
+func BenchmarkGetContexts5000(b *testing.B) {
+	pathOptions, cleanup := writeFleetConfig(b, 5000)
+	defer cleanup()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		options := GetContextsOptions{
+			configAccess: pathOptions,
+			contextNames: []string{"cluster-4999"},
+			showHeaders:  true,
+			IOStreams:    genericclioptions.IOStreams{Out: ioutil.Discard, ErrOut: ioutil.Discard},
+		}
+		if err := options.RunGetContexts(); err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
+
+func BenchmarkUseContext5000(b *testing.B) {
+	pathOptions, cleanup := writeFleetConfig(b, 5000)
+	defer cleanup()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		options := UseContextOptions{ConfigAccess: pathOptions, ContextName: fmt.Sprintf("cluster-%04d", i%5000)}
+		if err := options.Run(); err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
+
+func BenchmarkRenameContext5000(b *testing.B) {
+	pathOptions, cleanup := writeFleetConfig(b, 5000)
+	defer cleanup()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Alternate between two names so every iteration performs a real rename.
+		from, to := "cluster-4999", "renamed-4999"
+		if i%2 == 1 {
+			from, to = to, from
+		}
+		options := RenameContextOptions{ConfigAccess: pathOptions, ContextName: from, NewName: to}
+		if err := options.RunRenameContext(ioutil.Discard); err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
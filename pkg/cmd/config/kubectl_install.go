@@ -0,0 +1,328 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// defaultKubectlReleaseURL serves the kubectl releases, with their checksums and the latest patch
+// release of every minor version.
+const defaultKubectlReleaseURL = "https://dl.k8s.io/release"
+
+// kubectlInstallDir returns the directory kubectl binaries are installed in by "config kubectl
+// install", which "config exec" looks into besides the PATH.
+func kubectlInstallDir() string {
+	return filepath.Join(stateDir(), "kubectl")
+}
+
+// KubectlInstallOptions holds the command-line options for 'config kubectl install' sub command
+type KubectlInstallOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Context      string
+	Dir          string
+	ReleaseURL   string
+	NoPin        bool
+
+	// ServerVersion returns the version of the cluster of a context, and Client downloads the
+	// releases. They are fields so tests can replace them.
+	ServerVersion func(config *clientcmdapi.Config, context string) (*utilversion.Version, error)
+	Client        *http.Client
+
+	genericclioptions.IOStreams
+}
+
+// KubectlListOptions holds the command-line options for 'config kubectl list' sub command
+type KubectlListOptions struct {
+	Dir            string
+	KubectlVersion func(kubectl string) (*utilversion.Version, error)
+
+	genericclioptions.IOStreams
+}
+
+var (
+	kubectlLong = templates.LongDesc(`
+		Install and list the kubectl binaries kept for the clusters of contexts.
+
+		"kubectl install --for CONTEXT" asks the cluster of the context for its version, and
+		downloads the latest patch release of kubectl for the same minor version from dl.k8s.io,
+		checking it against its published SHA-256 checksum. The binary is kept in
+		~/.local/state/kubecfg/kubectl, where "kubectl config exec" finds it besides the PATH.
+
+		Unless the context already has a kubectl version range or --no-pin is given, the range
+		of versions supported against the cluster, one minor version older or newer, is recorded
+		for it as with "kubectl config set-kubectl-version". "kubectl config exec" then keeps
+		running the default kubectl when it is supported, and runs the installed one otherwise.`)
+
+	kubectlExample = templates.Examples(`
+		# Install the kubectl matching the cluster of prod
+		kubectl config kubectl install --for prod
+
+		# List the installed kubectl binaries
+		kubectl config kubectl list`)
+)
+
+// NewCmdConfigKubectl returns a Command instance for 'config kubectl' sub commands
+func NewCmdConfigKubectl(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	install := &KubectlInstallOptions{
+		ConfigAccess:  configAccess,
+		Dir:           kubectlInstallDir(),
+		ReleaseURL:    defaultKubectlReleaseURL,
+		ServerVersion: clusterServerVersion,
+		Client:        &http.Client{Timeout: 5 * time.Minute},
+
+		IOStreams: streams,
+	}
+	list := &KubectlListOptions{Dir: kubectlInstallDir(), KubectlVersion: kubectlClientVersion, IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "kubectl SUBCOMMAND",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Install kubectl binaries matching the clusters of contexts"),
+		Long:                  kubectlLong,
+		Example:               kubectlExample,
+		Run:                   cmdutil.DefaultSubCommandRun(streams.ErrOut),
+	}
+
+	installCmd := &cobra.Command{
+		Use:   "install --for=CONTEXT_NAME [--no-pin]",
+		Short: i18n.T("Download the kubectl release matching the cluster of a context"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			if len(install.Context) == 0 {
+				cmdutil.CheckErr(helpErrorf(cmd, "--for is required"))
+			}
+			cmdutil.CheckErr(requireNetwork(cmd))
+			cmdutil.CheckErr(install.Run())
+		},
+	}
+	installCmd.Flags().StringVar(&install.Context, "for", install.Context, "The context whose cluster version kubectl must match")
+	installCmd.Flags().BoolVar(&install.NoPin, "no-pin", install.NoPin, "If true, do not record a kubectl version range for the context")
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: i18n.T("List the installed kubectl binaries"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			cmdutil.CheckErr(list.Run())
+		},
+	}
+
+	cmd.AddCommand(installCmd)
+	cmd.AddCommand(noWriteCommand(listCmd))
+	return cmd
+}
+
+// Run downloads kubectl for the cluster of the context and pins the context to it
+func (o *KubectlInstallOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	name, err := resolveContextName(config, o.Context)
+	if err != nil {
+		return err
+	}
+	context, ok := config.Contexts[name]
+	if !ok {
+		return fmt.Errorf("no context exists with the name: %q", name)
+	}
+
+	server, err := o.ServerVersion(config, name)
+	if err != nil {
+		return fmt.Errorf("reading the version of the cluster of context %q: %v", name, err)
+	}
+	minor := fmt.Sprintf("%d.%d", server.Major(), server.Minor())
+	latest, err := o.get(fmt.Sprintf("%s/stable-%s.txt", o.ReleaseURL, minor))
+	if err != nil {
+		return err
+	}
+	release, err := utilversion.ParseGeneric(strings.TrimSpace(string(latest)))
+	if err != nil {
+		return fmt.Errorf("latest kubectl release for %s: %v", minor, err)
+	}
+
+	binary := filepath.Join(o.Dir, "kubectl-"+release.String()+executableSuffix())
+	if _, err := os.Stat(binary); err == nil {
+		fmt.Fprintf(o.Out, "kubectl v%s is already installed at %s.\n", release, binary)
+	} else if os.IsNotExist(err) {
+		if err := o.download(release, binary); err != nil {
+			return err
+		}
+		fmt.Fprintf(o.Out, "Installed kubectl v%s at %s.\n", release, binary)
+	} else {
+		return err
+	}
+
+	if o.NoPin {
+		return nil
+	}
+	if pinned, err := readKubectlVersionRange(context); err != nil || len(pinned) > 0 {
+		return err
+	}
+	// kubectl supports clusters one minor version older or newer than itself.
+	supported := fmt.Sprintf(">=%d.%d <%d.%d", server.Major(), subtractOne(server.Minor()), server.Major(), server.Minor()+2)
+	if err := writeExtension(&context.Extensions, kubectlVersionExtension, kubectlVersionPin{Range: supported}); err != nil {
+		return err
+	}
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "kubectl version range of context %q set to %q.\n", name, supported)
+	return nil
+}
+
+// download fetches a kubectl release and its checksum, and installs the binary once it matches.
+func (o *KubectlInstallOptions) download(release *utilversion.Version, binary string) error {
+	url := fmt.Sprintf("%s/v%s/bin/%s/%s/kubectl%s", o.ReleaseURL, release, runtime.GOOS, runtime.GOARCH, executableSuffix())
+	checksum, err := o.get(url + ".sha256")
+	if err != nil {
+		return err
+	}
+	// The checksum file holds the digest alone, or followed by the file name as sha256sum prints it.
+	fields := strings.Fields(string(checksum))
+	if len(fields) == 0 {
+		return fmt.Errorf("%s.sha256: no checksum", url)
+	}
+	expected := strings.ToLower(fields[0])
+
+	if err := os.MkdirAll(o.Dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(o.Dir, ".kubectl-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	resp, err := o.Client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("downloading %s: %s", url, resp.Status)
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hash), resp.Body); err != nil {
+		return fmt.Errorf("downloading %s: %v", url, err)
+	}
+	if actual := hex.EncodeToString(hash.Sum(nil)); actual != expected {
+		return fmt.Errorf("the checksum of %s is %s, expected %s", url, actual, expected)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), binary)
+}
+
+// get returns the body of a small document, such as a checksum.
+func (o *KubectlInstallOptions) get(url string) ([]byte, error) {
+	resp, err := o.Client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading %s: %s", url, resp.Status)
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// Run lists the installed kubectl binaries
+func (o *KubectlListOptions) Run() error {
+	files, err := ioutil.ReadDir(o.Dir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	w := printers.GetNewTabWriter(o.Out)
+	fmt.Fprintf(w, "VERSION\tPATH\n")
+	for _, file := range files {
+		if file.IsDir() || !kubectlBinaryRE.MatchString(file.Name()) {
+			continue
+		}
+		binary := filepath.Join(o.Dir, file.Name())
+		version := "unknown"
+		if v, err := o.KubectlVersion(binary); err == nil {
+			version = "v" + v.String()
+		}
+		fmt.Fprintf(w, "%s\t%s\n", version, binary)
+	}
+	return w.Flush()
+}
+
+// clusterServerVersion asks the cluster of a context for its version.
+func clusterServerVersion(config *clientcmdapi.Config, context string) (*utilversion.Version, error) {
+	restConfig, err := newProbeConfig(config, context, nil, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	client, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	info, err := client.ServerVersion()
+	if err != nil {
+		return nil, err
+	}
+	return utilversion.ParseGeneric(info.GitVersion)
+}
+
+// executableSuffix returns the file name suffix of executables on this platform.
+func executableSuffix() string {
+	if runtime.GOOS == "windows" {
+		return ".exe"
+	}
+	return ""
+}
+
+func subtractOne(n uint) uint {
+	if n == 0 {
+		return 0
+	}
+	return n - 1
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestKubectlInstall(t *testing.T) {
+	binary := []byte("#!/bin/sh\necho kubectl\n")
+	digest := sha256.Sum256(binary)
+	checksum := hex.EncodeToString(digest[:])
+	downloadPath := fmt.Sprintf("/v1.29.4/bin/%s/%s/kubectl%s", runtime.GOOS, runtime.GOARCH, executableSuffix())
+
+	tests := []struct {
+		name     string
+		checksum string
+		pinned   string
+		expected string
+		pin      string
+		err      string
+	}{
+		{
+			name:     "install and pin",
+			checksum: checksum,
+			expected: "Installed kubectl v1.29.4 at %s.\nkubectl version range of context \"federal-context\" set to \">=1.28 <1.31\".\n",
+			pin:      ">=1.28 <1.31",
+		},
+		{
+			name:     "keep the range already set",
+			checksum: checksum + "  kubectl",
+			pinned:   "1.29",
+			expected: "Installed kubectl v1.29.4 at %s.\n",
+			pin:      "1.29",
+		},
+		{
+			name:     "checksum mismatch",
+			checksum: strings.Repeat("0", 64),
+			err:      "the checksum of",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/stable-1.29.txt":
+					fmt.Fprintln(w, "v1.29.4")
+				case downloadPath:
+					w.Write(binary)
+				case downloadPath + ".sha256":
+					fmt.Fprint(w, tt.checksum)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+
+			dir, err := ioutil.TempDir("", "kubectl-install")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer os.RemoveAll(dir)
+			config := newRedFederalCowHammerConfig()
+			if len(tt.pinned) > 0 {
+				if err := writeExtension(&config.Contexts["federal-context"].Extensions, kubectlVersionExtension, kubectlVersionPin{Range: tt.pinned}); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			pathOptions := clientcmd.NewDefaultPathOptions()
+			pathOptions.GlobalFile = filepath.Join(dir, "config")
+			pathOptions.EnvVar = ""
+			if err := clientcmd.WriteToFile(config, pathOptions.GlobalFile); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			streams, _, out, _ := genericclioptions.NewTestIOStreams()
+			o := &KubectlInstallOptions{
+				ConfigAccess: pathOptions,
+				Context:      "federal-context",
+				Dir:          filepath.Join(dir, "kubectl"),
+				ReleaseURL:   server.URL,
+				ServerVersion: func(config *clientcmdapi.Config, context string) (*utilversion.Version, error) {
+					return utilversion.ParseGeneric("v1.29.2-eks-1234")
+				},
+				Client:    server.Client(),
+				IOStreams: streams,
+			}
+			err = o.Run()
+			installed := filepath.Join(o.Dir, "kubectl-1.29.4"+executableSuffix())
+			if len(tt.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("expected an error containing %q, got %v", tt.err, err)
+				}
+				if files, _ := ioutil.ReadDir(o.Dir); len(files) > 0 {
+					t.Errorf("expected nothing to be installed, got %d file(s)", len(files))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if expected := fmt.Sprintf(tt.expected, installed); out.String() != expected {
+				t.Errorf("expected output %q, got %q", expected, out.String())
+			}
+			if data, err := ioutil.ReadFile(installed); err != nil || string(data) != string(binary) {
+				t.Errorf("expected the binary to be installed, got %v", err)
+			}
+
+			modified, err := clientcmd.LoadFromFile(pathOptions.GlobalFile)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if pin, err := readKubectlVersionRange(modified.Contexts["federal-context"]); err != nil || pin != tt.pin {
+				t.Errorf("expected the range %q, got %q, %v", tt.pin, pin, err)
+			}
+
+			out.Reset()
+			if err := o.Run(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !strings.HasPrefix(out.String(), "kubectl v1.29.4 is already installed") {
+				t.Errorf("expected the second install to be skipped, got %q", out.String())
+			}
+		})
+	}
+}
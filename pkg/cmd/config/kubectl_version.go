@@ -0,0 +1,327 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// kubectlVersionPin is stored in a context's kubectlVersionExtension.
+type kubectlVersionPin struct {
+	Range string `json:"range"`
+}
+
+// versionBound is a single comparison of a version range, such as >=1.28.
+type versionBound struct {
+	op      string
+	version []uint
+}
+
+// versionRange is satisfied by the versions satisfying all of its bounds.
+type versionRange []versionBound
+
+var (
+	versionBoundRE = regexp.MustCompile(`^(>=|<=|!=|==|=|>|<)?v?([0-9]+(\.[0-9]+)*)$`)
+	// kubectlBinaryRE matches the names kubectl binaries of several versions are installed under,
+	// such as kubectl, kubectl-1.29 or kubectl1.29.3.
+	kubectlBinaryRE = regexp.MustCompile(`^kubectl([-.]?v?[0-9]+(\.[0-9]+)*)?(\.exe)?$`)
+)
+
+// parseVersionRange parses bounds separated by spaces or commas, such as ">=1.28 <1.31". A bound
+// only compares as many components as it has, so "<1.31" excludes every 1.31 patch release and
+// "1.29" alone means any 1.29 release.
+func parseVersionRange(s string) (versionRange, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ',' })
+	r := versionRange{}
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		// Allow a space between the operator and the version, as in ">= 1.28".
+		if strings.Trim(field, "<>=!") == "" && i+1 < len(fields) {
+			i++
+			field += fields[i]
+		}
+		match := versionBoundRE.FindStringSubmatch(field)
+		if match == nil {
+			return nil, fmt.Errorf("invalid version bound %q in %q, must be like >=1.28", field, s)
+		}
+		// ParseGeneric needs a minor version, which a bound such as >=2 does not have.
+		major := !strings.Contains(match[2], ".")
+		if major {
+			match[2] += ".0"
+		}
+		version, err := utilversion.ParseGeneric(match[2])
+		if err != nil {
+			return nil, err
+		}
+		components := version.Components()
+		if major {
+			components = components[:1]
+		}
+		op := match[1]
+		if op == "" || op == "==" {
+			op = "="
+		}
+		r = append(r, versionBound{op: op, version: components})
+	}
+	if len(r) == 0 {
+		return nil, errors.New("empty version range")
+	}
+	return r, nil
+}
+
+// matches reports whether v satisfies every bound of the range.
+func (r versionRange) matches(v *utilversion.Version) bool {
+	for _, bound := range r {
+		components := append(v.Components(), make([]uint, len(bound.version))...)[:len(bound.version)]
+		cmp := compareComponents(components, bound.version)
+		var ok bool
+		switch bound.op {
+		case "=":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func compareComponents(a, b []uint) int {
+	for i := range a {
+		switch {
+		case a[i] < b[i]:
+			return -1
+		case a[i] > b[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+// readKubectlVersionRange returns the kubectl version range of a context, empty when it has none.
+func readKubectlVersionRange(context *clientcmdapi.Context) (string, error) {
+	pin := kubectlVersionPin{}
+	_, err := readExtension(context.Extensions, kubectlVersionExtension, &pin)
+	return pin.Range, err
+}
+
+// findKubectlBinaries lists the kubectl binaries installed by "config kubectl install" and in the
+// PATH, under the names of kubectlBinaryRE.
+func findKubectlBinaries() []string {
+	binaries := []string{}
+	for _, dir := range append([]string{kubectlInstallDir()}, filepath.SplitList(os.Getenv("PATH"))...) {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			if !file.IsDir() && file.Mode()&0111 != 0 && kubectlBinaryRE.MatchString(file.Name()) {
+				binaries = append(binaries, filepath.Join(dir, file.Name()))
+			}
+		}
+	}
+	return binaries
+}
+
+// kubectlClientVersion runs "kubectl version --client" to find the version of a kubectl binary.
+func kubectlClientVersion(kubectl string) (*utilversion.Version, error) {
+	out, err := exec.Command(kubectl, "version", "--client", "-o", "json").Output()
+	if err != nil {
+		return nil, err
+	}
+	version := struct {
+		ClientVersion struct {
+			GitVersion string `json:"gitVersion"`
+		} `json:"clientVersion"`
+	}{}
+	if err := json.Unmarshal(out, &version); err != nil {
+		return nil, err
+	}
+	return utilversion.ParseGeneric(version.ClientVersion.GitVersion)
+}
+
+// selectKubectl returns the kubectl binary to run against a context: the default one when the
+// context pins no version range or the default satisfies it, else the newest installed binary
+// satisfying it. When none does, it warns and returns the default.
+func (o *ExecOptions) selectKubectl(name string, context *clientcmdapi.Context) (string, error) {
+	pinned, err := readKubectlVersionRange(context)
+	if err != nil || len(pinned) == 0 {
+		return o.Kubectl, err
+	}
+	r, err := parseVersionRange(pinned)
+	if err != nil {
+		return "", fmt.Errorf("context %q: %v", name, err)
+	}
+
+	best, bestVersion := "", (*utilversion.Version)(nil)
+	seen := map[string]bool{}
+	for _, kubectl := range append([]string{o.Kubectl}, o.FindKubectls()...) {
+		if seen[kubectl] {
+			continue
+		}
+		seen[kubectl] = true
+		version, err := o.KubectlVersion(kubectl)
+		if err != nil || !r.matches(version) {
+			continue
+		}
+		if kubectl == o.Kubectl {
+			return kubectl, nil
+		}
+		if bestVersion == nil || bestVersion.LessThan(version) {
+			best, bestVersion = kubectl, version
+		}
+	}
+	if len(best) == 0 {
+		fmt.Fprintf(o.ErrOut, "warning: no installed kubectl is in the version range %q of context %q, running %s\n", pinned, name, o.Kubectl)
+		return o.Kubectl, nil
+	}
+	return best, nil
+}
+
+// SetKubectlVersionOptions holds the command-line options for 'config set-kubectl-version' sub
+// command
+type SetKubectlVersionOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Context      string
+	Range        string
+	Unset        bool
+
+	genericclioptions.IOStreams
+}
+
+var (
+	setKubectlVersionLong = templates.LongDesc(`
+		Record the range of kubectl versions to use with a context.
+
+		"kubectl config exec" runs kubectl against the context with the kubectl it would run anyway
+		when its version is in the range, and otherwise with the newest kubectl in the PATH or
+		installed by "kubectl config kubectl install" that is, looking for binaries named like
+		kubectl-1.29 or kubectl1.29.3. When none is, it warns and runs the default kubectl. This
+		avoids using a kubectl more than one minor version away from the cluster.
+
+		The range is made of bounds such as >=1.28 or <1.31, all of which must hold. A bound only
+		compares as many version components as it has: <1.31 excludes every 1.31 release, and
+		1.29 alone allows any 1.29 release. The range is kept in a kubeconfig extension of the
+		context, --unset removes it.`)
+
+	setKubectlVersionExample = templates.Examples(`
+		# Use kubectl 1.28 to 1.30 with prod
+		kubectl config set-kubectl-version prod '>=1.28 <1.31'
+
+		# Use any kubectl with prod again
+		kubectl config set-kubectl-version prod --unset`)
+)
+
+// NewCmdConfigSetKubectlVersion returns a Command instance for 'config set-kubectl-version' sub
+// command
+func NewCmdConfigSetKubectlVersion(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &SetKubectlVersionOptions{ConfigAccess: configAccess, IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "set-kubectl-version CONTEXT_NAME (RANGE | --unset)",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Record the range of kubectl versions to use with a context"),
+		Long:                  setKubectlVersionLong,
+		Example:               setKubectlVersionExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) < 1 || len(args) > 2 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.Context = args[0]
+			if len(args) == 2 {
+				o.Range = args[1]
+			}
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	cmd.Flags().BoolVar(&o.Unset, "unset", o.Unset, "Remove the kubectl version range of the context")
+	return cmd
+}
+
+// Validate checks that there is either a valid range or --unset
+func (o *SetKubectlVersionOptions) Validate() error {
+	if o.Unset {
+		if len(o.Range) > 0 {
+			return errors.New("a version range cannot be combined with --unset")
+		}
+		return nil
+	}
+	if len(o.Range) == 0 {
+		return errors.New("a version range or --unset is required")
+	}
+	_, err := parseVersionRange(o.Range)
+	return err
+}
+
+// Run records the range in the context
+func (o *SetKubectlVersionOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	name, err := resolveContextName(config, o.Context)
+	if err != nil {
+		return err
+	}
+	context, ok := config.Contexts[name]
+	if !ok {
+		return fmt.Errorf("no context exists with the name: %q", name)
+	}
+	if o.Unset {
+		delete(context.Extensions, kubectlVersionExtension)
+	} else if err := writeExtension(&context.Extensions, kubectlVersionExtension, kubectlVersionPin{Range: o.Range}); err != nil {
+		return err
+	}
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+	if o.Unset {
+		fmt.Fprintf(o.Out, "kubectl version range of context %q removed.\n", name)
+	} else {
+		fmt.Fprintf(o.Out, "kubectl version range of context %q set to %q.\n", name, o.Range)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestVersionRange(t *testing.T) {
+	tests := []struct {
+		versionRange string
+		matching     []string
+		notMatching  []string
+		err          string
+	}{
+		{
+			versionRange: ">=1.28 <1.31",
+			matching:     []string{"v1.28.0", "v1.30.9"},
+			notMatching:  []string{"v1.27.4", "v1.31.0"},
+		},
+		{
+			versionRange: "1.29",
+			matching:     []string{"v1.29.0", "v1.29.12"},
+			notMatching:  []string{"v1.28.3", "v1.30.0"},
+		},
+		{
+			versionRange: ">= 1.28.3, != 1.29",
+			matching:     []string{"v1.28.3", "v1.30.1"},
+			notMatching:  []string{"v1.28.2", "v1.29.5"},
+		},
+		{
+			versionRange: "<2",
+			matching:     []string{"v1.31.0"},
+			notMatching:  []string{"v2.0.0"},
+		},
+		{versionRange: "~1.28", err: `invalid version bound "~1.28"`},
+		{versionRange: " , ", err: "empty version range"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.versionRange, func(t *testing.T) {
+			r, err := parseVersionRange(tt.versionRange)
+			if len(tt.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("expected an error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, v := range tt.matching {
+				if !r.matches(utilversion.MustParseGeneric(v)) {
+					t.Errorf("expected %s to be in the range", v)
+				}
+			}
+			for _, v := range tt.notMatching {
+				if r.matches(utilversion.MustParseGeneric(v)) {
+					t.Errorf("expected %s not to be in the range", v)
+				}
+			}
+		})
+	}
+}
+
+func TestSelectKubectl(t *testing.T) {
+	versions := map[string]string{
+		"/bin/kubectl":      "v1.31.2",
+		"/bin/kubectl-1.28": "v1.28.7",
+		"/bin/kubectl-1.29": "v1.29.4",
+	}
+	tests := []struct {
+		name         string
+		versionRange string
+		expected     string
+		warning      string
+	}{
+		{name: "no range", expected: "/bin/kubectl"},
+		{name: "default in range", versionRange: ">=1.30", expected: "/bin/kubectl"},
+		{name: "newest in range", versionRange: ">=1.28 <1.31", expected: "/bin/kubectl-1.29"},
+		{name: "none in range", versionRange: "1.25", expected: "/bin/kubectl", warning: "no installed kubectl is in the version range"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := newRedFederalCowHammerConfig()
+			context := config.Contexts["federal-context"]
+			if len(tt.versionRange) > 0 {
+				if err := writeExtension(&context.Extensions, kubectlVersionExtension, kubectlVersionPin{Range: tt.versionRange}); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			streams, _, _, errOut := genericclioptions.NewTestIOStreams()
+			o := &ExecOptions{
+				Kubectl: "/bin/kubectl",
+				FindKubectls: func() []string {
+					return []string{"/bin/kubectl-1.28", "/bin/kubectl", "/bin/kubectl-1.29", "/bin/kubectl-broken"}
+				},
+				KubectlVersion: func(kubectl string) (*utilversion.Version, error) {
+					if v, ok := versions[kubectl]; ok {
+						return utilversion.ParseGeneric(v)
+					}
+					return nil, errors.New("exit status 1")
+				},
+				IOStreams: streams,
+			}
+			kubectl, err := o.selectKubectl("federal-context", context)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if kubectl != tt.expected {
+				t.Errorf("expected %s to run, got %s", tt.expected, kubectl)
+			}
+			if warning := errOut.String(); (len(tt.warning) == 0) != (len(warning) == 0) || !strings.Contains(warning, tt.warning) {
+				t.Errorf("expected a warning containing %q, got %q", tt.warning, warning)
+			}
+		})
+	}
+}
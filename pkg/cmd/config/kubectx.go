@@ -0,0 +1,210 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// kubectxState reads and writes the files kubectx and kubens keep in Dir: kubectx holds the
+// context switched away from last, for "kubectx -", and kubens/CONTEXT the namespace of CONTEXT
+// switched away from last, for "kubens -".
+type kubectxState struct {
+	Dir string
+}
+
+func (s kubectxState) previousContext() (string, error) {
+	return readKubectxFile(filepath.Join(s.Dir, "kubectx"))
+}
+
+func (s kubectxState) setPreviousContext(name string) error {
+	return writeKubectxFile(filepath.Join(s.Dir, "kubectx"), name)
+}
+
+func (s kubectxState) previousNamespace(context string) (string, error) {
+	return readKubectxFile(s.namespaceFile(context))
+}
+
+func (s kubectxState) setPreviousNamespace(context, namespace string) error {
+	return writeKubectxFile(s.namespaceFile(context), namespace)
+}
+
+// namespaceFile is the file of kubens for context. Windows does not allow ":" in file names, which
+// kubens replaces with "__" there.
+func (s kubectxState) namespaceFile(context string) string {
+	if runtime.GOOS == "windows" {
+		context = strings.Replace(context, ":", "__", -1)
+	}
+	return filepath.Join(s.Dir, "kubens", context)
+}
+
+// namespaceContexts lists the contexts kubens recorded a previous namespace for.
+func (s kubectxState) namespaceContexts() ([]string, error) {
+	files, err := ioutil.ReadDir(filepath.Join(s.Dir, "kubens"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	contexts := []string{}
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		context := file.Name()
+		if runtime.GOOS == "windows" {
+			context = strings.Replace(context, "__", ":", -1)
+		}
+		contexts = append(contexts, context)
+	}
+	sort.Strings(contexts)
+	return contexts, nil
+}
+
+// readKubectxFile returns the content of a state file, empty when it does not exist.
+func readKubectxFile(filename string) (string, error) {
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func writeKubectxFile(filename, value string) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, []byte(value), 0644)
+}
+
+// ImportKubectxStateOptions holds the command-line options for 'config import kubectx-state' sub
+// command
+type ImportKubectxStateOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	State        kubectxState
+	SettingsFile string
+
+	genericclioptions.IOStreams
+}
+
+var (
+	importKubectxStateLong = templates.LongDesc(`
+		Use the previous context and namespaces of kubectx and kubens, so that both tools can be
+		used side by side while moving over.
+
+		The state kubectx and kubens keep below $XDG_CACHE_HOME, or ~/.kube, is listed and the
+		kubectxState setting is enabled. From then on "kubectl config use-context -" switches to the
+		context kubectx switched away from last, and switching contexts records the context left
+		for "kubectx -", as well as the namespace left when restoreNamespace changes it, for
+		"kubens -". The state stays in the files of kubectx and kubens, nothing is copied into
+		kubeconfig.
+
+		Disable it with "kubectl config settings set kubectxState false".`)
+
+	importKubectxStateExample = templates.Examples(`
+		# Share the previous context with kubectx
+		kubectl config import kubectx-state
+		kubectx prod
+		kubectl config use-context -`)
+)
+
+// NewCmdConfigImportKubectxState returns a Command instance for 'config import kubectx-state' sub
+// command
+func NewCmdConfigImportKubectxState(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &ImportKubectxStateOptions{ConfigAccess: configAccess, State: kubectxState{Dir: kubectxDir()}, SettingsFile: settingsFile(), IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "kubectx-state",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Share the previous context and namespaces with kubectx and kubens"),
+		Long:                  importKubectxStateLong,
+		Example:               importKubectxStateExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	return cmd
+}
+
+// Run lists the state of kubectx and kubens and enables the kubectxState setting
+func (o *ImportKubectxStateOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	previous, err := o.State.previousContext()
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(previous) == 0:
+		fmt.Fprintf(o.Out, "kubectx recorded no previous context.\n")
+	case config.Contexts[previous] == nil:
+		fmt.Fprintf(o.ErrOut, "warning: the previous context of kubectx, %q, is not in kubeconfig\n", previous)
+	default:
+		fmt.Fprintf(o.Out, "Previous context: %s\n", previous)
+	}
+
+	contexts, err := o.State.namespaceContexts()
+	if err != nil {
+		return err
+	}
+	for _, context := range contexts {
+		if config.Contexts[context] == nil {
+			continue
+		}
+		namespace, err := o.State.previousNamespace(context)
+		if err != nil {
+			return err
+		}
+		if len(namespace) > 0 {
+			fmt.Fprintf(o.Out, "Previous namespace of context %q: %s\n", context, namespace)
+		}
+	}
+
+	settings, err := loadSettings(o.SettingsFile)
+	if err != nil {
+		return err
+	}
+	settings.KubectxState = true
+	if err := saveSettings(o.SettingsFile, settings); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "Enabled the kubectxState setting, use-context now shares its state with kubectx and kubens.\n")
+	return nil
+}
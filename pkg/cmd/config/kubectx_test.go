@@ -0,0 +1,103 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestKubectxState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubectx")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	state := kubectxState{Dir: filepath.Join(dir, "cache")}
+	if err := state.setPreviousContext("prod"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for context, namespace := range map[string]string{"prod": "kube-system", "gone": "default"} {
+		if err := state.setPreviousNamespace(context, namespace); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	kubeconfig := filepath.Join(dir, "config")
+	config := newRedFederalCowHammerConfig()
+	prod := &clientcmdapi.Context{AuthInfo: "red-user", Cluster: "cow-cluster", Namespace: "web"}
+	if err := writeExtension(&prod.Extensions, lastNamespaceExtension, lastNamespace{Namespace: "team-a"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	config.Contexts["prod"] = prod
+	if err := clientcmd.WriteToFile(config, kubeconfig); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = kubeconfig
+	pathOptions.EnvVar = ""
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	settings := filepath.Join(dir, "kubecfg", "config.yaml")
+	o := &ImportKubectxStateOptions{ConfigAccess: pathOptions, State: state, SettingsFile: settings, IOStreams: streams}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `Previous context: prod
+Previous namespace of context "prod": kube-system
+Enabled the kubectxState setting, use-context now shares its state with kubectx and kubens.
+`
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+	if loaded, err := loadSettings(settings); err != nil || !loaded.KubectxState {
+		t.Errorf("expected the kubectxState setting to be enabled, got %v, %v", loaded, err)
+	}
+
+	use := func(name string) *UseContextOptions {
+		o := &UseContextOptions{ConfigAccess: pathOptions, ContextName: name, RestoreNamespace: true, Kubectx: &state}
+		if err := o.Run(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return o
+	}
+	if o := use("-"); o.ContextName != "prod" || o.RestoredNamespace != "team-a" {
+		t.Errorf("expected to switch to prod and restore team-a, got %q and %q", o.ContextName, o.RestoredNamespace)
+	}
+	if previous, err := state.previousContext(); err != nil || previous != "federal-context" {
+		t.Errorf("expected kubectx to see federal-context as previous, got %q, %v", previous, err)
+	}
+	if namespace, err := state.previousNamespace("prod"); err != nil || namespace != "web" {
+		t.Errorf("expected kubens to see web as the previous namespace of prod, got %q, %v", namespace, err)
+	}
+	if o := use("-"); o.ContextName != "federal-context" {
+		t.Errorf("expected to switch back to federal-context, got %q", o.ContextName)
+	}
+
+	if err := os.Remove(filepath.Join(state.Dir, "kubectx")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := (&UseContextOptions{ConfigAccess: pathOptions, ContextName: "-", Kubectx: &state}).Run(); err == nil {
+		t.Errorf("expected an error without a previous context")
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// writeLineDiff writes a line oriented diff between from and to, prefixing removed lines
+// with "-", added lines with "+" and unchanged lines with " ". It is meant for the small
+// kubeconfig stanzas shown by --dry-run, not for whole files.
+func writeLineDiff(out io.Writer, fromName, toName, from, to string) error {
+	a := splitLines(from)
+	b := splitLines(to)
+
+	// lcs[i][j] holds the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	if _, err := fmt.Fprintf(out, "--- %s\n+++ %s\n", fromName, toName); err != nil {
+		return err
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		var err error
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			_, err = fmt.Fprintf(out, " %s\n", a[i])
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] > lcs[i+1][j]):
+			_, err = fmt.Fprintf(out, "+%s\n", b[j])
+			j++
+		default:
+			_, err = fmt.Fprintf(out, "-%s\n", a[i])
+			i++
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if len(s) == 0 {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
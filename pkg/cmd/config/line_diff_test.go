@@ -0,0 +1,68 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteLineDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     string
+		to       string
+		expected string
+	}{
+		{
+			name:     "unchanged",
+			from:     "a\nb\n",
+			to:       "a\nb\n",
+			expected: "--- before\n+++ after\n a\n b\n",
+		},
+		{
+			name:     "replaced line",
+			from:     "a\nb\nc\n",
+			to:       "a\nx\nc\n",
+			expected: "--- before\n+++ after\n a\n-b\n+x\n c\n",
+		},
+		{
+			name:     "added and removed lines",
+			from:     "user:\n  auth-provider:\n    name: gcp\n",
+			to:       "user:\n  exec:\n    command: gke-gcloud-auth-plugin\n",
+			expected: "--- before\n+++ after\n user:\n-  auth-provider:\n-    name: gcp\n+  exec:\n+    command: gke-gcloud-auth-plugin\n",
+		},
+		{
+			name:     "from empty",
+			from:     "",
+			to:       "a\n",
+			expected: "--- before\n+++ after\n+a\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			if err := writeLineDiff(buf, "before", "after", test.from, test.to); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if buf.String() != test.expected {
+				t.Errorf("expected\n%s\nbut got\n%s", test.expected, buf.String())
+			}
+		})
+	}
+}
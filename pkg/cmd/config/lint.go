@@ -0,0 +1,255 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// LintOptions holds the command-line options for 'config lint' sub command
+type LintOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Naming       string
+	Template     string
+	Fix          bool
+	// Color prints the severities in color, see the color setting.
+	Color bool
+	// Confirm asks before the contexts the confirm setting covers are renamed.
+	Confirm changeConfirmer
+
+	genericclioptions.IOStreams
+}
+
+var (
+	lintLong = templates.LongDesc(`
+		Check that the names of the contexts, clusters and users of kubeconfig follow the naming
+		scheme of the organization.
+
+		The scheme is a regular expression given with --naming, or the namingPattern setting.
+		Names it does not match are reported, and the command fails when there are any, so that it
+		can run in CI.
+
+		With --fix, contexts are renamed to the name the naming template gives them: a Go template
+		executed with the fields Context, Cluster, User, Namespace and Tags of the context, given
+		with --template or the namingTemplate setting. The name is lowercased and characters other
+		than letters, digits, "-" and "_" are replaced by "-". Contexts whose new name does not
+		match the scheme either, or is taken, are left alone. Clusters and users are only
+		reported.
+
+		The entries locked with "kubectl config lock" that changed or were removed since are
+		reported as well.`)
+
+	lintExample = templates.Examples(`
+		# Check the names against the scheme of the namingPattern setting
+		kubectl config lint
+
+		# Contexts must start with their environment
+		kubectl config lint --naming '^(dev|stg|prod)-[a-z0-9-]+$'
+
+		# Rename the contexts that do not, after the cluster they point to
+		kubectl config lint --naming '^(dev|stg|prod)-[a-z0-9-]+$' --fix --template 'prod-{{.Cluster}}'`)
+)
+
+// NewCmdConfigLint returns a Command instance for 'config lint' sub command
+func NewCmdConfigLint(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &LintOptions{ConfigAccess: configAccess, IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "lint [--naming=REGEXP] [--fix] [--template=TEMPLATE]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Check the names of kubeconfig entries against a naming scheme"),
+		Long:                  lintLong,
+		Example:               lintExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.Confirm = newChangeConfirmer(cmd, configAccess, streams.In, streams.ErrOut)
+			cmdutil.CheckErr(o.Complete())
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	cmd.Flags().StringVar(&o.Naming, "naming", o.Naming, "Regular expression names must match, the namingPattern setting by default")
+	cmd.Flags().StringVar(&o.Template, "template", o.Template, "Go template naming the contexts renamed by --fix, the namingTemplate setting by default")
+	cmd.Flags().BoolVar(&o.Fix, "fix", o.Fix, "Rename the contexts not matching the naming scheme")
+	return cmd
+}
+
+// Complete defaults the naming scheme and template to the settings, which also tell whether the
+// output is colored
+func (o *LintOptions) Complete() error {
+	settings, err := loadSettings(settingsFile())
+	if err != nil {
+		return err
+	}
+	o.Color = colorEnabled(settings.Color, o.Out)
+	if len(o.Naming) == 0 {
+		o.Naming = settings.NamingPattern
+	}
+	if len(o.Template) == 0 {
+		o.Template = settings.NamingTemplate
+	}
+	return nil
+}
+
+// Validate makes sure there is a naming scheme, and a template to fix names with
+func (o *LintOptions) Validate() error {
+	if len(o.Naming) == 0 {
+		return errors.New("no naming scheme, use --naming or set one with: kubectl config settings set namingPattern REGEXP")
+	}
+	if _, err := regexp.Compile(o.Naming); err != nil {
+		return fmt.Errorf("invalid naming scheme: %v", err)
+	}
+	if o.Fix && len(o.Template) == 0 {
+		return errors.New("--fix needs a naming template, use --template or set one with: kubectl config settings set namingTemplate TEMPLATE")
+	}
+	return nil
+}
+
+// Run reports the entries not matching the naming scheme, and renames the contexts among them
+// with --fix
+func (o *LintOptions) Run() error {
+	naming, err := regexp.Compile(o.Naming)
+	if err != nil {
+		return fmt.Errorf("invalid naming scheme: %v", err)
+	}
+	var tmpl *template.Template
+	if o.Fix {
+		if tmpl, err = template.New("naming").Option("missingkey=error").Parse(o.Template); err != nil {
+			return fmt.Errorf("invalid naming template: %v", err)
+		}
+	}
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+
+	problems, renamed := 0, []string{}
+	// The report is written once the renames are confirmed.
+	report := &bytes.Buffer{}
+	for _, name := range sortedContextNames(config.Contexts) {
+		if naming.MatchString(name) {
+			continue
+		}
+		fmt.Fprintf(report, "[%s]\tcontext %q does not match %s\n", severityLabel(doctorError, o.Color), name, o.Naming)
+		if !o.Fix {
+			problems++
+			continue
+		}
+		newName, err := lintContextName(tmpl, config, name)
+		switch {
+		case err != nil:
+			fmt.Fprintf(report, "\tfix failed: %v\n", err)
+		case !naming.MatchString(newName):
+			err = fmt.Errorf("the naming template gives %q, which does not match either", newName)
+			fmt.Fprintf(report, "\tfix failed: %v\n", err)
+		case config.Contexts[newName] != nil:
+			err = fmt.Errorf("the context %q already exists", newName)
+			fmt.Fprintf(report, "\tfix failed: %v\n", err)
+		default:
+			renameContext(config, name, newName)
+			fmt.Fprintf(report, "\trenamed to %q\n", newName)
+			renamed = append(renamed, name)
+		}
+		if err != nil {
+			problems++
+		}
+	}
+	for _, name := range sortedClusterNames(config.Clusters) {
+		if !naming.MatchString(name) {
+			fmt.Fprintf(report, "[%s]\tcluster %q does not match %s\n", severityLabel(doctorError, o.Color), name, o.Naming)
+			problems++
+		}
+	}
+	for _, name := range sortedAuthInfoNames(config.AuthInfos) {
+		if !naming.MatchString(name) {
+			fmt.Fprintf(report, "[%s]\tuser %q does not match %s\n", severityLabel(doctorError, o.Color), name, o.Naming)
+			problems++
+		}
+	}
+
+	_, drift, err := lockDrift(config)
+	if err != nil {
+		return err
+	}
+	for _, message := range drift {
+		fmt.Fprintf(report, "[%s]\t%s\n", severityLabel(doctorError, o.Color), message)
+	}
+
+	if len(renamed) > 0 {
+		if err := o.Confirm.check(renamed); err != nil {
+			return err
+		}
+		if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+			return err
+		}
+	}
+	if _, err := io.Copy(o.Out, report); err != nil {
+		return err
+	}
+	switch {
+	case problems > 0 && len(drift) > 0:
+		return fmt.Errorf("found %d name(s) not matching the naming scheme and %d changed locked entries", problems, len(drift))
+	case problems > 0:
+		return fmt.Errorf("found %d name(s) not matching the naming scheme", problems)
+	case len(drift) > 0:
+		return fmt.Errorf("found %d changed locked entries", len(drift))
+	}
+	return nil
+}
+
+// lintContextName executes the naming template for the context called name, and normalizes the
+// result into a context name.
+func lintContextName(tmpl *template.Template, config *clientcmdapi.Config, name string) (string, error) {
+	context := config.Contexts[name]
+	metadata, err := readContextMetadata(context)
+	if err != nil {
+		return "", err
+	}
+	buf := &bytes.Buffer{}
+	data := aliasData{Context: name, Cluster: context.Cluster, User: context.AuthInfo, Namespace: context.Namespace, Tags: metadata.Tags}
+	if err := tmpl.Execute(buf, data); err != nil {
+		return "", err
+	}
+	newName := strings.ToLower(strings.TrimSpace(buf.String()))
+	return strings.Trim(invalidAliasCharacters.ReplaceAllString(newName, "-"), "-"), nil
+}
+
+// renameContext renames the context called name to newName, following it with the current-context.
+func renameContext(config *clientcmdapi.Config, name, newName string) {
+	config.Contexts[newName] = config.Contexts[name]
+	delete(config.Contexts, name)
+	if config.CurrentContext == name {
+		config.CurrentContext = newName
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestLint(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	config := clientcmdapi.Config{
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{
+			"prod-admin": {Token: "admin-token"}},
+		Clusters: map[string]*clientcmdapi.Cluster{
+			"prod-eu": {Server: "https://eu.example.com"},
+			"Prod_US": {Server: "https://us.example.com"},
+			"Dev.Box": {Server: "https://127.0.0.1:6443"}},
+		Contexts: map[string]*clientcmdapi.Context{
+			"prod-eu":   {AuthInfo: "prod-admin", Cluster: "prod-eu"},
+			"eu-admin":  {AuthInfo: "prod-admin", Cluster: "prod-eu"},
+			"us-admin":  {AuthInfo: "prod-admin", Cluster: "Prod_US"},
+			"box-admin": {AuthInfo: "prod-admin", Cluster: "Dev.Box"}},
+		CurrentContext: "box-admin",
+	}
+	if err := clientcmd.WriteToFile(config, fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+	naming := "^(dev|stg|prod)-[a-z0-9-]+$"
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := &LintOptions{ConfigAccess: pathOptions, Naming: naming, IOStreams: streams}
+	if err := o.Run(); err == nil || err.Error() != "found 5 name(s) not matching the naming scheme" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	expected := `[ERROR]	context "box-admin" does not match ^(dev|stg|prod)-[a-z0-9-]+$
+[ERROR]	context "eu-admin" does not match ^(dev|stg|prod)-[a-z0-9-]+$
+[ERROR]	context "us-admin" does not match ^(dev|stg|prod)-[a-z0-9-]+$
+[ERROR]	cluster "Dev.Box" does not match ^(dev|stg|prod)-[a-z0-9-]+$
+[ERROR]	cluster "Prod_US" does not match ^(dev|stg|prod)-[a-z0-9-]+$
+`
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+
+	streams, _, out, _ = genericclioptions.NewTestIOStreams()
+	o = &LintOptions{ConfigAccess: pathOptions, Naming: naming, Template: "{{.Cluster}}", Fix: true, IOStreams: streams}
+	if err := o.Run(); err == nil || err.Error() != "found 4 name(s) not matching the naming scheme" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	expected = `[ERROR]	context "box-admin" does not match ^(dev|stg|prod)-[a-z0-9-]+$
+	renamed to "dev-box"
+[ERROR]	context "eu-admin" does not match ^(dev|stg|prod)-[a-z0-9-]+$
+	fix failed: the context "prod-eu" already exists
+[ERROR]	context "us-admin" does not match ^(dev|stg|prod)-[a-z0-9-]+$
+	fix failed: the naming template gives "prod_us", which does not match either
+[ERROR]	cluster "Dev.Box" does not match ^(dev|stg|prod)-[a-z0-9-]+$
+[ERROR]	cluster "Prod_US" does not match ^(dev|stg|prod)-[a-z0-9-]+$
+`
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+	loaded, err := clientcmd.LoadFromFile(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := loaded.Contexts["dev-box"]; !ok || loaded.CurrentContext != "dev-box" {
+		t.Errorf("expected box-admin to be renamed to dev-box and stay current, got %v", loaded)
+	}
+
+	for _, invalid := range []*LintOptions{{}, {Naming: "^(dev"}, {Naming: naming, Fix: true}} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", invalid)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// entryLocks is stored in the preferences' locksExtension. It maps locked entries, as
+// context/NAME, cluster/NAME or user/NAME, to the checksum of their content when they were locked.
+// The locks are kept apart from the entries so that a tool rewriting an entry does not drop its
+// lock along with its extensions.
+type entryLocks map[string]string
+
+// LockOptions holds the command-line options for 'config lock' sub command
+type LockOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	// Entry is a context, or a cluster or user as cluster/NAME or user/NAME.
+	Entry  string
+	Remove bool
+
+	genericclioptions.IOStreams
+}
+
+var (
+	lockLong = templates.LongDesc(`
+		Lock kubeconfig entries to detect when they are changed by other tools.
+
+		The checksum of the content of the entry is recorded, and "kubectl config lint" and
+		"kubectl config doctor" report the locked entries that changed or were removed since, such
+		as a cluster whose server was rewritten by a cloud CLI. Extensions are not part of the
+		checksum, so the metadata kept by the config subcommands does not count as a change, and
+		neither does the namespace of a context, which the config subcommands switch.
+
+		Locking a context locks its cluster and user as well. Locking an entry again accepts its
+		current content, and --remove removes the lock.`)
+
+	lockExample = templates.Examples(`
+		# Lock the current context, its cluster and user
+		kubectl config lock
+
+		# Lock a cluster
+		kubectl config lock cluster/shop-prod
+
+		# Accept the change of a locked context after reviewing it
+		kubectl config lock shop-prod
+
+		# Remove the lock of a context, its cluster and user
+		kubectl config lock shop-prod --remove`)
+)
+
+// NewCmdConfigLock returns a Command instance for 'config lock' sub command
+func NewCmdConfigLock(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &LockOptions{ConfigAccess: configAccess, Entry: currentContextShorthand, IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "lock [CONTEXT_NAME | cluster/NAME | user/NAME] [--remove]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Lock kubeconfig entries to detect changes made by other tools"),
+		Long:                  lockLong,
+		Example:               lockExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) > 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			if len(args) == 1 {
+				o.Entry = args[0]
+			}
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().BoolVar(&o.Remove, "remove", o.Remove, "If true, remove the lock instead")
+	return cmd
+}
+
+// Run records or removes the checksums of the entry
+func (o *LockOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	locks, err := readEntryLocks(config)
+	if err != nil {
+		return err
+	}
+
+	entries := []string{}
+	switch {
+	case strings.HasPrefix(o.Entry, "cluster/"), strings.HasPrefix(o.Entry, "user/"):
+		entries = append(entries, o.Entry)
+	default:
+		name, err := resolveContextName(config, o.Entry)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, "context/"+name)
+		if context, ok := config.Contexts[name]; ok {
+			if len(context.Cluster) > 0 {
+				entries = append(entries, "cluster/"+context.Cluster)
+			}
+			if len(context.AuthInfo) > 0 {
+				entries = append(entries, "user/"+context.AuthInfo)
+			}
+		}
+	}
+
+	for _, entry := range entries {
+		if o.Remove {
+			if _, ok := locks[entry]; !ok {
+				return fmt.Errorf("%s is not locked", entry)
+			}
+			delete(locks, entry)
+			continue
+		}
+		checksum, found, err := entryChecksum(config, entry)
+		if err != nil {
+			return err
+		}
+		if !found {
+			kind, name := splitEntry(entry)
+			return fmt.Errorf("no %s exists with the name: %q", kind, name)
+		}
+		locks[entry] = checksum
+	}
+	if err := writeEntryLocks(config, locks); err != nil {
+		return err
+	}
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+
+	verb := "locked"
+	if o.Remove {
+		verb = "unlocked"
+	}
+	for _, entry := range entries {
+		fmt.Fprintf(o.Out, "%s %s.\n", entry, verb)
+	}
+	return nil
+}
+
+// readEntryLocks returns the entry locks of a kubeconfig.
+func readEntryLocks(config *clientcmdapi.Config) (entryLocks, error) {
+	locks := entryLocks{}
+	if _, err := readExtension(config.Preferences.Extensions, locksExtension, &locks); err != nil {
+		return nil, err
+	}
+	return locks, nil
+}
+
+// writeEntryLocks stores the entry locks of a kubeconfig, removing the extension when there are
+// none.
+func writeEntryLocks(config *clientcmdapi.Config, locks entryLocks) error {
+	if len(locks) == 0 {
+		delete(config.Preferences.Extensions, locksExtension)
+		return nil
+	}
+	return writeExtension(&config.Preferences.Extensions, locksExtension, locks)
+}
+
+// splitEntry returns the kind and name of an entry given as context/NAME, cluster/NAME or
+// user/NAME.
+func splitEntry(entry string) (kind, name string) {
+	if i := strings.Index(entry, "/"); i >= 0 {
+		return entry[:i], entry[i+1:]
+	}
+	return "", entry
+}
+
+// entryChecksum returns the checksum of the content of an entry given as context/NAME,
+// cluster/NAME or user/NAME, leaving out its extensions and where it was loaded from, and the
+// namespace of a context, which the config subcommands switch as a matter of course. found is
+// false when there is no such entry.
+func entryChecksum(config *clientcmdapi.Config, entry string) (checksum string, found bool, err error) {
+	var content interface{}
+	switch kind, name := splitEntry(entry); kind {
+	case "context":
+		if context, ok := config.Contexts[name]; ok {
+			copied := *context
+			copied.LocationOfOrigin, copied.Extensions, copied.Namespace = "", nil, ""
+			content = copied
+		}
+	case "cluster":
+		if cluster, ok := config.Clusters[name]; ok {
+			copied := *cluster
+			copied.LocationOfOrigin, copied.Extensions = "", nil
+			content = copied
+		}
+	case "user":
+		if authInfo, ok := config.AuthInfos[name]; ok {
+			copied := *authInfo
+			copied.LocationOfOrigin, copied.Extensions = "", nil
+			content = copied
+		}
+	default:
+		return "", false, fmt.Errorf("invalid entry %q, expected context/NAME, cluster/NAME or user/NAME", entry)
+	}
+	if content == nil {
+		return "", false, nil
+	}
+	data, err := json.Marshal(content)
+	if err != nil {
+		return "", true, err
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:]), true, nil
+}
+
+// lockDrift returns the number of locked entries of a kubeconfig, and a message for every one of
+// them that changed or was removed since it was locked, sorted by entry.
+func lockDrift(config *clientcmdapi.Config) (locked int, drift []string, err error) {
+	locks, err := readEntryLocks(config)
+	if err != nil {
+		return 0, nil, err
+	}
+	entries := make([]string, 0, len(locks))
+	for entry := range locks {
+		entries = append(entries, entry)
+	}
+	sort.Strings(entries)
+
+	for _, entry := range entries {
+		checksum, found, err := entryChecksum(config, entry)
+		switch {
+		case err != nil:
+			drift = append(drift, fmt.Sprintf("locked %s cannot be checked: %v", entry, err))
+		case !found:
+			drift = append(drift, fmt.Sprintf("locked %s was removed", entry))
+		case checksum != locks[entry]:
+			drift = append(drift, fmt.Sprintf("locked %s was changed", entry))
+		}
+	}
+	return len(entries), drift, nil
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func TestLock(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	if err := clientcmd.WriteToFile(newRedFederalCowHammerConfig(), fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := &LockOptions{ConfigAccess: pathOptions, Entry: "federal-context", IOStreams: streams}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "context/federal-context locked.\ncluster/cow-cluster locked.\nuser/red-user locked.\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+
+	// The metadata and namespaces of the config subcommands are not a change, another tool
+	// rewriting the server and dropping the user is.
+	config, err := pathOptions.GetStartingConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := writeExtension(&config.Contexts["federal-context"].Extensions, bannerExtension, "prod"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	config.Contexts["federal-context"].Namespace = "web"
+	if locked, drift, err := lockDrift(config); err != nil || locked != 3 || len(drift) != 0 {
+		t.Errorf("expected 3 unchanged locked entries, got %d, %v, %v", locked, drift, err)
+	}
+	config.Clusters["cow-cluster"].Server = "https://cow.example.org"
+	delete(config.AuthInfos, "red-user")
+	if err := clientcmd.ModifyConfig(pathOptions, *config, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	streams, _, out, _ = genericclioptions.NewTestIOStreams()
+	lint := &LintOptions{ConfigAccess: pathOptions, Naming: ".*", IOStreams: streams}
+	if err := lint.Run(); err == nil || err.Error() != "found 2 changed locked entries" {
+		t.Errorf("expected the changed locked entries to fail, got %v", err)
+	}
+	expected = "[ERROR]\tlocked cluster/cow-cluster was changed\n[ERROR]\tlocked user/red-user was removed\n"
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+	findings := checkLockedEntries(&DoctorOptions{ConfigAccess: pathOptions})
+	if len(findings) != 2 || findings[0].Severity != doctorWarning || findings[1].Message != "locked user/red-user was removed" {
+		t.Errorf("unexpected findings: %#v", findings)
+	}
+
+	// Locking the cluster again accepts its change, the lock of the removed user is removed.
+	o = &LockOptions{ConfigAccess: pathOptions, Entry: "cluster/cow-cluster", IOStreams: streams}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	o = &LockOptions{ConfigAccess: pathOptions, Entry: "user/red-user", Remove: true, IOStreams: streams}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.Run(); err == nil {
+		t.Errorf("expected removing a missing lock to fail")
+	}
+	findings = checkLockedEntries(&DoctorOptions{ConfigAccess: pathOptions})
+	if len(findings) != 1 || findings[0].Severity != doctorOK || findings[0].Message != "2 locked entries are unchanged" {
+		t.Errorf("unexpected findings: %#v", findings)
+	}
+
+	o = &LockOptions{ConfigAccess: pathOptions, Entry: "user/missing", IOStreams: streams}
+	if err := o.Run(); err == nil {
+		t.Errorf("expected locking a missing user to fail")
+	}
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	goflag "flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/kubectl/pkg/util/term"
+)
+
+const (
+	// FlagVerbosity is the persistent flag selecting how much diagnostic output is written.
+	FlagVerbosity = "v"
+	// FlagLogFormat is the persistent flag selecting between human readable and JSON log lines.
+	FlagLogFormat = "log-format"
+
+	logFormatText = "text"
+	logFormatJSON = "json"
+
+	progressBarWidth = 30
+)
+
+// klogFlags is where klog registers its --v flag, a variable so tests can use their own.
+var klogFlags = goflag.CommandLine
+
+// addLoggingFlags registers the verbosity and log format flags on the root config command so every
+// subcommand inherits them. The --v flag of klog, which kubectl passes on to its subcommands, is
+// reused when it is registered, so that -v sets the verbosity of klog and of the config subcommands
+// alike.
+func addLoggingFlags(cmd *cobra.Command) {
+	if verbosity := klogFlags.Lookup(FlagVerbosity); verbosity != nil {
+		cmd.PersistentFlags().AddGoFlag(verbosity)
+	} else {
+		cmd.PersistentFlags().IntP(FlagVerbosity, "v", 0, "Number for the log level verbosity")
+	}
+	cmd.PersistentFlags().String(FlagLogFormat, logFormatText, "Format of log messages and progress. One of: text|json")
+}
+
+// cmdLogger writes diagnostic messages, warnings and progress of config subcommands. It always writes
+// to the error stream, so that the output of a command stays machine readable whatever the verbosity.
+type cmdLogger struct {
+	out       io.Writer
+	verbosity int
+	json      bool
+	now       func() time.Time
+}
+
+// newCmdLogger returns a logger configured by the flags of cmd. cmd may be nil, which yields the
+// default text logger.
+func newCmdLogger(cmd *cobra.Command, out io.Writer) (*cmdLogger, error) {
+	l := &cmdLogger{out: out, now: time.Now}
+	if cmd == nil {
+		return l, nil
+	}
+	if flag := cmd.Flags().Lookup(FlagVerbosity); flag != nil {
+		if _, err := fmt.Sscan(flag.Value.String(), &l.verbosity); err != nil {
+			return nil, fmt.Errorf("invalid --%s %q: %v", FlagVerbosity, flag.Value.String(), err)
+		}
+	}
+	if flag := cmd.Flags().Lookup(FlagLogFormat); flag != nil {
+		switch format := flag.Value.String(); format {
+		case logFormatText:
+		case logFormatJSON:
+			l.json = true
+		default:
+			return nil, fmt.Errorf("invalid --%s %q, must be one of: %s|%s", FlagLogFormat, format, logFormatText, logFormatJSON)
+		}
+	}
+	return l, nil
+}
+
+// V reports whether messages of the given verbosity level are written.
+func (l *cmdLogger) V(level int) bool {
+	return l.verbosity >= level
+}
+
+// Infof writes a message when the verbosity is at least level.
+func (l *cmdLogger) Infof(level int, format string, args ...interface{}) {
+	if l.V(level) {
+		l.log("info", fmt.Sprintf(format, args...))
+	}
+}
+
+// Warningf writes a message regardless of the verbosity.
+func (l *cmdLogger) Warningf(format string, args ...interface{}) {
+	l.log("warning", fmt.Sprintf(format, args...))
+}
+
+// log writes a single message followed by alternating keys and values.
+func (l *cmdLogger) log(level, msg string, keysAndValues ...interface{}) {
+	if l.json {
+		entry := map[string]interface{}{
+			"ts":    l.now().UTC().Format(time.RFC3339),
+			"level": level,
+			"msg":   msg,
+		}
+		for i := 0; i+1 < len(keysAndValues); i += 2 {
+			entry[fmt.Sprint(keysAndValues[i])] = keysAndValues[i+1]
+		}
+		data, err := json.Marshal(entry)
+		if err != nil {
+			fmt.Fprintf(l.out, "%s: %s\n", level, msg)
+			return
+		}
+		fmt.Fprintf(l.out, "%s\n", data)
+		return
+	}
+
+	line := msg
+	if level == "warning" {
+		line = "warning: " + msg
+	}
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		line += fmt.Sprintf(" %v=%v", keysAndValues[i], keysAndValues[i+1])
+	}
+	fmt.Fprintln(l.out, line)
+}
+
+// progressBar reports the progress of an operation over a known number of items. On a terminal it
+// redraws a single bar; otherwise, and with --log-format=json, every step is logged on its own line.
+type progressBar struct {
+	log         *cmdLogger
+	total       int
+	current     int
+	interactive bool
+}
+
+func (l *cmdLogger) newProgressBar(total int) *progressBar {
+	return &progressBar{
+		log:         l,
+		total:       total,
+		interactive: !l.json && term.IsTerminal(l.out),
+	}
+}
+
+// Step marks one more item as processed and reports what happened to it.
+func (p *progressBar) Step(item, status string) {
+	p.current++
+	switch {
+	case p.log.json:
+		p.log.log("info", status, "item", item, "current", p.current, "total", p.total)
+	case p.interactive:
+		filled := progressBarWidth
+		if p.total > 0 {
+			filled = progressBarWidth * p.current / p.total
+		}
+		fmt.Fprintf(p.log.out, "\r[%s%s] %d/%d %s\033[K", strings.Repeat("=", filled), strings.Repeat(" ", progressBarWidth-filled), p.current, p.total, item)
+		if p.log.V(1) {
+			fmt.Fprintf(p.log.out, "\n%s: %s\n", item, status)
+		}
+	default:
+		fmt.Fprintf(p.log.out, "[%d/%d] %s: %s\n", p.current, p.total, item, status)
+	}
+}
+
+// Finish ends the bar, so that later output starts on a fresh line.
+func (p *progressBar) Finish() {
+	if p.interactive && p.current > 0 {
+		fmt.Fprintln(p.log.out)
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	goflag "flag"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func newTestCmdLogger(t *testing.T, flags ...string) (*cmdLogger, *bytes.Buffer) {
+	cmd := &cobra.Command{Use: "probe"}
+	addLoggingFlags(cmd)
+	if err := cmd.ParseFlags(flags); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := &bytes.Buffer{}
+	log, err := newCmdLogger(cmd, out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	log.now = func() time.Time { return time.Date(2019, 8, 1, 12, 0, 0, 0, time.UTC) }
+	return log, out
+}
+
+func TestCmdLoggerVerbosity(t *testing.T) {
+	log, out := newTestCmdLogger(t, "-v", "2")
+	log.Infof(2, "shown %d", 2)
+	log.Infof(3, "hidden")
+	log.Warningf("careful")
+
+	expected := "shown 2\nwarning: careful\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestCmdLoggerKlogVerbosity(t *testing.T) {
+	defer func(original *goflag.FlagSet) { klogFlags = original }(klogFlags)
+	klogFlags = goflag.NewFlagSet("klog", goflag.ContinueOnError)
+	klogVerbosity := klogFlags.Int(FlagVerbosity, 0, "number for the log level verbosity")
+
+	log, _ := newTestCmdLogger(t, "-v", "3")
+	if *klogVerbosity != 3 || !log.V(3) || log.V(4) {
+		t.Errorf("expected -v to set the verbosity of klog and of the logger to 3, got %d and %d", *klogVerbosity, log.verbosity)
+	}
+}
+
+func TestCmdLoggerJSON(t *testing.T) {
+	log, out := newTestCmdLogger(t, "--log-format=json")
+	log.Infof(0, "hello")
+	progress := log.newProgressBar(2)
+	progress.Step("a.yaml", "merged 3 entries")
+	progress.Finish()
+
+	expected := `{"level":"info","msg":"hello","ts":"2019-08-01T12:00:00Z"}` + "\n" +
+		`{"current":1,"item":"a.yaml","level":"info","msg":"merged 3 entries","total":2,"ts":"2019-08-01T12:00:00Z"}` + "\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestProgressBarWithoutTerminal(t *testing.T) {
+	log, out := newTestCmdLogger(t)
+	progress := log.newProgressBar(2)
+	progress.Step("a.yaml", "merged 3 entries")
+	progress.Step("b.yaml", "not a kubeconfig")
+	progress.Finish()
+
+	expected := "[1/2] a.yaml: merged 3 entries\n[2/2] b.yaml: not a kubeconfig\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestCmdLoggerInvalidFormat(t *testing.T) {
+	cmd := &cobra.Command{Use: "probe"}
+	addLoggingFlags(cmd)
+	if err := cmd.ParseFlags([]string{"--log-format=xml"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := newCmdLogger(cmd, &bytes.Buffer{}); err == nil {
+		t.Errorf("expected an error for an unknown log format")
+	}
+}
@@ -0,0 +1,331 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// Names of the conflict strategies, as accepted by --on-conflict and the onConflict setting.
+const (
+	conflictSkip         = "skip"
+	conflictOverwrite    = "overwrite"
+	conflictSuffix       = "suffix"
+	conflictSourcePrefix = "source-prefix"
+	conflictHash         = "hash"
+	conflictPrompt       = "prompt"
+)
+
+var conflictStrategyNames = []string{conflictSkip, conflictOverwrite, conflictSuffix, conflictSourcePrefix, conflictHash, conflictPrompt}
+
+// mergeConflict is an imported entry whose name the kubeconfig already uses.
+type mergeConflict struct {
+	// Kind is cluster, user or context.
+	Kind string
+	Name string
+	// Source names where the entry is imported from, it may be empty.
+	Source string
+	// Identical is set when the existing entry is the same as the imported one.
+	Identical bool
+	Imported  interface{}
+	// Used reports whether a name is taken by an entry of the same kind.
+	Used func(name string) bool
+}
+
+// conflictStrategy returns the name an imported entry is merged under when its name is taken: its
+// own name to replace the existing entry, an unused name, or an empty string to leave it out.
+type conflictStrategy func(conflict mergeConflict) (string, error)
+
+// mergeResult tells what merging a kubeconfig did.
+type mergeResult struct {
+	// Added is the number of entries added or replaced.
+	Added int
+	// Skipped has a reason for every entry left out.
+	Skipped []string
+	// Renamed describes every entry merged under another name.
+	Renamed []string
+}
+
+// newConflictStrategy returns the strategy called name. Only the prompt strategy uses the streams.
+func newConflictStrategy(name string, streams genericclioptions.IOStreams) (conflictStrategy, error) {
+	switch name {
+	case conflictSkip, "":
+		return skipConflicts, nil
+	case conflictOverwrite:
+		return overwriteConflicts, nil
+	case conflictSuffix:
+		return suffixConflicts, nil
+	case conflictSourcePrefix:
+		return sourcePrefixConflicts, nil
+	case conflictHash:
+		return hashConflicts, nil
+	case conflictPrompt:
+		return promptConflicts(bufio.NewReader(streams.In), streams.Out), nil
+	}
+	return nil, fmt.Errorf("unknown conflict strategy %q, must be one of: %s", name, strings.Join(conflictStrategyNames, ", "))
+}
+
+// confirmReplacements wraps resolve so that confirm is asked before an entry of config is replaced,
+// about the context replaced or the contexts using the cluster or user replaced.
+func confirmReplacements(resolve conflictStrategy, config *clientcmdapi.Config, confirm changeConfirmer) conflictStrategy {
+	if confirm == nil {
+		return resolve
+	}
+	return func(conflict mergeConflict) (string, error) {
+		target, err := resolve(conflict)
+		if err != nil || target != conflict.Name || conflict.Identical {
+			return target, err
+		}
+		names := []string{conflict.Name}
+		if conflict.Kind != "context" {
+			names = contextsUsing(config, conflict.Kind, conflict.Name)
+		}
+		return target, confirm.check(names)
+	}
+}
+
+func skipConflicts(conflict mergeConflict) (string, error) {
+	return "", nil
+}
+
+func overwriteConflicts(conflict mergeConflict) (string, error) {
+	return conflict.Name, nil
+}
+
+// suffixConflicts merges the entry as NAME-2, or the first of NAME-3, NAME-4... that is unused.
+func suffixConflicts(conflict mergeConflict) (string, error) {
+	if conflict.Identical {
+		return "", nil
+	}
+	return unusedName(conflict.Name, conflict.Used), nil
+}
+
+// sourcePrefixConflicts merges the entry as SOURCE-NAME, SOURCE being the file name of the source
+// without extension, and falls back to a suffix when that name is taken too.
+func sourcePrefixConflicts(conflict mergeConflict) (string, error) {
+	if conflict.Identical {
+		return "", nil
+	}
+	source := strings.TrimSuffix(filepath.Base(conflict.Source), filepath.Ext(conflict.Source))
+	if len(conflict.Source) == 0 || len(source) == 0 {
+		return unusedName(conflict.Name, conflict.Used), nil
+	}
+	name := source + "-" + conflict.Name
+	if !conflict.Used(name) {
+		return name, nil
+	}
+	return unusedName(name, conflict.Used), nil
+}
+
+// hashConflicts merges the entry as NAME-HASH, HASH being made of the content of the entry, so
+// that the same entry always gets the same name.
+func hashConflicts(conflict mergeConflict) (string, error) {
+	if conflict.Identical {
+		return "", nil
+	}
+	data, err := json.Marshal(conflict.Imported)
+	if err != nil {
+		return "", err
+	}
+	// The origin of the entry depends on the machine, it is left out of the hash.
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return "", err
+	}
+	delete(fields, "LocationOfOrigin")
+	if data, err = json.Marshal(fields); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	name := conflict.Name + "-" + hex.EncodeToString(sum[:])[:8]
+	if !conflict.Used(name) {
+		return name, nil
+	}
+	return unusedName(name, conflict.Used), nil
+}
+
+// promptConflicts asks what to do with every conflicting entry.
+func promptConflicts(in *bufio.Reader, out io.Writer) conflictStrategy {
+	return func(conflict mergeConflict) (string, error) {
+		if conflict.Identical {
+			return "", nil
+		}
+		from := ""
+		if len(conflict.Source) > 0 {
+			from = " from " + conflict.Source
+		}
+		for {
+			fmt.Fprintf(out, "The %s %q%s already exists. [s]kip, [o]verwrite or [r]ename? ", conflict.Kind, conflict.Name, from)
+			answer, err := in.ReadString('\n')
+			if err != nil && (err != io.EOF || len(answer) == 0) {
+				return "", fmt.Errorf("no answer for the %s %q: %v", conflict.Kind, conflict.Name, err)
+			}
+			switch strings.ToLower(strings.TrimSpace(answer)) {
+			case "s", "skip":
+				return "", nil
+			case "o", "overwrite":
+				return conflict.Name, nil
+			case "r", "rename":
+				suggested := unusedName(conflict.Name, conflict.Used)
+				fmt.Fprintf(out, "New name [%s]: ", suggested)
+				name, err := in.ReadString('\n')
+				if err != nil && err != io.EOF {
+					return "", err
+				}
+				name = strings.TrimSpace(name)
+				if len(name) == 0 {
+					return suggested, nil
+				}
+				if !conflict.Used(name) {
+					return name, nil
+				}
+				fmt.Fprintf(out, "The %s %q exists too.\n", conflict.Kind, name)
+			}
+		}
+	}
+}
+
+// unusedName returns the first of NAME-2, NAME-3... that is not used.
+func unusedName(name string, used func(string) bool) string {
+	for i := 2; ; i++ {
+		if candidate := fmt.Sprintf("%s-%d", name, i); !used(candidate) {
+			return candidate
+		}
+	}
+}
+
+// mergeConfigWithStrategy adds the clusters, users and contexts of from to into, letting resolve
+// decide what becomes of the entries whose name is taken. Contexts follow their cluster and user
+// when those are renamed. Entries are merged in the order of their names, so that the result only
+// depends on into and from.
+func mergeConfigWithStrategy(into, from *clientcmdapi.Config, source string, resolve conflictStrategy) (mergeResult, error) {
+	result := mergeResult{}
+	// merge returns the name an entry is merged under, or an empty string to leave it out.
+	merge := func(kind, name string, existing, imported interface{}, used func(string) bool) (string, error) {
+		if existing == nil || reflect.ValueOf(existing).IsNil() {
+			return name, nil
+		}
+		identical := reflect.DeepEqual(existing, imported)
+		target, err := resolve(mergeConflict{Kind: kind, Name: name, Source: source, Identical: identical, Imported: imported, Used: used})
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case len(target) == 0 && !identical:
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s %q, which already exists", kind, name))
+		case len(target) > 0 && target != name:
+			result.Renamed = append(result.Renamed, fmt.Sprintf("%s %q as %q", kind, name, target))
+		}
+		return target, nil
+	}
+
+	clusterNames := map[string]string{}
+	usedCluster := func(name string) bool { return into.Clusters[name] != nil || from.Clusters[name] != nil }
+	for _, name := range sortedClusterNames(from.Clusters) {
+		cluster := from.Clusters[name].DeepCopy()
+		existing := into.Clusters[name]
+		// Imported entries take over the origin of the entry they replace, if any, so that
+		// clientcmd.ModifyConfig writes them to the destination kubeconfig and never back to the source.
+		if existing != nil {
+			cluster.LocationOfOrigin = existing.LocationOfOrigin
+			keepProvenance(existing.Extensions, cluster.Extensions)
+		} else {
+			cluster.LocationOfOrigin = ""
+		}
+		target, err := merge("cluster", name, existing, cluster, usedCluster)
+		if err != nil {
+			return result, err
+		}
+		if len(target) == 0 {
+			continue
+		}
+		if target != name {
+			cluster.LocationOfOrigin = ""
+		}
+		into.Clusters[target] = cluster
+		clusterNames[name] = target
+		result.Added++
+	}
+
+	authInfoNames := map[string]string{}
+	usedAuthInfo := func(name string) bool { return into.AuthInfos[name] != nil || from.AuthInfos[name] != nil }
+	for _, name := range sortedAuthInfoNames(from.AuthInfos) {
+		authInfo := from.AuthInfos[name].DeepCopy()
+		existing := into.AuthInfos[name]
+		if existing != nil {
+			authInfo.LocationOfOrigin = existing.LocationOfOrigin
+			keepProvenance(existing.Extensions, authInfo.Extensions)
+		} else {
+			authInfo.LocationOfOrigin = ""
+		}
+		target, err := merge("user", name, existing, authInfo, usedAuthInfo)
+		if err != nil {
+			return result, err
+		}
+		if len(target) == 0 {
+			continue
+		}
+		if target != name {
+			authInfo.LocationOfOrigin = ""
+		}
+		into.AuthInfos[target] = authInfo
+		authInfoNames[name] = target
+		result.Added++
+	}
+
+	usedContext := func(name string) bool { return into.Contexts[name] != nil || from.Contexts[name] != nil }
+	for _, name := range sortedContextNames(from.Contexts) {
+		context := from.Contexts[name].DeepCopy()
+		if renamed, ok := clusterNames[context.Cluster]; ok {
+			context.Cluster = renamed
+		}
+		if renamed, ok := authInfoNames[context.AuthInfo]; ok {
+			context.AuthInfo = renamed
+		}
+		existing := into.Contexts[name]
+		if existing != nil {
+			context.LocationOfOrigin = existing.LocationOfOrigin
+			keepProvenance(existing.Extensions, context.Extensions)
+		} else {
+			context.LocationOfOrigin = ""
+		}
+		target, err := merge("context", name, existing, context, usedContext)
+		if err != nil {
+			return result, err
+		}
+		if len(target) == 0 {
+			continue
+		}
+		if target != name {
+			context.LocationOfOrigin = ""
+		}
+		into.Contexts[target] = context
+		result.Added++
+	}
+	return result, nil
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// newConflictingConfig imports a cluster, a user and a context named like those of
+// newRedFederalCowHammerConfig, but different, along with an identical copy of red-user.
+func newConflictingConfig() *clientcmdapi.Config {
+	from := clientcmdapi.NewConfig()
+	from.Clusters["cow-cluster"] = &clientcmdapi.Cluster{Server: "https://other.example.com"}
+	from.AuthInfos["blue-user"] = &clientcmdapi.AuthInfo{Token: "blue-token"}
+	from.AuthInfos["red-user"] = newRedFederalCowHammerConfig().AuthInfos["red-user"]
+	from.Contexts["federal-context"] = &clientcmdapi.Context{Cluster: "cow-cluster", AuthInfo: "blue-user"}
+	return from
+}
+
+func TestMergeConfigWithStrategy(t *testing.T) {
+	tests := []struct {
+		strategy string
+		source   string
+		// contexts maps the names of the merged contexts to their cluster.
+		contexts map[string]string
+		renamed  int
+		skipped  int
+	}{
+		{
+			strategy: conflictSkip,
+			contexts: map[string]string{"federal-context": "cow-cluster"},
+			skipped:  2,
+		},
+		{
+			strategy: conflictOverwrite,
+			contexts: map[string]string{"federal-context": "cow-cluster"},
+		},
+		{
+			strategy: conflictSuffix,
+			contexts: map[string]string{"federal-context": "cow-cluster", "federal-context-2": "cow-cluster-2"},
+			renamed:  2,
+		},
+		{
+			strategy: conflictSourcePrefix,
+			source:   "/tmp/imports/team-a.yaml",
+			contexts: map[string]string{"federal-context": "cow-cluster", "team-a-federal-context": "team-a-cow-cluster"},
+			renamed:  2,
+		},
+		{
+			strategy: conflictSourcePrefix,
+			contexts: map[string]string{"federal-context": "cow-cluster", "federal-context-2": "cow-cluster-2"},
+			renamed:  2,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.strategy+test.source, func(t *testing.T) {
+			resolve, err := newConflictStrategy(test.strategy, genericclioptions.NewTestIOStreamsDiscard())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			into := newRedFederalCowHammerConfig()
+			result, err := mergeConfigWithStrategy(&into, newConflictingConfig(), test.source, resolve)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(result.Renamed) != test.renamed || len(result.Skipped) != test.skipped {
+				t.Errorf("expected %d renamed and %d skipped entries, got %v and %v", test.renamed, test.skipped, result.Renamed, result.Skipped)
+			}
+			contexts := map[string]string{}
+			for name, context := range into.Contexts {
+				contexts[name] = context.Cluster
+				if into.Clusters[context.Cluster] == nil || into.AuthInfos[context.AuthInfo] == nil {
+					t.Errorf("context %q refers to missing entries: %#v", name, context)
+				}
+			}
+			if !reflect.DeepEqual(contexts, test.contexts) {
+				t.Errorf("expected contexts %v, got %v", test.contexts, contexts)
+			}
+			if test.strategy == conflictOverwrite && into.Clusters["cow-cluster"].Server != "https://other.example.com" {
+				t.Errorf("expected the existing cluster to be replaced")
+			}
+		})
+	}
+}
+
+func TestHashConflictsIsDeterministic(t *testing.T) {
+	names := []string{}
+	for _, origin := range []string{"/home/jane/.kube/config", "/builds/42/kubeconfig"} {
+		into := newRedFederalCowHammerConfig()
+		into.Clusters["cow-cluster"].LocationOfOrigin = origin
+		if _, err := mergeConfigWithStrategy(&into, newConflictingConfig(), "", hashConflicts); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for name := range into.Clusters {
+			if strings.HasPrefix(name, "cow-cluster-") {
+				names = append(names, name)
+			}
+		}
+	}
+	if len(names) != 2 || names[0] != names[1] || len(names[0]) != len("cow-cluster-")+8 {
+		t.Errorf("expected the same hashed name on every machine, got %v", names)
+	}
+}
+
+func TestPromptConflicts(t *testing.T) {
+	streams, in, out, _ := genericclioptions.NewTestIOStreams()
+	// The cluster is renamed to the suggested name, the user to a name of choice after an invalid
+	// answer, and the context is skipped.
+	in.WriteString("r\n\nwhat\nrename\nblue-user\nr\nblue\ns\n")
+	resolve, err := newConflictStrategy(conflictPrompt, streams)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	into := newRedFederalCowHammerConfig()
+	into.AuthInfos["blue-user"] = &clientcmdapi.AuthInfo{Token: "other-blue-token"}
+	result, err := mergeConfigWithStrategy(&into, newConflictingConfig(), "team-a.yaml", resolve)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if into.Clusters["cow-cluster-2"] == nil || into.AuthInfos["blue"] == nil || len(result.Skipped) != 1 {
+		t.Errorf("unexpected result %#v", result)
+	}
+	if !strings.Contains(out.String(), `The cluster "cow-cluster" from team-a.yaml already exists.`) || !strings.Contains(out.String(), `The user "blue-user" exists too.`) {
+		t.Errorf("unexpected prompts %q", out.String())
+	}
+
+	// Without answers, the merge fails rather than guess.
+	streams, _, _, _ = genericclioptions.NewTestIOStreams()
+	resolve, _ = newConflictStrategy(conflictPrompt, streams)
+	into = newRedFederalCowHammerConfig()
+	if _, err := mergeConfigWithStrategy(&into, newConflictingConfig(), "", resolve); err == nil {
+		t.Errorf("expected an error without answers")
+	}
+}
@@ -0,0 +1,267 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/tools/clientcmd/api/latest"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// MigrateOptions holds the command-line options for 'config migrate' sub command
+type MigrateOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	DryRun       bool
+
+	genericclioptions.IOStreams
+}
+
+// legacyFieldNames maps field names written by old or hand-rolled tooling to the names used by
+// the current kubeconfig format, per stanza. An empty replacement means the field is obsolete.
+var legacyFieldNames = map[string]map[string]string{
+	"config": {
+		"currentContext":  "current-context",
+		"current_context": "current-context",
+	},
+	"cluster": {
+		"certificateAuthority":     "certificate-authority",
+		"certificateAuthorityData": "certificate-authority-data",
+		"insecureSkipTLSVerify":    "insecure-skip-tls-verify",
+		"api-version":              "",
+	},
+	"user": {
+		"clientCertificate":     "client-certificate",
+		"clientCertificateData": "client-certificate-data",
+		"clientKey":             "client-key",
+		"clientKeyData":         "client-key-data",
+		"token-file":            "tokenFile",
+		"authProvider":          "auth-provider",
+	},
+	"context": {
+		"authInfo":  "user",
+		"auth-info": "user",
+	},
+}
+
+var (
+	migrateLong = templates.LongDesc(`
+		Rewrites kubeconfig files into the current kubeconfig format.
+
+		The following problems are fixed and reported:
+
+		    * missing apiVersion or kind
+		    * legacy or camelCase field names, and obsolete fields
+		    * Windows path separators in certificate, key and token file references
+
+		Every file of the loading chain is migrated on its own, so stanzas stay in the file that defines them.`)
+
+	migrateExample = templates.Examples(`
+		# Report what would be fixed without writing any file
+		kubectl config migrate --dry-run
+
+		# Migrate a single kubeconfig file
+		kubectl config migrate --kubeconfig=old-config`)
+)
+
+// NewCmdConfigMigrate returns a Command instance for 'config migrate' sub command
+func NewCmdConfigMigrate(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &MigrateOptions{
+		ConfigAccess: configAccess,
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "migrate [--dry-run]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Rewrite kubeconfig files into the current format"),
+		Long:                  migrateLong,
+		Example:               migrateExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(cmdutil.UsageErrorf(cmd, "unexpected arguments: %v", args))
+			}
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().BoolVar(&o.DryRun, "dry-run", o.DryRun, "If true, only report what would be fixed")
+	return cmd
+}
+
+// Run performs the execution of 'config migrate' sub command
+func (o MigrateOptions) Run() error {
+	for _, filename := range o.ConfigAccess.GetLoadingPrecedence() {
+		data, err := ioutil.ReadFile(filename)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		config, fixes, err := migrateKubeconfig(data)
+		if err != nil {
+			return fmt.Errorf("error migrating %s: %v", filename, err)
+		}
+		if len(fixes) == 0 {
+			fmt.Fprintf(o.Out, "%s: already in the current format\n", filename)
+			continue
+		}
+		for _, fix := range fixes {
+			fmt.Fprintf(o.Out, "%s: %s\n", filename, fix)
+		}
+		if o.DryRun {
+			continue
+		}
+		if err := clientcmd.WriteToFile(*config, filename); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateKubeconfig parses kubeconfig data written in any historical format and returns it in the
+// current format, together with a description of every fix applied.
+func migrateKubeconfig(data []byte) (*clientcmdapi.Config, []string, error) {
+	raw := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, nil, err
+	}
+	if raw == nil {
+		raw = map[string]interface{}{}
+	}
+
+	fixes := []string{}
+	if _, ok := raw["apiVersion"]; !ok {
+		raw["apiVersion"] = latest.Version
+		fixes = append(fixes, "added missing apiVersion")
+	}
+	if _, ok := raw["kind"]; !ok {
+		raw["kind"] = "Config"
+		fixes = append(fixes, "added missing kind")
+	}
+	fixes = append(fixes, renameLegacyFields(raw, "config", "")...)
+	for _, stanza := range []struct{ list, field string }{{"clusters", "cluster"}, {"users", "user"}, {"contexts", "context"}} {
+		entries, _ := raw[stanza.list].([]interface{})
+		for _, entry := range entries {
+			named, ok := entry.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			fields, ok := named[stanza.field].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			fixes = append(fixes, renameLegacyFields(fields, stanza.field, fmt.Sprintf("%s/%v", stanza.list, named["name"]))...)
+		}
+	}
+
+	normalized, err := json.Marshal(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	config, err := clientcmd.Load(normalized)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fixes = append(fixes, fixWindowsPathSeparators(config)...)
+	return config, fixes, nil
+}
+
+// renameLegacyFields renames the legacy fields of a single stanza in place.
+func renameLegacyFields(fields map[string]interface{}, stanza, location string) []string {
+	fixes := []string{}
+	renames := legacyFieldNames[stanza]
+	legacyNames := make([]string, 0, len(renames))
+	for legacyName := range renames {
+		legacyNames = append(legacyNames, legacyName)
+	}
+	sort.Strings(legacyNames)
+
+	prefix := ""
+	if len(location) > 0 {
+		prefix = location + ": "
+	}
+	for _, legacyName := range legacyNames {
+		value, ok := fields[legacyName]
+		if !ok {
+			continue
+		}
+		delete(fields, legacyName)
+
+		currentName := renames[legacyName]
+		switch _, exists := fields[currentName]; {
+		case len(currentName) == 0:
+			fixes = append(fixes, fmt.Sprintf("%sremoved obsolete field %q", prefix, legacyName))
+		case exists:
+			fixes = append(fixes, fmt.Sprintf("%sremoved legacy field %q shadowed by %q", prefix, legacyName, currentName))
+		default:
+			fields[currentName] = value
+			fixes = append(fixes, fmt.Sprintf("%srenamed field %q to %q", prefix, legacyName, currentName))
+		}
+	}
+	return fixes
+}
+
+// fixWindowsPathSeparators rewrites backslashes in file references to forward slashes, which
+// every platform accepts.
+func fixWindowsPathSeparators(config *clientcmdapi.Config) []string {
+	fixes := []string{}
+	fix := func(location string, refs []*string) {
+		for _, ref := range refs {
+			if strings.Contains(*ref, `\`) {
+				fixes = append(fixes, fmt.Sprintf("%s: replaced path separators in %q", location, *ref))
+				*ref = strings.Replace(*ref, `\`, "/", -1)
+			}
+		}
+	}
+
+	clusterNames := make([]string, 0, len(config.Clusters))
+	for name := range config.Clusters {
+		clusterNames = append(clusterNames, name)
+	}
+	sort.Strings(clusterNames)
+	for _, name := range clusterNames {
+		fix("clusters/"+name, clientcmd.GetClusterFileReferences(config.Clusters[name]))
+	}
+
+	userNames := make([]string, 0, len(config.AuthInfos))
+	for name := range config.AuthInfos {
+		userNames = append(userNames, name)
+	}
+	sort.Strings(userNames)
+	for _, name := range userNames {
+		fix("users/"+name, clientcmd.GetAuthInfoFileReferences(config.AuthInfos[name]))
+	}
+	return fixes
+}
@@ -0,0 +1,268 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// MigrateAuthOptions holds the command-line options for 'config migrate-auth' sub command
+type MigrateAuthOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Users        []string
+	DryRun       bool
+
+	genericclioptions.IOStreams
+}
+
+// authProviderMigration converts the configuration of an in-tree auth provider into
+// the exec credential plugin replacing it.
+type authProviderMigration func(config map[string]string) (*clientcmdapi.ExecConfig, error)
+
+// authProviderMigrations holds the known conversions keyed by auth-provider name.
+var authProviderMigrations = map[string]authProviderMigration{
+	"gcp":   migrateGCPAuthProvider,
+	"azure": migrateAzureAuthProvider,
+	"oidc":  migrateOIDCAuthProvider,
+}
+
+var (
+	migrateAuthLong = templates.LongDesc(`
+		Converts deprecated auth-provider user entries into their exec credential plugin equivalents.
+
+		The in-tree gcp, azure and oidc auth providers are replaced by the following plugins, which
+		must be installed separately:
+
+		    gcp:   gke-gcloud-auth-plugin
+		    azure: kubelogin
+		    oidc:  kubectl oidc-login
+
+		When no USER_NAME is given every user with a known auth-provider is migrated.`)
+
+	migrateAuthExample = templates.Examples(`
+		# Show the changes that would be made to every user without writing them
+		kubectl config migrate-auth --dry-run
+
+		# Migrate the "gke-admin" user to gke-gcloud-auth-plugin
+		kubectl config migrate-auth gke-admin`)
+)
+
+// NewCmdConfigMigrateAuth returns a Command instance for 'config migrate-auth' sub command
+func NewCmdConfigMigrateAuth(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &MigrateAuthOptions{
+		ConfigAccess: configAccess,
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "migrate-auth [USER_NAME...] [--dry-run]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Convert deprecated auth-provider users to exec credential plugins"),
+		Long:                  migrateAuthLong,
+		Example:               migrateAuthExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(cmd, args))
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().BoolVar(&o.DryRun, "dry-run", o.DryRun, "If true, only print the changes that would be made")
+	return cmd
+}
+
+// Complete assigns MigrateAuthOptions from the args.
+func (o *MigrateAuthOptions) Complete(cmd *cobra.Command, args []string) error {
+	o.Users = args
+	return nil
+}
+
+// Run performs the execution of 'config migrate-auth' sub command
+func (o MigrateAuthOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+
+	names := o.Users
+	if len(names) == 0 {
+		for name, authInfo := range config.AuthInfos {
+			if authInfo.AuthProvider != nil {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+	}
+
+	allErrs := []error{}
+	migrated := 0
+	for _, name := range names {
+		authInfo, exists := config.AuthInfos[name]
+		if !exists {
+			allErrs = append(allErrs, fmt.Errorf("user %q not found", name))
+			continue
+		}
+		if authInfo.AuthProvider == nil {
+			if len(o.Users) > 0 {
+				allErrs = append(allErrs, fmt.Errorf("user %q does not use an auth-provider", name))
+			}
+			continue
+		}
+		migrate, known := authProviderMigrations[authInfo.AuthProvider.Name]
+		if !known {
+			allErrs = append(allErrs, fmt.Errorf("user %q uses auth-provider %q which has no known exec replacement", name, authInfo.AuthProvider.Name))
+			continue
+		}
+		exec, err := migrate(authInfo.AuthProvider.Config)
+		if err != nil {
+			allErrs = append(allErrs, fmt.Errorf("cannot migrate user %q: %v", name, err))
+			continue
+		}
+
+		modified := *authInfo
+		modified.AuthProvider = nil
+		modified.Exec = exec
+
+		if o.DryRun {
+			if err := o.printAuthInfoDiff(name, authInfo, &modified); err != nil {
+				return err
+			}
+		} else {
+			fmt.Fprintf(o.Out, "User %q migrated from auth-provider %q to exec command %q.\n", name, authInfo.AuthProvider.Name, exec.Command)
+		}
+		config.AuthInfos[name] = &modified
+		migrated++
+	}
+
+	if migrated > 0 && !o.DryRun {
+		if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+			return err
+		}
+	}
+	if migrated == 0 && len(allErrs) == 0 {
+		fmt.Fprintln(o.Out, "No users use a deprecated auth-provider.")
+	}
+
+	return utilerrors.NewAggregate(allErrs)
+}
+
+// printAuthInfoDiff writes the kubeconfig form of a user before and after migration as a line diff.
+func (o MigrateAuthOptions) printAuthInfoDiff(name string, before, after *clientcmdapi.AuthInfo) error {
+	return writeAuthInfoDiff(o.Out, "users/"+name+" (auth-provider)", "users/"+name+" (exec)", name, before, after)
+}
+
+// writeAuthInfoDiff writes the kubeconfig form of a user before and after a change as a line diff.
+func writeAuthInfoDiff(out io.Writer, fromLabel, toLabel, name string, before, after *clientcmdapi.AuthInfo) error {
+	beforeData, err := clientcmd.Write(clientcmdapi.Config{AuthInfos: map[string]*clientcmdapi.AuthInfo{name: before}})
+	if err != nil {
+		return err
+	}
+	afterData, err := clientcmd.Write(clientcmdapi.Config{AuthInfos: map[string]*clientcmdapi.AuthInfo{name: after}})
+	if err != nil {
+		return err
+	}
+	return writeLineDiff(out, fromLabel, toLabel, string(beforeData), string(afterData))
+}
+
+func migrateGCPAuthProvider(config map[string]string) (*clientcmdapi.ExecConfig, error) {
+	// gke-gcloud-auth-plugin reads everything it needs from the gcloud configuration, the cached
+	// access-token and cmd-path settings of the old provider have no equivalent.
+	return &clientcmdapi.ExecConfig{
+		Command:    "gke-gcloud-auth-plugin",
+		APIVersion: defaultExecAPIVersion,
+	}, nil
+}
+
+func migrateAzureAuthProvider(config map[string]string) (*clientcmdapi.ExecConfig, error) {
+	for _, key := range []string{"apiserver-id", "client-id", "tenant-id"} {
+		if len(config[key]) == 0 {
+			return nil, fmt.Errorf("auth-provider config is missing %q", key)
+		}
+	}
+
+	environment := config["environment"]
+	if len(environment) == 0 {
+		environment = "AzurePublicCloud"
+	}
+	args := []string{
+		"get-token",
+		"--login", "devicecode",
+		"--environment", environment,
+		"--server-id", config["apiserver-id"],
+		"--client-id", config["client-id"],
+		"--tenant-id", config["tenant-id"],
+	}
+	// config-mode 0 (the default) requests tokens for "spn:<apiserver-id>", which kubelogin only
+	// reproduces in legacy mode.
+	if mode := config["config-mode"]; len(mode) == 0 || mode == "0" {
+		args = append(args, "--legacy")
+	}
+
+	return &clientcmdapi.ExecConfig{
+		Command:    "kubelogin",
+		Args:       args,
+		APIVersion: defaultExecAPIVersion,
+	}, nil
+}
+
+func migrateOIDCAuthProvider(config map[string]string) (*clientcmdapi.ExecConfig, error) {
+	for _, key := range []string{"idp-issuer-url", "client-id"} {
+		if len(config[key]) == 0 {
+			return nil, fmt.Errorf("auth-provider config is missing %q", key)
+		}
+	}
+
+	args := []string{
+		"oidc-login",
+		"get-token",
+		"--oidc-issuer-url=" + config["idp-issuer-url"],
+		"--oidc-client-id=" + config["client-id"],
+	}
+	if secret := config["client-secret"]; len(secret) > 0 {
+		args = append(args, "--oidc-client-secret="+secret)
+	}
+	for _, scope := range strings.Split(config["extra-scopes"], ",") {
+		if scope = strings.TrimSpace(scope); len(scope) > 0 {
+			args = append(args, "--oidc-extra-scope="+scope)
+		}
+	}
+	if ca := config["idp-certificate-authority"]; len(ca) > 0 {
+		args = append(args, "--certificate-authority="+ca)
+	}
+	if caData := config["idp-certificate-authority-data"]; len(caData) > 0 {
+		args = append(args, "--certificate-authority-data="+caData)
+	}
+
+	return &clientcmdapi.ExecConfig{
+		Command:    "kubectl",
+		Args:       args,
+		APIVersion: defaultExecAPIVersion,
+	}, nil
+}
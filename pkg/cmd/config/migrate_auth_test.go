@@ -0,0 +1,192 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestAuthProviderMigrations(t *testing.T) {
+	tests := []struct {
+		name        string
+		provider    string
+		config      map[string]string
+		expected    *clientcmdapi.ExecConfig
+		expectedErr string
+	}{
+		{
+			name:     "gcp",
+			provider: "gcp",
+			config:   map[string]string{"access-token": "ya29.token", "cmd-path": "/usr/bin/gcloud"},
+			expected: &clientcmdapi.ExecConfig{
+				Command:    "gke-gcloud-auth-plugin",
+				APIVersion: "client.authentication.k8s.io/v1beta1",
+			},
+		},
+		{
+			name:     "azure in the default config-mode",
+			provider: "azure",
+			config: map[string]string{
+				"apiserver-id": "server",
+				"client-id":    "client",
+				"tenant-id":    "tenant",
+			},
+			expected: &clientcmdapi.ExecConfig{
+				Command: "kubelogin",
+				Args: []string{
+					"get-token", "--login", "devicecode", "--environment", "AzurePublicCloud",
+					"--server-id", "server", "--client-id", "client", "--tenant-id", "tenant", "--legacy",
+				},
+				APIVersion: "client.authentication.k8s.io/v1beta1",
+			},
+		},
+		{
+			name:     "azure in config-mode 1",
+			provider: "azure",
+			config: map[string]string{
+				"apiserver-id": "server",
+				"client-id":    "client",
+				"tenant-id":    "tenant",
+				"environment":  "AzureChinaCloud",
+				"config-mode":  "1",
+			},
+			expected: &clientcmdapi.ExecConfig{
+				Command: "kubelogin",
+				Args: []string{
+					"get-token", "--login", "devicecode", "--environment", "AzureChinaCloud",
+					"--server-id", "server", "--client-id", "client", "--tenant-id", "tenant",
+				},
+				APIVersion: "client.authentication.k8s.io/v1beta1",
+			},
+		},
+		{
+			name:        "azure without a tenant",
+			provider:    "azure",
+			config:      map[string]string{"apiserver-id": "server", "client-id": "client"},
+			expectedErr: `missing "tenant-id"`,
+		},
+		{
+			name:     "oidc",
+			provider: "oidc",
+			config: map[string]string{
+				"idp-issuer-url": "https://issuer.example.com",
+				"client-id":      "kubernetes",
+				"client-secret":  "secret",
+				"extra-scopes":   "groups, email",
+				"id-token":       "cached",
+			},
+			expected: &clientcmdapi.ExecConfig{
+				Command: "kubectl",
+				Args: []string{
+					"oidc-login", "get-token",
+					"--oidc-issuer-url=https://issuer.example.com",
+					"--oidc-client-id=kubernetes",
+					"--oidc-client-secret=secret",
+					"--oidc-extra-scope=groups",
+					"--oidc-extra-scope=email",
+				},
+				APIVersion: "client.authentication.k8s.io/v1beta1",
+			},
+		},
+		{
+			name:        "oidc without an issuer",
+			provider:    "oidc",
+			config:      map[string]string{"client-id": "kubernetes"},
+			expectedErr: `missing "idp-issuer-url"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			exec, err := authProviderMigrations[test.provider](test.config)
+			if len(test.expectedErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(exec, test.expected) {
+				t.Errorf("expected %#v\nbut got %#v", test.expected, exec)
+			}
+		})
+	}
+}
+
+func TestMigrateAuth(t *testing.T) {
+	startingConfig := clientcmdapi.Config{
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{
+			"gke-user":   {AuthProvider: &clientcmdapi.AuthProviderConfig{Name: "gcp"}},
+			"token-user": {Token: "token"},
+		},
+	}
+
+	for _, dryRun := range []bool{true, false} {
+		fakeKubeFile, err := ioutil.TempFile("", "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer os.Remove(fakeKubeFile.Name())
+		if err := clientcmd.WriteToFile(startingConfig, fakeKubeFile.Name()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		pathOptions := clientcmd.NewDefaultPathOptions()
+		pathOptions.GlobalFile = fakeKubeFile.Name()
+		pathOptions.EnvVar = ""
+		streams, _, buf, _ := genericclioptions.NewTestIOStreams()
+		options := MigrateAuthOptions{
+			ConfigAccess: pathOptions,
+			DryRun:       dryRun,
+			IOStreams:    streams,
+		}
+		if err := options.Run(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		config, err := clientcmd.LoadFromFile(fakeKubeFile.Name())
+		if err != nil {
+			t.Fatalf("unexpected error loading kubeconfig file: %v", err)
+		}
+		authInfo := config.AuthInfos["gke-user"]
+		if dryRun {
+			if authInfo.AuthProvider == nil || authInfo.Exec != nil {
+				t.Errorf("dry-run modified the kubeconfig: %#v", authInfo)
+			}
+			if !strings.Contains(buf.String(), "+      command: gke-gcloud-auth-plugin") {
+				t.Errorf("expected the diff to add the exec command, got\n%s", buf.String())
+			}
+			continue
+		}
+		if authInfo.AuthProvider != nil || authInfo.Exec == nil || authInfo.Exec.Command != "gke-gcloud-auth-plugin" {
+			t.Errorf("expected gke-user to be migrated, got %#v", authInfo)
+		}
+		if config.AuthInfos["token-user"].Token != "token" {
+			t.Errorf("expected token-user to be untouched, got %#v", config.AuthInfos["token-user"])
+		}
+	}
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const legacyKubeconfig = `
+clusters:
+- name: legacy
+  cluster:
+    server: https://1.2.3.4
+    certificateAuthority: C:\Users\me\.kube\ca.crt
+    api-version: v1
+users:
+- name: legacy
+  user:
+    token-file: C:\Users\me\.kube\token
+    as: admin
+contexts:
+- name: legacy
+  context:
+    cluster: legacy
+    authInfo: legacy
+currentContext: legacy
+`
+
+func TestMigrateKubeconfig(t *testing.T) {
+	config, fixes, err := migrateKubeconfig([]byte(legacyKubeconfig))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedFixes := []string{
+		`added missing apiVersion`,
+		`added missing kind`,
+		`renamed field "currentContext" to "current-context"`,
+		`clusters/legacy: removed obsolete field "api-version"`,
+		`clusters/legacy: renamed field "certificateAuthority" to "certificate-authority"`,
+		`users/legacy: renamed field "token-file" to "tokenFile"`,
+		`contexts/legacy: renamed field "authInfo" to "user"`,
+		`clusters/legacy: replaced path separators in "C:\\Users\\me\\.kube\\ca.crt"`,
+		`users/legacy: replaced path separators in "C:\\Users\\me\\.kube\\token"`,
+	}
+	if !reflect.DeepEqual(fixes, expectedFixes) {
+		t.Errorf("expected fixes\n%s\nbut got\n%s", strings.Join(expectedFixes, "\n"), strings.Join(fixes, "\n"))
+	}
+
+	if config.CurrentContext != "legacy" {
+		t.Errorf("expected current-context %q, got %q", "legacy", config.CurrentContext)
+	}
+	if ca := config.Clusters["legacy"].CertificateAuthority; ca != "C:/Users/me/.kube/ca.crt" {
+		t.Errorf("unexpected certificate-authority %q", ca)
+	}
+	if user := config.AuthInfos["legacy"]; user.TokenFile != "C:/Users/me/.kube/token" || user.Impersonate != "admin" {
+		t.Errorf("unexpected user %#v", user)
+	}
+	if context := config.Contexts["legacy"]; context.AuthInfo != "legacy" {
+		t.Errorf("unexpected context %#v", context)
+	}
+}
+
+func TestMigrateCurrentKubeconfig(t *testing.T) {
+	data, err := clientcmd.Write(newRedFederalCowHammerConfig())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, fixes, err := migrateKubeconfig(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fixes) != 0 {
+		t.Errorf("expected no fixes for a current kubeconfig, got %v", fixes)
+	}
+}
+
+func TestMigrateDryRun(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	if _, err := fakeKubeFile.WriteString(legacyKubeconfig); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fakeKubeFile.Close()
+
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+	streams, _, buf, _ := genericclioptions.NewTestIOStreams()
+	options := MigrateOptions{ConfigAccess: pathOptions, DryRun: true, IOStreams: streams}
+	if err := options.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.Contains(buf.String(), "added missing apiVersion") {
+		t.Errorf("expected a report of the fixes, got %q", buf.String())
+	}
+	data, err := ioutil.ReadFile(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data) != legacyKubeconfig {
+		t.Errorf("dry-run modified the kubeconfig file:\n%s", data)
+	}
+}
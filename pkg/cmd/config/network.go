@@ -0,0 +1,63 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// FlagNoNetwork is the persistent flag that forbids config subcommands from using the network.
+	FlagNoNetwork = "no-network"
+
+	// NoNetworkEnvVar has the same effect as --no-network when set to a true value, which is
+	// convenient on air-gapped hosts where every invocation should be offline.
+	NoNetworkEnvVar = "KUBECTL_CONFIG_NO_NETWORK"
+)
+
+// addNoNetworkFlag registers --no-network on the root config command so every subcommand inherits it.
+func addNoNetworkFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().Bool(FlagNoNetwork, false, "Fail instead of contacting any server. Commands that only read or write kubeconfig files never use the network. Can also be set with $"+NoNetworkEnvVar)
+}
+
+// networkDisabled reports whether network access was forbidden by flag or environment.
+func networkDisabled(cmd *cobra.Command) bool {
+	if cmd != nil {
+		if flag := cmd.Flags().Lookup(FlagNoNetwork); flag != nil && flag.Changed {
+			disabled, _ := toBool(flag.Value.String())
+			return disabled
+		}
+	}
+	disabled, _ := toBool(os.Getenv(NoNetworkEnvVar))
+	return disabled
+}
+
+// requireNetwork must be called by every subcommand before it contacts a server, so that it fails
+// fast with a clear error instead of hanging on an unreachable network.
+func requireNetwork(cmd *cobra.Command) error {
+	if !networkDisabled(cmd) {
+		return nil
+	}
+	name := "this command"
+	if cmd != nil {
+		name = fmt.Sprintf("%q", cmd.CommandPath())
+	}
+	return fmt.Errorf("%s needs network access, which is disabled by --%s or $%s", name, FlagNoNetwork, NoNetworkEnvVar)
+}
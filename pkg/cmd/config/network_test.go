@@ -0,0 +1,97 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestRequireNetwork(t *testing.T) {
+	defer os.Unsetenv(NoNetworkEnvVar)
+
+	tests := []struct {
+		name        string
+		flags       []string
+		env         string
+		expectedErr bool
+	}{
+		{name: "network allowed"},
+		{name: "flag", flags: []string{"--no-network"}, expectedErr: true},
+		{name: "env", env: "true", expectedErr: true},
+		{name: "flag overrides env", flags: []string{"--no-network=false"}, env: "true"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			os.Setenv(NoNetworkEnvVar, test.env)
+			cmd := &cobra.Command{Use: "probe"}
+			addNoNetworkFlag(cmd)
+			if err := cmd.ParseFlags(test.flags); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			err := requireNetwork(cmd)
+			if test.expectedErr {
+				if err == nil || !strings.Contains(err.Error(), `"probe" needs network access`) {
+					t.Errorf("expected a network access error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCoreCommandsWorkOffline(t *testing.T) {
+	expectedConfig := newRedFederalCowHammerConfig()
+	expectedConfig.Contexts["offline-context"] = expectedConfig.Contexts["federal-context"]
+	delete(expectedConfig.Contexts, "federal-context")
+	expectedConfig.CurrentContext = "offline-context"
+
+	test := configCommandTest{
+		args:            []string{"rename-context", ".", "offline-context", "--no-network"},
+		startingConfig:  newRedFederalCowHammerConfig(),
+		expectedConfig:  expectedConfig,
+		expectedOutputs: []string{`Context "federal-context" renamed to "offline-context".`},
+	}
+	test.run(t)
+
+	expectedConfig = newRedFederalCowHammerConfig()
+	expectedConfig.Contexts["federal-context"] = &clientcmdapi.Context{AuthInfo: "red-user", Cluster: "cow-cluster", Namespace: "offline"}
+	test = configCommandTest{
+		args:           []string{"set", "contexts.federal-context.namespace", "offline", "--no-network"},
+		startingConfig: newRedFederalCowHammerConfig(),
+		expectedConfig: expectedConfig,
+	}
+	test.run(t)
+
+	test = configCommandTest{
+		args:            []string{"get-contexts", "--no-network"},
+		startingConfig:  newRedFederalCowHammerConfig(),
+		expectedConfig:  newRedFederalCowHammerConfig(),
+		expectedOutputs: []string{"federal-context"},
+	}
+	test.run(t)
+}
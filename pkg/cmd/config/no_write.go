@@ -0,0 +1,105 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// FlagNoWrite is the persistent flag that forbids config subcommands from writing any file.
+	FlagNoWrite = "no-write"
+
+	// NoWriteEnvVar has the same effect as --no-write when set to a true value, for audit shells
+	// where every invocation should leave the files inspected alone.
+	NoWriteEnvVar = "KUBECTL_CONFIG_NO_WRITE"
+
+	// noWriteAnnotation marks the subcommands that never write files. Its value lists the flags,
+	// separated by commas, that make the subcommand write after all.
+	noWriteAnnotation = "kubecfg.io/no-write"
+)
+
+// addNoWriteFlag registers --no-write on the root config command so every subcommand inherits it.
+func addNoWriteFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().Bool(FlagNoWrite, false, "Refuse every command that could write a file, to inspect kubeconfig files safely. Can also be set with $"+NoWriteEnvVar)
+}
+
+// noWriteCommand marks cmd as never writing files unless one of writingFlags is given, which allows
+// it to run with --no-write.
+func noWriteCommand(cmd *cobra.Command, writingFlags ...string) *cobra.Command {
+	if cmd.Annotations == nil {
+		cmd.Annotations = map[string]string{}
+	}
+	cmd.Annotations[noWriteAnnotation] = strings.Join(writingFlags, ",")
+	return cmd
+}
+
+// noWriteParents marks cmd and its descendants that only group subcommands, following the
+// "NAME SUBCOMMAND" usage, as never writing files: run on their own, they print their help.
+func noWriteParents(cmd *cobra.Command) {
+	if strings.HasSuffix(cmd.Use, " SUBCOMMAND") {
+		noWriteCommand(cmd)
+	}
+	for _, sub := range cmd.Commands() {
+		noWriteParents(sub)
+	}
+}
+
+// writesDisabled reports whether writing was forbidden by flag or environment.
+func writesDisabled(cmd *cobra.Command) bool {
+	if cmd != nil {
+		if flag := cmd.Flags().Lookup(FlagNoWrite); flag != nil && flag.Changed {
+			disabled, _ := toBool(flag.Value.String())
+			return disabled
+		}
+	}
+	disabled, _ := toBool(os.Getenv(NoWriteEnvVar))
+	return disabled
+}
+
+// checkNoWrite refuses to run cmd when writing is forbidden, unless cmd is marked with
+// noWriteCommand and none of its writing flags is given. Commands are refused unless marked, so
+// that a command added later cannot write by mistake.
+func checkNoWrite(cmd *cobra.Command) error {
+	if !writesDisabled(cmd) {
+		return nil
+	}
+	writingFlags, ok := cmd.Annotations[noWriteAnnotation]
+	if !ok {
+		return fmt.Errorf("%q may write files, which is forbidden by --%s or $%s", cmd.CommandPath(), FlagNoWrite, NoWriteEnvVar)
+	}
+	for _, name := range strings.Split(writingFlags, ",") {
+		if flag := cmd.Flags().Lookup(name); len(name) > 0 && flag != nil && flag.Changed {
+			return fmt.Errorf("--%s of %q writes files, which is forbidden by --%s or $%s", name, cmd.CommandPath(), FlagNoWrite, NoWriteEnvVar)
+		}
+	}
+	return nil
+}
+
+// checkWritingArgs refuses the arguments of cmd, marked with noWriteCommand, that make it write
+// files after all when writing is forbidden. Commands call it once they know their arguments
+// change something.
+func checkWritingArgs(cmd *cobra.Command) error {
+	if !writesDisabled(cmd) {
+		return nil
+	}
+	return fmt.Errorf("the arguments of %q write files, which is forbidden by --%s or $%s", cmd.CommandPath(), FlagNoWrite, NoWriteEnvVar)
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+func TestNoWrite(t *testing.T) {
+	defer os.Unsetenv(NoWriteEnvVar)
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	config := newRedFederalCowHammerConfig()
+	config.Contexts["other-context"] = &clientcmdapi.Context{AuthInfo: "red-user", Cluster: "cow-cluster"}
+	if err := clientcmd.WriteToFile(config, fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	before, err := ioutil.ReadFile(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		args        []string
+		env         string
+		expectedErr string
+	}{
+		{name: "view", args: []string{"--no-write", "view"}},
+		{name: "lint", args: []string{"--no-write", "lint", "--naming", ".*"}},
+		{name: "use-context", args: []string{"--no-write", "use-context", "other-context"}, expectedErr: `"config use-context" may write files`},
+		{name: "env", args: []string{"delete-context", "other-context"}, env: "true", expectedErr: `"config delete-context" may write files`},
+		{name: "writing flag", args: []string{"--no-write", "lint", "--naming", "^x$", "--fix", "--template", "x"}, expectedErr: `--fix of "config lint" writes files`},
+		{name: "export to secret", args: []string{"--no-write", "export", "--to-secret", "kube-system/kubeconfig"}, expectedErr: `--to-secret of "config export" writes files`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			os.Setenv(NoWriteEnvVar, test.env)
+			streams, _, _, _ := genericclioptions.NewTestIOStreams()
+			cmd := NewCmdConfig(cmdutil.NewFactory(genericclioptions.NewTestConfigFlags()), clientcmd.NewDefaultPathOptions(), streams)
+			cmd.SetOutput(ioutil.Discard)
+			cmd.SetArgs(append([]string{"-f", fakeKubeFile.Name()}, test.args...))
+			err := cmd.Execute()
+			if len(test.expectedErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Errorf("expected an error containing %q, got %v", test.expectedErr, err)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			after, err := ioutil.ReadFile(fakeKubeFile.Name())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(after) != string(before) {
+				t.Errorf("expected the kubeconfig to be left alone, got\n%s", after)
+			}
+		})
+	}
+}
+
+func TestNoWriteCommandTree(t *testing.T) {
+	// writing lists every command that may write a file. All the others must run with --no-write.
+	writing := sets.NewString(
+		"acknowledge", "as", "autoswitch add", "autoswitch remove", "autoswitch run", "banner set",
+		"banner unset", "cache clear", "convert-kubelogin", "credential", "delete-cluster",
+		"delete-context", "deprecate", "encrypt-user", "enrich gke", "exec", "gc", "group create",
+		"group delete", "import", "import capi", "import k0s", "import kubeadm", "import kubectx-state",
+		"import local", "import secret", "import talos", "import vcluster", "include add",
+		"include remove", "include sync", "init", "kubectl install", "lock", "migrate", "migrate-auth",
+		"ns", "ns bookmark add", "ns bookmark remove", "overlay create", "profile create",
+		"profile delete", "profile use", "protect", "pull", "push", "receive", "record start",
+		"record stop", "refresh", "refresh-endpoint", "refresh-local", "rename-context", "repair",
+		"replay", "rewrite-aws", "session end", "session start", "session use", "set", "set-cluster",
+		"set-context", "set-credentials", "set-kubectl-version", "set-namespace", "set-owner",
+		"settings set", "shell-init allow", "shell-init deny", "sign", "source add", "source remove",
+		"source sync", "state import", "support-bundle", "unset", "use-context", "verify-identity",
+	)
+
+	os.Setenv(NoWriteEnvVar, "true")
+	defer os.Unsetenv(NoWriteEnvVar)
+	streams, _, _, _ := genericclioptions.NewTestIOStreams()
+	root := NewCmdConfig(cmdutil.NewFactory(genericclioptions.NewTestConfigFlags()), clientcmd.NewDefaultPathOptions(), streams)
+
+	seen := sets.NewString()
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		path := strings.TrimPrefix(strings.TrimPrefix(cmd.CommandPath(), root.Name()), " ")
+		seen.Insert(path)
+		err := checkNoWrite(cmd)
+		switch {
+		case writing.Has(path) && err == nil:
+			t.Errorf("%q may write files, expected it to be refused with --no-write", cmd.CommandPath())
+		case !writing.Has(path) && err != nil:
+			t.Errorf("%q does not write files, expected it to run with --no-write: %v", cmd.CommandPath(), err)
+		}
+		for _, sub := range cmd.Commands() {
+			walk(sub)
+		}
+	}
+	walk(root)
+	if missing := writing.Difference(seen); missing.Len() > 0 {
+		t.Errorf("writing commands not found in the command tree: %v", missing.List())
+	}
+}
+
+func TestNoWriteArgs(t *testing.T) {
+	os.Setenv(NoWriteEnvVar, "true")
+	defer os.Unsetenv(NoWriteEnvVar)
+	streams, _, _, _ := genericclioptions.NewTestIOStreams()
+	cmd := NewCmdConfigReadOnly(streams, clientcmd.NewDefaultPathOptions())
+	o := &ReadOnlyOptions{IOStreams: streams}
+	if err := o.Complete(cmd, []string{"prod"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err := o.Complete(cmd, []string{"prod", "on"})
+	if err == nil || !strings.Contains(err.Error(), `the arguments of "readonly" write files`) {
+		t.Errorf("expected the state to be refused with --no-write, got %v", err)
+	}
+}
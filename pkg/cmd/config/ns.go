@@ -0,0 +1,596 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/printers"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// namespaceBookmarks is stored in a context's namespaceBookmarksExtension.
+type namespaceBookmarks struct {
+	Namespaces []string `json:"namespaces"`
+}
+
+// readNamespaceBookmarks returns the bookmarked namespaces of a context.
+func readNamespaceBookmarks(context *clientcmdapi.Context) ([]string, error) {
+	bookmarks := namespaceBookmarks{}
+	_, err := readExtension(context.Extensions, namespaceBookmarksExtension, &bookmarks)
+	return bookmarks.Namespaces, err
+}
+
+// writeNamespaceBookmarks sets the bookmarked namespaces of a context, removing the extension when
+// there are none.
+func writeNamespaceBookmarks(context *clientcmdapi.Context, namespaces []string) error {
+	if len(namespaces) == 0 {
+		delete(context.Extensions, namespaceBookmarksExtension)
+		return nil
+	}
+	return writeExtension(&context.Extensions, namespaceBookmarksExtension, namespaceBookmarks{Namespaces: namespaces})
+}
+
+// listClusterNamespaces lists the namespaces of the cluster of the context called name matching
+// the label selector, which the API server filters them with.
+func listClusterNamespaces(config *clientcmdapi.Config, name, selector string) ([]string, error) {
+	restConfig, err := newProbeConfig(config, name, nil, 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	list, err := clientset.CoreV1().Namespaces().List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	namespaces := []string{}
+	for _, namespace := range list.Items {
+		namespaces = append(namespaces, namespace.Name)
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
+
+// listAllNamespaces is the answer of "config ns" listing the namespaces of the cluster.
+const listAllNamespaces = "*"
+
+// defaultNamespaceCacheTTL is how long "config ns" reuses the namespaces of a cluster by default.
+const defaultNamespaceCacheTTL = 30 * time.Second
+
+// namespaceCacheFile returns the file the namespaces of clusters are cached in.
+func namespaceCacheFile() string {
+	return filepath.Join(stateDir(), "namespace-cache.json")
+}
+
+// namespaceCacheEntry is the namespaces of the cluster of a context matching a selector, with the
+// fingerprint of the context, cluster and user they were listed with.
+type namespaceCacheEntry struct {
+	Fingerprint string    `json:"fingerprint"`
+	Listed      time.Time `json:"listed"`
+	Namespaces  []string  `json:"namespaces"`
+}
+
+// namespaceCache answers the listings of namespaces from the cache file while they are younger
+// than TTL and the context, its cluster and its user are unchanged. A TTL of 0 disables it.
+type namespaceCache struct {
+	Filename string
+	TTL      time.Duration
+	Now      func() time.Time
+}
+
+// list returns the namespaces of the cluster of the context called name matching selector, from
+// the cache or else from list, whose result is cached.
+func (c namespaceCache) list(config *clientcmdapi.Config, name, selector string, list func(config *clientcmdapi.Config, name, selector string) ([]string, error)) ([]string, error) {
+	if c.TTL <= 0 {
+		return list(config, name, selector)
+	}
+	entries, err := c.load()
+	if err != nil {
+		return nil, err
+	}
+	now := c.Now()
+	key, fingerprint := name+"\x00"+selector, healthFingerprint(config, name)
+	if entry, ok := entries[key]; ok && entry.Fingerprint == fingerprint && now.Sub(entry.Listed) < c.TTL {
+		return entry.Namespaces, nil
+	}
+
+	namespaces, err := list(config, name, selector)
+	if err != nil {
+		return nil, err
+	}
+	for key, entry := range entries {
+		if now.Sub(entry.Listed) >= c.TTL {
+			delete(entries, key)
+		}
+	}
+	entries[key] = namespaceCacheEntry{Fingerprint: fingerprint, Listed: now, Namespaces: namespaces}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.Filename), 0700); err != nil {
+		return nil, err
+	}
+	return namespaces, ioutil.WriteFile(c.Filename, data, 0600)
+}
+
+// cached returns the namespaces of the cluster of the context called name matching selector from
+// the cache however old they are, as long as the context, its cluster and its user are unchanged,
+// and whether there were any. It is used when the cluster cannot be listed without the network.
+func (c namespaceCache) cached(config *clientcmdapi.Config, name, selector string) ([]string, bool) {
+	entries, err := c.load()
+	if err != nil {
+		return nil, false
+	}
+	entry, ok := entries[name+"\x00"+selector]
+	if !ok || entry.Fingerprint != healthFingerprint(config, name) {
+		return nil, false
+	}
+	return entry.Namespaces, true
+}
+
+// load reads the entries of the cache file, keyed by context name and selector.
+func (c namespaceCache) load() (map[string]namespaceCacheEntry, error) {
+	entries := map[string]namespaceCacheEntry{}
+	if data, err := ioutil.ReadFile(c.Filename); err == nil {
+		// A broken cache is empty.
+		if json.Unmarshal(data, &entries) != nil {
+			entries = map[string]namespaceCacheEntry{}
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// filterNamespaces returns the namespaces starting with prefix.
+func filterNamespaces(namespaces []string, prefix string) []string {
+	if len(prefix) == 0 {
+		return namespaces
+	}
+	filtered := []string{}
+	for _, namespace := range namespaces {
+		if strings.HasPrefix(namespace, prefix) {
+			filtered = append(filtered, namespace)
+		}
+	}
+	return filtered
+}
+
+// NamespaceOptions holds the command-line options for 'config ns' sub command
+type NamespaceOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Context      string
+	Namespaces   []string
+	Filter       string
+	Selector     string
+	List         bool
+	CacheTTL     time.Duration
+	CacheFile    string
+
+	// ListNamespaces lists the namespaces of the cluster of a context matching a label selector,
+	// it is a field so tests can describe a cluster.
+	ListNamespaces func(config *clientcmdapi.Config, name, selector string) ([]string, error)
+	Now            func() time.Time
+
+	cmd *cobra.Command
+	// Confirm asks before the contexts the confirm setting covers are changed.
+	Confirm changeConfirmer
+
+	genericclioptions.IOStreams
+}
+
+var (
+	namespaceLong = templates.LongDesc(`
+		Pick the namespace of a context, the current-context by default.
+
+		The namespaces bookmarked for the context with "kubectl config ns bookmark add" are offered
+		first, without listing the namespaces of the cluster, which takes long on clusters with
+		thousands of them. Answer with the number of a namespace, with its name, or with * to list
+		the namespaces of the cluster and pick one of them. Without bookmarks, the namespaces of the
+		cluster are listed right away.
+
+		--filter only offers the namespaces starting with a prefix, and --selector only lists the
+		namespaces of the cluster whose labels match a selector, which the API server filters them
+		with. --list prints the bookmarked namespaces and then those of the cluster, one per line,
+		without asking, for shell completion.
+
+		The namespaces of a cluster are cached below $XDG_STATE_HOME/kubecfg, or
+		~/.local/state/kubecfg, for --cache-ttl, so that repeated invocations do not list them
+		again. Changing the context, its cluster or its user invalidates the cache. With
+		--no-network, only the bookmarks and the cached namespaces are offered, however old.
+
+		Bookmarks are kept in a kubeconfig extension of the context.`)
+
+	namespaceExample = templates.Examples(`
+		# Bookmark the namespaces used most in prod
+		kubectl config ns bookmark add prod payments web
+
+		# Pick the namespace of the current-context
+		kubectl config ns
+
+		# Pick among the namespaces of team payments starting with pay
+		kubectl config ns prod --filter pay --selector team=payments
+
+		# Print the namespaces of the current-context for completion, listed at most every minute
+		kubectl config ns --list --cache-ttl 1m
+
+		# List the bookmarks of every context
+		kubectl config ns bookmark list
+
+		# Forget a bookmark
+		kubectl config ns bookmark remove prod web`)
+)
+
+// NewCmdConfigNamespace returns a Command instance for 'config ns' sub command
+func NewCmdConfigNamespace(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &NamespaceOptions{
+		ConfigAccess:   configAccess,
+		Context:        currentContextShorthand,
+		CacheTTL:       defaultNamespaceCacheTTL,
+		CacheFile:      namespaceCacheFile(),
+		ListNamespaces: listClusterNamespaces,
+		Now:            time.Now,
+		IOStreams:      streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "ns [CONTEXT_NAME] [--filter=PREFIX] [--selector=SELECTOR] [--list] [--cache-ttl=DURATION]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Pick the namespace of a context, bookmarked namespaces first"),
+		Long:                  namespaceLong,
+		Example:               namespaceExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) > 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			if len(args) == 1 {
+				o.Context = args[0]
+			}
+			o.cmd = cmd
+			// The namespace and the confirmation are read through the same buffer.
+			o.In = bufio.NewReader(streams.In)
+			o.Confirm = newChangeConfirmer(cmd, configAccess, o.In, streams.ErrOut)
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	cmd.Flags().StringVar(&o.Filter, "filter", o.Filter, "Only offer the namespaces starting with this prefix")
+	cmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, "Only list the namespaces of the cluster whose labels match this selector, such as team=payments")
+	cmd.Flags().BoolVar(&o.List, "list", o.List, "Print the namespaces one per line instead of asking for one")
+	cmd.Flags().DurationVar(&o.CacheTTL, "cache-ttl", o.CacheTTL, "How long the namespaces of the cluster are reused, 0 to always list them")
+
+	bookmarkCmd := &cobra.Command{
+		Use:   "bookmark SUBCOMMAND",
+		Short: i18n.T("Manage the namespaces bookmarked for contexts"),
+		Run:   cmdutil.DefaultSubCommandRun(streams.ErrOut),
+	}
+	bookmarkCmd.AddCommand(&cobra.Command{
+		Use:   "add CONTEXT_NAME NAMESPACE...",
+		Short: i18n.T("Bookmark namespaces for a context"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) < 2 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.Context, o.Namespaces = args[0], args[1:]
+			cmdutil.CheckErr(o.RunAdd())
+		},
+	})
+	bookmarkCmd.AddCommand(&cobra.Command{
+		Use:   "remove CONTEXT_NAME NAMESPACE...",
+		Short: i18n.T("Remove bookmarked namespaces of a context"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) < 2 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.Context, o.Namespaces = args[0], args[1:]
+			cmdutil.CheckErr(o.RunRemove())
+		},
+	})
+	bookmarkCmd.AddCommand(noWriteCommand(&cobra.Command{
+		Use:   "list [CONTEXT_NAME]",
+		Short: i18n.T("List the bookmarked namespaces of contexts"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) > 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.Context = ""
+			if len(args) == 1 {
+				o.Context = args[0]
+			}
+			cmdutil.CheckErr(o.RunList())
+		},
+	}))
+	cmd.AddCommand(bookmarkCmd)
+	return cmd
+}
+
+// context loads the kubeconfig and returns the context of the options, with its name.
+func (o *NamespaceOptions) context() (*clientcmdapi.Config, string, *clientcmdapi.Context, error) {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return nil, "", nil, err
+	}
+	name, err := resolveContextName(config, o.Context)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	context, ok := config.Contexts[name]
+	if !ok {
+		return nil, "", nil, fmt.Errorf("no context exists with the name: %q", name)
+	}
+	return config, name, context, nil
+}
+
+// RunAdd bookmarks the namespaces for the context, after the ones it has
+func (o *NamespaceOptions) RunAdd() error {
+	config, name, context, err := o.context()
+	if err != nil {
+		return err
+	}
+	bookmarks, err := readNamespaceBookmarks(context)
+	if err != nil {
+		return fmt.Errorf("context %q: %v", name, err)
+	}
+	added := 0
+	for _, namespace := range o.Namespaces {
+		if !containsString(bookmarks, namespace) {
+			bookmarks = append(bookmarks, namespace)
+			added++
+		}
+	}
+	if err := writeNamespaceBookmarks(context, bookmarks); err != nil {
+		return err
+	}
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "%d namespace(s) bookmarked for context %q.\n", added, name)
+	return nil
+}
+
+// RunRemove removes the namespaces from the bookmarks of the context
+func (o *NamespaceOptions) RunRemove() error {
+	config, name, context, err := o.context()
+	if err != nil {
+		return err
+	}
+	bookmarks, err := readNamespaceBookmarks(context)
+	if err != nil {
+		return fmt.Errorf("context %q: %v", name, err)
+	}
+	kept := []string{}
+	for _, namespace := range bookmarks {
+		if !containsString(o.Namespaces, namespace) {
+			kept = append(kept, namespace)
+		}
+	}
+	if len(kept) == len(bookmarks) {
+		return fmt.Errorf("context %q has none of the namespaces bookmarked: %s", name, strings.Join(o.Namespaces, ", "))
+	}
+	if err := writeNamespaceBookmarks(context, kept); err != nil {
+		return err
+	}
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "%d bookmark(s) removed from context %q.\n", len(bookmarks)-len(kept), name)
+	return nil
+}
+
+// RunList prints the bookmarks of the context, or of every context that has some
+func (o *NamespaceOptions) RunList() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	names := sortedContextNames(config.Contexts)
+	if len(o.Context) > 0 {
+		name, err := resolveContextName(config, o.Context)
+		if err != nil {
+			return err
+		}
+		if _, ok := config.Contexts[name]; !ok {
+			return fmt.Errorf("no context exists with the name: %q", name)
+		}
+		names = []string{name}
+	}
+	rows := [][2]string{}
+	for _, name := range names {
+		bookmarks, err := readNamespaceBookmarks(config.Contexts[name])
+		if err != nil {
+			return fmt.Errorf("context %q: %v", name, err)
+		}
+		if len(bookmarks) > 0 || len(o.Context) > 0 {
+			rows = append(rows, [2]string{name, valueOrNone(strings.Join(bookmarks, ","))})
+		}
+	}
+	if len(rows) == 0 {
+		fmt.Fprintln(o.Out, "No bookmarks found.")
+		return nil
+	}
+	w := printers.GetNewTabWriter(o.Out)
+	fmt.Fprintf(w, "CONTEXT\tNAMESPACES\n")
+	for _, row := range rows {
+		fmt.Fprintf(w, "%s\t%s\n", row[0], row[1])
+	}
+	return w.Flush()
+}
+
+// Validate makes sure the label selector parses, and the cache TTL is not negative
+func (o *NamespaceOptions) Validate() error {
+	if _, err := labels.Parse(o.Selector); err != nil {
+		return fmt.Errorf("invalid selector %q: %v", o.Selector, err)
+	}
+	if o.CacheTTL < 0 {
+		return errors.New("--cache-ttl cannot be negative")
+	}
+	return nil
+}
+
+// clusterNamespaces lists the namespaces of the cluster of the context called name, through the
+// cache, filtered. Without network access, only the cached namespaces are returned, however old.
+func (o *NamespaceOptions) clusterNamespaces(config *clientcmdapi.Config, name string) ([]string, error) {
+	cache := namespaceCache{Filename: o.CacheFile, TTL: o.CacheTTL, Now: o.Now}
+	if err := requireNetwork(o.cmd); err != nil {
+		namespaces, ok := cache.cached(config, name, o.Selector)
+		if !ok {
+			return nil, err
+		}
+		return filterNamespaces(namespaces, o.Filter), nil
+	}
+	namespaces, err := cache.list(config, name, o.Selector, o.ListNamespaces)
+	if err != nil {
+		return nil, fmt.Errorf("listing the namespaces of context %q: %v", name, err)
+	}
+	return filterNamespaces(namespaces, o.Filter), nil
+}
+
+// Run asks for the namespace of the context, offering its bookmarks first, and sets it
+func (o *NamespaceOptions) Run() error {
+	config, name, context, err := o.context()
+	if err != nil {
+		return err
+	}
+	bookmarks, err := readNamespaceBookmarks(context)
+	if err != nil {
+		return fmt.Errorf("context %q: %v", name, err)
+	}
+	bookmarks = filterNamespaces(bookmarks, o.Filter)
+
+	if o.List {
+		// Completion offers the bookmarks alone when the cluster cannot be listed offline.
+		namespaces, err := o.clusterNamespaces(config, name)
+		if err != nil && !networkDisabled(o.cmd) {
+			return err
+		}
+		for _, namespace := range bookmarks {
+			fmt.Fprintln(o.Out, namespace)
+		}
+		for _, namespace := range namespaces {
+			if !containsString(bookmarks, namespace) {
+				fmt.Fprintln(o.Out, namespace)
+			}
+		}
+		return nil
+	}
+
+	in := bufio.NewReader(o.In)
+	choices, listed := bookmarks, false
+	if len(choices) == 0 {
+		if choices, err = o.clusterNamespaces(config, name); err != nil {
+			return err
+		}
+		listed = true
+	}
+	namespace := ""
+	for len(namespace) == 0 {
+		if listed {
+			fmt.Fprintf(o.Out, "Namespaces of context %q:\n", name)
+		} else {
+			fmt.Fprintf(o.Out, "Bookmarked namespaces of context %q:\n", name)
+		}
+		for i, choice := range choices {
+			current := ""
+			if choice == context.Namespace {
+				current = " (current)"
+			}
+			fmt.Fprintf(o.Out, "  %d) %s%s\n", i+1, choice, current)
+		}
+		if !listed {
+			fmt.Fprintf(o.Out, "  %s) list the namespaces of the cluster\n", listAllNamespaces)
+		}
+		answer, err := readNamespaceAnswer(in, o.Out, context.Namespace)
+		if err != nil {
+			return err
+		}
+		switch i, convErr := strconv.Atoi(answer); {
+		case answer == listAllNamespaces && !listed:
+			namespaces, err := o.clusterNamespaces(config, name)
+			if err != nil && networkDisabled(o.cmd) {
+				fmt.Fprintf(o.Out, "The namespaces of the cluster are not cached: %v.\n", err)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			choices, listed = namespaces, true
+		case convErr == nil && i >= 1 && i <= len(choices):
+			namespace = choices[i-1]
+		case convErr == nil || len(answer) == 0:
+			fmt.Fprintf(o.Out, "%q is not one of the choices.\n", answer)
+		default:
+			namespace = answer
+		}
+	}
+
+	if namespace == context.Namespace {
+		fmt.Fprintf(o.Out, "Context %q already uses namespace %q.\n", name, namespace)
+		return nil
+	}
+	if err := o.Confirm.check([]string{name}); err != nil {
+		return err
+	}
+	context.Namespace = namespace
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "Context %q now uses namespace %q.\n", name, namespace)
+	return nil
+}
+
+// readNamespaceAnswer asks for a namespace, current by default.
+func readNamespaceAnswer(in *bufio.Reader, out io.Writer, current string) (string, error) {
+	prompt := "Namespace: "
+	if len(current) > 0 {
+		prompt = fmt.Sprintf("Namespace [%s]: ", current)
+	}
+	fmt.Fprint(out, prompt)
+	answer, err := in.ReadString('\n')
+	if err != nil && (err != io.EOF || len(answer) == 0) {
+		return "", fmt.Errorf("no namespace picked: %v", err)
+	}
+	answer = strings.TrimSpace(answer)
+	if len(answer) == 0 {
+		answer = current
+	}
+	return answer, nil
+}
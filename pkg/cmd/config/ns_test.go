@@ -0,0 +1,271 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestNamespaceBookmarks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ns")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := clientcmd.WriteToFile(newRedFederalCowHammerConfig(), filepath.Join(dir, "config")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = filepath.Join(dir, "config")
+	pathOptions.EnvVar = ""
+
+	newOptions := func(context string, namespaces ...string) (*NamespaceOptions, func() string) {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		return &NamespaceOptions{ConfigAccess: pathOptions, Context: context, Namespaces: namespaces, IOStreams: streams}, out.String
+	}
+	bookmarks := func() []string {
+		config, err := clientcmd.LoadFromFile(pathOptions.GlobalFile)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		namespaces, err := readNamespaceBookmarks(config.Contexts["federal-context"])
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return namespaces
+	}
+
+	o, out := newOptions("federal-context", "web", "payments")
+	if err := o.RunAdd(); err != nil || out() != "2 namespace(s) bookmarked for context \"federal-context\".\n" {
+		t.Errorf("unexpected output %q, %v", out(), err)
+	}
+	o, _ = newOptions(".", "payments", "batch")
+	if err := o.RunAdd(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected, actual := []string{"web", "payments", "batch"}, bookmarks(); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+
+	o, out = newOptions("")
+	if err := o.RunList(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out()), "\n"); len(lines) != 2 || strings.Fields(lines[1])[1] != "web,payments,batch" {
+		t.Errorf("unexpected list %q", out())
+	}
+
+	o, _ = newOptions("federal-context", "web")
+	if err := o.RunRemove(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.RunRemove(); err == nil {
+		t.Errorf("expected removing a missing bookmark to fail")
+	}
+	o, _ = newOptions("federal-context", "payments", "batch")
+	if err := o.RunRemove(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actual := bookmarks(); len(actual) != 0 {
+		t.Errorf("expected no bookmarks, got %v", actual)
+	}
+	config, err := clientcmd.LoadFromFile(pathOptions.GlobalFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := config.Contexts["federal-context"].Extensions[namespaceBookmarksExtension]; ok {
+		t.Errorf("expected the extension to be removed")
+	}
+}
+
+func TestPickNamespace(t *testing.T) {
+	tests := map[string]struct {
+		bookmarks []string
+		answers   string
+		expected  string
+		listed    bool
+	}{
+		"bookmark by number": {
+			bookmarks: []string{"web", "payments"},
+			answers:   "2\n",
+			expected:  "payments",
+		},
+		"name typed": {
+			bookmarks: []string{"web"},
+			answers:   "batch\n",
+			expected:  "batch",
+		},
+		"invalid choice": {
+			bookmarks: []string{"web"},
+			answers:   "7\n1\n",
+			expected:  "web",
+		},
+		"listed on demand": {
+			bookmarks: []string{"web"},
+			answers:   "*\n3\n",
+			expected:  "monitoring",
+			listed:    true,
+		},
+		"listed without bookmarks": {
+			answers:  "1\n",
+			expected: "default",
+			listed:   true,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "ns")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer os.RemoveAll(dir)
+			config := newRedFederalCowHammerConfig()
+			if err := writeNamespaceBookmarks(config.Contexts["federal-context"], test.bookmarks); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := clientcmd.WriteToFile(config, filepath.Join(dir, "config")); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			pathOptions := clientcmd.NewDefaultPathOptions()
+			pathOptions.GlobalFile = filepath.Join(dir, "config")
+			pathOptions.EnvVar = ""
+
+			streams, in, out, _ := genericclioptions.NewTestIOStreams()
+			in.WriteString(test.answers)
+			listed := false
+			o := &NamespaceOptions{
+				ConfigAccess: pathOptions,
+				Context:      currentContextShorthand,
+				ListNamespaces: func(config *clientcmdapi.Config, name, selector string) ([]string, error) {
+					listed = true
+					return []string{"default", "kube-system", "monitoring", "web"}, nil
+				},
+				IOStreams: streams,
+			}
+			if err := o.Run(); err != nil {
+				t.Fatalf("unexpected error: %v\n%s", err, out.String())
+			}
+			if listed != test.listed {
+				t.Errorf("expected the namespaces of the cluster to be listed: %v, got %v", test.listed, listed)
+			}
+			loaded, err := clientcmd.LoadFromFile(pathOptions.GlobalFile)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual := loaded.Contexts["federal-context"].Namespace; actual != test.expected {
+				t.Errorf("expected namespace %q, got %q\n%s", test.expected, actual, out.String())
+			}
+		})
+	}
+}
+
+func TestListNamespaces(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ns")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	config := newRedFederalCowHammerConfig()
+	if err := writeNamespaceBookmarks(config.Contexts["federal-context"], []string{"web", "payments"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := clientcmd.WriteToFile(config, filepath.Join(dir, "config")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = filepath.Join(dir, "config")
+	pathOptions.EnvVar = ""
+
+	now := time.Date(2019, 8, 1, 0, 0, 0, 0, time.UTC)
+	selectors := []string{}
+	run := func(filter, selector string) string {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		o := &NamespaceOptions{
+			ConfigAccess: pathOptions,
+			Context:      currentContextShorthand,
+			Filter:       filter,
+			Selector:     selector,
+			List:         true,
+			CacheTTL:     time.Minute,
+			CacheFile:    filepath.Join(dir, "namespace-cache.json"),
+			ListNamespaces: func(config *clientcmdapi.Config, name, selector string) ([]string, error) {
+				selectors = append(selectors, selector)
+				if selector == "team=payments" {
+					return []string{"payments", "payouts"}, nil
+				}
+				return []string{"default", "payments", "payouts", "web"}, nil
+			},
+			Now:       func() time.Time { return now },
+			IOStreams: streams,
+		}
+		if err := o.Validate(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := o.Run(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return out.String()
+	}
+
+	if out := run("", ""); out != "web\npayments\ndefault\npayouts\n" {
+		t.Errorf("expected the bookmarks first, got %q", out)
+	}
+	if out := run("pay", ""); out != "payments\npayouts\n" {
+		t.Errorf("expected the namespaces starting with pay, got %q", out)
+	}
+	if out := run("", "team=payments"); out != "web\npayments\npayouts\n" {
+		t.Errorf("expected the namespaces of the selector, got %q", out)
+	}
+	if expected := []string{"", "team=payments"}; !reflect.DeepEqual(expected, selectors) {
+		t.Errorf("expected the cluster to be listed once per selector, got %q", selectors)
+	}
+
+	now = now.Add(2 * time.Minute)
+	run("", "")
+	if expected := []string{"", "team=payments", ""}; !reflect.DeepEqual(expected, selectors) {
+		t.Errorf("expected an expired listing to be listed again, got %q", selectors)
+	}
+
+	defer os.Setenv(NoNetworkEnvVar, os.Getenv(NoNetworkEnvVar))
+	os.Setenv(NoNetworkEnvVar, "true")
+	now = now.Add(time.Hour)
+	if out := run("", ""); out != "web\npayments\ndefault\npayouts\n" {
+		t.Errorf("expected the expired cache to be used without network, got %q", out)
+	}
+	if out := run("", "team=web"); out != "web\npayments\n" {
+		t.Errorf("expected only the bookmarks without network nor cache, got %q", out)
+	}
+	if expected := []string{"", "team=payments", ""}; !reflect.DeepEqual(expected, selectors) {
+		t.Errorf("expected the cluster not to be listed without network, got %q", selectors)
+	}
+	os.Unsetenv(NoNetworkEnvVar)
+
+	o := &NamespaceOptions{Selector: "team in (", CacheTTL: time.Minute}
+	if err := o.Validate(); err == nil {
+		t.Errorf("expected an invalid selector to be rejected")
+	}
+}
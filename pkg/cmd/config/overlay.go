@@ -0,0 +1,325 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/util/homedir"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// overlayConfigAccess routes the writes of the config subcommands away from the managed kubeconfig
+// files of the loading chain, as named by the managedKubeconfigs setting, to the first file of the
+// chain that is not managed: the overlay. Entries of managed files are changed by writing a copy
+// to the overlay, which wins over the managed file as it comes first.
+type overlayConfigAccess struct {
+	*clientcmd.PathOptions
+	SettingsFile string
+}
+
+// overlay returns the overlay file and the managed files of the loading chain, and no managed files
+// when the chain has none or a kubeconfig file was given explicitly. The overlay must come first, or
+// the managed files before it would win over the copies written to it.
+func (a *overlayConfigAccess) overlay() (string, sets.String, error) {
+	managed := sets.NewString()
+	if a.IsExplicitFile() {
+		return "", managed, nil
+	}
+	settings, err := loadSettings(a.SettingsFile)
+	if err != nil || len(settings.ManagedKubeconfigs) == 0 {
+		return "", managed, err
+	}
+	overlay, first := "", ""
+	for _, filename := range a.GetLoadingPrecedence() {
+		switch {
+		case matchesManagedKubeconfig(settings.ManagedKubeconfigs, filename):
+			managed.Insert(filename)
+			if len(overlay) == 0 && len(first) == 0 {
+				first = filename
+			}
+		case len(overlay) == 0:
+			overlay = filename
+		}
+	}
+	switch {
+	case managed.Len() > 0 && len(overlay) == 0:
+		return "", managed, fmt.Errorf("every kubeconfig file is managed and cannot be written to, put an overlay file first in $%s, see \"kubectl config overlay\"", a.EnvVar)
+	case len(first) > 0:
+		return "", managed, fmt.Errorf("the managed kubeconfig %s comes before the overlay %s, put the overlay first in $%s, see \"kubectl config overlay\"", first, overlay, a.EnvVar)
+	}
+	return overlay, managed, nil
+}
+
+// GetStartingConfig loads the kubeconfig, pointing the entries of managed files at the overlay so
+// that changing them writes a copy there.
+func (a *overlayConfigAccess) GetStartingConfig() (*clientcmdapi.Config, error) {
+	config, err := a.PathOptions.GetStartingConfig()
+	if err != nil {
+		return nil, err
+	}
+	overlay, managed, err := a.overlay()
+	if err != nil || managed.Len() == 0 {
+		return config, err
+	}
+	for _, cluster := range config.Clusters {
+		if managed.Has(cluster.LocationOfOrigin) {
+			cluster.LocationOfOrigin = overlay
+		}
+	}
+	for _, authInfo := range config.AuthInfos {
+		if managed.Has(authInfo.LocationOfOrigin) {
+			authInfo.LocationOfOrigin = overlay
+		}
+	}
+	for _, context := range config.Contexts {
+		if managed.Has(context.LocationOfOrigin) {
+			context.LocationOfOrigin = overlay
+		}
+	}
+	return config, nil
+}
+
+// GetDefaultFilename returns the overlay, where new entries are written, when the loading chain has
+// managed files.
+func (a *overlayConfigAccess) GetDefaultFilename() string {
+	if overlay, managed, err := a.overlay(); err == nil && managed.Len() > 0 {
+		return overlay
+	}
+	return a.PathOptions.GetDefaultFilename()
+}
+
+// managedOrigin returns the managed file the entry called name of kind, one of context, cluster or
+// user, is read from, and an empty string when it is read from a file that is not managed.
+func (a *overlayConfigAccess) managedOrigin(kind, name string) (string, error) {
+	_, managed, err := a.overlay()
+	if err != nil || managed.Len() == 0 {
+		return "", err
+	}
+	config, err := a.PathOptions.GetStartingConfig()
+	if err != nil {
+		return "", err
+	}
+	origin := ""
+	switch kind {
+	case "context":
+		if context, ok := config.Contexts[name]; ok {
+			origin = context.LocationOfOrigin
+		}
+	case "cluster":
+		if cluster, ok := config.Clusters[name]; ok {
+			origin = cluster.LocationOfOrigin
+		}
+	case "user":
+		if authInfo, ok := config.AuthInfos[name]; ok {
+			origin = authInfo.LocationOfOrigin
+		}
+	}
+	if !managed.Has(origin) {
+		return "", nil
+	}
+	return origin, nil
+}
+
+// checkDeletable returns an error when the entry called name of kind, one of context, cluster or
+// user, is read from a managed kubeconfig file, whose entries cannot be deleted as the config
+// subcommands never write to it.
+func checkDeletable(configAccess clientcmd.ConfigAccess, kind, name string) error {
+	access, ok := configAccess.(*overlayConfigAccess)
+	if !ok {
+		return nil
+	}
+	filename, err := access.managedOrigin(kind, name)
+	if err != nil || len(filename) == 0 {
+		return err
+	}
+	return fmt.Errorf("cannot delete %s %s, it comes from the managed kubeconfig %s, whose entries can only be overridden", kind, name, filename)
+}
+
+// matchesManagedKubeconfig reports whether filename matches one of the file patterns.
+func matchesManagedKubeconfig(patterns []string, filename string) bool {
+	for _, pattern := range patterns {
+		pattern = expandPath(pattern)
+		if matched, _ := filepath.Match(pattern, filename); matched || pattern == filename {
+			return true
+		}
+	}
+	return false
+}
+
+// OverlayOptions holds the command-line options for 'config overlay' sub commands
+type OverlayOptions struct {
+	Name         string
+	Base         string
+	Overlay      string
+	SettingsFile string
+	ProfilesFile string
+
+	genericclioptions.IOStreams
+}
+
+var (
+	overlayLong = templates.LongDesc(`
+		Combine a kubeconfig file managed by a platform team with an overlay file of your own,
+		holding your personal contexts and namespaces.
+
+		"overlay create" marks the base file as managed in the managedKubeconfigs setting, creates
+		the overlay file, and a profile NAME loading the overlay first and the base after it, as
+		$KUBECONFIG. kubectl combines both files when reading them. The config subcommands never
+		write to a managed file: new entries, the current-context and the preferences go to the
+		overlay, and changing an entry of the base writes a copy of it to the overlay, which wins
+		over the base. The base can then be replaced with a new version at any time.
+
+		Entries of the base cannot be deleted, only overridden: delete-context, delete-cluster and
+		gc refuse to delete them. The overlay must come first in $KUBECONFIG, the config
+		subcommands refuse to write otherwise. Nothing is redirected when a kubeconfig file is
+		given with --kubeconfig.
+
+		Two writes made by the kubeconfig library of kubectl are not redirected. Clearing the
+		current-context, as "unset current-context" does, writes to the first file setting one,
+		which is the base once the overlay sets none. Changing the preferences writes to the first
+		file whose preferences differ from the new ones, which is the base when the overlay already
+		holds them. Keep a current-context in the overlay, as "overlay create" does, and change the
+		preferences of the overlay file itself with --kubeconfig, to leave the base untouched.`)
+
+	overlayExample = templates.Examples(`
+		# Combine the kubeconfig of the platform team with a personal overlay
+		kubectl config overlay create team-a /etc/kubernetes/team-a.yaml
+		eval "$(kubectl config profile use team-a)"
+
+		# Changes now go to ~/.kube/overlays/team-a.yaml, never to the team kubeconfig
+		kubectl config set-context --current --namespace=my-feature`)
+)
+
+// NewCmdConfigOverlay returns a Command instance for 'config overlay' sub commands
+func NewCmdConfigOverlay(streams genericclioptions.IOStreams) *cobra.Command {
+	o := &OverlayOptions{
+		SettingsFile: settingsFile(),
+		ProfilesFile: filepath.Join(configDir(), "profiles.yaml"),
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "overlay SUBCOMMAND",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Combine a managed kubeconfig with a personal overlay file"),
+		Long:                  overlayLong,
+		Example:               overlayExample,
+		Run:                   cmdutil.DefaultSubCommandRun(streams.ErrOut),
+	}
+
+	createCmd := &cobra.Command{
+		Use:   "create NAME BASE [--overlay=FILE]",
+		Short: i18n.T("Mark a kubeconfig as managed and create an overlay and a profile for it"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 2 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.Name, o.Base = args[0], args[1]
+			cmdutil.CheckErr(o.Complete())
+			cmdutil.CheckErr(o.RunCreate())
+		},
+	}
+	createCmd.Flags().StringVar(&o.Overlay, "overlay", o.Overlay, "The overlay file. Defaults to ~/.kube/overlays/NAME.yaml")
+	cmd.AddCommand(createCmd)
+	return cmd
+}
+
+// Complete resolves the base and overlay files
+func (o *OverlayOptions) Complete() error {
+	if len(o.Name) == 0 {
+		return errors.New("you must specify a non-empty overlay name")
+	}
+	if len(o.Overlay) == 0 {
+		o.Overlay = filepath.Join(homedir.HomeDir(), ".kube", "overlays", o.Name+".yaml")
+	}
+	var err error
+	if o.Base, err = filepath.Abs(expandPath(o.Base)); err != nil {
+		return err
+	}
+	if o.Overlay, err = filepath.Abs(expandPath(o.Overlay)); err != nil {
+		return err
+	}
+	if o.Base == o.Overlay {
+		return fmt.Errorf("the overlay cannot be the base kubeconfig %s", o.Base)
+	}
+	return nil
+}
+
+// RunCreate marks the base as managed, creates the overlay and the profile
+func (o *OverlayOptions) RunCreate() error {
+	base, err := clientcmd.LoadFromFile(o.Base)
+	if err != nil {
+		return err
+	}
+
+	settings, err := loadSettings(o.SettingsFile)
+	if err != nil {
+		return err
+	}
+	if !matchesManagedKubeconfig(settings.ManagedKubeconfigs, o.Base) {
+		settings.ManagedKubeconfigs = append(settings.ManagedKubeconfigs, o.Base)
+		if err := saveSettings(o.SettingsFile, settings); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(o.Out, "Marked %s as managed.\n", o.Base)
+
+	switch _, err := os.Stat(o.Overlay); {
+	case os.IsNotExist(err):
+		// The overlay sets the current-context, which client-go writes to the first file setting it.
+		overlay := clientcmdapi.NewConfig()
+		overlay.CurrentContext = base.CurrentContext
+		if err := os.MkdirAll(filepath.Dir(o.Overlay), 0700); err != nil {
+			return err
+		}
+		if err := clientcmd.WriteToFile(*overlay, o.Overlay); err != nil {
+			return err
+		}
+		fmt.Fprintf(o.Out, "Created the overlay %s.\n", o.Overlay)
+	case err != nil:
+		return err
+	default:
+		fmt.Fprintf(o.Out, "Using the existing overlay %s.\n", o.Overlay)
+	}
+
+	all, err := loadProfiles(o.ProfilesFile)
+	if err != nil {
+		return err
+	}
+	if all.Profiles == nil {
+		all.Profiles = map[string][]string{}
+	}
+	all.Profiles[o.Name] = []string{o.Overlay, o.Base}
+	if err := saveProfiles(o.ProfilesFile, all); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "Created the profile %s, switch to it with: eval \"$(kubectl config profile use %s)\"\n", o.Name, o.Name)
+	return nil
+}
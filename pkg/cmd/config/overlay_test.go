@@ -0,0 +1,170 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestOverlay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "overlay")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	base := filepath.Join(dir, "team-a.yaml")
+	if err := clientcmd.WriteToFile(newRedFederalCowHammerConfig(), base); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	baseData, err := ioutil.ReadFile(base)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	streams, _, _, _ := genericclioptions.NewTestIOStreams()
+	o := &OverlayOptions{
+		Name:         "team-a",
+		Base:         base,
+		Overlay:      filepath.Join(dir, "overlays", "team-a.yaml"),
+		SettingsFile: filepath.Join(dir, "config.yaml"),
+		ProfilesFile: filepath.Join(dir, "profiles.yaml"),
+		IOStreams:    streams,
+	}
+	if err := o.Complete(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.RunCreate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	all, err := loadProfiles(o.ProfilesFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if files := all.Profiles["team-a"]; !reflect.DeepEqual(files, []string{o.Overlay, base}) {
+		t.Errorf("expected the profile to load the overlay before the base, got %v", files)
+	}
+
+	envVar := "KUBECFG_TEST_OVERLAY"
+	os.Setenv(envVar, o.Overlay+string(filepath.ListSeparator)+base)
+	defer os.Unsetenv(envVar)
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.EnvVar = envVar
+	access := &overlayConfigAccess{PathOptions: pathOptions, SettingsFile: o.SettingsFile}
+
+	config, err := access.GetStartingConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	config.Contexts["federal-context"].Namespace = "my-feature"
+	config.Contexts["mine"] = &clientcmdapi.Context{Cluster: "cow-cluster", AuthInfo: "red-user"}
+	config.CurrentContext = "mine"
+	if err := clientcmd.ModifyConfig(access, *config, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if data, err := ioutil.ReadFile(base); err != nil || !bytes.Equal(data, baseData) {
+		t.Errorf("expected the managed base to be left alone, got %v:\n%s", err, data)
+	}
+	overlay, err := clientcmd.LoadFromFile(o.Overlay)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if overlay.CurrentContext != "mine" || overlay.Contexts["mine"] == nil {
+		t.Errorf("expected the new context and current-context in the overlay, got %+v", overlay)
+	}
+	if context := overlay.Contexts["federal-context"]; context == nil || context.Namespace != "my-feature" {
+		t.Errorf("expected the changed context of the base to be copied to the overlay, got %+v", context)
+	}
+	if access.GetDefaultFilename() != o.Overlay {
+		t.Errorf("expected new entries to go to the overlay, not %s", access.GetDefaultFilename())
+	}
+
+	merged, err := access.GetStartingConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if merged.Contexts["federal-context"].Namespace != "my-feature" || merged.Clusters["cow-cluster"] == nil {
+		t.Errorf("expected the overlay to be combined with the base, got %+v", merged)
+	}
+
+	os.Setenv(envVar, base+string(filepath.ListSeparator)+o.Overlay)
+	if _, err := access.GetStartingConfig(); err == nil || !strings.Contains(err.Error(), "comes before the overlay") {
+		t.Errorf("expected an overlay after the managed base to be refused, got %v", err)
+	}
+}
+
+func TestOverlayDeleteManaged(t *testing.T) {
+	dir, err := ioutil.TempDir("", "overlay")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	base := filepath.Join(dir, "team-a.yaml")
+	if err := clientcmd.WriteToFile(newRedFederalCowHammerConfig(), base); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	overlayFile := filepath.Join(dir, "overlay.yaml")
+	overlay := clientcmdapi.NewConfig()
+	overlay.Contexts["mine"] = &clientcmdapi.Context{Cluster: "cow-cluster", AuthInfo: "red-user"}
+	if err := clientcmd.WriteToFile(*overlay, overlayFile); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	settingsFile := filepath.Join(dir, "config.yaml")
+	if err := saveSettings(settingsFile, &Settings{ManagedKubeconfigs: []string{base}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	envVar := "KUBECFG_TEST_OVERLAY"
+	os.Setenv(envVar, overlayFile+string(filepath.ListSeparator)+base)
+	defer os.Unsetenv(envVar)
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.EnvVar = envVar
+	access := &overlayConfigAccess{PathOptions: pathOptions, SettingsFile: settingsFile}
+
+	out := &bytes.Buffer{}
+	cmd := NewCmdConfigDeleteContext(out, out, access)
+	cmd.ParseFlags([]string{"federal-context"})
+	if err := RunDeleteContext(out, out, access, cmd); err == nil || !strings.Contains(err.Error(), "managed kubeconfig "+base) {
+		t.Errorf("expected deleting a context of the base to be refused, got %v, %q", err, out.String())
+	}
+	cmd = NewCmdConfigDeleteCluster(out, access)
+	cmd.ParseFlags([]string{"cow-cluster"})
+	if err := RunDeleteCluster(out, access, cmd); err == nil || !strings.Contains(err.Error(), "managed kubeconfig "+base) {
+		t.Errorf("expected deleting a cluster of the base to be refused, got %v, %q", err, out.String())
+	}
+
+	cmd = NewCmdConfigDeleteContext(out, out, access)
+	cmd.ParseFlags([]string{"mine"})
+	if err := RunDeleteContext(out, out, access, cmd); err != nil {
+		t.Fatalf("expected a context of the overlay to be deleted, got %v", err)
+	}
+	if config, err := clientcmd.LoadFromFile(overlayFile); err != nil || config.Contexts["mine"] != nil {
+		t.Errorf("expected mine to be deleted from the overlay, got %v, %v", config, err)
+	}
+	if config, err := clientcmd.LoadFromFile(base); err != nil || config.Contexts["federal-context"] == nil {
+		t.Errorf("expected the base to be left alone, got %v, %v", config, err)
+	}
+}
@@ -0,0 +1,282 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/printers"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// contextOwner is who is responsible for a context and how to reach them, kept in the owner
+// annotations of the context.
+type contextOwner struct {
+	Context string `json:"context"`
+	Team    string `json:"team,omitempty"`
+	Slack   string `json:"slack,omitempty"`
+	Email   string `json:"email,omitempty"`
+	URL     string `json:"url,omitempty"`
+}
+
+// ownerAnnotations maps the owner annotations to the fields of o.
+func (o *contextOwner) ownerAnnotations() map[string]*string {
+	return map[string]*string{
+		ownerAnnotation:      &o.Team,
+		ownerSlackAnnotation: &o.Slack,
+		ownerEmailAnnotation: &o.Email,
+		ownerURLAnnotation:   &o.URL,
+	}
+}
+
+// SetOwnerOptions holds the command-line options for 'config set-owner' sub command
+type SetOwnerOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Owner        contextOwner
+	Clear        bool
+
+	// changed holds the annotations whose flag was given.
+	changed []string
+
+	genericclioptions.IOStreams
+}
+
+// OwnerOptions holds the command-line options for 'config owner' sub command
+type OwnerOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Context      string
+	Output       string
+
+	genericclioptions.IOStreams
+}
+
+var (
+	setOwnerLong = templates.LongDesc(`
+		Record who owns a context and how to reach them.
+
+		The team, Slack channel, email address and URL, such as a runbook or an on-call page, are
+		kept in the owner, owner-slack, owner-email and owner-url annotations of the context, and
+		"kubectl config inventory" lists the team. Only the fields given are changed, an empty value
+		removes one, and --clear removes them all.`)
+
+	setOwnerExample = templates.Examples(`
+		# Record that the platform team owns prod, reachable on #platform
+		kubectl config set-owner prod --team platform --slack '#platform'
+
+		# Add the runbook of the current-context
+		kubectl config set-owner . --url https://wiki.example.com/runbooks/prod`)
+
+	ownerLong = templates.LongDesc(`
+		Show who owns a context and how to reach them, as recorded with "kubectl config set-owner".
+
+		The current-context is shown unless a context is named.`)
+
+	ownerExample = templates.Examples(`
+		# Who do I ask about the current-context?
+		kubectl config owner
+
+		# The owner of prod, as JSON
+		kubectl config owner prod -o json`)
+)
+
+// NewCmdConfigSetOwner returns a Command instance for 'config set-owner' sub command
+func NewCmdConfigSetOwner(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &SetOwnerOptions{ConfigAccess: configAccess, IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "set-owner CONTEXT_NAME [--team=TEAM] [--slack=CHANNEL] [--email=EMAIL] [--url=URL] [--clear]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Record who owns a context and how to reach them"),
+		Long:                  setOwnerLong,
+		Example:               setOwnerExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	cmd.Flags().StringVar(&o.Owner.Team, "team", o.Owner.Team, "Team or person owning the context")
+	cmd.Flags().StringVar(&o.Owner.Slack, "slack", o.Owner.Slack, "Slack channel of the owner")
+	cmd.Flags().StringVar(&o.Owner.Email, "email", o.Owner.Email, "Email address of the owner")
+	cmd.Flags().StringVar(&o.Owner.URL, "url", o.Owner.URL, "URL of a runbook, on-call page or documentation")
+	cmd.Flags().BoolVar(&o.Clear, "clear", o.Clear, "Remove every owner field of the context")
+	return cmd
+}
+
+// Complete sets the context from the arguments and records which fields are changed
+func (o *SetOwnerOptions) Complete(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return helpErrorf(cmd, "Unexpected args: %v", args)
+	}
+	o.Owner.Context = args[0]
+	flags := map[string]string{"team": ownerAnnotation, "slack": ownerSlackAnnotation, "email": ownerEmailAnnotation, "url": ownerURLAnnotation}
+	o.changed = nil
+	for flag, annotation := range flags {
+		if cmd.Flags().Changed(flag) {
+			o.changed = append(o.changed, annotation)
+		}
+	}
+	return nil
+}
+
+// Validate makes sure there is something to change
+func (o *SetOwnerOptions) Validate() error {
+	if o.Clear && len(o.changed) > 0 {
+		return errors.New("--clear cannot be combined with owner fields")
+	}
+	if !o.Clear && len(o.changed) == 0 {
+		return errors.New("at least one of --team, --slack, --email, --url or --clear is required")
+	}
+	return nil
+}
+
+// Run changes the owner annotations of the context
+func (o *SetOwnerOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	name, err := resolveContextName(config, o.Owner.Context)
+	if err != nil {
+		return err
+	}
+	context, ok := config.Contexts[name]
+	if !ok {
+		return fmt.Errorf("no context exists with the name: %q", name)
+	}
+	metadata, err := readContextMetadata(context)
+	if err != nil {
+		return err
+	}
+
+	fields := o.Owner.ownerAnnotations()
+	changed := o.changed
+	if o.Clear {
+		changed = nil
+		for annotation := range fields {
+			changed = append(changed, annotation)
+		}
+	}
+	if metadata.Annotations == nil {
+		metadata.Annotations = map[string]string{}
+	}
+	for _, annotation := range changed {
+		if value := *fields[annotation]; len(value) > 0 && !o.Clear {
+			metadata.Annotations[annotation] = value
+		} else {
+			delete(metadata.Annotations, annotation)
+		}
+	}
+	if err := writeContextMetadata(context, metadata); err != nil {
+		return err
+	}
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+	if o.Clear {
+		fmt.Fprintf(o.Out, "Removed the owner of context %q.\n", name)
+	} else {
+		fmt.Fprintf(o.Out, "Owner of context %q updated.\n", name)
+	}
+	return nil
+}
+
+// NewCmdConfigOwner returns a Command instance for 'config owner' sub command
+func NewCmdConfigOwner(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &OwnerOptions{ConfigAccess: configAccess, Output: "text", IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "owner [CONTEXT_NAME] [-o text|json]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Show who owns a context and how to reach them"),
+		Long:                  ownerLong,
+		Example:               ownerExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) > 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.Context = currentContextShorthand
+			if len(args) == 1 {
+				o.Context = args[0]
+			}
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	cmd.Flags().StringVarP(&o.Output, "output", "o", o.Output, "Output format. One of: text|json")
+	return cmd
+}
+
+// Validate makes sure the output format is supported
+func (o *OwnerOptions) Validate() error {
+	if o.Output != "text" && o.Output != "json" {
+		return fmt.Errorf("unsupported output format %q, must be one of: text, json", o.Output)
+	}
+	return nil
+}
+
+// Run prints the owner of the context
+func (o *OwnerOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	name, err := resolveContextName(config, o.Context)
+	if err != nil {
+		return err
+	}
+	context, ok := config.Contexts[name]
+	if !ok {
+		return fmt.Errorf("no context exists with the name: %q", name)
+	}
+	metadata, err := readContextMetadata(context)
+	if err != nil {
+		return err
+	}
+	owner := contextOwner{Context: name}
+	for annotation, field := range owner.ownerAnnotations() {
+		*field = metadata.Annotations[annotation]
+	}
+
+	if o.Output == "json" {
+		data, err := json.Marshal(owner)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(o.Out, "%s\n", data)
+		return err
+	}
+	if owner == (contextOwner{Context: name}) {
+		fmt.Fprintf(o.Out, "No owner recorded for context %q, see \"kubectl config set-owner\".\n", name)
+		return nil
+	}
+	w := printers.GetNewTabWriter(o.Out)
+	fmt.Fprintf(w, "Context:\t%s\n", name)
+	fmt.Fprintf(w, "Team:\t%s\n", valueOrNone(owner.Team))
+	fmt.Fprintf(w, "Slack:\t%s\n", valueOrNone(owner.Slack))
+	fmt.Fprintf(w, "Email:\t%s\n", valueOrNone(owner.Email))
+	fmt.Fprintf(w, "URL:\t%s\n", valueOrNone(owner.URL))
+	return w.Flush()
+}
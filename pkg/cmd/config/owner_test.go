@@ -0,0 +1,102 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func TestOwner(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	config := newRedFederalCowHammerConfig()
+	if err := writeContextMetadata(config.Contexts["federal-context"], contextMetadata{Annotations: map[string]string{"region": "eu"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := clientcmd.WriteToFile(config, fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	setOwner := func(args ...string) string {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		cmd := NewCmdConfigSetOwner(streams, pathOptions)
+		if err := cmd.ParseFlags(args); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		cmd.Run(cmd, cmd.Flags().Args())
+		return out.String()
+	}
+	owner := func(output string) string {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		o := &OwnerOptions{ConfigAccess: pathOptions, Context: ".", Output: output, IOStreams: streams}
+		if err := o.Run(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return out.String()
+	}
+
+	if out := owner("text"); out != "No owner recorded for context \"federal-context\", see \"kubectl config set-owner\".\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+	if out := setOwner("federal-context", "--team", "platform", "--slack", "#platform", "--email", "platform@example.com"); out != "Owner of context \"federal-context\" updated.\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+	// Fields not given are kept.
+	setOwner(".", "--url", "https://wiki.example.com/prod", "--email", "")
+
+	expected := `Context:   federal-context
+Team:      platform
+Slack:     #platform
+Email:     <none>
+URL:       https://wiki.example.com/prod
+`
+	if out := owner("text"); out != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out)
+	}
+	if out := owner("json"); out != `{"context":"federal-context","team":"platform","slack":"#platform","url":"https://wiki.example.com/prod"}`+"\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+
+	if out := setOwner("federal-context", "--clear"); out != "Removed the owner of context \"federal-context\".\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+	loaded, err := clientcmd.LoadFromFile(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	metadata, err := readContextMetadata(loaded.Contexts["federal-context"])
+	if err != nil || len(metadata.Annotations) != 1 || metadata.Annotations["region"] != "eu" {
+		t.Errorf("expected only the owner annotations to be removed, got %v, %v", metadata.Annotations, err)
+	}
+
+	for _, invalid := range []*SetOwnerOptions{{}, {Clear: true, changed: []string{ownerAnnotation}}} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", invalid)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/pkcs12"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	clientauthv1beta1 "k8s.io/client-go/pkg/apis/clientauthentication/v1beta1"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// p12PasswordEnv is the environment variable "config p12-credential" reads the password of the
+// bundle from.
+const p12PasswordEnv = "KUBECTL_CONFIG_P12_PASSWORD"
+
+// readP12Bundle returns the client certificate, followed by the rest of its chain, and the private
+// key of a PKCS#12 bundle, PEM encoded.
+func readP12Bundle(filename, password string) (certificate, key []byte, err error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	blocks, err := pkcs12.ToPEM(data, password)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot read PKCS#12 bundle %s: %v", filename, err)
+	}
+
+	var keyBlock *pem.Block
+	certificates := []*pem.Block{}
+	for _, block := range blocks {
+		if block.Type == "CERTIFICATE" {
+			certificates = append(certificates, block)
+			continue
+		}
+		if keyBlock != nil {
+			return nil, nil, fmt.Errorf("PKCS#12 bundle %s holds more than one private key", filename)
+		}
+		keyBlock = block
+	}
+	if keyBlock == nil || len(certificates) == 0 {
+		return nil, nil, fmt.Errorf("PKCS#12 bundle %s does not hold a certificate and its private key", filename)
+	}
+
+	// ToPEM labels every key "PRIVATE KEY", whatever the encoding it gives them.
+	if _, err := x509.ParsePKCS1PrivateKey(keyBlock.Bytes); err == nil {
+		key = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: keyBlock.Bytes})
+	} else {
+		key = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBlock.Bytes})
+	}
+	// The certificate of the key shares its local key ID, and comes first.
+	buf := &bytes.Buffer{}
+	for _, leaf := range []bool{true, false} {
+		for _, block := range certificates {
+			if (block.Headers["localKeyId"] == keyBlock.Headers["localKeyId"]) == leaf {
+				pem.Encode(buf, &pem.Block{Type: block.Type, Bytes: block.Bytes})
+			}
+		}
+	}
+	return buf.Bytes(), key, nil
+}
+
+// p12Exec returns the exec plugin reading the certificate and key of a user from a PKCS#12 bundle
+// each time it is used. Without a password, it is taken from the environment kubectl runs in.
+func p12Exec(filename, password string) *clientcmdapi.ExecConfig {
+	exec := &clientcmdapi.ExecConfig{
+		APIVersion: clientauthv1beta1.SchemeGroupVersion.String(),
+		Command:    "kubectl",
+		Args:       []string{"config", "p12-credential", filename},
+	}
+	if len(password) > 0 {
+		exec.Env = []clientcmdapi.ExecEnvVar{{Name: p12PasswordEnv, Value: password}}
+	}
+	return exec
+}
+
+// isP12Exec reports whether an exec plugin was set by p12Exec.
+func isP12Exec(exec *clientcmdapi.ExecConfig) bool {
+	return exec != nil && exec.Command == "kubectl" && len(exec.Args) == 3 && exec.Args[0] == "config" && exec.Args[1] == "p12-credential"
+}
+
+// P12CredentialOptions holds the command-line options for 'config p12-credential' sub command
+type P12CredentialOptions struct {
+	Bundle   string
+	Password string
+
+	genericclioptions.IOStreams
+}
+
+// NewCmdConfigP12Credential returns a Command instance for 'config p12-credential' sub command
+func NewCmdConfigP12Credential(streams genericclioptions.IOStreams) *cobra.Command {
+	o := &P12CredentialOptions{IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "p12-credential BUNDLE",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Print the client certificate and key of a PKCS#12 bundle as an exec credential plugin"),
+		Long: templates.LongDesc(`
+			Print the client certificate and key of a PKCS#12 bundle as an exec credential plugin
+			does.
+
+			It is the exec plugin of the users set with "kubectl config set-credentials --p12
+			--p12-exec", which keeps the bundle the only copy of the key. The password of the bundle
+			is read from the ` + p12PasswordEnv + ` environment variable.`),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.Bundle = args[0]
+			o.Password = os.Getenv(p12PasswordEnv)
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	return noWriteCommand(cmd)
+}
+
+// Run prints the certificate and key of the bundle as an ExecCredential
+func (o *P12CredentialOptions) Run() error {
+	if len(o.Bundle) == 0 {
+		return errors.New("you must specify a PKCS#12 bundle")
+	}
+	certificate, key, err := readP12Bundle(o.Bundle, o.Password)
+	if err != nil {
+		return err
+	}
+	credential := &clientauthv1beta1.ExecCredential{
+		Status: &clientauthv1beta1.ExecCredentialStatus{
+			ClientCertificateData: string(certificate),
+			ClientKeyData:         string(key),
+		},
+	}
+	credential.APIVersion = clientauthv1beta1.SchemeGroupVersion.String()
+	credential.Kind = "ExecCredential"
+	return json.NewEncoder(o.Out).Encode(credential)
+}
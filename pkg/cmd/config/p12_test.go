@@ -0,0 +1,184 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	clientauthv1beta1 "k8s.io/client-go/pkg/apis/clientauthentication/v1beta1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// testP12Bundle holds the EC key and certificate of cluster-admin and the certificate of the
+// kubernetes-ca that signed it, with the password "changeit".
+const testP12Bundle = `
+MIIFAgIBAzCCBMgGCSqGSIb3DQEHAaCCBLkEggS1MIIEsTCCA6cGCSqGSIb3DQEHBqCCA5gwggOU
+AgEAMIIDjQYJKoZIhvcNAQcBMBwGCiqGSIb3DQEMAQYwDgQIzXhy55OdUqcCAggAgIIDYEhWxvl8
+7ilmZh2Z3mOuJVG8y8AwRGST+KWaEkClIrVhZs1wHthHVvCKi7N7aOhEQKMIi5bcByq+QLUIlsOT
+RuEcUgxFcHNHKWurhdwKFV/fwVasZbQ/+MlgIEGppz5WBPIZcJY0w4GVvGSJbA8J9Q9kZu1jp/j2
+qYCUkcyP/T2vJJ73MTIaT0fgOYfaGlOwi0ZKBytT630wD4o+Uq9RUBVXWslfOXRQU0/mi47Sr2bF
+F7qBVF6Nd0k8fX6FfHY6SGYfnYNI9bu+8vd8fp0UNDJ0DRb/bpYdFJhiQr5+wvxIB1iW4VBaKBM+
+jZduSvAK6oHw8CvLYOI1oicqVXueTVwu2GnkRcHPOSVlvd1IeJ++uVjEcG8j76QIS5zVHcXHomHd
+kbZChqKmyaLF3CAqcmaZyfK8PZHuxwEr/OTV8mHW++U9IEtzElk17mzzRg5rw0J+XzeRXAMB5mkk
+v5XS25tMuSyCIn9/3NQ30pvzpz8qZwqy1gIzcoOKF++u5LncXIY9hTq1cxlwr5+si7nQFs5pQOmH
+0QEF2nZ19U2riC1eYWvyp+rNm8YfySS07Os04iKWD/6AaXXbD0NXhawBQuFLYdZSLAIrCDD7rAlt
+zX4rENHLbq+JK6kvxQ7BtLEDGcXB8vx/+CTB1xeX5fVYNGZtE4HeQhZlyIHQ2XhCC6V+YjeHQ6qc
+dI5+u4rZRn45HykX7PpayprPDnBJnyXiM0O4n7hDq6XSVBS9rv4JoVwnuMmUuTR7tChv3MxYJCPb
++oBtniFGUu9Zpd8K1eBfJvfVqQQVY1uZscdZBNujYAs1MVc30/eN/e1ooCSBo7jTYr2B7gVePsB3
+bjbYqX89nWrijp4BjFzwE2qyVpwZyq7rueTk/XHHbVx0WTzMxRPsPtgfUKRYS+QWXWbZqb8mu4lB
+7UcJrVwnjZgmCrs2DlU/PJncYnTNuKvK4WD+r7fkhe4BONm1zIUGA/htcO6yj9rC16e99YtTIlhN
+rtjeHhsfxstkgNdPr3iK0Kl53qhz7l4sYoA2hE9ljxxYkMmpqj/TyhNCuhVY24rqBgRQ7pxoUS5R
+uTeTGwzNIxkwCjIAT3gnyJ8AQWfrRGWolEJRTIOU4fVkDW+vMZTnLk1hAPxNBINYUvIM0viulZ3A
+uXMYQTCCAQIGCSqGSIb3DQEHAaCB9ASB8TCB7jCB6wYLKoZIhvcNAQwKAQKggbQwgbEwHAYKKoZI
+hvcNAQwBAzAOBAiCBpbaJ6DjhgICCAAEgZA0oiWYwXzzKnJvsMG8cOoaLZSxGNijFj12H36FCl5t
+6wKeF/+ymIiWBitc4ZsoeOj7FWuC6PaKMWkX3CaE06ouTO2n4a2wOFboPVhAl0ezppJLq/0jzNJf
+KKsEjTGvI1skfYt1JyfEj0nPO94YOzldPy9Pe7ARYhSDiTtrmXiqlJ506tIMKGTd1kApWPizExgx
+JTAjBgkqhkiG9w0BCRUxFgQUPXp6My3F6D6OqdnvYa1M7mcSAXEwMTAhMAkGBSsOAwIaBQAEFOey
+Ld8jaa2EUHHDCNgE6fxqJNmMBAhJ+KmsJTaKpgICCAA=`
+
+func writeTestP12Bundle(t *testing.T, dir string) string {
+	data, err := base64.StdEncoding.DecodeString(testP12Bundle)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	filename := filepath.Join(dir, "admin.p12")
+	if err := ioutil.WriteFile(filename, data, 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return filename
+}
+
+// checkP12Credentials checks that certificate and key are the ones of testP12Bundle.
+func checkP12Credentials(t *testing.T, certificate, key []byte) {
+	if _, err := tls.X509KeyPair(certificate, key); err != nil {
+		t.Errorf("expected a usable certificate and key, got %v", err)
+	}
+	if block, _ := pem.Decode(key); block == nil || block.Type != "EC PRIVATE KEY" {
+		t.Errorf("expected an EC private key, got %q", key)
+	}
+	names := []string{}
+	for rest := certificate; ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		parsed, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		names = append(names, parsed.Subject.CommonName)
+	}
+	if expected := []string{"cluster-admin", "kubernetes-ca"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected the certificates %v, got %v", expected, names)
+	}
+}
+
+func TestSetCredentialsP12(t *testing.T) {
+	dir, err := ioutil.TempDir("", "p12")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	bundle := writeTestP12Bundle(t, dir)
+	kubeconfig := filepath.Join(dir, "config")
+	if err := clientcmd.WriteToFile(clientcmdapi.Config{}, kubeconfig); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = kubeconfig
+	pathOptions.EnvVar = ""
+
+	buf := &bytes.Buffer{}
+	cmd := NewCmdConfigSetAuthInfo(buf, pathOptions)
+	cmd.SetArgs([]string{"cluster-admin", "--p12=" + bundle, "--p12-exec"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	config, err := clientcmd.LoadFromFile(kubeconfig)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if exec := config.AuthInfos["cluster-admin"].Exec; !isP12Exec(exec) || exec.Args[2] != bundle || len(exec.Env) != 0 {
+		t.Errorf("expected the bundle to be read by the exec plugin, got %#v", exec)
+	}
+
+	options := &CreateAuthInfoOptions{ConfigAccess: pathOptions, Name: "cluster-admin"}
+	options.P12.Set(bundle)
+	options.P12Password.Set("wrong")
+	if err := options.Run(); err == nil {
+		t.Errorf("expected a wrong password to fail")
+	}
+	options.P12Password.Set("changeit")
+	if err := options.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	config, err = clientcmd.LoadFromFile(kubeconfig)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	authInfo := config.AuthInfos["cluster-admin"]
+	if authInfo.Exec != nil {
+		t.Errorf("expected the exec plugin reading the bundle to be removed, got %#v", authInfo.Exec)
+	}
+	checkP12Credentials(t, authInfo.ClientCertificateData, authInfo.ClientKeyData)
+
+	options = &CreateAuthInfoOptions{ConfigAccess: pathOptions, Name: "cluster-admin"}
+	options.P12.Set(bundle)
+	options.ClientKey.Set("admin.key")
+	if err := options.Run(); err == nil {
+		t.Errorf("expected --p12 and --client-key to be exclusive")
+	}
+	options = &CreateAuthInfoOptions{ConfigAccess: pathOptions, Name: "cluster-admin"}
+	options.P12Password.Set("changeit")
+	if err := options.Run(); err == nil {
+		t.Errorf("expected --p12-password without --p12 to fail")
+	}
+}
+
+func TestP12Credential(t *testing.T) {
+	dir, err := ioutil.TempDir("", "p12")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := &P12CredentialOptions{Bundle: writeTestP12Bundle(t, dir), Password: "changeit", IOStreams: streams}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	credential := &clientauthv1beta1.ExecCredential{}
+	if err := json.Unmarshal(out.Bytes(), credential); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if credential.Kind != "ExecCredential" || credential.APIVersion != clientauthv1beta1.SchemeGroupVersion.String() {
+		t.Errorf("unexpected credential type %s %s", credential.APIVersion, credential.Kind)
+	}
+	checkP12Credentials(t, []byte(credential.Status.ClientCertificateData), []byte(credential.Status.ClientKeyData))
+}
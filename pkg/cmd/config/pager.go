@@ -0,0 +1,264 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/kubectl/pkg/util/term"
+)
+
+const (
+	// FlagNoPager is the persistent flag that prints the output of every subcommand directly.
+	FlagNoPager = "no-pager"
+
+	// NoPagerEnvVar has the same effect as --no-pager when set to any value.
+	NoPagerEnvVar = "NOPAGER"
+
+	// PagerEnvVar names the command output is piped to. Setting it to an empty string or to cat
+	// disables paging.
+	PagerEnvVar = "PAGER"
+
+	// pagedAnnotation marks the subcommands whose output goes through the pager.
+	pagedAnnotation = "kubecfg.io/paged"
+
+	// defaultPagerHeight is used when the height of the terminal is unknown.
+	defaultPagerHeight = 24
+)
+
+// addPagerFlag registers --no-pager on the root config command so every subcommand inherits it.
+func addPagerFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().Bool(FlagNoPager, false, "Print the output directly instead of through a pager. Can also be set with $"+NoPagerEnvVar)
+}
+
+// pagedCommand marks cmd as printing output that may be longer than a screen. On a terminal, its
+// output goes through $PAGER, less or more, or a built-in pager when none is installed.
+func pagedCommand(cmd *cobra.Command) *cobra.Command {
+	if cmd.Annotations == nil {
+		cmd.Annotations = map[string]string{}
+	}
+	cmd.Annotations[pagedAnnotation] = "true"
+	return cmd
+}
+
+// pagerDisabled reports whether paging was turned off by flag or environment.
+func pagerDisabled(cmd *cobra.Command, lookupEnv func(string) (string, bool)) bool {
+	if cmd != nil {
+		if flag := cmd.Flags().Lookup(FlagNoPager); flag != nil && flag.Changed {
+			disabled, _ := toBool(flag.Value.String())
+			return disabled
+		}
+	}
+	_, disabled := lookupEnv(NoPagerEnvVar)
+	return disabled
+}
+
+// selectPager returns the command line of the pager to run, an empty one for the built-in pager,
+// and whether output should be paged at all. Like git, it prefers $PAGER, then less and more.
+func selectPager(lookupEnv func(string) (string, bool), lookPath func(string) (string, error)) ([]string, bool) {
+	if pager, ok := lookupEnv(PagerEnvVar); ok {
+		pager = strings.TrimSpace(pager)
+		if len(pager) == 0 || pager == "cat" {
+			return nil, false
+		}
+		// $PAGER may hold arguments, as in "less -S", so it is run by the shell.
+		if runtime.GOOS == "windows" {
+			return []string{"cmd", "/C", pager}, true
+		}
+		return []string{"sh", "-c", pager}, true
+	}
+	for _, pager := range []string{"less", "more"} {
+		if path, err := lookPath(pager); err == nil {
+			return []string{path}, true
+		}
+	}
+	return nil, true
+}
+
+// pager stands in for the standard output of the config subcommands. It prints directly until a
+// paged command starts, and then sends everything to a pager until the command finishes.
+type pager struct {
+	out    io.Writer
+	in     io.Reader
+	errOut io.Writer
+
+	// w receives what is written: out, the input of the pager process or the buffer of the
+	// built-in pager.
+	w       io.Writer
+	process *exec.Cmd
+	stdin   io.WriteCloser
+	buffer  *bytes.Buffer
+
+	lookupEnv func(string) (string, bool)
+	lookPath  func(string) (string, error)
+}
+
+func newPager(streams genericclioptions.IOStreams) *pager {
+	return &pager{
+		out:       streams.Out,
+		in:        streams.In,
+		errOut:    streams.ErrOut,
+		w:         streams.Out,
+		lookupEnv: os.LookupEnv,
+		lookPath:  exec.LookPath,
+	}
+}
+
+func (p *pager) Write(data []byte) (int, error) {
+	n, err := p.w.Write(data)
+	if err != nil && p.stdin != nil {
+		// The pager exited before reading everything, because the user quit it. The rest of the
+		// output is of no interest to anyone.
+		p.w = ioutil.Discard
+		return len(data), nil
+	}
+	return n, err
+}
+
+// active reports whether output is currently paged.
+func (p *pager) active() bool {
+	return p.process != nil || p.buffer != nil
+}
+
+// start pages the output of cmd if it is marked with pagedCommand and printed on a terminal.
+func (p *pager) start(cmd *cobra.Command) error {
+	if cmd.Annotations[pagedAnnotation] != "true" || pagerDisabled(cmd, p.lookupEnv) || !term.IsTerminal(p.out) {
+		return nil
+	}
+	argv, ok := selectPager(p.lookupEnv, p.lookPath)
+	if !ok {
+		return nil
+	}
+	if len(argv) > 0 {
+		process := exec.Command(argv[0], argv[1:]...)
+		process.Stdout = p.out
+		process.Stderr = p.errOut
+		// Like git, let less quit when the output fits on a screen, keep colors and leave the
+		// output on the screen, unless the user configured it otherwise.
+		if _, ok := p.lookupEnv("LESS"); !ok {
+			process.Env = append(os.Environ(), "LESS=FRX")
+		}
+		stdin, err := process.StdinPipe()
+		if err != nil {
+			return err
+		}
+		if err := process.Start(); err == nil {
+			p.process, p.stdin, p.w = process, stdin, stdin
+			return nil
+		}
+		// A pager that cannot be started is replaced by the built-in one.
+		stdin.Close()
+	}
+	p.buffer = &bytes.Buffer{}
+	p.w = p.buffer
+	return nil
+}
+
+// finish waits for the user to be done with the pager, and prints the output directly again.
+func (p *pager) finish() {
+	switch {
+	case p.process != nil:
+		p.stdin.Close()
+		// The exit status of the pager is not the one of the command.
+		p.process.Wait()
+		p.process, p.stdin = nil, nil
+	case p.buffer != nil:
+		data := p.buffer.Bytes()
+		p.buffer = nil
+		keys, closeKeys := p.openKeys()
+		if keys == nil {
+			p.out.Write(data)
+			break
+		}
+		height := defaultPagerHeight
+		if size := (term.TTY{Out: p.out}).GetSize(); size != nil && size.Height > 1 {
+			height = int(size.Height)
+		}
+		pageText(p.out, keys, data, height)
+		closeKeys()
+	}
+	p.w = p.out
+}
+
+// openKeys returns where the keys of the user are read from by the built-in pager, or nil when
+// there is no terminal to read them from.
+func (p *pager) openKeys() (io.Reader, func()) {
+	if term.IsTerminal(p.in) {
+		return p.in, func() {}
+	}
+	name := "/dev/tty"
+	if runtime.GOOS == "windows" {
+		name = "CONIN$"
+	}
+	tty, err := os.Open(name)
+	if err != nil {
+		return nil, nil
+	}
+	return tty, func() { tty.Close() }
+}
+
+// pageText writes text to out a screen of height lines at a time. Between screens it prompts for
+// a line from keys: an empty line shows the next screen and q quits. The terminal stays in line
+// mode, so the user's Enter moves past the prompt before the next screen.
+func pageText(out io.Writer, keys io.Reader, text []byte, height int) error {
+	lines := bytes.SplitAfter(text, []byte("\n"))
+	if len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) < height {
+		_, err := out.Write(text)
+		return err
+	}
+
+	reader := bufio.NewReader(keys)
+	// The last line of the screen is taken by the prompt.
+	screen := height - 1
+	for start := 0; start < len(lines); {
+		end := start + screen
+		if end > len(lines) {
+			end = len(lines)
+		}
+		for _, line := range lines[start:end] {
+			if _, err := out.Write(line); err != nil {
+				return err
+			}
+		}
+		if start = end; start == len(lines) {
+			break
+		}
+		fmt.Fprintf(out, "--More-- (%d%%, Enter for more, q to quit) ", start*100/len(lines))
+		answer, err := reader.ReadString('\n')
+		if strings.EqualFold(strings.TrimSpace(answer), "q") || err != nil {
+			if err != nil {
+				fmt.Fprintln(out)
+			}
+			return nil
+		}
+	}
+	return nil
+}
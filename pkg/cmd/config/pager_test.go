@@ -0,0 +1,199 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func fakeLookupEnv(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+}
+
+func fakeLookPath(installed ...string) func(string) (string, error) {
+	return func(name string) (string, error) {
+		if containsString(installed, name) {
+			return "/usr/bin/" + name, nil
+		}
+		return "", errors.New("not found")
+	}
+}
+
+func TestPagerDisabled(t *testing.T) {
+	tests := []struct {
+		name     string
+		flags    []string
+		env      map[string]string
+		expected bool
+	}{
+		{name: "enabled"},
+		{name: "flag", flags: []string{"--no-pager"}, expected: true},
+		{name: "env", env: map[string]string{NoPagerEnvVar: ""}, expected: true},
+		{name: "flag overrides env", flags: []string{"--no-pager=false"}, env: map[string]string{NoPagerEnvVar: "1"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "probe"}
+			addPagerFlag(cmd)
+			if err := cmd.ParseFlags(test.flags); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if disabled := pagerDisabled(cmd, fakeLookupEnv(test.env)); disabled != test.expected {
+				t.Errorf("expected disabled to be %v, got %v", test.expected, disabled)
+			}
+		})
+	}
+}
+
+func TestSelectPager(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("$PAGER is run by cmd on windows")
+	}
+	tests := []struct {
+		name      string
+		env       map[string]string
+		installed []string
+		expected  []string
+		paged     bool
+	}{
+		{name: "PAGER", env: map[string]string{PagerEnvVar: "less -S"}, installed: []string{"less"}, expected: []string{"sh", "-c", "less -S"}, paged: true},
+		{name: "empty PAGER", env: map[string]string{PagerEnvVar: " "}, installed: []string{"less"}},
+		{name: "PAGER is cat", env: map[string]string{PagerEnvVar: "cat"}, installed: []string{"less"}},
+		{name: "less", installed: []string{"more", "less"}, expected: []string{"/usr/bin/less"}, paged: true},
+		{name: "more", installed: []string{"more"}, expected: []string{"/usr/bin/more"}, paged: true},
+		{name: "built-in", paged: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			argv, paged := selectPager(fakeLookupEnv(test.env), fakeLookPath(test.installed...))
+			if paged != test.paged {
+				t.Errorf("expected paged to be %v, got %v", test.paged, paged)
+			}
+			if !reflect.DeepEqual(argv, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, argv)
+			}
+		})
+	}
+}
+
+func TestPagerOutsideTerminal(t *testing.T) {
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	p := newPager(streams)
+	p.lookupEnv = fakeLookupEnv(nil)
+	p.lookPath = fakeLookPath()
+
+	cmd := pagedCommand(&cobra.Command{Use: "probe"})
+	addPagerFlag(cmd)
+	if err := p.start(cmd); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.active() {
+		t.Errorf("expected output that is not a terminal not to be paged")
+	}
+	fmt.Fprintln(p, "hello")
+	p.finish()
+	if out.String() != "hello\n" {
+		t.Errorf("expected the output to be printed directly, got %q", out.String())
+	}
+}
+
+func TestPagerQuit(t *testing.T) {
+	out := &bytes.Buffer{}
+	p := &pager{out: out, w: &failingWriter{}, stdin: nopWriteCloser{}}
+	if n, err := fmt.Fprint(p, "lost"); err != nil || n != 4 {
+		t.Errorf("expected the output to be dropped once the pager quit, got %d, %v", n, err)
+	}
+	if n, err := fmt.Fprint(p, "lost too"); err != nil || n != 8 {
+		t.Errorf("expected the output to be dropped once the pager quit, got %d, %v", n, err)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("broken pipe") }
+
+type nopWriteCloser struct{}
+
+func (nopWriteCloser) Write(data []byte) (int, error) { return len(data), nil }
+func (nopWriteCloser) Close() error                   { return nil }
+
+func numberedLines(n int) string {
+	lines := []string{}
+	for i := 1; i <= n; i++ {
+		lines = append(lines, fmt.Sprintf("line %d\n", i))
+	}
+	return strings.Join(lines, "")
+}
+
+func TestPageText(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		keys     string
+		expected string
+	}{
+		{
+			name:     "fits on a screen",
+			text:     numberedLines(4),
+			expected: numberedLines(4),
+		},
+		{
+			name:     "all screens",
+			text:     numberedLines(7),
+			keys:     "\n\n",
+			expected: "line 1\nline 2\nline 3\nline 4\n--More-- (57%, Enter for more, q to quit) line 5\nline 6\nline 7\n",
+		},
+		{
+			name:     "quit",
+			text:     numberedLines(7),
+			keys:     "q\n",
+			expected: "line 1\nline 2\nline 3\nline 4\n--More-- (57%, Enter for more, q to quit) ",
+		},
+		{
+			name:     "no more keys",
+			text:     numberedLines(7),
+			expected: "line 1\nline 2\nline 3\nline 4\n--More-- (57%, Enter for more, q to quit) \n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			if err := pageText(out, strings.NewReader(test.keys), []byte(test.text), 5); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.String() != test.expected {
+				t.Errorf("expected\n%q\ngot\n%q", test.expected, out.String())
+			}
+		})
+	}
+}
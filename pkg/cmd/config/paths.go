@@ -0,0 +1,76 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"k8s.io/client-go/util/homedir"
+)
+
+// pluginDirName names the directories holding settings and state the config subcommands keep outside
+// of kubeconfig files.
+const pluginDirName = "kubecfg"
+
+// stateDir returns the directory for local state such as import checkpoints and caches, following
+// the XDG base directory specification.
+func stateDir() string {
+	if dir := os.Getenv("XDG_STATE_HOME"); len(dir) > 0 {
+		return filepath.Join(dir, pluginDirName)
+	}
+	return filepath.Join(homedir.HomeDir(), ".local", "state", pluginDirName)
+}
+
+// configDir returns the directory holding the user's settings for the config subcommands, following
+// the XDG base directory specification.
+func configDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); len(dir) > 0 {
+		return filepath.Join(dir, pluginDirName)
+	}
+	return filepath.Join(homedir.HomeDir(), ".config", pluginDirName)
+}
+
+// kubectxDir returns the directory kubectx and kubens keep their previous context and namespaces
+// in: $XDG_CACHE_HOME, or ~/.kube when it is not set.
+func kubectxDir() string {
+	if dir := os.Getenv("XDG_CACHE_HOME"); len(dir) > 0 {
+		return dir
+	}
+	return filepath.Join(homedir.HomeDir(), ".kube")
+}
+
+// windowsEnvVarReference matches environment variable references in the %USERPROFILE% syntax.
+var windowsEnvVarReference = regexp.MustCompile(`%([A-Za-z_][A-Za-z0-9_()]*)%`)
+
+// expandPath expands a leading ~ and %VAR% references to environment variables, which shells leave
+// alone in quoted arguments or, for %USERPROFILE% and the like, outside of cmd.exe. References to
+// variables that are not set are kept as they are.
+func expandPath(p string) string {
+	p = windowsEnvVarReference.ReplaceAllStringFunc(p, func(reference string) string {
+		if value, ok := os.LookupEnv(reference[1 : len(reference)-1]); ok {
+			return value
+		}
+		return reference
+	})
+	if p == "~" || strings.HasPrefix(p, "~/") || strings.HasPrefix(p, "~"+string(filepath.Separator)) {
+		return filepath.Join(homedir.HomeDir(), p[1:])
+	}
+	return filepath.FromSlash(p)
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/client-go/util/homedir"
+)
+
+func TestExpandPath(t *testing.T) {
+	defer os.Unsetenv("KUBECFG_TEST_PROFILE")
+	os.Setenv("KUBECFG_TEST_PROFILE", filepath.FromSlash("/users/jane"))
+
+	tests := map[string]string{
+		"~":                                   homedir.HomeDir(),
+		"~/.kube/config":                      filepath.Join(homedir.HomeDir(), ".kube", "config"),
+		"%KUBECFG_TEST_PROFILE%/.kube/config": filepath.FromSlash("/users/jane/.kube/config"),
+		"%KUBECFG_TEST_UNSET%/.kube/config":   filepath.FromSlash("%KUBECFG_TEST_UNSET%/.kube/config"),
+		"/etc/kubernetes/admin.conf":          filepath.FromSlash("/etc/kubernetes/admin.conf"),
+		"~other/config":                       filepath.FromSlash("~other/config"),
+	}
+	for path, expected := range tests {
+		if expanded := expandPath(path); expanded != expected {
+			t.Errorf("expandPath(%q): expected %q, got %q", path, expected, expanded)
+		}
+	}
+}
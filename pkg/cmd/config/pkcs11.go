@@ -0,0 +1,282 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	certutil "k8s.io/client-go/util/cert"
+)
+
+// pkcs11PINEnv is the environment variable the PIN of a PKCS#11 token is read from. Without it,
+// pkcs11-tool asks for the PIN on the terminal.
+const pkcs11PINEnv = "KUBECTL_CONFIG_PKCS11_PIN"
+
+// pkcs11Tool is the OpenSC command the certificate and signatures of a PKCS#11 token are obtained
+// with, so that the private key never leaves the token.
+const pkcs11Tool = "pkcs11-tool"
+
+// pkcs11Key is stored in the pkcs11Extension of a user whose client certificate and key are held by
+// a PKCS#11 token, such as a PIV smart card or a YubiKey.
+type pkcs11Key struct {
+	// Module is the PKCS#11 library of the token, such as /usr/lib/opensc-pkcs11.so.
+	Module string `json:"module"`
+	// Slot is the slot of the token, the first one with a token by default.
+	Slot string `json:"slot,omitempty"`
+	// ID is the hexadecimal ID of the key and certificate objects, 01 for the PIV authentication
+	// slot 9a.
+	ID string `json:"id"`
+	// Certificate is a file holding the certificate of the key, read from the token by default.
+	Certificate string `json:"certificate,omitempty"`
+}
+
+// parsePKCS11Key parses the 'module=...;slot=...;id=...;cert=...' form of --pkcs11.
+func parsePKCS11Key(value string) (*pkcs11Key, error) {
+	key := &pkcs11Key{}
+	for _, field := range strings.Split(value, ";") {
+		if len(strings.TrimSpace(field)) == 0 {
+			continue
+		}
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid PKCS#11 field %q, expected KEY=VALUE", field)
+		}
+		name, fieldValue := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		switch name {
+		case "module":
+			key.Module = fieldValue
+		case "slot":
+			key.Slot = fieldValue
+		case "id":
+			if _, err := hex.DecodeString(fieldValue); err != nil {
+				return nil, fmt.Errorf("invalid PKCS#11 id %q, expected hexadecimal", fieldValue)
+			}
+			key.ID = fieldValue
+		case "cert":
+			key.Certificate = fieldValue
+		default:
+			return nil, fmt.Errorf("unknown PKCS#11 field %q, expected module, slot, id or cert", name)
+		}
+	}
+	if len(key.Module) == 0 || len(key.ID) == 0 {
+		return nil, errors.New("the PKCS#11 module and id are required")
+	}
+	if len(key.Certificate) > 0 {
+		key.Certificate, _ = filepath.Abs(key.Certificate)
+	}
+	return key, nil
+}
+
+// readPKCS11Key returns the PKCS#11 token of a user, if it has one.
+func readPKCS11Key(authInfo *clientcmdapi.AuthInfo) (*pkcs11Key, bool, error) {
+	if authInfo == nil {
+		return nil, false, nil
+	}
+	key := &pkcs11Key{}
+	found, err := readExtension(authInfo.Extensions, pkcs11Extension, key)
+	return key, found, err
+}
+
+// pkcs11Signer signs with the private key of a PKCS#11 token by running pkcs11-tool.
+type pkcs11Signer struct {
+	key         *pkcs11Key
+	pin         string
+	certificate *x509.Certificate
+	run         commandRunner
+}
+
+// rsaDigestInfoPrefixes are the DER prefixes of the digests signed with the RSA-PKCS mechanism,
+// which pads its input as is.
+var rsaDigestInfoPrefixes = map[crypto.Hash][]byte{
+	crypto.SHA1:   {0x30, 0x21, 0x30, 0x09, 0x06, 0x05, 0x2b, 0x0e, 0x03, 0x02, 0x1a, 0x05, 0x00, 0x04, 0x14},
+	crypto.SHA256: {0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20},
+	crypto.SHA384: {0x30, 0x41, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x02, 0x05, 0x00, 0x04, 0x30},
+	crypto.SHA512: {0x30, 0x51, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x03, 0x05, 0x00, 0x04, 0x40},
+}
+
+// pkcs11HashNames are the names pkcs11-tool gives the hashes of RSA-PSS signatures.
+var pkcs11HashNames = map[crypto.Hash]string{
+	crypto.SHA1:   "SHA-1",
+	crypto.SHA256: "SHA256",
+	crypto.SHA384: "SHA384",
+	crypto.SHA512: "SHA512",
+}
+
+// newPKCS11Signer returns the signer of a PKCS#11 token, with the certificate of its key.
+func newPKCS11Signer(key *pkcs11Key, pin string, run commandRunner) (*pkcs11Signer, error) {
+	s := &pkcs11Signer{key: key, pin: pin, run: run}
+	if len(key.Certificate) > 0 {
+		data, err := ioutil.ReadFile(key.Certificate)
+		if err != nil {
+			return nil, err
+		}
+		certificates, err := certutil.ParseCertsPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", key.Certificate, err)
+		}
+		s.certificate = certificates[0]
+		return s, nil
+	}
+	der, err := run(nil, pkcs11Tool, append(s.tokenArgs(false), "--read-object", "--type", "cert")...)
+	if err != nil {
+		return nil, fmt.Errorf("reading the certificate %s of the PKCS#11 token: %v", key.ID, err)
+	}
+	if s.certificate, err = x509.ParseCertificate(der); err != nil {
+		return nil, fmt.Errorf("invalid certificate %s on the PKCS#11 token: %v", key.ID, err)
+	}
+	return s, nil
+}
+
+// tokenArgs returns the pkcs11-tool arguments selecting the objects of the key, logged in when
+// login is true.
+func (s *pkcs11Signer) tokenArgs(login bool) []string {
+	args := []string{"--module", s.key.Module}
+	if len(s.key.Slot) > 0 {
+		args = append(args, "--slot", s.key.Slot)
+	}
+	args = append(args, "--id", s.key.ID)
+	if login {
+		args = append(args, "--login")
+		if len(s.pin) > 0 {
+			args = append(args, "--pin", s.pin)
+		}
+	}
+	return args
+}
+
+// Public returns the public key of the certificate of the token
+func (s *pkcs11Signer) Public() crypto.PublicKey {
+	return s.certificate.PublicKey
+}
+
+// Sign signs digest with the private key of the token
+func (s *pkcs11Signer) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	input := digest
+	args := s.tokenArgs(true)
+	switch s.certificate.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		args = append(args, "--mechanism", "ECDSA", "--signature-format", "openssl")
+	case *rsa.PublicKey:
+		if _, ok := opts.(*rsa.PSSOptions); ok {
+			hash, ok := pkcs11HashNames[opts.HashFunc()]
+			if !ok {
+				return nil, fmt.Errorf("unsupported hash %v", opts.HashFunc())
+			}
+			args = append(args, "--mechanism", "RSA-PKCS-PSS", "--hash-algorithm", hash, "--mgf", "MGF1-"+strings.Replace(hash, "-", "", 1))
+			break
+		}
+		prefix, ok := rsaDigestInfoPrefixes[opts.HashFunc()]
+		if !ok {
+			return nil, fmt.Errorf("unsupported hash %v", opts.HashFunc())
+		}
+		input = append(append([]byte{}, prefix...), digest...)
+		args = append(args, "--mechanism", "RSA-PKCS")
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", s.certificate.PublicKey)
+	}
+
+	dir, err := ioutil.TempDir("", "pkcs11")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	inputFile, outputFile := filepath.Join(dir, "input"), filepath.Join(dir, "signature")
+	if err := ioutil.WriteFile(inputFile, input, 0600); err != nil {
+		return nil, err
+	}
+	args = append(args, "--sign", "--input-file", inputFile, "--output-file", outputFile)
+	if _, err := s.run(nil, pkcs11Tool, args...); err != nil {
+		return nil, fmt.Errorf("signing with the PKCS#11 token: %v", err)
+	}
+	return ioutil.ReadFile(outputFile)
+}
+
+// startPKCS11Proxy serves the API server of a cluster on a loopback address, authenticating to it
+// with the certificate and key of a PKCS#11 token. Only the requests bearing token are proxied. It
+// returns the address of the proxy and a function stopping it.
+func startPKCS11Proxy(cluster *clientcmdapi.Cluster, signer *pkcs11Signer, token string) (string, func(), error) {
+	target, err := url.Parse(cluster.Server)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid server %q: %v", cluster.Server, err)
+	}
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: cluster.InsecureSkipTLSVerify,
+		Certificates:       []tls.Certificate{{Certificate: [][]byte{signer.certificate.Raw}, PrivateKey: signer, Leaf: signer.certificate}},
+	}
+	ca := cluster.CertificateAuthorityData
+	if len(ca) == 0 && len(cluster.CertificateAuthority) > 0 {
+		if ca, err = ioutil.ReadFile(cluster.CertificateAuthority); err != nil {
+			return "", nil, err
+		}
+	}
+	if len(ca) > 0 {
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return "", nil, errors.New("invalid certificate authority of the cluster")
+		}
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	proxy.Transport = &http.Transport{Proxy: http.ProxyFromEnvironment, DialContext: clusterDialer(cluster, dialer), TLSClientConfig: tlsConfig}
+	// Watches stream their events as they come.
+	proxy.FlushInterval = -1
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		r.Header.Del("Authorization")
+		proxy.ServeHTTP(w, r)
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", nil, err
+	}
+	server := &http.Server{Handler: handler}
+	go server.Serve(listener)
+	return "http://" + listener.Addr().String(), func() { server.Close() }, nil
+}
+
+// randomToken returns a random hexadecimal token.
+func randomToken() (string, error) {
+	data := make([]byte, 16)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(data), nil
+}
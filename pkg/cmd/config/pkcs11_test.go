@@ -0,0 +1,217 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestParsePKCS11Key(t *testing.T) {
+	key, err := parsePKCS11Key("module=/usr/lib/opensc-pkcs11.so; slot=0;id=01;")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := (&pkcs11Key{Module: "/usr/lib/opensc-pkcs11.so", Slot: "0", ID: "01"}); !reflect.DeepEqual(key, expected) {
+		t.Errorf("expected %+v, got %+v", expected, key)
+	}
+	for _, invalid := range []string{"", "module=/usr/lib/opensc-pkcs11.so", "module=/usr/lib/opensc-pkcs11.so;id=9a-key", "module=/usr/lib/opensc-pkcs11.so;id=01;pin=123456", "id"} {
+		if _, err := parsePKCS11Key(invalid); err == nil {
+			t.Errorf("expected %q to be invalid", invalid)
+		}
+	}
+}
+
+func TestExecPKCS11(t *testing.T) {
+	// The key of the token, which only the fake pkcs11-tool uses.
+	tokenKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "cluster-admin"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, tokenKey.Public(), tokenKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	signatures := 0
+	run := func(env []string, name string, args ...string) ([]byte, error) {
+		if name != pkcs11Tool || args[1] != "/usr/lib/opensc-pkcs11.so" {
+			return nil, fmt.Errorf("unexpected command %s %v", name, args)
+		}
+		flags := map[string]string{}
+		for i, arg := range args {
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "--") {
+				flags[arg] = args[i+1]
+			}
+		}
+		if strings.Join(args, " ") == "--module /usr/lib/opensc-pkcs11.so --id 01 --read-object --type cert" {
+			return certificate, nil
+		}
+		if flags["--mechanism"] != "ECDSA" || flags["--pin"] != "123456" {
+			return nil, fmt.Errorf("unexpected arguments %v", args)
+		}
+		digest, err := ioutil.ReadFile(flags["--input-file"])
+		if err != nil {
+			return nil, err
+		}
+		signature, err := tokenKey.Sign(rand.Reader, digest, crypto.SHA256)
+		if err != nil {
+			return nil, err
+		}
+		signatures++
+		return nil, ioutil.WriteFile(flags["--output-file"], signature, 0600)
+	}
+
+	apiServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.Header.Get("Authorization")) > 0 {
+			t.Errorf("expected the token of the proxy not to reach the cluster")
+		}
+		fmt.Fprintf(w, "%s %s", r.URL.Path, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	apiServer.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	apiServer.StartTLS()
+	defer apiServer.Close()
+
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	config := newRedFederalCowHammerConfig()
+	config.Clusters["cow-cluster"] = &clientcmdapi.Cluster{
+		Server:                   apiServer.URL,
+		CertificateAuthorityData: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: apiServer.Certificate().Raw}),
+	}
+	if err := clientcmd.WriteToFile(config, fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	options := &CreateAuthInfoOptions{ConfigAccess: pathOptions, Name: "red-user"}
+	options.PKCS11.Set("module=/usr/lib/opensc-pkcs11.so;id=01")
+	if err := options.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	saved, err := clientcmd.LoadFromFile(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if key, found, err := readPKCS11Key(saved.AuthInfos["red-user"]); err != nil || !found || key.ID != "01" {
+		t.Fatalf("expected the token of the user to be set, got %+v, %v", key, err)
+	}
+
+	os.Setenv(pkcs11PINEnv, "123456")
+	defer os.Unsetenv(pkcs11PINEnv)
+	responses := []string{}
+	streams, _, _, _ := genericclioptions.NewTestIOStreams()
+	o := &ExecOptions{
+		ConfigAccess: pathOptions,
+		Context:      "federal-context",
+		Args:         []string{"get", "--raw", "/version"},
+		Kubectl:      "kubectl",
+		RunCommand:   run,
+		RunKubectl: func(kubectl string, args []string) error {
+			flags := map[string]string{}
+			for _, arg := range args {
+				if strings.Contains(arg, "--token") || strings.Contains(arg, "--server") {
+					t.Errorf("expected the proxy not to be on the command line, got %v", args)
+				}
+				if parts := strings.SplitN(arg, "=", 2); len(parts) == 2 {
+					flags[parts[0]] = parts[1]
+				}
+			}
+			if flags["--kubeconfig"] == fakeKubeFile.Name() || flags["--context"] != "federal-context" {
+				t.Errorf("expected the context of a kubeconfig of the proxy, got %v", args)
+			}
+			info, err := os.Stat(flags["--kubeconfig"])
+			if err != nil {
+				return err
+			}
+			if info.Mode().Perm() != 0600 {
+				t.Errorf("expected the kubeconfig of the proxy to be private, got %04o", info.Mode().Perm())
+			}
+			proxyConfig, err := clientcmd.LoadFromFile(flags["--kubeconfig"])
+			if err != nil {
+				return err
+			}
+			proxyContext := proxyConfig.Contexts["federal-context"]
+			server := proxyConfig.Clusters[proxyContext.Cluster].Server
+			for _, token := range []string{proxyConfig.AuthInfos[proxyContext.AuthInfo].Token, "guessed"} {
+				request, err := http.NewRequest("GET", server+"/version", nil)
+				if err != nil {
+					return err
+				}
+				request.Header.Set("Authorization", "Bearer "+token)
+				response, err := http.DefaultClient.Do(request)
+				if err != nil {
+					return err
+				}
+				body, err := ioutil.ReadAll(response.Body)
+				response.Body.Close()
+				if err != nil {
+					return err
+				}
+				responses = append(responses, fmt.Sprintf("%d %s", response.StatusCode, strings.TrimSpace(string(body))))
+			}
+			return nil
+		},
+		IOStreams: streams,
+	}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"200 /version cluster-admin", "401 Unauthorized"}; !reflect.DeepEqual(responses, expected) {
+		t.Errorf("expected %v, got %v", expected, responses)
+	}
+	if signatures == 0 {
+		t.Errorf("expected the token to sign the handshake")
+	}
+
+	o.Args = []string{"--server=https://elsewhere", "get", "pods"}
+	if err := o.Run(); err == nil || !strings.Contains(err.Error(), "PKCS#11") {
+		t.Errorf("expected --server to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,370 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/util/homedir"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// contextSensitiveAnnotation is the annotation of the krew manifest of a plugin telling whether
+// it talks to the cluster of the current context, "true" or "false".
+const contextSensitiveAnnotation = "kubecfg.io/context-sensitive"
+
+// kubectlBuiltinCommands are the kubectl commands, which plugins cannot replace.
+var kubectlBuiltinCommands = sets.NewString(
+	"alpha", "annotate", "api-resources", "api-versions", "apply", "attach", "auth", "autoscale",
+	"certificate", "cluster-info", "completion", "config", "convert", "cordon", "cp", "create",
+	"debug", "delete", "describe", "diff", "drain", "edit", "events", "exec", "explain", "expose",
+	"get", "help", "kustomize", "label", "logs", "options", "patch", "plugin", "port-forward",
+	"proxy", "replace", "rollout", "run", "scale", "set", "taint", "top", "uncordon", "version",
+	"wait",
+)
+
+// kubectlPlugin is a kubectl plugin found in the PATH.
+type kubectlPlugin struct {
+	// Name is the kubectl command line running the plugin, such as "oidc-login" for
+	// kubectl-oidc_login, or "foo bar" for kubectl-foo-bar.
+	Name string
+	Path string
+	// ContextSensitive is "true" or "false" as declared by the plugin, or empty when it does not
+	// tell.
+	ContextSensitive string
+}
+
+// Words returns the kubectl command line words running the plugin.
+func (p kubectlPlugin) Words() []string {
+	return strings.Fields(p.Name)
+}
+
+// pluginPolicy is stored in a context's pluginPolicyExtension.
+type pluginPolicy struct {
+	// Allow lists the only plugins allowed, when it is not empty. Plugins declaring they are not
+	// context-sensitive are always allowed.
+	Allow []string `json:"allow,omitempty"`
+	// Deny lists the plugins refused.
+	Deny []string `json:"deny,omitempty"`
+}
+
+// allows reports whether the policy lets plugin run.
+func (p pluginPolicy) allows(plugin kubectlPlugin) bool {
+	if matchesPlugin(p.Deny, plugin) {
+		return false
+	}
+	return len(p.Allow) == 0 || plugin.ContextSensitive == "false" || matchesPlugin(p.Allow, plugin)
+}
+
+// matchesPlugin reports whether names has the name of plugin, or the first words of it, so that
+// "foo" matches the plugin "foo bar".
+func matchesPlugin(names []string, plugin kubectlPlugin) bool {
+	for _, name := range names {
+		if plugin.Name == name || strings.HasPrefix(plugin.Name, name+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// readPluginPolicy returns the plugin policy of a context.
+func readPluginPolicy(context *clientcmdapi.Context) (pluginPolicy, bool, error) {
+	policy := pluginPolicy{}
+	found, err := readExtension(context.Extensions, pluginPolicyExtension, &policy)
+	return policy, found, err
+}
+
+// krewRoot returns the directory krew installs plugins in.
+func krewRoot() string {
+	if dir := os.Getenv("KREW_ROOT"); len(dir) > 0 {
+		return dir
+	}
+	return filepath.Join(homedir.HomeDir(), ".krew")
+}
+
+// krewReceipt is the part of the krew manifest of an installed plugin read from its receipt.
+type krewReceipt struct {
+	Metadata struct {
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+}
+
+// findKubectlPlugins lists the kubectl plugins of the PATH as kubectl runs them: the first of each
+// name, skipping those named like kubectl commands. Whether they are context-sensitive is read from
+// contextSensitiveAnnotation in the receipts krew keeps of the plugins it installed.
+func findKubectlPlugins() []kubectlPlugin {
+	plugins := []kubectlPlugin{}
+	seen := sets.NewString()
+	receipts := filepath.Join(krewRoot(), "receipts")
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			if file.IsDir() || !strings.HasPrefix(file.Name(), "kubectl-") {
+				continue
+			}
+			if runtime.GOOS != "windows" && file.Mode()&0111 == 0 {
+				continue
+			}
+			binary := strings.TrimPrefix(file.Name(), "kubectl-")
+			if runtime.GOOS == "windows" {
+				binary = strings.TrimSuffix(binary, filepath.Ext(binary))
+			}
+			words := strings.Split(binary, "-")
+			for i := range words {
+				words[i] = strings.Replace(words[i], "_", "-", -1)
+			}
+			name := strings.Join(words, " ")
+			if kubectlBuiltinCommands.Has(words[0]) || seen.Has(name) {
+				continue
+			}
+			seen.Insert(name)
+			plugin := kubectlPlugin{Name: name, Path: filepath.Join(dir, file.Name())}
+			if data, err := ioutil.ReadFile(filepath.Join(receipts, words[0]+".yaml")); err == nil {
+				receipt := krewReceipt{}
+				if yaml.Unmarshal(data, &receipt) == nil {
+					plugin.ContextSensitive = receipt.Metadata.Annotations[contextSensitiveAnnotation]
+				}
+			}
+			plugins = append(plugins, plugin)
+		}
+	}
+	sort.SliceStable(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+	return plugins
+}
+
+// kubectlPluginCommand returns the plugin a kubectl command line runs, the one of plugins with the
+// most words the command line starts with, if any.
+func kubectlPluginCommand(args []string, plugins []kubectlPlugin) (kubectlPlugin, bool) {
+	best, found := kubectlPlugin{}, false
+	for _, plugin := range plugins {
+		words := plugin.Words()
+		commands, err := kubectlCommandWords(args, len(words))
+		if err != nil || len(commands) == 0 || kubectlBuiltinCommands.Has(commands[0]) || strings.Join(commands, " ") != plugin.Name {
+			continue
+		}
+		if !found || len(words) > len(best.Words()) {
+			best, found = plugin, true
+		}
+	}
+	return best, found
+}
+
+// guardKubectlPlugin returns an error when a kubectl command line runs a plugin the plugin policy
+// of a context does not allow.
+func guardKubectlPlugin(name string, context *clientcmdapi.Context, args []string, findPlugins func() []kubectlPlugin) error {
+	policy, found, err := readPluginPolicy(context)
+	if err != nil || !found {
+		return err
+	}
+	plugin, ok := kubectlPluginCommand(args, findPlugins())
+	if !ok || policy.allows(plugin) {
+		return nil
+	}
+	return fmt.Errorf("context %q does not allow the kubectl plugin %q, refusing to run it; see \"kubectl config plugins %s\"",
+		name, plugin.Name, name)
+}
+
+// PluginsOptions holds the command-line options for 'config plugins' sub command
+type PluginsOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Context      string
+	Allow        []string
+	Deny         []string
+	Clear        bool
+
+	// FindPlugins lists the kubectl plugins, it is a field so tests can describe them.
+	FindPlugins func() []kubectlPlugin
+
+	allowSet, denySet bool
+
+	genericclioptions.IOStreams
+}
+
+var (
+	pluginsLong = templates.LongDesc(`
+		List the kubectl plugins, and set the plugins "kubectl config exec" runs against a
+		context.
+
+		Without a context, lists the kubectl plugins of the PATH, and whether they declare being
+		context-sensitive, that is talking to the cluster of the current context. Plugins declare
+		it with the kubecfg.io/context-sensitive annotation of their krew manifest, "true" or
+		"false"; the plugins installed otherwise, or not annotated, are listed as unknown.
+
+		With a context, lists whether each plugin is allowed to run against it. --deny refuses
+		plugins, and --allow refuses every plugin that is not listed, except those declaring they
+		are not context-sensitive. A plugin is named as it is run, such as "oidc-login" or
+		"foo bar", and a name also matches the plugins it is the first words of. Commands running a
+		refused plugin against the context are refused by "kubectl config exec" before kubectl is
+		started. As with "kubectl config readonly", this is a local safety net, not access control.`)
+
+	pluginsExample = templates.Examples(`
+		# List the kubectl plugins
+		kubectl config plugins
+
+		# Only allow the plugins reading from the cluster against prod
+		kubectl config plugins prod --allow=tree,neat
+
+		# Refuse view-secret against prod
+		kubectl config plugins prod --deny=view-secret
+
+		# Show the plugins allowed against the current-context
+		kubectl config plugins .
+
+		# Allow every plugin against prod again
+		kubectl config plugins prod --clear`)
+)
+
+// NewCmdConfigPlugins returns a Command instance for 'config plugins' sub command
+func NewCmdConfigPlugins(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &PluginsOptions{ConfigAccess: configAccess, FindPlugins: findKubectlPlugins, IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "plugins [CONTEXT_NAME [--allow=PLUGIN,...] [--deny=PLUGIN,...] [--clear]]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("List kubectl plugins and set the ones allowed against a context"),
+		Long:                  pluginsLong,
+		Example:               pluginsExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	cmd.Flags().StringSliceVar(&o.Allow, "allow", o.Allow, "The only plugins allowed against the context, besides those declaring they are not context-sensitive")
+	cmd.Flags().StringSliceVar(&o.Deny, "deny", o.Deny, "Plugins refused against the context")
+	cmd.Flags().BoolVar(&o.Clear, "clear", o.Clear, "Remove the plugin policy of the context")
+	return cmd
+}
+
+// Complete sets the context from the arguments, and which lists are set
+func (o *PluginsOptions) Complete(cmd *cobra.Command, args []string) error {
+	if len(args) > 1 {
+		return helpErrorf(cmd, "Unexpected args: %v", args)
+	}
+	if len(args) == 1 {
+		o.Context = args[0]
+	}
+	o.allowSet = cmd.Flags().Changed("allow")
+	o.denySet = cmd.Flags().Changed("deny")
+	return nil
+}
+
+// Validate makes sure the policy is set for a context, and not both set and cleared
+func (o *PluginsOptions) Validate() error {
+	changing := o.allowSet || o.denySet
+	if (changing || o.Clear) && len(o.Context) == 0 {
+		return errors.New("a context is required to set the plugins allowed against it")
+	}
+	if changing && o.Clear {
+		return errors.New("--clear cannot be combined with --allow or --deny")
+	}
+	return nil
+}
+
+// Run lists the plugins, or sets the plugin policy of the context
+func (o *PluginsOptions) Run() error {
+	if len(o.Context) == 0 {
+		return o.list(nil)
+	}
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	name, err := resolveContextName(config, o.Context)
+	if err != nil {
+		return err
+	}
+	context, ok := config.Contexts[name]
+	if !ok {
+		return fmt.Errorf("no context exists with the name: %q", name)
+	}
+	policy, _, err := readPluginPolicy(context)
+	if err != nil {
+		return err
+	}
+	if !o.allowSet && !o.denySet && !o.Clear {
+		return o.list(&policy)
+	}
+
+	if o.allowSet {
+		policy.Allow = o.Allow
+	}
+	if o.denySet {
+		policy.Deny = o.Deny
+	}
+	if o.Clear || (len(policy.Allow) == 0 && len(policy.Deny) == 0) {
+		delete(context.Extensions, pluginPolicyExtension)
+	} else if err := writeExtension(&context.Extensions, pluginPolicyExtension, policy); err != nil {
+		return err
+	}
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+	if _, found := context.Extensions[pluginPolicyExtension]; !found {
+		fmt.Fprintf(o.Out, "Every plugin is allowed against context %q.\n", name)
+	} else {
+		fmt.Fprintf(o.Out, "Plugin policy of context %q set.\n", name)
+	}
+	return nil
+}
+
+// list prints the plugins, and whether policy allows them when it is not nil.
+func (o *PluginsOptions) list(policy *pluginPolicy) error {
+	w := printers.GetNewTabWriter(o.Out)
+	defer w.Flush()
+	header := "NAME\tCONTEXT-SENSITIVE\tPATH"
+	if policy != nil {
+		header = "NAME\tCONTEXT-SENSITIVE\tALLOWED\tPATH"
+	}
+	fmt.Fprintln(w, header)
+	for _, plugin := range o.FindPlugins() {
+		sensitive := plugin.ContextSensitive
+		if sensitive != "true" && sensitive != "false" {
+			sensitive = "unknown"
+		}
+		if policy == nil {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", plugin.Name, sensitive, plugin.Path)
+			continue
+		}
+		allowed := "no"
+		if policy.allows(plugin) {
+			allowed = "yes"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", plugin.Name, sensitive, allowed, plugin.Path)
+	}
+	return nil
+}
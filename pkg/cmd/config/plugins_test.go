@@ -0,0 +1,206 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestFindKubectlPlugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are found by their mode")
+	}
+	dir, err := ioutil.TempDir("", "plugins")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	bin, other, krew := filepath.Join(dir, "bin"), filepath.Join(dir, "other"), filepath.Join(dir, "krew")
+	for _, d := range []string{bin, other, filepath.Join(krew, "receipts")} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	files := map[string]os.FileMode{
+		filepath.Join(bin, "kubectl-tree"):              0755,
+		filepath.Join(bin, "kubectl-oidc_login"):        0755,
+		filepath.Join(bin, "kubectl-foo-bar"):           0755,
+		filepath.Join(bin, "kubectl-get"):               0755,
+		filepath.Join(bin, "kubectl-notes"):             0644,
+		filepath.Join(other, "kubectl-tree"):            0755,
+		filepath.Join(bin, "helm"):                      0755,
+		filepath.Join(krew, "receipts/tree.yaml"):       0644,
+		filepath.Join(krew, "receipts/oidc-login.yaml"): 0644,
+	}
+	for file, mode := range files {
+		data := []byte("#!/bin/sh\n")
+		if strings.HasSuffix(file, ".yaml") {
+			sensitive := "true"
+			if strings.Contains(file, "oidc-login") {
+				sensitive = "false"
+			}
+			data = []byte("apiVersion: krew.googlecontainertools.github.com/v1alpha2\nkind: Plugin\nmetadata:\n  annotations:\n    kubecfg.io/context-sensitive: \"" + sensitive + "\"\n")
+		}
+		if err := ioutil.WriteFile(file, data, mode); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	defer os.Setenv("KREW_ROOT", os.Getenv("KREW_ROOT"))
+	os.Setenv("PATH", bin+string(os.PathListSeparator)+other)
+	os.Setenv("KREW_ROOT", krew)
+
+	expected := []kubectlPlugin{
+		{Name: "foo bar", Path: filepath.Join(bin, "kubectl-foo-bar")},
+		{Name: "oidc-login", Path: filepath.Join(bin, "kubectl-oidc_login"), ContextSensitive: "false"},
+		{Name: "tree", Path: filepath.Join(bin, "kubectl-tree"), ContextSensitive: "true"},
+	}
+	if actual := findKubectlPlugins(); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+}
+
+func TestGuardKubectlPlugin(t *testing.T) {
+	plugins := []kubectlPlugin{
+		{Name: "tree", ContextSensitive: "true"},
+		{Name: "view-secret"},
+		{Name: "oidc-login", ContextSensitive: "false"},
+		{Name: "foo"},
+		{Name: "foo bar"},
+	}
+	findPlugins := func() []kubectlPlugin { return plugins }
+	context := &clientcmdapi.Context{}
+	if err := writeExtension(&context.Extensions, pluginPolicyExtension, pluginPolicy{Allow: []string{"tree", "foo"}, Deny: []string{"foo bar"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		args    []string
+		refused bool
+	}{
+		{args: []string{"get", "pods"}},
+		{args: []string{"tree", "deployment", "web"}},
+		{args: []string{"-n", "shop", "tree", "deployment", "web"}},
+		{args: []string{"oidc-login", "get-token"}},
+		{args: []string{"foo", "baz"}},
+		{args: []string{"view-secret", "db"}, refused: true},
+		{args: []string{"--namespace", "shop", "view-secret", "db"}, refused: true},
+		{args: []string{"foo", "bar", "--all"}, refused: true},
+	}
+	for _, tt := range tests {
+		err := guardKubectlPlugin("prod", context, tt.args, findPlugins)
+		if tt.refused && (err == nil || !strings.Contains(err.Error(), `context "prod" does not allow the kubectl plugin`)) {
+			t.Errorf("expected %v to be refused, got %v", tt.args, err)
+		}
+		if !tt.refused && err != nil {
+			t.Errorf("expected %v to be allowed, got %v", tt.args, err)
+		}
+	}
+
+	if err := guardKubectlPlugin("dev", &clientcmdapi.Context{}, []string{"view-secret", "db"}, nil); err != nil {
+		t.Errorf("expected a context without policy to allow everything, got %v", err)
+	}
+}
+
+func TestPlugins(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plugins")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := clientcmd.WriteToFile(newRedFederalCowHammerConfig(), filepath.Join(dir, "config")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = filepath.Join(dir, "config")
+	pathOptions.EnvVar = ""
+
+	run := func(context string, allow, deny []string, clear bool) (string, error) {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		o := &PluginsOptions{
+			ConfigAccess: pathOptions,
+			Context:      context,
+			Allow:        allow,
+			Deny:         deny,
+			Clear:        clear,
+			FindPlugins: func() []kubectlPlugin {
+				return []kubectlPlugin{
+					{Name: "oidc-login", Path: "/bin/kubectl-oidc_login", ContextSensitive: "false"},
+					{Name: "tree", Path: "/bin/kubectl-tree", ContextSensitive: "true"},
+					{Name: "view-secret", Path: "/bin/kubectl-view_secret"},
+				}
+			},
+			allowSet:  allow != nil,
+			denySet:   deny != nil,
+			IOStreams: streams,
+		}
+		if err := o.Validate(); err != nil {
+			return "", err
+		}
+		err := o.Run()
+		return out.String(), err
+	}
+
+	out, err := run("", nil, nil, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lines := strings.Split(out, "\n"); !strings.HasPrefix(lines[0], "NAME") || !strings.Contains(lines[3], "unknown") {
+		t.Errorf("unexpected list %q", out)
+	}
+	if _, err := run("", nil, []string{"tree"}, false); err == nil {
+		t.Errorf("expected a policy without context to fail")
+	}
+	if out, err := run("federal-context", []string{"tree"}, nil, false); err != nil || out != "Plugin policy of context \"federal-context\" set.\n" {
+		t.Errorf("unexpected output %q, %v", out, err)
+	}
+	out, err = run(".", nil, nil, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	allowed := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n")[1:] {
+		fields := strings.Fields(line)
+		allowed[fields[0]] = fields[2]
+	}
+	if expected := map[string]string{"oidc-login": "yes", "tree": "yes", "view-secret": "no"}; !reflect.DeepEqual(expected, allowed) {
+		t.Errorf("expected %v, got %v", expected, allowed)
+	}
+	if _, err := run("federal-context", nil, []string{"tree"}, true); err == nil {
+		t.Errorf("expected --clear with --deny to fail")
+	}
+	if out, err := run("federal-context", nil, nil, true); err != nil || out != "Every plugin is allowed against context \"federal-context\".\n" {
+		t.Errorf("unexpected output %q, %v", out, err)
+	}
+	config, err := clientcmd.LoadFromFile(pathOptions.GlobalFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(config.Contexts["federal-context"].Extensions) != 0 {
+		t.Errorf("expected the extension to be removed, got %v", config.Contexts["federal-context"].Extensions)
+	}
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/transport"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// Defaults of the requests probes make to a single host, when the context sets no client tuning.
+const (
+	defaultProbeQPS   = 5
+	defaultProbeBurst = 10
+)
+
+// probeBackoff is how often, and how long apart, probes try again after failing for reasons a
+// flaky network explains.
+type probeBackoff struct {
+	Attempts int
+	Initial  time.Duration
+	Max      time.Duration
+}
+
+// defaultProbeBackoff tries three times, half a second and then a second after failing.
+var defaultProbeBackoff = probeBackoff{Attempts: 3, Initial: 500 * time.Millisecond, Max: 4 * time.Second}
+
+// probeSleep waits between attempts unless done or cancel is closed first, as they are when the
+// request is cancelled or times out. It is a variable so tests do not wait.
+var probeSleep = func(d time.Duration, done, cancel <-chan struct{}) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-done:
+		return false
+	case <-cancel:
+		return false
+	}
+}
+
+// delays returns the delay before each attempt after the first.
+func (b probeBackoff) delays() []time.Duration {
+	delays := []time.Duration{}
+	delay := b.Initial
+	for attempt := 1; attempt < b.Attempts; attempt++ {
+		delays = append(delays, delay)
+		if delay *= 2; delay > b.Max {
+			delay = b.Max
+		}
+	}
+	return delays
+}
+
+// retry runs attempt until it succeeds, fails for good or the attempts run out, and returns its
+// last error.
+func (b probeBackoff) retry(attempt func() error) error {
+	err := attempt()
+	for _, delay := range b.delays() {
+		if err == nil || !retriableError(err) {
+			break
+		}
+		probeSleep(delay, nil, nil)
+		err = attempt()
+	}
+	return err
+}
+
+// hostRateLimiters share a rate limiter between all the probes made to the same host, so that
+// checking many contexts of a cluster at once does not flood its API server.
+type hostRateLimiters struct {
+	lock     sync.Mutex
+	limiters map[string]flowcontrol.RateLimiter
+}
+
+var probeRateLimiters = &hostRateLimiters{limiters: map[string]flowcontrol.RateLimiter{}}
+
+// forHost returns the rate limiter of host, created with qps and burst the first time.
+func (l *hostRateLimiters) forHost(host string, qps float32, burst int) flowcontrol.RateLimiter {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	limiter, ok := l.limiters[host]
+	if !ok {
+		limiter = flowcontrol.NewTokenBucketRateLimiter(qps, burst)
+		l.limiters[host] = limiter
+	}
+	return limiter
+}
+
+// newProbeConfig returns the client configuration probes of the cluster of the context called name
+// use. The certificate authority, client credentials and TLS server name come from the cluster and
+// user of the context, the proxy from the environment as for kubectl, and the servers of clusters
+// preferring IPv6 are dialed over IPv6 first. Requests that can safely be
+// repeated are retried with defaultProbeBackoff, and requests to a host are rate limited together.
+func newProbeConfig(config *clientcmdapi.Config, name string, overrides *clientcmd.ConfigOverrides, timeout time.Duration) (*rest.Config, error) {
+	if overrides == nil {
+		overrides = &clientcmd.ConfigOverrides{}
+	}
+	restConfig, err := clientcmd.NewNonInteractiveClientConfig(*config, name, overrides, nil).ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	restConfig.Timeout = timeout
+	if context, ok := config.Contexts[name]; ok {
+		if err := applyClientTuning(restConfig, context); err != nil {
+			return nil, err
+		}
+	}
+
+	qps, burst := restConfig.QPS, restConfig.Burst
+	if qps <= 0 {
+		qps = defaultProbeQPS
+	}
+	if burst <= 0 {
+		burst = defaultProbeBurst
+	}
+	host := restConfig.Host
+	if server, err := url.Parse(restConfig.Host); err == nil && len(server.Host) > 0 {
+		host = server.Host
+	}
+	restConfig.RateLimiter = probeRateLimiters.forHost(host, qps, burst)
+	if context, ok := config.Contexts[name]; ok && len(overrides.ClusterInfo.Server) == 0 {
+		if cluster := config.Clusters[context.Cluster]; prefersIPv6(cluster) {
+			restConfig.Dial = dialPreferringIPv6(&net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second})
+		}
+	}
+	restConfig.WrapTransport = transport.Wrappers(restConfig.WrapTransport, func(rt http.RoundTripper) http.RoundTripper {
+		return &retryRoundTripper{delegate: rt, backoff: defaultProbeBackoff}
+	})
+	return restConfig, nil
+}
+
+// retryRoundTripper repeats GET and HEAD requests that failed to reach the server, or that a proxy
+// or load balancer in front of it failed. Requests the server throttled are retried by client-go.
+type retryRoundTripper struct {
+	delegate http.RoundTripper
+	backoff  probeBackoff
+}
+
+func (r *retryRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return r.delegate.RoundTrip(req)
+	}
+	resp, err := r.delegate.RoundTrip(req)
+	for _, delay := range r.backoff.delays() {
+		if !retriableResponse(resp, err) {
+			break
+		}
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if !probeSleep(delay, req.Context().Done(), req.Cancel) {
+			return nil, errRequestCanceled
+		}
+		resp, err = r.delegate.RoundTrip(req)
+	}
+	return resp, err
+}
+
+// retriableError reports whether err may be a failure of the network rather than of the
+// certificates of the server, which trying again does not fix.
+func retriableError(err error) bool {
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	switch err.(type) {
+	case x509.CertificateInvalidError, x509.HostnameError, x509.UnknownAuthorityError, tls.RecordHeaderError:
+		return false
+	}
+	return true
+}
+
+// errRequestCanceled is returned when a request is cancelled while waiting to be tried again.
+var errRequestCanceled = errors.New("net/http: request canceled while waiting to retry")
+
+// retriableResponse reports whether a request failed in a way trying again may fix.
+func retriableResponse(resp *http.Response, err error) bool {
+	if err != nil {
+		return retriableError(err)
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
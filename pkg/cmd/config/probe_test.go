@@ -0,0 +1,162 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestRetryRoundTripper(t *testing.T) {
+	slept := []time.Duration{}
+	defer func(sleep func(time.Duration, <-chan struct{}, <-chan struct{}) bool) { probeSleep = sleep }(probeSleep)
+	probeSleep = func(d time.Duration, done, cancel <-chan struct{}) bool {
+		slept = append(slept, d)
+		return true
+	}
+
+	tests := map[string]struct {
+		method    string
+		responses []int
+		errs      []error
+		expected  int
+		attempts  int
+	}{
+		"unavailable then ok": {
+			method:    http.MethodGet,
+			responses: []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK},
+			expected:  http.StatusOK,
+			attempts:  3,
+		},
+		"network error then ok": {
+			method:    http.MethodGet,
+			responses: []int{0, http.StatusOK},
+			errs:      []error{errors.New("connection reset by peer")},
+			expected:  http.StatusOK,
+			attempts:  2,
+		},
+		"attempts run out": {
+			method:    http.MethodGet,
+			responses: []int{http.StatusGatewayTimeout, http.StatusGatewayTimeout, http.StatusGatewayTimeout, http.StatusOK},
+			expected:  http.StatusGatewayTimeout,
+			attempts:  3,
+		},
+		"unauthorized": {
+			method:    http.MethodGet,
+			responses: []int{http.StatusUnauthorized, http.StatusOK},
+			expected:  http.StatusUnauthorized,
+			attempts:  1,
+		},
+		"not repeatable": {
+			method:    http.MethodPost,
+			responses: []int{http.StatusServiceUnavailable, http.StatusOK},
+			expected:  http.StatusServiceUnavailable,
+			attempts:  1,
+		},
+		"certificate error": {
+			method:    http.MethodGet,
+			responses: []int{0, http.StatusOK},
+			errs:      []error{x509.UnknownAuthorityError{}},
+			attempts:  1,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			slept = []time.Duration{}
+			attempts := 0
+			rt := &retryRoundTripper{
+				backoff: defaultProbeBackoff,
+				delegate: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+					attempts++
+					if attempts <= len(test.errs) && test.errs[attempts-1] != nil {
+						return nil, test.errs[attempts-1]
+					}
+					return &http.Response{StatusCode: test.responses[attempts-1], Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+				}),
+			}
+			req, _ := http.NewRequest(test.method, "https://prod.example.com/api", nil)
+			resp, err := rt.RoundTrip(req)
+			if attempts != test.attempts {
+				t.Errorf("expected %d attempts, got %d", test.attempts, attempts)
+			}
+			if test.expected == 0 {
+				if err == nil {
+					t.Errorf("expected an error, got %v", resp.StatusCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.StatusCode != test.expected {
+				t.Errorf("expected %d, got %d", test.expected, resp.StatusCode)
+			}
+			if expected := defaultProbeBackoff.delays()[:attempts-1]; !reflect.DeepEqual(slept, expected) {
+				t.Errorf("expected to wait %v, waited %v", expected, slept)
+			}
+		})
+	}
+}
+
+func TestProbeBackoffDelays(t *testing.T) {
+	backoff := probeBackoff{Attempts: 5, Initial: time.Second, Max: 3 * time.Second}
+	expected := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}
+	if delays := backoff.delays(); !reflect.DeepEqual(delays, expected) {
+		t.Errorf("expected %v, got %v", expected, delays)
+	}
+}
+
+func TestNewProbeConfig(t *testing.T) {
+	config := clientcmdapi.NewConfig()
+	config.Clusters["prod"] = &clientcmdapi.Cluster{Server: "https://prod.example.com:6443"}
+	config.Clusters["prod-admin"] = &clientcmdapi.Cluster{Server: "https://prod.example.com:6443"}
+	config.Clusters["dev"] = &clientcmdapi.Cluster{Server: "https://dev.example.com"}
+	config.AuthInfos["user"] = &clientcmdapi.AuthInfo{Token: "token"}
+	for _, name := range []string{"prod", "prod-admin", "dev"} {
+		config.Contexts[name] = &clientcmdapi.Context{Cluster: name, AuthInfo: "user"}
+	}
+
+	prod, err := newProbeConfig(config, "prod", nil, 10*time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if prod.Timeout != 10*time.Second || prod.BearerToken != "token" || prod.WrapTransport == nil {
+		t.Errorf("unexpected config %+v", prod)
+	}
+	admin, err := newProbeConfig(config, "prod-admin", nil, 10*time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dev, err := newProbeConfig(config, "dev", nil, 10*time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if prod.RateLimiter != admin.RateLimiter {
+		t.Errorf("expected contexts of the same server to share a rate limiter")
+	}
+	if prod.RateLimiter == dev.RateLimiter {
+		t.Errorf("expected contexts of different servers to have their own rate limiter")
+	}
+}
@@ -0,0 +1,336 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/printers"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// profiles is the content of the profiles file: named sets of kubeconfig files, each of which
+// becomes the value of $KUBECONFIG when the profile is used.
+type profiles struct {
+	Current  string              `json:"current,omitempty"`
+	Profiles map[string][]string `json:"profiles,omitempty"`
+}
+
+// ProfileOptions holds the command-line options for 'config profile' sub commands
+type ProfileOptions struct {
+	Filename string
+	Name     string
+	Files    []string
+	Shell    string
+
+	genericclioptions.IOStreams
+}
+
+var (
+	profileLong = templates.LongDesc(`
+		Manage named sets of kubeconfig files and switch between them.
+
+		A profile lists kubeconfig files or glob patterns, which are expanded every time the profile
+		is used so that files added later are picked up. "profile use" prints the shell commands
+		setting $KUBECONFIG for the shell to evaluate; with the integration of "kubectl config
+		shell-init" loaded, "kcfg profile use NAME" does so directly.
+
+		Profiles are stored in profiles.yaml next to the settings file.`)
+
+	profileExample = templates.Examples(`
+		# Create a profile from all kubeconfig files of a directory, including ones added later
+		kubectl config profile create work '~/.kube/work/*.yaml'
+
+		# Switch the current shell to the home profile
+		eval "$(kubectl config profile use home)"
+
+		# Switch a fish shell to the work profile
+		kubectl config profile use work --shell=fish | source`)
+)
+
+// NewCmdConfigProfile returns a Command instance for 'config profile' sub command
+func NewCmdConfigProfile(streams genericclioptions.IOStreams) *cobra.Command {
+	o := &ProfileOptions{IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "profile SUBCOMMAND",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Switch between named sets of kubeconfig files"),
+		Long:                  profileLong,
+		Example:               profileExample,
+		Run:                   cmdutil.DefaultSubCommandRun(streams.ErrOut),
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "create NAME FILE...",
+		Short: i18n.T("Create or replace a profile"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) < 2 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			cmdutil.CheckErr(o.Complete(args[0], args[1:]))
+			cmdutil.CheckErr(o.RunCreate())
+		},
+	})
+	useCmd := &cobra.Command{
+		Use:   "use NAME [--shell=sh|fish|powershell]",
+		Short: i18n.T("Print the shell commands switching to a profile"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			cmdutil.CheckErr(o.Complete(args[0], nil))
+			cmdutil.CheckErr(o.RunUse())
+		},
+	}
+	useCmd.Flags().StringVar(&o.Shell, "shell", "sh", "Syntax of the printed commands. One of: sh|fish|powershell")
+	cmd.AddCommand(useCmd)
+	cmd.AddCommand(noWriteCommand(&cobra.Command{
+		Use:   "list",
+		Short: i18n.T("List profiles"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			cmdutil.CheckErr(o.Complete("", nil))
+			cmdutil.CheckErr(o.RunList())
+		},
+	}))
+	cmd.AddCommand(&cobra.Command{
+		Use:   "delete NAME",
+		Short: i18n.T("Delete a profile"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			cmdutil.CheckErr(o.Complete(args[0], nil))
+			cmdutil.CheckErr(o.RunDelete())
+		},
+	})
+	return cmd
+}
+
+// Complete assigns the arguments and locates the profiles file
+func (o *ProfileOptions) Complete(name string, files []string) error {
+	o.Name = name
+	o.Files = files
+	if len(o.Filename) == 0 {
+		o.Filename = filepath.Join(configDir(), "profiles.yaml")
+	}
+	return nil
+}
+
+// RunCreate stores a profile
+func (o *ProfileOptions) RunCreate() error {
+	if len(o.Name) == 0 {
+		return fmt.Errorf("empty profile names are not allowed")
+	}
+	all, err := loadProfiles(o.Filename)
+	if err != nil {
+		return err
+	}
+	files := []string{}
+	for _, file := range o.Files {
+		file, err := filepath.Abs(expandPath(file))
+		if err != nil {
+			return err
+		}
+		files = append(files, file)
+	}
+	all.Profiles[o.Name] = files
+	if err := saveProfiles(o.Filename, all); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "Profile %q created with %d file(s).\n", o.Name, len(files))
+	return nil
+}
+
+// RunUse prints the commands setting $KUBECONFIG to the files of a profile
+func (o *ProfileOptions) RunUse() error {
+	all, err := loadProfiles(o.Filename)
+	if err != nil {
+		return err
+	}
+	patterns, ok := all.Profiles[o.Name]
+	if !ok {
+		return fmt.Errorf("no profile exists with the name: %q", o.Name)
+	}
+	files, err := expandProfileFiles(patterns)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("profile %q matches no kubeconfig files", o.Name)
+	}
+
+	value := strings.Join(files, string(filepath.ListSeparator))
+	export, err := shellExport(o.Shell, clientcmd.RecommendedConfigPathEnvVar, value)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(o.Out, export)
+
+	all.Current = o.Name
+	return saveProfiles(o.Filename, all)
+}
+
+// RunList prints all profiles, marking the one used last
+func (o *ProfileOptions) RunList() error {
+	all, err := loadProfiles(o.Filename)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(all.Profiles))
+	for name := range all.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := printers.GetNewTabWriter(o.Out)
+	fmt.Fprintf(w, "CURRENT\tNAME\tFILES\n")
+	for _, name := range names {
+		current := ""
+		if name == all.Current {
+			current = "*"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", current, name, strings.Join(all.Profiles[name], ","))
+	}
+	return w.Flush()
+}
+
+// RunDelete removes a profile
+func (o *ProfileOptions) RunDelete() error {
+	all, err := loadProfiles(o.Filename)
+	if err != nil {
+		return err
+	}
+	if _, ok := all.Profiles[o.Name]; !ok {
+		return fmt.Errorf("no profile exists with the name: %q", o.Name)
+	}
+	delete(all.Profiles, o.Name)
+	if all.Current == o.Name {
+		all.Current = ""
+	}
+	if err := saveProfiles(o.Filename, all); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "Deleted profile %q.\n", o.Name)
+	return nil
+}
+
+// expandProfileFiles expands the glob patterns of a profile. Plain file names are kept even when the
+// file does not exist yet, as kubectl creates the last file of $KUBECONFIG when writing.
+func expandProfileFiles(patterns []string) ([]string, error) {
+	files := []string{}
+	seen := map[string]bool{}
+	for _, pattern := range patterns {
+		matches := []string{pattern}
+		if strings.ContainsAny(pattern, "*?[") {
+			var err error
+			if matches, err = filepath.Glob(pattern); err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+			}
+			sort.Strings(matches)
+		}
+		for _, file := range matches {
+			if !seen[file] {
+				seen[file] = true
+				files = append(files, file)
+			}
+		}
+	}
+	return files, nil
+}
+
+// shellExport returns the command setting the environment variable name to value in shell, one of
+// sh, fish or powershell.
+func shellExport(shell, name, value string) (string, error) {
+	switch shell {
+	case "sh":
+		return fmt.Sprintf("export %s=%s\n", name, shellQuote(value)), nil
+	case "fish":
+		return fmt.Sprintf("set -gx %s %s\n", name, fishQuote(value)), nil
+	case "powershell":
+		return fmt.Sprintf("$Env:%s = '%s'\n", name, strings.Replace(value, "'", "''", -1)), nil
+	default:
+		return "", fmt.Errorf("unsupported shell %q, must be one of: sh, fish, powershell", shell)
+	}
+}
+
+// shellUnset returns the command removing the environment variable name in shell.
+func shellUnset(shell, name string) (string, error) {
+	switch shell {
+	case "sh":
+		return fmt.Sprintf("unset %s\n", name), nil
+	case "fish":
+		return fmt.Sprintf("set -e %s\n", name), nil
+	case "powershell":
+		return fmt.Sprintf("Remove-Item Env:%s -ErrorAction SilentlyContinue\n", name), nil
+	default:
+		return "", fmt.Errorf("unsupported shell %q, must be one of: sh, fish, powershell", shell)
+	}
+}
+
+// shellQuote quotes s for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// fishQuote quotes s for fish, which allows escaping quotes and backslashes within single quotes.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+func loadProfiles(filename string) (*profiles, error) {
+	all := &profiles{}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := yaml.Unmarshal(data, all); err != nil {
+			return nil, fmt.Errorf("error loading profiles from %s: %v", filename, err)
+		}
+	}
+	if all.Profiles == nil {
+		all.Profiles = map[string][]string{}
+	}
+	return all, nil
+}
+
+func saveProfiles(filename string, all *profiles) error {
+	data, err := yaml.Marshal(all)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0600)
+}
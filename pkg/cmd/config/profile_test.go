@@ -0,0 +1,92 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestProfiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "profiles")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"b.yaml", "a.yaml", "notes.txt"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	filename := filepath.Join(dir, "kubecfg", "profiles.yaml")
+	newOptions := func(name string, files ...string) (*ProfileOptions, *bytes.Buffer) {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		o := &ProfileOptions{Filename: filename, Shell: "sh", IOStreams: streams}
+		if err := o.Complete(name, files); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return o, out
+	}
+
+	o, _ := newOptions("work", filepath.Join(dir, "*.yaml"), filepath.Join(dir, "personal", "config"))
+	if err := o.RunCreate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	o, out := newOptions("work")
+	if err := o.RunUse(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files := []string{filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.yaml"), filepath.Join(dir, "personal", "config")}
+	expected := "export KUBECONFIG='" + strings.Join(files, string(filepath.ListSeparator)) + "'\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+
+	o, out = newOptions("")
+	if err := o.RunList(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(strings.Join(strings.Fields(lines[1]), " "), "* work ") {
+		t.Errorf("expected the used profile to be marked, got %q", out.String())
+	}
+
+	o, _ = newOptions("work")
+	if err := o.RunDelete(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	o, _ = newOptions("work")
+	if err := o.RunUse(); err == nil {
+		t.Errorf("expected an error using a deleted profile")
+	}
+}
+
+func TestProfileShellQuoting(t *testing.T) {
+	if quoted := shellQuote("it's"); quoted != `'it'\''s'` {
+		t.Errorf("unexpected sh quoting %s", quoted)
+	}
+	if quoted := fishQuote(`it's \`); quoted != `'it\'s \\'` {
+		t.Errorf("unexpected fish quoting %s", quoted)
+	}
+}
@@ -0,0 +1,345 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// The libfido2 commands the credentials and assertions of security keys are made with.
+const (
+	fido2TokenTool  = "fido2-token"
+	fido2CredTool   = "fido2-cred"
+	fido2AssertTool = "fido2-assert"
+
+	// fido2RelyingParty is the relying party of the credentials made by "config protect".
+	fido2RelyingParty = "kubectl-config"
+	// fido2DeviceEnv names the security key to use, the first one found by default.
+	fido2DeviceEnv = "KUBECTL_CONFIG_FIDO2_DEVICE"
+)
+
+// contextProtection is stored in a context's protectionExtension.
+type contextProtection struct {
+	FIDO2 *fido2Credential `json:"fido2,omitempty"`
+}
+
+// fido2Credential is the credential of a security key a context requires a touch of.
+type fido2Credential struct {
+	RelyingParty string `json:"relyingParty"`
+	// ID is the base64 encoded ID of the credential.
+	ID string `json:"id"`
+	// PublicKey is the PEM encoded ES256 public key of the credential.
+	PublicKey string `json:"publicKey"`
+}
+
+// ProtectOptions holds the command-line options for 'config protect' sub command
+type ProtectOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Context      string
+	RequireFIDO2 bool
+	Remove       bool
+	Device       string
+
+	RunCommand commandRunner
+
+	genericclioptions.IOStreams
+}
+
+var (
+	protectLong = templates.LongDesc(`
+		Require a touch of a FIDO2 security key to use a context.
+
+		With --require-fido2, a credential is made on the security key, given with --device or
+		the first one found, and "kubectl config use-context", "session use", "replay" and "exec"
+		then perform a WebAuthn assertion with it, which needs the key to be touched, before
+		switching to the context or running kubectl against it; "autoswitch" never switches to
+		it. This is a strong local confirmation for sensitive clusters, not access control:
+		kubectl run directly is not affected.
+
+		The libfido2 tools fido2-token, fido2-cred and fido2-assert are used. The security key is
+		the one named by the ` + fido2DeviceEnv + ` environment variable, or the first one found.
+
+		Without --require-fido2 or --remove, prints how the context is protected.`)
+
+	protectExample = templates.Examples(`
+		# Require a touch of the security key to use prod
+		kubectl config protect prod --require-fido2
+
+		# Show how the current-context is protected
+		kubectl config protect .
+
+		# Stop requiring a touch for prod
+		kubectl config protect prod --remove`)
+)
+
+// NewCmdConfigProtect returns a Command instance for 'config protect' sub command
+func NewCmdConfigProtect(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &ProtectOptions{ConfigAccess: configAccess, RunCommand: runCommand, IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "protect CONTEXT_NAME [--require-fido2 [--device=DEVICE] | --remove]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Require a touch of a FIDO2 security key to use a context"),
+		Long:                  protectLong,
+		Example:               protectExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.Context = args[0]
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().BoolVar(&o.RequireFIDO2, "require-fido2", o.RequireFIDO2, "If true, require a touch of a FIDO2 security key to use the context")
+	cmd.Flags().StringVar(&o.Device, "device", o.Device, "The security key to make the credential on, such as /dev/hidraw0. Defaults to the first one found")
+	cmd.Flags().BoolVar(&o.Remove, "remove", o.Remove, "If true, stop protecting the context")
+	return cmd
+}
+
+// Validate makes sure the flags do not conflict
+func (o *ProtectOptions) Validate() error {
+	if o.RequireFIDO2 && o.Remove {
+		return errors.New("--require-fido2 cannot be combined with --remove")
+	}
+	if len(o.Device) > 0 && !o.RequireFIDO2 {
+		return errors.New("--device requires --require-fido2")
+	}
+	return nil
+}
+
+// Run prints or sets the protection of the context
+func (o *ProtectOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	name, err := resolveContextName(config, o.Context)
+	if err != nil {
+		return err
+	}
+	context, ok := config.Contexts[name]
+	if !ok {
+		return fmt.Errorf("no context exists with the name: %q", name)
+	}
+
+	if !o.RequireFIDO2 && !o.Remove {
+		protection, err := readContextProtection(context)
+		if err != nil {
+			return err
+		}
+		if protection.FIDO2 != nil {
+			fmt.Fprintln(o.Out, "fido2")
+		} else {
+			fmt.Fprintln(o.Out, "none")
+		}
+		return nil
+	}
+
+	if o.Remove {
+		delete(context.Extensions, protectionExtension)
+	} else {
+		device := o.Device
+		if len(device) == 0 {
+			if device, err = findFIDO2Device(o.RunCommand); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(o.ErrOut, "Touch the security key to make a credential for context %q...\n", name)
+		credential, err := makeFIDO2Credential(o.RunCommand, device, name)
+		if err != nil {
+			return err
+		}
+		if err := writeExtension(&context.Extensions, protectionExtension, contextProtection{FIDO2: credential}); err != nil {
+			return err
+		}
+	}
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+	if o.Remove {
+		fmt.Fprintf(o.Out, "Context %q is not protected.\n", name)
+	} else {
+		fmt.Fprintf(o.Out, "Context %q requires a touch of the security key.\n", name)
+	}
+	return nil
+}
+
+// readContextProtection returns the protection of a context, which is empty when it has none.
+func readContextProtection(context *clientcmdapi.Context) (contextProtection, error) {
+	protection := contextProtection{}
+	_, err := readExtension(context.Extensions, protectionExtension, &protection)
+	return protection, err
+}
+
+// findFIDO2Device returns the security key named by fido2DeviceEnv, or else the first one plugged
+// in.
+func findFIDO2Device(run commandRunner) (string, error) {
+	if device := os.Getenv(fido2DeviceEnv); len(device) > 0 {
+		return device, nil
+	}
+	out, err := run(nil, fido2TokenTool, "-L")
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", fido2TokenTool, err)
+	}
+	// Every line is "DEVICE: VENDOR PRODUCT".
+	for _, line := range strings.Split(string(out), "\n") {
+		if i := strings.Index(line, ": "); i > 0 {
+			return line[:i], nil
+		}
+	}
+	return "", errors.New("no FIDO2 security key found")
+}
+
+// runFIDO2 runs a libfido2 tool with the lines of input as its input file, and returns the lines of
+// its output file.
+func runFIDO2(run commandRunner, tool string, input []string, args ...string) ([]string, error) {
+	dir, err := ioutil.TempDir("", "fido2")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	inputFile, outputFile := filepath.Join(dir, "input"), filepath.Join(dir, "output")
+	if err := ioutil.WriteFile(inputFile, []byte(strings.Join(input, "\n")+"\n"), 0600); err != nil {
+		return nil, err
+	}
+	args = append([]string{args[0], "-i", inputFile, "-o", outputFile}, args[1:]...)
+	if _, err := run(nil, tool, args...); err != nil {
+		return nil, fmt.Errorf("%s: %v", tool, err)
+	}
+	output, err := ioutil.ReadFile(outputFile)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimRight(string(output), "\n"), "\n"), nil
+}
+
+// fido2Challenge returns a random client data hash, base64 encoded.
+func fido2Challenge() (string, error) {
+	challenge := make([]byte, 32)
+	if _, err := rand.Read(challenge); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(challenge), nil
+}
+
+// makeFIDO2Credential makes a credential on the security key device for the context called name,
+// and verifies its attestation.
+func makeFIDO2Credential(run commandRunner, device, name string) (*fido2Credential, error) {
+	challenge, err := fido2Challenge()
+	if err != nil {
+		return nil, err
+	}
+	userID := base64.StdEncoding.EncodeToString([]byte(name))
+	made, err := runFIDO2(run, fido2CredTool, []string{challenge, fido2RelyingParty, name, userID}, "-M", device, "es256")
+	if err != nil {
+		return nil, err
+	}
+	// The credential is verified, which also gives its ID and public key.
+	verified, err := runFIDO2(run, fido2CredTool, made, "-V", "es256")
+	if err != nil {
+		return nil, err
+	}
+	if len(verified) < 2 || !strings.HasPrefix(verified[1], "-----BEGIN PUBLIC KEY-----") {
+		return nil, fmt.Errorf("%s: unexpected output %q", fido2CredTool, strings.Join(verified, "\n"))
+	}
+	return &fido2Credential{
+		RelyingParty: fido2RelyingParty,
+		ID:           verified[0],
+		PublicKey:    strings.Join(verified[1:], "\n") + "\n",
+	}, nil
+}
+
+// assertFIDO2 asks for a touch of the security key holding credential, and verifies the assertion
+// it signs.
+func assertFIDO2(run commandRunner, credential *fido2Credential) error {
+	device, err := findFIDO2Device(run)
+	if err != nil {
+		return err
+	}
+	challenge, err := fido2Challenge()
+	if err != nil {
+		return err
+	}
+	assertion, err := runFIDO2(run, fido2AssertTool, []string{challenge, credential.RelyingParty, credential.ID}, "-G", "-p", device)
+	if err != nil {
+		return err
+	}
+	// The assertion starts with the client data hash it signs, which must be the fresh one.
+	if len(assertion) < 4 || assertion[0] != challenge {
+		return errors.New("the security key did not sign the challenge")
+	}
+
+	dir, err := ioutil.TempDir("", "fido2")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	keyFile := filepath.Join(dir, "public.pem")
+	if err := ioutil.WriteFile(keyFile, []byte(credential.PublicKey), 0600); err != nil {
+		return err
+	}
+	inputFile := filepath.Join(dir, "assertion")
+	if err := ioutil.WriteFile(inputFile, []byte(strings.Join(assertion, "\n")+"\n"), 0600); err != nil {
+		return err
+	}
+	if _, err := run(nil, fido2AssertTool, "-V", "-p", "-i", inputFile, keyFile, "es256"); err != nil {
+		return fmt.Errorf("invalid assertion of the security key: %v", err)
+	}
+	return nil
+}
+
+// requireFIDO2Touch performs the WebAuthn assertion the context called name requires, if it is
+// protected with "config protect --require-fido2", telling prompt to touch the key.
+func requireFIDO2Touch(config *clientcmdapi.Config, name string, run commandRunner, prompt io.Writer) error {
+	context, ok := config.Contexts[name]
+	if !ok {
+		return nil
+	}
+	protection, err := readContextProtection(context)
+	if err != nil || protection.FIDO2 == nil {
+		return err
+	}
+	if run == nil {
+		run = runCommand
+	}
+	if prompt != nil {
+		fmt.Fprintf(prompt, "Touch the security key to use context %q...\n", name)
+	}
+	if err := assertFIDO2(run, protection.FIDO2); err != nil {
+		return fmt.Errorf("context %q requires a touch of the security key: %v", name, err)
+	}
+	return nil
+}
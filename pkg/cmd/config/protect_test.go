@@ -0,0 +1,180 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const fakeFIDO2PublicKey = "-----BEGIN PUBLIC KEY-----\nZmFrZQ==\n-----END PUBLIC KEY-----\n"
+
+// fakeFIDO2 runs the libfido2 tools against a security key holding a single credential, which only
+// signs while touched is true.
+func fakeFIDO2(touched *bool) commandRunner {
+	return func(env []string, name string, args ...string) ([]byte, error) {
+		flag := func(flag string) string {
+			for i := range args[:len(args)-1] {
+				if args[i] == flag {
+					return args[i+1]
+				}
+			}
+			return ""
+		}
+		readInput := func() []string {
+			data, err := ioutil.ReadFile(flag("-i"))
+			if err != nil {
+				panic(err)
+			}
+			return strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+		}
+		writeOutput := func(lines ...string) ([]byte, error) {
+			return nil, ioutil.WriteFile(flag("-o"), []byte(strings.Join(lines, "\n")+"\n"), 0600)
+		}
+
+		switch name + " " + args[0] {
+		case fido2TokenTool + " -L":
+			return []byte("/dev/hidraw0: vendor=0x1050, product=0x0407 (Yubico YubiKey OTP+FIDO+CCID)\n"), nil
+		case fido2CredTool + " -M":
+			input := readInput()
+			if !*touched {
+				return nil, errors.New("fido2-cred: fido_dev_make_cred: FIDO_ERR_ACTION_TIMEOUT")
+			}
+			return writeOutput(input[0], input[1], "packed", "YXV0aGRhdGE=", "Y3JlZA==", "c2ln")
+		case fido2CredTool + " -V":
+			if input := readInput(); len(input) != 6 || input[1] != fido2RelyingParty {
+				return nil, fmt.Errorf("unexpected credential %q", input)
+			}
+			return writeOutput("Y3JlZA==", strings.TrimSuffix(fakeFIDO2PublicKey, "\n"))
+		case fido2AssertTool + " -G":
+			input := readInput()
+			if !*touched {
+				return nil, errors.New("fido2-assert: fido_dev_get_assert: FIDO_ERR_ACTION_TIMEOUT")
+			}
+			if input[2] != "Y3JlZA==" {
+				return nil, errors.New("fido2-assert: fido_dev_get_assert: FIDO_ERR_NO_CREDENTIALS")
+			}
+			return writeOutput(input[0], input[1], "YXV0aGRhdGE=", "c2lnLQ==")
+		case fido2AssertTool + " -V":
+			key, err := ioutil.ReadFile(args[len(args)-2])
+			if err != nil || string(key) != fakeFIDO2PublicKey {
+				return nil, fmt.Errorf("unexpected public key %q", key)
+			}
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unexpected command %s %v", name, args)
+	}
+}
+
+func TestProtect(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	config := newRedFederalCowHammerConfig()
+	config.Contexts["shaker-context"] = config.Contexts["federal-context"].DeepCopy()
+	if err := clientcmd.WriteToFile(config, fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	touched := true
+	protect := func(o *ProtectOptions) (string, error) {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		o.ConfigAccess, o.Context, o.RunCommand, o.IOStreams = pathOptions, "federal-context", fakeFIDO2(&touched), streams
+		if err := o.Validate(); err != nil {
+			return "", err
+		}
+		err := o.Run()
+		return out.String(), err
+	}
+	use := func(name string) (string, error) {
+		prompt := &bytes.Buffer{}
+		o := &UseContextOptions{ConfigAccess: pathOptions, ContextName: name, RunCommand: fakeFIDO2(&touched), Prompt: prompt}
+		err := o.Run()
+		return prompt.String(), err
+	}
+
+	if _, err := protect(&ProtectOptions{RequireFIDO2: true, Remove: true}); err == nil {
+		t.Errorf("expected --require-fido2 and --remove to conflict")
+	}
+	if out, err := protect(&ProtectOptions{}); err != nil || out != "none\n" {
+		t.Errorf("expected no protection, got %q, %v", out, err)
+	}
+	touched = false
+	if _, err := protect(&ProtectOptions{RequireFIDO2: true}); err == nil || !strings.Contains(err.Error(), "FIDO_ERR_ACTION_TIMEOUT") {
+		t.Errorf("expected an untouched key to fail, got %v", err)
+	}
+	touched = true
+	out, err := protect(&ProtectOptions{RequireFIDO2: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "Context \"federal-context\" requires a touch of the security key.\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+	if out, err := protect(&ProtectOptions{}); err != nil || out != "fido2\n" {
+		t.Errorf("expected the FIDO2 protection, got %q, %v", out, err)
+	}
+
+	touched = false
+	if _, err := use("federal-context"); err == nil || !strings.Contains(err.Error(), `context "federal-context" requires a touch of the security key`) {
+		t.Errorf("expected switching without a touch to fail, got %v", err)
+	}
+	if _, err := use("shaker-context"); err != nil {
+		t.Errorf("expected an unprotected context to need no touch, got %v", err)
+	}
+	touched = true
+	prompt, err := use("federal-context")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if prompt != "Touch the security key to use context \"federal-context\"...\n" {
+		t.Errorf("unexpected prompt %q", prompt)
+	}
+
+	if _, err := protect(&ProtectOptions{Remove: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	touched = false
+	if _, err := use("federal-context"); err != nil {
+		t.Errorf("expected the protection to be removed, got %v", err)
+	}
+}
+
+func TestFindFIDO2Device(t *testing.T) {
+	touched := false
+	device, err := findFIDO2Device(fakeFIDO2(&touched))
+	if err != nil || device != "/dev/hidraw0" {
+		t.Errorf("expected /dev/hidraw0, got %q, %v", device, err)
+	}
+	none := func(env []string, name string, args ...string) ([]byte, error) { return nil, nil }
+	if _, err := findFIDO2Device(none); err == nil || err.Error() != "no FIDO2 security key found" {
+		t.Errorf("expected no security key to be found, got %v", err)
+	}
+}
@@ -0,0 +1,230 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/printers"
+	"k8s.io/kubectl/pkg/util/templates"
+	"k8s.io/kubectl/pkg/version"
+)
+
+// provenance is stored in the provenanceExtension of the clusters, users and contexts created by an
+// import, telling where they come from.
+type provenance struct {
+	// Source is the kind of source, such as file, eks or kind.
+	Source string `json:"source"`
+	// Identity tells the source apart from the other sources of its kind, such as the path of a
+	// file or the region and name of an EKS cluster.
+	Identity string    `json:"identity,omitempty"`
+	Imported time.Time `json:"imported"`
+	// Version is the version of kubectl that imported the entry.
+	Version string `json:"version,omitempty"`
+}
+
+// stampProvenance records in every cluster, user and context of config that it was imported now
+// from the source of the given kind and identity.
+func stampProvenance(config *clientcmdapi.Config, source, identity string, now time.Time) error {
+	stamp := provenance{Source: source, Identity: identity, Imported: now.UTC(), Version: version.Get().GitVersion}
+	for _, cluster := range config.Clusters {
+		if err := writeExtension(&cluster.Extensions, provenanceExtension, stamp); err != nil {
+			return err
+		}
+	}
+	for _, authInfo := range config.AuthInfos {
+		if err := writeExtension(&authInfo.Extensions, provenanceExtension, stamp); err != nil {
+			return err
+		}
+	}
+	for _, context := range config.Contexts {
+		if err := writeExtension(&context.Extensions, provenanceExtension, stamp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keepProvenance carries the provenance of an existing entry over to the entry imported in its
+// place when both come from the same source, so that importing an unchanged entry again leaves it
+// identical. The entry keeps the time and version it was first imported with.
+func keepProvenance(existing map[string]runtime.Object, imported map[string]runtime.Object) {
+	previous, current := provenance{}, provenance{}
+	if found, err := readExtension(existing, provenanceExtension, &previous); !found || err != nil {
+		return
+	}
+	if found, err := readExtension(imported, provenanceExtension, &current); !found || err != nil {
+		return
+	}
+	if previous.Source == current.Source && previous.Identity == current.Identity {
+		imported[provenanceExtension] = existing[provenanceExtension]
+	}
+}
+
+// importedFrom reports whether an entry was imported from a source of the given kind.
+func importedFrom(extensions map[string]runtime.Object, source string) (bool, error) {
+	stamp := provenance{}
+	if _, err := readExtension(extensions, provenanceExtension, &stamp); err != nil {
+		return false, err
+	}
+	return stamp.Source == source, nil
+}
+
+// selectContextsFrom returns the sorted names of the contexts whose tags match the label selector
+// and, unless source is empty, that were imported from a source of that kind.
+func selectContextsFrom(config *clientcmdapi.Config, selector, source string) ([]string, error) {
+	names, err := selectContexts(config, selector)
+	if err != nil || len(source) == 0 {
+		return names, err
+	}
+	selected := []string{}
+	for _, name := range names {
+		ok, err := importedFrom(config.Contexts[name].Extensions, source)
+		if err != nil {
+			return nil, fmt.Errorf("context %q: %v", name, err)
+		}
+		if ok {
+			selected = append(selected, name)
+		}
+	}
+	return selected, nil
+}
+
+// ProvenanceOptions holds the command-line options for 'config provenance' sub command
+type ProvenanceOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	// Entry is a context, or a cluster or user as cluster/NAME or user/NAME.
+	Entry string
+
+	genericclioptions.IOStreams
+}
+
+var (
+	provenanceLong = templates.LongDesc(`
+		Show where kubeconfig entries come from.
+
+		The clusters, users and contexts created by "kubectl config import" and its subcommands,
+		"kubectl config pull", "kubectl config source sync" and "kubectl config init" record the kind
+		of source they were imported from, such as file, eks or kind, which source it was, when they
+		were imported and by which version of kubectl.
+
+		For a context, its cluster and user are shown as well. The kind of source selects contexts
+		in other commands with --from, such as "kubectl config get-contexts --from eks".`)
+
+	provenanceExample = templates.Examples(`
+		# Show where the current context, its cluster and user come from
+		kubectl config provenance
+
+		# Show where a cluster comes from
+		kubectl config provenance cluster/shop-prod
+
+		# List the contexts imported from Amazon EKS
+		kubectl config get-contexts --from eks`)
+)
+
+// NewCmdConfigProvenance returns a Command instance for 'config provenance' sub command
+func NewCmdConfigProvenance(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &ProvenanceOptions{ConfigAccess: configAccess, Entry: currentContextShorthand, IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "provenance [CONTEXT_NAME | cluster/NAME | user/NAME]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Show where kubeconfig entries come from"),
+		Long:                  provenanceLong,
+		Example:               provenanceExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) > 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			if len(args) == 1 {
+				o.Entry = args[0]
+			}
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	return noWriteCommand(cmd)
+}
+
+// Run prints the provenance of the entry
+func (o *ProvenanceOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+
+	type entry struct {
+		name       string
+		extensions map[string]runtime.Object
+	}
+	entries := []entry{}
+	switch {
+	case strings.HasPrefix(o.Entry, "cluster/"):
+		cluster, ok := config.Clusters[strings.TrimPrefix(o.Entry, "cluster/")]
+		if !ok {
+			return fmt.Errorf("no cluster exists with the name: %q", strings.TrimPrefix(o.Entry, "cluster/"))
+		}
+		entries = append(entries, entry{o.Entry, cluster.Extensions})
+	case strings.HasPrefix(o.Entry, "user/"):
+		authInfo, ok := config.AuthInfos[strings.TrimPrefix(o.Entry, "user/")]
+		if !ok {
+			return fmt.Errorf("no user exists with the name: %q", strings.TrimPrefix(o.Entry, "user/"))
+		}
+		entries = append(entries, entry{o.Entry, authInfo.Extensions})
+	default:
+		name, err := resolveContextName(config, o.Entry)
+		if err != nil {
+			return err
+		}
+		context, ok := config.Contexts[name]
+		if !ok {
+			return fmt.Errorf("no context exists with the name: %q", name)
+		}
+		entries = append(entries, entry{"context/" + name, context.Extensions})
+		if cluster, ok := config.Clusters[context.Cluster]; ok {
+			entries = append(entries, entry{"cluster/" + context.Cluster, cluster.Extensions})
+		}
+		if authInfo, ok := config.AuthInfos[context.AuthInfo]; ok {
+			entries = append(entries, entry{"user/" + context.AuthInfo, authInfo.Extensions})
+		}
+	}
+
+	w := printers.GetNewTabWriter(o.Out)
+	fmt.Fprintln(w, "ENTRY\tSOURCE\tIDENTITY\tIMPORTED\tVERSION")
+	for _, e := range entries {
+		stamp := provenance{}
+		found, err := readExtension(e.extensions, provenanceExtension, &stamp)
+		if err != nil {
+			return fmt.Errorf("%s: %v", e.name, err)
+		}
+		imported := ""
+		if found {
+			imported = stamp.Imported.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", e.name, valueOrNone(stamp.Source), valueOrNone(stamp.Identity), valueOrNone(imported), valueOrNone(stamp.Version))
+	}
+	return w.Flush()
+}
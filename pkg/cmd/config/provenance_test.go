@@ -0,0 +1,132 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/kubectl/pkg/version"
+)
+
+func newImportedConfig(name, source, identity string, now time.Time) (*clientcmdapi.Config, error) {
+	from := clientcmdapi.NewConfig()
+	from.Clusters[name] = &clientcmdapi.Cluster{Server: "https://" + name + ".example.com"}
+	from.AuthInfos[name] = &clientcmdapi.AuthInfo{Token: name + "-token"}
+	from.Contexts[name] = &clientcmdapi.Context{Cluster: name, AuthInfo: name}
+	return from, stampProvenance(from, source, identity, now)
+}
+
+func TestProvenance(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+
+	imported := time.Date(2019, 8, 2, 10, 0, 0, 0, time.UTC)
+	config := newRedFederalCowHammerConfig()
+	for _, entry := range []struct{ name, source, identity string }{
+		{"shop-prod", "eks", "eu-west-1/shop-prod"},
+		{"dev", "kind", "dev"},
+	} {
+		from, err := newImportedConfig(entry.name, entry.source, entry.identity, imported)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if added, _ := mergeImportedConfig(&config, from, false); added != 3 {
+			t.Errorf("expected 3 entries to be imported from %s, got %d", entry.source, added)
+		}
+	}
+
+	// Importing the same entries again later leaves them identical.
+	again, err := newImportedConfig("dev", "kind", "dev", imported.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if added, skipped := mergeImportedConfig(&config, again, false); added != 0 || len(skipped) != 0 {
+		t.Errorf("expected entries imported again to be identical, got %d added, skipped %v", added, skipped)
+	}
+	stamp := provenance{}
+	if _, err := readExtension(config.Contexts["dev"].Extensions, provenanceExtension, &stamp); err != nil || !stamp.Imported.Equal(imported) {
+		t.Errorf("expected the first import to be kept, got %+v, %v", stamp, err)
+	}
+
+	if err := clientcmd.WriteToFile(config, fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := &ProvenanceOptions{ConfigAccess: pathOptions, Entry: "shop-prod", IOStreams: streams}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v := version.Get().GitVersion
+	expected := `ENTRY               SOURCE   IDENTITY              IMPORTED               VERSION
+context/shop-prod   eks      eu-west-1/shop-prod   2019-08-02T10:00:00Z   ` + v + `
+cluster/shop-prod   eks      eu-west-1/shop-prod   2019-08-02T10:00:00Z   ` + v + `
+user/shop-prod      eks      eu-west-1/shop-prod   2019-08-02T10:00:00Z   ` + v + `
+`
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+
+	out.Reset()
+	o.Entry = "cluster/cow-cluster"
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected = `ENTRY                 SOURCE   IDENTITY   IMPORTED   VERSION
+cluster/cow-cluster   <none>   <none>     <none>     <none>
+`
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+	o.Entry = "user/missing"
+	if err := o.Run(); err == nil {
+		t.Errorf("expected a missing user to fail")
+	}
+
+	loaded, err := pathOptions.GetStartingConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if names, err := selectContextsFrom(loaded, "", "eks"); err != nil || !reflect.DeepEqual(names, []string{"shop-prod"}) {
+		t.Errorf("expected only the EKS context, got %v, %v", names, err)
+	}
+	if names, err := selectContextsFrom(loaded, "", ""); err != nil || len(names) != 3 {
+		t.Errorf("expected every context without --from, got %v, %v", names, err)
+	}
+
+	streams, _, out, _ = genericclioptions.NewTestIOStreams()
+	cmd := NewCmdConfigGetContexts(streams, pathOptions)
+	cmd.Flags().Set("output", "name")
+	cmd.Flags().Set("from", "kind")
+	cmd.Run(cmd, nil)
+	if out.String() != "dev\n" {
+		t.Errorf("expected only the kind context, got %q", out.String())
+	}
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// PullOptions holds the command-line options for 'config pull' sub command
+type PullOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Source       string
+	Name         string
+	Sudo         bool
+	Overwrite    bool
+
+	// RunSSH runs ssh, it is a field so tests can replace it.
+	RunSSH sshRunner
+	log    *cmdLogger
+
+	genericclioptions.IOStreams
+}
+
+var (
+	pullLong = templates.LongDesc(`
+		Import the kubeconfig of a remote host over SSH.
+
+		The source is ssh://[USER@]HOST[:PORT][/PATH], PATH being ~/.kube/config on the remote host
+		by default. Servers on the loopback address, like the one of the kubeconfig k3s writes to
+		/etc/rancher/k3s/k3s.yaml, are replaced by the address of the host. The current context of
+		the kubeconfig, with its cluster and user, is merged under a single name, HOST by default.
+		Entries whose name already exists are kept unless --overwrite is given.
+
+		With --sudo, the kubeconfig is read with sudo, for kubeconfigs only root can read.`)
+
+	pullExample = templates.Examples(`
+		# Import the kubeconfig of a freshly provisioned k3s node, named k3s-lab
+		kubectl config pull ssh://ubuntu@10.0.0.12/etc/rancher/k3s/k3s.yaml --sudo --name k3s-lab
+
+		# Import the kubeconfig of the bastion host
+		kubectl config pull ssh://bastion.example.com`)
+)
+
+// NewCmdConfigPull returns a Command instance for 'config pull' sub command
+func NewCmdConfigPull(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &PullOptions{ConfigAccess: configAccess, RunSSH: runSSH, IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "pull ssh://[USER@]HOST[:PORT][/PATH] [--name=NAME] [--sudo] [--overwrite]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Import the kubeconfig of a remote host over SSH"),
+		Long:                  pullLong,
+		Example:               pullExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.Source = args[0]
+			cmdutil.CheckErr(requireNetwork(cmd))
+			var err error
+			o.log, err = newCmdLogger(cmd, o.ErrOut)
+			cmdutil.CheckErr(err)
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().StringVar(&o.Name, "name", o.Name, "Name of the imported entries. Defaults to the host")
+	cmd.Flags().BoolVar(&o.Sudo, "sudo", o.Sudo, "If true, read the kubeconfig with sudo on the remote host")
+	cmd.Flags().BoolVar(&o.Overwrite, "overwrite", o.Overwrite, "If true, replace existing entries of the same name")
+	return cmd
+}
+
+// Run performs the execution of 'config pull' sub command
+func (o *PullOptions) Run() error {
+	from, host, err := o.fetch()
+	if err != nil {
+		return err
+	}
+	name := o.Name
+	if len(name) == 0 {
+		name = host
+	}
+	if err := stampProvenance(from, "ssh", pullIdentity(o.Source, o.Sudo), time.Now()); err != nil {
+		return err
+	}
+	return importClusterEntries(o.ConfigAccess, from, name, o.Overwrite, o.log, o.IOStreams)
+}
+
+// pullIdentity is the provenance identity of a pulled kubeconfig: its target, with sudo=true in the
+// query when it was read with sudo, so that "config import --sync" reads it the same way.
+func pullIdentity(source string, sudo bool) string {
+	if !sudo {
+		return source
+	}
+	return source + "?sudo=true"
+}
+
+// parsePullIdentity returns the target of a pulled kubeconfig and whether it was read with sudo.
+func parsePullIdentity(identity string) (string, bool, error) {
+	u, err := url.Parse(identity)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid source %q: %v", identity, err)
+	}
+	sudo := u.Query().Get("sudo") == "true"
+	u.RawQuery = ""
+	return u.String(), sudo, nil
+}
+
+// fetch reads the remote kubeconfig, pointing its loopback servers at the remote host. It returns
+// the kubeconfig and the remote host.
+func (o *PullOptions) fetch() (*clientcmdapi.Config, string, error) {
+	sshArgs, remotePath, err := parseSSHTarget(o.Source)
+	if err != nil {
+		return nil, "", err
+	}
+	read := "cat " + shellQuote(remotePath)
+	if o.Sudo {
+		read = "sudo " + read
+	}
+	data, err := o.RunSSH(append(sshArgs, read), nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("reading %s on the remote host: %v", remotePath, err)
+	}
+	from, err := clientcmd.Load(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("the remote kubeconfig %s is invalid: %v", remotePath, err)
+	}
+
+	// parseSSHTarget validated the URL.
+	u, _ := url.Parse(o.Source)
+	host := u.Hostname()
+	for name, cluster := range from.Clusters {
+		if !isLoopbackServer(cluster.Server) {
+			continue
+		}
+		server, err := replaceServerHost(cluster.Server, host)
+		if err != nil {
+			return nil, "", fmt.Errorf("cluster %q: %v", name, err)
+		}
+		cluster.Server = server
+	}
+	return from, host, nil
+}
+
+// isLoopbackServer returns whether a server URL is on localhost or a loopback or unspecified address,
+// which only work from the host the kubeconfig was written on.
+func isLoopbackServer(server string) bool {
+	u, err := url.Parse(server)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsUnspecified())
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func TestPull(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	if err := clientcmd.WriteToFile(newRedFederalCowHammerConfig(), fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	outputs := map[string]string{
+		"ubuntu@10.0.0.12 sudo cat '/etc/rancher/k3s/k3s.yaml'": localClusterKubeconfig("default", "https://127.0.0.1:6443"),
+		"-p 2222 bastion cat '.kube/config'":                    localClusterKubeconfig("prod", "https://api.prod.example.com"),
+	}
+	run := func(args []string, stdin []byte) ([]byte, error) {
+		command := strings.Join(args, " ")
+		output, ok := outputs[command]
+		if !ok {
+			return nil, fmt.Errorf("unexpected command %q", command)
+		}
+		return []byte(output), nil
+	}
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := &PullOptions{ConfigAccess: pathOptions, Source: "ssh://ubuntu@10.0.0.12/etc/rancher/k3s/k3s.yaml", Name: "k3s-lab", Sudo: true, RunSSH: run, IOStreams: streams}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "Imported 3 entries as \"k3s-lab\".\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+
+	streams, _, out, _ = genericclioptions.NewTestIOStreams()
+	o = &PullOptions{ConfigAccess: pathOptions, Source: "ssh://bastion:2222", RunSSH: run, IOStreams: streams}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "Imported 3 entries as \"bastion\".\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+
+	config, err := clientcmd.LoadFromFile(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cluster := config.Clusters["k3s-lab"]; cluster == nil || cluster.Server != "https://10.0.0.12:6443" {
+		t.Errorf("expected the loopback server to be replaced by the host, got %v", cluster)
+	}
+	if cluster := config.Clusters["bastion"]; cluster == nil || cluster.Server != "https://api.prod.example.com" {
+		t.Errorf("expected the server to be kept, got %v", cluster)
+	}
+
+	// Syncing reads each kubeconfig the way it was pulled, with sudo only for the one pulled with
+	// --sudo: run fails for any other command.
+	streams, _, out, _ = genericclioptions.NewTestIOStreams()
+	sync := &ImportOptions{ConfigAccess: pathOptions, Sync: true, From: "ssh", RunSSH: run, IOStreams: streams}
+	if err := sync.Complete(nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := sync.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "Synced 2 source(s)") {
+		t.Errorf("unexpected output %q", out.String())
+	}
+}
+
+func TestIsLoopbackServer(t *testing.T) {
+	tests := map[string]bool{
+		"https://127.0.0.1:6443":      true,
+		"https://localhost:6443":      true,
+		"https://[::1]:6443":          true,
+		"https://0.0.0.0:6443":        true,
+		"https://10.0.0.12:6443":      false,
+		"https://api.example.com:443": false,
+	}
+	for server, expected := range tests {
+		if isLoopbackServer(server) != expected {
+			t.Errorf("expected %q loopback: %v", server, expected)
+		}
+	}
+}
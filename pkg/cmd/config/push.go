@@ -0,0 +1,227 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// defaultRemoteKubeconfig is the kubeconfig written on the remote host when the URL has no path,
+// relative to the home directory ssh starts in.
+const defaultRemoteKubeconfig = ".kube/config"
+
+// sshRunner runs ssh with args, feeding it stdin, and returns its standard output.
+type sshRunner func(args []string, stdin []byte) ([]byte, error)
+
+// PushOptions holds the command-line options for 'config push' sub command
+type PushOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Target       string
+	Contexts     []string
+	Selector     string
+
+	// RunSSH runs ssh, it is a field so tests can replace it.
+	RunSSH sshRunner
+
+	genericclioptions.IOStreams
+}
+
+var (
+	pushLong = templates.LongDesc(`
+		Copy contexts to the kubeconfig of a remote host over SSH.
+
+		The contexts, the current-context by default, are minified and flattened like with "kubectl
+		config export", so that they work without the files they refer to here, and merged into the
+		kubeconfig of the remote host, replacing the entries of the same name. The remote
+		current-context is only set when the remote kubeconfig has none. This is a quick way to
+		provision a bastion host with the credentials needed there.
+
+		The target is ssh://[USER@]HOST[:PORT][/PATH], PATH being ~/.kube/config on the remote host
+		by default. ssh is run with the configuration of the user, so aliases of ~/.ssh/config and
+		the SSH agent work as usual.`)
+
+	pushExample = templates.Examples(`
+		# Copy the current-context to the bastion host
+		kubectl config push ssh://bastion.example.com
+
+		# Copy two contexts as the ops user, on port 2222
+		kubectl config push ssh://ops@bastion.example.com:2222 prod-eu prod-us
+
+		# Copy the contexts tagged prod to another kubeconfig of the remote host
+		kubectl config push ssh://bastion/etc/kubernetes/admin.conf -l prod`)
+)
+
+// NewCmdConfigPush returns a Command instance for 'config push' sub command
+func NewCmdConfigPush(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &PushOptions{ConfigAccess: configAccess, RunSSH: runSSH, IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "push ssh://[USER@]HOST[:PORT][/PATH] [CONTEXT_NAME...] [--selector=SELECTOR]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Copy contexts to the kubeconfig of a remote host over SSH"),
+		Long:                  pushLong,
+		Example:               pushExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	cmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, "Selector (label query) on the tags and annotations of contexts, see 'config get-contexts'")
+	return cmd
+}
+
+// Complete sets the target and the contexts from the arguments
+func (o *PushOptions) Complete(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return helpErrorf(cmd, "Unexpected args: %v", args)
+	}
+	o.Target, o.Contexts = args[0], args[1:]
+	return requireNetwork(cmd)
+}
+
+// Validate makes sure the target is an ssh URL and contexts are not both named and selected
+func (o *PushOptions) Validate() error {
+	if _, _, err := parseSSHTarget(o.Target); err != nil {
+		return err
+	}
+	if len(o.Contexts) > 0 && len(o.Selector) > 0 {
+		return errors.New("contexts cannot be named together with --selector")
+	}
+	return nil
+}
+
+// Run merges the contexts into the remote kubeconfig
+func (o *PushOptions) Run() error {
+	sshArgs, remotePath, err := parseSSHTarget(o.Target)
+	if err != nil {
+		return err
+	}
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	contexts := o.Contexts
+	switch {
+	case len(o.Selector) > 0:
+		if contexts, err = selectContexts(config, o.Selector); err != nil {
+			return err
+		}
+		if len(contexts) == 0 {
+			return fmt.Errorf("no context matches the selector %q", o.Selector)
+		}
+	case len(contexts) == 0:
+		contexts = []string{currentContextShorthand}
+	}
+
+	pushed := clientcmdapi.NewConfig()
+	names := []string{}
+	for _, name := range contexts {
+		exported, err := exportContext(config, name, false)
+		if err != nil {
+			return err
+		}
+		mergeImportedConfig(pushed, exported, true)
+		names = append(names, exported.CurrentContext)
+	}
+
+	quotedPath := shellQuote(remotePath)
+	data, err := o.RunSSH(append(sshArgs, fmt.Sprintf("if [ -f %s ]; then cat %s; fi", quotedPath, quotedPath)), nil)
+	if err != nil {
+		return fmt.Errorf("reading %s on the remote host: %v", remotePath, err)
+	}
+	remote := clientcmdapi.NewConfig()
+	if len(bytes.TrimSpace(data)) > 0 {
+		if remote, err = clientcmd.Load(data); err != nil {
+			return fmt.Errorf("the remote kubeconfig %s is invalid: %v", remotePath, err)
+		}
+	}
+	merged, _ := mergeImportedConfig(remote, pushed, true)
+	if len(remote.CurrentContext) == 0 {
+		remote.CurrentContext = names[0]
+	}
+
+	if data, err = clientcmd.Write(*remote); err != nil {
+		return err
+	}
+	dir := path.Dir(remotePath)
+	temp := shellQuote(remotePath + ".tmp")
+	write := fmt.Sprintf("mkdir -p %s && umask 077 && cat > %s && mv %s %s", shellQuote(dir), temp, temp, quotedPath)
+	if _, err := o.RunSSH(append(sshArgs, write), data); err != nil {
+		return fmt.Errorf("writing %s on the remote host: %v", remotePath, err)
+	}
+	fmt.Fprintf(o.Out, "Pushed %d context(s) to %s, %d entries merged into %s.\n", len(names), o.Target, merged, remotePath)
+	return nil
+}
+
+// parseSSHTarget returns the arguments of ssh reaching the host of an ssh URL, and the path of the
+// remote kubeconfig.
+func parseSSHTarget(target string) ([]string, string, error) {
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme != "ssh" || len(u.Hostname()) == 0 || len(u.RawQuery) > 0 {
+		return nil, "", fmt.Errorf("invalid target %q, must be ssh://[USER@]HOST[:PORT][/PATH]", target)
+	}
+	destination := u.Hostname()
+	if u.User != nil {
+		destination = u.User.Username() + "@" + destination
+	}
+	if strings.HasPrefix(destination, "-") {
+		return nil, "", fmt.Errorf("invalid host in target %q", target)
+	}
+	args := []string{}
+	if len(u.Port()) > 0 {
+		args = append(args, "-p", u.Port())
+	}
+	args = append(args, destination)
+
+	remotePath := u.Path
+	if len(remotePath) == 0 || remotePath == "/" {
+		remotePath = defaultRemoteKubeconfig
+	}
+	return args, remotePath, nil
+}
+
+// runSSH runs ssh, returning an error including its standard error when it fails.
+func runSSH(args []string, stdin []byte) ([]byte, error) {
+	cmd := exec.Command("ssh", args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); len(message) > 0 {
+			return nil, fmt.Errorf("%v: %s", err, message)
+		}
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestParseSSHTarget(t *testing.T) {
+	tests := []struct {
+		target       string
+		expectedArgs []string
+		expectedPath string
+		expectedErr  bool
+	}{
+		{target: "ssh://bastion", expectedArgs: []string{"bastion"}, expectedPath: ".kube/config"},
+		{target: "ssh://ops@bastion:2222/", expectedArgs: []string{"-p", "2222", "ops@bastion"}, expectedPath: ".kube/config"},
+		{target: "ssh://bastion/etc/kubernetes/admin.conf", expectedArgs: []string{"bastion"}, expectedPath: "/etc/kubernetes/admin.conf"},
+		{target: "bastion", expectedErr: true},
+		{target: "https://bastion", expectedErr: true},
+		{target: "ssh://-oProxyCommand=x", expectedErr: true},
+	}
+	for _, tt := range tests {
+		args, remotePath, err := parseSSHTarget(tt.target)
+		if tt.expectedErr {
+			if err == nil {
+				t.Errorf("expected %q to be invalid", tt.target)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(args, tt.expectedArgs) || remotePath != tt.expectedPath {
+			t.Errorf("expected %v and %q for %q, got %v and %q", tt.expectedArgs, tt.expectedPath, tt.target, args, remotePath)
+		}
+	}
+}
+
+func TestPush(t *testing.T) {
+	config := newRedFederalCowHammerConfig()
+	config.Contexts["other-context"] = &clientcmdapi.Context{AuthInfo: "blue-user", Cluster: "pig-cluster"}
+	config.AuthInfos["blue-user"] = &clientcmdapi.AuthInfo{Token: "blue-token"}
+	config.Clusters["pig-cluster"] = &clientcmdapi.Cluster{Server: "http://pig.org:8080"}
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	if err := clientcmd.WriteToFile(config, fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	remote := clientcmdapi.NewConfig()
+	remote.Contexts["bastion-context"] = &clientcmdapi.Context{Cluster: "local"}
+	remote.Contexts["federal-context"] = &clientcmdapi.Context{Cluster: "stale"}
+	remote.CurrentContext = "bastion-context"
+	remoteData, err := clientcmd.Write(*remote)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var written []byte
+	commands := []string{}
+	runSSH := func(args []string, stdin []byte) ([]byte, error) {
+		if args[0] != "ops@bastion" {
+			t.Errorf("unexpected ssh args %v", args)
+		}
+		commands = append(commands, args[len(args)-1])
+		if stdin != nil {
+			written = stdin
+			return nil, nil
+		}
+		return remoteData, nil
+	}
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := &PushOptions{ConfigAccess: pathOptions, Target: "ssh://ops@bastion", RunSSH: runSSH, IOStreams: streams}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "Pushed 1 context(s) to ssh://ops@bastion, 3 entries merged into .kube/config.\n"; out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+	expectedCommands := []string{
+		"if [ -f '.kube/config' ]; then cat '.kube/config'; fi",
+		"mkdir -p '.kube' && umask 077 && cat > '.kube/config.tmp' && mv '.kube/config.tmp' '.kube/config'",
+	}
+	if !reflect.DeepEqual(commands, expectedCommands) {
+		t.Errorf("expected commands %q, got %q", expectedCommands, commands)
+	}
+
+	pushed, err := clientcmd.Load(written)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pushed.CurrentContext != "bastion-context" {
+		t.Errorf("expected the remote current-context to be kept, got %q", pushed.CurrentContext)
+	}
+	if context := pushed.Contexts["federal-context"]; context == nil || context.Cluster != "cow-cluster" {
+		t.Errorf("expected the remote context to be replaced, got %v", context)
+	}
+	if _, ok := pushed.Contexts["other-context"]; ok {
+		t.Errorf("expected only the current-context to be pushed")
+	}
+	if _, ok := pushed.Contexts["bastion-context"]; !ok {
+		t.Errorf("expected the remote contexts to be kept")
+	}
+
+	// An empty remote kubeconfig gets the first context pushed as current-context.
+	remoteData = nil
+	o.Contexts = []string{"other-context", "federal-context"}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pushed, err = clientcmd.Load(written); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pushed.CurrentContext != "other-context" || len(pushed.Contexts) != 2 {
+		t.Errorf("unexpected remote kubeconfig %v", pushed)
+	}
+
+	o.Contexts = []string{"missing"}
+	if err := o.Run(); err == nil || !strings.Contains(err.Error(), "no context exists") {
+		t.Errorf("expected a missing context to fail, got %v", err)
+	}
+}
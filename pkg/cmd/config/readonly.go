@@ -0,0 +1,248 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// readOnly is stored in a context's readOnlyExtension.
+type readOnly struct {
+	Enabled bool `json:"enabled"`
+}
+
+// readVerbs are the kubectl commands allowed against a read-only context. A nil list allows every
+// subcommand, otherwise only the listed subcommands are allowed. Every other command, including
+// exec, attach, port-forward, proxy and plugins, is refused.
+var readVerbs = map[string][]string{
+	"api-resources": nil,
+	"api-versions":  nil,
+	"apply":         {"view-last-applied"},
+	"auth":          {"can-i"},
+	"cluster-info":  nil,
+	"describe":      nil,
+	"diff":          nil,
+	"explain":       nil,
+	"get":           nil,
+	"logs":          nil,
+	"rollout":       {"history", "status"},
+	"top":           nil,
+	"version":       nil,
+}
+
+// ReadOnlyOptions holds the command-line options for 'config readonly' sub command
+type ReadOnlyOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Context      string
+	// State is on, off, or empty to print whether the context is read-only.
+	State string
+	// Confirm asks before a context the confirm setting covers is made writable.
+	Confirm changeConfirmer
+
+	genericclioptions.IOStreams
+}
+
+var (
+	readOnlyLong = templates.LongDesc(`
+		Make a context read-only, or writable again.
+
+		Only kubectl commands that read from the cluster, such as get, describe, logs, top or diff,
+		are run by "kubectl config exec" against a read-only context. Every other command, including
+		exec, port-forward and plugins, is refused before kubectl is started, as well as command
+		lines where a flag that is not a global kubectl flag comes before the command. This is a
+		local safety net against changing production by mistake, not access control: kubectl run
+		directly, or other clients, are not affected.
+
+		Without on or off, prints whether the context is read-only.`)
+
+	readOnlyExample = templates.Examples(`
+		# Only allow reading commands against prod
+		kubectl config readonly prod on
+
+		# Show whether the current-context is read-only
+		kubectl config readonly .
+
+		# Allow changes to prod again
+		kubectl config readonly prod off`)
+)
+
+// NewCmdConfigReadOnly returns a Command instance for 'config readonly' sub command
+func NewCmdConfigReadOnly(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &ReadOnlyOptions{ConfigAccess: configAccess, IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "readonly CONTEXT_NAME [on|off]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Only allow reading kubectl commands against a context"),
+		Long:                  readOnlyLong,
+		Example:               readOnlyExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	return cmd
+}
+
+// Complete sets the context and the state from the arguments
+func (o *ReadOnlyOptions) Complete(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return helpErrorf(cmd, "Unexpected args: %v", args)
+	}
+	o.Context = args[0]
+	o.Confirm = newChangeConfirmer(cmd, o.ConfigAccess, o.In, o.ErrOut)
+	if len(args) == 2 {
+		o.State = args[1]
+		return checkWritingArgs(cmd)
+	}
+	return nil
+}
+
+// Validate makes sure the state is on or off
+func (o *ReadOnlyOptions) Validate() error {
+	switch o.State {
+	case "", "on", "off":
+		return nil
+	}
+	return fmt.Errorf("the state must be on or off, got %q", o.State)
+}
+
+// Run prints or sets whether the context is read-only
+func (o *ReadOnlyOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	name, err := resolveContextName(config, o.Context)
+	if err != nil {
+		return err
+	}
+	context, ok := config.Contexts[name]
+	if !ok {
+		return fmt.Errorf("no context exists with the name: %q", name)
+	}
+
+	if len(o.State) == 0 {
+		enabled, err := isReadOnly(context)
+		if err != nil {
+			return err
+		}
+		if enabled {
+			fmt.Fprintln(o.Out, "on")
+		} else {
+			fmt.Fprintln(o.Out, "off")
+		}
+		return nil
+	}
+
+	if o.State == "on" {
+		err = writeExtension(&context.Extensions, readOnlyExtension, readOnly{Enabled: true})
+	} else if err = o.Confirm.check([]string{name}); err == nil {
+		delete(context.Extensions, readOnlyExtension)
+	}
+	if err != nil {
+		return err
+	}
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+	if o.State == "on" {
+		fmt.Fprintf(o.Out, "Context %q is read-only.\n", name)
+	} else {
+		fmt.Fprintf(o.Out, "Context %q is writable.\n", name)
+	}
+	return nil
+}
+
+// isReadOnly reports whether a context is read-only.
+func isReadOnly(context *clientcmdapi.Context) (bool, error) {
+	value := readOnly{}
+	_, err := readExtension(context.Extensions, readOnlyExtension, &value)
+	return value.Enabled, err
+}
+
+// guardKubectlCommand returns an error when a kubectl command line is not known to only read from
+// the cluster of a read-only context.
+func guardKubectlCommand(name string, context *clientcmdapi.Context, args []string) error {
+	enabled, err := isReadOnly(context)
+	if err != nil || !enabled {
+		return err
+	}
+	refuseFlag := func(err error) error {
+		return fmt.Errorf("context %q is read-only, refusing to run \"kubectl %s\": %v; run \"kubectl config readonly %s off\" to allow changes",
+			name, strings.Join(args, " "), err, name)
+	}
+	commands, err := kubectlCommandWords(args, 1)
+	if err != nil {
+		return refuseFlag(err)
+	}
+	if len(commands) == 0 {
+		return nil
+	}
+	readSubcommands, read := readVerbs[commands[0]]
+	if read && readSubcommands == nil {
+		return nil
+	}
+	if read {
+		// The subcommand is found the same way, so that a flag cannot hide it either.
+		words, err := kubectlCommandWords(args, 2)
+		if err != nil {
+			return refuseFlag(err)
+		}
+		if len(words) > 1 && containsString(readSubcommands, words[1]) {
+			return nil
+		}
+	}
+	return fmt.Errorf("context %q is read-only, refusing to run \"kubectl %s\"; run \"kubectl config readonly %s off\" to allow changes",
+		name, commands[0], name)
+}
+
+// kubectlCommandWords returns the first max arguments of a kubectl command line that are neither
+// global flags nor their values. Any other flag before them fails, because whether it takes the
+// next argument as its value, and so which command runs, is only known to kubectl.
+func kubectlCommandWords(args []string, max int) ([]string, error) {
+	commands := []string{}
+	for i := 0; i < len(args) && len(commands) < max; i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			commands = append(commands, arg)
+			continue
+		}
+		name, takesValue := globalFlag(arg)
+		if len(name) == 0 {
+			return nil, fmt.Errorf("%s comes before the command and is not a global kubectl flag", arg)
+		}
+		if flagTakesSeparateValue(arg, takesValue, true) {
+			i++
+		}
+	}
+	return commands, nil
+}
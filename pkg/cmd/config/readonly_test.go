@@ -0,0 +1,124 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestReadOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "readonly")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := clientcmd.WriteToFile(newRedFederalCowHammerConfig(), filepath.Join(dir, "config")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = filepath.Join(dir, "config")
+	pathOptions.EnvVar = ""
+
+	run := func(state string) (string, error) {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		o := &ReadOnlyOptions{ConfigAccess: pathOptions, Context: ".", State: state, IOStreams: streams}
+		if err := o.Validate(); err != nil {
+			return "", err
+		}
+		err := o.Run()
+		return out.String(), err
+	}
+
+	if out, err := run(""); err != nil || out != "off\n" {
+		t.Errorf("expected the context to be writable, got %q, %v", out, err)
+	}
+	if out, err := run("on"); err != nil || out != "Context \"federal-context\" is read-only.\n" {
+		t.Errorf("unexpected output %q, %v", out, err)
+	}
+	if out, err := run(""); err != nil || out != "on\n" {
+		t.Errorf("expected the context to be read-only, got %q, %v", out, err)
+	}
+	if _, err := run("yes"); err == nil {
+		t.Errorf("expected an invalid state to fail")
+	}
+	if out, err := run("off"); err != nil || out != "Context \"federal-context\" is writable.\n" {
+		t.Errorf("unexpected output %q, %v", out, err)
+	}
+	config, err := clientcmd.LoadFromFile(pathOptions.GlobalFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(config.Contexts["federal-context"].Extensions) != 0 {
+		t.Errorf("expected the extension to be removed, got %v", config.Contexts["federal-context"].Extensions)
+	}
+}
+
+func TestGuardKubectlCommand(t *testing.T) {
+	context := &clientcmdapi.Context{}
+	if err := writeExtension(&context.Extensions, readOnlyExtension, readOnly{Enabled: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		args    []string
+		refused bool
+	}{
+		{args: []string{"get", "pods"}},
+		{args: []string{"-n", "shop", "describe", "deployment", "web"}},
+		{args: []string{"rollout", "status", "deployment/web"}},
+		{args: []string{"apply", "view-last-applied", "deployment/web"}},
+		{args: []string{"auth", "can-i", "delete", "pods"}},
+		{args: []string{"logs", "-f", "web"}},
+		{args: []string{"exec", "web", "--", "rm", "-rf", "/tmp/cache"}, refused: true},
+		{args: []string{"attach", "web"}, refused: true},
+		{args: []string{"port-forward", "web", "8080"}, refused: true},
+		{args: []string{"debug", "web"}, refused: true},
+		{args: []string{"proxy"}, refused: true},
+		{args: []string{"auth", "reconcile", "-f", "rbac.yaml"}, refused: true},
+		{args: []string{"apply", "-k", "overlays/prod"}, refused: true},
+		{args: []string{"some-plugin", "sync"}, refused: true},
+		{args: []string{"delete", "pod", "web"}, refused: true},
+		{args: []string{"--namespace", "delete", "apply", "-f", "web.yaml"}, refused: true},
+		{args: []string{"-v", "4", "scale", "--replicas=0", "deployment/web"}, refused: true},
+		{args: []string{"rollout", "restart", "deployment/web"}, refused: true},
+		{args: []string{"patch", "deployment", "web", "-p", "{}"}, refused: true},
+		{args: []string{"-l", "get", "delete", "pods"}, refused: true},
+		{args: []string{"rollout", "-l", "status", "restart", "deployment/web"}, refused: true},
+		{args: []string{"get", "-o", "yaml", "pods"}},
+	}
+	for _, tt := range tests {
+		err := guardKubectlCommand("prod", context, tt.args)
+		if tt.refused && (err == nil || !strings.Contains(err.Error(), `context "prod" is read-only`)) {
+			t.Errorf("expected %v to be refused, got %v", tt.args, err)
+		}
+		if !tt.refused && err != nil {
+			t.Errorf("expected %v to be allowed, got %v", tt.args, err)
+		}
+	}
+
+	if err := guardKubectlCommand("dev", &clientcmdapi.Context{}, []string{"delete", "pod", "web"}); err != nil {
+		t.Errorf("expected a writable context to allow everything, got %v", err)
+	}
+}
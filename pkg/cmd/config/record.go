@@ -0,0 +1,376 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// switchRecording is the sequence of context and namespace switches "config record" captures.
+type switchRecording struct {
+	Started  time.Time     `json:"started"`
+	Switches []switchEvent `json:"switches"`
+}
+
+// switchEvent is the current-context, and its namespace, from Time on.
+type switchEvent struct {
+	Time      time.Time `json:"time"`
+	Context   string    `json:"context"`
+	Namespace string    `json:"namespace,omitempty"`
+}
+
+// recordingFile returns the file holding the recording in progress, if any.
+func recordingFile() string {
+	return filepath.Join(stateDir(), "recording.json")
+}
+
+// readRecording returns the recording in filename, nil when there is none.
+func readRecording(filename string) (*switchRecording, error) {
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	recording := &switchRecording{}
+	if err := json.Unmarshal(data, recording); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return recording, nil
+}
+
+func writeRecording(filename string, recording *switchRecording) error {
+	data, err := json.MarshalIndent(recording, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, append(data, '\n'), 0600)
+}
+
+// recordSwitch appends the current-context and its namespace to the recording in progress when
+// they changed since the last switch recorded. It runs after every config subcommand.
+func recordSwitch(filename string, configAccess clientcmd.ConfigAccess, now time.Time) error {
+	recording, err := readRecording(filename)
+	if err != nil || recording == nil {
+		return err
+	}
+	current, err := currentSwitch(configAccess, now)
+	if err != nil {
+		return err
+	}
+	if n := len(recording.Switches); n > 0 {
+		last := recording.Switches[n-1]
+		if last.Context == current.Context && last.Namespace == current.Namespace {
+			return nil
+		}
+	}
+	recording.Switches = append(recording.Switches, current)
+	return writeRecording(filename, recording)
+}
+
+// currentSwitch returns the current-context and its namespace.
+func currentSwitch(configAccess clientcmd.ConfigAccess, now time.Time) (switchEvent, error) {
+	config, err := configAccess.GetStartingConfig()
+	if err != nil {
+		return switchEvent{}, err
+	}
+	event := switchEvent{Time: now, Context: config.CurrentContext}
+	if context, ok := config.Contexts[config.CurrentContext]; ok {
+		event.Namespace = context.Namespace
+	}
+	return event, nil
+}
+
+// RecordOptions holds the command-line options for 'config record' sub commands
+type RecordOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Filename     string
+	Output       string
+	Now          func() time.Time
+
+	genericclioptions.IOStreams
+}
+
+// ReplayOptions holds the command-line options for 'config replay' sub command
+type ReplayOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Filename     string
+	Speed        float64
+	Step         bool
+	Acknowledge  bool
+	Cooloff      *cooloffPolicy
+	Sleep        func(time.Duration)
+	// RunCommand runs the libfido2 tools for the contexts protected with "config protect".
+	RunCommand commandRunner
+
+	genericclioptions.IOStreams
+}
+
+var (
+	recordLong = templates.LongDesc(`
+		Record the context and namespace switches made with the config subcommands, to replay
+		them later with "kubectl config replay".
+
+		"record start" notes the current-context and its namespace, and every config subcommand
+		run afterwards, such as use-context or set-context --namespace, records the switches it
+		made with their time. "record stop" ends the recording and writes it to the file given, or
+		prints it. Switches made by other tools are recorded the next time a config subcommand
+		runs.`)
+
+	recordExample = templates.Examples(`
+		# Record the switches of a multi-cluster runbook
+		kubectl config record start
+		kubectl config use-context staging
+		kubectl config set-context --current --namespace=web
+		kubectl config use-context prod
+		kubectl config record stop runbook.json`)
+
+	replayLong = templates.LongDesc(`
+		Replay the context and namespace switches recorded with "kubectl config record".
+
+		The switches are made in order, waiting between them as long as when they were recorded,
+		divided by --speed; --speed=0 does not wait. With --step, every switch waits for Enter
+		instead, to walk through a runbook or a demo. Contexts the cooloff setting applies to
+		need --acknowledge, as with "kubectl config use-context", and the security key of the
+		contexts protected with "kubectl config protect" is touched before the first switch.`)
+
+	replayExample = templates.Examples(`
+		# Walk through the switches of a runbook, one Enter at a time
+		kubectl config replay runbook.json --step
+
+		# Replay them twice as fast as they were recorded
+		kubectl config replay runbook.json --speed=2`)
+)
+
+// NewCmdConfigRecord returns a Command instance for 'config record' sub commands
+func NewCmdConfigRecord(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &RecordOptions{ConfigAccess: configAccess, Filename: recordingFile(), Now: time.Now, IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "record SUBCOMMAND",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Record context and namespace switches to replay them"),
+		Long:                  recordLong,
+		Example:               recordExample,
+		Run:                   cmdutil.DefaultSubCommandRun(streams.ErrOut),
+	}
+
+	startCmd := &cobra.Command{
+		Use:   "start",
+		Short: i18n.T("Start recording context and namespace switches"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			cmdutil.CheckErr(o.RunStart())
+		},
+	}
+	stopCmd := &cobra.Command{
+		Use:   "stop [FILE]",
+		Short: i18n.T("Stop recording and write the switches recorded to FILE, or print them"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) > 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.Output = ""
+			if len(args) == 1 {
+				o.Output = args[0]
+			}
+			cmdutil.CheckErr(o.RunStop())
+		},
+	}
+
+	cmd.AddCommand(startCmd)
+	cmd.AddCommand(stopCmd)
+	return cmd
+}
+
+// RunStart starts a recording with the current switch
+func (o *RecordOptions) RunStart() error {
+	recording, err := readRecording(o.Filename)
+	if err != nil {
+		return err
+	}
+	if recording != nil {
+		return fmt.Errorf("a recording started at %s is in progress, stop it with \"kubectl config record stop\"", recording.Started.Format(time.RFC3339))
+	}
+	now := o.Now()
+	current, err := currentSwitch(o.ConfigAccess, now)
+	if err != nil {
+		return err
+	}
+	if err := writeRecording(o.Filename, &switchRecording{Started: now, Switches: []switchEvent{current}}); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "Recording context switches, starting from context %q.\n", current.Context)
+	return nil
+}
+
+// RunStop ends the recording and writes it out
+func (o *RecordOptions) RunStop() error {
+	// The last switch may have been made by another tool.
+	if err := recordSwitch(o.Filename, o.ConfigAccess, o.Now()); err != nil {
+		return err
+	}
+	recording, err := readRecording(o.Filename)
+	if err != nil {
+		return err
+	}
+	if recording == nil {
+		return errors.New("no recording is in progress, start one with \"kubectl config record start\"")
+	}
+	data, err := json.MarshalIndent(recording, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if len(o.Output) == 0 {
+		if _, err := o.Out.Write(data); err != nil {
+			return err
+		}
+	} else {
+		if err := ioutil.WriteFile(o.Output, data, 0600); err != nil {
+			return err
+		}
+		fmt.Fprintf(o.Out, "Recorded %d switch(es) to %s.\n", len(recording.Switches)-1, o.Output)
+	}
+	return os.Remove(o.Filename)
+}
+
+// NewCmdConfigReplay returns a Command instance for 'config replay' sub command
+func NewCmdConfigReplay(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &ReplayOptions{ConfigAccess: configAccess, Speed: 1, Sleep: time.Sleep, RunCommand: runCommand, IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "replay FILE [--step] [--speed=FACTOR]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Replay the context and namespace switches of a recording"),
+		Long:                  replayLong,
+		Example:               replayExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.Filename = args[0]
+			cmdutil.CheckErr(o.Complete())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	cmd.Flags().BoolVar(&o.Step, "step", o.Step, "If true, wait for Enter before every switch instead of the recorded time")
+	cmd.Flags().Float64Var(&o.Speed, "speed", o.Speed, "How much faster than recorded to replay the switches, 0 to not wait")
+	cmd.Flags().BoolVar(&o.Acknowledge, "acknowledge", o.Acknowledge, "Acknowledge switching to the contexts the cooloff setting applies to")
+	return cmd
+}
+
+// Complete sets the cooloff policy from the settings
+func (o *ReplayOptions) Complete() error {
+	if o.Speed < 0 {
+		return fmt.Errorf("invalid --speed %v, must not be negative", o.Speed)
+	}
+	settings, err := loadSettings(settingsFile())
+	if err != nil {
+		return err
+	}
+	o.Cooloff, err = newCooloffPolicy(settings)
+	return err
+}
+
+// Run makes the recorded switches
+func (o *ReplayOptions) Run() error {
+	recording, err := readRecording(o.Filename)
+	if err != nil {
+		return err
+	}
+	if recording == nil || len(recording.Switches) == 0 {
+		return fmt.Errorf("%s holds no recorded switches", o.Filename)
+	}
+
+	// Every context is checked first, so that the replay does not stop half way.
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	for _, event := range recording.Switches {
+		if _, ok := config.Contexts[event.Context]; !ok {
+			return fmt.Errorf("no context exists with the name: %q", event.Context)
+		}
+		if o.Cooloff != nil {
+			if err := o.Cooloff.check(config, event.Context, o.Acknowledge); err != nil {
+				return err
+			}
+		}
+	}
+	// The security key of a protected context is touched once, before the first switch.
+	touched := sets.NewString()
+	for _, event := range recording.Switches {
+		if touched.Has(event.Context) {
+			continue
+		}
+		if err := requireFIDO2Touch(config, event.Context, o.RunCommand, o.ErrOut); err != nil {
+			return err
+		}
+		touched.Insert(event.Context)
+	}
+
+	in := bufio.NewReader(o.In)
+	for i, event := range recording.Switches {
+		if o.Step {
+			fmt.Fprintf(o.Out, "Press Enter to switch to context %q, namespace %s: ", event.Context, valueOrNone(event.Namespace))
+			if _, err := in.ReadString('\n'); err != nil {
+				return err
+			}
+		} else if i > 0 && o.Speed > 0 {
+			o.Sleep(time.Duration(float64(event.Time.Sub(recording.Switches[i-1].Time)) / o.Speed))
+		}
+
+		config, err := o.ConfigAccess.GetStartingConfig()
+		if err != nil {
+			return err
+		}
+		context, ok := config.Contexts[event.Context]
+		if !ok {
+			return fmt.Errorf("no context exists with the name: %q", event.Context)
+		}
+		config.CurrentContext = event.Context
+		context.Namespace = event.Namespace
+		if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+			return err
+		}
+		fmt.Fprintf(o.Out, "Switched to context %q, namespace %s.\n", event.Context, valueOrNone(event.Namespace))
+	}
+	return nil
+}
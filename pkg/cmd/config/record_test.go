@@ -0,0 +1,145 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestRecordReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "record")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	config := newRedFederalCowHammerConfig()
+	config.Contexts["staging"] = &clientcmdapi.Context{Cluster: "cow-cluster", AuthInfo: "red-user"}
+	config.CurrentContext = "federal-context"
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = filepath.Join(dir, "config")
+	pathOptions.EnvVar = ""
+	if err := clientcmd.WriteToFile(config, pathOptions.GlobalFile); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// switchTo changes the current-context and its namespace, as use-context and set-context would.
+	switchTo := func(context, namespace string) {
+		config, err := pathOptions.GetStartingConfig()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		config.CurrentContext = context
+		config.Contexts[context].Namespace = namespace
+		if err := clientcmd.ModifyConfig(pathOptions, *config, true); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	started := time.Date(2019, 8, 2, 10, 0, 0, 0, time.UTC)
+	now := started
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	record := &RecordOptions{
+		ConfigAccess: pathOptions,
+		Filename:     filepath.Join(dir, "recording.json"),
+		Output:       filepath.Join(dir, "runbook.json"),
+		Now:          func() time.Time { return now },
+		IOStreams:    streams,
+	}
+	if err := recordSwitch(record.Filename, pathOptions, now); err != nil {
+		t.Fatalf("expected nothing to be recorded before starting, got %v", err)
+	}
+	if err := record.RunStart(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := record.RunStart(); err == nil || !strings.Contains(err.Error(), "is in progress") {
+		t.Errorf("expected a second recording to be refused, got %v", err)
+	}
+
+	now = now.Add(time.Minute)
+	switchTo("staging", "")
+	if err := recordSwitch(record.Filename, pathOptions, now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now = now.Add(time.Minute)
+	if err := recordSwitch(record.Filename, pathOptions, now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now = now.Add(time.Minute)
+	// Switched by another tool, recorded when stopping.
+	switchTo("staging", "web")
+
+	out.Reset()
+	if err := record.RunStop(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "Recorded 2 switch(es) to " + record.Output + ".\n"; out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+	if _, err := os.Stat(record.Filename); !os.IsNotExist(err) {
+		t.Errorf("expected the recording to be stopped, got %v", err)
+	}
+
+	switchTo("federal-context", "other")
+	streams, _, out, _ = genericclioptions.NewTestIOStreams()
+	var slept []time.Duration
+	replay := &ReplayOptions{
+		ConfigAccess: pathOptions,
+		Filename:     record.Output,
+		Speed:        2,
+		Sleep:        func(d time.Duration) { slept = append(slept, d) },
+		IOStreams:    streams,
+	}
+	if err := replay.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `Switched to context "federal-context", namespace <none>.
+Switched to context "staging", namespace <none>.
+Switched to context "staging", namespace web.
+`
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+	if expected := []time.Duration{30 * time.Second, time.Minute}; !reflect.DeepEqual(slept, expected) {
+		t.Errorf("expected the recorded gaps at twice the speed %v, got %v", expected, slept)
+	}
+	replayed, err := clientcmd.LoadFromFile(pathOptions.GlobalFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if replayed.CurrentContext != "staging" || replayed.Contexts["staging"].Namespace != "web" || replayed.Contexts["federal-context"].Namespace != "" {
+		t.Errorf("expected the switches to be replayed, got %+v", replayed)
+	}
+
+	streams, in, out, _ := genericclioptions.NewTestIOStreams()
+	in.WriteString("\n")
+	replay = &ReplayOptions{ConfigAccess: pathOptions, Filename: record.Output, Step: true, IOStreams: streams}
+	if err := replay.Run(); err == nil {
+		t.Errorf("expected the replay to stop when the input ends")
+	}
+	if !strings.HasPrefix(out.String(), `Press Enter to switch to context "federal-context"`) {
+		t.Errorf("expected a prompt before every switch, got %q", out.String())
+	}
+}
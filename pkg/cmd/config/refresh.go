@@ -0,0 +1,500 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	clientauthv1beta1 "k8s.io/client-go/pkg/apis/clientauthentication/v1beta1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// execRunner runs an exec credential plugin and returns the credential it printed.
+type execRunner func(exec *clientcmdapi.ExecConfig) (*clientauthv1beta1.ExecCredential, error)
+
+// RefreshOptions holds the command-line options for 'config refresh' sub command
+type RefreshOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Contexts     []string
+	Selector     string
+	Before       time.Duration
+	Daemon       bool
+	Interval     time.Duration
+	Wrap         bool
+	Unwrap       bool
+	CacheDir     string
+
+	RunExec execRunner
+	log     *cmdLogger
+
+	genericclioptions.IOStreams
+}
+
+var (
+	refreshLong = templates.LongDesc(`
+		Refresh the tokens of exec credential plugins before they expire.
+
+		Plugins such as "aws eks get-token", kubelogin or gke-gcloud-auth-plugin often take seconds
+		to mint a token, which kubectl waits for on the first call after the previous token
+		expired. "config refresh" runs the plugins of the users of the selected contexts, by name or
+		with --selector, and stores the credentials they print in the credential cache of the
+		config subcommands, below the kubecfg state directory. Credentials are only refreshed when
+		they expire within --before, and credentials without an expiry are not cached.
+
+		With --daemon, it keeps refreshing every --interval until it is stopped, so that a fresh
+		token is always waiting.
+
+		kubectl reads the cache once the users are wrapped with --wrap: their exec plugin is
+		replaced by "kubectl config credential", which prints the cached credential, or runs the
+		original plugin when there is none. The original plugin is kept in a kubeconfig extension
+		and restored with --unwrap.`)
+
+	refreshExample = templates.Examples(`
+		# Have the users of the production contexts read their tokens from the cache
+		kubectl config refresh --selector env=prod --wrap
+
+		# Keep their tokens fresh in the background
+		kubectl config refresh --selector env=prod --daemon &`)
+)
+
+// NewCmdConfigRefresh returns a Command instance for 'config refresh' sub command
+func NewCmdConfigRefresh(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &RefreshOptions{
+		ConfigAccess: configAccess,
+		Before:       5 * time.Minute,
+		Interval:     time.Minute,
+		RunExec:      runExecPlugin,
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "refresh [CONTEXT...] [--selector=SELECTOR] [--before=DURATION] [--daemon] [--wrap|--unwrap]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Refresh the tokens of exec credential plugins before they expire"),
+		Long:                  refreshLong,
+		Example:               refreshExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(cmd, args))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run(nil))
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, "Selector (label query) on the tags and annotations of contexts, see 'config get-contexts'")
+	cmd.Flags().DurationVar(&o.Before, "before", o.Before, "Refresh credentials expiring within this duration")
+	cmd.Flags().BoolVar(&o.Daemon, "daemon", o.Daemon, "If true, keep refreshing every --interval until stopped")
+	cmd.Flags().DurationVar(&o.Interval, "interval", o.Interval, "Time between two rounds of refreshes with --daemon")
+	cmd.Flags().BoolVar(&o.Wrap, "wrap", o.Wrap, "If true, make the users of the contexts read their credentials from the cache")
+	cmd.Flags().BoolVar(&o.Unwrap, "unwrap", o.Unwrap, "If true, restore the exec plugins replaced by --wrap")
+	return cmd
+}
+
+// Complete sets the contexts and the cache directory, and sets up logging
+func (o *RefreshOptions) Complete(cmd *cobra.Command, args []string) error {
+	o.Contexts = args
+	if len(o.CacheDir) == 0 {
+		o.CacheDir = credentialCacheDir()
+	}
+	var err error
+	if o.log, err = newCmdLogger(cmd, o.ErrOut); err != nil {
+		return err
+	}
+	if !o.Wrap && !o.Unwrap {
+		// Plugins reach their identity provider, only wrapping stays offline.
+		return requireNetwork(cmd)
+	}
+	return nil
+}
+
+// Validate makes sure the flags can be used together
+func (o *RefreshOptions) Validate() error {
+	if len(o.Contexts) > 0 && len(o.Selector) > 0 {
+		return errors.New("contexts cannot be named together with --selector")
+	}
+	if o.Wrap && o.Unwrap {
+		return errors.New("--wrap and --unwrap cannot be used together")
+	}
+	if o.Daemon && (o.Wrap || o.Unwrap) {
+		return errors.New("--daemon cannot be used with --wrap or --unwrap")
+	}
+	if o.Daemon && o.Interval <= 0 {
+		return errors.New("--interval must be positive")
+	}
+	return nil
+}
+
+// Run refreshes the credentials once, or until stop is closed with --daemon, which never happens
+// for a nil channel
+func (o *RefreshOptions) Run(stop <-chan struct{}) error {
+	if o.log == nil {
+		o.log, _ = newCmdLogger(nil, o.ErrOut)
+	}
+	if o.Wrap || o.Unwrap {
+		return o.wrap()
+	}
+	for {
+		config, err := o.ConfigAccess.GetStartingConfig()
+		if err != nil {
+			return err
+		}
+		users, err := o.selectExecUsers(config)
+		if err != nil {
+			return err
+		}
+		failed := 0
+		for _, name := range users {
+			status, err := refreshCredential(config.AuthInfos[name], name, o.CacheDir, o.Before, time.Now(), o.RunExec)
+			if err != nil {
+				o.log.Warningf("user %q: %v", name, err)
+				failed++
+				continue
+			}
+			if !o.Daemon || status != "fresh" {
+				fmt.Fprintf(o.Out, "user %q: %s\n", name, status)
+			}
+		}
+		if !o.Daemon {
+			if failed > 0 {
+				return fmt.Errorf("%d of %d user(s) could not be refreshed", failed, len(users))
+			}
+			return nil
+		}
+
+		select {
+		case <-stop:
+			return nil
+		case <-time.After(o.Interval):
+		}
+	}
+}
+
+// selectExecUsers returns the users with an exec plugin of the selected contexts, each once.
+func (o *RefreshOptions) selectExecUsers(config *clientcmdapi.Config) ([]string, error) {
+	contexts := o.Contexts
+	if len(contexts) == 0 {
+		var err error
+		if contexts, err = selectContexts(config, o.Selector); err != nil {
+			return nil, err
+		}
+	}
+	users := []string{}
+	for _, name := range contexts {
+		name, err := resolveContextName(config, name)
+		if err != nil {
+			return nil, err
+		}
+		context, ok := config.Contexts[name]
+		if !ok {
+			return nil, fmt.Errorf("no context exists with the name: %q", name)
+		}
+		authInfo, ok := config.AuthInfos[context.AuthInfo]
+		if !ok || containsString(users, context.AuthInfo) {
+			continue
+		}
+		exec, err := originalExec(authInfo)
+		if err != nil {
+			return nil, fmt.Errorf("user %q: %v", context.AuthInfo, err)
+		}
+		if exec != nil {
+			users = append(users, context.AuthInfo)
+		}
+	}
+	return users, nil
+}
+
+// wrap replaces the exec plugins of the selected users by "kubectl config credential", or restores
+// them.
+func (o *RefreshOptions) wrap() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	users, err := o.selectExecUsers(config)
+	if err != nil {
+		return err
+	}
+	changed := 0
+	for _, name := range users {
+		authInfo := config.AuthInfos[name]
+		var done bool
+		if o.Wrap {
+			done, err = wrapExec(authInfo, name, o.ConfigAccess)
+		} else {
+			done, err = unwrapExec(authInfo)
+		}
+		if err != nil {
+			return fmt.Errorf("user %q: %v", name, err)
+		}
+		if done {
+			changed++
+		}
+	}
+	verb := "Wrapped"
+	if o.Unwrap {
+		verb = "Unwrapped"
+	}
+	if changed == 0 {
+		fmt.Fprintf(o.Out, "%s no users.\n", verb)
+		return nil
+	}
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "%s %d user(s).\n", verb, changed)
+	return nil
+}
+
+// wrapExec keeps the exec plugin of a user in an extension and replaces it by "kubectl config
+// credential", and reports whether the user was not wrapped already.
+func wrapExec(authInfo *clientcmdapi.AuthInfo, name string, configAccess clientcmd.ConfigAccess) (bool, error) {
+	if _, ok := authInfo.Extensions[credentialRefreshExtension]; ok {
+		return false, nil
+	}
+	if err := writeExtension(&authInfo.Extensions, credentialRefreshExtension, authInfo.Exec); err != nil {
+		return false, err
+	}
+	args := []string{"config", "credential", name}
+	if configAccess.IsExplicitFile() {
+		args = append(args, "--"+clientcmd.RecommendedConfigPathFlag, configAccess.GetExplicitFile())
+	}
+	authInfo.Exec = &clientcmdapi.ExecConfig{
+		APIVersion: clientauthv1beta1.SchemeGroupVersion.String(),
+		Command:    "kubectl",
+		Args:       args,
+	}
+	return true, nil
+}
+
+// unwrapExec restores the exec plugin kept by wrapExec, and reports whether the user was wrapped.
+func unwrapExec(authInfo *clientcmdapi.AuthInfo) (bool, error) {
+	exec := &clientcmdapi.ExecConfig{}
+	found, err := readExtension(authInfo.Extensions, credentialRefreshExtension, exec)
+	if err != nil || !found {
+		return false, err
+	}
+	authInfo.Exec = exec
+	delete(authInfo.Extensions, credentialRefreshExtension)
+	return true, nil
+}
+
+// originalExec returns the exec plugin of a user, looking through the wrapping of wrapExec.
+func originalExec(authInfo *clientcmdapi.AuthInfo) (*clientcmdapi.ExecConfig, error) {
+	exec := &clientcmdapi.ExecConfig{}
+	found, err := readExtension(authInfo.Extensions, credentialRefreshExtension, exec)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return exec, nil
+	}
+	return authInfo.Exec, nil
+}
+
+// refreshCredential runs the exec plugin of a user unless its cached credential is valid for longer
+// than before, and caches the new credential. It returns what it did.
+func refreshCredential(authInfo *clientcmdapi.AuthInfo, name, cacheDir string, before time.Duration, now time.Time, run execRunner) (string, error) {
+	exec, err := originalExec(authInfo)
+	if err != nil {
+		return "", err
+	}
+	if exec == nil {
+		return "", errors.New("the user has no exec plugin")
+	}
+	if cached, err := readCachedCredential(cacheDir, name); err == nil && credentialValid(cached, now.Add(before)) {
+		return "fresh", nil
+	}
+	credential, err := run(exec)
+	if err != nil {
+		return "", err
+	}
+	if credential.Status.ExpirationTimestamp == nil {
+		return "not cached, the plugin gives no expiry", nil
+	}
+	if err := writeCachedCredential(cacheDir, name, credential); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("refreshed, expires %s", credential.Status.ExpirationTimestamp.UTC().Format(time.RFC3339)), nil
+}
+
+// credentialValid reports whether a credential is still valid at the given time.
+func credentialValid(credential *clientauthv1beta1.ExecCredential, at time.Time) bool {
+	return credential.Status != nil && credential.Status.ExpirationTimestamp != nil && credential.Status.ExpirationTimestamp.Time.After(at)
+}
+
+// credentialCacheDir is where the credentials refreshed by "config refresh" are kept.
+func credentialCacheDir() string {
+	return filepath.Join(stateDir(), "credentials")
+}
+
+func cachedCredentialFile(cacheDir, name string) string {
+	// User names are ARNs or URLs at times, which are escaped into valid file names.
+	return filepath.Join(cacheDir, url.QueryEscape(name)+".json")
+}
+
+func readCachedCredential(cacheDir, name string) (*clientauthv1beta1.ExecCredential, error) {
+	data, err := ioutil.ReadFile(cachedCredentialFile(cacheDir, name))
+	if err != nil {
+		return nil, err
+	}
+	credential := &clientauthv1beta1.ExecCredential{}
+	if err := json.Unmarshal(data, credential); err != nil {
+		return nil, err
+	}
+	return credential, nil
+}
+
+func writeCachedCredential(cacheDir, name string, credential *clientauthv1beta1.ExecCredential) error {
+	data, err := json.Marshal(credential)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(cacheDir, 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(cachedCredentialFile(cacheDir, name), data, 0600)
+}
+
+// runExecPlugin runs an exec plugin the way client-go does, without a terminal to interact with.
+func runExecPlugin(config *clientcmdapi.ExecConfig) (*clientauthv1beta1.ExecCredential, error) {
+	apiVersion := config.APIVersion
+	if len(apiVersion) == 0 {
+		apiVersion = clientauthv1beta1.SchemeGroupVersion.String()
+	}
+	info, err := json.Marshal(map[string]interface{}{"apiVersion": apiVersion, "kind": "ExecCredential", "spec": map[string]interface{}{"interactive": false}})
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(config.Command, config.Args...)
+	cmd.Env = append(os.Environ(), "KUBERNETES_EXEC_INFO="+string(info))
+	for _, env := range config.Env {
+		cmd.Env = append(cmd.Env, env.Name+"="+env.Value)
+	}
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); len(message) > 0 {
+			return nil, fmt.Errorf("%s: %v: %s", config.Command, err, message)
+		}
+		return nil, fmt.Errorf("%s: %v", config.Command, err)
+	}
+	credential := &clientauthv1beta1.ExecCredential{}
+	if err := json.Unmarshal(out, credential); err != nil {
+		return nil, fmt.Errorf("%s printed an invalid credential: %v", config.Command, err)
+	}
+	if credential.Status == nil {
+		return nil, fmt.Errorf("%s printed a credential without status", config.Command)
+	}
+	return credential, nil
+}
+
+// CredentialOptions holds the command-line options for 'config credential' sub command
+type CredentialOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	User         string
+	CacheDir     string
+
+	RunExec execRunner
+
+	genericclioptions.IOStreams
+}
+
+// NewCmdConfigCredential returns a Command instance for 'config credential' sub command
+func NewCmdConfigCredential(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &CredentialOptions{
+		ConfigAccess: configAccess,
+		RunExec:      runExecPlugin,
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "credential USER_NAME",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Print the cached credential of a user wrapped by 'config refresh --wrap'"),
+		Long: templates.LongDesc(`
+			Print the cached credential of a user as an exec credential plugin does.
+
+			It is the exec plugin of the users wrapped by "kubectl config refresh --wrap". When the
+			cached credential expired, the original plugin of the user is run instead, and what it
+			prints is cached.`),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.User = args[0]
+			if len(o.CacheDir) == 0 {
+				o.CacheDir = credentialCacheDir()
+			}
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	return cmd
+}
+
+// Run prints the cached credential, refreshing it first when it expired
+func (o *CredentialOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	authInfo, ok := config.AuthInfos[o.User]
+	if !ok {
+		return fmt.Errorf("no user exists with the name: %q", o.User)
+	}
+	credential, err := readCachedCredential(o.CacheDir, o.User)
+	// A credential about to expire could expire before the request using it reaches the server.
+	if err != nil || !credentialValid(credential, time.Now().Add(10*time.Second)) {
+		exec, err := originalExec(authInfo)
+		if err != nil {
+			return err
+		}
+		if exec == nil || (exec.Command == "kubectl" && len(exec.Args) > 1 && exec.Args[1] == "credential") {
+			return fmt.Errorf("user %q is not wrapped by 'kubectl config refresh --wrap'", o.User)
+		}
+		if credential, err = o.RunExec(exec); err != nil {
+			return err
+		}
+		if credential.Status.ExpirationTimestamp != nil {
+			if err := writeCachedCredential(o.CacheDir, o.User, credential); err != nil {
+				return err
+			}
+		}
+	}
+	// client-go expects the version the wrapper was declared with, whatever the original plugin printed.
+	credential.APIVersion = clientauthv1beta1.SchemeGroupVersion.String()
+	credential.Kind = "ExecCredential"
+	return json.NewEncoder(o.Out).Encode(credential)
+}
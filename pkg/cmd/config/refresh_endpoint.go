@@ -0,0 +1,209 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// The ConfigMap kubeadm and other installers publish the endpoint and certificate authority of the
+// cluster in, readable without credentials.
+const (
+	clusterInfoNamespace = "kube-public"
+	clusterInfoName      = "cluster-info"
+	clusterInfoKey       = "kubeconfig"
+)
+
+// RefreshEndpointOptions holds the command-line options for 'config refresh-endpoint' sub command
+type RefreshEndpointOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Context      string
+	// Via is the server the cluster is reached at instead of the one of the context, when that one
+	// no longer answers.
+	Via    string
+	DryRun bool
+
+	genericclioptions.IOStreams
+}
+
+var (
+	refreshEndpointLong = templates.LongDesc(`
+		Update the server and certificate authority of the cluster of a context after its control
+		plane endpoint moved.
+
+		The cluster-info ConfigMap of the kube-public namespace, which kubeadm and most installers
+		publish, holds the current endpoint and certificate authority of the cluster. When it does
+		not exist, the server address the API server advertises to every client is used instead,
+		and the certificate authority is kept.
+
+		The cluster is reached at the server of the context, or at --via when that one no longer
+		answers, such as the address of a control plane node. The certificate authority of the
+		context is used to verify the cluster either way.
+
+		With --dry-run, the changes are only reported.`)
+
+	refreshEndpointExample = templates.Examples(`
+		# Update the endpoint of the prod context after the load balancer of its control plane changed
+		kubectl config refresh-endpoint prod
+
+		# Reach the cluster through one of its control plane nodes
+		kubectl config refresh-endpoint prod --via https://10.0.0.11:6443`)
+)
+
+// NewCmdConfigRefreshEndpoint returns a Command instance for 'config refresh-endpoint' sub command
+func NewCmdConfigRefreshEndpoint(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &RefreshEndpointOptions{ConfigAccess: configAccess, IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "refresh-endpoint CONTEXT_NAME [--via=SERVER] [--dry-run]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Update the server and certificate authority of a cluster from the cluster itself"),
+		Long:                  refreshEndpointLong,
+		Example:               refreshEndpointExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.Context = args[0]
+			cmdutil.CheckErr(requireNetwork(cmd))
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().StringVar(&o.Via, "via", o.Via, "Server to reach the cluster at instead of the one of the context")
+	cmd.Flags().BoolVar(&o.DryRun, "dry-run", o.DryRun, "If true, only report the changes")
+	return cmd
+}
+
+// Run performs the execution of 'config refresh-endpoint' sub command
+func (o *RefreshEndpointOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	name, err := resolveContextName(config, o.Context)
+	if err != nil {
+		return err
+	}
+	context, ok := config.Contexts[name]
+	if !ok {
+		return fmt.Errorf("no context exists with the name: %q", name)
+	}
+	cluster, ok := config.Clusters[context.Cluster]
+	if !ok {
+		return fmt.Errorf("context %q has no cluster %q", name, context.Cluster)
+	}
+
+	overrides := &clientcmd.ConfigOverrides{}
+	if len(o.Via) > 0 {
+		overrides.ClusterInfo.Server = o.Via
+	}
+	restConfig, err := newProbeConfig(config, name, overrides, 30*time.Second)
+	if err != nil {
+		return err
+	}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+	fresh, err := fetchClusterEndpoint(clientset)
+	if err != nil {
+		return fmt.Errorf("context %q: %v", name, err)
+	}
+
+	changed := false
+	if fresh.Server != cluster.Server {
+		fmt.Fprintf(o.Out, "Cluster %q: server %s -> %s\n", context.Cluster, cluster.Server, fresh.Server)
+		cluster.Server = fresh.Server
+		changed = true
+	}
+	if len(fresh.CertificateAuthorityData) > 0 && !bytes.Equal(fresh.CertificateAuthorityData, cluster.CertificateAuthorityData) {
+		fmt.Fprintf(o.Out, "Cluster %q: new certificate authority\n", context.Cluster)
+		cluster.CertificateAuthority = ""
+		cluster.CertificateAuthorityData = fresh.CertificateAuthorityData
+		changed = true
+	}
+	if !changed {
+		fmt.Fprintf(o.Out, "Cluster %q of context %q is up to date.\n", context.Cluster, name)
+		return nil
+	}
+	if o.DryRun {
+		return nil
+	}
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "Cluster %q updated.\n", context.Cluster)
+	return nil
+}
+
+// fetchClusterEndpoint returns the server and certificate authority of the cluster as published in
+// its cluster-info ConfigMap, or the server the API server advertises when there is no such
+// ConfigMap.
+func fetchClusterEndpoint(clientset kubernetes.Interface) (*clientcmdapi.Cluster, error) {
+	configMap, err := clientset.CoreV1().ConfigMaps(clusterInfoNamespace).Get(clusterInfoName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return advertisedClusterEndpoint(clientset)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading the %s/%s ConfigMap: %v", clusterInfoNamespace, clusterInfoName, err)
+	}
+	published, err := clientcmd.Load([]byte(configMap.Data[clusterInfoKey]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s/%s ConfigMap: %v", clusterInfoNamespace, clusterInfoName, err)
+	}
+	if len(published.Clusters) != 1 {
+		return nil, fmt.Errorf("expected one cluster in the %s/%s ConfigMap, got %d", clusterInfoNamespace, clusterInfoName, len(published.Clusters))
+	}
+	for _, cluster := range published.Clusters {
+		if len(cluster.Server) == 0 {
+			return nil, fmt.Errorf("the %s/%s ConfigMap has no server", clusterInfoNamespace, clusterInfoName)
+		}
+		return cluster, nil
+	}
+	return nil, nil
+}
+
+// advertisedClusterEndpoint returns the server the API server advertises to every client through
+// discovery, without a certificate authority.
+func advertisedClusterEndpoint(clientset kubernetes.Interface) (*clientcmdapi.Cluster, error) {
+	versions := &metav1.APIVersions{}
+	if err := clientset.Discovery().RESTClient().Get().AbsPath("/api").Do().Into(versions); err != nil {
+		return nil, fmt.Errorf("no %s/%s ConfigMap, and discovery failed: %v", clusterInfoNamespace, clusterInfoName, err)
+	}
+	for _, address := range versions.ServerAddressByClientCIDRs {
+		if address.ClientCIDR == "0.0.0.0/0" && len(address.ServerAddress) > 0 {
+			return &clientcmdapi.Cluster{Server: "https://" + address.ServerAddress}, nil
+		}
+	}
+	return nil, errors.New("no cluster-info ConfigMap, and the API server advertises no address for every client")
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestRefreshEndpoint(t *testing.T) {
+	published := clientcmdapi.NewConfig()
+	published.Clusters[""] = &clientcmdapi.Cluster{Server: "https://api.new.example.com:6443", CertificateAuthorityData: []byte("new-ca")}
+	kubeconfig, err := clientcmd.Write(*published)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	withClusterInfo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path != "/api/v1/namespaces/kube-public/configmaps/cluster-info" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(&corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kube-public", Name: "cluster-info"},
+			Data:       map[string]string{"kubeconfig": string(kubeconfig)},
+		})
+	}))
+	defer withClusterInfo.Close()
+	withoutClusterInfo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path != "/api" {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(&metav1.Status{
+				TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+				Status:   metav1.StatusFailure,
+				Reason:   metav1.StatusReasonNotFound,
+				Code:     http.StatusNotFound,
+			})
+			return
+		}
+		json.NewEncoder(w).Encode(&metav1.APIVersions{
+			TypeMeta: metav1.TypeMeta{Kind: "APIVersions"},
+			Versions: []string{"v1"},
+			ServerAddressByClientCIDRs: []metav1.ServerAddressByClientCIDR{
+				{ClientCIDR: "0.0.0.0/0", ServerAddress: "10.0.0.11:6443"},
+			},
+		})
+	}))
+	defer withoutClusterInfo.Close()
+
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	config := newRedFederalCowHammerConfig()
+	config.Clusters["cow-cluster"].Server = withClusterInfo.URL
+	config.Clusters["kubeadm"] = &clientcmdapi.Cluster{Server: withoutClusterInfo.URL}
+	config.Contexts["kubeadm"] = &clientcmdapi.Context{Cluster: "kubeadm", AuthInfo: "red-user"}
+	if err := clientcmd.WriteToFile(config, fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := &RefreshEndpointOptions{ConfigAccess: pathOptions, Context: "federal-context", DryRun: true, IOStreams: streams}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `Cluster "cow-cluster": server ` + withClusterInfo.URL + ` -> https://api.new.example.com:6443
+Cluster "cow-cluster": new certificate authority
+`
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+	if unchanged, err := clientcmd.LoadFromFile(fakeKubeFile.Name()); err != nil || unchanged.Clusters["cow-cluster"].Server != withClusterInfo.URL {
+		t.Errorf("expected nothing to be written with --dry-run, got %v", err)
+	}
+
+	out.Reset()
+	o.DryRun = false
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out.Reset()
+	o.Context = "kubeadm"
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected = `Cluster "kubeadm": server ` + withoutClusterInfo.URL + ` -> https://10.0.0.11:6443
+Cluster "kubeadm" updated.
+`
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+
+	refreshed, err := clientcmd.LoadFromFile(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cluster := refreshed.Clusters["cow-cluster"]; cluster.Server != "https://api.new.example.com:6443" || string(cluster.CertificateAuthorityData) != "new-ca" {
+		t.Errorf("expected the published endpoint, got %#v", cluster)
+	}
+	if cluster := refreshed.Clusters["kubeadm"]; cluster.Server != "https://10.0.0.11:6443" || len(cluster.CertificateAuthorityData) > 0 {
+		t.Errorf("expected the advertised endpoint, got %#v", cluster)
+	}
+
+	// The cluster is reached at --via once its own server moved away.
+	streams, _, out, _ = genericclioptions.NewTestIOStreams()
+	o = &RefreshEndpointOptions{ConfigAccess: pathOptions, Context: "kubeadm", Via: withoutClusterInfo.URL, IOStreams: streams}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "Cluster \"kubeadm\" of context \"kubeadm\" is up to date.\n"; out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
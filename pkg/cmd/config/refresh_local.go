@@ -0,0 +1,266 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/util/homedir"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/printers"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+const (
+	dockerDesktopContext = "docker-desktop"
+	colimaContext        = "colima"
+	// colimaDefaultProfile is the profile colima names its default context after.
+	colimaDefaultProfile = "default"
+)
+
+// RefreshLocalOptions holds the command-line options for 'config refresh-local' sub command
+type RefreshLocalOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Contexts     []string
+	DryRun       bool
+
+	// DockerDesktopKubeconfig is the file Docker Desktop writes its entries to.
+	DockerDesktopKubeconfig string
+	LookPath                func(file string) (string, error)
+	RunCommand              commandRunner
+
+	genericclioptions.IOStreams
+}
+
+var (
+	refreshLocalLong = templates.LongDesc(`
+		Refresh the credentials of Docker Desktop and colima contexts.
+
+		Resetting the Kubernetes cluster of Docker Desktop or colima creates new certificates,
+		which leaves copies of their entries in other kubeconfig files broken. For every
+		docker-desktop, colima and colima-PROFILE context, the current entries are fetched and
+		compared with the cluster and user of the context, which are updated when they differ:
+
+		    * Docker Desktop writes its entries to ~/.kube/config, they are copied from there
+		    * colima entries are read from the k3s kubeconfig inside the colima virtual machine
+
+		With --dry-run, stale contexts are only reported.`)
+
+	refreshLocalExample = templates.Examples(`
+		# Report which local contexts have stale credentials
+		kubectl config refresh-local --dry-run
+
+		# Refresh the credentials of the colima context
+		kubectl config refresh-local colima`)
+)
+
+// NewCmdConfigRefreshLocal returns a Command instance for 'config refresh-local' sub command
+func NewCmdConfigRefreshLocal(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &RefreshLocalOptions{
+		ConfigAccess:            configAccess,
+		DockerDesktopKubeconfig: filepath.Join(homedir.HomeDir(), ".kube", "config"),
+		LookPath:                exec.LookPath,
+		RunCommand:              runCommand,
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "refresh-local [CONTEXT_NAME...] [--dry-run]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Refresh the credentials of Docker Desktop and colima contexts"),
+		Long:                  refreshLocalLong,
+		Example:               refreshLocalExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			o.Contexts = args
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().BoolVar(&o.DryRun, "dry-run", o.DryRun, "If true, only report the stale contexts")
+	return cmd
+}
+
+// Run performs the execution of 'config refresh-local' sub command
+func (o *RefreshLocalOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	names := o.Contexts
+	explicit := len(names) > 0
+	if !explicit {
+		for _, name := range sortedContextNames(config.Contexts) {
+			if _, ok := colimaProfile(name); ok || name == dockerDesktopContext {
+				names = append(names, name)
+			}
+		}
+	}
+
+	refreshed := 0
+	w := printers.GetNewTabWriter(o.Out)
+	fmt.Fprintf(w, "CONTEXT\tSTATUS\n")
+	for _, name := range names {
+		context, ok := config.Contexts[name]
+		if !ok {
+			return fmt.Errorf("no context exists with the name: %q", name)
+		}
+		fresh, err := o.fetch(name)
+		if err != nil {
+			if explicit {
+				return fmt.Errorf("context %q: %v", name, err)
+			}
+			fmt.Fprintf(w, "%s\terror: %v\n", name, err)
+			continue
+		}
+		if fresh == nil {
+			fmt.Fprintf(w, "%s\tmanaged by Docker Desktop\n", name)
+			continue
+		}
+
+		cluster, authInfo := config.Clusters[context.Cluster], config.AuthInfos[context.AuthInfo]
+		if cluster != nil && authInfo != nil && sameClusterAccess(cluster, fresh.Clusters[fresh.Contexts[name].Cluster]) && sameCredentials(authInfo, fresh.AuthInfos[fresh.Contexts[name].AuthInfo]) {
+			fmt.Fprintf(w, "%s\tup to date\n", name)
+			continue
+		}
+		status := "refreshed"
+		if o.DryRun {
+			status = "stale"
+		}
+		fmt.Fprintf(w, "%s\t%s\n", name, status)
+		refreshLocalEntries(config, name, fresh)
+		refreshed++
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if refreshed == 0 || o.DryRun {
+		return nil
+	}
+	return clientcmd.ModifyConfig(o.ConfigAccess, *config, true)
+}
+
+// fetch returns a kubeconfig holding the current entries of a local context under its name, or nil
+// for a docker-desktop context loaded from the very file Docker Desktop maintains.
+func (o *RefreshLocalOptions) fetch(name string) (*clientcmdapi.Config, error) {
+	var data []byte
+	var err error
+	if profile, ok := colimaProfile(name); ok {
+		if _, err := o.LookPath("colima"); err != nil {
+			return nil, fmt.Errorf("colima is not installed")
+		}
+		data, err = o.RunCommand(nil, "colima", "ssh", "--profile", profile, "--", "sudo", "cat", "/etc/rancher/k3s/k3s.yaml")
+	} else if name == dockerDesktopContext {
+		for _, filename := range o.ConfigAccess.GetLoadingPrecedence() {
+			if sameFile(filename, o.DockerDesktopKubeconfig) {
+				return nil, nil
+			}
+		}
+		data, err = ioutil.ReadFile(o.DockerDesktopKubeconfig)
+	} else {
+		return nil, fmt.Errorf("not a Docker Desktop or colima context")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := clientcmd.Load(data)
+	if err != nil {
+		return nil, err
+	}
+	if name == dockerDesktopContext {
+		from.CurrentContext = dockerDesktopContext
+	}
+	fresh := clientcmdapi.NewConfig()
+	if err := renameLocalClusterEntries(fresh, from, name); err != nil {
+		return nil, err
+	}
+	return fresh, nil
+}
+
+// colimaProfile returns the colima profile a context belongs to, going by the names colima gives
+// its contexts.
+func colimaProfile(name string) (string, bool) {
+	if name == colimaContext {
+		return colimaDefaultProfile, true
+	}
+	if strings.HasPrefix(name, colimaContext+"-") {
+		return strings.TrimPrefix(name, colimaContext+"-"), true
+	}
+	return "", false
+}
+
+// refreshLocalEntries replaces what is needed to reach the cluster in the cluster and user of a
+// context with the entries of fresh, keeping everything the user added, such as extensions.
+func refreshLocalEntries(config *clientcmdapi.Config, name string, fresh *clientcmdapi.Config) {
+	context := config.Contexts[name]
+	freshCluster := fresh.Clusters[fresh.Contexts[name].Cluster]
+	freshAuthInfo := fresh.AuthInfos[fresh.Contexts[name].AuthInfo]
+
+	if cluster, ok := config.Clusters[context.Cluster]; ok {
+		cluster.Server = freshCluster.Server
+		cluster.CertificateAuthority = freshCluster.CertificateAuthority
+		cluster.CertificateAuthorityData = freshCluster.CertificateAuthorityData
+		cluster.InsecureSkipTLSVerify = freshCluster.InsecureSkipTLSVerify
+	} else {
+		config.Clusters[context.Cluster] = freshCluster
+	}
+	if authInfo, ok := config.AuthInfos[context.AuthInfo]; ok {
+		authInfo.ClientCertificate = freshAuthInfo.ClientCertificate
+		authInfo.ClientCertificateData = freshAuthInfo.ClientCertificateData
+		authInfo.ClientKey = freshAuthInfo.ClientKey
+		authInfo.ClientKeyData = freshAuthInfo.ClientKeyData
+		authInfo.Token = freshAuthInfo.Token
+	} else {
+		config.AuthInfos[context.AuthInfo] = freshAuthInfo
+	}
+}
+
+// sameFile reports whether both names refer to a file that exists.
+func sameFile(a, b string) bool {
+	aInfo, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bInfo, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(aInfo, bInfo)
+}
+
+func sameClusterAccess(a, b *clientcmdapi.Cluster) bool {
+	return a.Server == b.Server && a.CertificateAuthority == b.CertificateAuthority && reflect.DeepEqual(a.CertificateAuthorityData, b.CertificateAuthorityData) && a.InsecureSkipTLSVerify == b.InsecureSkipTLSVerify
+}
+
+func sameCredentials(a, b *clientcmdapi.AuthInfo) bool {
+	return a.ClientCertificate == b.ClientCertificate && reflect.DeepEqual(a.ClientCertificateData, b.ClientCertificateData) &&
+		a.ClientKey == b.ClientKey && reflect.DeepEqual(a.ClientKeyData, b.ClientKeyData) && a.Token == b.Token
+}
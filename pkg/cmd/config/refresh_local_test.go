@@ -0,0 +1,139 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestRefreshLocal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "refresh-local")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Docker Desktop rewrote its own file after a reset.
+	dockerDesktopKubeconfig := filepath.Join(dir, "docker-desktop")
+	if err := ioutil.WriteFile(dockerDesktopKubeconfig, []byte(localClusterKubeconfig("docker-desktop", "https://kubernetes.docker.internal:6443")), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	kubeconfig := filepath.Join(dir, "config")
+	startingConfig := newRedFederalCowHammerConfig()
+	startingConfig.Clusters["docker-desktop"] = &clientcmdapi.Cluster{Server: "https://kubernetes.docker.internal:6443", CertificateAuthorityData: []byte("old-ca")}
+	startingConfig.AuthInfos["docker-desktop"] = &clientcmdapi.AuthInfo{Token: "old-token"}
+	startingConfig.Contexts["docker-desktop"] = &clientcmdapi.Context{Cluster: "docker-desktop", AuthInfo: "docker-desktop", Namespace: "web"}
+	startingConfig.Clusters["colima"] = &clientcmdapi.Cluster{Server: "https://127.0.0.1:6443"}
+	startingConfig.AuthInfos["colima"] = &clientcmdapi.AuthInfo{Token: "secret"}
+	startingConfig.Contexts["colima"] = &clientcmdapi.Context{Cluster: "colima", AuthInfo: "colima"}
+	startingConfig.Contexts["colima-work"] = &clientcmdapi.Context{Cluster: "colima", AuthInfo: "colima"}
+	if err := clientcmd.WriteToFile(startingConfig, kubeconfig); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = kubeconfig
+	pathOptions.EnvVar = ""
+
+	run := func(env []string, name string, args ...string) ([]byte, error) {
+		switch command := strings.Join(append([]string{name}, args...), " "); command {
+		case "colima ssh --profile default -- sudo cat /etc/rancher/k3s/k3s.yaml":
+			return []byte(localClusterKubeconfig("default", "https://127.0.0.1:6443")), nil
+		default:
+			return nil, fmt.Errorf("unexpected command %q", command)
+		}
+	}
+	// refresh returns the status of every context.
+	refresh := func(dryRun bool, contexts ...string) (map[string]string, error) {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		o := &RefreshLocalOptions{
+			ConfigAccess:            pathOptions,
+			Contexts:                contexts,
+			DryRun:                  dryRun,
+			DockerDesktopKubeconfig: dockerDesktopKubeconfig,
+			LookPath:                func(file string) (string, error) { return "/usr/bin/" + file, nil },
+			RunCommand:              run,
+			IOStreams:               streams,
+		}
+		if err := o.Run(); err != nil {
+			return nil, err
+		}
+		statuses := map[string]string{}
+		for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n")[1:] {
+			fields := strings.Fields(line)
+			statuses[fields[0]] = strings.Join(fields[1:], " ")
+		}
+		return statuses, nil
+	}
+
+	statuses, err := refresh(true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if statuses["colima"] != "up to date" || statuses["docker-desktop"] != "stale" || !strings.HasPrefix(statuses["colima-work"], "error: ") {
+		t.Errorf("unexpected statuses %v", statuses)
+	}
+	if _, ok := statuses["federal-context"]; ok {
+		t.Errorf("expected only local contexts to be checked")
+	}
+	config, err := clientcmd.LoadFromFile(kubeconfig)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.AuthInfos["docker-desktop"].Token != "old-token" {
+		t.Errorf("expected --dry-run to leave the kubeconfig alone")
+	}
+
+	if statuses, err := refresh(false, "docker-desktop"); err != nil || statuses["docker-desktop"] != "refreshed" {
+		t.Fatalf("unexpected statuses %v, error %v", statuses, err)
+	}
+	config, err = clientcmd.LoadFromFile(kubeconfig)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cluster := config.Clusters["docker-desktop"]; len(cluster.CertificateAuthorityData) != 0 {
+		t.Errorf("expected the old certificate authority to be replaced, got %q", cluster.CertificateAuthorityData)
+	}
+	if config.AuthInfos["docker-desktop"].Token != "secret" || config.Contexts["docker-desktop"].Namespace != "web" {
+		t.Errorf("expected refreshed credentials in the unchanged context, got %#v", config.AuthInfos["docker-desktop"])
+	}
+
+	if _, err := refresh(false, "colima-work"); err == nil {
+		t.Errorf("expected an error for a named context that cannot be refreshed")
+	}
+	if _, err := refresh(false, "federal-context"); err == nil {
+		t.Errorf("expected an error for a context that is not local")
+	}
+}
+
+func TestColimaProfile(t *testing.T) {
+	for name, expected := range map[string]string{"colima": "default", "colima-work": "work", "colimax": "", "kind-dev": ""} {
+		profile, ok := colimaProfile(name)
+		if profile != expected || ok != (len(expected) > 0) {
+			t.Errorf("%s: expected profile %q, got %q", name, expected, profile)
+		}
+	}
+}
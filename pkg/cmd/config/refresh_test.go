@@ -0,0 +1,228 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	clientauthv1beta1 "k8s.io/client-go/pkg/apis/clientauthentication/v1beta1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func newRefreshTestConfig(t *testing.T) (string, *clientcmd.PathOptions) {
+	dir, err := ioutil.TempDir("", "refresh")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	config := clientcmdapi.NewConfig()
+	config.Clusters["eks"] = &clientcmdapi.Cluster{Server: "https://eks.example.com"}
+	config.AuthInfos["arn:aws:eks:eu-west-1:1234:cluster/prod"] = &clientcmdapi.AuthInfo{Exec: &clientcmdapi.ExecConfig{
+		APIVersion: "client.authentication.k8s.io/v1beta1",
+		Command:    "aws",
+		Args:       []string{"eks", "get-token", "--cluster-name", "prod"},
+		Env:        []clientcmdapi.ExecEnvVar{{Name: "AWS_PROFILE", Value: "prod"}},
+	}}
+	config.AuthInfos["admin"] = &clientcmdapi.AuthInfo{Token: "secret"}
+	config.Contexts["prod"] = &clientcmdapi.Context{Cluster: "eks", AuthInfo: "arn:aws:eks:eu-west-1:1234:cluster/prod"}
+	config.Contexts["prod-ro"] = &clientcmdapi.Context{Cluster: "eks", AuthInfo: "arn:aws:eks:eu-west-1:1234:cluster/prod", Namespace: "readonly"}
+	config.Contexts["admin"] = &clientcmdapi.Context{Cluster: "eks", AuthInfo: "admin"}
+	if err := clientcmd.WriteToFile(*config, filepath.Join(dir, "config")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = filepath.Join(dir, "config")
+	pathOptions.EnvVar = ""
+	return dir, pathOptions
+}
+
+func fakeExecRunner(calls *int, expiry time.Time) execRunner {
+	return func(exec *clientcmdapi.ExecConfig) (*clientauthv1beta1.ExecCredential, error) {
+		if exec.Command != "aws" {
+			return nil, errors.New("unexpected plugin " + exec.Command)
+		}
+		*calls++
+		credential := &clientauthv1beta1.ExecCredential{Status: &clientauthv1beta1.ExecCredentialStatus{Token: "k8s-aws-v1.token"}}
+		credential.APIVersion = "client.authentication.k8s.io/v1beta1"
+		credential.Kind = "ExecCredential"
+		if !expiry.IsZero() {
+			credential.Status.ExpirationTimestamp = &metav1.Time{Time: expiry}
+		}
+		return credential, nil
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	dir, pathOptions := newRefreshTestConfig(t)
+	defer os.RemoveAll(dir)
+
+	calls := 0
+	expiry := time.Now().Add(time.Hour).Truncate(time.Second)
+	run := func(before time.Duration) string {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		o := &RefreshOptions{ConfigAccess: pathOptions, Before: before, CacheDir: filepath.Join(dir, "credentials"), RunExec: fakeExecRunner(&calls, expiry), IOStreams: streams}
+		if err := o.Validate(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := o.Run(nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return out.String()
+	}
+
+	expected := `user "arn:aws:eks:eu-west-1:1234:cluster/prod": refreshed, expires ` + expiry.UTC().Format(time.RFC3339) + "\n"
+	if out := run(5 * time.Minute); out != expected {
+		t.Errorf("expected %q, got %q", expected, out)
+	}
+	if calls != 1 {
+		t.Errorf("expected the plugin of the user shared by two contexts to run once, ran %d times", calls)
+	}
+	if out := run(5 * time.Minute); out != `user "arn:aws:eks:eu-west-1:1234:cluster/prod": fresh`+"\n" || calls != 1 {
+		t.Errorf("expected the cached credential to be kept, got %q after %d calls", out, calls)
+	}
+	if run(2 * time.Hour); calls != 2 {
+		t.Errorf("expected a credential expiring within --before to be refreshed, ran %d times", calls)
+	}
+
+	files, err := ioutil.ReadDir(filepath.Join(dir, "credentials"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(files) != 1 || strings.ContainsAny(files[0].Name(), "/:") {
+		t.Errorf("expected a single cache file with a valid name, got %v", files)
+	}
+}
+
+func TestRefreshWithoutExpiry(t *testing.T) {
+	dir, pathOptions := newRefreshTestConfig(t)
+	defer os.RemoveAll(dir)
+
+	calls := 0
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := &RefreshOptions{ConfigAccess: pathOptions, Contexts: []string{"prod"}, CacheDir: filepath.Join(dir, "credentials"), RunExec: fakeExecRunner(&calls, time.Time{}), IOStreams: streams}
+	if err := o.Run(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "not cached, the plugin gives no expiry") {
+		t.Errorf("unexpected output %q", out.String())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "credentials")); !os.IsNotExist(err) {
+		t.Errorf("expected nothing to be cached, got %v", err)
+	}
+}
+
+func TestRefreshWrap(t *testing.T) {
+	dir, pathOptions := newRefreshTestConfig(t)
+	defer os.RemoveAll(dir)
+	before, err := pathOptions.GetStartingConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	user := "arn:aws:eks:eu-west-1:1234:cluster/prod"
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := &RefreshOptions{ConfigAccess: pathOptions, Wrap: true, IOStreams: streams}
+	if err := o.Run(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "Wrapped 1 user(s).\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+	config, err := pathOptions.GetStartingConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exec := config.AuthInfos[user].Exec
+	if exec.Command != "kubectl" || !reflect.DeepEqual(exec.Args, []string{"config", "credential", user}) {
+		t.Errorf("expected the user to run kubectl config credential, got %v", exec)
+	}
+	if original, err := originalExec(config.AuthInfos[user]); err != nil || !reflect.DeepEqual(original, before.AuthInfos[user].Exec) {
+		t.Errorf("expected the original plugin to be kept, got %v, %v", original, err)
+	}
+
+	// Wrapping again changes nothing.
+	streams, _, out, _ = genericclioptions.NewTestIOStreams()
+	o.IOStreams = streams
+	if err := o.Run(nil); err != nil || out.String() != "Wrapped no users.\n" {
+		t.Errorf("expected nothing to be wrapped again, got %q, %v", out.String(), err)
+	}
+
+	streams, _, _, _ = genericclioptions.NewTestIOStreams()
+	o = &RefreshOptions{ConfigAccess: pathOptions, Unwrap: true, IOStreams: streams}
+	if err := o.Run(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	config, err = pathOptions.GetStartingConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(config.AuthInfos[user].Exec, before.AuthInfos[user].Exec) || len(config.AuthInfos[user].Extensions) != 0 {
+		t.Errorf("expected the original plugin to be restored, got %#v", config.AuthInfos[user])
+	}
+}
+
+func TestCredential(t *testing.T) {
+	dir, pathOptions := newRefreshTestConfig(t)
+	defer os.RemoveAll(dir)
+	user := "arn:aws:eks:eu-west-1:1234:cluster/prod"
+
+	calls := 0
+	expiry := time.Now().Add(time.Hour)
+	run := func(name string) (*clientauthv1beta1.ExecCredential, error) {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		o := &CredentialOptions{ConfigAccess: pathOptions, User: name, CacheDir: filepath.Join(dir, "credentials"), RunExec: fakeExecRunner(&calls, expiry), IOStreams: streams}
+		if err := o.Run(); err != nil {
+			return nil, err
+		}
+		credential := &clientauthv1beta1.ExecCredential{}
+		if err := json.Unmarshal(out.Bytes(), credential); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return credential, nil
+	}
+
+	if _, err := run("admin"); err == nil || !strings.Contains(err.Error(), "is not wrapped") {
+		t.Errorf("expected a user without exec plugin to fail, got %v", err)
+	}
+
+	streams, _, _, _ := genericclioptions.NewTestIOStreams()
+	if err := (&RefreshOptions{ConfigAccess: pathOptions, Wrap: true, IOStreams: streams}).Run(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		credential, err := run(user)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if credential.Kind != "ExecCredential" || credential.Status == nil || credential.Status.Token != "k8s-aws-v1.token" {
+			t.Errorf("unexpected credential %#v", credential)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected the second credential to come from the cache, the plugin ran %d times", calls)
+	}
+}
@@ -33,10 +33,11 @@ type RenameContextOptions struct {
 	ConfigAccess clientcmd.ConfigAccess
 	ContextName  string
 	NewName      string
+	CurrContext  bool
 }
 
 const (
-	renameContextUse = "rename-context CONTEXT_NAME NEW_NAME"
+	renameContextUse = "rename-context (CONTEXT_NAME | --current) NEW_NAME"
 
 	renameContextShort = "Renames a context from the kubeconfig file."
 )
@@ -45,7 +46,7 @@ var (
 	renameContextLong = templates.LongDesc(`
 		Renames a context from the kubeconfig file.
 
-		CONTEXT_NAME is the context name that you wish to change.
+		CONTEXT_NAME is the context name that you wish to change. Use "." or --current to rename the current-context.
 
 		NEW_NAME is the new name you wish to set.
 
@@ -53,7 +54,10 @@ var (
 
 	renameContextExample = templates.Examples(`
 		# Rename the context 'old-name' to 'new-name' in your kubeconfig file
-		kubectl config rename-context old-name new-name`)
+		kubectl config rename-context old-name new-name
+
+		# Rename the current-context to 'new-name'
+		kubectl config rename-context . new-name`)
 )
 
 // NewCmdConfigRenameContext creates a command object for the "rename-context" action
@@ -72,11 +76,22 @@ func NewCmdConfigRenameContext(out io.Writer, configAccess clientcmd.ConfigAcces
 			cmdutil.CheckErr(options.RunRenameContext(out))
 		},
 	}
+
+	cmd.Flags().BoolVar(&options.CurrContext, "current", options.CurrContext, "Rename the current context")
 	return cmd
 }
 
 // Complete assigns RenameContextOptions from the args.
 func (o *RenameContextOptions) Complete(cmd *cobra.Command, args []string, out io.Writer) error {
+	if o.CurrContext {
+		if len(args) != 1 {
+			return helpErrorf(cmd, "Unexpected args: %v", args)
+		}
+		o.ContextName = currentContextShorthand
+		o.NewName = args[0]
+		return nil
+	}
+
 	if len(args) != 2 {
 		return helpErrorf(cmd, "Unexpected args: %v", args)
 	}
@@ -91,12 +106,15 @@ func (o RenameContextOptions) Validate() error {
 	if len(o.NewName) == 0 {
 		return errors.New("You must specify a new non-empty context name")
 	}
+	if o.NewName == currentContextShorthand {
+		return fmt.Errorf("%q refers to the current-context and cannot be used as a context name", currentContextShorthand)
+	}
 	return nil
 }
 
 // RunRenameContext performs the execution for 'config rename-context' sub command
 func (o RenameContextOptions) RunRenameContext(out io.Writer) error {
-	config, err := o.ConfigAccess.GetStartingConfig()
+	config, index, err := loadContexts(o.ConfigAccess, o.ContextName, o.NewName)
 	if err != nil {
 		return err
 	}
@@ -106,27 +124,32 @@ func (o RenameContextOptions) RunRenameContext(out io.Writer) error {
 		configFile = o.ConfigAccess.GetExplicitFile()
 	}
 
-	context, exists := config.Contexts[o.ContextName]
+	contextName, err := resolveContextName(config, o.ContextName)
+	if err != nil {
+		return err
+	}
+
+	context, exists := config.Contexts[contextName]
 	if !exists {
-		return fmt.Errorf("cannot rename the context %q, it's not in %s", o.ContextName, configFile)
+		return fmt.Errorf("cannot rename the context %q, it's not in %s", contextName, configFile)
 	}
 
 	_, newExists := config.Contexts[o.NewName]
 	if newExists {
-		return fmt.Errorf("cannot rename the context %q, the context %q already exists in %s", o.ContextName, o.NewName, configFile)
+		return fmt.Errorf("cannot rename the context %q, the context %q already exists in %s", contextName, o.NewName, configFile)
 	}
 
 	config.Contexts[o.NewName] = context
-	delete(config.Contexts, o.ContextName)
+	delete(config.Contexts, contextName)
 
-	if config.CurrentContext == o.ContextName {
+	if config.CurrentContext == contextName {
 		config.CurrentContext = o.NewName
 	}
 
-	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+	if err := saveContexts(o.ConfigAccess, config, index, contextName, o.NewName); err != nil {
 		return err
 	}
 
-	fmt.Fprintf(out, "Context %q renamed to %q.\n", o.ContextName, o.NewName)
+	fmt.Fprintf(out, "Context %q renamed to %q.\n", contextName, o.NewName)
 	return nil
 }
@@ -44,6 +44,7 @@ type renameContextTest struct {
 	initialConfig  clientcmdapi.Config // initial config
 	expectedConfig clientcmdapi.Config // expected config
 	args           []string            // kubectl rename-context args
+	currContext    bool                // kubectl rename-context --current
 	expectedOut    string              // expected out message
 	expectedErr    string              // expected error message
 }
@@ -68,6 +69,62 @@ func TestRenameContext(t *testing.T) {
 	test.run(t)
 }
 
+func TestRenameCurrentContextShorthand(t *testing.T) {
+	initialConfig := clientcmdapi.Config{
+		CurrentContext: currentContext,
+		Contexts:       map[string]*clientcmdapi.Context{currentContext: contextData}}
+
+	expectedConfig := clientcmdapi.Config{
+		CurrentContext: newContext,
+		Contexts:       map[string]*clientcmdapi.Context{newContext: contextData}}
+
+	test := renameContextTest{
+		description:    "Testing for kubectl config rename-context using '.' to refer to the CurrentContext",
+		initialConfig:  initialConfig,
+		expectedConfig: expectedConfig,
+		args:           []string{".", newContext},
+		expectedOut:    fmt.Sprintf("Context %q renamed to %q.\n", currentContext, newContext),
+		expectedErr:    "",
+	}
+	test.run(t)
+}
+
+func TestRenameCurrentContextFlag(t *testing.T) {
+	initialConfig := clientcmdapi.Config{
+		CurrentContext: currentContext,
+		Contexts:       map[string]*clientcmdapi.Context{currentContext: contextData}}
+
+	expectedConfig := clientcmdapi.Config{
+		CurrentContext: newContext,
+		Contexts:       map[string]*clientcmdapi.Context{newContext: contextData}}
+
+	test := renameContextTest{
+		description:    "Testing for kubectl config rename-context --current",
+		initialConfig:  initialConfig,
+		expectedConfig: expectedConfig,
+		args:           []string{newContext},
+		currContext:    true,
+		expectedOut:    fmt.Sprintf("Context %q renamed to %q.\n", currentContext, newContext),
+		expectedErr:    "",
+	}
+	test.run(t)
+}
+
+func TestRenameCurrentContextShorthandUnset(t *testing.T) {
+	initialConfig := clientcmdapi.Config{
+		Contexts: map[string]*clientcmdapi.Context{currentContext: contextData}}
+
+	test := renameContextTest{
+		description:    "Testing for kubectl config rename-context using '.' without a CurrentContext",
+		initialConfig:  initialConfig,
+		expectedConfig: initialConfig,
+		args:           []string{".", newContext},
+		expectedOut:    "",
+		expectedErr:    "current-context is not set",
+	}
+	test.run(t)
+}
+
 func TestRenameNonexistentContext(t *testing.T) {
 	initialConfig := clientcmdapi.Config{
 		CurrentContext: currentContext,
@@ -114,15 +171,18 @@ func (test renameContextTest) run(t *testing.T) {
 	pathOptions.GlobalFile = fakeKubeFile.Name()
 	pathOptions.EnvVar = ""
 	options := RenameContextOptions{
-		configAccess: pathOptions,
-		contextName:  test.args[0],
-		newName:      test.args[1],
+		ConfigAccess: pathOptions,
+		CurrContext:  test.currContext,
 	}
 	buf := bytes.NewBuffer([]byte{})
-	cmd := NewCmdConfigRenameContext(buf, options.configAccess)
+	cmd := NewCmdConfigRenameContext(buf, options.ConfigAccess)
 
-	options.Complete(cmd, test.args, buf)
-	options.Validate()
+	if err := options.Complete(cmd, test.args, buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := options.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
 	err = options.RunRenameContext(buf)
 
 	if len(test.expectedErr) != 0 {
@@ -0,0 +1,446 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// metadataSnapshotFile returns the state store of "config repair", the metadata of the contexts
+// and clusters as the config subcommands last wrote them.
+func metadataSnapshotFile() string {
+	return filepath.Join(stateDir(), "metadata-snapshot.json")
+}
+
+// metadataSnapshot holds the namespaces and kubecfg.io extensions of the contexts and clusters, by
+// entry name. The server of every entry finds it again once a cloud CLI renamed it. ModTimes are
+// the modification times of the kubeconfig files once recorded.
+type metadataSnapshot struct {
+	Contexts map[string]contextSnapshot `json:"contexts,omitempty"`
+	Clusters map[string]clusterSnapshot `json:"clusters,omitempty"`
+	ModTimes map[string]time.Time       `json:"modTimes,omitempty"`
+}
+
+type contextSnapshot struct {
+	Server     string                     `json:"server,omitempty"`
+	Namespace  string                     `json:"namespace,omitempty"`
+	Extensions map[string]json.RawMessage `json:"extensions,omitempty"`
+}
+
+type clusterSnapshot struct {
+	Server     string                     `json:"server,omitempty"`
+	Extensions map[string]json.RawMessage `json:"extensions,omitempty"`
+}
+
+// kubeconfigDamage is damage found in kubeconfig, with the change repairing it.
+type kubeconfigDamage struct {
+	Message string
+	// Contexts are the names of the contexts the repair changes.
+	Contexts []string
+	repair   func(config *clientcmdapi.Config)
+}
+
+// RepairOptions holds the command-line options for 'config repair' sub command
+type RepairOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	SnapshotFile string
+	DryRun       bool
+	// Confirm asks before the contexts the confirm setting covers are repaired.
+	Confirm changeConfirmer
+
+	genericclioptions.IOStreams
+}
+
+var (
+	repairLong = templates.LongDesc(`
+		Repair kubeconfig after a cloud CLI rewrote it.
+
+		"aws eks update-kubeconfig", "gcloud container clusters get-credentials" and
+		"az aks get-credentials" write their context, cluster and user entries over the existing
+		ones, dropping the namespace and the metadata the config subcommands keep in them, and
+		add entries again under their own names when they were renamed.
+
+		After every config subcommand that writes kubeconfig, the namespaces and kubecfg.io
+		extensions of its contexts and clusters are recorded. Against this record, repair:
+
+		* restores the namespace and the metadata, such as tags, owners or read-only marks, of the
+		  contexts and clusters that lost them
+		* renames a context back when it was added again under another name for the same server,
+		  and none is left under its name
+		* deletes a context added for the server of a context that is still there, and switches
+		  to the latter when it was made the current-context
+
+		Users are not restored, as their credentials are the ones the cloud CLI just wrote. With
+		--dry-run, the damage is only reported.`)
+
+	repairExample = templates.Examples(`
+		# Show what aws eks update-kubeconfig broke
+		kubectl config repair --dry-run
+
+		# Repair it
+		kubectl config repair`)
+)
+
+// NewCmdConfigRepair returns a Command instance for 'config repair' sub command
+func NewCmdConfigRepair(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &RepairOptions{ConfigAccess: configAccess, SnapshotFile: metadataSnapshotFile(), IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "repair [--dry-run]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Restore the metadata and names a cloud CLI overwrote in kubeconfig"),
+		Long:                  repairLong,
+		Example:               repairExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.Confirm = newChangeConfirmer(cmd, configAccess, streams.In, streams.ErrOut)
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().BoolVar(&o.DryRun, "dry-run", o.DryRun, "If true, only report the damage")
+	return cmd
+}
+
+// Run repairs the damage found in kubeconfig
+func (o *RepairOptions) Run() error {
+	snapshot, err := readMetadataSnapshot(o.SnapshotFile)
+	if err != nil {
+		return err
+	}
+	if snapshot == nil {
+		return fmt.Errorf("no metadata was recorded in %s yet, it is by the next config subcommand that writes kubeconfig", o.SnapshotFile)
+	}
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	damage, err := findKubeconfigDamage(config, snapshot)
+	if err != nil {
+		return err
+	}
+	if len(damage) == 0 {
+		fmt.Fprintln(o.Out, "Nothing to repair.")
+		return nil
+	}
+	if !o.DryRun {
+		changed := sets.NewString()
+		for _, d := range damage {
+			changed.Insert(d.Contexts...)
+		}
+		if err := o.Confirm.check(changed.List()); err != nil {
+			return err
+		}
+	}
+	for _, d := range damage {
+		fmt.Fprintln(o.Out, d.Message)
+		if !o.DryRun {
+			d.repair(config)
+		}
+	}
+	if o.DryRun {
+		return nil
+	}
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "Repaired %d problem(s).\n", len(damage))
+	return nil
+}
+
+// findKubeconfigDamage compares kubeconfig with the snapshot of its metadata, and returns the damage
+// cloud CLIs typically leave: contexts and clusters that lost their metadata, contexts added again
+// under another name, and contexts duplicated for the same server.
+func findKubeconfigDamage(config *clientcmdapi.Config, snapshot *metadataSnapshot) ([]kubeconfigDamage, error) {
+	damage := []kubeconfigDamage{}
+
+	// contexts unknown to the snapshot are the ones added since, by server
+	added := map[string][]string{}
+	for _, name := range sets.StringKeySet(config.Contexts).List() {
+		if _, ok := snapshot.Contexts[name]; ok {
+			continue
+		}
+		if server := contextServer(config, config.Contexts[name]); len(server) > 0 {
+			added[server] = append(added[server], name)
+		}
+	}
+
+	for _, name := range sets.StringKeySet(snapshot.Contexts).List() {
+		recorded := snapshot.Contexts[name]
+		if context, ok := config.Contexts[name]; ok {
+			if d, ok := restoreContext(name, context, recorded); ok {
+				damage = append(damage, d)
+			}
+			continue
+		}
+		candidates := added[recorded.Server]
+		if len(recorded.Server) == 0 || len(candidates) != 1 {
+			continue
+		}
+		from, to := candidates[0], name
+		delete(added, recorded.Server)
+		damage = append(damage, kubeconfigDamage{
+			Message:  fmt.Sprintf("Context %q was added again as %q: renaming it back.", to, from),
+			Contexts: []string{from},
+			repair: func(config *clientcmdapi.Config) {
+				context := config.Contexts[from]
+				delete(config.Contexts, from)
+				config.Contexts[to] = context
+				if config.CurrentContext == from {
+					config.CurrentContext = to
+				}
+				if d, ok := restoreContext(to, context, snapshot.Contexts[to]); ok {
+					d.repair(config)
+				}
+			},
+		})
+	}
+
+	for _, name := range sets.StringKeySet(snapshot.Contexts).List() {
+		recorded := snapshot.Contexts[name]
+		if _, ok := config.Contexts[name]; !ok || len(recorded.Server) == 0 {
+			continue
+		}
+		for _, duplicate := range added[recorded.Server] {
+			duplicate, original := duplicate, name
+			damage = append(damage, kubeconfigDamage{
+				Message:  fmt.Sprintf("Context %q duplicates %q: deleting it.", duplicate, original),
+				Contexts: []string{duplicate},
+				repair: func(config *clientcmdapi.Config) {
+					delete(config.Contexts, duplicate)
+					if config.CurrentContext == duplicate {
+						config.CurrentContext = original
+					}
+				},
+			})
+		}
+		delete(added, recorded.Server)
+	}
+
+	for _, name := range sets.StringKeySet(snapshot.Clusters).List() {
+		recorded := snapshot.Clusters[name]
+		if len(recorded.Extensions) == 0 {
+			continue
+		}
+		target := name
+		if _, ok := config.Clusters[name]; !ok {
+			target = ""
+			for _, other := range sets.StringKeySet(config.Clusters).List() {
+				if _, known := snapshot.Clusters[other]; !known && config.Clusters[other].Server == recorded.Server {
+					target = other
+					break
+				}
+			}
+			if len(target) == 0 {
+				continue
+			}
+		}
+		missing := missingExtensions(config.Clusters[target].Extensions, recorded.Extensions)
+		if len(missing) == 0 {
+			continue
+		}
+		message := fmt.Sprintf("Cluster %q lost %d extension(s): restoring them.", name, len(missing))
+		if target != name {
+			message = fmt.Sprintf("Cluster %q was added again as %q: restoring %d extension(s).", name, target, len(missing))
+		}
+		damage = append(damage, kubeconfigDamage{
+			Message:  message,
+			Contexts: contextsUsing(config, confirmCluster, target),
+			repair: func(config *clientcmdapi.Config) {
+				restoreExtensions(&config.Clusters[target].Extensions, recorded.Extensions, missing)
+			},
+		})
+	}
+	return damage, nil
+}
+
+// restoreContext returns the damage of a context that lost the namespace or the extensions it was
+// recorded with.
+func restoreContext(name string, context *clientcmdapi.Context, recorded contextSnapshot) (kubeconfigDamage, bool) {
+	missing := missingExtensions(context.Extensions, recorded.Extensions)
+	lostNamespace := len(context.Namespace) == 0 && len(recorded.Namespace) > 0
+	if len(missing) == 0 && !lostNamespace {
+		return kubeconfigDamage{}, false
+	}
+	message := fmt.Sprintf("Context %q lost %d extension(s): restoring them.", name, len(missing))
+	if lostNamespace {
+		message = fmt.Sprintf("Context %q lost its namespace %q and %d extension(s): restoring them.", name, recorded.Namespace, len(missing))
+	}
+	return kubeconfigDamage{
+		Message:  message,
+		Contexts: []string{name},
+		repair: func(config *clientcmdapi.Config) {
+			context := config.Contexts[name]
+			if lostNamespace {
+				context.Namespace = recorded.Namespace
+			}
+			restoreExtensions(&context.Extensions, recorded.Extensions, missing)
+		},
+	}, true
+}
+
+// missingExtensions returns the names of the recorded extensions that are not set.
+func missingExtensions(extensions map[string]runtime.Object, recorded map[string]json.RawMessage) []string {
+	missing := []string{}
+	for _, name := range sets.StringKeySet(recorded).List() {
+		if _, ok := extensions[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// restoreExtensions sets the recorded extensions called names.
+func restoreExtensions(extensions *map[string]runtime.Object, recorded map[string]json.RawMessage, names []string) {
+	if *extensions == nil {
+		*extensions = map[string]runtime.Object{}
+	}
+	for _, name := range names {
+		(*extensions)[name] = &runtime.Unknown{Raw: recorded[name], ContentType: runtime.ContentTypeJSON}
+	}
+}
+
+// contextServer returns the server of the cluster of a context, or an empty string.
+func contextServer(config *clientcmdapi.Config, context *clientcmdapi.Context) string {
+	if cluster, ok := config.Clusters[context.Cluster]; ok {
+		return cluster.Server
+	}
+	return ""
+}
+
+// takeMetadataSnapshot records the namespaces and kubecfg.io extensions of the contexts and clusters.
+func takeMetadataSnapshot(config *clientcmdapi.Config) (*metadataSnapshot, error) {
+	snapshot := &metadataSnapshot{Contexts: map[string]contextSnapshot{}, Clusters: map[string]clusterSnapshot{}}
+	for name, context := range config.Contexts {
+		extensions, err := pluginExtensions(context.Extensions)
+		if err != nil {
+			return nil, fmt.Errorf("context %q: %v", name, err)
+		}
+		snapshot.Contexts[name] = contextSnapshot{Server: contextServer(config, context), Namespace: context.Namespace, Extensions: extensions}
+	}
+	for name, cluster := range config.Clusters {
+		extensions, err := pluginExtensions(cluster.Extensions)
+		if err != nil {
+			return nil, fmt.Errorf("cluster %q: %v", name, err)
+		}
+		snapshot.Clusters[name] = clusterSnapshot{Server: cluster.Server, Extensions: extensions}
+	}
+	return snapshot, nil
+}
+
+func readMetadataSnapshot(filename string) (*metadataSnapshot, error) {
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &metadataSnapshot{}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return snapshot, nil
+}
+
+func writeMetadataSnapshot(filename string, snapshot *metadataSnapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, append(data, '\n'), 0600)
+}
+
+// kubeconfigModTimes returns the modification times of the kubeconfig files of the loading chain,
+// to tell whether a subcommand wrote one of them.
+func kubeconfigModTimes(configAccess clientcmd.ConfigAccess) map[string]time.Time {
+	files := configAccess.GetLoadingPrecedence()
+	if configAccess.IsExplicitFile() {
+		files = []string{configAccess.GetExplicitFile()}
+	}
+	times := map[string]time.Time{}
+	for _, filename := range files {
+		if info, err := os.Stat(filename); err == nil {
+			times[filename] = info.ModTime()
+		}
+	}
+	return times
+}
+
+// sameModTimes reports whether the kubeconfig files have the same modification times in a and b.
+func sameModTimes(a, b map[string]time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for filename, t := range a {
+		if other, ok := b[filename]; !ok || !other.Equal(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// updateMetadataSnapshot records the metadata of kubeconfig when a subcommand wrote it, from before
+// to after, or when none was recorded yet. When another tool also wrote kubeconfig since the last
+// snapshot, it is kept as long as kubeconfig is damaged compared to it, so that "config repair" can
+// still restore what a cloud CLI overwrote. It runs after every config subcommand.
+func updateMetadataSnapshot(filename string, configAccess clientcmd.ConfigAccess, before, after map[string]time.Time) error {
+	previous, err := readMetadataSnapshot(filename)
+	if err != nil || (previous != nil && sameModTimes(before, after)) {
+		return err
+	}
+	config, err := configAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	if previous != nil && !sameModTimes(previous.ModTimes, before) {
+		damage, err := findKubeconfigDamage(config, previous)
+		if err != nil {
+			return err
+		}
+		if len(damage) > 0 {
+			return fmt.Errorf("kubeconfig looks damaged by another tool, run \"kubectl config repair --dry-run\"")
+		}
+	}
+	snapshot, err := takeMetadataSnapshot(config)
+	if err != nil {
+		return err
+	}
+	snapshot.ModTimes = after
+	return writeMetadataSnapshot(filename, snapshot)
+}
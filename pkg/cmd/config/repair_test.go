@@ -0,0 +1,260 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+// newRepairTestConfig returns the kubeconfig as the config subcommands left it: federal-context in
+// namespace web and read-only, and prod in namespace shop, on clusters with metadata.
+func newRepairTestConfig(t *testing.T) *clientcmdapi.Config {
+	config := newRedFederalCowHammerConfig()
+	config.Contexts["federal-context"].Namespace = "web"
+	config.Contexts["prod"] = &clientcmdapi.Context{Cluster: "prod-cluster", AuthInfo: "red-user", Namespace: "shop"}
+	config.Clusters["prod-cluster"] = &clientcmdapi.Cluster{Server: "https://prod.example.com"}
+	if err := writeExtension(&config.Contexts["federal-context"].Extensions, readOnlyExtension, readOnly{Enabled: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := writeExtension(&config.Clusters["cow-cluster"].Extensions, preferIPv6Extension, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := writeExtension(&config.Clusters["prod-cluster"].Extensions, preferIPv6Extension, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return &config
+}
+
+func TestRepair(t *testing.T) {
+	dir, err := ioutil.TempDir("", "repair")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	snapshotFile := filepath.Join(dir, "metadata-snapshot.json")
+	snapshot, err := takeMetadataSnapshot(newRepairTestConfig(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := writeMetadataSnapshot(snapshotFile, snapshot); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// what a cloud CLI leaves: entries written over, and added again under its own names
+	config := newRedFederalCowHammerConfig()
+	config.Contexts["arn:aws:eks:cow"] = &clientcmdapi.Context{Cluster: "cow-cluster", AuthInfo: "red-user"}
+	config.Contexts["gke_prod"] = &clientcmdapi.Context{Cluster: "gke_prod", AuthInfo: "red-user"}
+	config.Clusters["gke_prod"] = &clientcmdapi.Cluster{Server: "https://prod.example.com"}
+	config.CurrentContext = "arn:aws:eks:cow"
+	if err := clientcmd.WriteToFile(config, filepath.Join(dir, "config")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = filepath.Join(dir, "config")
+	pathOptions.EnvVar = ""
+
+	run := func(dryRun bool) string {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		o := &RepairOptions{ConfigAccess: pathOptions, SnapshotFile: snapshotFile, DryRun: dryRun, IOStreams: streams}
+		if err := o.Run(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return out.String()
+	}
+
+	expected := []string{
+		`Context "federal-context" lost its namespace "web" and 1 extension(s): restoring them.`,
+		`Context "prod" was added again as "gke_prod": renaming it back.`,
+		`Context "arn:aws:eks:cow" duplicates "federal-context": deleting it.`,
+		`Cluster "cow-cluster" lost 1 extension(s): restoring them.`,
+		`Cluster "prod-cluster" was added again as "gke_prod": restoring 1 extension(s).`,
+	}
+	if out := run(true); out != strings.Join(expected, "\n")+"\n" {
+		t.Errorf("expected the damage:\n%s\ngot:\n%s", strings.Join(expected, "\n"), out)
+	}
+	if out := run(false); !strings.HasSuffix(out, "Repaired 5 problem(s).\n") {
+		t.Errorf("unexpected output %q", out)
+	}
+
+	repaired, err := clientcmd.LoadFromFile(pathOptions.GlobalFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repaired.CurrentContext != "federal-context" {
+		t.Errorf("expected the current-context to be switched back, got %q", repaired.CurrentContext)
+	}
+	if _, ok := repaired.Contexts["arn:aws:eks:cow"]; ok {
+		t.Errorf("expected the duplicate context to be deleted")
+	}
+	if context := repaired.Contexts["prod"]; context == nil || context.Namespace != "shop" || context.Cluster != "gke_prod" {
+		t.Errorf("expected prod to be renamed back, got %+v", context)
+	}
+	if enabled, err := isReadOnly(repaired.Contexts["federal-context"]); err != nil || !enabled || repaired.Contexts["federal-context"].Namespace != "web" {
+		t.Errorf("expected federal-context to be restored, got %+v", repaired.Contexts["federal-context"])
+	}
+	if _, ok := repaired.Clusters["gke_prod"].Extensions[preferIPv6Extension]; !ok {
+		t.Errorf("expected the extension of the cluster to be restored")
+	}
+	if out := run(false); out != "Nothing to repair.\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+}
+
+func TestUpdateMetadataSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "repair")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	snapshotFile := filepath.Join(dir, "metadata-snapshot.json")
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = filepath.Join(dir, "config")
+	pathOptions.EnvVar = ""
+	write := func(config *clientcmdapi.Config, modTime time.Time) map[string]time.Time {
+		if err := clientcmd.WriteToFile(*config, pathOptions.GlobalFile); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := os.Chtimes(pathOptions.GlobalFile, modTime, modTime); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return kubeconfigModTimes(pathOptions)
+	}
+	recorded := func() *metadataSnapshot {
+		snapshot, err := readMetadataSnapshot(snapshotFile)
+		if err != nil || snapshot == nil {
+			t.Fatalf("expected a snapshot, got %v", err)
+		}
+		return snapshot
+	}
+	now := time.Date(2019, 8, 1, 0, 0, 0, 0, time.UTC)
+
+	// the first run records the metadata
+	config := newRepairTestConfig(t)
+	first := write(config, now)
+	if err := updateMetadataSnapshot(snapshotFile, pathOptions, first, first); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if recorded().Contexts["federal-context"].Namespace != "web" {
+		t.Errorf("expected the namespace to be recorded, got %+v", recorded())
+	}
+
+	// a subcommand adding a context for the same server is not damage
+	config.Contexts["federal-admin"] = &clientcmdapi.Context{Cluster: "cow-cluster", AuthInfo: "red-user"}
+	second := write(config, now.Add(time.Minute))
+	if err := updateMetadataSnapshot(snapshotFile, pathOptions, first, second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := recorded().Contexts["federal-admin"]; !ok {
+		t.Errorf("expected the new context to be recorded")
+	}
+
+	// a cloud CLI overwrites federal-context, then a subcommand writes kubeconfig
+	config.Contexts["federal-context"] = &clientcmdapi.Context{Cluster: "cow-cluster", AuthInfo: "red-user"}
+	third := write(config, now.Add(2*time.Minute))
+	fourth := write(config, now.Add(3*time.Minute))
+	if err := updateMetadataSnapshot(snapshotFile, pathOptions, third, fourth); err == nil || !strings.Contains(err.Error(), "config repair") {
+		t.Errorf("expected the damage to be reported, got %v", err)
+	}
+	if recorded().Contexts["federal-context"].Namespace != "web" {
+		t.Errorf("expected the snapshot to be kept, got %+v", recorded())
+	}
+}
+
+func TestRepairSops(t *testing.T) {
+	dir, err := ioutil.TempDir("", "repair")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.Setenv("XDG_CONFIG_HOME", os.Getenv("XDG_CONFIG_HOME"))
+	defer os.Setenv("XDG_STATE_HOME", os.Getenv("XDG_STATE_HOME"))
+	os.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	os.Setenv("XDG_STATE_HOME", filepath.Join(dir, "state"))
+	edits := 0
+	defer func(original func([]string, ...string) ([]byte, error)) { sopsCommand = original }(sopsCommand)
+	sopsCommand = fakeSops(t, &edits)
+
+	kubeconfig := filepath.Join(dir, "kubeconfig")
+	plaintext, err := clientcmd.Write(newRedFederalCowHammerConfig())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	writeFakeSopsFile(t, kubeconfig, plaintext)
+	run := func(args ...string) string {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		cmd := NewCmdConfig(cmdutil.NewFactory(genericclioptions.NewTestConfigFlags()), clientcmd.NewDefaultPathOptions(), streams)
+		cmd.SetOutput(ioutil.Discard)
+		cmd.SetArgs(append([]string{"-f", kubeconfig}, args...))
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return out.String()
+	}
+	decrypt := func() *clientcmdapi.Config {
+		data, err := sopsCommand(nil, "--decrypt", kubeconfig)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		config, err := clientcmd.Load(data)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return config
+	}
+
+	run("set-context", "federal-context", "--namespace=web")
+	snapshot, err := readMetadataSnapshot(metadataSnapshotFile())
+	if err != nil || snapshot == nil {
+		t.Fatalf("expected a snapshot, got %v, %v", snapshot, err)
+	}
+	info, err := os.Stat(kubeconfig)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if modTime, ok := snapshot.ModTimes[kubeconfig]; len(snapshot.ModTimes) != 1 || !ok || !modTime.Equal(info.ModTime()) {
+		t.Errorf("expected the snapshot to hold the modification time of the encrypted file %s, got %v", info.ModTime(), snapshot.ModTimes)
+	}
+	if snapshot.Contexts["federal-context"].Namespace != "web" {
+		t.Errorf("expected the snapshot to be taken from the decrypted kubeconfig, got %+v", snapshot.Contexts)
+	}
+
+	// Another tool writes the file again, dropping the namespace.
+	writeFakeSopsFile(t, kubeconfig, plaintext)
+	later := info.ModTime().Add(time.Minute)
+	if err := os.Chtimes(kubeconfig, later, later); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out := run("repair", "--dry-run"); !strings.Contains(out, `"federal-context"`) {
+		t.Errorf("expected the lost namespace to be reported, got %q", out)
+	}
+	if out := run("repair"); !strings.Contains(out, "Repaired 1 problem(s).") {
+		t.Errorf("expected the lost namespace to be repaired, got %q", out)
+	}
+	if namespace := decrypt().Contexts["federal-context"].Namespace; namespace != "web" {
+		t.Errorf("expected the namespace to be restored in the encrypted file, got %q", namespace)
+	}
+}
@@ -0,0 +1,254 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+const (
+	awsProfileEnvVar       = "AWS_PROFILE"
+	awsRegionEnvVar        = "AWS_REGION"
+	awsDefaultRegionEnvVar = "AWS_DEFAULT_REGION"
+)
+
+// RewriteAWSOptions holds the command-line options for 'config rewrite-aws' sub command
+type RewriteAWSOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Users        []string
+	Selector     string
+	FromProfile  string
+	Profile      string
+	Region       string
+	DryRun       bool
+
+	genericclioptions.IOStreams
+}
+
+var (
+	rewriteAWSLong = templates.LongDesc(`
+		Change the AWS profile and region of EKS users in bulk.
+
+		Users running "aws eks get-token" are rewritten: --profile sets AWS_PROFILE in the env of
+		the exec entry, and replaces a --profile argument if there is one; --region replaces the
+		--region argument, adding it when missing, and updates AWS_REGION and AWS_DEFAULT_REGION
+		when the entry sets them.
+
+		The users rewritten are the ones named, the ones used by the contexts matching --selector,
+		or every EKS user. --from-profile limits the rewrite to users currently using that profile,
+		which makes renaming a profile a single command.`)
+
+	rewriteAWSExample = templates.Examples(`
+		# Rename the "legacy" AWS profile to "platform" in every EKS user
+		kubectl config rewrite-aws --from-profile legacy --profile platform
+
+		# Move the users of production contexts to another region
+		kubectl config rewrite-aws --selector prod --region eu-west-1 --dry-run`)
+)
+
+// NewCmdConfigRewriteAWS returns a Command instance for 'config rewrite-aws' sub command
+func NewCmdConfigRewriteAWS(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &RewriteAWSOptions{
+		ConfigAccess: configAccess,
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "rewrite-aws [USER_NAME...] [--profile=PROFILE] [--region=REGION] [--selector=SELECTOR] [--from-profile=PROFILE] [--dry-run]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Change the AWS profile and region of EKS users in bulk"),
+		Long:                  rewriteAWSLong,
+		Example:               rewriteAWSExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			o.Users = args
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().StringVar(&o.Profile, "profile", o.Profile, "AWS profile the users use")
+	cmd.Flags().StringVar(&o.Region, "region", o.Region, "AWS region the users request tokens for")
+	cmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, "Rewrite the users of the contexts whose tags match this selector, such as prod or region=eu")
+	cmd.Flags().StringVar(&o.FromProfile, "from-profile", o.FromProfile, "Only rewrite users currently using this AWS profile")
+	cmd.Flags().BoolVar(&o.DryRun, "dry-run", o.DryRun, "If true, only print the changes that would be made")
+	return cmd
+}
+
+// Validate checks that there is something to rewrite
+func (o *RewriteAWSOptions) Validate() error {
+	if len(o.Profile) == 0 && len(o.Region) == 0 {
+		return errors.New("at least one of --profile or --region is required")
+	}
+	if len(o.Users) > 0 && len(o.Selector) > 0 {
+		return errors.New("user names and --selector cannot be combined")
+	}
+	return nil
+}
+
+// Run performs the execution of 'config rewrite-aws' sub command
+func (o *RewriteAWSOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+
+	names := o.Users
+	explicit := len(names) > 0
+	switch {
+	case explicit:
+	case len(o.Selector) > 0:
+		contexts, err := selectContexts(config, o.Selector)
+		if err != nil {
+			return err
+		}
+		users := sets.NewString()
+		for _, name := range contexts {
+			users.Insert(config.Contexts[name].AuthInfo)
+		}
+		names = users.List()
+	default:
+		for name := range config.AuthInfos {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	rewritten := 0
+	for _, name := range names {
+		authInfo, exists := config.AuthInfos[name]
+		if !exists {
+			return fmt.Errorf("user %q not found", name)
+		}
+		if !isAWSExec(authInfo.Exec) {
+			if explicit {
+				return fmt.Errorf("user %q does not run aws eks get-token", name)
+			}
+			continue
+		}
+		if len(o.FromProfile) > 0 && awsProfile(authInfo.Exec) != o.FromProfile {
+			continue
+		}
+
+		modified := *authInfo
+		modified.Exec = o.rewrite(authInfo.Exec)
+		if o.DryRun {
+			if err := writeAuthInfoDiff(o.Out, "users/"+name, "users/"+name, name, authInfo, &modified); err != nil {
+				return err
+			}
+		} else {
+			fmt.Fprintf(o.Out, "User %q rewritten.\n", name)
+		}
+		config.AuthInfos[name] = &modified
+		rewritten++
+	}
+
+	if rewritten == 0 {
+		fmt.Fprintln(o.Out, "No EKS users to rewrite.")
+		return nil
+	}
+	if o.DryRun {
+		return nil
+	}
+	return clientcmd.ModifyConfig(o.ConfigAccess, *config, true)
+}
+
+// rewrite returns a copy of exec using the profile and region of the options.
+func (o *RewriteAWSOptions) rewrite(exec *clientcmdapi.ExecConfig) *clientcmdapi.ExecConfig {
+	rewritten := exec.DeepCopy()
+	if len(o.Profile) > 0 {
+		setExecEnv(rewritten, awsProfileEnvVar, o.Profile, true)
+		rewritten.Args, _ = replaceFlagValue(rewritten.Args, "profile", o.Profile)
+	}
+	if len(o.Region) > 0 {
+		var found bool
+		if rewritten.Args, found = replaceFlagValue(rewritten.Args, "region", o.Region); !found {
+			rewritten.Args = append(rewritten.Args, "--region", o.Region)
+		}
+		setExecEnv(rewritten, awsRegionEnvVar, o.Region, false)
+		setExecEnv(rewritten, awsDefaultRegionEnvVar, o.Region, false)
+	}
+	return rewritten
+}
+
+// isAWSExec reports whether exec runs "aws eks get-token".
+func isAWSExec(exec *clientcmdapi.ExecConfig) bool {
+	if exec == nil || strings.TrimSuffix(filepath.Base(exec.Command), ".exe") != "aws" {
+		return false
+	}
+	args := strings.Join(exec.Args, " ")
+	return strings.Contains(" "+args+" ", " eks get-token ")
+}
+
+// awsProfile returns the profile an aws exec entry uses: its --profile argument, or AWS_PROFILE.
+func awsProfile(exec *clientcmdapi.ExecConfig) string {
+	if profile := execFlagValue(exec.Args, "profile"); len(profile) > 0 {
+		return profile
+	}
+	for _, env := range exec.Env {
+		if env.Name == awsProfileEnvVar {
+			return env.Value
+		}
+	}
+	return ""
+}
+
+// setExecEnv sets the variable in the env of exec when it is there, or when add is true.
+func setExecEnv(exec *clientcmdapi.ExecConfig, name, value string, add bool) {
+	for i := range exec.Env {
+		if exec.Env[i].Name == name {
+			exec.Env[i].Value = value
+			return
+		}
+	}
+	if add {
+		exec.Env = append(exec.Env, clientcmdapi.ExecEnvVar{Name: name, Value: value})
+	}
+}
+
+// replaceFlagValue sets the value of every --name flag in args, in both the "--name value" and the
+// "--name=value" form, and reports whether there was one.
+func replaceFlagValue(args []string, name, value string) ([]string, bool) {
+	replaced := append([]string{}, args...)
+	found := false
+	for i := 0; i < len(replaced); i++ {
+		switch {
+		case replaced[i] == "--"+name && i+1 < len(replaced):
+			i++
+			replaced[i] = value
+			found = true
+		case strings.HasPrefix(replaced[i], "--"+name+"="):
+			replaced[i] = "--" + name + "=" + value
+			found = true
+		}
+	}
+	return replaced, found
+}
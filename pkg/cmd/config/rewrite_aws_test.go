@@ -0,0 +1,156 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func newEKSExec(profile string, args ...string) *clientcmdapi.ExecConfig {
+	exec := &clientcmdapi.ExecConfig{
+		Command:    "aws",
+		Args:       append([]string{"eks", "get-token", "--cluster-name", "prod"}, args...),
+		APIVersion: "client.authentication.k8s.io/v1beta1",
+	}
+	if len(profile) > 0 {
+		exec.Env = []clientcmdapi.ExecEnvVar{{Name: "AWS_PROFILE", Value: profile}}
+	}
+	return exec
+}
+
+func TestRewriteAWS(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  RewriteAWSOptions
+		exec     *clientcmdapi.ExecConfig
+		expected *clientcmdapi.ExecConfig
+	}{
+		{
+			name:     "profile in the env",
+			options:  RewriteAWSOptions{Profile: "platform"},
+			exec:     newEKSExec("legacy"),
+			expected: newEKSExec("platform"),
+		},
+		{
+			name:     "profile added to the env and replaced in the args",
+			options:  RewriteAWSOptions{Profile: "platform"},
+			exec:     newEKSExec("", "--profile", "legacy"),
+			expected: newEKSExec("platform", "--profile", "platform"),
+		},
+		{
+			name:     "region replaced",
+			options:  RewriteAWSOptions{Region: "eu-west-1"},
+			exec:     newEKSExec("legacy", "--region=us-east-1"),
+			expected: newEKSExec("legacy", "--region=eu-west-1"),
+		},
+		{
+			name:     "region added",
+			options:  RewriteAWSOptions{Region: "eu-west-1"},
+			exec:     newEKSExec("legacy"),
+			expected: newEKSExec("legacy", "--region", "eu-west-1"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rewritten := test.options.rewrite(test.exec)
+			if !reflect.DeepEqual(rewritten, test.expected) {
+				t.Errorf("expected %#v\nbut got %#v", test.expected, rewritten)
+			}
+		})
+	}
+}
+
+func TestRewriteAWSRun(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	startingConfig := clientcmdapi.Config{
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{
+			"eks-prod":    {Exec: newEKSExec("legacy")},
+			"eks-staging": {Exec: newEKSExec("legacy")},
+			"eks-other":   {Exec: newEKSExec("other")},
+			"token-user":  {Token: "token"},
+		},
+		Contexts: map[string]*clientcmdapi.Context{
+			"prod":    {AuthInfo: "eks-prod"},
+			"staging": {AuthInfo: "eks-staging"},
+		},
+	}
+	if err := writeExtension(&startingConfig.Contexts["prod"].Extensions, contextMetadataExtension, contextMetadata{Tags: []string{"prod"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := clientcmd.WriteToFile(startingConfig, fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	run := func(o RewriteAWSOptions) string {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		o.ConfigAccess = pathOptions
+		o.IOStreams = streams
+		if err := o.Validate(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := o.Run(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return out.String()
+	}
+	profiles := func() map[string]string {
+		config, err := clientcmd.LoadFromFile(fakeKubeFile.Name())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		profiles := map[string]string{}
+		for name, authInfo := range config.AuthInfos {
+			if authInfo.Exec != nil {
+				profiles[name] = awsProfile(authInfo.Exec)
+			}
+		}
+		return profiles
+	}
+
+	if out := run(RewriteAWSOptions{Selector: "prod", Profile: "platform"}); out != `User "eks-prod" rewritten.`+"\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+	if expected := map[string]string{"eks-prod": "platform", "eks-staging": "legacy", "eks-other": "other"}; !reflect.DeepEqual(profiles(), expected) {
+		t.Errorf("expected %v, got %v", expected, profiles())
+	}
+
+	run(RewriteAWSOptions{FromProfile: "legacy", Profile: "platform"})
+	if expected := map[string]string{"eks-prod": "platform", "eks-staging": "platform", "eks-other": "other"}; !reflect.DeepEqual(profiles(), expected) {
+		t.Errorf("expected %v, got %v", expected, profiles())
+	}
+
+	if out := run(RewriteAWSOptions{FromProfile: "legacy", Profile: "platform"}); out != "No EKS users to rewrite.\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+	if err := (&RewriteAWSOptions{}).Validate(); err == nil {
+		t.Errorf("expected an error without --profile or --region")
+	}
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+// The defaults of the settings safe mode fills in when they are left empty.
+const (
+	safeModeCooloff     = "30m"
+	safeModeConfirm     = confirmProtected
+	safeModeVerifyOnUse = verifyWarn
+)
+
+var (
+	// safeModeCooloffTags are the tags of prod-like contexts.
+	safeModeCooloffTags = []string{"prod", "production", "live"}
+	// safeModeProtectedPatterns match the names of prod-like contexts.
+	safeModeProtectedPatterns = []string{"*prod*", "*prd*", "*live*"}
+)
+
+// withSafeMode returns the settings to apply: settings itself when safe mode is off, and otherwise
+// a copy where the settings left empty protect prod-like contexts. Contexts tagged or named like
+// production need to be acknowledged every 30 minutes, changes to them ask for confirmation, and
+// use-context warns about credentials that do not work. Settings given explicitly are kept, which
+// allows adjusting safe mode with "config settings set".
+func withSafeMode(settings *Settings) *Settings {
+	if !settings.SafeMode {
+		return settings
+	}
+	safe := *settings
+	if len(safe.Cooloff) == 0 {
+		safe.Cooloff = safeModeCooloff
+	}
+	if len(safe.CooloffTags) == 0 {
+		safe.CooloffTags = safeModeCooloffTags
+	}
+	if len(safe.ProtectedPatterns) == 0 {
+		safe.ProtectedPatterns = safeModeProtectedPatterns
+	}
+	if len(safe.Confirm) == 0 {
+		safe.Confirm = safeModeConfirm
+	}
+	if len(safe.VerifyOnUse) == 0 {
+		safe.VerifyOnUse = safeModeVerifyOnUse
+	}
+	return &safe
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestWithSafeMode(t *testing.T) {
+	off := &Settings{Cooloff: "1h"}
+	if withSafeMode(off) != off {
+		t.Errorf("expected the settings to be used as is without safe mode")
+	}
+
+	settings := &Settings{SafeMode: true, VerifyOnUse: verifyAbort}
+	safe := withSafeMode(settings)
+	expected := &Settings{
+		SafeMode:          true,
+		Cooloff:           safeModeCooloff,
+		CooloffTags:       safeModeCooloffTags,
+		ProtectedPatterns: safeModeProtectedPatterns,
+		Confirm:           safeModeConfirm,
+		VerifyOnUse:       verifyAbort,
+	}
+	if !reflect.DeepEqual(safe, expected) {
+		t.Errorf("expected %+v, got %+v", expected, safe)
+	}
+	if len(settings.Cooloff) > 0 {
+		t.Errorf("expected the settings loaded to be left alone, so saving them does not store the defaults")
+	}
+}
+
+func TestSafeModeCooloff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "safe-mode")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := newRedFederalCowHammerConfig()
+	config.Contexts["shop-prod-eu"] = &clientcmdapi.Context{AuthInfo: "red-user", Cluster: "cow-cluster"}
+	config.Contexts["billing"] = &clientcmdapi.Context{AuthInfo: "red-user", Cluster: "cow-cluster"}
+	if err := writeContextMetadata(config.Contexts["billing"], contextMetadata{Tags: []string{"production"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	policy, err := newCooloffPolicy(&Settings{SafeMode: true})
+	if err != nil || policy == nil {
+		t.Fatalf("expected safe mode to enable a cooloff, got %v, %v", policy, err)
+	}
+	policy.Filename = filepath.Join(dir, "cooloff.yaml")
+	policy.Now = func() time.Time { return time.Date(2019, 8, 1, 12, 0, 0, 0, time.UTC) }
+	if policy.Period != 30*time.Minute {
+		t.Errorf("expected a cooloff of 30m, got %s", policy.Period)
+	}
+	for name, reason := range map[string]string{
+		"shop-prod-eu":    "is named like *prod*",
+		"billing":         "is tagged production",
+		"federal-context": "",
+	} {
+		err := policy.check(&config, name, false)
+		if len(reason) == 0 {
+			if err != nil {
+				t.Errorf("expected %s not to be protected, got %v", name, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), reason) {
+			t.Errorf("expected %s to be protected as it %s, got %v", name, reason, err)
+		}
+	}
+
+	policy, err = newCooloffPolicy(&Settings{Cooloff: "30m"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	policy.Filename = filepath.Join(dir, "cooloff.yaml")
+	if err := policy.check(&config, "shop-prod-eu", false); err != nil {
+		t.Errorf("expected names to be matched only in safe mode, got %v", err)
+	}
+}
+
+func TestInitEnablesSafeMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "safe-mode")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := &InitOptions{SettingsFile: filepath.Join(dir, "config.yaml"), IOStreams: streams}
+	if err := o.enableSafeMode(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if settings, err := loadSettings(o.SettingsFile); err != nil || !settings.SafeMode {
+		t.Errorf("expected safe mode to be enabled for a new install, got %+v, %v", settings, err)
+	}
+	if !strings.HasPrefix(out.String(), "Enabled safe mode") {
+		t.Errorf("unexpected output %q", out.String())
+	}
+
+	if err := saveSettings(o.SettingsFile, &Settings{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.enableSafeMode(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if settings, err := loadSettings(o.SettingsFile); err != nil || settings.SafeMode {
+		t.Errorf("expected the settings of an existing install to be kept, got %+v, %v", settings, err)
+	}
+}
@@ -0,0 +1,216 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// fileSchema describes one of the files of the config subcommands for 'config schema'. The schema
+// is derived from the Go type the file is read into, with descriptions and enums keyed by
+// property name.
+type fileSchema struct {
+	title        string
+	value        interface{}
+	descriptions map[string]string
+	enums        map[string][]string
+}
+
+// SchemaOptions holds the command-line options for 'config schema' sub command
+type SchemaOptions struct {
+	Kind string
+
+	genericclioptions.IOStreams
+}
+
+var (
+	schemaLong = templates.LongDesc(`
+		Print the JSON Schema of one of the files the config subcommands read, so that editors
+		validate and complete them.
+
+		The kinds are config, the settings file config.yaml, which also holds the policies such as
+		protectedPatterns, confirm and namingPattern; profiles, the profiles.yaml file of "kubectl
+		config profile"; and sources, the sources.yaml file of "kubectl config source".`)
+
+	schemaExample = templates.Examples(`
+		# Save the schema of the settings file next to it, for editors using the YAML language
+		# server to pick up with a "# yaml-language-server: $schema=config.schema.json" comment
+		kubectl config schema config > ~/.config/kubecfg/config.schema.json
+
+		# The schema of the sources file
+		kubectl config schema sources`)
+)
+
+// NewCmdConfigSchema returns a Command instance for 'config schema' sub command
+func NewCmdConfigSchema(streams genericclioptions.IOStreams) *cobra.Command {
+	o := &SchemaOptions{IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "schema config|profiles|sources",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Print the JSON Schema of a file of the config subcommands"),
+		Long:                  schemaLong,
+		Example:               schemaExample,
+		ValidArgs:             schemaKindNames(),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.Kind = args[0]
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	return cmd
+}
+
+// Run prints the schema of the kind
+func (o *SchemaOptions) Run() error {
+	kind, ok := schemaKinds()[o.Kind]
+	if !ok {
+		return fmt.Errorf("unknown kind %q, must be one of: %s", o.Kind, strings.Join(schemaKindNames(), ", "))
+	}
+	schema := kind.schema(reflect.TypeOf(kind.value))
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["title"] = kind.title
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(o.Out, "%s\n", data)
+	return err
+}
+
+// schemaKinds returns the files 'config schema' describes, by kind.
+func schemaKinds() map[string]fileSchema {
+	settings := fileSchema{
+		title:        "kubecfg settings",
+		value:        Settings{},
+		descriptions: map[string]string{},
+		enums: map[string][]string{
+			"color":       nonEmpty(validColorSettings),
+			"confirm":     nonEmpty(validConfirmSettings),
+			"onConflict":  conflictStrategyNames,
+			"verifyOnUse": nonEmpty(validVerifySettings),
+		},
+	}
+	for _, definition := range settingDefinitions {
+		settings.descriptions[definition.name] = definition.description
+	}
+
+	return map[string]fileSchema{
+		"config": settings,
+		"profiles": {
+			title: "kubecfg profiles",
+			value: profiles{},
+			descriptions: map[string]string{
+				"current":  "Name of the profile in use",
+				"profiles": "Kubeconfig files of every profile, which become $KUBECONFIG when the profile is used",
+			},
+		},
+		"sources": {
+			title: "kubecfg sources",
+			value: remoteSources{},
+			descriptions: map[string]string{
+				"sources":  "Kubeconfigs served over HTTP(S) and merged into the kubeconfig",
+				"name":     "Name of the source",
+				"url":      "HTTP(S) URL the kubeconfig is fetched from",
+				"refresh":  "How long a fetched copy is used before it is fetched again, such as 1h",
+				"auth":     "bearer:TOKEN or basic:USER:PASSWORD, environment variables are expanded",
+				"sha256":   "Checksum the fetched content must have",
+				"lastSync": "When the source was last fetched, set by kubectl config source sync",
+				"checksum": "Checksum of the last fetched content, set by kubectl config source sync",
+			},
+		},
+	}
+}
+
+func schemaKindNames() []string {
+	names := []string{}
+	for name := range schemaKinds() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// nonEmpty lists the values of set other than the empty string, which stands for the default.
+func nonEmpty(set sets.String) []string {
+	return sets.NewString(set.List()...).Delete("").List()
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schema returns the JSON Schema of values of type t, as read by sigs.k8s.io/yaml.
+func (f fileSchema) schema(t reflect.Type) map[string]interface{} {
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case t.Kind() == reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": f.schema(t.Elem())}
+	case t.Kind() == reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": f.schema(t.Elem())}
+	case t.Kind() == reflect.Ptr:
+		return f.schema(t.Elem())
+	case t.Kind() == reflect.Struct:
+		properties := map[string]interface{}{}
+		required := []string{}
+		for i := 0; i < t.NumField(); i++ {
+			tag := strings.Split(t.Field(i).Tag.Get("json"), ",")
+			if len(tag[0]) == 0 || tag[0] == "-" {
+				continue
+			}
+			property := f.schema(t.Field(i).Type)
+			if description, ok := f.descriptions[tag[0]]; ok {
+				property["description"] = description
+			}
+			if enum, ok := f.enums[tag[0]]; ok {
+				property["enum"] = enum
+			}
+			properties[tag[0]] = property
+			if len(tag) == 1 {
+				required = append(required, tag[0])
+			}
+		}
+		schema := map[string]interface{}{"type": "object", "properties": properties, "additionalProperties": false}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	}
+	return map[string]interface{}{}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestSchema(t *testing.T) {
+	type property struct {
+		Type        string   `json:"type"`
+		Format      string   `json:"format"`
+		Description string   `json:"description"`
+		Enum        []string `json:"enum"`
+		Items       *struct {
+			Type       string              `json:"type"`
+			Properties map[string]property `json:"properties"`
+			Required   []string            `json:"required"`
+		} `json:"items"`
+	}
+	type schema struct {
+		Schema               string              `json:"$schema"`
+		Title                string              `json:"title"`
+		Type                 string              `json:"type"`
+		Properties           map[string]property `json:"properties"`
+		AdditionalProperties bool                `json:"additionalProperties"`
+	}
+	run := func(kind string) schema {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		o := &SchemaOptions{Kind: kind, IOStreams: streams}
+		if err := o.Run(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		result := schema{}
+		if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return result
+	}
+
+	config := run("config")
+	if config.Type != "object" || config.AdditionalProperties || config.Title != "kubecfg settings" {
+		t.Errorf("unexpected schema %+v", config)
+	}
+	if len(config.Properties) != len(settingDefinitions) {
+		t.Errorf("expected a property per setting, got %v", config.Properties)
+	}
+	if color := config.Properties["color"]; color.Type != "string" || !reflect.DeepEqual(color.Enum, []string{"always", "auto", "never"}) {
+		t.Errorf("unexpected color property %+v", color)
+	}
+	if patterns := config.Properties["protectedPatterns"]; patterns.Type != "array" || patterns.Description == "" {
+		t.Errorf("unexpected protectedPatterns property %+v", patterns)
+	}
+	if restore := config.Properties["restoreNamespace"]; restore.Type != "boolean" {
+		t.Errorf("unexpected restoreNamespace property %+v", restore)
+	}
+	if !validColorSettings.Has("") {
+		t.Errorf("expected the color settings to still accept the default")
+	}
+
+	sources := run("sources").Properties["sources"]
+	if sources.Items == nil || !reflect.DeepEqual(sources.Items.Required, []string{"name", "url"}) {
+		t.Fatalf("unexpected sources property %+v", sources)
+	}
+	if lastSync := sources.Items.Properties["lastSync"]; lastSync.Format != "date-time" {
+		t.Errorf("unexpected lastSync property %+v", lastSync)
+	}
+
+	if profiles := run("profiles").Properties["profiles"]; profiles.Type != "object" {
+		t.Errorf("unexpected profiles property %+v", profiles)
+	}
+
+	streams, _, _, _ := genericclioptions.NewTestIOStreams()
+	if err := (&SchemaOptions{Kind: "batch", IOStreams: streams}).Run(); err == nil {
+		t.Errorf("expected an unknown kind to fail")
+	}
+}
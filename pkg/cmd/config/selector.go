@@ -0,0 +1,64 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// contextLabels returns the tags of a context as labels for selectors: a tag of the form key=value
+// is the label key with that value, any other tag a label with an empty value, so that "prod"
+// selects contexts tagged prod and "gcp-project=shop" the ones tagged gcp-project=shop.
+func contextLabels(context *clientcmdapi.Context) (labels.Set, error) {
+	metadata, err := readContextMetadata(context)
+	if err != nil {
+		return nil, err
+	}
+	set := labels.Set{}
+	for _, tag := range metadata.Tags {
+		parts := strings.SplitN(tag, "=", 2)
+		if len(parts) == 2 {
+			set[parts[0]] = parts[1]
+		} else {
+			set[tag] = ""
+		}
+	}
+	return set, nil
+}
+
+// selectContexts returns the sorted names of the contexts whose tags match the label selector.
+func selectContexts(config *clientcmdapi.Config, selector string) ([]string, error) {
+	parsed, err := labels.Parse(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector %q: %v", selector, err)
+	}
+	selected := []string{}
+	for _, name := range sortedContextNames(config.Contexts) {
+		set, err := contextLabels(config.Contexts[name])
+		if err != nil {
+			return nil, fmt.Errorf("context %q: %v", name, err)
+		}
+		if parsed.Matches(set) {
+			selected = append(selected, name)
+		}
+	}
+	return selected, nil
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestSelectContexts(t *testing.T) {
+	config := clientcmdapi.NewConfig()
+	for name, tags := range map[string][]string{
+		"prod-eu":    {"prod", "region=eu"},
+		"prod-us":    {"prod", "region=us"},
+		"staging-eu": {"region=eu"},
+		"untagged":   nil,
+	} {
+		context := &clientcmdapi.Context{}
+		if tags != nil {
+			if err := writeExtension(&context.Extensions, contextMetadataExtension, contextMetadata{Tags: tags}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		config.Contexts[name] = context
+	}
+
+	tests := []struct {
+		selector string
+		expected []string
+	}{
+		{selector: "prod", expected: []string{"prod-eu", "prod-us"}},
+		{selector: "!prod", expected: []string{"staging-eu", "untagged"}},
+		{selector: "region=eu", expected: []string{"prod-eu", "staging-eu"}},
+		{selector: "prod,region!=eu", expected: []string{"prod-us"}},
+		{selector: "region in (eu,us)", expected: []string{"prod-eu", "prod-us", "staging-eu"}},
+		{selector: "", expected: []string{"prod-eu", "prod-us", "staging-eu", "untagged"}},
+	}
+	for _, test := range tests {
+		selected, err := selectContexts(config, test.selector)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.selector, err)
+			continue
+		}
+		if !reflect.DeepEqual(selected, test.expected) {
+			t.Errorf("%q: expected %v, got %v", test.selector, test.expected, selected)
+		}
+	}
+
+	if _, err := selectContexts(config, "region in (eu"); err == nil {
+		t.Errorf("expected an error for an invalid selector")
+	}
+}
@@ -0,0 +1,377 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/printers"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+const (
+	// sessionEnvVar holds the id of the session the shell is in.
+	sessionEnvVar = "KCFG_SESSION"
+	// sessionBaseEnvVar holds the value $KUBECONFIG had before the session started.
+	sessionBaseEnvVar = "KCFG_SESSION_BASE"
+
+	sessionFileSuffix = ".kubeconfig"
+)
+
+var validSessionID = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// SessionOptions holds the command-line options for 'config session' sub commands
+type SessionOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Dir          string
+	ID           string
+	Shell        string
+	Context      string
+	Acknowledge  bool
+	Cooloff      *cooloffPolicy
+	// RunCommand runs the libfido2 tools for the contexts protected with "config protect".
+	RunCommand commandRunner
+
+	Getenv func(string) string
+	// TTY returns the terminal device the command runs on, if it can tell.
+	TTY func() (string, error)
+
+	genericclioptions.IOStreams
+}
+
+var (
+	sessionLong = templates.LongDesc(`
+		Give a terminal, or a tmux pane, a current-context of its own.
+
+		"session start" creates a small kubeconfig in the kubecfg state directory holding only a
+		current-context, and prints the commands putting it in front of $KUBECONFIG. As the first
+		file setting current-context wins, switching contexts in that shell, with "session use" or
+		"kubectl config use-context", only changes the session's file, while clusters, users and
+		contexts keep coming from the usual kubeconfig files. "session end" restores $KUBECONFIG.
+
+		A session is keyed by the tmux pane when run inside tmux, or by the terminal device
+		otherwise, so starting it again in the same pane picks up where it left off. With the
+		integration of "kubectl config shell-init" loaded, "kcfg session start" and "kcfg session
+		end" change the shell directly, and setting KCFG_TMUX_SESSIONS=1 starts a session in
+		every new tmux pane.`)
+
+	sessionExample = templates.Examples(`
+		# Start a session in the current shell
+		eval "$(kubectl config session start)"
+
+		# Switch context in this terminal only
+		kubectl config session use staging
+
+		# Leave the session
+		eval "$(kubectl config session end)"`)
+)
+
+// NewCmdConfigSession returns a Command instance for 'config session' sub command
+func NewCmdConfigSession(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &SessionOptions{
+		ConfigAccess: configAccess,
+		RunCommand:   runCommand,
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "session SUBCOMMAND",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Give a terminal or tmux pane its own current-context"),
+		Long:                  sessionLong,
+		Example:               sessionExample,
+		Run:                   cmdutil.DefaultSubCommandRun(streams.ErrOut),
+	}
+
+	startCmd := &cobra.Command{
+		Use:   "start [--id=ID] [--shell=sh|fish|powershell]",
+		Short: i18n.T("Print the shell commands starting a session"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			cmdutil.CheckErr(o.Complete())
+			cmdutil.CheckErr(o.RunStart())
+		},
+	}
+	startCmd.Flags().StringVar(&o.ID, "id", o.ID, "Identifier of the session. Defaults to one derived from the tmux pane or terminal")
+	startCmd.Flags().StringVar(&o.Shell, "shell", "sh", "Syntax of the printed commands. One of: sh|fish|powershell")
+	cmd.AddCommand(startCmd)
+
+	useCmd := &cobra.Command{
+		Use:   "use CONTEXT [--acknowledge]",
+		Short: i18n.T("Switch the context of the current session"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.Context = args[0]
+			cmdutil.CheckErr(o.Complete())
+			settings, err := loadSettings(settingsFile())
+			cmdutil.CheckErr(err)
+			o.Cooloff, err = newCooloffPolicy(settings)
+			cmdutil.CheckErr(err)
+			cmdutil.CheckErr(o.RunUse())
+		},
+	}
+	useCmd.Flags().BoolVar(&o.Acknowledge, "acknowledge", o.Acknowledge, "Acknowledge switching to a context the cooloff setting applies to")
+	cmd.AddCommand(useCmd)
+
+	endCmd := &cobra.Command{
+		Use:   "end [--shell=sh|fish|powershell]",
+		Short: i18n.T("Print the shell commands ending the current session"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			cmdutil.CheckErr(o.Complete())
+			cmdutil.CheckErr(o.RunEnd())
+		},
+	}
+	endCmd.Flags().StringVar(&o.Shell, "shell", "sh", "Syntax of the printed commands. One of: sh|fish|powershell")
+	cmd.AddCommand(endCmd)
+
+	cmd.AddCommand(noWriteCommand(&cobra.Command{
+		Use:   "list",
+		Short: i18n.T("List sessions and their contexts"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			cmdutil.CheckErr(o.Complete())
+			cmdutil.CheckErr(o.RunList())
+		},
+	}))
+	return cmd
+}
+
+// Complete fills in the session directory and environment access
+func (o *SessionOptions) Complete() error {
+	if len(o.Dir) == 0 {
+		o.Dir = filepath.Join(stateDir(), "sessions")
+	}
+	if o.Getenv == nil {
+		o.Getenv = os.Getenv
+	}
+	if o.TTY == nil {
+		o.TTY = func() (string, error) { return os.Readlink("/proc/self/fd/0") }
+	}
+	return nil
+}
+
+// RunStart creates the session's kubeconfig unless it exists, and prints the commands entering it
+func (o *SessionOptions) RunStart() error {
+	id := o.ID
+	if len(id) == 0 {
+		var err error
+		if id, err = o.defaultID(); err != nil {
+			return err
+		}
+	}
+	if !validSessionID.MatchString(id) {
+		return fmt.Errorf("invalid session id %q", id)
+	}
+	file := o.sessionFile(id)
+
+	// Restarting a session, or starting one from within another, keeps the original $KUBECONFIG.
+	original := o.Getenv(clientcmd.RecommendedConfigPathEnvVar)
+	if len(o.Getenv(sessionEnvVar)) > 0 {
+		original = o.Getenv(sessionBaseEnvVar)
+	}
+	chain := []string{file}
+	for _, filename := range o.ConfigAccess.GetLoadingPrecedence() {
+		if filepath.Dir(filename) != o.Dir {
+			chain = append(chain, filename)
+		}
+	}
+
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		config, err := o.ConfigAccess.GetStartingConfig()
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(o.Dir, 0700); err != nil {
+			return err
+		}
+		session := clientcmdapi.NewConfig()
+		session.CurrentContext = config.CurrentContext
+		if err := clientcmd.WriteToFile(*session, file); err != nil {
+			return err
+		}
+	}
+
+	for _, env := range [][2]string{
+		{sessionEnvVar, id},
+		{sessionBaseEnvVar, original},
+		{clientcmd.RecommendedConfigPathEnvVar, strings.Join(chain, string(filepath.ListSeparator))},
+	} {
+		export, err := shellExport(o.Shell, env[0], env[1])
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(o.Out, export)
+	}
+	return nil
+}
+
+// RunUse switches the context of the current session
+func (o *SessionOptions) RunUse() error {
+	id, err := o.currentID()
+	if err != nil {
+		return err
+	}
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	name, err := resolveContextName(config, o.Context)
+	if err != nil {
+		return err
+	}
+	if _, ok := config.Contexts[name]; !ok {
+		return fmt.Errorf("no context exists with the name: %q", name)
+	}
+	if o.Cooloff != nil {
+		if err := o.Cooloff.check(config, name, o.Acknowledge); err != nil {
+			return err
+		}
+	}
+	if err := requireFIDO2Touch(config, name, o.RunCommand, o.ErrOut); err != nil {
+		return err
+	}
+
+	file := o.sessionFile(id)
+	session, err := clientcmd.LoadFromFile(file)
+	if err != nil {
+		return err
+	}
+	session.CurrentContext = name
+	if err := clientcmd.WriteToFile(*session, file); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "Switched to context %q in session %s.\n", name, id)
+	return nil
+}
+
+// RunEnd removes the session's kubeconfig and prints the commands restoring $KUBECONFIG
+func (o *SessionOptions) RunEnd() error {
+	id, err := o.currentID()
+	if err != nil {
+		return err
+	}
+	commands := []string{}
+	if original := o.Getenv(sessionBaseEnvVar); len(original) > 0 {
+		export, err := shellExport(o.Shell, clientcmd.RecommendedConfigPathEnvVar, original)
+		if err != nil {
+			return err
+		}
+		commands = append(commands, export)
+	} else {
+		unset, err := shellUnset(o.Shell, clientcmd.RecommendedConfigPathEnvVar)
+		if err != nil {
+			return err
+		}
+		commands = append(commands, unset)
+	}
+	for _, name := range []string{sessionEnvVar, sessionBaseEnvVar} {
+		unset, err := shellUnset(o.Shell, name)
+		if err != nil {
+			return err
+		}
+		commands = append(commands, unset)
+	}
+
+	if err := os.Remove(o.sessionFile(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	fmt.Fprint(o.Out, strings.Join(commands, ""))
+	return nil
+}
+
+// RunList prints every session with its context, marking the current one
+func (o *SessionOptions) RunList() error {
+	entries, err := ioutil.ReadDir(o.Dir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	ids := []string{}
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), sessionFileSuffix) {
+			ids = append(ids, strings.TrimSuffix(entry.Name(), sessionFileSuffix))
+		}
+	}
+	sort.Strings(ids)
+
+	w := printers.GetNewTabWriter(o.Out)
+	fmt.Fprintf(w, "CURRENT\tID\tCONTEXT\n")
+	for _, id := range ids {
+		session, err := clientcmd.LoadFromFile(o.sessionFile(id))
+		if err != nil {
+			return err
+		}
+		current := ""
+		if id == o.Getenv(sessionEnvVar) {
+			current = "*"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", current, id, session.CurrentContext)
+	}
+	return w.Flush()
+}
+
+func (o *SessionOptions) sessionFile(id string) string {
+	return filepath.Join(o.Dir, id+sessionFileSuffix)
+}
+
+// currentID returns the id of the session the shell is in.
+func (o *SessionOptions) currentID() (string, error) {
+	id := o.Getenv(sessionEnvVar)
+	if len(id) == 0 || !validSessionID.MatchString(id) {
+		return "", errors.New(`not in a session, start one with: eval "$(kubectl config session start)"`)
+	}
+	return id, nil
+}
+
+// defaultID derives a session id from the tmux pane, or from the terminal device outside tmux.
+func (o *SessionOptions) defaultID() (string, error) {
+	if pane := o.Getenv("TMUX_PANE"); len(pane) > 0 {
+		// $TMUX starts with the server's socket; panes of different servers share numbers.
+		socket := strings.SplitN(o.Getenv("TMUX"), ",", 2)[0]
+		sum := sha256.Sum256([]byte(socket))
+		return fmt.Sprintf("tmux-%s-%s", hex.EncodeToString(sum[:4]), strings.TrimPrefix(pane, "%")), nil
+	}
+	if tty, err := o.TTY(); err == nil && strings.HasPrefix(tty, "/dev/") {
+		return "tty-" + strings.Replace(strings.TrimPrefix(tty, "/dev/"), "/", "-", -1), nil
+	}
+	return "", errors.New("cannot tell which terminal this is, choose a session id with --id")
+}
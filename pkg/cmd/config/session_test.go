@@ -0,0 +1,145 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestSessionLifecycle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "session")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := newRedFederalCowHammerConfig()
+	config.Contexts["shaker-context"] = &clientcmdapi.Context{AuthInfo: "red-user", Cluster: "cow-cluster"}
+	kubeconfig := filepath.Join(dir, "config")
+	if err := clientcmd.WriteToFile(config, kubeconfig); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sessionDir := filepath.Join(dir, "sessions")
+	env := map[string]string{"TMUX": "/tmp/tmux-1000/default,123,0", "TMUX_PANE": "%7"}
+
+	newOptions := func() (*SessionOptions, *bytes.Buffer) {
+		pathOptions := clientcmd.NewDefaultPathOptions()
+		pathOptions.GlobalFile = kubeconfig
+		pathOptions.EnvVar = ""
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		o := &SessionOptions{
+			ConfigAccess: pathOptions,
+			Dir:          sessionDir,
+			Shell:        "sh",
+			Getenv:       func(key string) string { return env[key] },
+			IOStreams:    streams,
+		}
+		if err := o.Complete(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return o, out
+	}
+
+	o, out := newOptions()
+	if err := o.RunStart(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sum := sha256.Sum256([]byte("/tmp/tmux-1000/default"))
+	id := "tmux-" + hex.EncodeToString(sum[:4]) + "-7"
+	file := filepath.Join(sessionDir, id+sessionFileSuffix)
+	expected := "export KCFG_SESSION='" + id + "'\n" +
+		"export KCFG_SESSION_BASE=''\n" +
+		"export KUBECONFIG='" + file + string(filepath.ListSeparator) + kubeconfig + "'\n"
+	if out.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+	session, err := clientcmd.LoadFromFile(file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if session.CurrentContext != "federal-context" || len(session.Contexts) != 0 {
+		t.Errorf("expected the session to only hold the current-context, got %#v", session)
+	}
+
+	env[sessionEnvVar] = id
+	o, out = newOptions()
+	o.Context = "shaker-context"
+	if err := o.RunUse(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if session, err = clientcmd.LoadFromFile(file); err != nil || session.CurrentContext != "shaker-context" {
+		t.Errorf("expected the session to switch to shaker-context, got %v, %v", session, err)
+	}
+	if config, err := clientcmd.LoadFromFile(kubeconfig); err != nil || config.CurrentContext != "federal-context" {
+		t.Errorf("expected the kubeconfig to be left alone, got %v, %v", config, err)
+	}
+	o.Context = "missing-context"
+	if err := o.RunUse(); err == nil {
+		t.Errorf("expected an error for a missing context")
+	}
+
+	o, out = newOptions()
+	if err := o.RunList(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fields := strings.Fields(strings.Split(out.String(), "\n")[1]); len(fields) != 3 || fields[0] != "*" || fields[2] != "shaker-context" {
+		t.Errorf("expected the current session to be listed, got %q", out.String())
+	}
+
+	o, out = newOptions()
+	if err := o.RunEnd(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "unset KUBECONFIG\nunset KCFG_SESSION\nunset KCFG_SESSION_BASE\n"; out.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("expected the session file to be removed, got %v", err)
+	}
+
+	delete(env, sessionEnvVar)
+	o, _ = newOptions()
+	if err := o.RunEnd(); err == nil || !strings.Contains(err.Error(), "not in a session") {
+		t.Errorf("expected an error outside of a session, got %v", err)
+	}
+}
+
+func TestSessionDefaultID(t *testing.T) {
+	o := &SessionOptions{
+		Getenv: func(string) string { return "" },
+		TTY:    func() (string, error) { return "/dev/pts/3", nil },
+	}
+	if id, err := o.defaultID(); err != nil || id != "tty-pts-3" {
+		t.Errorf("expected tty-pts-3, got %q, %v", id, err)
+	}
+	o.TTY = func() (string, error) { return "", errors.New("no terminal") }
+	if _, err := o.defaultID(); err == nil {
+		t.Errorf("expected an error without a terminal")
+	}
+}
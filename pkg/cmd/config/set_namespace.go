@@ -0,0 +1,158 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/printers"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// SetNamespaceOptions holds the command-line options for 'config set-namespace' sub command
+type SetNamespaceOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Namespace    string
+	Contexts     []string
+	Selector     string
+	DryRun       bool
+	// Confirm asks before the contexts the confirm setting covers are changed.
+	Confirm changeConfirmer
+
+	genericclioptions.IOStreams
+}
+
+var (
+	setNamespaceLong = templates.LongDesc(`
+		Set the namespace of many contexts at once.
+
+		The contexts changed are the ones named, or the ones whose tags match --selector. The
+		changes are listed before they are made, and only listed with --dry-run. Contexts already
+		using the namespace are left alone.`)
+
+	setNamespaceExample = templates.Examples(`
+		# Preview using the web namespace in every context tagged dev
+		kubectl config set-namespace web --selector dev --dry-run
+
+		# Use the web namespace in the current-context and staging
+		kubectl config set-namespace web . staging`)
+)
+
+// NewCmdConfigSetNamespace returns a Command instance for 'config set-namespace' sub command
+func NewCmdConfigSetNamespace(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &SetNamespaceOptions{ConfigAccess: configAccess, IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "set-namespace NAMESPACE (CONTEXT_NAME... | --selector=SELECTOR) [--dry-run]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Set the namespace of many contexts at once"),
+		Long:                  setNamespaceLong,
+		Example:               setNamespaceExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.Namespace, o.Contexts = args[0], args[1:]
+			o.Confirm = newChangeConfirmer(cmd, configAccess, streams.In, streams.ErrOut)
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	cmd.Flags().StringVarP(&o.Selector, "selector", "l", o.Selector, "Set the namespace of the contexts whose tags match this selector, such as dev or region=eu")
+	cmd.Flags().BoolVar(&o.DryRun, "dry-run", o.DryRun, "If true, only print the changes that would be made")
+	return cmd
+}
+
+// Validate makes sure there is a namespace and a way to pick the contexts
+func (o *SetNamespaceOptions) Validate() error {
+	if len(o.Namespace) == 0 {
+		return errors.New("you must specify a non-empty namespace")
+	}
+	if len(o.Contexts) > 0 && len(o.Selector) > 0 {
+		return errors.New("context names and --selector cannot be combined")
+	}
+	if len(o.Contexts) == 0 && len(o.Selector) == 0 {
+		return errors.New("context names or --selector are required")
+	}
+	return nil
+}
+
+// Run lists and makes the changes
+func (o *SetNamespaceOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+
+	names := []string{}
+	if len(o.Selector) > 0 {
+		if names, err = selectContexts(config, o.Selector); err != nil {
+			return err
+		}
+	}
+	for _, name := range o.Contexts {
+		name, err := resolveContextName(config, name)
+		if err != nil {
+			return err
+		}
+		if _, ok := config.Contexts[name]; !ok {
+			return fmt.Errorf("no context exists with the name: %q", name)
+		}
+		if !containsString(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	changed := []string{}
+	for _, name := range names {
+		if config.Contexts[name].Namespace != o.Namespace {
+			changed = append(changed, name)
+		}
+	}
+	if len(changed) == 0 {
+		fmt.Fprintf(o.Out, "No context to change, the %d selected already use namespace %q.\n", len(names), o.Namespace)
+		return nil
+	}
+
+	w := printers.GetNewTabWriter(o.Out)
+	fmt.Fprintln(w, "CONTEXT\tNAMESPACE\tNEW NAMESPACE")
+	for _, name := range changed {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", name, valueOrNone(config.Contexts[name].Namespace), o.Namespace)
+		config.Contexts[name].Namespace = o.Namespace
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if o.DryRun {
+		return nil
+	}
+	if err := o.Confirm.check(changed); err != nil {
+		return err
+	}
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "Namespace %q set in %d context(s).\n", o.Namespace, len(changed))
+	return nil
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestSetNamespace(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	config := newRedFederalCowHammerConfig()
+	for name, tag := range map[string]string{"dev-a": "dev", "dev-b": "dev", "dev-c": "dev", "prod": "prod"} {
+		context := &clientcmdapi.Context{AuthInfo: "red-user", Cluster: "cow-cluster"}
+		if err := writeContextMetadata(context, contextMetadata{Tags: []string{tag}}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		config.Contexts[name] = context
+	}
+	config.Contexts["dev-b"].Namespace = "api"
+	config.Contexts["dev-c"].Namespace = "web"
+	if err := clientcmd.WriteToFile(config, fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	preview := `CONTEXT   NAMESPACE   NEW NAMESPACE
+dev-a     <none>      web
+dev-b     api         web
+`
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := &SetNamespaceOptions{ConfigAccess: pathOptions, Namespace: "web", Selector: "dev", DryRun: true, IOStreams: streams}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != preview {
+		t.Errorf("expected\n%s\ngot\n%s", preview, out.String())
+	}
+	if unchanged, err := clientcmd.LoadFromFile(fakeKubeFile.Name()); err != nil || unchanged.Contexts["dev-a"].Namespace != "" {
+		t.Errorf("expected a dry run to change nothing, got %v, %v", unchanged, err)
+	}
+
+	streams, _, out, _ = genericclioptions.NewTestIOStreams()
+	o = &SetNamespaceOptions{ConfigAccess: pathOptions, Namespace: "web", Selector: "dev", IOStreams: streams}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := preview + "Namespace \"web\" set in 2 context(s).\n"; out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+	loaded, err := clientcmd.LoadFromFile(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for name, namespace := range map[string]string{"dev-a": "web", "dev-b": "web", "dev-c": "web", "prod": "", "federal-context": ""} {
+		if loaded.Contexts[name].Namespace != namespace {
+			t.Errorf("expected context %q to use namespace %q, got %q", name, namespace, loaded.Contexts[name].Namespace)
+		}
+	}
+
+	streams, _, out, _ = genericclioptions.NewTestIOStreams()
+	o = &SetNamespaceOptions{ConfigAccess: pathOptions, Namespace: "web", Contexts: []string{"dev-a", "dev-b"}, IOStreams: streams}
+	if err := o.Run(); err != nil || out.String() != "No context to change, the 2 selected already use namespace \"web\".\n" {
+		t.Errorf("unexpected output %q, %v", out.String(), err)
+	}
+
+	for _, invalid := range []*SetNamespaceOptions{{Selector: "dev"}, {Namespace: "web"}, {Namespace: "web", Selector: "dev", Contexts: []string{"prod"}}} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", invalid)
+		}
+	}
+}
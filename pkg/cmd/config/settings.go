@@ -0,0 +1,560 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/printers"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// Settings are the user's defaults for the config subcommands. They are kept in their own file rather
+// than in kubeconfig, which is often generated or shared.
+type Settings struct {
+	// Output is the output format used when a command supporting it is run without --output.
+	Output string `json:"output,omitempty"`
+	// Color is one of auto, always or never, and selects whether the reports of "config doctor" and
+	// "config lint" are colored. auto colors them on a terminal unless $NO_COLOR is set.
+	Color string `json:"color,omitempty"`
+	// AliasTemplate is a Go template naming the shell alias of a context in "config alias export".
+	AliasTemplate string `json:"aliasTemplate,omitempty"`
+	// AuditExec makes "config exec" record the kubectl commands it runs in the audit log.
+	AuditExec bool `json:"auditExec,omitempty"`
+	// AuditWebhook is a URL the records of the audit log are also posted to.
+	AuditWebhook string `json:"auditWebhook,omitempty"`
+	// ExportPresets are the named shapes of kubeconfig "config export --preset" prints. They are
+	// edited in the settings file, not with "config settings set".
+	ExportPresets map[string]exportPreset `json:"exportPresets,omitempty"`
+	// Cooloff is how long switching to a context with one of CooloffTags stays acknowledged, as a
+	// Go duration. Empty disables the cooloff.
+	Cooloff string `json:"cooloff,omitempty"`
+	// CooloffTags are the context tags the cooloff applies to, prod when empty.
+	CooloffTags []string `json:"cooloffTags,omitempty"`
+	// Confirm is one of always, protected or never, and selects which changes ask for confirmation.
+	Confirm string `json:"confirm,omitempty"`
+	// OnConflict is the strategy imports use for entries whose name is taken, see newConflictStrategy.
+	OnConflict string `json:"onConflict,omitempty"`
+	// HealthCacheTTL is how long the result of checking a context is reused by listings showing
+	// health, as a Go duration. Empty means 5m.
+	HealthCacheTTL string `json:"healthCacheTTL,omitempty"`
+	// HealthConcurrency is how many contexts "config health" and listings showing health check at
+	// once. 0 means 10.
+	HealthConcurrency int `json:"healthConcurrency,omitempty"`
+	// KubectxState makes use-context read and write the previous context and namespaces kubectx and
+	// kubens keep, so that both tools can be used together.
+	KubectxState bool `json:"kubectxState,omitempty"`
+	// ManagedKubeconfigs are file patterns of kubeconfig files managed by someone else, which the
+	// config subcommands never write to, writing to the overlay file before them instead.
+	ManagedKubeconfigs []string `json:"managedKubeconfigs,omitempty"`
+	// NamingTemplate is a Go template naming the contexts created by "config import", and the
+	// contexts renamed by "config lint --fix".
+	NamingTemplate string `json:"namingTemplate,omitempty"`
+	// NamingPattern is the regular expression "config lint" checks entry names against.
+	NamingPattern string `json:"namingPattern,omitempty"`
+	// ProtectedPatterns are shell patterns of context names that need confirmation before changing.
+	ProtectedPatterns []string `json:"protectedPatterns,omitempty"`
+	// RestoreNamespace makes use-context return to the namespace last used in the context switched to.
+	RestoreNamespace bool `json:"restoreNamespace,omitempty"`
+	// SafeMode protects prod-like contexts with defaults for the settings left empty, see withSafeMode.
+	SafeMode bool `json:"safeMode,omitempty"`
+	// VerifyOnUse is one of never, warn or abort, and selects what use-context does when the
+	// credentials of the context switched to fail an authenticated request.
+	VerifyOnUse string `json:"verifyOnUse,omitempty"`
+}
+
+// setting describes a single key of the settings file for 'config settings'.
+type setting struct {
+	name        string
+	description string
+	get         func(*Settings) string
+	set         func(*Settings, string) error
+}
+
+var (
+	validColorSettings   = sets.NewString("", "auto", "always", "never")
+	validConfirmSettings = sets.NewString("", confirmAlways, confirmProtected, confirmNever)
+	validVerifySettings  = sets.NewString("", verifyNever, verifyWarn, verifyAbort)
+)
+
+// settingDefinitions lists every key of the settings file, in the order they are listed.
+var settingDefinitions = []setting{
+	{
+		name:        "output",
+		description: "Default output format of commands supporting --output",
+		get:         func(s *Settings) string { return s.Output },
+		set: func(s *Settings, value string) error {
+			s.Output = value
+			return nil
+		},
+	},
+	{
+		name:        "color",
+		description: "Whether the reports of doctor and lint are colored: auto, always or never",
+		get:         func(s *Settings) string { return s.Color },
+		set: func(s *Settings, value string) error {
+			if !validColorSettings.Has(value) {
+				return fmt.Errorf("color must be one of auto, always or never, got %q", value)
+			}
+			s.Color = value
+			return nil
+		},
+	},
+	{
+		name:        "aliasTemplate",
+		description: "Go template naming the shell alias of a context, such as k{{.Context}}",
+		get:         func(s *Settings) string { return s.AliasTemplate },
+		set: func(s *Settings, value string) error {
+			if _, err := template.New("alias").Parse(value); err != nil {
+				return fmt.Errorf("invalid alias template: %v", err)
+			}
+			s.AliasTemplate = value
+			return nil
+		},
+	},
+	{
+		name:        "auditExec",
+		description: "Whether the kubectl commands run by exec, their context and exit code are recorded in the audit log",
+		get:         func(s *Settings) string { return fmt.Sprint(s.AuditExec) },
+		set: func(s *Settings, value string) error {
+			enabled, err := toBool(value)
+			if err != nil {
+				return err
+			}
+			s.AuditExec = enabled
+			return nil
+		},
+	},
+	{
+		name:        "auditWebhook",
+		description: "URL the records of the audit log are also posted to as JSON",
+		get:         func(s *Settings) string { return s.AuditWebhook },
+		set: func(s *Settings, value string) error {
+			if len(value) > 0 {
+				if u, err := url.Parse(value); err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+					return fmt.Errorf("auditWebhook must be an http or https URL, got %q", value)
+				}
+			}
+			s.AuditWebhook = value
+			return nil
+		},
+	},
+	{
+		name:        "cooloff",
+		description: "How long switching to a tagged context stays acknowledged, such as 30m",
+		get:         func(s *Settings) string { return s.Cooloff },
+		set: func(s *Settings, value string) error {
+			if len(value) > 0 {
+				if period, err := time.ParseDuration(value); err != nil || period <= 0 {
+					return fmt.Errorf("cooloff must be a positive duration such as 30m, got %q", value)
+				}
+			}
+			s.Cooloff = value
+			return nil
+		},
+	},
+	{
+		name:        "cooloffTags",
+		description: "Comma separated context tags the cooloff applies to, prod by default",
+		get:         func(s *Settings) string { return strings.Join(s.CooloffTags, ",") },
+		set: func(s *Settings, value string) error {
+			tags := []string{}
+			for _, tag := range strings.Split(value, ",") {
+				if tag = strings.TrimSpace(tag); len(tag) > 0 {
+					tags = append(tags, tag)
+				}
+			}
+			s.CooloffTags = tags
+			return nil
+		},
+	},
+	{
+		name:        "confirm",
+		description: "Which changes ask for confirmation: always, protected or never",
+		get:         func(s *Settings) string { return s.Confirm },
+		set: func(s *Settings, value string) error {
+			if !validConfirmSettings.Has(value) {
+				return fmt.Errorf("confirm must be one of always, protected or never, got %q", value)
+			}
+			s.Confirm = value
+			return nil
+		},
+	},
+	{
+		name:        "healthCacheTTL",
+		description: "How long the health of a context shown by get-contexts -o wide is cached, such as 10m, 5m by default",
+		get:         func(s *Settings) string { return s.HealthCacheTTL },
+		set: func(s *Settings, value string) error {
+			if len(value) > 0 {
+				if ttl, err := time.ParseDuration(value); err != nil || ttl < 0 {
+					return fmt.Errorf("healthCacheTTL must be a duration such as 10m, got %q", value)
+				}
+			}
+			s.HealthCacheTTL = value
+			return nil
+		},
+	},
+	{
+		name:        "healthConcurrency",
+		description: "How many contexts health and get-contexts -o wide check at once, 10 by default",
+		get: func(s *Settings) string {
+			if s.HealthConcurrency == 0 {
+				return ""
+			}
+			return strconv.Itoa(s.HealthConcurrency)
+		},
+		set: func(s *Settings, value string) error {
+			if len(value) == 0 {
+				s.HealthConcurrency = 0
+				return nil
+			}
+			concurrency, err := strconv.Atoi(value)
+			if err != nil || concurrency <= 0 {
+				return fmt.Errorf("healthConcurrency must be a positive number, got %q", value)
+			}
+			s.HealthConcurrency = concurrency
+			return nil
+		},
+	},
+	{
+		name:        "kubectxState",
+		description: "Whether use-context reads and writes the previous context and namespaces of kubectx and kubens",
+		get:         func(s *Settings) string { return fmt.Sprint(s.KubectxState) },
+		set: func(s *Settings, value string) error {
+			enabled, err := toBool(value)
+			if err != nil {
+				return err
+			}
+			s.KubectxState = enabled
+			return nil
+		},
+	},
+	{
+		name:        "managedKubeconfigs",
+		description: "Comma separated file patterns of managed kubeconfig files, never written to, see overlay",
+		get:         func(s *Settings) string { return strings.Join(s.ManagedKubeconfigs, ",") },
+		set: func(s *Settings, value string) error {
+			patterns := []string{}
+			for _, pattern := range strings.Split(value, ",") {
+				pattern = strings.TrimSpace(pattern)
+				if len(pattern) == 0 {
+					continue
+				}
+				if _, err := filepath.Match(pattern, ""); err != nil {
+					return fmt.Errorf("invalid pattern %q: %v", pattern, err)
+				}
+				patterns = append(patterns, pattern)
+			}
+			s.ManagedKubeconfigs = patterns
+			return nil
+		},
+	},
+	{
+		name:        "namingPattern",
+		description: "Regular expression the names of contexts, clusters and users must match, checked by lint",
+		get:         func(s *Settings) string { return s.NamingPattern },
+		set: func(s *Settings, value string) error {
+			if _, err := regexp.Compile(value); err != nil {
+				return fmt.Errorf("invalid naming pattern: %v", err)
+			}
+			s.NamingPattern = value
+			return nil
+		},
+	},
+	{
+		name:        "namingTemplate",
+		description: "Go template naming the contexts created by imports",
+		get:         func(s *Settings) string { return s.NamingTemplate },
+		set: func(s *Settings, value string) error {
+			if _, err := template.New("naming").Parse(value); err != nil {
+				return fmt.Errorf("invalid naming template: %v", err)
+			}
+			s.NamingTemplate = value
+			return nil
+		},
+	},
+	{
+		name:        "onConflict",
+		description: "What imports do with entries whose name is taken: skip, overwrite, suffix, source-prefix, hash or prompt",
+		get:         func(s *Settings) string { return s.OnConflict },
+		set: func(s *Settings, value string) error {
+			if len(value) > 0 {
+				if _, err := newConflictStrategy(value, genericclioptions.IOStreams{}); err != nil {
+					return err
+				}
+			}
+			s.OnConflict = value
+			return nil
+		},
+	},
+	{
+		name:        "protectedPatterns",
+		description: "Comma separated shell patterns of context names to protect",
+		get:         func(s *Settings) string { return strings.Join(s.ProtectedPatterns, ",") },
+		set: func(s *Settings, value string) error {
+			patterns := []string{}
+			for _, pattern := range strings.Split(value, ",") {
+				pattern = strings.TrimSpace(pattern)
+				if len(pattern) == 0 {
+					continue
+				}
+				if _, err := path.Match(pattern, ""); err != nil {
+					return fmt.Errorf("invalid pattern %q: %v", pattern, err)
+				}
+				patterns = append(patterns, pattern)
+			}
+			s.ProtectedPatterns = patterns
+			return nil
+		},
+	},
+	{
+		name:        "restoreNamespace",
+		description: "Whether use-context returns to the namespace last used in a context",
+		get:         func(s *Settings) string { return fmt.Sprint(s.RestoreNamespace) },
+		set: func(s *Settings, value string) error {
+			restore, err := toBool(value)
+			if err != nil {
+				return err
+			}
+			s.RestoreNamespace = restore
+			return nil
+		},
+	},
+	{
+		name:        "safeMode",
+		description: "Whether prod-like contexts are protected by defaults for cooloff, confirm, protectedPatterns and verifyOnUse",
+		get:         func(s *Settings) string { return fmt.Sprint(s.SafeMode) },
+		set: func(s *Settings, value string) error {
+			enabled, err := toBool(value)
+			if err != nil {
+				return err
+			}
+			s.SafeMode = enabled
+			return nil
+		},
+	},
+	{
+		name:        "verifyOnUse",
+		description: "What use-context does when the credentials of a context fail: never checks them, warn or abort",
+		get:         func(s *Settings) string { return s.VerifyOnUse },
+		set: func(s *Settings, value string) error {
+			if !validVerifySettings.Has(value) {
+				return fmt.Errorf("verifyOnUse must be one of never, warn or abort, got %q", value)
+			}
+			s.VerifyOnUse = value
+			return nil
+		},
+	},
+}
+
+func lookupSetting(name string) (setting, error) {
+	for _, definition := range settingDefinitions {
+		if definition.name == name {
+			return definition, nil
+		}
+	}
+	names := []string{}
+	for _, definition := range settingDefinitions {
+		names = append(names, definition.name)
+	}
+	return setting{}, fmt.Errorf("unknown setting %q, must be one of: %s", name, strings.Join(names, ", "))
+}
+
+// settingsFile returns the location of the settings file.
+func settingsFile() string {
+	return filepath.Join(configDir(), "config.yaml")
+}
+
+// loadSettings reads the settings file. A missing file yields the defaults.
+func loadSettings(filename string) (*Settings, error) {
+	settings := &Settings{}
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, settings); err != nil {
+		return nil, fmt.Errorf("error loading settings from %s: %v", filename, err)
+	}
+	return settings, nil
+}
+
+func saveSettings(filename string, settings *Settings) error {
+	data, err := yaml.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0600)
+}
+
+// SettingsOptions holds the command-line options for 'config settings' sub commands
+type SettingsOptions struct {
+	Filename string
+	Name     string
+	Value    string
+
+	genericclioptions.IOStreams
+}
+
+var (
+	settingsLong = templates.LongDesc(`
+		Display or change the defaults of the config subcommands.
+
+		Settings are stored in config.yaml below $XDG_CONFIG_HOME/kubecfg, or ~/.config/kubecfg
+		when XDG_CONFIG_HOME is not set. Flags given on the command line take precedence.`)
+
+	settingsExample = templates.Examples(`
+		# List all settings with their current values
+		kubectl config settings list
+
+		# Ask for confirmation before changing production contexts
+		kubectl config settings set protectedPatterns 'prod-*,*-production'
+		kubectl config settings set confirm protected
+
+		# Protect the contexts tagged or named like production with the defaults of safe mode
+		kubectl config settings set safeMode true
+
+		# Show the default output format
+		kubectl config settings get output`)
+)
+
+// NewCmdConfigSettings returns a Command instance for 'config settings' sub command
+func NewCmdConfigSettings(streams genericclioptions.IOStreams) *cobra.Command {
+	o := &SettingsOptions{IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "settings SUBCOMMAND",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Display or change the defaults of the config subcommands"),
+		Long:                  settingsLong,
+		Example:               settingsExample,
+		Run:                   cmdutil.DefaultSubCommandRun(streams.ErrOut),
+	}
+
+	cmd.AddCommand(noWriteCommand(&cobra.Command{
+		Use:   "list",
+		Short: i18n.T("List all settings with their current values"),
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(cmd, args, 0))
+			cmdutil.CheckErr(o.RunList())
+		},
+	}))
+	cmd.AddCommand(noWriteCommand(&cobra.Command{
+		Use:   "get NAME",
+		Short: i18n.T("Display a single setting"),
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(cmd, args, 1))
+			cmdutil.CheckErr(o.RunGet())
+		},
+	}))
+	cmd.AddCommand(&cobra.Command{
+		Use:   "set NAME VALUE",
+		Short: i18n.T("Change a single setting"),
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(cmd, args, 2))
+			cmdutil.CheckErr(o.RunSet())
+		},
+	})
+	return cmd
+}
+
+// Complete checks the number of arguments and locates the settings file
+func (o *SettingsOptions) Complete(cmd *cobra.Command, args []string, expected int) error {
+	if len(args) != expected {
+		return helpErrorf(cmd, "Unexpected args: %v", args)
+	}
+	if expected > 0 {
+		o.Name = args[0]
+	}
+	if expected > 1 {
+		o.Value = args[1]
+	}
+	if len(o.Filename) == 0 {
+		o.Filename = settingsFile()
+	}
+	return nil
+}
+
+// RunList prints every setting with its value
+func (o *SettingsOptions) RunList() error {
+	settings, err := loadSettings(o.Filename)
+	if err != nil {
+		return err
+	}
+	w := printers.GetNewTabWriter(o.Out)
+	fmt.Fprintf(w, "NAME\tVALUE\tDESCRIPTION\n")
+	for _, definition := range settingDefinitions {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", definition.name, definition.get(settings), definition.description)
+	}
+	return w.Flush()
+}
+
+// RunGet prints the value of a single setting
+func (o *SettingsOptions) RunGet() error {
+	definition, err := lookupSetting(o.Name)
+	if err != nil {
+		return err
+	}
+	settings, err := loadSettings(o.Filename)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(o.Out, definition.get(settings))
+	return nil
+}
+
+// RunSet changes a single setting and writes the settings file
+func (o *SettingsOptions) RunSet() error {
+	definition, err := lookupSetting(o.Name)
+	if err != nil {
+		return err
+	}
+	settings, err := loadSettings(o.Filename)
+	if err != nil {
+		return err
+	}
+	if err := definition.set(settings, o.Value); err != nil {
+		return err
+	}
+	if err := saveSettings(o.Filename, settings); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "Setting %q set to %q.\n", o.Name, definition.get(settings))
+	return nil
+}
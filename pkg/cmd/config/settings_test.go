@@ -0,0 +1,129 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestLoadSettings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "settings")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "config.yaml")
+
+	settings, err := loadSettings(filename)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if settings.RestoreNamespace {
+		t.Errorf("expected defaults when the settings file is missing, got %#v", settings)
+	}
+
+	if err := ioutil.WriteFile(filename, []byte("restoreNamespace: true\n"), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if settings, err = loadSettings(filename); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !settings.RestoreNamespace {
+		t.Errorf("expected restoreNamespace to be read, got %#v", settings)
+	}
+}
+
+func TestSettingsCommands(t *testing.T) {
+	dir, err := ioutil.TempDir("", "settings")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "kubecfg", "config.yaml")
+
+	run := func(args ...string) (string, error) {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		o := &SettingsOptions{Filename: filename, IOStreams: streams}
+		if err := o.Complete(nil, args[1:], len(args)-1); err != nil {
+			return "", err
+		}
+		switch args[0] {
+		case "list":
+			err = o.RunList()
+		case "get":
+			err = o.RunGet()
+		case "set":
+			err = o.RunSet()
+		}
+		return out.String(), err
+	}
+
+	if out, err := run("set", "protectedPatterns", "prod-*, *-production"); err != nil || out != `Setting "protectedPatterns" set to "prod-*,*-production".`+"\n" {
+		t.Errorf("unexpected result %q, %v", out, err)
+	}
+	if out, err := run("set", "restoreNamespace", "true"); err != nil {
+		t.Errorf("unexpected result %q, %v", out, err)
+	}
+	if out, err := run("get", "protectedPatterns"); err != nil || out != "prod-*,*-production\n" {
+		t.Errorf("unexpected result %q, %v", out, err)
+	}
+	if out, err := run("list"); err != nil || !strings.Contains(out, "restoreNamespace") || !strings.Contains(out, "true") {
+		t.Errorf("unexpected result %q, %v", out, err)
+	}
+
+	settings, err := loadSettings(filename)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := &Settings{ProtectedPatterns: []string{"prod-*", "*-production"}, RestoreNamespace: true}
+	if !reflect.DeepEqual(settings, expected) {
+		t.Errorf("expected %#v, got %#v", expected, settings)
+	}
+
+	for _, args := range [][]string{
+		{"set", "color", "sometimes"},
+		{"set", "aliasTemplate", "k{{.Context"},
+		{"set", "auditExec", "sometimes"},
+		{"set", "auditWebhook", "audit.example.com/exec"},
+		{"set", "confirm", "maybe"},
+		{"set", "cooloff", "soon"},
+		{"set", "cooloff", "-5m"},
+		{"set", "healthCacheTTL", "later"},
+		{"set", "healthConcurrency", "0"},
+		{"set", "kubectxState", "both"},
+		{"set", "managedKubeconfigs", "/etc/kube/[a-"},
+		{"set", "namingPattern", "^(dev|prod"},
+		{"set", "namingTemplate", "{{.Name"},
+		{"set", "onConflict", "ignore"},
+		{"set", "protectedPatterns", "prod-["},
+		{"set", "restoreNamespace", "perhaps"},
+		{"set", "safeMode", "always"},
+		{"set", "verifyOnUse", "sometimes"},
+		{"get", "no-such-setting"},
+	} {
+		if _, err := run(args...); err == nil {
+			t.Errorf("expected an error for %v", args)
+		}
+	}
+}
@@ -0,0 +1,463 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+const (
+	// sharePrefix starts every bundle printed by "config share".
+	sharePrefix = "kubecfg-share:1:"
+	// shareCodeAlphabet leaves out the letters and digits that are easily mistaken for one another.
+	shareCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	shareCodeLength   = 12
+	// maxShareTTL bounds how long a bundle can be received.
+	maxShareTTL = 24 * time.Hour
+	// qrCapacity is the number of bytes the largest QR code holds with the lowest error correction.
+	qrCapacity = 2953
+
+	qrEncodeTool = "qrencode"
+	qrScanTool   = "zbarcam"
+)
+
+// sharedBundle is what a bundle of "config share" holds once decrypted.
+type sharedBundle struct {
+	Expires time.Time `json:"expires"`
+	// From is the host name of the machine the context was shared from.
+	From       string `json:"from,omitempty"`
+	Kubeconfig []byte `json:"kubeconfig"`
+}
+
+// ShareOptions holds the command-line options for 'config share' sub command
+type ShareOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Context      string
+	QR           bool
+	TTL          time.Duration
+
+	Now func() time.Time
+	// EncodeQR renders a bundle as a QR code with qrencode.
+	EncodeQR func(text string) ([]byte, error)
+
+	genericclioptions.IOStreams
+}
+
+var (
+	shareLong = templates.LongDesc(`
+		Print a context, with its cluster and user, as an encrypted bundle to move it to another
+		machine with "kubectl config receive", without storage both machines share.
+
+		The bundle is encrypted with a one-time code, printed apart from it, which has to be typed
+		on the other machine, and it can only be received until --ttl elapses. Files the entries
+		refer to are embedded as with "kubectl config export".
+
+		With --qr, the bundle is rendered as a QR code in the terminal with qrencode, to be scanned
+		by "kubectl config receive --qr" or any QR code reader.`)
+
+	shareExample = templates.Examples(`
+		# Show the current context as a QR code, for 10 minutes
+		kubectl config share . --qr
+
+		# Print the staging context as a bundle valid for an hour, to paste it elsewhere
+		kubectl config share staging --ttl=1h`)
+)
+
+// NewCmdConfigShare returns a Command instance for 'config share' sub command
+func NewCmdConfigShare(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &ShareOptions{
+		ConfigAccess: configAccess,
+		TTL:          10 * time.Minute,
+		Now:          time.Now,
+		EncodeQR:     encodeQR,
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "share CONTEXT_NAME [--qr] [--ttl=DURATION]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Print a context as an encrypted, short-lived bundle for another machine"),
+		Long:                  shareLong,
+		Example:               shareExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.Context = args[0]
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().BoolVar(&o.QR, "qr", o.QR, "If true, render the bundle as a QR code in the terminal")
+	cmd.Flags().DurationVar(&o.TTL, "ttl", o.TTL, "How long the bundle can be received, at most 24h")
+	return cmd
+}
+
+// Validate makes sure the bundle is short-lived
+func (o *ShareOptions) Validate() error {
+	if o.TTL <= 0 || o.TTL > maxShareTTL {
+		return fmt.Errorf("--ttl must be positive and at most %v", maxShareTTL)
+	}
+	return nil
+}
+
+// Run prints the encrypted bundle of the context, and the code to receive it with
+func (o *ShareOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	exported, err := exportContext(config, o.Context, false)
+	if err != nil {
+		return err
+	}
+	kubeconfig, err := clientcmd.Write(*exported)
+	if err != nil {
+		return err
+	}
+	bundle := &sharedBundle{Expires: o.Now().Add(o.TTL).UTC().Truncate(time.Second), Kubeconfig: kubeconfig}
+	bundle.From, _ = os.Hostname()
+	code, err := newShareCode()
+	if err != nil {
+		return err
+	}
+	text, err := sealSharedBundle(bundle, code)
+	if err != nil {
+		return err
+	}
+
+	if o.QR {
+		if len(text) > qrCapacity {
+			return fmt.Errorf("the bundle takes %d bytes, more than the %d a QR code holds, share it without --qr", len(text), qrCapacity)
+		}
+		qr, err := o.EncodeQR(text)
+		if err != nil {
+			return fmt.Errorf("%s: %v", qrEncodeTool, err)
+		}
+		o.Out.Write(qr)
+		fmt.Fprintf(o.Out, "\nScan it with \"kubectl config receive --qr\".\n")
+	} else {
+		fmt.Fprintf(o.Out, "%s\n\nReceive it with \"kubectl config receive\".\n", text)
+	}
+	fmt.Fprintf(o.Out, "Code: %s\nExpires: %s\n", formatShareCode(code), bundle.Expires.Format(time.RFC3339))
+	return nil
+}
+
+// encodeQR renders text as a QR code for the terminal with qrencode. The bundle is passed on the
+// standard input, where other users cannot read it as they can the arguments of a process.
+func encodeQR(text string) ([]byte, error) {
+	cmd := exec.Command(qrEncodeTool, "--type=UTF8", "--level=L", "--margin=2", "--output=-")
+	cmd.Stdin = strings.NewReader(text)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); len(message) > 0 {
+			return nil, fmt.Errorf("%v: %s", err, message)
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+// newShareCode returns a random code of shareCodeLength characters of shareCodeAlphabet.
+func newShareCode() (string, error) {
+	data := make([]byte, shareCodeLength)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	// The alphabet has 32 characters, which divides 256, so every character is equally likely.
+	for i := range data {
+		data[i] = shareCodeAlphabet[int(data[i])%len(shareCodeAlphabet)]
+	}
+	return string(data), nil
+}
+
+// formatShareCode groups the characters of a code by four, to be read out and typed.
+func formatShareCode(code string) string {
+	groups := []string{}
+	for i := 0; i < len(code); i += 4 {
+		end := i + 4
+		if end > len(code) {
+			end = len(code)
+		}
+		groups = append(groups, code[i:end])
+	}
+	return strings.Join(groups, "-")
+}
+
+// normalizeShareCode undoes formatShareCode, and forgives lower case.
+func normalizeShareCode(code string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+}
+
+// shareKey derives the key of a bundle from its code.
+func shareKey(code string, salt []byte) (*[32]byte, error) {
+	derived, err := scrypt.Key([]byte(code), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	key := &[32]byte{}
+	copy(key[:], derived)
+	return key, nil
+}
+
+// sealSharedBundle compresses and encrypts a bundle with code, and returns it as text.
+func sealSharedBundle(bundle *sharedBundle, code string) (string, error) {
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		return "", err
+	}
+	compressed := &bytes.Buffer{}
+	writer, _ := gzip.NewWriterLevel(compressed, gzip.BestCompression)
+	writer.Write(data)
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	var salt [16]byte
+	var nonce [24]byte
+	if _, err := rand.Read(salt[:]); err != nil {
+		return "", err
+	}
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return "", err
+	}
+	key, err := shareKey(code, salt[:])
+	if err != nil {
+		return "", err
+	}
+	sealed := append(append([]byte{}, salt[:]...), nonce[:]...)
+	sealed = secretbox.Seal(sealed, compressed.Bytes(), &nonce, key)
+	return sharePrefix + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// openSharedBundle decrypts a bundle printed by "config share" with its code.
+func openSharedBundle(text, code string) (*sharedBundle, error) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, sharePrefix) {
+		return nil, errors.New("not a bundle printed by 'kubectl config share'")
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(text, sharePrefix))
+	if err != nil || len(sealed) < 16+24+secretbox.Overhead {
+		return nil, errors.New("the bundle is truncated or damaged")
+	}
+	var nonce [24]byte
+	copy(nonce[:], sealed[16:40])
+	key, err := shareKey(code, sealed[:16])
+	if err != nil {
+		return nil, err
+	}
+	compressed, ok := secretbox.Open(nil, sealed[40:], &nonce, key)
+	if !ok {
+		return nil, errors.New("wrong code, or the bundle is damaged")
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	bundle := &sharedBundle{}
+	if err := json.Unmarshal(data, bundle); err != nil {
+		return nil, fmt.Errorf("invalid bundle: %v", err)
+	}
+	return bundle, nil
+}
+
+// ReceiveOptions holds the command-line options for 'config receive' sub command
+type ReceiveOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Bundle       string
+	QR           bool
+	Code         string
+	OnConflict   string
+	Use          bool
+
+	Now        func() time.Time
+	RunCommand commandRunner
+
+	genericclioptions.IOStreams
+}
+
+var (
+	receiveLong = templates.LongDesc(`
+		Merge a context shared by "kubectl config share" on another machine into kubeconfig.
+
+		The bundle is the argument, or is read from standard input, where it can be pasted. With
+		--qr, it is scanned with the camera by zbarcam instead. The code printed with the bundle is
+		asked for unless it is given with --code. Bundles are refused once expired.
+
+		Entries whose name is taken are handled as by "kubectl config import --on-conflict", and
+		skipped by default.`)
+
+	receiveExample = templates.Examples(`
+		# Scan a QR code shown by 'kubectl config share --qr', and switch to its context
+		kubectl config receive --qr --use
+
+		# Paste a bundle, then its code
+		kubectl config receive`)
+)
+
+// NewCmdConfigReceive returns a Command instance for 'config receive' sub command
+func NewCmdConfigReceive(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &ReceiveOptions{
+		ConfigAccess: configAccess,
+		Now:          time.Now,
+		RunCommand:   runCommand,
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "receive [BUNDLE | --qr] [--code=CODE] [--on-conflict=STRATEGY] [--use]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Merge a context shared by 'config share' into kubeconfig"),
+		Long:                  receiveLong,
+		Example:               receiveExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) > 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			if len(args) == 1 {
+				o.Bundle = args[0]
+			}
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().BoolVar(&o.QR, "qr", o.QR, "If true, scan the bundle from a QR code with the camera")
+	cmd.Flags().StringVar(&o.Code, "code", o.Code, "Code printed with the bundle, asked for by default")
+	cmd.Flags().StringVar(&o.OnConflict, "on-conflict", o.OnConflict, "What to do with entries whose name is taken: skip, overwrite, suffix or hash")
+	cmd.Flags().BoolVar(&o.Use, "use", o.Use, "If true, switch to the received context")
+	return cmd
+}
+
+// Validate checks where the bundle comes from
+func (o *ReceiveOptions) Validate() error {
+	if o.QR && len(o.Bundle) > 0 {
+		return errors.New("a bundle cannot be given with --qr")
+	}
+	if o.OnConflict == conflictPrompt {
+		return errors.New("--on-conflict=prompt cannot be used, standard input is read for the bundle")
+	}
+	return nil
+}
+
+// Run decrypts the bundle and merges its context into kubeconfig
+func (o *ReceiveOptions) Run() error {
+	resolve, err := newConflictStrategy(o.OnConflict, o.IOStreams)
+	if err != nil {
+		return err
+	}
+	in := bufio.NewReader(o.In)
+	text := o.Bundle
+	if o.QR {
+		fmt.Fprintf(o.ErrOut, "Show the QR code to the camera...\n")
+		scanned, err := o.RunCommand(nil, qrScanTool, "--raw", "--oneshot")
+		if err != nil {
+			return fmt.Errorf("%s: %v", qrScanTool, err)
+		}
+		text = string(scanned)
+	} else if len(text) == 0 {
+		fmt.Fprintf(o.ErrOut, "Bundle: ")
+		if text, err = in.ReadString('\n'); err != nil && len(text) == 0 {
+			return errors.New("no bundle was given")
+		}
+	}
+	code := o.Code
+	if len(code) == 0 {
+		fmt.Fprintf(o.ErrOut, "Code: ")
+		if code, err = in.ReadString('\n'); err != nil && len(code) == 0 {
+			return errors.New("no code was given")
+		}
+	}
+
+	bundle, err := openSharedBundle(text, normalizeShareCode(code))
+	if err != nil {
+		return err
+	}
+	if o.Now().After(bundle.Expires) {
+		return fmt.Errorf("the bundle expired at %s, share it again", bundle.Expires.Format(time.RFC3339))
+	}
+	from, err := clientcmd.Load(bundle.Kubeconfig)
+	if err != nil {
+		return fmt.Errorf("invalid bundle: %v", err)
+	}
+	if err := stampProvenance(from, "share", bundle.From+"/"+from.CurrentContext, o.Now()); err != nil {
+		return err
+	}
+
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	existing := config.Contexts[from.CurrentContext]
+	merged, err := mergeConfigWithStrategy(config, from, bundle.From, resolve)
+	if err != nil {
+		return err
+	}
+	for _, reason := range merged.Skipped {
+		fmt.Fprintf(o.ErrOut, "skipped %s\n", reason)
+	}
+	for _, renamed := range merged.Renamed {
+		fmt.Fprintf(o.ErrOut, "received %s\n", renamed)
+	}
+	// The context was merged under its own name when it replaced or joined the existing ones.
+	received := ""
+	if context := config.Contexts[from.CurrentContext]; context != nil && context != existing {
+		received = from.CurrentContext
+	}
+	if o.Use && len(received) > 0 {
+		config.CurrentContext = received
+	}
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "Received %d entries from %s.\n", merged.Added, valueOrNone(bundle.From))
+	if o.Use && len(received) > 0 {
+		fmt.Fprintf(o.Out, "Switched to context %q.\n", received)
+	}
+	return nil
+}
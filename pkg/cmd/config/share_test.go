@@ -0,0 +1,165 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestSharedBundle(t *testing.T) {
+	code, err := newShareCode()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(code) != shareCodeLength || strings.Trim(code, shareCodeAlphabet) != "" {
+		t.Fatalf("unexpected code %q", code)
+	}
+	formatted := formatShareCode(code)
+	if len(formatted) != 14 || normalizeShareCode(strings.ToLower(formatted)) != code {
+		t.Errorf("expected %q to normalize to %q", formatted, code)
+	}
+
+	bundle := &sharedBundle{Expires: time.Date(2019, 8, 1, 12, 0, 0, 0, time.UTC), From: "laptop", Kubeconfig: []byte("apiVersion: v1\nkind: Config\n")}
+	text, err := sealSharedBundle(bundle, code)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	opened, err := openSharedBundle(" "+text+"\n", code)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !opened.Expires.Equal(bundle.Expires) || opened.From != "laptop" || !bytes.Equal(opened.Kubeconfig, bundle.Kubeconfig) {
+		t.Errorf("expected %+v, got %+v", bundle, opened)
+	}
+	if _, err := openSharedBundle(text, "AAAABBBBCCCC"); err == nil || !strings.Contains(err.Error(), "wrong code") {
+		t.Errorf("expected a wrong code to be refused, got %v", err)
+	}
+	if _, err := openSharedBundle(text[:len(text)-10], code); err == nil {
+		t.Errorf("expected a truncated bundle to be refused")
+	}
+	if _, err := openSharedBundle("apiVersion: v1", code); err == nil {
+		t.Errorf("expected a kubeconfig to be refused")
+	}
+}
+
+func TestShareReceive(t *testing.T) {
+	senderFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(senderFile.Name())
+	if err := clientcmd.WriteToFile(newRedFederalCowHammerConfig(), senderFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	senderOptions := clientcmd.NewDefaultPathOptions()
+	senderOptions.GlobalFile = senderFile.Name()
+	senderOptions.EnvVar = ""
+
+	receiverFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(receiverFile.Name())
+	if err := clientcmd.WriteToFile(*clientcmdapi.NewConfig(), receiverFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	receiverOptions := clientcmd.NewDefaultPathOptions()
+	receiverOptions.GlobalFile = receiverFile.Name()
+	receiverOptions.EnvVar = ""
+
+	now := time.Date(2019, 8, 1, 12, 0, 0, 0, time.UTC)
+	qrInput := ""
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	share := &ShareOptions{
+		ConfigAccess: senderOptions,
+		Context:      "federal-context",
+		QR:           true,
+		TTL:          10 * time.Minute,
+		Now:          func() time.Time { return now },
+		EncodeQR: func(text string) ([]byte, error) {
+			qrInput = text
+			return []byte("█▀▀▀▀▀█\n"), nil
+		},
+		IOStreams: streams,
+	}
+	if err := share.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := share.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(qrInput, sharePrefix) || !strings.HasPrefix(out.String(), "█▀▀▀▀▀█\n") {
+		t.Fatalf("expected the bundle to be rendered as a QR code, got %q", out.String())
+	}
+	code := ""
+	for _, line := range strings.Split(out.String(), "\n") {
+		if strings.HasPrefix(line, "Code: ") {
+			code = strings.TrimPrefix(line, "Code: ")
+		}
+	}
+	if !strings.Contains(out.String(), "Expires: 2019-08-01T12:10:00Z") || len(code) == 0 {
+		t.Fatalf("expected the code and expiry, got %q", out.String())
+	}
+
+	receive := func(at time.Time, input string) (string, error) {
+		streams, in, out, _ := genericclioptions.NewTestIOStreams()
+		in.WriteString(input)
+		o := &ReceiveOptions{ConfigAccess: receiverOptions, Use: true, Now: func() time.Time { return at }, IOStreams: streams}
+		if err := o.Validate(); err != nil {
+			return "", err
+		}
+		err := o.Run()
+		return out.String(), err
+	}
+	if _, err := receive(now.Add(time.Hour), qrInput+"\n"+code+"\n"); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("expected an expired bundle to be refused, got %v", err)
+	}
+	if _, err := receive(now, qrInput+"\nAAAA-BBBB-CCCC\n"); err == nil || !strings.Contains(err.Error(), "wrong code") {
+		t.Errorf("expected a wrong code to be refused, got %v", err)
+	}
+	output, err := receive(now.Add(time.Minute), qrInput+"\n"+strings.ToLower(code)+"\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(output, "Received 3 entries from ") || !strings.Contains(output, `Switched to context "federal-context".`) {
+		t.Errorf("unexpected output %q", output)
+	}
+	received, err := clientcmd.LoadFromFile(receiverFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if received.CurrentContext != "federal-context" || received.AuthInfos["red-user"] == nil || received.AuthInfos["red-user"].Token != "red-token" {
+		t.Errorf("expected the context and its credentials to be received, got %+v", received)
+	}
+	if len(received.Contexts) != 1 {
+		t.Errorf("expected only the shared context, got %v", sortedContextNames(received.Contexts))
+	}
+
+	share.TTL = 48 * time.Hour
+	if err := share.Validate(); err == nil {
+		t.Errorf("expected a TTL over a day to be refused")
+	}
+}
@@ -0,0 +1,647 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/util/homedir"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// ShellInitOptions holds the command-line options for 'config shell-init' sub command
+type ShellInitOptions struct {
+	Shell   string
+	Install bool
+	RCFile  string
+
+	genericclioptions.IOStreams
+}
+
+// shellIntegration is the script printed for a shell, and how it is loaded from the shell's startup
+// file. completion loads the completion of kubectl, which "config init" offers to install.
+type shellIntegration struct {
+	script     string
+	loader     string
+	completion string
+	rcFile     func() string
+}
+
+const shPrompt = `
+_kcfg_refresh_prompt() {
+  KCFG_PROMPT="$(command kubectl config view --minify -o 'jsonpath={.current-context}:{..namespace}' 2>/dev/null)"
+}
+
+_kcfg_refresh_aliases() {
+  if [ "${KCFG_ALIASES:-}" = "1" ]; then
+    eval "$(command kubectl config alias export 2>/dev/null)"
+  fi
+}
+
+kcfg() {
+  if { [ "$1" = "profile" ] && [ "$2" = "use" ]; } ||
+    { [ "$1" = "session" ] && { [ "$2" = "start" ] || [ "$2" = "end" ]; }; }; then
+    local script
+    script="$(command kubectl config "$@")" || return
+    eval "$script"
+  elif [ "$1" = "use" ] || [ "$1" = "use-context" ]; then
+    local previous target="$2"
+    previous="$(command kubectl config current-context 2>/dev/null)"
+    if [ "$target" = "-" ]; then
+      if [ -z "${KCFG_PREVIOUS_CONTEXT:-}" ]; then
+        echo "kcfg: no previous context" >&2
+        return 1
+      fi
+      target="$KCFG_PREVIOUS_CONTEXT"
+    fi
+    if [ $# -ge 2 ]; then shift 2; else shift $#; fi
+    command kubectl config use-context "$target" "$@" || return
+    KCFG_PREVIOUS_CONTEXT="$previous"
+  else
+    command kubectl config "$@" || return
+    if [ "$1" = "shell-init" ] && [ "$2" = "allow" ]; then
+      unset KCFG_DENIED_DIR
+      _kcfg_chpwd
+    fi
+  fi
+  _kcfg_refresh_prompt
+  _kcfg_refresh_aliases
+}
+
+_kcfg_autoswitch() {
+  if [ "${KCFG_AUTOSWITCH:-}" = "1" ]; then
+    local switched
+    switched="$(command kubectl config autoswitch run 2>/dev/null)"
+    if [ -n "$switched" ]; then
+      echo "$switched"
+      _kcfg_refresh_prompt
+    fi
+  fi
+}
+
+_kcfg_auto_session() {
+  if [ -n "${TMUX_PANE:-}" ] && [ "${KCFG_TMUX_SESSIONS:-}" = "1" ]; then
+    kcfg session start
+  fi
+}
+
+_kcfg_chpwd() {
+  if [ -f "$PWD/.kubeconfig" ]; then
+    if [ "${KCFG_DIR:-}" != "$PWD" ]; then
+      [ "${KCFG_DENIED_DIR:-}" = "$PWD" ] && return
+      if ! command kubectl config shell-init allowed "$PWD" >/dev/null 2>&1; then
+        KCFG_DENIED_DIR="$PWD"
+        echo "kcfg: $PWD/.kubeconfig is not allowed, run \"kcfg shell-init allow\" to use it" >&2
+        return
+      fi
+      [ -z "${KCFG_DIR:-}" ] && KCFG_SAVED_KUBECONFIG="${KUBECONFIG:-}"
+      KCFG_DIR="$PWD"
+      export KUBECONFIG="$PWD/.kubeconfig"
+      _kcfg_refresh_prompt
+    fi
+  elif [ -n "${KCFG_DIR:-}" ]; then
+    case "$PWD/" in
+      "$KCFG_DIR"/*) return ;;
+    esac
+    if [ -n "${KCFG_SAVED_KUBECONFIG:-}" ]; then
+      export KUBECONFIG="$KCFG_SAVED_KUBECONFIG"
+    else
+      unset KUBECONFIG
+    fi
+    unset KCFG_DIR KCFG_SAVED_KUBECONFIG
+    _kcfg_refresh_prompt
+  fi
+}
+`
+
+const bashScript = `# kubectl config shell integration for bash
+` + shPrompt + `
+case ";${PROMPT_COMMAND:-};" in
+  *";_kcfg_chpwd;"*) ;;
+  *) PROMPT_COMMAND="_kcfg_chpwd${PROMPT_COMMAND:+;$PROMPT_COMMAND}" ;;
+esac
+case ";${PROMPT_COMMAND:-};" in
+  *";_kcfg_autoswitch;"*) ;;
+  *) PROMPT_COMMAND="_kcfg_autoswitch;$PROMPT_COMMAND" ;;
+esac
+_kcfg_auto_session
+_kcfg_chpwd
+_kcfg_refresh_prompt
+_kcfg_refresh_aliases
+`
+
+const zshScript = `# kubectl config shell integration for zsh
+` + shPrompt + `
+autoload -Uz add-zsh-hook
+add-zsh-hook chpwd _kcfg_chpwd
+add-zsh-hook precmd _kcfg_autoswitch
+_kcfg_auto_session
+_kcfg_chpwd
+_kcfg_refresh_prompt
+_kcfg_refresh_aliases
+`
+
+const fishScript = `# kubectl config shell integration for fish
+
+function _kcfg_refresh_prompt
+    set -g KCFG_PROMPT (command kubectl config view --minify -o 'jsonpath={.current-context}:{..namespace}' 2>/dev/null)
+end
+
+function _kcfg_refresh_aliases
+    if test "$KCFG_ALIASES" = 1
+        command kubectl config alias export --shell=fish 2>/dev/null | source
+    end
+end
+
+function kcfg
+    if test "$argv[1]" = profile -a "$argv[2]" = use; or test "$argv[1]" = session -a \( "$argv[2]" = start -o "$argv[2]" = end \)
+        command kubectl config $argv --shell=fish | source
+    else if contains -- "$argv[1]" use use-context
+        set -l previous (command kubectl config current-context 2>/dev/null)
+        set -l target $argv[2]
+        set -l rest
+        if test (count $argv) -gt 2
+            set rest $argv[3..-1]
+        end
+        if test "$target" = -
+            if not set -q KCFG_PREVIOUS_CONTEXT
+                echo "kcfg: no previous context" >&2
+                return 1
+            end
+            set target $KCFG_PREVIOUS_CONTEXT
+        end
+        command kubectl config use-context $target $rest; or return
+        set -g KCFG_PREVIOUS_CONTEXT $previous
+    else
+        command kubectl config $argv; or return
+        if test "$argv[1]" = shell-init -a "$argv[2]" = allow
+            _kcfg_chpwd
+        end
+    end
+    _kcfg_refresh_prompt
+    _kcfg_refresh_aliases
+end
+
+function _kcfg_chpwd --on-variable PWD
+    if test -f "$PWD/.kubeconfig"
+        if test "$KCFG_DIR" != "$PWD"
+            if not command kubectl config shell-init allowed "$PWD" >/dev/null 2>&1
+                echo "kcfg: $PWD/.kubeconfig is not allowed, run \"kcfg shell-init allow\" to use it" >&2
+                return
+            end
+            if not set -q KCFG_DIR
+                set -g KCFG_SAVED_KUBECONFIG "$KUBECONFIG"
+            end
+            set -g KCFG_DIR $PWD
+            set -gx KUBECONFIG "$PWD/.kubeconfig"
+            _kcfg_refresh_prompt
+        end
+    else if set -q KCFG_DIR
+        if string match -q -- "$KCFG_DIR/*" "$PWD/"
+            return
+        end
+        if test -n "$KCFG_SAVED_KUBECONFIG"
+            set -gx KUBECONFIG $KCFG_SAVED_KUBECONFIG
+        else
+            set -e KUBECONFIG
+        end
+        set -e KCFG_DIR
+        set -e KCFG_SAVED_KUBECONFIG
+        _kcfg_refresh_prompt
+    end
+end
+
+function _kcfg_autoswitch --on-event fish_prompt
+    if test "$KCFG_AUTOSWITCH" = 1
+        set -l switched (command kubectl config autoswitch run 2>/dev/null)
+        if test -n "$switched"
+            printf '%s\n' $switched
+            _kcfg_refresh_prompt
+        end
+    end
+end
+
+if set -q TMUX_PANE; and test "$KCFG_TMUX_SESSIONS" = 1
+    kcfg session start
+end
+_kcfg_chpwd
+_kcfg_refresh_prompt
+_kcfg_refresh_aliases
+`
+
+const pwshScript = `# kubectl config shell integration for PowerShell
+
+function global:_kcfg_refresh_prompt {
+    $global:KCFG_PROMPT = & kubectl config view --minify -o 'jsonpath={.current-context}:{..namespace}' 2>$null
+}
+
+function global:_kcfg_refresh_aliases {
+    if ($env:KCFG_ALIASES -eq '1') {
+        & kubectl config alias export --shell=powershell 2>$null | Out-String | Invoke-Expression
+    }
+}
+
+function global:kcfg {
+    if ($args.Count -ge 2 -and (($args[0] -eq 'profile' -and $args[1] -eq 'use') -or ($args[0] -eq 'session' -and ($args[1] -eq 'start' -or $args[1] -eq 'end')))) {
+        $script = & kubectl config @args --shell=powershell
+        if ($LASTEXITCODE -ne 0) { return }
+        Invoke-Expression ($script -join "` + "`" + `n")
+    } elseif ($args.Count -ge 1 -and ($args[0] -eq 'use' -or $args[0] -eq 'use-context')) {
+        $previous = & kubectl config current-context 2>$null
+        $target = $args[1]
+        if ($target -eq '-') {
+            if (-not $global:KCFG_PREVIOUS_CONTEXT) { Write-Error 'kcfg: no previous context'; return }
+            $target = $global:KCFG_PREVIOUS_CONTEXT
+        }
+        $rest = @($args | Select-Object -Skip 2)
+        & kubectl config use-context $target @rest
+        if ($LASTEXITCODE -ne 0) { return }
+        $global:KCFG_PREVIOUS_CONTEXT = $previous
+    } else {
+        & kubectl config @args
+        if ($LASTEXITCODE -ne 0) { return }
+        if ($args.Count -ge 2 -and $args[0] -eq 'shell-init' -and $args[1] -eq 'allow') {
+            $global:KCFG_DENIED_DIR = $null
+            _kcfg_chpwd
+        }
+    }
+    _kcfg_refresh_prompt
+    _kcfg_refresh_aliases
+}
+
+function global:_kcfg_chpwd {
+    $dir = $PWD.ProviderPath
+    $file = Join-Path $dir '.kubeconfig'
+    if (Test-Path -PathType Leaf $file) {
+        if ($global:KCFG_DIR -ne $dir) {
+            if ($global:KCFG_DENIED_DIR -eq $dir) { return }
+            & kubectl config shell-init allowed $dir *> $null
+            if ($LASTEXITCODE -ne 0) {
+                $global:KCFG_DENIED_DIR = $dir
+                Write-Warning "kcfg: $file is not allowed, run ""kcfg shell-init allow"" to use it"
+                return
+            }
+            if (-not $global:KCFG_DIR) { $global:KCFG_SAVED_KUBECONFIG = $env:KUBECONFIG }
+            $global:KCFG_DIR = $dir
+            $env:KUBECONFIG = $file
+            _kcfg_refresh_prompt
+        }
+    } elseif ($global:KCFG_DIR) {
+        $separator = [IO.Path]::DirectorySeparatorChar
+        if (($dir + $separator).StartsWith($global:KCFG_DIR + $separator)) { return }
+        $env:KUBECONFIG = $global:KCFG_SAVED_KUBECONFIG
+        $global:KCFG_DIR = $null
+        $global:KCFG_SAVED_KUBECONFIG = $null
+        _kcfg_refresh_prompt
+    }
+}
+
+function global:_kcfg_autoswitch {
+    if ($env:KCFG_AUTOSWITCH -eq '1') {
+        $switched = & kubectl config autoswitch run 2>$null
+        if ($switched) {
+            Write-Host ($switched -join "` + "`" + `n")
+            _kcfg_refresh_prompt
+        }
+    }
+}
+
+if (-not $global:_kcfgPrompt) {
+    $global:_kcfgPrompt = $function:prompt
+    function global:prompt { _kcfg_chpwd; _kcfg_autoswitch; & $global:_kcfgPrompt }
+}
+if ($env:TMUX_PANE -and $env:KCFG_TMUX_SESSIONS -eq '1') { kcfg session start }
+_kcfg_chpwd
+_kcfg_refresh_prompt
+_kcfg_refresh_aliases
+`
+
+var shellIntegrations = map[string]shellIntegration{
+	"bash": {
+		script:     bashScript,
+		completion: `source <(kubectl completion bash)`,
+		loader:     `eval "$(kubectl config shell-init bash)"`,
+		rcFile:     func() string { return filepath.Join(homedir.HomeDir(), ".bashrc") },
+	},
+	"zsh": {
+		script:     zshScript,
+		completion: `source <(kubectl completion zsh)`,
+		loader:     `eval "$(kubectl config shell-init zsh)"`,
+		rcFile: func() string {
+			if dir := os.Getenv("ZDOTDIR"); len(dir) > 0 {
+				return filepath.Join(dir, ".zshrc")
+			}
+			return filepath.Join(homedir.HomeDir(), ".zshrc")
+		},
+	},
+	"fish": {
+		script:     fishScript,
+		completion: `kubectl completion fish | source`,
+		loader:     `kubectl config shell-init fish | source`,
+		rcFile: func() string {
+			if dir := os.Getenv("XDG_CONFIG_HOME"); len(dir) > 0 {
+				return filepath.Join(dir, "fish", "config.fish")
+			}
+			return filepath.Join(homedir.HomeDir(), ".config", "fish", "config.fish")
+		},
+	},
+	"pwsh": {
+		script:     pwshScript,
+		completion: `kubectl completion powershell | Out-String | Invoke-Expression`,
+		loader:     `kubectl config shell-init pwsh | Out-String | Invoke-Expression`,
+		rcFile: func() string {
+			if runtime.GOOS == "windows" {
+				return filepath.Join(homedir.HomeDir(), "Documents", "PowerShell", "Microsoft.PowerShell_profile.ps1")
+			}
+			return filepath.Join(homedir.HomeDir(), ".config", "powershell", "Microsoft.PowerShell_profile.ps1")
+		},
+	},
+}
+
+var (
+	shellInitLong = templates.LongDesc(`
+		Prints the shell functions needed by features that change the state of the calling shell,
+		which kubectl itself cannot do:
+
+		    * kcfg, a wrapper of "kubectl config" that applies "kcfg profile use NAME", "kcfg session
+		      start" and "kcfg session end" to the shell
+		    * "kcfg use -", switching back to the context used before the last "kcfg use"
+		    * per-directory kubeconfig: entering a directory containing a .kubeconfig file allowed
+		      with "kubectl config shell-init allow" sets $KUBECONFIG to it, leaving it restores the
+		      previous value
+		    * $KCFG_PROMPT, holding "context:namespace" for use in the prompt, refreshed after every
+		      change made through kcfg
+		    * with KCFG_TMUX_SESSIONS=1, a session with its own current-context in every tmux pane
+		    * with KCFG_AUTOSWITCH=1, "kubectl config autoswitch run" before every prompt, switching
+		      contexts when the network changes
+		    * with KCFG_ALIASES=1, the aliases printed by "kubectl config alias export", defined again
+		      after every change made through kcfg
+
+		With --install, a line loading the integration is added to the startup file of the shell.
+
+		A .kubeconfig file can run any command through the exec credential plugin of its users, so
+		the one of a directory is only used once allowed with "shell-init allow", like direnv does
+		with .envrc files. The allowance is kept below $XDG_STATE_HOME/kubecfg, or
+		~/.local/state/kubecfg, with the checksum of the file: a file changed since is not used
+		until allowed again. "shell-init deny" withdraws it.`)
+
+	shellInitExample = templates.Examples(`
+		# Load the integration into the current bash session
+		eval "$(kubectl config shell-init bash)"
+
+		# Load the integration in every new zsh session
+		kubectl config shell-init zsh --install
+
+		# Use the .kubeconfig file of the current directory, after reviewing it
+		kubectl config shell-init allow`)
+)
+
+// NewCmdConfigShellInit returns a Command instance for 'config shell-init' sub command
+func NewCmdConfigShellInit(streams genericclioptions.IOStreams) *cobra.Command {
+	o := &ShellInitOptions{IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "shell-init bash|zsh|fish|pwsh [--install]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Print or install the shell integration of the config subcommands"),
+		Long:                  shellInitLong,
+		Example:               shellInitExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(cmd, args))
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().BoolVar(&o.Install, "install", o.Install, "If true, load the integration from the startup file of the shell instead of printing it")
+	cmd.Flags().StringVar(&o.RCFile, "rc-file", o.RCFile, "Startup file written by --install. Defaults to the usual file of the shell")
+	cmd.AddCommand(newCmdShellInitAllow(streams, "allow", i18n.T("Allow the shell integration to use the .kubeconfig file of a directory")))
+	cmd.AddCommand(newCmdShellInitAllow(streams, "deny", i18n.T("Withdraw the allowance of the .kubeconfig file of a directory")))
+	allowedCmd := newCmdShellInitAllow(streams, "allowed", i18n.T("Fail unless the .kubeconfig file of a directory is allowed"))
+	allowedCmd.Hidden = true
+	cmd.AddCommand(noWriteCommand(allowedCmd))
+	return cmd
+}
+
+// allowedDirs are the directories whose .kubeconfig file the shell integration uses, with the
+// checksum of the file when it was allowed.
+type allowedDirs struct {
+	Dirs map[string]string `json:"dirs,omitempty"`
+}
+
+// allowedDirsFile returns where the directories allowed with "shell-init allow" are recorded.
+func allowedDirsFile() string {
+	return filepath.Join(stateDir(), "allowed-dirs.yaml")
+}
+
+// dirKubeconfigChecksum returns the checksum of the .kubeconfig file of dir.
+func dirKubeconfigChecksum(dir string) (string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, ".kubeconfig"))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
+
+func loadAllowedDirs(filename string) (*allowedDirs, error) {
+	allowed := &allowedDirs{}
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return allowed, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, allowed); err != nil {
+		return nil, fmt.Errorf("error loading %s: %v", filename, err)
+	}
+	return allowed, nil
+}
+
+func saveAllowedDirs(filename string, allowed *allowedDirs) error {
+	data, err := yaml.Marshal(allowed)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0600)
+}
+
+// ShellInitAllowOptions holds the command-line options for 'config shell-init allow', deny and
+// allowed sub commands
+type ShellInitAllowOptions struct {
+	Action   string
+	Dir      string
+	Filename string
+
+	genericclioptions.IOStreams
+}
+
+func newCmdShellInitAllow(streams genericclioptions.IOStreams, action, short string) *cobra.Command {
+	o := &ShellInitAllowOptions{Action: action, Filename: allowedDirsFile(), IOStreams: streams}
+	return &cobra.Command{
+		Use:                   action + " [DIR]",
+		DisableFlagsInUseLine: true,
+		Short:                 short,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) > 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.Dir = "."
+			if len(args) == 1 {
+				o.Dir = args[0]
+			}
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+}
+
+// Run allows, denies or checks the .kubeconfig file of the directory
+func (o *ShellInitAllowOptions) Run() error {
+	dir, err := filepath.Abs(o.Dir)
+	if err != nil {
+		return err
+	}
+	allowed, err := loadAllowedDirs(o.Filename)
+	if err != nil {
+		return err
+	}
+	switch o.Action {
+	case "allowed":
+		checksum, err := dirKubeconfigChecksum(dir)
+		if err != nil {
+			return err
+		}
+		if allowed.Dirs[dir] != checksum {
+			return fmt.Errorf("%s is not allowed, run \"kubectl config shell-init allow %s\" after reviewing it", filepath.Join(dir, ".kubeconfig"), dir)
+		}
+		return nil
+	case "deny":
+		if _, ok := allowed.Dirs[dir]; !ok {
+			fmt.Fprintf(o.Out, "%s was not allowed.\n", filepath.Join(dir, ".kubeconfig"))
+			return nil
+		}
+		delete(allowed.Dirs, dir)
+		if err := saveAllowedDirs(o.Filename, allowed); err != nil {
+			return err
+		}
+		fmt.Fprintf(o.Out, "%s is no longer allowed.\n", filepath.Join(dir, ".kubeconfig"))
+		return nil
+	}
+	checksum, err := dirKubeconfigChecksum(dir)
+	if err != nil {
+		return err
+	}
+	if allowed.Dirs == nil {
+		allowed.Dirs = map[string]string{}
+	}
+	allowed.Dirs[dir] = checksum
+	if err := saveAllowedDirs(o.Filename, allowed); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "%s is allowed.\n", filepath.Join(dir, ".kubeconfig"))
+	return nil
+}
+
+// Complete validates the shell argument
+func (o *ShellInitOptions) Complete(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return helpErrorf(cmd, "Unexpected args: %v", args)
+	}
+	o.Shell = args[0]
+	integration, ok := shellIntegrations[o.Shell]
+	if !ok {
+		shells := []string{}
+		for shell := range shellIntegrations {
+			shells = append(shells, shell)
+		}
+		sort.Strings(shells)
+		return fmt.Errorf("unsupported shell %q, must be one of: %s", o.Shell, strings.Join(shells, ", "))
+	}
+	if o.Install && len(o.RCFile) == 0 {
+		o.RCFile = integration.rcFile()
+	}
+	return nil
+}
+
+// Run prints or installs the shell integration
+func (o *ShellInitOptions) Run() error {
+	integration := shellIntegrations[o.Shell]
+	if !o.Install {
+		fmt.Fprint(o.Out, integration.script)
+		return nil
+	}
+
+	added, err := appendToRCFile(o.RCFile, "kubectl config shell integration", integration.loader)
+	if err != nil {
+		return err
+	}
+	if !added {
+		fmt.Fprintf(o.Out, "Shell integration is already loaded by %s.\n", o.RCFile)
+		return nil
+	}
+	fmt.Fprintf(o.Out, "Shell integration added to %s, start a new shell to load it.\n", o.RCFile)
+	return nil
+}
+
+// appendToRCFile adds line to the startup file of a shell, after a comment, unless the file has it
+// already. It reports whether the line was added.
+func appendToRCFile(rcFile, comment, line string) (bool, error) {
+	data, err := ioutil.ReadFile(rcFile)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	for _, existing := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(existing) == line {
+			return false, nil
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(rcFile), 0755); err != nil {
+		return false, err
+	}
+	f, err := os.OpenFile(rcFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	prefix := ""
+	if len(data) > 0 {
+		prefix = "\n"
+		if !strings.HasSuffix(string(data), "\n") {
+			prefix = "\n\n"
+		}
+	}
+	if _, err := fmt.Fprintf(f, "%s# %s\n%s\n", prefix, comment, line); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func TestShellInitPrintsScript(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish", "pwsh"} {
+		streams, _, out, _ := genericclioptions.NewTestIOStreams()
+		o := &ShellInitOptions{IOStreams: streams}
+		if err := o.Complete(nil, []string{shell}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := o.Run(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, expected := range []string{"kcfg", "_kcfg_chpwd", "KCFG_PROMPT", "KCFG_PREVIOUS_CONTEXT", "KCFG_ALIASES", "shell-init allowed"} {
+			if !strings.Contains(out.String(), expected) {
+				t.Errorf("expected the %s script to define %s", shell, expected)
+			}
+		}
+	}
+
+	o := &ShellInitOptions{}
+	if err := o.Complete(nil, []string{"csh"}); err == nil {
+		t.Errorf("expected an error for an unsupported shell")
+	}
+}
+
+func TestShellInitInstall(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shell-init")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	rcFile := filepath.Join(dir, ".bashrc")
+	if err := ioutil.WriteFile(rcFile, []byte("alias k=kubectl"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		streams, _, _, _ := genericclioptions.NewTestIOStreams()
+		o := &ShellInitOptions{Install: true, RCFile: rcFile, IOStreams: streams}
+		if err := o.Complete(nil, []string{"bash"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := o.Run(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	data, err := ioutil.ReadFile(rcFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "alias k=kubectl\n\n# kubectl config shell integration\n" + `eval "$(kubectl config shell-init bash)"` + "\n"
+	if string(data) != expected {
+		t.Errorf("expected the loader to be added once, got %q", string(data))
+	}
+}
+
+func TestShellInitAllow(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shell-init")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	project := filepath.Join(dir, "project")
+	if err := os.MkdirAll(project, 0755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	kubeconfig := filepath.Join(project, ".kubeconfig")
+	if err := ioutil.WriteFile(kubeconfig, []byte("apiVersion: v1\nkind: Config\n"), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	filename := filepath.Join(dir, "allowed-dirs.yaml")
+	run := func(action string) error {
+		streams, _, _, _ := genericclioptions.NewTestIOStreams()
+		o := &ShellInitAllowOptions{Action: action, Dir: project, Filename: filename, IOStreams: streams}
+		return o.Run()
+	}
+
+	if err := run("allowed"); err == nil || !strings.Contains(err.Error(), "is not allowed") {
+		t.Errorf("expected a directory never allowed to be refused, got %v", err)
+	}
+	if err := run("allow"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := run("allowed"); err != nil {
+		t.Errorf("expected the directory to be allowed, got %v", err)
+	}
+	if err := ioutil.WriteFile(kubeconfig, []byte("apiVersion: v1\nkind: Config\nusers:\n- name: x\n  user:\n    exec:\n      command: sh\n"), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := run("allowed"); err == nil {
+		t.Errorf("expected a file changed since it was allowed to be refused")
+	}
+	if err := run("allow"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := run("deny"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := run("allowed"); err == nil {
+		t.Errorf("expected a denied directory to be refused")
+	}
+}
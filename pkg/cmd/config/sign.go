@@ -0,0 +1,269 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// signatureSuffix is appended to a bundle's file name to find its signature by default.
+const signatureSuffix = ".sig"
+
+// SignOptions holds the command-line options for 'config sign' and 'config verify' sub commands
+type SignOptions struct {
+	Bundle    string
+	Signature string
+	Key       string
+
+	genericclioptions.IOStreams
+}
+
+var (
+	signLong = templates.LongDesc(`
+		Signs a kubeconfig bundle with a private key, so that the users it is distributed to can
+		check where it comes from with "kubectl config verify" or "kubectl config import
+		--signature-key".
+
+		The key is a PEM encoded RSA or ECDSA private key, in PKCS #8, PKCS #1 or SEC 1 form. The
+		signature covers the SHA-256 digest of the file and is written base64 encoded.`)
+
+	signExample = templates.Examples(`
+		# Create a key pair with openssl and sign a bundle
+		openssl ecparam -name prime256v1 -genkey -noout -out key.pem
+		openssl ec -in key.pem -pubout -out pub.pem
+		kubectl config sign bundle.yaml --key key.pem -o bundle.sig`)
+
+	verifyLong = templates.LongDesc(`
+		Verifies the signature "kubectl config sign" made of a kubeconfig bundle with the signer's
+		PEM encoded public key. The signature defaults to the bundle's file name followed by .sig.`)
+
+	verifyExample = templates.Examples(`
+		# Verify a bundle before importing it
+		kubectl config verify bundle.yaml bundle.sig --key pub.pem`)
+)
+
+// NewCmdConfigSign returns a Command instance for 'config sign' sub command
+func NewCmdConfigSign(streams genericclioptions.IOStreams) *cobra.Command {
+	o := &SignOptions{IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "sign FILE --key=PRIVATE_KEY [-o SIGNATURE]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Sign a kubeconfig bundle"),
+		Long:                  signLong,
+		Example:               signExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.Bundle = args[0]
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.RunSign())
+		},
+	}
+
+	cmd.Flags().StringVar(&o.Key, "key", o.Key, "PEM encoded private key to sign with")
+	cmd.Flags().StringVarP(&o.Signature, "output", "o", o.Signature, "Where to write the signature. Defaults to FILE.sig")
+	return cmd
+}
+
+// NewCmdConfigVerify returns a Command instance for 'config verify' sub command
+func NewCmdConfigVerify(streams genericclioptions.IOStreams) *cobra.Command {
+	o := &SignOptions{IOStreams: streams}
+
+	cmd := &cobra.Command{
+		Use:                   "verify FILE [SIGNATURE] --key=PUBLIC_KEY",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Verify the signature of a kubeconfig bundle"),
+		Long:                  verifyLong,
+		Example:               verifyExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 && len(args) != 2 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.Bundle = args[0]
+			if len(args) == 2 {
+				o.Signature = args[1]
+			}
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.RunVerify())
+		},
+	}
+
+	cmd.Flags().StringVar(&o.Key, "key", o.Key, "PEM encoded public key of the signer")
+	return cmd
+}
+
+// Validate makes sure a key was given and defaults the signature file
+func (o *SignOptions) Validate() error {
+	if len(o.Key) == 0 {
+		return errors.New("--key is required")
+	}
+	if len(o.Signature) == 0 {
+		o.Signature = o.Bundle + signatureSuffix
+	}
+	return nil
+}
+
+// RunSign writes the signature of the bundle
+func (o *SignOptions) RunSign() error {
+	keyData, err := ioutil.ReadFile(o.Key)
+	if err != nil {
+		return err
+	}
+	key, err := parseSigningKey(keyData)
+	if err != nil {
+		return fmt.Errorf("%s: %v", o.Key, err)
+	}
+	data, err := ioutil.ReadFile(o.Bundle)
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256(data)
+	signature, err := key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(o.Signature, []byte(base64.StdEncoding.EncodeToString(signature)+"\n"), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "Signature written to %s.\n", o.Signature)
+	return nil
+}
+
+// RunVerify checks the signature of the bundle
+func (o *SignOptions) RunVerify() error {
+	keyData, err := ioutil.ReadFile(o.Key)
+	if err != nil {
+		return err
+	}
+	key, err := parseVerificationKey(keyData)
+	if err != nil {
+		return fmt.Errorf("%s: %v", o.Key, err)
+	}
+	if err := verifyBundleSignature(o.Bundle, o.Signature, key); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "Signature of %s is valid.\n", o.Bundle)
+	return nil
+}
+
+// verifyBundleSignature checks that signatureFile holds a signature of bundle made with the private
+// key matching key.
+func verifyBundleSignature(bundle, signatureFile string, key crypto.PublicKey) error {
+	data, err := ioutil.ReadFile(bundle)
+	if err != nil {
+		return err
+	}
+	encoded, err := ioutil.ReadFile(signatureFile)
+	if err != nil {
+		return err
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil {
+		return fmt.Errorf("%s is not a base64 encoded signature: %v", signatureFile, err)
+	}
+
+	digest := sha256.Sum256(data)
+	valid := false
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		valid = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	case *ecdsa.PublicKey:
+		valid = ecdsaVerifyASN1(key, digest[:], signature)
+	default:
+		return fmt.Errorf("unsupported public key type %T", key)
+	}
+	if !valid {
+		return fmt.Errorf("the signature in %s does not match %s", signatureFile, bundle)
+	}
+	return nil
+}
+
+// ecdsaVerifyASN1 verifies an ASN.1 encoded ECDSA signature, the form crypto.Signer produces.
+func ecdsaVerifyASN1(key *ecdsa.PublicKey, digest, signature []byte) bool {
+	var sig struct {
+		R, S *big.Int
+	}
+	if rest, err := asn1.Unmarshal(signature, &sig); err != nil || len(rest) > 0 {
+		return false
+	}
+	return ecdsa.Verify(key, digest, sig.R, sig.S)
+}
+
+// parseSigningKey decodes a PEM encoded RSA or ECDSA private key.
+func parseSigningKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM encoded key found")
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		if signer, ok := key.(crypto.Signer); ok {
+			return signer, nil
+		}
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("not an RSA or ECDSA private key")
+}
+
+// parseVerificationKey decodes a PEM encoded RSA or ECDSA public key, or the key of a certificate.
+func parseVerificationKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM encoded key found")
+	}
+	if block.Type == "CERTIFICATE" {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	}
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("not an RSA or ECDSA public key")
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+// writeSigningKeys writes a PEM encoded key pair to dir and returns the private and public key files.
+func writeSigningKeys(t *testing.T, dir string, private crypto.Signer) (string, string) {
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(private.Public())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	privateFile := filepath.Join(dir, "key.pem")
+	publicFile := filepath.Join(dir, "pub.pem")
+	if err := ioutil.WriteFile(privateFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ioutil.WriteFile(publicFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return privateFile, publicFile
+}
+
+func TestSignAndVerify(t *testing.T) {
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for name, key := range map[string]crypto.Signer{"ecdsa": ecdsaKey, "rsa": rsaKey} {
+		t.Run(name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "sign")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer os.RemoveAll(dir)
+			privateFile, publicFile := writeSigningKeys(t, dir, key)
+			bundle := filepath.Join(dir, "bundle.yaml")
+			if err := ioutil.WriteFile(bundle, []byte("apiVersion: v1\nkind: Config\n"), 0644); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			streams, _, out, _ := genericclioptions.NewTestIOStreams()
+			sign := &SignOptions{Bundle: bundle, Key: privateFile, IOStreams: streams}
+			if err := sign.Validate(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := sign.RunSign(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if sign.Signature != bundle+".sig" {
+				t.Errorf("expected the signature to default to %s.sig, got %s", bundle, sign.Signature)
+			}
+
+			verify := &SignOptions{Bundle: bundle, Key: publicFile, IOStreams: streams}
+			if err := verify.Validate(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := verify.RunVerify(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !strings.Contains(out.String(), "Signature of "+bundle+" is valid.") {
+				t.Errorf("unexpected output %q", out.String())
+			}
+
+			if err := ioutil.WriteFile(bundle, []byte("apiVersion: v1\nkind: Config\nusers: []\n"), 0644); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := verify.RunVerify(); err == nil || !strings.Contains(err.Error(), "does not match") {
+				t.Errorf("expected a tampered bundle to be rejected, got %v", err)
+			}
+		})
+	}
+}
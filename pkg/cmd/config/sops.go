@@ -0,0 +1,210 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// sopsCommand runs the sops binary. Tests replace it.
+var sopsCommand = func(env []string, args ...string) ([]byte, error) {
+	cmd := exec.Command("sops", args...)
+	cmd.Env = append(os.Environ(), env...)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if _, notFound := err.(*exec.Error); notFound {
+			return nil, fmt.Errorf("sops is needed to work with encrypted kubeconfig files: %v", err)
+		}
+		return nil, fmt.Errorf("sops %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// sopsFile is an encrypted kubeconfig file and its decrypted copy.
+type sopsFile struct {
+	encrypted string
+	plaintext string
+	format    string
+	checksum  [sha256.Size]byte
+}
+
+// sopsSession lets the config subcommands work on SOPS encrypted kubeconfig files. It decrypts them
+// to private temporary files, points the path options at those, and encrypts the files the command
+// changed back with sops, which keeps the keys the files were encrypted with.
+type sopsSession struct {
+	dir   string
+	files []sopsFile
+}
+
+// isSopsEncrypted reports whether data is a YAML or JSON document encrypted by sops, which adds a
+// top-level "sops" stanza holding the message authentication code.
+func isSopsEncrypted(data []byte) bool {
+	document := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return false
+	}
+	metadata, ok := document["sops"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	_, ok = metadata["mac"]
+	return ok
+}
+
+// startSopsSession decrypts the encrypted files among the ones pathOptions loads. It returns nil
+// when there are none. With noWrite, encrypted files are refused instead, since their decrypted
+// copies would be written.
+func startSopsSession(pathOptions *clientcmd.PathOptions, noWrite bool) (*sopsSession, error) {
+	files := pathOptions.GetLoadingPrecedence()
+	if pathOptions.IsExplicitFile() {
+		files = []string{pathOptions.GetExplicitFile()}
+	}
+
+	s := &sopsSession{}
+	replaced := make([]string, 0, len(files))
+	for _, filename := range files {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil || !isSopsEncrypted(data) {
+			replaced = append(replaced, filename)
+			continue
+		}
+		if noWrite {
+			s.cleanup()
+			return nil, fmt.Errorf("%s is encrypted with SOPS, it cannot be decrypted with --%s since its plaintext would be written to a temporary file", filename, FlagNoWrite)
+		}
+		if len(s.dir) == 0 {
+			if s.dir, err = ioutil.TempDir("", "kubecfg-sops"); err != nil {
+				return nil, err
+			}
+		}
+		file, err := s.decrypt(filename, data)
+		if err != nil {
+			s.cleanup()
+			return nil, err
+		}
+		s.files = append(s.files, file)
+		replaced = append(replaced, file.plaintext)
+	}
+	if len(s.files) == 0 {
+		return nil, nil
+	}
+
+	switch {
+	case pathOptions.IsExplicitFile():
+		pathOptions.LoadingRules.ExplicitPath = replaced[0]
+	case len(pathOptions.GetEnvVarFiles()) > 0:
+		// The variable is read again on every access, and exec credential plugins started by the
+		// command see the same files.
+		os.Setenv(pathOptions.EnvVar, strings.Join(replaced, string(filepath.ListSeparator)))
+	default:
+		pathOptions.GlobalFile = replaced[0]
+	}
+	return s, nil
+}
+
+func (s *sopsSession) decrypt(filename string, data []byte) (sopsFile, error) {
+	format := "yaml"
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		format = "json"
+	}
+	plaintext, err := sopsCommand(nil, "--decrypt", "--input-type", format, "--output-type", format, filename)
+	if err != nil {
+		return sopsFile{}, err
+	}
+	file := sopsFile{
+		encrypted: filename,
+		plaintext: filepath.Join(s.dir, fmt.Sprintf("%d-%s", len(s.files), filepath.Base(filename))),
+		format:    format,
+		checksum:  sha256.Sum256(plaintext),
+	}
+	return file, ioutil.WriteFile(file.plaintext, plaintext, 0600)
+}
+
+// finish encrypts the decrypted copies the command changed back into their files and removes the
+// copies.
+func (s *sopsSession) finish() error {
+	defer s.cleanup()
+	return s.encrypt()
+}
+
+// encrypt encrypts the decrypted copies the command changed back into their files, keeping the
+// copies.
+func (s *sopsSession) encrypt() error {
+	for _, file := range s.files {
+		plaintext, err := ioutil.ReadFile(file.plaintext)
+		if err != nil {
+			return err
+		}
+		if sha256.Sum256(plaintext) == file.checksum {
+			continue
+		}
+		// sops re-encrypts what its editor leaves behind with the file's original keys; copying the
+		// changed copy over the file it opens is how sops edits files without a terminal.
+		editor := "EDITOR=cp " + shellQuote(file.plaintext)
+		if _, err := sopsCommand([]string{editor}, "--input-type", file.format, "--output-type", file.format, file.encrypted); err != nil {
+			return fmt.Errorf("%s was changed but could not be encrypted again: %v", file.encrypted, err)
+		}
+	}
+	return nil
+}
+
+// encryptedModTimes replaces the decrypted copies among the files of times with their encrypted
+// files, at the modification times of those.
+func (s *sopsSession) encryptedModTimes(times map[string]time.Time) map[string]time.Time {
+	mapped := map[string]time.Time{}
+	for filename, t := range times {
+		mapped[filename] = t
+	}
+	for _, file := range s.files {
+		if _, ok := mapped[file.plaintext]; !ok {
+			continue
+		}
+		delete(mapped, file.plaintext)
+		if info, err := os.Stat(file.encrypted); err == nil {
+			mapped[file.encrypted] = info.ModTime()
+		}
+	}
+	return mapped
+}
+
+// cleanup removes the decrypted copies without encrypting them.
+func (s *sopsSession) cleanup() {
+	if len(s.dir) > 0 {
+		os.RemoveAll(s.dir)
+	}
+}
+
+// exit is installed as the behavior on fatal errors for the duration of a session, so that the
+// decrypted copies are removed even when a command exits early.
+func (s *sopsSession) exit(msg string, code int) {
+	s.cleanup()
+	exitWithMessage(msg, code)
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// fakeSopsDocument is what the fake sops below writes for an encrypted file: the plaintext next to
+// the sops stanza.
+type fakeSopsDocument struct {
+	Sops      map[string]string `json:"sops"`
+	Plaintext string            `json:"plaintext"`
+}
+
+func writeFakeSopsFile(t *testing.T, filename string, plaintext []byte) {
+	data, err := yaml.Marshal(fakeSopsDocument{Sops: map[string]string{"mac": "fake"}, Plaintext: string(plaintext)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ioutil.WriteFile(filename, data, 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// fakeSops decrypts and edits files written by writeFakeSopsFile the way sops does.
+func fakeSops(t *testing.T, edits *int) func([]string, ...string) ([]byte, error) {
+	return func(env []string, args ...string) ([]byte, error) {
+		filename := args[len(args)-1]
+		if args[0] == "--decrypt" {
+			data, err := ioutil.ReadFile(filename)
+			if err != nil {
+				return nil, err
+			}
+			document := fakeSopsDocument{}
+			if err := yaml.Unmarshal(data, &document); err != nil {
+				return nil, err
+			}
+			return []byte(document.Plaintext), nil
+		}
+
+		*edits++
+		if len(env) != 1 || !strings.HasPrefix(env[0], "EDITOR=cp '") {
+			t.Fatalf("unexpected environment %v", env)
+		}
+		changed, err := ioutil.ReadFile(strings.Trim(strings.TrimPrefix(env[0], "EDITOR=cp "), "'"))
+		if err != nil {
+			return nil, err
+		}
+		writeFakeSopsFile(t, filename, changed)
+		return nil, nil
+	}
+}
+
+func TestSopsSession(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sops")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	plaintext, err := clientcmd.Write(newRedFederalCowHammerConfig())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	kubeconfig := filepath.Join(dir, "config")
+	writeFakeSopsFile(t, kubeconfig, plaintext)
+	if isSopsEncrypted(plaintext) {
+		t.Errorf("expected a plain kubeconfig not to be reported as encrypted")
+	}
+
+	edits := 0
+	defer func(original func([]string, ...string) ([]byte, error)) { sopsCommand = original }(sopsCommand)
+	sopsCommand = fakeSops(t, &edits)
+
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = kubeconfig
+	pathOptions.EnvVar = ""
+
+	// A command only reading the kubeconfig leaves the encrypted file alone.
+	session, err := startSopsSession(pathOptions, false)
+	if err != nil || session == nil {
+		t.Fatalf("expected a session, got %v, %v", session, err)
+	}
+	if pathOptions.GlobalFile == kubeconfig {
+		t.Fatalf("expected the path options to point at the decrypted copy")
+	}
+	config, err := pathOptions.GetStartingConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.CurrentContext != "federal-context" {
+		t.Errorf("expected the decrypted kubeconfig to be loaded, got current-context %q", config.CurrentContext)
+	}
+	if err := session.finish(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if edits != 0 {
+		t.Errorf("expected an unchanged file not to be encrypted again")
+	}
+	if _, err := os.Stat(session.dir); !os.IsNotExist(err) {
+		t.Errorf("expected the decrypted copy to be removed, got %v", err)
+	}
+
+	// A command changing the kubeconfig has its change encrypted into the original file.
+	pathOptions.GlobalFile = kubeconfig
+	session, err = startSopsSession(pathOptions, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	config, err = pathOptions.GetStartingConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	config.CurrentContext = ""
+	if err := clientcmd.ModifyConfig(pathOptions, *config, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := session.finish(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if edits != 1 {
+		t.Errorf("expected the changed file to be encrypted again, got %d edits", edits)
+	}
+	data, err := ioutil.ReadFile(kubeconfig)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !isSopsEncrypted(data) || strings.Contains(string(data), "current-context: federal-context") {
+		t.Errorf("expected the encrypted file to hold the change, got:\n%s", data)
+	}
+
+	// With --no-write, the plaintext is never written, the encrypted file is refused instead.
+	if session, err = startSopsSession(pathOptions, true); err == nil || !strings.Contains(err.Error(), "--no-write") {
+		t.Errorf("expected the encrypted file to be refused with --no-write, got %v", err)
+	}
+	if session != nil || pathOptions.GlobalFile != kubeconfig {
+		t.Errorf("expected no decrypted copy with --no-write, got %v, %s", session, pathOptions.GlobalFile)
+	}
+}
+
+func TestSopsSessionWithoutEncryptedFiles(t *testing.T) {
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	if err := clientcmd.WriteToFile(newRedFederalCowHammerConfig(), fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	session, err := startSopsSession(pathOptions, false)
+	if err != nil || session != nil {
+		t.Errorf("expected no session, got %v, %v", session, err)
+	}
+	if pathOptions.GlobalFile != fakeKubeFile.Name() {
+		t.Errorf("expected the path options to be left alone, got %s", pathOptions.GlobalFile)
+	}
+}
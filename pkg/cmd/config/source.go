@@ -0,0 +1,465 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/printers"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// remoteSource is a kubeconfig served over HTTP(S) and merged into the kubeconfig.
+type remoteSource struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// Refresh is how long a fetched copy is used before "source sync" fetches it again.
+	Refresh string `json:"refresh,omitempty"`
+	// Auth is "bearer:TOKEN" or "basic:USER:PASSWORD". Environment variables are expanded when
+	// fetching, so a reference such as $TOKEN keeps the secret out of the sources file.
+	Auth string `json:"auth,omitempty"`
+	// SHA256 pins the checksum the fetched content must have.
+	SHA256 string `json:"sha256,omitempty"`
+
+	LastSync time.Time `json:"lastSync,omitempty"`
+	Checksum string    `json:"checksum,omitempty"`
+}
+
+// remoteSources is the content of the sources file.
+type remoteSources struct {
+	Sources []remoteSource `json:"sources,omitempty"`
+}
+
+// SourceOptions holds the command-line options for 'config source' sub commands
+type SourceOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Filename     string
+	CacheDir     string
+	Source       remoteSource
+	Force        bool
+
+	Client *http.Client
+	Now    func() time.Time
+	log    *cmdLogger
+
+	genericclioptions.IOStreams
+}
+
+var validSourceName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+var (
+	sourceLong = templates.LongDesc(`
+		Merge kubeconfig files published over HTTP(S), such as the ones a platform team serves
+		for all its clusters, and refresh them periodically.
+
+		Sources are stored in sources.yaml next to the settings file and their last fetched copy
+		in the kubecfg state directory, so that "source sync" can merge them again without the
+		network when they are not due for a refresh. The entries merged from sources are recorded
+		in the kubecfg.io/sources extension of the kubeconfig preferences; each sync replaces them
+		and removes the ones no source defines anymore. Entries of the kubeconfig itself are never
+		replaced.
+
+		--auth takes bearer:TOKEN or basic:USER:PASSWORD. Environment variables in it are
+		expanded each time the source is fetched, so quoting a reference like '$TOKEN' keeps the
+		secret out of the sources file. --sha256 makes fetches fail unless the content has the
+		given checksum.`)
+
+	sourceExample = templates.Examples(`
+		# Merge the platform team's clusters and refresh them daily
+		kubectl config source add https://cfg.corp/teams/platform.yaml --refresh=24h --auth='bearer:$TOKEN'
+
+		# Refresh the sources that are due
+		kubectl config source sync
+
+		# Refresh all sources now
+		kubectl config source sync --force`)
+)
+
+// NewCmdConfigSource returns a Command instance for 'config source' sub command
+func NewCmdConfigSource(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &SourceOptions{
+		ConfigAccess: configAccess,
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "source SUBCOMMAND",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Merge remote kubeconfig files and refresh them periodically"),
+		Long:                  sourceLong,
+		Example:               sourceExample,
+		Run:                   cmdutil.DefaultSubCommandRun(streams.ErrOut),
+	}
+
+	addCmd := &cobra.Command{
+		Use:   "add URL [--name=NAME] [--refresh=DURATION] [--auth=bearer:TOKEN] [--sha256=CHECKSUM]",
+		Short: i18n.T("Register a remote kubeconfig and merge it"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.Source.URL = args[0]
+			cmdutil.CheckErr(o.Complete(cmd))
+			cmdutil.CheckErr(requireNetwork(cmd))
+			cmdutil.CheckErr(o.RunAdd())
+		},
+	}
+	addCmd.Flags().StringVar(&o.Source.Name, "name", "", "Name of the source. Defaults to the file name of the URL")
+	addCmd.Flags().StringVar(&o.Source.Refresh, "refresh", "24h", "How long a fetched copy is used before it is fetched again")
+	addCmd.Flags().StringVar(&o.Source.Auth, "auth", "", "Credentials sent with the request. One of: bearer:TOKEN|basic:USER:PASSWORD")
+	addCmd.Flags().StringVar(&o.Source.SHA256, "sha256", "", "Checksum the fetched content must have")
+	cmd.AddCommand(addCmd)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "remove NAME",
+		Short: i18n.T("Unregister a remote kubeconfig and remove its entries"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.Source.Name = args[0]
+			cmdutil.CheckErr(o.Complete(cmd))
+			cmdutil.CheckErr(o.RunRemove())
+		},
+	})
+	cmd.AddCommand(noWriteCommand(&cobra.Command{
+		Use:   "list",
+		Short: i18n.T("List remote kubeconfigs"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			cmdutil.CheckErr(o.Complete(cmd))
+			cmdutil.CheckErr(o.RunList())
+		},
+	}))
+	syncCmd := &cobra.Command{
+		Use:   "sync [--force]",
+		Short: i18n.T("Fetch the remote kubeconfigs due for a refresh and merge all of them"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			cmdutil.CheckErr(o.Complete(cmd))
+			cmdutil.CheckErr(o.RunSync(requireNetwork(cmd) == nil))
+		},
+	}
+	syncCmd.Flags().BoolVar(&o.Force, "force", o.Force, "If true, fetch every source even if its copy is recent enough")
+	cmd.AddCommand(syncCmd)
+	return cmd
+}
+
+// Complete locates the sources file and cache, and sets up logging
+func (o *SourceOptions) Complete(cmd *cobra.Command) error {
+	if len(o.Filename) == 0 {
+		o.Filename = filepath.Join(configDir(), "sources.yaml")
+	}
+	if len(o.CacheDir) == 0 {
+		o.CacheDir = filepath.Join(stateDir(), "sources")
+	}
+	if o.Client == nil {
+		o.Client = &http.Client{Timeout: 30 * time.Second}
+	}
+	if o.Now == nil {
+		o.Now = time.Now
+	}
+	var err error
+	o.log, err = newCmdLogger(cmd, o.ErrOut)
+	return err
+}
+
+// RunAdd registers a source, fetches it and merges all sources
+func (o *SourceOptions) RunAdd() error {
+	source := o.Source
+	u, err := url.Parse(source.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || len(u.Host) == 0 {
+		return fmt.Errorf("%q is not an http or https URL", source.URL)
+	}
+	if len(source.Name) == 0 {
+		source.Name = strings.TrimSuffix(path.Base(u.Path), path.Ext(u.Path))
+	}
+	if !validSourceName.MatchString(source.Name) {
+		return fmt.Errorf("invalid source name %q, use --name to choose one", source.Name)
+	}
+	if _, err := time.ParseDuration(source.Refresh); err != nil {
+		return fmt.Errorf("invalid --refresh: %v", err)
+	}
+	if _, err := sourceAuthHeader(source.Auth); err != nil {
+		return err
+	}
+	source.SHA256 = strings.ToLower(source.SHA256)
+
+	all, err := loadRemoteSources(o.Filename)
+	if err != nil {
+		return err
+	}
+	for _, existing := range all.Sources {
+		if existing.Name == source.Name {
+			return fmt.Errorf("a source named %q already exists", source.Name)
+		}
+	}
+	if err := o.fetch(&source); err != nil {
+		return err
+	}
+	all.Sources = append(all.Sources, source)
+	if err := saveRemoteSources(o.Filename, all); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "Source %q added.\n", source.Name)
+	return o.merge(all)
+}
+
+// RunRemove unregisters a source and merges the remaining ones, which removes its entries
+func (o *SourceOptions) RunRemove() error {
+	all, err := loadRemoteSources(o.Filename)
+	if err != nil {
+		return err
+	}
+	remaining := []remoteSource{}
+	for _, source := range all.Sources {
+		if source.Name != o.Source.Name {
+			remaining = append(remaining, source)
+		}
+	}
+	if len(remaining) == len(all.Sources) {
+		return fmt.Errorf("no source exists with the name: %q", o.Source.Name)
+	}
+	all.Sources = remaining
+	if err := saveRemoteSources(o.Filename, all); err != nil {
+		return err
+	}
+	if err := os.Remove(o.cacheFile(o.Source.Name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	fmt.Fprintf(o.Out, "Source %q removed.\n", o.Source.Name)
+	return o.merge(all)
+}
+
+// RunList prints all sources with the time they were fetched last
+func (o *SourceOptions) RunList() error {
+	all, err := loadRemoteSources(o.Filename)
+	if err != nil {
+		return err
+	}
+	w := printers.GetNewTabWriter(o.Out)
+	fmt.Fprintf(w, "NAME\tURL\tREFRESH\tLAST SYNC\n")
+	for _, source := range all.Sources {
+		lastSync := "never"
+		if !source.LastSync.IsZero() {
+			lastSync = source.LastSync.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", source.Name, source.URL, source.Refresh, lastSync)
+	}
+	return w.Flush()
+}
+
+// RunSync fetches the sources due for a refresh, unless online is false, and merges all of them
+func (o *SourceOptions) RunSync(online bool) error {
+	all, err := loadRemoteSources(o.Filename)
+	if err != nil {
+		return err
+	}
+	failed := []string{}
+	for i := range all.Sources {
+		source := &all.Sources[i]
+		refresh, _ := time.ParseDuration(source.Refresh)
+		if !o.Force && !source.LastSync.IsZero() && o.Now().Before(source.LastSync.Add(refresh)) {
+			o.log.Infof(1, "%s: fetched at %s, not due yet", source.Name, source.LastSync.Format(time.RFC3339))
+			continue
+		}
+		if !online {
+			o.log.Warningf("%s: not fetched, network access is disabled", source.Name)
+			continue
+		}
+		if err := o.fetch(source); err != nil {
+			o.log.Warningf("%s: %v", source.Name, err)
+			failed = append(failed, source.Name)
+		}
+	}
+	if err := saveRemoteSources(o.Filename, all); err != nil {
+		return err
+	}
+	if err := o.merge(all); err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d sources could not be fetched, their previous copy was merged: %s", len(failed), len(all.Sources), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// fetch downloads a source, verifies it and replaces its cached copy.
+func (o *SourceOptions) fetch(source *remoteSource) error {
+	req, err := http.NewRequest(http.MethodGet, source.URL, nil)
+	if err != nil {
+		return err
+	}
+	authorization, err := sourceAuthHeader(os.ExpandEnv(source.Auth))
+	if err != nil {
+		return err
+	}
+	if len(authorization) > 0 {
+		req.Header.Set("Authorization", authorization)
+	}
+	o.log.Infof(2, "GET %s", source.URL)
+	resp, err := o.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching %s: %s", source.URL, resp.Status)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+	if len(source.SHA256) > 0 && checksum != source.SHA256 {
+		return fmt.Errorf("%s has checksum %s, expected %s", source.URL, checksum, source.SHA256)
+	}
+	if _, err := clientcmd.Load(data); err != nil {
+		return fmt.Errorf("%s is not a valid kubeconfig: %v", source.URL, err)
+	}
+
+	if err := os.MkdirAll(o.CacheDir, 0700); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(o.cacheFile(source.Name), data, 0600); err != nil {
+		return err
+	}
+	if len(source.Checksum) > 0 && source.Checksum != checksum {
+		fmt.Fprintf(o.Out, "Source %q changed.\n", source.Name)
+	}
+	source.Checksum = checksum
+	source.LastSync = o.Now()
+	return nil
+}
+
+// merge merges the cached copies of all sources into the kubeconfig, the first source defining a name
+// winning.
+func (o *SourceOptions) merge(all *remoteSources) error {
+	fetched := clientcmdapi.NewConfig()
+	for _, source := range all.Sources {
+		from, err := clientcmd.LoadFromFile(o.cacheFile(source.Name))
+		if os.IsNotExist(err) {
+			o.log.Warningf("%s: skipped, it was never fetched", source.Name)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := stampProvenance(from, "source", source.URL, o.Now()); err != nil {
+			return err
+		}
+		_, skipped := mergeImportedConfig(fetched, from, false)
+		for _, reason := range skipped {
+			o.log.Warningf("%s: skipped %s", source.Name, reason)
+		}
+	}
+
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	managed := &managedEntries{}
+	if _, err := readExtension(config.Preferences.Extensions, sourcesExtension, managed); err != nil {
+		return err
+	}
+	added, removed := syncManagedEntries(config, fetched, managed, o.log)
+	if len(all.Sources) == 0 {
+		delete(config.Preferences.Extensions, sourcesExtension)
+	} else if err := writeExtension(&config.Preferences.Extensions, sourcesExtension, managed); err != nil {
+		return err
+	}
+	if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "Merged %d entries from %d source(s), removed %d.\n", added, len(all.Sources), removed)
+	return nil
+}
+
+func (o *SourceOptions) cacheFile(name string) string {
+	return filepath.Join(o.CacheDir, name+".yaml")
+}
+
+// sourceAuthHeader turns the --auth value of a source into an Authorization header.
+func sourceAuthHeader(auth string) (string, error) {
+	switch {
+	case len(auth) == 0:
+		return "", nil
+	case strings.HasPrefix(auth, "bearer:"):
+		return "Bearer " + strings.TrimPrefix(auth, "bearer:"), nil
+	case strings.HasPrefix(auth, "basic:"):
+		req := &http.Request{Header: http.Header{}}
+		credentials := strings.SplitN(strings.TrimPrefix(auth, "basic:"), ":", 2)
+		if len(credentials) != 2 {
+			return "", fmt.Errorf("invalid --auth, basic credentials must be given as basic:USER:PASSWORD")
+		}
+		req.SetBasicAuth(credentials[0], credentials[1])
+		return req.Header.Get("Authorization"), nil
+	default:
+		return "", fmt.Errorf("invalid --auth, must be one of: bearer:TOKEN, basic:USER:PASSWORD")
+	}
+}
+
+func loadRemoteSources(filename string) (*remoteSources, error) {
+	all := &remoteSources{}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := yaml.Unmarshal(data, all); err != nil {
+			return nil, fmt.Errorf("error loading sources from %s: %v", filename, err)
+		}
+	}
+	return all, nil
+}
+
+func saveRemoteSources(filename string, all *remoteSources) error {
+	data, err := yaml.Marshal(all)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0600)
+}
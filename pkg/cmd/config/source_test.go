@@ -0,0 +1,168 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestSourceAddSyncRemove(t *testing.T) {
+	dir, err := ioutil.TempDir("", "source")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	kubeconfig := filepath.Join(dir, "config")
+	if err := clientcmd.WriteToFile(newRedFederalCowHammerConfig(), kubeconfig); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	served := clientcmdapi.Config{Clusters: map[string]*clientcmdapi.Cluster{"platform": {Server: "https://platform.corp"}}}
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("Authorization") != "Bearer s3cr3t" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		data, err := clientcmd.Write(served)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+	os.Setenv("KCFG_TEST_TOKEN", "s3cr3t")
+	defer os.Unsetenv("KCFG_TEST_TOKEN")
+
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = kubeconfig
+	pathOptions.EnvVar = ""
+	now := time.Date(2019, 8, 1, 12, 0, 0, 0, time.UTC)
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	newOptions := func() *SourceOptions {
+		o := &SourceOptions{
+			ConfigAccess: pathOptions,
+			Filename:     filepath.Join(dir, "sources.yaml"),
+			CacheDir:     filepath.Join(dir, "cache"),
+			Now:          func() time.Time { return now },
+			IOStreams:    streams,
+		}
+		if err := o.Complete(nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return o
+	}
+	loadClusters := func() map[string]*clientcmdapi.Cluster {
+		config, err := clientcmd.LoadFromFile(kubeconfig)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return config.Clusters
+	}
+
+	o := newOptions()
+	o.Source = remoteSource{URL: server.URL + "/teams/platform.yaml", Refresh: "24h", Auth: "bearer:$KCFG_TEST_TOKEN", SHA256: "0000"}
+	if err := o.RunAdd(); err == nil || !strings.Contains(err.Error(), "expected 0000") {
+		t.Fatalf("expected a checksum mismatch, got %v", err)
+	}
+
+	o.Source.SHA256 = ""
+	if err := o.RunAdd(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), `Source "platform" added.`) {
+		t.Errorf("unexpected output %q", out.String())
+	}
+	if cluster, ok := loadClusters()["platform"]; !ok || cluster.Server != "https://platform.corp" {
+		t.Fatalf("expected the platform cluster to be merged, got %#v", cluster)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "sources.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(string(data), "s3cr3t") {
+		t.Errorf("expected the token to be kept out of the sources file, got:\n%s", data)
+	}
+
+	served.Clusters["platform"].Server = "https://platform-v2.corp"
+	now = now.Add(time.Hour)
+	if err := newOptions().RunSync(true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("expected no fetch before the refresh interval, got %d requests", requests)
+	}
+
+	now = now.Add(24 * time.Hour)
+	out.Reset()
+	if err := newOptions().RunSync(true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), `Source "platform" changed.`) {
+		t.Errorf("unexpected output %q", out.String())
+	}
+	if server := loadClusters()["platform"].Server; server != "https://platform-v2.corp" {
+		t.Errorf("expected the refreshed server, got %q", server)
+	}
+
+	o = newOptions()
+	o.Source.Name = "platform"
+	if err := o.RunRemove(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	clusters := loadClusters()
+	if _, ok := clusters["platform"]; ok {
+		t.Errorf("expected the platform cluster to be removed with its source")
+	}
+	if _, ok := clusters["cow-cluster"]; !ok {
+		t.Errorf("expected cow-cluster to be kept")
+	}
+}
+
+func TestSourceAuthHeader(t *testing.T) {
+	tests := map[string]string{
+		"":                "",
+		"bearer:abc":      "Bearer abc",
+		"basic:ann:p:w:d": "Basic YW5uOnA6dzpk",
+	}
+	for auth, expected := range tests {
+		actual, err := sourceAuthHeader(auth)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", auth, err)
+		}
+		if actual != expected {
+			t.Errorf("%q: expected %q, got %q", auth, expected, actual)
+		}
+	}
+	for _, auth := range []string{"token:abc", "basic:ann"} {
+		if _, err := sourceAuthHeader(auth); err == nil {
+			t.Errorf("%q: expected an error", auth)
+		}
+	}
+}
@@ -0,0 +1,346 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// pluginExtensionPrefix starts the names of the kubeconfig extensions the config subcommands own.
+const pluginExtensionPrefix = "kubecfg.io/"
+
+// pluginState is the bundle of 'config state export': the metadata of the config subcommands,
+// without the clusters, users and contexts it is attached to.
+type pluginState struct {
+	// Contexts, Clusters and Users hold the kubecfg.io extensions of the entries, by entry name.
+	Contexts map[string]map[string]json.RawMessage `json:"contexts,omitempty"`
+	Clusters map[string]map[string]json.RawMessage `json:"clusters,omitempty"`
+	Users    map[string]map[string]json.RawMessage `json:"users,omitempty"`
+	// Preferences holds the kubecfg.io extensions of the preferences, such as context groups.
+	Preferences map[string]json.RawMessage `json:"preferences,omitempty"`
+	Settings    *Settings                  `json:"settings,omitempty"`
+	Profiles    *profiles                  `json:"profiles,omitempty"`
+}
+
+// StateOptions holds the command-line options for 'config state' sub commands
+type StateOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	SettingsFile string
+	ProfilesFile string
+	Filename     string
+	Overwrite    bool
+
+	genericclioptions.IOStreams
+}
+
+var (
+	stateLong = templates.LongDesc(`
+		Export or import the metadata the config subcommands keep, separately from the clusters,
+		users and contexts themselves, to move it to another machine or back it up.
+
+		The bundle holds the kubecfg.io extensions of contexts, clusters, users and preferences,
+		which keep tags, annotations, owners, read-only marks, kubectl flags, client tuning,
+		deprecations, kubectl version ranges, banners, provenance, the last namespace used in
+		every context, context groups and autoswitch rules, as well as the settings, with the
+		alias template, protected patterns and export presets, and the profiles. Credentials are never part of it. Acknowledgements
+		of the cooloff and other caches stay on every machine.
+
+		Importing attaches the metadata to the entries of the same name in kubeconfig, and reports
+		the entries kubeconfig does not have. Metadata, settings and profiles already present are
+		kept unless --overwrite is given.`)
+
+	stateExample = templates.Examples(`
+		# Back up the metadata of the config subcommands
+		kubectl config state export > kubecfg-state.yaml
+
+		# Restore it on another machine, once its kubeconfig has the same contexts
+		kubectl config state import kubecfg-state.yaml`)
+)
+
+// NewCmdConfigState returns a Command instance for 'config state' sub commands
+func NewCmdConfigState(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &StateOptions{
+		ConfigAccess: configAccess,
+		SettingsFile: settingsFile(),
+		ProfilesFile: filepath.Join(configDir(), "profiles.yaml"),
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "state SUBCOMMAND",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Export or import the metadata of the config subcommands"),
+		Long:                  stateLong,
+		Example:               stateExample,
+		Run:                   cmdutil.DefaultSubCommandRun(streams.ErrOut),
+	}
+
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: i18n.T("Print the metadata of the config subcommands as YAML"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			cmdutil.CheckErr(o.RunExport())
+		},
+	}
+	importCmd := &cobra.Command{
+		Use:   "import FILE [--overwrite]",
+		Short: i18n.T("Restore the metadata of the config subcommands from a file made by export"),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			o.Filename = args[0]
+			cmdutil.CheckErr(o.RunImport())
+		},
+	}
+	importCmd.Flags().BoolVar(&o.Overwrite, "overwrite", o.Overwrite, "If true, replace the metadata, settings and profiles already present")
+
+	cmd.AddCommand(noWriteCommand(exportCmd))
+	cmd.AddCommand(importCmd)
+	return cmd
+}
+
+// RunExport prints the bundle
+func (o *StateOptions) RunExport() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	state := &pluginState{}
+	for name, context := range config.Contexts {
+		if extensions, err := pluginExtensions(context.Extensions); err != nil {
+			return fmt.Errorf("context %q: %v", name, err)
+		} else if len(extensions) > 0 {
+			if state.Contexts == nil {
+				state.Contexts = map[string]map[string]json.RawMessage{}
+			}
+			state.Contexts[name] = extensions
+		}
+	}
+	for name, cluster := range config.Clusters {
+		if extensions, err := pluginExtensions(cluster.Extensions); err != nil {
+			return fmt.Errorf("cluster %q: %v", name, err)
+		} else if len(extensions) > 0 {
+			if state.Clusters == nil {
+				state.Clusters = map[string]map[string]json.RawMessage{}
+			}
+			state.Clusters[name] = extensions
+		}
+	}
+	for name, authInfo := range config.AuthInfos {
+		if extensions, err := pluginExtensions(authInfo.Extensions); err != nil {
+			return fmt.Errorf("user %q: %v", name, err)
+		} else if len(extensions) > 0 {
+			if state.Users == nil {
+				state.Users = map[string]map[string]json.RawMessage{}
+			}
+			state.Users[name] = extensions
+		}
+	}
+	if state.Preferences, err = pluginExtensions(config.Preferences.Extensions); err != nil {
+		return fmt.Errorf("preferences: %v", err)
+	}
+
+	if state.Settings, err = loadSettings(o.SettingsFile); err != nil {
+		return err
+	}
+	if reflect.DeepEqual(*state.Settings, Settings{}) {
+		state.Settings = nil
+	}
+	if state.Profiles, err = loadProfiles(o.ProfilesFile); err != nil {
+		return err
+	}
+	if len(state.Profiles.Profiles) == 0 {
+		state.Profiles = nil
+	}
+
+	data, err := yaml.Marshal(state)
+	if err != nil {
+		return err
+	}
+	_, err = o.Out.Write(data)
+	return err
+}
+
+// RunImport attaches the metadata of the bundle to kubeconfig and restores the settings and profiles
+func (o *StateOptions) RunImport() error {
+	data, err := ioutil.ReadFile(o.Filename)
+	if err != nil {
+		return err
+	}
+	state := &pluginState{}
+	if err := yaml.Unmarshal(data, state); err != nil {
+		return fmt.Errorf("%s: %v", o.Filename, err)
+	}
+
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	restored, missing := 0, []string{}
+	for _, name := range sets.StringKeySet(state.Contexts).List() {
+		if context, ok := config.Contexts[name]; ok {
+			restored += o.restoreExtensions(&context.Extensions, state.Contexts[name])
+		} else {
+			missing = append(missing, fmt.Sprintf("context %q", name))
+		}
+	}
+	for _, name := range sets.StringKeySet(state.Clusters).List() {
+		if cluster, ok := config.Clusters[name]; ok {
+			restored += o.restoreExtensions(&cluster.Extensions, state.Clusters[name])
+		} else {
+			missing = append(missing, fmt.Sprintf("cluster %q", name))
+		}
+	}
+	for _, name := range sets.StringKeySet(state.Users).List() {
+		if authInfo, ok := config.AuthInfos[name]; ok {
+			restored += o.restoreExtensions(&authInfo.Extensions, state.Users[name])
+		} else {
+			missing = append(missing, fmt.Sprintf("user %q", name))
+		}
+	}
+	restored += o.restoreExtensions(&config.Preferences.Extensions, state.Preferences)
+	for _, entry := range missing {
+		fmt.Fprintf(o.ErrOut, "warning: skipped the metadata of %s, which is not in kubeconfig\n", entry)
+	}
+	if restored > 0 {
+		if err := clientcmd.ModifyConfig(o.ConfigAccess, *config, true); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(o.Out, "Restored %d kubeconfig extension(s).\n", restored)
+
+	if state.Settings != nil {
+		settings, err := loadSettings(o.SettingsFile)
+		if err != nil {
+			return err
+		}
+		changed := []string{}
+		for _, definition := range settingDefinitions {
+			value := definition.get(state.Settings)
+			if value == definition.get(&Settings{}) || value == definition.get(settings) {
+				continue
+			}
+			if !o.Overwrite && definition.get(settings) != definition.get(&Settings{}) {
+				continue
+			}
+			if err := definition.set(settings, value); err != nil {
+				return fmt.Errorf("setting %s: %v", definition.name, err)
+			}
+			changed = append(changed, definition.name)
+		}
+		for _, name := range sets.StringKeySet(state.Settings.ExportPresets).List() {
+			preset := state.Settings.ExportPresets[name]
+			if existing, ok := settings.ExportPresets[name]; (ok && !o.Overwrite) || reflect.DeepEqual(existing, preset) {
+				continue
+			}
+			if settings.ExportPresets == nil {
+				settings.ExportPresets = map[string]exportPreset{}
+			}
+			settings.ExportPresets[name] = preset
+			changed = append(changed, "exportPresets."+name)
+		}
+		if len(changed) > 0 {
+			if err := saveSettings(o.SettingsFile, settings); err != nil {
+				return err
+			}
+			fmt.Fprintf(o.Out, "Restored the settings %s.\n", strings.Join(changed, ", "))
+		}
+	}
+
+	if state.Profiles != nil {
+		all, err := loadProfiles(o.ProfilesFile)
+		if err != nil {
+			return err
+		}
+		if all.Profiles == nil {
+			all.Profiles = map[string][]string{}
+		}
+		changed := []string{}
+		for _, name := range sets.StringKeySet(state.Profiles.Profiles).List() {
+			files := state.Profiles.Profiles[name]
+			if existing, ok := all.Profiles[name]; (ok && !o.Overwrite) || reflect.DeepEqual(existing, files) {
+				continue
+			}
+			all.Profiles[name] = files
+			changed = append(changed, name)
+		}
+		if len(changed) > 0 {
+			if err := saveProfiles(o.ProfilesFile, all); err != nil {
+				return err
+			}
+			fmt.Fprintf(o.Out, "Restored the profiles %s.\n", strings.Join(changed, ", "))
+		}
+	}
+	return nil
+}
+
+// restoreExtensions stores the bundled extensions, keeping the ones already set unless
+// overwriting, and returns how many it stored.
+func (o *StateOptions) restoreExtensions(extensions *map[string]runtime.Object, bundled map[string]json.RawMessage) int {
+	restored := 0
+	for _, name := range sets.StringKeySet(bundled).List() {
+		if _, ok := (*extensions)[name]; ok && !o.Overwrite {
+			continue
+		}
+		if *extensions == nil {
+			*extensions = map[string]runtime.Object{}
+		}
+		(*extensions)[name] = &runtime.Unknown{Raw: bundled[name], ContentType: runtime.ContentTypeJSON}
+		restored++
+	}
+	return restored
+}
+
+// pluginExtensions returns the kubecfg.io extensions of extensions as JSON.
+func pluginExtensions(extensions map[string]runtime.Object) (map[string]json.RawMessage, error) {
+	var owned map[string]json.RawMessage
+	for name := range extensions {
+		if !strings.HasPrefix(name, pluginExtensionPrefix) {
+			continue
+		}
+		var value json.RawMessage
+		if _, err := readExtension(extensions, name, &value); err != nil {
+			return nil, err
+		}
+		if owned == nil {
+			owned = map[string]json.RawMessage{}
+		}
+		owned[name] = value
+	}
+	return owned, nil
+}
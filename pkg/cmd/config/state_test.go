@@ -0,0 +1,142 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestStateExportImport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// newMachine writes the kubeconfig, settings and profiles of a machine to their own directory.
+	newMachine := func(name string, config clientcmdapi.Config, settings *Settings) (*StateOptions, func() (string, string)) {
+		machine := filepath.Join(dir, name)
+		if err := os.Mkdir(machine, 0700); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		pathOptions := clientcmd.NewDefaultPathOptions()
+		pathOptions.GlobalFile = filepath.Join(machine, "config")
+		pathOptions.EnvVar = ""
+		if err := clientcmd.WriteToFile(config, pathOptions.GlobalFile); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		o := &StateOptions{
+			ConfigAccess: pathOptions,
+			SettingsFile: filepath.Join(machine, "config.yaml"),
+			ProfilesFile: filepath.Join(machine, "profiles.yaml"),
+		}
+		if err := saveSettings(o.SettingsFile, settings); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var out, errOut *strings.Builder
+		reset := func() (string, string) {
+			var outString, errString string
+			if out != nil {
+				outString, errString = out.String(), errOut.String()
+			}
+			out, errOut = &strings.Builder{}, &strings.Builder{}
+			o.IOStreams = genericclioptions.IOStreams{Out: out, ErrOut: errOut}
+			return outString, errString
+		}
+		reset()
+		return o, reset
+	}
+
+	config := newRedFederalCowHammerConfig()
+	config.Contexts["gone"] = &clientcmdapi.Context{Cluster: "cow-cluster", AuthInfo: "red-user"}
+	if err := writeContextMetadata(config.Contexts["federal-context"], contextMetadata{Tags: []string{"prod"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := writeContextMetadata(config.Contexts["gone"], contextMetadata{Tags: []string{"dev"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := writeExtension(&config.Contexts["federal-context"].Extensions, "example.com/other", map[string]string{"a": "b"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	source, sourceOutput := newMachine("source", config, &Settings{AliasTemplate: "k-{{.Context}}", ProtectedPatterns: []string{"prod-*"}})
+	if err := saveProfiles(source.ProfilesFile, &profiles{Current: "work", Profiles: map[string][]string{"work": {"/work/config"}}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := source.RunExport(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	bundle, _ := sourceOutput()
+	if strings.Contains(bundle, "example.com/other") || strings.Contains(bundle, "red-token") {
+		t.Errorf("expected only the metadata of the config subcommands to be exported, got:\n%s", bundle)
+	}
+	bundleFile := filepath.Join(dir, "bundle.yaml")
+	if err := ioutil.WriteFile(bundleFile, []byte(bundle), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	destination, destinationOutput := newMachine("destination", newRedFederalCowHammerConfig(), &Settings{AliasTemplate: "{{.Context}}"})
+	destination.Filename = bundleFile
+	if err := destination.RunImport(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out, errOut := destinationOutput()
+	if expected := "Restored 1 kubeconfig extension(s).\nRestored the settings protectedPatterns.\nRestored the profiles work.\n"; out != expected {
+		t.Errorf("expected output %q, got %q", expected, out)
+	}
+	if expected := "warning: skipped the metadata of context \"gone\", which is not in kubeconfig\n"; errOut != expected {
+		t.Errorf("expected warnings %q, got %q", expected, errOut)
+	}
+
+	restored, err := clientcmd.LoadFromFile(destination.ConfigAccess.GetDefaultFilename())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	metadata, err := readContextMetadata(restored.Contexts["federal-context"])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(metadata.Tags, []string{"prod"}) {
+		t.Errorf("expected the tags to be restored, got %v", metadata.Tags)
+	}
+	settings, err := loadSettings(destination.SettingsFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if settings.AliasTemplate != "{{.Context}}" || !reflect.DeepEqual(settings.ProtectedPatterns, []string{"prod-*"}) {
+		t.Errorf("expected the settings already present to be kept, got %+v", settings)
+	}
+
+	destination.Overwrite = true
+	if err := destination.RunImport(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if settings, err = loadSettings(destination.SettingsFile); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if settings.AliasTemplate != "k-{{.Context}}" {
+		t.Errorf("expected --overwrite to replace the alias template, got %q", settings.AliasTemplate)
+	}
+}
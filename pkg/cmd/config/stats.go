@@ -0,0 +1,257 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/printers"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// StatsOptions holds the command-line options for 'config stats' sub command
+type StatsOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Top          int
+
+	genericclioptions.IOStreams
+}
+
+// statsEntry is the encoded size of a single named stanza.
+type statsEntry struct {
+	kind string
+	name string
+	size int
+}
+
+var (
+	statsLong = templates.LongDesc(`
+		Reports the size and composition of the kubeconfig, to help finding out why it grew large.
+
+		The report lists every file of the loading chain with its size, the number of contexts,
+		clusters and users, how users authenticate, how much space embedded certificates and keys
+		take, and the largest entries.`)
+
+	statsExample = templates.Examples(`
+		# Report on the current kubeconfig
+		kubectl config stats
+
+		# Show the 20 largest entries
+		kubectl config stats --top=20`)
+)
+
+// NewCmdConfigStats returns a Command instance for 'config stats' sub command
+func NewCmdConfigStats(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &StatsOptions{
+		ConfigAccess: configAccess,
+		Top:          5,
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "stats [--top=N]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Report the size and composition of the kubeconfig"),
+		Long:                  statsLong,
+		Example:               statsExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(cmdutil.UsageErrorf(cmd, "unexpected arguments: %v", args))
+			}
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmd.Flags().IntVar(&o.Top, "top", o.Top, "Number of largest entries to list")
+	return cmd
+}
+
+// Run performs the execution of 'config stats' sub command
+func (o *StatsOptions) Run() error {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	w := printers.GetNewTabWriter(o.Out)
+
+	fmt.Fprintf(w, "FILE\tSIZE\n")
+	files := o.ConfigAccess.GetLoadingPrecedence()
+	if o.ConfigAccess.IsExplicitFile() {
+		files = []string{o.ConfigAccess.GetExplicitFile()}
+	}
+	for _, filename := range files {
+		info, err := os.Stat(filename)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\t%s\n", filename, formatByteSize(int(info.Size())))
+	}
+
+	fmt.Fprintf(w, "\nCONTEXTS\tCLUSTERS\tUSERS\n")
+	fmt.Fprintf(w, "%d\t%d\t%d\n", len(config.Contexts), len(config.Clusters), len(config.AuthInfos))
+
+	authTypes := map[string]int{}
+	for _, authInfo := range config.AuthInfos {
+		authTypes[authInfoType(authInfo)]++
+	}
+	fmt.Fprintf(w, "\nAUTH TYPE\tUSERS\n")
+	for _, authType := range sortedCountKeys(authTypes) {
+		fmt.Fprintf(w, "%s\t%d\n", authType, authTypes[authType])
+	}
+
+	fmt.Fprintf(w, "\nEMBEDDED DATA\tCOUNT\tSIZE\n")
+	writeEmbeddedStats(w, "certificate-authority-data", func(add func([]byte)) {
+		for _, cluster := range config.Clusters {
+			add(cluster.CertificateAuthorityData)
+		}
+	})
+	writeEmbeddedStats(w, "client-certificate-data", func(add func([]byte)) {
+		for _, authInfo := range config.AuthInfos {
+			add(authInfo.ClientCertificateData)
+		}
+	})
+	writeEmbeddedStats(w, "client-key-data", func(add func([]byte)) {
+		for _, authInfo := range config.AuthInfos {
+			add(authInfo.ClientKeyData)
+		}
+	})
+
+	entries, err := statsEntries(config)
+	if err != nil {
+		return err
+	}
+	if o.Top > 0 && len(entries) > 0 {
+		if len(entries) > o.Top {
+			entries = entries[:o.Top]
+		}
+		fmt.Fprintf(w, "\nKIND\tLARGEST ENTRIES\tSIZE\n")
+		for _, entry := range entries {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", entry.kind, entry.name, formatByteSize(entry.size))
+		}
+	}
+	return w.Flush()
+}
+
+// authInfoType names the way a user authenticates, for reports grouping users by it.
+func authInfoType(authInfo *clientcmdapi.AuthInfo) string {
+	switch {
+	case authInfo.Exec != nil:
+		return fmt.Sprintf("exec (%s)", authInfo.Exec.Command)
+	case authInfo.AuthProvider != nil:
+		return fmt.Sprintf("auth-provider (%s)", authInfo.AuthProvider.Name)
+	case len(authInfo.ClientCertificate) > 0 || len(authInfo.ClientCertificateData) > 0:
+		return "client-certificate"
+	case len(authInfo.Token) > 0:
+		return "token"
+	case len(authInfo.TokenFile) > 0:
+		return "token-file"
+	case len(authInfo.Username) > 0 || len(authInfo.Password) > 0:
+		return "basic"
+	default:
+		return "none"
+	}
+}
+
+// writeEmbeddedStats writes the number and total size of the non-empty payloads passed to add.
+func writeEmbeddedStats(w io.Writer, field string, each func(add func([]byte))) {
+	count, size := 0, 0
+	each(func(data []byte) {
+		if len(data) > 0 {
+			count++
+			size += len(data)
+		}
+	})
+	fmt.Fprintf(w, "%s\t%d\t%s\n", field, count, formatByteSize(size))
+}
+
+// statsEntries returns every context, cluster and user with its encoded size, largest first.
+func statsEntries(config *clientcmdapi.Config) ([]statsEntry, error) {
+	entries := []statsEntry{}
+	add := func(kind, name string, obj interface{}) error {
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, statsEntry{kind: kind, name: name, size: len(data)})
+		return nil
+	}
+	for name, context := range config.Contexts {
+		if err := add("context", name, context); err != nil {
+			return nil, err
+		}
+	}
+	for name, cluster := range config.Clusters {
+		if err := add("cluster", name, cluster); err != nil {
+			return nil, err
+		}
+	}
+	for name, authInfo := range config.AuthInfos {
+		if err := add("user", name, authInfo); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].size != entries[j].size {
+			return entries[i].size > entries[j].size
+		}
+		if entries[i].kind != entries[j].kind {
+			return entries[i].kind < entries[j].kind
+		}
+		return entries[i].name < entries[j].name
+	})
+	return entries, nil
+}
+
+func sortedCountKeys(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatByteSize formats a size with a binary unit, e.g. 1536 as "1.5 KiB".
+func formatByteSize(size int) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value := float64(size)
+	for _, suffix := range []string{"KiB", "MiB", "GiB"} {
+		value /= unit
+		if value < unit || suffix == "GiB" {
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
+	}
+	return fmt.Sprintf("%d B", size)
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestStats(t *testing.T) {
+	config := newRedFederalCowHammerConfig()
+	config.Clusters["cow-cluster"].CertificateAuthorityData = bytes.Repeat([]byte("c"), 3000)
+	config.AuthInfos["red-user"].ClientCertificateData = []byte("cert")
+	config.AuthInfos["red-user"].ClientKeyData = []byte("key")
+	config.AuthInfos["eks-user"] = &clientcmdapi.AuthInfo{Exec: &clientcmdapi.ExecConfig{Command: "aws"}}
+	config.AuthInfos["blue-user"] = &clientcmdapi.AuthInfo{Token: "blue-token"}
+
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	if err := clientcmd.WriteToFile(config, fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	options := &StatsOptions{ConfigAccess: pathOptions, Top: 1, IOStreams: streams}
+	if err := options.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := []string{}
+	for _, line := range strings.Split(out.String(), "\n") {
+		lines = append(lines, strings.Join(strings.Fields(line), " "))
+	}
+	output := strings.Join(lines, "\n")
+	for _, expected := range []string{
+		"CONTEXTS CLUSTERS USERS\n1 1 3\n",
+		"AUTH TYPE USERS\nclient-certificate 1\nexec (aws) 1\ntoken 1\n",
+		"certificate-authority-data 1 2.9 KiB\n",
+		"client-certificate-data 1 4 B\n",
+		"client-key-data 1 3 B\n",
+		"LARGEST ENTRIES SIZE\ncluster cow-cluster ",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+	if !strings.Contains(output, fakeKubeFile.Name()+" ") {
+		t.Errorf("expected the size of %s in the output, got:\n%s", fakeKubeFile.Name(), output)
+	}
+	if strings.Contains(output, "\nuser ") {
+		t.Errorf("expected --top=1 to limit the largest entries, got:\n%s", output)
+	}
+}
+
+func TestFormatByteSize(t *testing.T) {
+	tests := map[int]string{
+		0:               "0 B",
+		1023:            "1023 B",
+		1536:            "1.5 KiB",
+		5 * 1024 * 1024: "5.0 MiB",
+		1 << 30:         "1.0 GiB",
+	}
+	for size, expected := range tests {
+		if actual := formatByteSize(size); actual != expected {
+			t.Errorf("formatByteSize(%d): expected %q, got %q", size, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,348 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/util/homedir"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+	"k8s.io/kubectl/pkg/version"
+)
+
+// secretEnvironmentName matches the names of environment variables likely to hold secrets, whose
+// values are left out of support bundles.
+var secretEnvironmentName = regexp.MustCompile(`(?i)secret|token|password|passwd|passphrase|key|credential|cookie|session|pin$|auth`)
+
+// SupportBundleOptions holds the command-line options for 'config support-bundle' sub command
+type SupportBundleOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	EnvVar       string
+	Output       string
+	AuditEntries int
+	AuditLog     string
+
+	// Now, Environ, FindKubectls and KubectlVersion default to the real clock, environment and
+	// kubectl binaries, they are fields so tests can describe them.
+	Now            func() time.Time
+	Environ        func() []string
+	FindKubectls   func() []string
+	KubectlVersion func(kubectl string) (*utilversion.Version, error)
+
+	genericclioptions.IOStreams
+}
+
+var (
+	supportBundleLong = templates.LongDesc(`
+		Collects what is needed to investigate a kubectl problem into a gzipped tar archive, to
+		attach to a support ticket.
+
+		The archive holds:
+
+		    * kubeconfig.yaml, the merged kubeconfig, with the credentials of its users replaced
+		      by REDACTED
+		    * doctor.txt and lint.txt, the results of "kubectl config doctor" and "kubectl config lint"
+		    * versions.txt, the versions of this command, of Go and of the installed kubectl binaries
+		    * environment.txt, the environment variables, with the values of those whose names
+		      suggest secrets, and the passwords of URLs, replaced by REDACTED
+		    * audit.log, the latest commands recorded by "kubectl config exec", when auditExec is set
+
+		Nothing is sent anywhere: review the archive before sharing it.`)
+
+	supportBundleExample = templates.Examples(`
+		# Collect a support bundle
+		kubectl config support-bundle -o bundle.tgz
+
+		# Collect it with the last 1000 audited commands
+		kubectl config support-bundle -o bundle.tgz --audit-entries 1000`)
+)
+
+// NewCmdConfigSupportBundle returns a Command instance for 'config support-bundle' sub command
+func NewCmdConfigSupportBundle(streams genericclioptions.IOStreams, pathOptions *clientcmd.PathOptions) *cobra.Command {
+	o := &SupportBundleOptions{
+		ConfigAccess:   pathOptions,
+		EnvVar:         pathOptions.EnvVar,
+		AuditEntries:   200,
+		AuditLog:       auditLogFile(),
+		Now:            time.Now,
+		Environ:        os.Environ,
+		FindKubectls:   findKubectlBinaries,
+		KubectlVersion: kubectlClientVersion,
+		IOStreams:      streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "support-bundle -o FILE [--audit-entries=N]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Collect sanitized diagnostics into an archive for support"),
+		Long:                  supportBundleLong,
+		Example:               supportBundleExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(helpErrorf(cmd, "Unexpected args: %v", args))
+			}
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+	cmd.Flags().StringVarP(&o.Output, "output", "o", o.Output, "File the archive is written to, - for the standard output")
+	cmd.Flags().IntVar(&o.AuditEntries, "audit-entries", o.AuditEntries, "Number of the latest audit log entries included")
+	return cmd
+}
+
+// Validate makes sure there is a file to write the archive to
+func (o *SupportBundleOptions) Validate() error {
+	if len(o.Output) == 0 {
+		return errors.New("an output file is required, use -o FILE")
+	}
+	if o.AuditEntries < 0 {
+		return errors.New("--audit-entries cannot be negative")
+	}
+	return nil
+}
+
+// Run collects the diagnostics and writes the archive
+func (o *SupportBundleOptions) Run() error {
+	now := o.Now()
+	files := []struct {
+		name    string
+		collect func() ([]byte, error)
+	}{
+		{"kubeconfig.yaml", o.kubeconfig},
+		{"doctor.txt", o.doctor},
+		{"lint.txt", o.lint},
+		{"versions.txt", o.versions},
+		{"environment.txt", o.environment},
+		{"audit.log", o.auditEntries},
+	}
+
+	archive := &bytes.Buffer{}
+	compressed := gzip.NewWriter(archive)
+	writer := tar.NewWriter(compressed)
+	for _, file := range files {
+		data, err := file.collect()
+		if err != nil {
+			// A part that cannot be collected is itself worth reporting.
+			data = []byte(fmt.Sprintf("error: %v\n", err))
+		}
+		if data == nil {
+			continue
+		}
+		header := &tar.Header{Name: file.name, Mode: 0600, Size: int64(len(data)), ModTime: now, Typeflag: tar.TypeReg}
+		if err := writer.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := writer.Write(data); err != nil {
+			return err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	if err := compressed.Close(); err != nil {
+		return err
+	}
+
+	if o.Output == "-" {
+		_, err := o.Out.Write(archive.Bytes())
+		return err
+	}
+	if err := ioutil.WriteFile(o.Output, archive.Bytes(), 0600); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.Out, "Support bundle written to %s, review it before sharing it.\n", o.Output)
+	return nil
+}
+
+// kubeconfig returns the merged kubeconfig without credentials.
+func (o *SupportBundleOptions) kubeconfig() ([]byte, error) {
+	config, err := o.ConfigAccess.GetStartingConfig()
+	if err != nil {
+		return nil, err
+	}
+	sanitized, err := sanitizeConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return clientcmd.Write(*sanitized)
+}
+
+// sanitizeConfig returns a copy of config whose users have no credentials, including the
+// encrypted and refreshed ones kept in their extensions.
+func sanitizeConfig(config *clientcmdapi.Config) (*clientcmdapi.Config, error) {
+	sanitized := config.DeepCopy()
+	for name, authInfo := range sanitized.AuthInfos {
+		authInfo = sanitizeAuthInfo(authInfo)
+		for _, extension := range []string{encryptedUserExtension, credentialRefreshExtension} {
+			if _, ok := authInfo.Extensions[extension]; ok {
+				if err := writeExtension(&authInfo.Extensions, extension, "REDACTED"); err != nil {
+					return nil, err
+				}
+			}
+		}
+		sanitized.AuthInfos[name] = authInfo
+	}
+	return sanitized, nil
+}
+
+// doctor returns the output of "config doctor", without fixing anything.
+func (o *SupportBundleOptions) doctor() ([]byte, error) {
+	out := &bytes.Buffer{}
+	doctor := &DoctorOptions{
+		ConfigAccess: o.ConfigAccess,
+		EnvVar:       o.EnvVar,
+		LookPath:     exec.LookPath,
+		Getenv:       o.getenv,
+		HomeDir:      homedir.HomeDir(),
+		IOStreams:    genericclioptions.IOStreams{In: &bytes.Buffer{}, Out: out, ErrOut: out},
+	}
+	if err := doctor.Run(); err != nil {
+		fmt.Fprintf(out, "\n%v\n", err)
+	}
+	return out.Bytes(), nil
+}
+
+// lint returns the output of "config lint" with the naming scheme of the settings, without fixing
+// anything.
+func (o *SupportBundleOptions) lint() ([]byte, error) {
+	out := &bytes.Buffer{}
+	lint := &LintOptions{ConfigAccess: o.ConfigAccess, IOStreams: genericclioptions.IOStreams{In: &bytes.Buffer{}, Out: out, ErrOut: out}}
+	if err := lint.Complete(); err != nil {
+		return nil, err
+	}
+	if len(lint.Naming) == 0 {
+		return []byte("no naming scheme, the namingPattern setting is not set\n"), nil
+	}
+	if err := lint.Run(); err != nil {
+		fmt.Fprintf(out, "\n%v\n", err)
+	}
+	return out.Bytes(), nil
+}
+
+// versions returns the versions of this command, of Go and of the kubectl binaries installed.
+func (o *SupportBundleOptions) versions() ([]byte, error) {
+	out := &bytes.Buffer{}
+	fmt.Fprintf(out, "kubectl config: %s\n", version.Get().GitVersion)
+	fmt.Fprintf(out, "go: %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	for _, kubectl := range o.FindKubectls() {
+		kubectlVersion, err := o.KubectlVersion(kubectl)
+		if err != nil {
+			fmt.Fprintf(out, "%s: %v\n", kubectl, err)
+			continue
+		}
+		fmt.Fprintf(out, "%s: v%s\n", kubectl, kubectlVersion)
+	}
+	return out.Bytes(), nil
+}
+
+// environment returns the sorted environment variables, redacted.
+func (o *SupportBundleOptions) environment() ([]byte, error) {
+	variables := o.Environ()
+	sort.Strings(variables)
+	out := &bytes.Buffer{}
+	for _, variable := range variables {
+		name, value := variable, ""
+		if i := strings.Index(variable, "="); i >= 0 {
+			name, value = variable[:i], variable[i+1:]
+		}
+		fmt.Fprintf(out, "%s=%s\n", name, redactEnvironmentValue(name, value))
+	}
+	return out.Bytes(), nil
+}
+
+// getenv looks name up in o.Environ.
+func (o *SupportBundleOptions) getenv(name string) string {
+	for _, variable := range o.Environ() {
+		if strings.HasPrefix(variable, name+"=") {
+			return variable[len(name)+1:]
+		}
+	}
+	return ""
+}
+
+// redactEnvironmentValue returns the value of the environment variable called name, or REDACTED
+// when the name suggests a secret. The passwords of URLs, such as the ones of proxies, are
+// redacted as well.
+func redactEnvironmentValue(name, value string) string {
+	if len(value) == 0 {
+		return value
+	}
+	if secretEnvironmentName.MatchString(name) {
+		return "REDACTED"
+	}
+	if u, err := url.Parse(value); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "REDACTED")
+			return u.String()
+		}
+	}
+	return value
+}
+
+// auditEntries returns the latest o.AuditEntries lines of the audit log, or nil when there is none.
+func (o *SupportBundleOptions) auditEntries() ([]byte, error) {
+	if o.AuditEntries == 0 {
+		return nil, nil
+	}
+	file, err := os.Open(o.AuditLog)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return lastLines(file, o.AuditEntries)
+}
+
+// lastLines returns the last n lines read from r.
+func lastLines(r io.Reader, n int) ([]byte, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if len(lines) > 0 && len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return []byte(strings.Join(lines, "")), nil
+}
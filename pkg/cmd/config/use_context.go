@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -31,39 +32,156 @@ import (
 )
 
 var (
+	useContextLong = templates.LongDesc(`
+		Sets the current-context in a kubeconfig file.
+
+		With restoreNamespace enabled in the settings file, the namespace of the context being left is
+		remembered in a kubeconfig extension, and switching back to a context returns to the namespace
+		last used there, even if another tool changed it in the meantime.
+
+		With kubectxState enabled in the settings file, see "kubectl config import kubectx-state",
+		the context switched away from is recorded where kubectx keeps it, and "-" switches back to
+		it like "kubectx -" does.
+
+		With the cooloff setting, switching to a context tagged prod, or another of cooloffTags, needs
+		--acknowledge once the last acknowledgement of the context is older than the cooloff. The
+		safeMode setting enables a cooloff of 30m for contexts tagged or named like production,
+		and --verify=warn, unless those settings are given.
+
+		With --verify, or the verifyOnUse setting, an authenticated request is made with the
+		credentials of the context before switching to it, so that expired or broken credentials are
+		found at switch time rather than in the middle of a task. --verify=abort, the same as
+		--verify alone, refuses to switch when the request fails, --verify=warn switches anyway
+		with a warning and --verify=never skips the check set by verifyOnUse.
+
+		With --group, the context switched to is a member of a group created with "kubectl config
+		group create", picked with --strategy: first-healthy, the default, switches to the first
+		member whose credentials work, round-robin to the member following the one picked last.
+
+		With a context protected with "kubectl config protect --require-fido2", the security key
+		has to be touched before switching to it.
+
+		The banners set with "kubectl config banner" for the context switched to, or its tags,
+		are printed after switching.`)
+
 	useContextExample = templates.Examples(`
 		# Use the context for the minikube cluster
-		kubectl config use-context minikube`)
+		kubectl config use-context minikube
+
+		# Switch back to the context switched away from last, with the kubectxState setting
+		kubectl config use-context -
+
+		# Use a production context whose acknowledgement ran out
+		kubectl config use-context prod-eu --acknowledge
+
+		# Only switch to the staging context if its credentials still work
+		kubectl config use-context staging --verify
+
+		# Switch to the first working endpoint of the api-prod group
+		kubectl config use-context --group api-prod`)
 )
 
 type UseContextOptions struct {
-	ConfigAccess clientcmd.ConfigAccess
-	ContextName  string
+	ConfigAccess     clientcmd.ConfigAccess
+	ContextName      string
+	RestoreNamespace bool
+	Acknowledge      bool
+	Cooloff          *cooloffPolicy
+	// Group names the group the context is picked from, with Strategy.
+	Group    string
+	Strategy string
+	// Verify is one of never, warn or abort.
+	Verify string
+	// Check makes an authenticated request with the credentials of a context.
+	Check func(config *clientcmdapi.Config, context string) contextHealth
+	// Kubectx is set when the previous context and namespaces are shared with kubectx and kubens.
+	Kubectx *kubectxState
+	// RunCommand runs the libfido2 tools for the contexts protected with "config protect".
+	RunCommand commandRunner
+	// Prompt is told to touch the security key of a protected context.
+	Prompt io.Writer
+
+	// VerifyWarning is set by Run when the context was switched to although it failed verification.
+	VerifyWarning string
+	// RestoredNamespace is set by Run when it returned to the namespace last used in the context.
+	RestoredNamespace string
+	// DeprecationWarning is set by Run when the context switched to is deprecated.
+	DeprecationWarning string
+	// Banners is set by Run to the banners of the context switched to, see "config banner".
+	Banners []string
 }
 
 // NewCmdConfigUseContext returns a Command instance for 'config use-context' sub command
 func NewCmdConfigUseContext(out io.Writer, configAccess clientcmd.ConfigAccess) *cobra.Command {
-	options := &UseContextOptions{ConfigAccess: configAccess}
+	options := &UseContextOptions{ConfigAccess: configAccess, Check: checkContextHealth, RunCommand: runCommand, Prompt: out}
 
 	cmd := &cobra.Command{
-		Use:                   "use-context CONTEXT_NAME",
+		Use:                   "use-context (CONTEXT_NAME | --group NAME [--strategy STRATEGY])",
 		DisableFlagsInUseLine: true,
 		Short:                 i18n.T("Sets the current-context in a kubeconfig file"),
 		Aliases:               []string{"use"},
-		Long:                  `Sets the current-context in a kubeconfig file`,
+		Long:                  useContextLong,
 		Example:               useContextExample,
 		Run: func(cmd *cobra.Command, args []string) {
 			cmdutil.CheckErr(options.Complete(cmd))
 			cmdutil.CheckErr(options.Run())
 			fmt.Fprintf(out, "Switched to context %q.\n", options.ContextName)
+			if len(options.VerifyWarning) > 0 {
+				fmt.Fprintf(out, "warning: %s\n", options.VerifyWarning)
+			}
+			if len(options.RestoredNamespace) > 0 {
+				fmt.Fprintf(out, "Restored namespace %q.\n", options.RestoredNamespace)
+			}
+			if len(options.DeprecationWarning) > 0 {
+				fmt.Fprintf(out, "warning: %s\n", options.DeprecationWarning)
+			}
+			printBanners(out, options.ContextName, options.Banners)
 		},
 	}
+	cmd.Flags().BoolVar(&options.Acknowledge, "acknowledge", options.Acknowledge, "Acknowledge switching to a context the cooloff setting applies to")
+	cmd.Flags().StringVar(&options.Verify, "verify", options.Verify, "Check the credentials of the context before switching to it: abort, warn or never. Defaults to the verifyOnUse setting")
+	cmd.Flags().Lookup("verify").NoOptDefVal = verifyAbort
+	cmd.Flags().StringVar(&options.Group, "group", options.Group, "Switch to a member of this group of contexts instead of a named context")
+	cmd.Flags().StringVar(&options.Strategy, "strategy", groupStrategyFirstHealthy, "How the member of --group is picked. One of: "+strings.Join(groupStrategies, "|"))
 
 	return cmd
 }
 
-func (o UseContextOptions) Run() error {
-	config, err := o.ConfigAccess.GetStartingConfig()
+func (o *UseContextOptions) Run() error {
+	var err error
+	if o.ContextName == "-" && o.Kubectx != nil && len(o.Group) == 0 {
+		if o.ContextName, err = o.Kubectx.previousContext(); err != nil {
+			return err
+		}
+		if len(o.ContextName) == 0 {
+			return errors.New("kubectx recorded no previous context")
+		}
+	}
+
+	// Switching only looks at the context left and the context switched to, unless a member of a
+	// group has to be picked among all of them.
+	var config *clientcmdapi.Config
+	var index *kubeconfigIndex
+	if len(o.Group) > 0 {
+		config, err = o.ConfigAccess.GetStartingConfig()
+	} else {
+		config, index, err = loadContexts(o.ConfigAccess, o.ContextName)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(o.Group) > 0 {
+		if o.ContextName, err = pickGroupMember(config, o.Group, o.Strategy, o.Check); err != nil {
+			return err
+		}
+		if o.Strategy == groupStrategyFirstHealthy {
+			// The member picked was just checked.
+			o.Verify = verifyNever
+		}
+	}
+
+	o.ContextName, err = resolveContextName(config, o.ContextName)
 	if err != nil {
 		return err
 	}
@@ -73,21 +191,169 @@ func (o UseContextOptions) Run() error {
 		return err
 	}
 
+	if o.Cooloff != nil {
+		if err := o.Cooloff.check(config, o.ContextName, o.Acknowledge); err != nil {
+			return err
+		}
+	}
+
+	if err := requireFIDO2Touch(config, o.ContextName, o.RunCommand, o.Prompt); err != nil {
+		return err
+	}
+
+	if err := o.verify(config); err != nil {
+		return err
+	}
+	o.DeprecationWarning = warnDeprecatedContext(o.ContextName, config.Contexts[o.ContextName])
+	if o.Banners, err = contextBanners(config, o.ContextName); err != nil {
+		return err
+	}
+
+	left, namespace := config.CurrentContext, config.Contexts[o.ContextName].Namespace
+	if o.RestoreNamespace {
+		if o.RestoredNamespace, err = switchNamespaces(config, config.CurrentContext, o.ContextName); err != nil {
+			return err
+		}
+	}
 	config.CurrentContext = o.ContextName
 
-	return clientcmd.ModifyConfig(o.ConfigAccess, *config, true)
+	if err := saveContexts(o.ConfigAccess, config, index, left, o.ContextName); err != nil {
+		return err
+	}
+	if o.Kubectx != nil {
+		return o.recordKubectxState(left, namespace)
+	}
+	return nil
+}
+
+// recordKubectxState records the context left, and the namespace left when one was restored, for
+// "kubectx -" and "kubens -".
+func (o *UseContextOptions) recordKubectxState(left, namespace string) error {
+	if len(left) > 0 && left != o.ContextName {
+		if err := o.Kubectx.setPreviousContext(left); err != nil {
+			return err
+		}
+	}
+	if len(o.RestoredNamespace) > 0 {
+		if len(namespace) == 0 {
+			namespace = "default"
+		}
+		return o.Kubectx.setPreviousNamespace(o.ContextName, namespace)
+	}
+	return nil
 }
 
 func (o *UseContextOptions) Complete(cmd *cobra.Command) error {
 	endingArgs := cmd.Flags().Args()
-	if len(endingArgs) != 1 {
+	if len(o.Group) > 0 {
+		if len(endingArgs) != 0 {
+			return helpErrorf(cmd, "a context name cannot be combined with --group")
+		}
+		if !containsString(groupStrategies, o.Strategy) {
+			return fmt.Errorf("--strategy must be one of %s, got %q", strings.Join(groupStrategies, ", "), o.Strategy)
+		}
+		if o.Strategy == groupStrategyFirstHealthy {
+			if err := requireNetwork(cmd); err != nil {
+				return err
+			}
+		}
+	} else if len(endingArgs) != 1 {
 		return helpErrorf(cmd, "Unexpected args: %v", endingArgs)
+	} else {
+		o.ContextName = endingArgs[0]
+	}
+
+	verifyFlag := cmd.Flags().Lookup("verify")
+	if err := o.applySettings(verifyFlag != nil && verifyFlag.Changed); err != nil {
+		return err
+	}
+	if !validVerifySettings.Has(o.Verify) {
+		return fmt.Errorf("--verify must be one of abort, warn or never, got %q", o.Verify)
+	}
+	if o.Verify == verifyWarn || o.Verify == verifyAbort {
+		// An explicit --verify needs the network, the verifyOnUse setting only applies when it is allowed.
+		if verifyFlag != nil && verifyFlag.Changed {
+			return requireNetwork(cmd)
+		}
+		if networkDisabled(cmd) {
+			o.Verify = verifyNever
+		}
+	}
+	return nil
+}
+
+// applySettings configures restoring namespaces, the cooloff policy and, unless given by flag, the
+// verification of credentials from the user's settings.
+func (o *UseContextOptions) applySettings(verifyFlagSet bool) error {
+	settings, err := loadSettings(settingsFile())
+	if err != nil {
+		return err
 	}
+	settings = withSafeMode(settings)
+	o.RestoreNamespace = settings.RestoreNamespace
+	if settings.KubectxState {
+		o.Kubectx = &kubectxState{Dir: kubectxDir()}
+	}
+	if !verifyFlagSet {
+		o.Verify = settings.VerifyOnUse
+	}
+	o.Cooloff, err = newCooloffPolicy(settings)
+	return err
+}
 
-	o.ContextName = endingArgs[0]
+// Values of --verify and of the verifyOnUse setting.
+const (
+	verifyNever = "never"
+	verifyWarn  = "warn"
+	verifyAbort = "abort"
+)
+
+// verify makes an authenticated request with the credentials of the context switched to, and
+// refuses the switch or records a warning when it fails.
+func (o *UseContextOptions) verify(config *clientcmdapi.Config) error {
+	if o.Verify != verifyWarn && o.Verify != verifyAbort {
+		return nil
+	}
+	health := o.Check(config, o.ContextName)
+	if health.Healthy() {
+		return nil
+	}
+	message := fmt.Sprintf("context %q failed verification: %s", o.ContextName, health.Status())
+	if len(health.Error) > 0 {
+		message += ": " + health.Error
+	}
+	if o.Verify == verifyAbort {
+		return fmt.Errorf("%s, use --verify=never to switch anyway", message)
+	}
+	o.VerifyWarning = message
 	return nil
 }
 
+// switchNamespaces remembers the namespace of the context being left and returns the namespace last
+// used in the context being switched to, if it had to be restored.
+func switchNamespaces(config *clientcmdapi.Config, from, to string) (string, error) {
+	if from == to {
+		return "", nil
+	}
+	if context, ok := config.Contexts[from]; ok {
+		if err := writeExtension(&context.Extensions, lastNamespaceExtension, lastNamespace{Namespace: context.Namespace}); err != nil {
+			return "", err
+		}
+	}
+
+	context := config.Contexts[to]
+	last := lastNamespace{}
+	found, err := readExtension(context.Extensions, lastNamespaceExtension, &last)
+	if err != nil {
+		return "", fmt.Errorf("context %q: %v", to, err)
+	}
+	if !found || last.Namespace == context.Namespace {
+		return "", nil
+	}
+	context.Namespace = last.Namespace
+	return last.Namespace, nil
+}
+
 func (o UseContextOptions) validate(config *clientcmdapi.Config) error {
 	if len(o.ContextName) == 0 {
 		return errors.New("empty context names are not allowed")
@@ -102,3 +102,164 @@ func (test useContextTest) run(t *testing.T) {
 		t.Errorf("Failed in :%q\n expected config %v, but found %v\n in kubeconfig\n", test.description, test.expectedConfig, config)
 	}
 }
+
+func TestUseContextRestoresNamespace(t *testing.T) {
+	settingsDir, err := ioutil.TempDir("", "settings")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(settingsDir)
+	defer os.Setenv("XDG_CONFIG_HOME", os.Getenv("XDG_CONFIG_HOME"))
+	os.Setenv("XDG_CONFIG_HOME", settingsDir)
+	if err := os.MkdirAll(configDir(), 0700); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ioutil.WriteFile(settingsFile(), []byte("restoreNamespace: true\n"), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fakeKubeFile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(fakeKubeFile.Name())
+	conf := clientcmdapi.Config{
+		Clusters: map[string]*clientcmdapi.Cluster{"minikube": {Server: "https://192.168.99.100:8443"}},
+		Contexts: map[string]*clientcmdapi.Context{
+			"minikube":   {AuthInfo: "minikube", Cluster: "minikube", Namespace: "team-a"},
+			"my-cluster": {AuthInfo: "minikube", Cluster: "minikube"},
+		},
+		CurrentContext: "minikube",
+	}
+	if err := clientcmd.WriteToFile(conf, fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	pathOptions.GlobalFile = fakeKubeFile.Name()
+	pathOptions.EnvVar = ""
+
+	useContext := func(name string) string {
+		buf := bytes.NewBuffer([]byte{})
+		cmd := NewCmdConfigUseContext(buf, pathOptions)
+		cmd.SetArgs([]string{name})
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return buf.String()
+	}
+
+	useContext("my-cluster")
+
+	// Simulate a tool resetting the namespace while the context is not in use.
+	config, err := clientcmd.LoadFromFile(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	config.Contexts["minikube"].Namespace = ""
+	if err := clientcmd.WriteToFile(*config, fakeKubeFile.Name()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `Switched to context "minikube".` + "\n" + `Restored namespace "team-a".` + "\n"
+	if out := useContext("minikube"); out != expected {
+		t.Errorf("expected %q, got %q", expected, out)
+	}
+	config, err = clientcmd.LoadFromFile(fakeKubeFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Contexts["minikube"].Namespace != "team-a" {
+		t.Errorf("expected the namespace to be restored, got %q", config.Contexts["minikube"].Namespace)
+	}
+}
+
+func TestUseContextVerify(t *testing.T) {
+	conf := clientcmdapi.Config{
+		Clusters: map[string]*clientcmdapi.Cluster{"minikube": {Server: "https://192.168.99.100:8443"}},
+		Contexts: map[string]*clientcmdapi.Context{
+			"minikube":   {AuthInfo: "minikube", Cluster: "minikube"},
+			"my-cluster": {AuthInfo: "minikube", Cluster: "minikube"},
+		},
+		CurrentContext: "minikube",
+	}
+	expired := func(config *clientcmdapi.Config, context string) contextHealth {
+		return contextHealth{Context: context, Reachable: true, Error: "Unauthorized"}
+	}
+
+	tests := []struct {
+		name            string
+		verify          string
+		check           func(config *clientcmdapi.Config, context string) contextHealth
+		expectedErr     string
+		expectedWarning string
+		expectedCurrent string
+	}{
+		{
+			name:   "healthy",
+			verify: verifyAbort,
+			check: func(*clientcmdapi.Config, string) contextHealth {
+				return contextHealth{Reachable: true, Authenticated: true}
+			},
+			expectedCurrent: "my-cluster",
+		},
+		{
+			name:            "abort",
+			verify:          verifyAbort,
+			check:           expired,
+			expectedErr:     `context "my-cluster" failed verification: unauthenticated: Unauthorized, use --verify=never to switch anyway`,
+			expectedCurrent: "minikube",
+		},
+		{
+			name:            "warn",
+			verify:          verifyWarn,
+			check:           expired,
+			expectedWarning: `context "my-cluster" failed verification: unauthenticated: Unauthorized`,
+			expectedCurrent: "my-cluster",
+		},
+		{
+			name:   "never",
+			verify: verifyNever,
+			check: func(*clientcmdapi.Config, string) contextHealth {
+				t.Errorf("expected the context not to be checked")
+				return contextHealth{}
+			},
+			expectedCurrent: "my-cluster",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fakeKubeFile, err := ioutil.TempFile("", "")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer os.Remove(fakeKubeFile.Name())
+			if err := clientcmd.WriteToFile(conf, fakeKubeFile.Name()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			pathOptions := clientcmd.NewDefaultPathOptions()
+			pathOptions.GlobalFile = fakeKubeFile.Name()
+			pathOptions.EnvVar = ""
+
+			options := UseContextOptions{ConfigAccess: pathOptions, ContextName: "my-cluster", Verify: test.verify, Check: test.check}
+			err = options.Run()
+			if len(test.expectedErr) > 0 {
+				if err == nil || err.Error() != test.expectedErr {
+					t.Errorf("expected error %q, got %v", test.expectedErr, err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if options.VerifyWarning != test.expectedWarning {
+				t.Errorf("expected warning %q, got %q", test.expectedWarning, options.VerifyWarning)
+			}
+			config, err := clientcmd.LoadFromFile(fakeKubeFile.Name())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if config.CurrentContext != test.expectedCurrent {
+				t.Errorf("expected current-context %q, got %q", test.expectedCurrent, config.CurrentContext)
+			}
+		})
+	}
+}
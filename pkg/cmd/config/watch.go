@@ -0,0 +1,344 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/tools/clientcmd/api/latest"
+	clientcmdapiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// Types of configEvent.
+const (
+	configEventAdded    = "added"
+	configEventModified = "modified"
+	configEventDeleted  = "deleted"
+)
+
+// configEvent describes a change of the kubeconfig. Its JSON form is a stable schema consumed by
+// tools: Entity is current-context, context/NAME, cluster/NAME or user/NAME, Old and New are the
+// entry before and after the change, with credentials redacted, and File is the file holding it.
+type configEvent struct {
+	Type      string      `json:"type"`
+	Entity    string      `json:"entity"`
+	Old       interface{} `json:"old,omitempty"`
+	New       interface{} `json:"new,omitempty"`
+	File      string      `json:"file,omitempty"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// WatchOptions holds the command-line options for 'config watch' sub command
+type WatchOptions struct {
+	ConfigAccess clientcmd.ConfigAccess
+	Output       string
+	Interval     time.Duration
+
+	log *cmdLogger
+
+	genericclioptions.IOStreams
+}
+
+var (
+	watchLong = templates.LongDesc(`
+		Print the changes made to the kubeconfig as they happen.
+
+		The files of the loading chain are polled every --interval. When one of them changes,
+		an event is printed for every context, cluster and user added, modified or deleted, and
+		for a change of the current context.
+
+		With -o json, every event is printed as a JSON object on its own line, with the fields
+		type (added, modified or deleted), entity (current-context, context/NAME, cluster/NAME or
+		user/NAME), old and new (the entry before and after the change), file (the kubeconfig
+		file holding the entry) and timestamp. Credentials are redacted from old and new. This
+		lets editors and status bars follow changes without parsing kubeconfig files.`)
+
+	watchExample = templates.Examples(`
+		# Print changes of the kubeconfig
+		kubectl config watch
+
+		# Stream changes as JSON lines to another program
+		kubectl config watch -o json | my-status-bar`)
+)
+
+// NewCmdConfigWatch returns a Command instance for 'config watch' sub command
+func NewCmdConfigWatch(streams genericclioptions.IOStreams, configAccess clientcmd.ConfigAccess) *cobra.Command {
+	o := &WatchOptions{
+		ConfigAccess: configAccess,
+		Output:       "text",
+		Interval:     time.Second,
+
+		IOStreams: streams,
+	}
+
+	cmd := &cobra.Command{
+		Use:                   "watch [-o text|json] [--interval=DURATION]",
+		DisableFlagsInUseLine: true,
+		Short:                 i18n.T("Print the changes made to the kubeconfig as they happen"),
+		Long:                  watchLong,
+		Example:               watchExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 0 {
+				cmdutil.CheckErr(cmdutil.UsageErrorf(cmd, "unexpected arguments: %v", args))
+			}
+			cmdutil.CheckErr(o.Complete(cmd))
+			cmdutil.CheckErr(o.Validate())
+			cmdutil.CheckErr(o.Run(nil))
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.Output, "output", "o", o.Output, "Output format. One of: text|json")
+	cmd.Flags().DurationVar(&o.Interval, "interval", o.Interval, "Time between two checks of the kubeconfig files")
+	return cmd
+}
+
+// Complete sets up logging
+func (o *WatchOptions) Complete(cmd *cobra.Command) error {
+	var err error
+	o.log, err = newCmdLogger(cmd, o.ErrOut)
+	return err
+}
+
+// Validate makes sure the output format and the interval are supported
+func (o *WatchOptions) Validate() error {
+	if o.Output != "text" && o.Output != "json" {
+		return fmt.Errorf("unsupported output format %q, must be one of: text, json", o.Output)
+	}
+	if o.Interval <= 0 {
+		return errors.New("--interval must be positive")
+	}
+	return nil
+}
+
+// Run prints events until stop is closed, which never happens for a nil channel
+func (o *WatchOptions) Run(stop <-chan struct{}) error {
+	return watchConfig(o.ConfigAccess, o.Interval, stop, o.log, func(event configEvent) error {
+		return writeConfigEvent(o.Out, o.Output, event)
+	})
+}
+
+// watchConfig polls the kubeconfig files every interval and passes the changes found to emit, until
+// stop is closed or emit fails.
+func watchConfig(configAccess clientcmd.ConfigAccess, interval time.Duration, stop <-chan struct{}, log *cmdLogger, emit func(event configEvent) error) error {
+	var last *clientcmdapi.Config
+	lastStamp := ""
+	for {
+		if stamp := kubeconfigFilesStamp(configAccess); stamp != lastStamp {
+			config, err := configAccess.GetStartingConfig()
+			if err != nil {
+				// The file may be half written, the next check picks up the rest.
+				log.Warningf("cannot load kubeconfig: %v", err)
+			} else {
+				if last != nil {
+					for _, event := range diffConfigs(last, config, time.Now()) {
+						if err := emit(event); err != nil {
+							return err
+						}
+					}
+				}
+				last, lastStamp = config, stamp
+			}
+		}
+
+		select {
+		case <-stop:
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// kubeconfigFilesStamp changes whenever one of the kubeconfig files is written, created or removed.
+func kubeconfigFilesStamp(configAccess clientcmd.ConfigAccess) string {
+	files := configAccess.GetLoadingPrecedence()
+	if configAccess.IsExplicitFile() {
+		files = []string{configAccess.GetExplicitFile()}
+	}
+	stamps := make([]string, 0, len(files))
+	for _, filename := range files {
+		if info, err := os.Stat(filename); err == nil {
+			stamps = append(stamps, fmt.Sprintf("%s:%d:%d", filename, info.ModTime().UnixNano(), info.Size()))
+		} else {
+			stamps = append(stamps, filename+":-")
+		}
+	}
+	return strings.Join(stamps, "\n")
+}
+
+// diffConfigs returns the events turning before into after, in a stable order.
+func diffConfigs(before, after *clientcmdapi.Config, now time.Time) []configEvent {
+	events := []configEvent{}
+	if before.CurrentContext != after.CurrentContext {
+		event := configEvent{Type: configEventModified, Entity: "current-context", Timestamp: now}
+		switch {
+		case len(before.CurrentContext) == 0:
+			event.Type = configEventAdded
+		case len(after.CurrentContext) == 0:
+			event.Type = configEventDeleted
+		}
+		if len(before.CurrentContext) > 0 {
+			event.Old = before.CurrentContext
+		}
+		if len(after.CurrentContext) > 0 {
+			event.New = after.CurrentContext
+		}
+		events = append(events, event)
+	}
+
+	// entry returns an entry of config as it is compared and as it is shown in events.
+	type entry func(config *clientcmdapi.Config, name string) (compared, shown interface{}, file string, ok bool)
+	diff := func(kind string, names []string, get entry) {
+		for _, name := range names {
+			previous, previousShown, previousFile, inBefore := get(before, name)
+			current, currentShown, currentFile, inAfter := get(after, name)
+			event := configEvent{Entity: kind + "/" + name, Timestamp: now}
+			switch {
+			case inBefore && inAfter:
+				if reflect.DeepEqual(previous, current) {
+					continue
+				}
+				event.Type, event.Old, event.New, event.File = configEventModified, previousShown, currentShown, currentFile
+			case inAfter:
+				event.Type, event.New, event.File = configEventAdded, currentShown, currentFile
+			default:
+				event.Type, event.Old, event.File = configEventDeleted, previousShown, previousFile
+			}
+			events = append(events, event)
+		}
+	}
+	diff("context", mergedNames(sortedContextNames(before.Contexts), sortedContextNames(after.Contexts)), func(config *clientcmdapi.Config, name string) (interface{}, interface{}, string, bool) {
+		context, ok := config.Contexts[name]
+		if !ok {
+			return nil, nil, "", false
+		}
+		return context, externalEntry(context, &clientcmdapiv1.Context{}), context.LocationOfOrigin, true
+	})
+	diff("cluster", mergedNames(sortedClusterNames(before.Clusters), sortedClusterNames(after.Clusters)), func(config *clientcmdapi.Config, name string) (interface{}, interface{}, string, bool) {
+		cluster, ok := config.Clusters[name]
+		if !ok {
+			return nil, nil, "", false
+		}
+		shortened := &clientcmdapi.Config{Clusters: map[string]*clientcmdapi.Cluster{name: cluster.DeepCopy()}}
+		clientcmdapi.ShortenConfig(shortened)
+		return cluster, externalEntry(shortened.Clusters[name], &clientcmdapiv1.Cluster{}), cluster.LocationOfOrigin, true
+	})
+	diff("user", mergedNames(sortedAuthInfoNames(before.AuthInfos), sortedAuthInfoNames(after.AuthInfos)), func(config *clientcmdapi.Config, name string) (interface{}, interface{}, string, bool) {
+		authInfo, ok := config.AuthInfos[name]
+		if !ok {
+			return nil, nil, "", false
+		}
+		return authInfo, externalEntry(redactAuthInfo(authInfo), &clientcmdapiv1.AuthInfo{}), authInfo.LocationOfOrigin, true
+	})
+	return events
+}
+
+// externalEntry converts an entry to its v1 form, the one users know from kubeconfig files.
+func externalEntry(internal, external interface{}) interface{} {
+	if err := latest.Scheme.Convert(internal, external, nil); err != nil {
+		return internal
+	}
+	return external
+}
+
+// mergedNames returns the sorted union of two sorted lists of names.
+func mergedNames(a, b []string) []string {
+	merged := make([]string, 0, len(a)+len(b))
+	for len(a) > 0 || len(b) > 0 {
+		switch {
+		case len(b) == 0 || (len(a) > 0 && a[0] < b[0]):
+			merged, a = append(merged, a[0]), a[1:]
+		case len(a) == 0 || b[0] < a[0]:
+			merged, b = append(merged, b[0]), b[1:]
+		default:
+			merged, a, b = append(merged, a[0]), a[1:], b[1:]
+		}
+	}
+	return merged
+}
+
+// secretEnvVarName matches the names of exec plugin variables likely to hold secrets.
+var secretEnvVarName = regexp.MustCompile(`(?i)secret|token|password|key`)
+
+// redactAuthInfo returns a copy of the user with its secrets replaced by a marker.
+func redactAuthInfo(authInfo *clientcmdapi.AuthInfo) *clientcmdapi.AuthInfo {
+	redacted := authInfo.DeepCopy()
+	for _, value := range []*string{&redacted.Token, &redacted.Password} {
+		if len(*value) > 0 {
+			*value = "REDACTED"
+		}
+	}
+	clientcmdapi.ShortenConfig(&clientcmdapi.Config{AuthInfos: map[string]*clientcmdapi.AuthInfo{"": redacted}})
+	if redacted.AuthProvider != nil {
+		for _, key := range []string{"id-token", "refresh-token", "client-secret", "access-token"} {
+			if _, ok := redacted.AuthProvider.Config[key]; ok {
+				redacted.AuthProvider.Config[key] = "REDACTED"
+			}
+		}
+	}
+	if redacted.Exec != nil {
+		for i, env := range redacted.Exec.Env {
+			if secretEnvVarName.MatchString(env.Name) {
+				redacted.Exec.Env[i].Value = "REDACTED"
+			}
+		}
+	}
+	return redacted
+}
+
+// writeConfigEvent prints an event as a line of text or of JSON.
+func writeConfigEvent(out io.Writer, output string, event configEvent) error {
+	if output == "json" {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(out, "%s\n", data)
+		return err
+	}
+	line := fmt.Sprintf("%s\t%s\t%s", event.Timestamp.Format(time.RFC3339), event.Type, event.Entity)
+	if event.Entity == "current-context" {
+		line += fmt.Sprintf("\t%s -> %s", valueOrNone(stringOrEmpty(event.Old)), valueOrNone(stringOrEmpty(event.New)))
+	}
+	if len(event.File) > 0 {
+		line += "\t" + event.File
+	}
+	_, err := fmt.Fprintln(out, line)
+	return err
+}
+
+func stringOrEmpty(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return ""
+}
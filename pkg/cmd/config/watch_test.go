@@ -0,0 +1,99 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	clientcmdapiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+)
+
+func TestDiffConfigs(t *testing.T) {
+	now := time.Date(2019, 8, 1, 12, 0, 0, 0, time.UTC)
+	before := newRedFederalCowHammerConfig()
+	after := newRedFederalCowHammerConfig()
+	after.CurrentContext = "shaker-context"
+	after.Contexts["shaker-context"] = &clientcmdapi.Context{AuthInfo: "blue-user", Cluster: "cow-cluster", LocationOfOrigin: "/tmp/config"}
+	after.AuthInfos["red-user"] = &clientcmdapi.AuthInfo{Token: "new-token"}
+	delete(after.Clusters, "cow-cluster")
+
+	events := diffConfigs(&before, &after, now)
+	summary := []string{}
+	for _, event := range events {
+		summary = append(summary, event.Type+" "+event.Entity)
+	}
+	expected := []string{
+		"modified current-context",
+		"added context/shaker-context",
+		"deleted cluster/cow-cluster",
+		"modified user/red-user",
+	}
+	if !reflect.DeepEqual(summary, expected) {
+		t.Fatalf("expected events %v, got %v", expected, summary)
+	}
+	if events[1].File != "/tmp/config" {
+		t.Errorf("expected the file of the new context, got %q", events[1].File)
+	}
+	if user := events[3].New.(*clientcmdapiv1.AuthInfo); user.Token != "REDACTED" {
+		t.Errorf("expected the token to be redacted, got %q", user.Token)
+	}
+	if len(diffConfigs(&after, &after, now)) != 0 {
+		t.Errorf("expected no events for an unchanged config")
+	}
+}
+
+func TestWriteConfigEvent(t *testing.T) {
+	now := time.Date(2019, 8, 1, 12, 0, 0, 0, time.UTC)
+	event := configEvent{Type: configEventModified, Entity: "current-context", Old: "prod", New: "staging", Timestamp: now}
+
+	buf := &bytes.Buffer{}
+	if err := writeConfigEvent(buf, "json", event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := `{"type":"modified","entity":"current-context","old":"prod","new":"staging","timestamp":"2019-08-01T12:00:00Z"}` + "\n"; buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+
+	buf.Reset()
+	if err := writeConfigEvent(buf, "text", event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "2019-08-01T12:00:00Z\tmodified\tcurrent-context\tprod -> staging\n"; buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+
+	buf.Reset()
+	context := configEvent{Type: configEventDeleted, Entity: "context/prod", Old: &clientcmdapiv1.Context{Cluster: "prod"}, File: "/tmp/config", Timestamp: now}
+	if err := writeConfigEvent(buf, "json", context); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"old":{"cluster":"prod","user":""}`) || !strings.Contains(buf.String(), `"file":"/tmp/config"`) {
+		t.Errorf("unexpected event %s", buf.String())
+	}
+}
+
+func TestMergedNames(t *testing.T) {
+	merged := mergedNames([]string{"a", "c", "d"}, []string{"b", "c", "e"})
+	if expected := []string{"a", "b", "c", "d", "e"}; !reflect.DeepEqual(merged, expected) {
+		t.Errorf("expected %v, got %v", expected, merged)
+	}
+}